@@ -0,0 +1,184 @@
+package adapter_3_16
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/core"
+	protocol "github.com/SCKelemen/lsp/protocol"
+)
+
+// CoreToProtocolCodeAction converts a core code action to protocol.
+// Diagnostics are converted with content, the content of the document the
+// actions were requested for, and the edit with the content contentFor
+// returns by URI.
+func CoreToProtocolCodeAction(action core.CodeAction, content string, contentFor func(uri string) string) protocol.CodeAction {
+	result := protocol.CodeAction{
+		Title: action.Title,
+		Kind:  (*protocol.CodeActionKind)(action.Kind),
+		Data:  action.Data,
+	}
+	if len(action.Diagnostics) > 0 {
+		result.Diagnostics = CoreToProtocolDiagnostics(action.Diagnostics, content)
+	}
+	if action.IsPreferred {
+		result.IsPreferred = &action.IsPreferred
+	}
+	if action.Disabled != nil {
+		result.Disabled = &struct {
+			Reason string `json:"reason"`
+		}{Reason: action.Disabled.Reason}
+	}
+	if action.Edit != nil {
+		edit := CoreToProtocolWorkspaceEdit(*action.Edit, contentFor)
+		result.Edit = &edit
+	}
+	if action.Command != nil {
+		result.Command = coreToProtocolCommand(*action.Command)
+	}
+	return result
+}
+
+// protocolToCoreCodeAction converts a protocol code action to core, but
+// its edit: codeAction/resolve is sent for actions whose edit is not
+// computed yet.
+func protocolToCoreCodeAction(action protocol.CodeAction, content string) core.CodeAction {
+	result := core.CodeAction{
+		Title:       action.Title,
+		Kind:        (*core.CodeActionKind)(action.Kind),
+		Diagnostics: ProtocolToCoreDiagnostics(action.Diagnostics, content),
+		IsPreferred: action.IsPreferred != nil && *action.IsPreferred,
+		Data:        action.Data,
+	}
+	if action.Disabled != nil {
+		result.Disabled = &core.CodeActionDisabled{Reason: action.Disabled.Reason}
+	}
+	if action.Command != nil {
+		command := protocolToCoreCommand(*action.Command)
+		result.Command = &command
+	}
+	return result
+}
+
+// CodeActionEditResolveSupport reports whether a client with the
+// capabilities resolves the edit of code actions lazily with
+// codeAction/resolve. Other clients apply the actions as they were
+// returned by textDocument/codeAction.
+func CodeActionEditResolveSupport(capabilities *protocol.ClientCapabilities) bool {
+	if capabilities == nil || capabilities.TextDocument == nil ||
+		capabilities.TextDocument.CodeAction == nil || capabilities.TextDocument.CodeAction.ResolveSupport == nil {
+		return false
+	}
+	for _, property := range capabilities.TextDocument.CodeAction.ResolveSupport.Properties {
+		if property == "edit" {
+			return true
+		}
+	}
+	return false
+}
+
+// codeActionData wraps the Data of the actions returned by handlers set
+// with SetCodeActionHandlers, so codeAction/resolve, which carries no
+// document, knows the document of the action.
+type codeActionData struct {
+	URI  string `json:"uri"`
+	Data any    `json:"data,omitempty"`
+}
+
+// SetCodeActionHandlers sets the textDocument/codeAction and
+// codeAction/resolve handlers of handler to use provider, such as a
+// core.CodeFixRegistry. contentFor returns the content of a document by
+// URI.
+//
+// resolveEdit reports whether the client resolves the edit of actions
+// lazily, typically CodeActionEditResolveSupport of the capabilities
+// received in initialize; a nil resolveEdit means it does. If it does not,
+// actions without an edit are resolved before they are returned, so that
+// the client never executes the command of an action without applying its
+// edit first.
+func SetCodeActionHandlers(handler *protocol.Handler, provider core.CodeFixProvider, contentFor func(uri string) string, resolveEdit func() bool) {
+	resolver, resolves := provider.(core.CodeActionResolveProvider)
+
+	handler.TextDocumentCodeAction = func(context *lsp.Context, params *protocol.CodeActionParams) (any, error) {
+		uri := string(params.TextDocument.URI)
+		content := contentFor(uri)
+		ctx := core.CodeFixContext{
+			URI:         uri,
+			Content:     content,
+			Range:       ProtocolToCoreRange(params.Range, content),
+			Diagnostics: ProtocolToCoreDiagnostics(params.Context.Diagnostics, content),
+		}
+		for _, kind := range params.Context.Only {
+			ctx.Only = append(ctx.Only, core.CodeActionKind(kind))
+		}
+		if params.Context.TriggerKind != nil {
+			ctx.TriggerKind = core.CodeActionTriggerKind(*params.Context.TriggerKind)
+		}
+
+		actions := provider.ProvideCodeFixes(ctx)
+		eager := resolves && resolveEdit != nil && !resolveEdit()
+		result := make([]protocol.CodeAction, len(actions))
+		for i, action := range actions {
+			if eager && action.Edit == nil {
+				action = resolver.ResolveCodeAction(action)
+			}
+			result[i] = CoreToProtocolCodeAction(action, content, contentFor)
+			result[i].Data = codeActionData{URI: uri, Data: result[i].Data}
+		}
+		return result, nil
+	}
+
+	if !resolves {
+		return
+	}
+	handler.CodeActionResolve = func(context *lsp.Context, params *protocol.CodeAction) (*protocol.CodeAction, error) {
+		var data codeActionData
+		if raw, err := json.Marshal(params.Data); err == nil {
+			if err := json.Unmarshal(raw, &data); err != nil || data.URI == "" {
+				// Not an action of ours
+				return params, nil
+			}
+		}
+		content := contentFor(data.URI)
+
+		action := protocolToCoreCodeAction(*params, content)
+		action.Data = data.Data
+		resolved := CoreToProtocolCodeAction(resolver.ResolveCodeAction(action), content, contentFor)
+		if resolved.Edit == nil {
+			resolved.Edit = params.Edit
+		}
+		resolved.Data = codeActionData{URI: data.URI, Data: resolved.Data}
+		return &resolved, nil
+	}
+}
+
+// ApplyCodeAction applies a code action on behalf of the client of
+// context, in the order of the specification: its edit first, with a
+// workspace/applyEdit request, and then its command, with execute. The
+// command is only executed once the client applied the edit, as it
+// typically relies on it, like a command moving the cursor into the code
+// the edit inserted.
+//
+// Servers use it when they apply actions themselves, e.g. from a
+// workspace/executeCommand wrapping an action for clients that only accept
+// commands as code actions.
+func ApplyCodeAction(context *lsp.Context, action protocol.CodeAction, execute func(command protocol.Command) error) error {
+	if action.Edit != nil {
+		var response protocol.ApplyWorkspaceEditResponse
+		context.Call(string(protocol.ServerWorkspaceApplyEdit), protocol.ApplyWorkspaceEditParams{
+			Label: &action.Title,
+			Edit:  *action.Edit,
+		}, &response)
+		if !response.Applied {
+			if response.FailureReason != nil {
+				return fmt.Errorf("code action %q: edit not applied: %s", action.Title, *response.FailureReason)
+			}
+			return fmt.Errorf("code action %q: edit not applied", action.Title)
+		}
+	}
+	if action.Command != nil && execute != nil {
+		return execute(*action.Command)
+	}
+	return nil
+}
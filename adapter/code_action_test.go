@@ -0,0 +1,149 @@
+package adapter_3_16
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/core"
+	protocol "github.com/SCKelemen/lsp/protocol"
+)
+
+// insertingCodeFixProvider offers to insert a comment, computing the edit
+// when the action is resolved, and then moving the cursor with a command.
+type insertingCodeFixProvider struct{}
+
+func (insertingCodeFixProvider) ProvideCodeFixes(ctx core.CodeFixContext) []core.CodeAction {
+	return []core.CodeAction{{
+		Title:   "Insert comment",
+		Command: &core.Command{Title: "Move cursor", Command: "cursorMove", Arguments: []interface{}{"down"}},
+		Data:    "// TODO",
+	}}
+}
+
+func (insertingCodeFixProvider) ResolveCodeAction(action core.CodeAction) core.CodeAction {
+	action.Edit = &core.WorkspaceEdit{Changes: map[string][]core.TextEdit{
+		"file:///a.go": {{
+			Range: core.Range{
+				Start: core.Position{Line: 0, Character: 8},
+				End:   core.Position{Line: 0, Character: 8},
+			},
+			NewText: action.Data.(string),
+		}},
+	}}
+	return action
+}
+
+func codeActions(t *testing.T, handler *protocol.Handler) []protocol.CodeAction {
+	t.Helper()
+	params, _ := json.Marshal(protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///a.go"},
+	})
+	result, validMethod, validParams, err := handler.Handle(&lsp.Context{
+		Method: string(protocol.MethodTextDocumentCodeAction),
+		Params: params,
+	})
+	if !validMethod || !validParams || err != nil {
+		t.Fatalf("Handle failed: %v %v %v", validMethod, validParams, err)
+	}
+	actions := result.([]protocol.CodeAction)
+	if len(actions) != 1 {
+		t.Fatalf("expected one action, got %+v", actions)
+	}
+	return actions
+}
+
+func TestSetCodeActionHandlers(t *testing.T) {
+	documents := map[string]string{"file:///a.go": "/*🙂*/ p\n"}
+	contentFor := func(uri string) string { return documents[uri] }
+	registry := core.NewCodeFixRegistry()
+	registry.Register(insertingCodeFixProvider{})
+
+	handler := &protocol.Handler{}
+	handler.SetInitialized(true)
+	SetCodeActionHandlers(handler, registry, contentFor, nil)
+
+	action := codeActions(t, handler)[0]
+	if action.Edit != nil {
+		t.Fatalf("expected the edit to be resolved lazily, got %+v", action.Edit)
+	}
+
+	// Round trip the action through JSON, as the client does
+	raw, _ := json.Marshal(action)
+	result, validMethod, validParams, err := handler.Handle(&lsp.Context{
+		Method: string(protocol.MethodCodeActionResolve),
+		Params: raw,
+	})
+	if !validMethod || !validParams || err != nil {
+		t.Fatalf("Handle failed: %v %v %v", validMethod, validParams, err)
+	}
+	resolved := result.(*protocol.CodeAction)
+	if resolved.Edit == nil || len(resolved.Edit.Changes["file:///a.go"]) != 1 {
+		t.Fatalf("expected the resolved edit, got %+v", resolved.Edit)
+	}
+	// "/*🙂*/" is 8 bytes and 6 UTF-16 code units long
+	if edit := resolved.Edit.Changes["file:///a.go"][0]; edit.Range.Start.Character != 6 || edit.NewText != "// TODO" {
+		t.Errorf("expected the edit converted against the document, got %+v", edit)
+	}
+	if resolved.Command == nil || resolved.Command.Command != "cursorMove" {
+		t.Errorf("expected the follow-up command to be kept, got %+v", resolved.Command)
+	}
+
+	// Clients that do not resolve edits get them right away
+	SetCodeActionHandlers(handler, registry, contentFor, func() bool { return false })
+	action = codeActions(t, handler)[0]
+	if action.Edit == nil || action.Command == nil {
+		t.Errorf("expected both the edit and the command, got %+v", action)
+	}
+}
+
+func TestCodeActionEditResolveSupport(t *testing.T) {
+	var capabilities protocol.ClientCapabilities
+	json.Unmarshal([]byte(`{"textDocument":{"codeAction":{"resolveSupport":{"properties":["edit"]}}}}`), &capabilities)
+	if !CodeActionEditResolveSupport(&capabilities) {
+		t.Error("expected edit resolve support")
+	}
+	if CodeActionEditResolveSupport(&protocol.ClientCapabilities{}) {
+		t.Error("expected no edit resolve support without capabilities")
+	}
+}
+
+func TestApplyCodeAction(t *testing.T) {
+	edit := protocol.WorkspaceEdit{Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+		"file:///a.go": {{NewText: "// TODO"}},
+	}}
+	action := protocol.CodeAction{
+		Title:   "Insert comment",
+		Edit:    &edit,
+		Command: &protocol.Command{Title: "Move cursor", Command: "cursorMove"},
+	}
+
+	for _, applied := range []bool{true, false} {
+		var steps []string
+		context := &lsp.Context{Call: func(method string, params any, result any) error {
+			steps = append(steps, method)
+			result.(*protocol.ApplyWorkspaceEditResponse).Applied = applied
+			return nil
+		}}
+		err := ApplyCodeAction(context, action, func(command protocol.Command) error {
+			steps = append(steps, command.Command)
+			return nil
+		})
+
+		if applied {
+			if err != nil || len(steps) != 2 || steps[0] != string(protocol.ServerWorkspaceApplyEdit) || steps[1] != "cursorMove" {
+				t.Errorf("expected the edit applied before the command, got %v %v", steps, err)
+			}
+		} else if err == nil || len(steps) != 1 {
+			t.Errorf("expected no command once the edit is not applied, got %v %v", steps, err)
+		}
+	}
+
+	failing := errors.New("no such command")
+	if err := ApplyCodeAction(&lsp.Context{}, protocol.CodeAction{Command: action.Command}, func(protocol.Command) error {
+		return failing
+	}); !errors.Is(err, failing) {
+		t.Errorf("expected the error of the command, got %v", err)
+	}
+}
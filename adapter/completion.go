@@ -1,6 +1,9 @@
 package adapter_3_16
 
 import (
+	"encoding/json"
+
+	"github.com/SCKelemen/lsp"
 	"github.com/SCKelemen/lsp/core"
 	protocol "github.com/SCKelemen/lsp/protocol"
 )
@@ -21,6 +24,18 @@ func CoreToProtocolCompletionItem(item core.CompletionItem, content string) prot
 		Label: item.Label,
 	}
 
+	// Convert label details
+	if item.LabelDetails != nil {
+		details := &protocol.CompletionItemLabelDetails{}
+		if item.LabelDetails.Detail != "" {
+			details.Detail = &item.LabelDetails.Detail
+		}
+		if item.LabelDetails.Description != "" {
+			details.Description = &item.LabelDetails.Description
+		}
+		result.LabelDetails = details
+	}
+
 	// Convert kind
 	if item.Kind != nil {
 		kind := CoreToProtocolCompletionItemKind(*item.Kind)
@@ -47,6 +62,11 @@ func CoreToProtocolCompletionItem(item core.CompletionItem, content string) prot
 
 	if item.Deprecated {
 		result.Deprecated = &item.Deprecated
+
+		// Clients supporting tags ignore the deprecated property
+		if !hasCompletionItemTag(result.Tags, protocol.CompletionItemTagDeprecated) {
+			result.Tags = append(result.Tags, protocol.CompletionItemTagDeprecated)
+		}
 	}
 
 	if item.Preselect {
@@ -71,6 +91,12 @@ func CoreToProtocolCompletionItem(item core.CompletionItem, content string) prot
 		result.InsertTextFormat = &format
 	}
 
+	// Convert insert text mode
+	if item.InsertTextMode != nil {
+		mode := protocol.InsertTextMode(*item.InsertTextMode)
+		result.InsertTextMode = &mode
+	}
+
 	// Convert text edit
 	if item.TextEdit != nil {
 		protocolEdit := CoreToProtocolTextEdit(*item.TextEdit, content)
@@ -104,6 +130,18 @@ func ProtocolToCoreCompletionItem(item protocol.CompletionItem, content string)
 		Label: item.Label,
 	}
 
+	// Convert label details
+	if item.LabelDetails != nil {
+		details := &core.CompletionItemLabelDetails{}
+		if item.LabelDetails.Detail != nil {
+			details.Detail = *item.LabelDetails.Detail
+		}
+		if item.LabelDetails.Description != nil {
+			details.Description = *item.LabelDetails.Description
+		}
+		result.LabelDetails = details
+	}
+
 	// Convert kind
 	if item.Kind != nil {
 		kind := ProtocolToCoreCompletionItemKind(*item.Kind)
@@ -157,12 +195,21 @@ func ProtocolToCoreCompletionItem(item protocol.CompletionItem, content string)
 		result.InsertTextFormat = &format
 	}
 
+	// Convert insert text mode
+	if item.InsertTextMode != nil {
+		mode := core.InsertTextMode(*item.InsertTextMode)
+		result.InsertTextMode = &mode
+	}
+
 	// Convert text edit
 	if item.TextEdit != nil {
 		switch edit := item.TextEdit.(type) {
 		case protocol.TextEdit:
 			coreEdit := ProtocolToCoreTextEdit(edit, content)
 			result.TextEdit = &coreEdit
+		case *protocol.TextEdit:
+			coreEdit := ProtocolToCoreTextEdit(*edit, content)
+			result.TextEdit = &coreEdit
 		}
 	}
 
@@ -342,3 +389,86 @@ func ProtocolToCoreInlineCompletionList(list *protocol.InlineCompletionList, con
 
 	return result
 }
+
+// completionItemData wraps the Data of the items returned by handlers set
+// with SetCompletionHandlers, so completionItem/resolve, which carries no
+// document, knows the document of the item.
+type completionItemData struct {
+	URI  string `json:"uri"`
+	Data any    `json:"data,omitempty"`
+}
+
+// SetCompletionHandlers sets the textDocument/completion and
+// completionItem/resolve handlers of handler to use provider, with the
+// content and version of the open documents in documents.
+//
+// Edits of items, including the AdditionalTextEdits inserting an import
+// when an item is accepted, are converted against the content of the
+// document completion was requested in, also when the provider computes
+// them lazily in ResolveCompletionItem.
+func SetCompletionHandlers(handler *protocol.Handler, provider core.CompletionProvider, documents *core.DocumentManager) {
+	documentFor := func(uri string) (string, int) {
+		if doc, ok := documents.Get(uri); ok {
+			return doc.GetContentAndVersion()
+		}
+		return "", 0
+	}
+
+	handler.TextDocumentCompletion = func(context *lsp.Context, params *protocol.CompletionParams) (any, error) {
+		uri := string(params.TextDocument.URI)
+		content, version := documentFor(uri)
+		ctx := core.CompletionContext{
+			URI:         uri,
+			Content:     content,
+			Version:     version,
+			Position:    ProtocolToCorePosition(params.Position, content),
+			TriggerKind: core.CompletionTriggerKindInvoked,
+		}
+		if params.Context != nil {
+			ctx.TriggerKind = core.CompletionTriggerKind(params.Context.TriggerKind)
+			if params.Context.TriggerCharacter != nil {
+				ctx.TriggerCharacter = *params.Context.TriggerCharacter
+			}
+		}
+
+		list := provider.ProvideCompletions(ctx)
+		if list == nil {
+			return nil, nil
+		}
+		result := CoreToProtocolCompletionList(list, content)
+		for i := range result.Items {
+			result.Items[i].Data = completionItemData{URI: uri, Data: result.Items[i].Data}
+		}
+		return result, nil
+	}
+
+	resolver, ok := provider.(core.CompletionItemResolveProvider)
+	if !ok {
+		return
+	}
+	handler.CompletionItemResolve = func(context *lsp.Context, params *protocol.CompletionItem) (*protocol.CompletionItem, error) {
+		var data completionItemData
+		if raw, err := json.Marshal(params.Data); err == nil {
+			if err := json.Unmarshal(raw, &data); err != nil || data.URI == "" {
+				// Not an item of ours
+				return params, nil
+			}
+		}
+		content, _ := documentFor(data.URI)
+
+		item := ProtocolToCoreCompletionItem(*params, content)
+		item.Data = data.Data
+		resolved := CoreToProtocolCompletionItem(resolver.ResolveCompletionItem(item), content)
+		resolved.Data = completionItemData{URI: data.URI, Data: resolved.Data}
+		return &resolved, nil
+	}
+}
+
+func hasCompletionItemTag(tags []protocol.CompletionItemTag, tag protocol.CompletionItemTag) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
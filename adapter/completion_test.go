@@ -0,0 +1,174 @@
+package adapter_3_16
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/core"
+	protocol "github.com/SCKelemen/lsp/protocol"
+)
+
+// importingCompletionProvider completes "Builder" and adds the import of
+// strings when the item is resolved. It records the context of the last
+// request.
+type importingCompletionProvider struct {
+	last *core.CompletionContext
+}
+
+func (p importingCompletionProvider) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
+	*p.last = ctx
+	return &core.CompletionList{Items: []core.CompletionItem{{Label: "Builder", Data: "strings"}}}
+}
+
+func (importingCompletionProvider) ResolveCompletionItem(item core.CompletionItem) core.CompletionItem {
+	item.AdditionalTextEdits = []core.TextEdit{{
+		Range: core.Range{
+			Start: core.Position{Line: 0, Character: 8},
+			End:   core.Position{Line: 0, Character: 8},
+		},
+		NewText: "; import \"" + item.Data.(string) + "\"",
+	}}
+	return item
+}
+
+func TestSetCompletionHandlers(t *testing.T) {
+	documents := core.NewDocumentManager()
+	documents.Open("file:///a.go", "/*🙂*/ p\n", 3)
+	provider := importingCompletionProvider{last: &core.CompletionContext{}}
+	handler := &protocol.Handler{}
+	handler.SetInitialized(true)
+	SetCompletionHandlers(handler, provider, documents)
+
+	params, _ := json.Marshal(protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: "file:///a.go"},
+			Position:     protocol.Position{Line: 0, Character: 7},
+		},
+	})
+	result, validMethod, validParams, err := handler.Handle(&lsp.Context{
+		Method: string(protocol.MethodTextDocumentCompletion),
+		Params: params,
+	})
+	if !validMethod || !validParams || err != nil {
+		t.Fatalf("Handle failed: %v %v %v", validMethod, validParams, err)
+	}
+	if provider.last.Version != 3 || provider.last.Content != "/*🙂*/ p\n" {
+		t.Errorf("context = %+v, want the document at version 3", *provider.last)
+	}
+	list := result.(*protocol.CompletionList)
+	if len(list.Items) != 1 {
+		t.Fatalf("expected one item, got %+v", list.Items)
+	}
+
+	// Round trip the item through JSON, as the client does
+	item, _ := json.Marshal(list.Items[0])
+	result, validMethod, validParams, err = handler.Handle(&lsp.Context{
+		Method: string(protocol.MethodCompletionItemResolve),
+		Params: item,
+	})
+	if !validMethod || !validParams || err != nil {
+		t.Fatalf("Handle failed: %v %v %v", validMethod, validParams, err)
+	}
+	resolved := result.(*protocol.CompletionItem)
+	if len(resolved.AdditionalTextEdits) != 1 {
+		t.Fatalf("expected the import edit, got %+v", resolved.AdditionalTextEdits)
+	}
+	// "/*🙂*/" is 8 bytes and 6 UTF-16 code units long
+	if start := resolved.AdditionalTextEdits[0].Range.Start; start.Character != 6 {
+		t.Errorf("expected the edit converted against the document, got %+v", start)
+	}
+	if resolved.AdditionalTextEdits[0].NewText != "; import \"strings\"" {
+		t.Errorf("expected the provider's data to be restored, got %q", resolved.AdditionalTextEdits[0].NewText)
+	}
+}
+
+func TestProtocolToCoreCompletionItemTextEditPointer(t *testing.T) {
+	content := "🙂x"
+	item := CoreToProtocolCompletionItem(core.CompletionItem{
+		Label: "x",
+		TextEdit: &core.TextEdit{
+			Range:   core.Range{Start: core.Position{Character: 4}, End: core.Position{Character: 5}},
+			NewText: "y",
+		},
+	}, content)
+
+	back := ProtocolToCoreCompletionItem(item, content)
+	if back.TextEdit == nil || back.TextEdit.Range.Start.Character != 4 {
+		t.Errorf("expected the text edit to survive the round trip, got %+v", back.TextEdit)
+	}
+}
+
+func TestCompletionItemInsertTextModeRoundTrip(t *testing.T) {
+	mode := core.InsertTextModeAdjustIndentation
+	item := CoreToProtocolCompletionItem(core.CompletionItem{
+		Label:          "if",
+		InsertText:     "if ${1:cond} {\n\t$0\n}",
+		InsertTextMode: &mode,
+	}, "")
+	if item.InsertTextMode == nil || *item.InsertTextMode != protocol.InsertTextModeAdjustIndentation {
+		t.Fatalf("expected adjustIndentation, got %v", item.InsertTextMode)
+	}
+
+	back := ProtocolToCoreCompletionItem(item, "")
+	if back.InsertTextMode == nil || *back.InsertTextMode != mode {
+		t.Errorf("expected the insert text mode to survive the round trip, got %v", back.InsertTextMode)
+	}
+
+	if plain := CoreToProtocolCompletionItem(core.CompletionItem{Label: "x"}, ""); plain.InsertTextMode != nil {
+		t.Errorf("expected no insert text mode by default, got %v", *plain.InsertTextMode)
+	}
+}
+
+func TestCompletionItemLabelDetailsRoundTrip(t *testing.T) {
+	item := CoreToProtocolCompletionItem(core.CompletionItem{
+		Label:        "Join",
+		LabelDetails: &core.CompletionItemLabelDetails{Detail: "(elem ...string) string", Description: "path/filepath"},
+	}, "")
+	data, err := json.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"labelDetails":{"detail":"(elem ...string) string","description":"path/filepath"}`; !strings.Contains(string(data), want) {
+		t.Errorf("item = %s, want %s", data, want)
+	}
+
+	var decoded protocol.CompletionItem
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	back := ProtocolToCoreCompletionItem(decoded, "")
+	if back.LabelDetails == nil || back.LabelDetails.Description != "path/filepath" || back.LabelDetails.Detail != "(elem ...string) string" {
+		t.Errorf("expected the label details to survive the round trip, got %+v", back.LabelDetails)
+	}
+
+	// Empty parts are left out
+	data, _ = json.Marshal(CoreToProtocolCompletionItem(core.CompletionItem{
+		Label:        "os",
+		LabelDetails: &core.CompletionItemLabelDetails{Description: "os"},
+	}, ""))
+	if !strings.Contains(string(data), `"labelDetails":{"description":"os"}`) {
+		t.Errorf("item = %s, want only a description", data)
+	}
+}
+
+func TestCompletionItemDeprecatedTag(t *testing.T) {
+	item := CoreToProtocolCompletionItem(core.CompletionItem{Label: "Title", Deprecated: true}, "")
+	if item.Deprecated == nil || !*item.Deprecated {
+		t.Error("expected the deprecated property")
+	}
+	if len(item.Tags) != 1 || item.Tags[0] != protocol.CompletionItemTagDeprecated {
+		t.Errorf("tags = %v, want the deprecated tag", item.Tags)
+	}
+
+	// A tag set by the provider is not repeated
+	item = CoreToProtocolCompletionItem(core.CompletionItem{
+		Label:      "Title",
+		Deprecated: true,
+		Tags:       []core.CompletionItemTag{core.CompletionItemTagDeprecated},
+	}, "")
+	if len(item.Tags) != 1 {
+		t.Errorf("tags = %v, want one deprecated tag", item.Tags)
+	}
+}
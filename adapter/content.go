@@ -0,0 +1,33 @@
+package adapter_3_16
+
+import (
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/core"
+	protocol "github.com/SCKelemen/lsp/protocol"
+)
+
+// CoreToProtocolTextDocumentContentOptions returns the textDocumentContent
+// server capability for the providers in registry, announcing their
+// schemes. Returns nil if no provider is registered.
+func CoreToProtocolTextDocumentContentOptions(registry *core.TextDocumentContentRegistry) *protocol.TextDocumentContentOptions {
+	schemes := registry.Schemes()
+	if len(schemes) == 0 {
+		return nil
+	}
+	return &protocol.TextDocumentContentOptions{Schemes: schemes}
+}
+
+// SetTextDocumentContentHandler routes the workspace/textDocumentContent
+// requests of handler to registry.
+//
+// The capabilities created by handler.CreateServerCapabilities announce no
+// schemes; replace them with CoreToProtocolTextDocumentContentOptions.
+func SetTextDocumentContentHandler(handler *protocol.Handler, registry *core.TextDocumentContentRegistry) {
+	handler.WorkspaceTextDocumentContent = func(context *lsp.Context, params *protocol.TextDocumentContentParams) (*protocol.TextDocumentContentResult, error) {
+		text, err := registry.ProvideTextDocumentContent(params.URI)
+		if err != nil {
+			return nil, err
+		}
+		return &protocol.TextDocumentContentResult{Text: text}, nil
+	}
+}
@@ -0,0 +1,60 @@
+package adapter_3_16
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/core"
+	protocol "github.com/SCKelemen/lsp/protocol"
+)
+
+type staticContent string
+
+func (c staticContent) ProvideTextDocumentContent(uri string) (string, error) {
+	return string(c) + " " + uri, nil
+}
+
+func TestSetTextDocumentContentHandler(t *testing.T) {
+	registry := core.NewTextDocumentContentRegistry()
+	registry.Register("gostub", staticContent("stub"))
+	registry.Register("preview", staticContent("preview"))
+
+	handler := &protocol.Handler{}
+	handler.SetInitialized(true)
+	SetTextDocumentContentHandler(handler, registry)
+
+	params, _ := json.Marshal(protocol.TextDocumentContentParams{URI: "gostub:///a.go"})
+	result, validMethod, validParams, err := handler.Handle(&lsp.Context{
+		Method: string(protocol.MethodWorkspaceTextDocumentContent),
+		Params: params,
+	})
+	if !validMethod || !validParams || err != nil {
+		t.Fatalf("Handle failed: %v %v %v", validMethod, validParams, err)
+	}
+	if text := result.(*protocol.TextDocumentContentResult).Text; text != "stub gostub:///a.go" {
+		t.Errorf("text = %q", text)
+	}
+
+	capabilities := handler.CreateServerCapabilities()
+	capabilities.Workspace.TextDocumentContent = CoreToProtocolTextDocumentContentOptions(registry)
+	encoded, _ := json.Marshal(capabilities)
+	var decoded struct {
+		Workspace struct {
+			TextDocumentContent struct {
+				Schemes []string `json:"schemes"`
+			} `json:"textDocumentContent"`
+		} `json:"workspace"`
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if schemes := decoded.Workspace.TextDocumentContent.Schemes; !reflect.DeepEqual(schemes, []string{"gostub", "preview"}) {
+		t.Errorf("advertised schemes = %v, want [gostub preview]", schemes)
+	}
+
+	if CoreToProtocolTextDocumentContentOptions(core.NewTextDocumentContentRegistry()) != nil {
+		t.Error("expected nil options for an empty registry")
+	}
+}
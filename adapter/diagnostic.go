@@ -3,6 +3,7 @@ package adapter_3_16
 import (
 	"github.com/SCKelemen/lsp/core"
 	protocol "github.com/SCKelemen/lsp/protocol"
+	uripkg "github.com/SCKelemen/lsp/uri"
 )
 
 // ContentResolver returns document content for a URI when converting ranges.
@@ -39,6 +40,26 @@ func CoreToProtocolDiagnostic(diag core.Diagnostic, content string) protocol.Dia
 // The content parameter is used for the main diagnostic range.
 // If resolver is provided, it is used to map related information ranges in their own files.
 func CoreToProtocolDiagnosticWithResolver(diag core.Diagnostic, content string, resolver ContentResolver) protocol.Diagnostic {
+	return coreToProtocolDiagnostic(diag, "", content, resolver)
+}
+
+// CoreToProtocolDocumentDiagnostics converts the diagnostics of the document
+// at uri, with the given content. Related information in that document is
+// converted against content, and in other documents against the content
+// resolver returns for them. Related information in other documents
+// resolver does not know, or with a nil resolver, keeps its character
+// offsets, which is only exact for ASCII lines.
+func CoreToProtocolDocumentDiagnostics(uri string, diags []core.Diagnostic, content string, resolver ContentResolver) []protocol.Diagnostic {
+	result := make([]protocol.Diagnostic, len(diags))
+	for i, diag := range diags {
+		result[i] = coreToProtocolDiagnostic(diag, uri, content, resolver)
+	}
+	return result
+}
+
+// coreToProtocolDiagnostic converts a diagnostic of the document at uri,
+// or of an unknown document if uri is empty.
+func coreToProtocolDiagnostic(diag core.Diagnostic, uri, content string, resolver ContentResolver) protocol.Diagnostic {
 	result := protocol.Diagnostic{
 		Range:   CoreToProtocolRange(diag.Range, content),
 		Message: diag.Message,
@@ -87,15 +108,15 @@ func CoreToProtocolDiagnosticWithResolver(diag core.Diagnostic, content string,
 	if len(diag.RelatedInformation) > 0 {
 		relatedInfo := make([]protocol.DiagnosticRelatedInformation, len(diag.RelatedInformation))
 		for i, info := range diag.RelatedInformation {
-			relatedContent := content
-			if resolver != nil {
-				if resolvedContent, ok := resolver(info.Location.URI); ok {
-					relatedContent = resolvedContent
-				}
+			location := protocol.Location{URI: protocol.DocumentUri(info.Location.URI)}
+			if relatedContent, ok := relatedDocumentContent(info.Location.URI, uri, content, resolver); ok {
+				location.Range = CoreToProtocolRange(info.Location.Range, relatedContent)
+			} else {
+				location.Range = unconvertedRange(info.Location.Range)
 			}
 
 			relatedInfo[i] = protocol.DiagnosticRelatedInformation{
-				Location: CoreToProtocolLocation(info.Location, relatedContent),
+				Location: location,
 				Message:  info.Message,
 			}
 		}
@@ -212,3 +233,28 @@ func ProtocolToCoreDiagnosticsWithResolver(diags []protocol.Diagnostic, content
 	}
 	return result
 }
+
+// relatedDocumentContent returns the content of the document at
+// relatedURI, referenced by a diagnostic of the document at uri with the
+// given content. Without a uri, a document resolver does not know is taken
+// to be the diagnostic's own.
+func relatedDocumentContent(relatedURI, uri, content string, resolver ContentResolver) (string, bool) {
+	if uri != "" && uripkg.Normalize(relatedURI) == uripkg.Normalize(uri) {
+		return content, true
+	}
+	if resolver != nil {
+		if resolvedContent, ok := resolver(relatedURI); ok {
+			return resolvedContent, true
+		}
+	}
+	return content, uri == ""
+}
+
+// unconvertedRange returns a range of a document whose content is unknown
+// with its UTF-8 character offsets as UTF-16 offsets.
+func unconvertedRange(r core.Range) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: protocol.UInteger(r.Start.Line), Character: protocol.UInteger(r.Start.Character)},
+		End:   protocol.Position{Line: protocol.UInteger(r.End.Line), Character: protocol.UInteger(r.End.Character)},
+	}
+}
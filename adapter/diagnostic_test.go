@@ -64,6 +64,49 @@ func TestCoreToProtocolDiagnosticWithResolverRelatedInfo(t *testing.T) {
 	}
 }
 
+func TestCoreToProtocolDocumentDiagnostics(t *testing.T) {
+	mainURI := "file:///main.go"
+	mainContent := "var é = 1\nvar é = 2\n"
+	otherURI := "file:///other.go"
+	otherContent := "// 😀\nvar y = 1\n"
+
+	redeclared := core.Diagnostic{
+		Range:   core.Range{Start: core.Position{Line: 1, Character: 4}, End: core.Position{Line: 1, Character: 6}},
+		Message: "é redeclared in this block",
+	}
+	redeclared.AddRelated(mainURI, core.Range{Start: core.Position{Line: 0, Character: 4}, End: core.Position{Line: 0, Character: 6}}, "other declaration of é")
+	redeclared.AddRelated(otherURI, core.Range{Start: core.Position{Line: 0, Character: 3}, End: core.Position{Line: 0, Character: 7}}, "in another file")
+	redeclared.AddRelated("file:///unknown.go", core.Range{Start: core.Position{Line: 3, Character: 5}, End: core.Position{Line: 3, Character: 6}}, "unknown")
+
+	resolver := func(uri string) (string, bool) {
+		if uri == otherURI {
+			return otherContent, true
+		}
+		return "", false
+	}
+	got := CoreToProtocolDocumentDiagnostics(mainURI, []core.Diagnostic{redeclared}, mainContent, resolver)
+
+	related := got[0].RelatedInformation
+	if len(related) != 3 {
+		t.Fatalf("expected 3 related info entries, got %d", len(related))
+	}
+	tests := []struct {
+		name       string
+		line       protocol.UInteger
+		start, end protocol.UInteger
+	}{
+		{"same document", 0, 4, 5},
+		{"resolved document", 0, 3, 5},
+		{"unknown document", 3, 5, 6},
+	}
+	for i, tt := range tests {
+		r := related[i].Location.Range
+		if r.Start.Line != tt.line || r.Start.Character != tt.start || r.End.Character != tt.end {
+			t.Errorf("%s: got %+v, want line %d characters [%d,%d]", tt.name, r, tt.line, tt.start, tt.end)
+		}
+	}
+}
+
 func TestProtocolToCoreDiagnosticWithResolverRelatedInfo(t *testing.T) {
 	mainContent := "abcdef"
 	otherURI := "file:///other.txt"
@@ -0,0 +1,148 @@
+package adapter_3_16
+
+import (
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/core"
+	protocol "github.com/SCKelemen/lsp/protocol"
+)
+
+// ProtocolToCoreFileCreates converts the files of a create files request.
+func ProtocolToCoreFileCreates(params *protocol.CreateFilesParams) []core.FileCreate {
+	result := make([]core.FileCreate, len(params.Files))
+	for i, file := range params.Files {
+		result[i] = core.FileCreate{URI: file.URI}
+	}
+	return result
+}
+
+// ProtocolToCoreFileRenames converts the files of a rename files request.
+func ProtocolToCoreFileRenames(params *protocol.RenameFilesParams) []core.FileRename {
+	result := make([]core.FileRename, len(params.Files))
+	for i, file := range params.Files {
+		result[i] = core.FileRename{OldURI: file.OldURI, NewURI: file.NewURI}
+	}
+	return result
+}
+
+// ProtocolToCoreFileDeletes converts the files of a delete files request.
+func ProtocolToCoreFileDeletes(params *protocol.DeleteFilesParams) []core.FileDelete {
+	result := make([]core.FileDelete, len(params.Files))
+	for i, file := range params.Files {
+		result[i] = core.FileDelete{URI: file.URI}
+	}
+	return result
+}
+
+// ProtocolToCoreFileEvents converts the changes of a did change watched
+// files notification.
+func ProtocolToCoreFileEvents(params *protocol.DidChangeWatchedFilesParams) []core.FileEvent {
+	result := make([]core.FileEvent, len(params.Changes))
+	for i, change := range params.Changes {
+		result[i] = core.FileEvent{URI: change.URI, Type: core.FileChangeType(change.Type)}
+	}
+	return result
+}
+
+// CoreToProtocolFileOperationFilter converts a core file operation filter to
+// protocol.
+func CoreToProtocolFileOperationFilter(filter core.FileOperationFilter) protocol.FileOperationFilter {
+	glob := filter.Glob
+	if glob == "" {
+		glob = "**/*"
+	}
+	result := protocol.FileOperationFilter{
+		Scheme:  optionalString(filter.Scheme),
+		Pattern: protocol.FileOperationPattern{Glob: glob},
+	}
+	if filter.Matches != "" {
+		matches := protocol.FileOperationPatternKind(filter.Matches)
+		result.Pattern.Matches = &matches
+	}
+	if filter.IgnoreCase {
+		result.Pattern.Options = &protocol.FileOperationPatternOptions{IgnoreCase: &protocol.True}
+	}
+	return result
+}
+
+// CoreToProtocolFileOperationCapabilities returns the file operation server
+// capabilities for the handlers in registry, announcing their filters.
+// Returns nil if the registry handles no file operation.
+func CoreToProtocolFileOperationCapabilities(registry *core.FileOperationRegistry) *protocol.ServerCapabilitiesWorkspaceFileOperations {
+	options := func(op core.FileOperation) *protocol.FileOperationRegistrationOptions {
+		if !registry.Handles(op) {
+			return nil
+		}
+		filters := registry.Filters(op)
+		result := &protocol.FileOperationRegistrationOptions{
+			Filters: make([]protocol.FileOperationFilter, len(filters)),
+		}
+		for i, filter := range filters {
+			result.Filters[i] = CoreToProtocolFileOperationFilter(filter)
+		}
+		return result
+	}
+
+	capabilities := &protocol.ServerCapabilitiesWorkspaceFileOperations{
+		DidCreate:  options(core.FileOperationDidCreate),
+		WillCreate: options(core.FileOperationWillCreate),
+		DidRename:  options(core.FileOperationDidRename),
+		WillRename: options(core.FileOperationWillRename),
+		DidDelete:  options(core.FileOperationDidDelete),
+		WillDelete: options(core.FileOperationWillDelete),
+	}
+	if *capabilities == (protocol.ServerCapabilitiesWorkspaceFileOperations{}) {
+		return nil
+	}
+	return capabilities
+}
+
+// SetFileOperationHandlers routes the workspace file operation requests and
+// notifications of handler to registry, for each operation the registry
+// handles. Workspace edits are converted with the content returned by
+// contentFor, as in CoreToProtocolWorkspaceEdit.
+//
+// The capabilities created by handler.CreateServerCapabilities announce
+// empty filters; replace them with CoreToProtocolFileOperationCapabilities.
+func SetFileOperationHandlers(handler *protocol.Handler, registry *core.FileOperationRegistry, contentFor func(uri string) string) {
+	convert := func(edit *core.WorkspaceEdit) *protocol.WorkspaceEdit {
+		if edit == nil {
+			return nil
+		}
+		result := CoreToProtocolWorkspaceEdit(*edit, contentFor)
+		return &result
+	}
+
+	if registry.Handles(core.FileOperationWillCreate) {
+		handler.WorkspaceWillCreateFiles = func(context *lsp.Context, params *protocol.CreateFilesParams) (*protocol.WorkspaceEdit, error) {
+			return convert(registry.WillCreateFiles(ProtocolToCoreFileCreates(params))), nil
+		}
+	}
+	if registry.Handles(core.FileOperationDidCreate) {
+		handler.WorkspaceDidCreateFiles = func(context *lsp.Context, params *protocol.CreateFilesParams) error {
+			registry.DidCreateFiles(ProtocolToCoreFileCreates(params))
+			return nil
+		}
+	}
+	if registry.Handles(core.FileOperationWillRename) {
+		handler.WorkspaceWillRenameFiles = func(context *lsp.Context, params *protocol.RenameFilesParams) (*protocol.WorkspaceEdit, error) {
+			return convert(registry.WillRenameFiles(ProtocolToCoreFileRenames(params))), nil
+		}
+	}
+	if registry.Handles(core.FileOperationDidRename) {
+		handler.WorkspaceDidRenameFiles = func(context *lsp.Context, params *protocol.RenameFilesParams) error {
+			registry.DidRenameFiles(ProtocolToCoreFileRenames(params))
+			return nil
+		}
+	}
+	if registry.Handles(core.FileOperationWillDelete) {
+		handler.WorkspaceWillDeleteFiles = func(context *lsp.Context, params *protocol.DeleteFilesParams) (*protocol.WorkspaceEdit, error) {
+			return convert(registry.WillDeleteFiles(ProtocolToCoreFileDeletes(params))), nil
+		}
+	}
+	if registry.Handles(core.FileOperationDidDelete) {
+		handler.WorkspaceDidDeleteFiles = func(context *lsp.Context, params *protocol.DeleteFilesParams) error {
+			registry.DidDeleteFiles(ProtocolToCoreFileDeletes(params))
+			return nil
+		}
+	}
+}
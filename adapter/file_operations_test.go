@@ -0,0 +1,73 @@
+package adapter_3_16
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/core"
+	protocol "github.com/SCKelemen/lsp/protocol"
+)
+
+type renameEditor struct{}
+
+func (renameEditor) WillRenameFiles(files []core.FileRename) *core.WorkspaceEdit {
+	return &core.WorkspaceEdit{
+		Changes: map[string][]core.TextEdit{
+			"file:///ws/main.go": {{
+				// "🙂" is 4 bytes in UTF-8 and 2 code units in UTF-16
+				Range:   core.Range{Start: core.Position{Line: 0, Character: 4}, End: core.Position{Line: 0, Character: 5}},
+				NewText: "b",
+			}},
+		},
+		DocumentChanges: []interface{}{
+			core.RenameFile{OldURI: files[0].OldURI, NewURI: files[0].NewURI, Options: &core.RenameFileOptions{Overwrite: true}},
+		},
+	}
+}
+
+func TestSetFileOperationHandlers(t *testing.T) {
+	registry := core.NewFileOperationRegistry()
+	registry.Register([]core.FileOperationFilter{{Scheme: "file", Glob: "**/*.go", IgnoreCase: true}}, renameEditor{})
+
+	handler := &protocol.Handler{}
+	handler.SetInitialized(true)
+	SetFileOperationHandlers(handler, registry, func(uri string) string { return "🙂a" })
+	if handler.WorkspaceWillRenameFiles == nil || handler.WorkspaceDidRenameFiles != nil {
+		t.Fatal("expected only the willRenameFiles handler to be set")
+	}
+
+	params, _ := json.Marshal(protocol.RenameFilesParams{
+		Files: []protocol.FileRename{{OldURI: "file:///ws/a.go", NewURI: "file:///ws/b.go"}},
+	})
+	result, validMethod, validParams, err := handler.Handle(&lsp.Context{
+		Method: string(protocol.MethodWorkspaceWillRenameFiles),
+		Params: params,
+	})
+	if !validMethod || !validParams || err != nil {
+		t.Fatalf("Handle failed: %v %v %v", validMethod, validParams, err)
+	}
+
+	edit := result.(*protocol.WorkspaceEdit)
+	edits := edit.Changes["file:///ws/main.go"]
+	if len(edits) != 1 || edits[0].Range.Start.Character != 2 {
+		t.Errorf("expected UTF-16 converted edit, got %+v", edits)
+	}
+	rename, ok := edit.DocumentChanges[0].(protocol.RenameFile)
+	if !ok || rename.Kind != "rename" || rename.Options == nil || rename.Options.Overwrite == nil {
+		t.Errorf("unexpected rename operation %+v", edit.DocumentChanges[0])
+	}
+
+	capabilities := CoreToProtocolFileOperationCapabilities(registry)
+	if capabilities == nil || capabilities.WillRename == nil || capabilities.DidRename != nil {
+		t.Fatalf("unexpected capabilities %+v", capabilities)
+	}
+	filter := capabilities.WillRename.Filters[0]
+	if *filter.Scheme != "file" || filter.Pattern.Glob != "**/*.go" || filter.Pattern.Options == nil {
+		t.Errorf("unexpected filter %+v", filter)
+	}
+
+	if CoreToProtocolFileOperationCapabilities(core.NewFileOperationRegistry()) != nil {
+		t.Error("expected nil capabilities for an empty registry")
+	}
+}
@@ -1,6 +1,7 @@
 package adapter_3_16
 
 import (
+	"github.com/SCKelemen/lsp"
 	"github.com/SCKelemen/lsp/core"
 	protocol "github.com/SCKelemen/lsp/protocol"
 )
@@ -33,6 +34,10 @@ func CoreToProtocolFoldingRange(fr core.FoldingRange, content string) protocol.F
 		result.Kind = &kind
 	}
 
+	if fr.CollapsedText != "" {
+		result.CollapsedText = &fr.CollapsedText
+	}
+
 	return result
 }
 
@@ -61,9 +66,25 @@ func ProtocolToCoreFoldingRange(fr protocol.FoldingRange, content string) core.F
 		result.Kind = &kind
 	}
 
+	if fr.CollapsedText != nil {
+		result.CollapsedText = *fr.CollapsedText
+	}
+
 	return result
 }
 
+// CollapsedTextSupport reports whether a client with the capabilities
+// shows the CollapsedText of folding ranges. Servers may skip computing it
+// for other clients, which show their own text.
+func CollapsedTextSupport(capabilities *protocol.ClientCapabilities) bool {
+	return capabilities != nil &&
+		capabilities.TextDocument != nil &&
+		capabilities.TextDocument.FoldingRange != nil &&
+		capabilities.TextDocument.FoldingRange.FoldingRange != nil &&
+		capabilities.TextDocument.FoldingRange.FoldingRange.CollapsedText != nil &&
+		*capabilities.TextDocument.FoldingRange.FoldingRange.CollapsedText
+}
+
 // CoreToProtocolFoldingRanges converts a slice of core folding ranges to protocol folding ranges.
 func CoreToProtocolFoldingRanges(ranges []core.FoldingRange, content string) []protocol.FoldingRange {
 	result := make([]protocol.FoldingRange, len(ranges))
@@ -152,6 +173,11 @@ func CoreToProtocolDocumentSymbol(sym core.DocumentSymbol, content string) proto
 	// Convert deprecated flag
 	if sym.Deprecated {
 		result.Deprecated = &sym.Deprecated
+
+		// Clients supporting tags ignore the deprecated property
+		if !hasSymbolTag(result.Tags, protocol.SymbolTagDeprecated) {
+			result.Tags = append(result.Tags, protocol.SymbolTagDeprecated)
+		}
 	}
 
 	// Convert children recursively
@@ -224,6 +250,67 @@ func ProtocolToCoreDocumentSymbols(symbols []protocol.DocumentSymbol, content st
 	return result
 }
 
+// CoreToProtocolSymbolInformation converts a core.WorkspaceSymbol to a protocol SymbolInformation.
+func CoreToProtocolSymbolInformation(sym core.WorkspaceSymbol, content string) protocol.SymbolInformation {
+	result := protocol.SymbolInformation{
+		Name:     sym.Name,
+		Kind:     CoreToProtocolSymbolKind(sym.Kind),
+		Location: CoreToProtocolLocation(sym.Location, content),
+	}
+
+	// Convert tags
+	if len(sym.Tags) > 0 {
+		tags := make([]protocol.SymbolTag, len(sym.Tags))
+		for i, tag := range sym.Tags {
+			tags[i] = protocol.SymbolTag(tag)
+		}
+		result.Tags = tags
+	}
+
+	if sym.ContainerName != "" {
+		result.ContainerName = &sym.ContainerName
+	}
+
+	return result
+}
+
+// HierarchicalDocumentSymbolSupport reports whether a client with the
+// capabilities accepts a hierarchy of DocumentSymbol in response to
+// textDocument/documentSymbol, rather than a flat list of SymbolInformation.
+func HierarchicalDocumentSymbolSupport(capabilities *protocol.ClientCapabilities) bool {
+	return capabilities != nil &&
+		capabilities.TextDocument != nil &&
+		capabilities.TextDocument.DocumentSymbol != nil &&
+		capabilities.TextDocument.DocumentSymbol.HierarchicalDocumentSymbolSupport != nil &&
+		*capabilities.TextDocument.DocumentSymbol.HierarchicalDocumentSymbolSupport
+}
+
+// SetDocumentSymbolHandler sets the textDocument/documentSymbol handler of
+// handler to use provider, such as a core.DocumentSymbolRegistry.
+//
+// hierarchical reports whether the client supports hierarchical document
+// symbols, typically HierarchicalDocumentSymbolSupport of the capabilities
+// received in initialize. If it does not, the symbols are flattened with
+// core.FlattenDocumentSymbols. A nil hierarchical means it does.
+// contentFor returns the content of an open document by URI.
+func SetDocumentSymbolHandler(handler *protocol.Handler, provider core.DocumentSymbolProvider, contentFor func(uri string) string, hierarchical func() bool) {
+	handler.TextDocumentDocumentSymbol = func(context *lsp.Context, params *protocol.DocumentSymbolParams) (any, error) {
+		uri := string(params.TextDocument.URI)
+		content := contentFor(uri)
+		symbols := provider.ProvideDocumentSymbols(uri, content)
+
+		if hierarchical == nil || hierarchical() {
+			return CoreToProtocolDocumentSymbols(symbols, content), nil
+		}
+		flat := core.FlattenDocumentSymbols(uri, symbols)
+		result := make([]protocol.SymbolInformation, len(flat))
+		for i, sym := range flat {
+			result[i] = CoreToProtocolSymbolInformation(sym, content)
+		}
+		return result, nil
+	}
+}
+
 // CoreToProtocolSelectionRange converts a core.SelectionRange to a protocol SelectionRange.
 func CoreToProtocolSelectionRange(sr core.SelectionRange, content string) protocol.SelectionRange {
 	result := protocol.SelectionRange{
@@ -383,3 +470,12 @@ func ProtocolToCoreColorPresentations(presentations []protocol.ColorPresentation
 	}
 	return result
 }
+
+func hasSymbolTag(tags []protocol.SymbolTag, tag protocol.SymbolTag) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
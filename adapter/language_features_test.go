@@ -0,0 +1,122 @@
+package adapter_3_16
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/core"
+	protocol "github.com/SCKelemen/lsp/protocol"
+)
+
+type outlineProvider struct{}
+
+func (outlineProvider) ProvideDocumentSymbols(uri, content string) []core.DocumentSymbol {
+	field := core.Range{Start: core.Position{Line: 1, Character: 1}, End: core.Position{Line: 1, Character: 6}}
+	return []core.DocumentSymbol{{
+		Name:           "Point",
+		Kind:           core.SymbolKindStruct,
+		Range:          core.Range{Start: core.Position{Line: 0}, End: core.Position{Line: 2, Character: 1}},
+		SelectionRange: core.Range{Start: core.Position{Line: 0, Character: 5}, End: core.Position{Line: 0, Character: 10}},
+		Children:       []core.DocumentSymbol{{Name: "X", Kind: core.SymbolKindField, Range: field, SelectionRange: field}},
+	}}
+}
+
+func TestHierarchicalDocumentSymbolSupport(t *testing.T) {
+	yes := true
+	tests := []struct {
+		name         string
+		capabilities *protocol.ClientCapabilities
+		expected     bool
+	}{
+		{"nil", nil, false},
+		{"no text document capabilities", &protocol.ClientCapabilities{}, false},
+		{"unset", &protocol.ClientCapabilities{TextDocument: &protocol.TextDocumentClientCapabilities{
+			DocumentSymbol: &protocol.DocumentSymbolClientCapabilities{},
+		}}, false},
+		{"supported", &protocol.ClientCapabilities{TextDocument: &protocol.TextDocumentClientCapabilities{
+			DocumentSymbol: &protocol.DocumentSymbolClientCapabilities{HierarchicalDocumentSymbolSupport: &yes},
+		}}, true},
+	}
+	for _, tt := range tests {
+		if got := HierarchicalDocumentSymbolSupport(tt.capabilities); got != tt.expected {
+			t.Errorf("%s: got %v, expected %v", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestSetDocumentSymbolHandler(t *testing.T) {
+	documents := map[string]string{"file:///point.go": "type Point struct {\n\tX int\n}\n"}
+
+	for _, hierarchical := range []bool{true, false} {
+		handler := &protocol.Handler{}
+		handler.SetInitialized(true)
+		SetDocumentSymbolHandler(handler, outlineProvider{}, func(uri string) string { return documents[uri] }, func() bool { return hierarchical })
+
+		params, _ := json.Marshal(protocol.DocumentSymbolParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: "file:///point.go"},
+		})
+		result, validMethod, validParams, err := handler.Handle(&lsp.Context{
+			Method: string(protocol.MethodTextDocumentDocumentSymbol),
+			Params: params,
+		})
+		if !validMethod || !validParams || err != nil {
+			t.Fatalf("Handle failed: %v %v %v", validMethod, validParams, err)
+		}
+
+		if hierarchical {
+			symbols, ok := result.([]protocol.DocumentSymbol)
+			if !ok || len(symbols) != 1 || len(symbols[0].Children) != 1 {
+				t.Errorf("expected one symbol with one child, got %#v", result)
+			}
+			continue
+		}
+		symbols, ok := result.([]protocol.SymbolInformation)
+		if !ok || len(symbols) != 2 {
+			t.Fatalf("expected two flat symbols, got %#v", result)
+		}
+		if symbols[1].Name != "X" || symbols[1].ContainerName == nil || *symbols[1].ContainerName != "Point" {
+			t.Errorf("expected X in Point, got %+v", symbols[1])
+		}
+		if symbols[1].Location.URI != "file:///point.go" || symbols[1].Location.Range.Start.Line != 1 {
+			t.Errorf("unexpected location %+v", symbols[1].Location)
+		}
+	}
+}
+
+func TestFoldingRangeCollapsedText(t *testing.T) {
+	kind := core.FoldingRangeKindRegion
+	fr := CoreToProtocolFoldingRange(core.FoldingRange{StartLine: 0, EndLine: 2, Kind: &kind, CollapsedText: "Utilities"}, "")
+	if fr.CollapsedText == nil || *fr.CollapsedText != "Utilities" {
+		t.Fatalf("expected collapsed text, got %+v", fr)
+	}
+	if back := ProtocolToCoreFoldingRange(fr, ""); back.CollapsedText != "Utilities" {
+		t.Errorf("expected collapsed text to round trip, got %+v", back)
+	}
+	if fr := CoreToProtocolFoldingRange(core.FoldingRange{EndLine: 2}, ""); fr.CollapsedText != nil {
+		t.Errorf("expected no collapsed text, got %q", *fr.CollapsedText)
+	}
+}
+
+func TestCollapsedTextSupport(t *testing.T) {
+	var capabilities protocol.ClientCapabilities
+	if CollapsedTextSupport(&capabilities) {
+		t.Error("expected no support without capabilities")
+	}
+	if err := json.Unmarshal([]byte(`{"textDocument":{"foldingRange":{"foldingRange":{"collapsedText":true}}}}`), &capabilities); err != nil {
+		t.Fatal(err)
+	}
+	if !CollapsedTextSupport(&capabilities) {
+		t.Error("expected support")
+	}
+}
+
+func TestCoreToProtocolDocumentSymbolDeprecatedTag(t *testing.T) {
+	symbol := CoreToProtocolDocumentSymbol(core.DocumentSymbol{Name: "Old", Kind: core.SymbolKindFunction, Deprecated: true}, "")
+	if symbol.Deprecated == nil || !*symbol.Deprecated {
+		t.Error("expected the deprecated property")
+	}
+	if len(symbol.Tags) != 1 || symbol.Tags[0] != protocol.SymbolTagDeprecated {
+		t.Errorf("tags = %v, want the deprecated tag", symbol.Tags)
+	}
+}
@@ -0,0 +1,65 @@
+package adapter_3_16
+
+import (
+	"github.com/SCKelemen/lsp/core"
+	protocol "github.com/SCKelemen/lsp/protocol"
+)
+
+// CoreToProtocolDocumentFilter converts a core document filter to protocol.
+// Empty fields are left out.
+func CoreToProtocolDocumentFilter(filter core.DocumentFilter) protocol.DocumentFilter {
+	return protocol.DocumentFilter{
+		Language: optionalString(filter.Language),
+		Scheme:   optionalString(filter.Scheme),
+		Pattern:  optionalString(filter.Pattern),
+	}
+}
+
+// ProtocolToCoreDocumentFilter converts a protocol document filter to core.
+func ProtocolToCoreDocumentFilter(filter protocol.DocumentFilter) core.DocumentFilter {
+	var result core.DocumentFilter
+	if filter.Language != nil {
+		result.Language = *filter.Language
+	}
+	if filter.Scheme != nil {
+		result.Scheme = *filter.Scheme
+	}
+	if filter.Pattern != nil {
+		result.Pattern = *filter.Pattern
+	}
+	return result
+}
+
+// CoreToProtocolDocumentSelector converts a core document selector to
+// protocol, e.g. for the registration options of a dynamic registration.
+// A nil selector converts to nil, which tells the client to use the
+// selector it provided.
+func CoreToProtocolDocumentSelector(selector core.DocumentSelector) *protocol.DocumentSelector {
+	if selector == nil {
+		return nil
+	}
+	result := make(protocol.DocumentSelector, len(selector))
+	for i, filter := range selector {
+		result[i] = CoreToProtocolDocumentFilter(filter)
+	}
+	return &result
+}
+
+// ProtocolToCoreDocumentSelector converts a protocol document selector to core.
+func ProtocolToCoreDocumentSelector(selector *protocol.DocumentSelector) core.DocumentSelector {
+	if selector == nil {
+		return nil
+	}
+	result := make(core.DocumentSelector, len(*selector))
+	for i, filter := range *selector {
+		result[i] = ProtocolToCoreDocumentFilter(filter)
+	}
+	return result
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
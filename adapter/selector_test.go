@@ -0,0 +1,32 @@
+package adapter_3_16
+
+import (
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+func TestDocumentSelectorRoundTrip(t *testing.T) {
+	selector := core.DocumentSelector{
+		{Language: "go", Scheme: "file"},
+		{Pattern: "**/*.mod"},
+	}
+
+	converted := CoreToProtocolDocumentSelector(selector)
+	if converted == nil || len(*converted) != 2 {
+		t.Fatalf("unexpected protocol selector %+v", converted)
+	}
+	first := (*converted)[0]
+	if first.Language == nil || *first.Language != "go" || first.Pattern != nil {
+		t.Errorf("expected empty fields to be left out, got %+v", first)
+	}
+
+	back := ProtocolToCoreDocumentSelector(converted)
+	if len(back) != 2 || back[0] != selector[0] || back[1] != selector[1] {
+		t.Errorf("round trip changed selector: %+v", back)
+	}
+
+	if CoreToProtocolDocumentSelector(nil) != nil || ProtocolToCoreDocumentSelector(nil) != nil {
+		t.Error("expected nil selectors to stay nil")
+	}
+}
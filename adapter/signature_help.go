@@ -0,0 +1,169 @@
+package adapter_3_16
+
+import (
+	"sync"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/core"
+	protocol "github.com/SCKelemen/lsp/protocol"
+)
+
+// CoreToProtocolSignatureHelp converts core signature help to protocol.
+func CoreToProtocolSignatureHelp(help core.SignatureHelp) protocol.SignatureHelp {
+	result := protocol.SignatureHelp{
+		Signatures:      make([]protocol.SignatureInformation, len(help.Signatures)),
+		ActiveSignature: optionalUInteger(help.ActiveSignature),
+		ActiveParameter: optionalUInteger(help.ActiveParameter),
+	}
+	for i, signature := range help.Signatures {
+		info := protocol.SignatureInformation{
+			Label:           signature.Label,
+			ActiveParameter: optionalUInteger(signature.ActiveParameter),
+		}
+		if signature.Documentation != "" {
+			info.Documentation = signature.Documentation
+		}
+		for _, parameter := range signature.Parameters {
+			param := protocol.ParameterInformation{Label: parameter.Label}
+			if parameter.Documentation != "" {
+				param.Documentation = parameter.Documentation
+			}
+			info.Parameters = append(info.Parameters, param)
+		}
+		result.Signatures[i] = info
+	}
+	return result
+}
+
+// ProtocolToCoreSignatureHelp converts protocol signature help to core.
+// Parameter labels given as UTF-16 offsets into the signature label become
+// the substring of the label they delimit.
+func ProtocolToCoreSignatureHelp(help protocol.SignatureHelp) core.SignatureHelp {
+	result := core.SignatureHelp{
+		Signatures:      make([]core.SignatureInformation, len(help.Signatures)),
+		ActiveSignature: optionalInt(help.ActiveSignature),
+		ActiveParameter: optionalInt(help.ActiveParameter),
+	}
+	for i, signature := range help.Signatures {
+		info := core.SignatureInformation{
+			Label:           signature.Label,
+			Documentation:   documentationText(signature.Documentation),
+			ActiveParameter: optionalInt(signature.ActiveParameter),
+		}
+		for _, parameter := range signature.Parameters {
+			info.Parameters = append(info.Parameters, core.ParameterInformation{
+				Label:         parameterLabel(signature.Label, parameter.Label),
+				Documentation: documentationText(parameter.Documentation),
+			})
+		}
+		result.Signatures[i] = info
+	}
+	return result
+}
+
+// parameterLabel returns the label of a parameter of a signature, which is
+// a string or UTF-16 offsets into the label of the signature.
+func parameterLabel(signature string, label any) string {
+	switch label := label.(type) {
+	case string:
+		return label
+	case []protocol.UInteger:
+		if len(label) == 2 {
+			start := core.UTF16ToUTF8Offset(signature, 0, int(label[0]))
+			end := core.UTF16ToUTF8Offset(signature, 0, int(label[1]))
+			if start <= end && end <= len(signature) {
+				return signature[start:end]
+			}
+		}
+	}
+	return ""
+}
+
+// documentationText returns the text of documentation that is a string or
+// markup content.
+func documentationText(documentation any) string {
+	switch documentation := documentation.(type) {
+	case string:
+		return documentation
+	case protocol.MarkupContent:
+		return documentation.Value
+	case *protocol.MarkupContent:
+		if documentation != nil {
+			return documentation.Value
+		}
+	}
+	return ""
+}
+
+func optionalUInteger(value *int) *protocol.UInteger {
+	if value == nil || *value < 0 {
+		return nil
+	}
+	result := protocol.UInteger(*value)
+	return &result
+}
+
+func optionalInt(value *protocol.UInteger) *int {
+	if value == nil {
+		return nil
+	}
+	result := int(*value)
+	return &result
+}
+
+// SetSignatureHelpHandler sets the textDocument/signatureHelp handler of
+// handler to use provider. contentFor returns the content of an open
+// document by URI.
+//
+// Retriggers are tracked per document: clients that do not send the
+// signature help showing when they retrigger get the one last returned
+// for the document as ActiveSignatureHelp, so providers can keep the
+// signature the user selected with core.PreserveActiveSignature.
+func SetSignatureHelpHandler(handler *protocol.Handler, provider core.SignatureHelpProvider, contentFor func(uri string) string) {
+	var lock sync.Mutex
+	showing := make(map[string]*core.SignatureHelp)
+
+	handler.TextDocumentSignatureHelp = func(context *lsp.Context, params *protocol.SignatureHelpParams) (*protocol.SignatureHelp, error) {
+		uri := string(params.TextDocument.URI)
+		content := contentFor(uri)
+		ctx := core.SignatureHelpContext{
+			URI:         uri,
+			Content:     content,
+			Position:    ProtocolToCorePosition(params.Position, content),
+			TriggerKind: core.SignatureHelpTriggerKindInvoked,
+		}
+		if params.Context != nil {
+			ctx.TriggerKind = core.SignatureHelpTriggerKind(params.Context.TriggerKind)
+			if params.Context.TriggerCharacter != nil {
+				ctx.TriggerCharacter = *params.Context.TriggerCharacter
+			}
+			ctx.IsRetrigger = params.Context.IsRetrigger
+			if params.Context.ActiveSignatureHelp != nil {
+				active := ProtocolToCoreSignatureHelp(*params.Context.ActiveSignatureHelp)
+				ctx.ActiveSignatureHelp = &active
+			}
+		}
+
+		lock.Lock()
+		if ctx.IsRetrigger && ctx.ActiveSignatureHelp == nil {
+			ctx.ActiveSignatureHelp = showing[uri]
+		}
+		lock.Unlock()
+
+		help := provider.ProvideSignatureHelp(ctx)
+
+		lock.Lock()
+		if help == nil || len(help.Signatures) == 0 {
+			delete(showing, uri)
+		} else {
+			showing[uri] = help
+		}
+		lock.Unlock()
+
+		if help == nil {
+			return nil, nil
+		}
+		result := CoreToProtocolSignatureHelp(*help)
+		return &result, nil
+	}
+}
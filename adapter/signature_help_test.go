@@ -0,0 +1,108 @@
+package adapter_3_16
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/core"
+	protocol "github.com/SCKelemen/lsp/protocol"
+)
+
+// overloadedSignatureHelpProvider offers the two signatures of Println,
+// keeping the one the user selected when retriggered.
+type overloadedSignatureHelpProvider struct {
+	contexts []core.SignatureHelpContext
+}
+
+func (p *overloadedSignatureHelpProvider) ProvideSignatureHelp(ctx core.SignatureHelpContext) *core.SignatureHelp {
+	p.contexts = append(p.contexts, ctx)
+	active, parameter := 0, 0
+	help := &core.SignatureHelp{
+		Signatures: []core.SignatureInformation{
+			{Label: "Println(a ...any)", Parameters: []core.ParameterInformation{{Label: "a ...any"}}},
+			{Label: "Println(w io.Writer, a ...any)", Parameters: []core.ParameterInformation{{Label: "w io.Writer"}, {Label: "a ...any"}}},
+		},
+		ActiveSignature: &active,
+		ActiveParameter: &parameter,
+	}
+	core.PreserveActiveSignature(ctx, help)
+	return help
+}
+
+func signatureHelp(t *testing.T, handler *protocol.Handler, context *protocol.SignatureHelpContext) *protocol.SignatureHelp {
+	t.Helper()
+	params, _ := json.Marshal(protocol.SignatureHelpParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: "file:///a.go"},
+			Position:     protocol.Position{Line: 0, Character: 8},
+		},
+		Context: context,
+	})
+	result, validMethod, validParams, err := handler.Handle(&lsp.Context{
+		Method: string(protocol.MethodTextDocumentSignatureHelp),
+		Params: params,
+	})
+	if !validMethod || !validParams || err != nil {
+		t.Fatalf("Handle failed: %v %v %v", validMethod, validParams, err)
+	}
+	return result.(*protocol.SignatureHelp)
+}
+
+func TestSetSignatureHelpHandler(t *testing.T) {
+	provider := &overloadedSignatureHelpProvider{}
+	handler := &protocol.Handler{}
+	handler.SetInitialized(true)
+	SetSignatureHelpHandler(handler, provider, func(uri string) string { return "Println(" })
+
+	help := signatureHelp(t, handler, &protocol.SignatureHelpContext{
+		TriggerKind:      protocol.SignatureHelpTriggerKindTriggerCharacter,
+		TriggerCharacter: &[]string{"("}[0],
+	})
+	if len(help.Signatures) != 2 || *help.ActiveSignature != 0 {
+		t.Fatalf("expected two signatures, the first active, got %+v", help)
+	}
+	if ctx := provider.contexts[0]; ctx.TriggerKind != core.SignatureHelpTriggerKindTriggerCharacter || ctx.TriggerCharacter != "(" || ctx.IsRetrigger {
+		t.Errorf("unexpected context %+v", ctx)
+	}
+
+	// The user selects the second signature and types on
+	selected := protocol.UInteger(1)
+	help.ActiveSignature = &selected
+	help = signatureHelp(t, handler, &protocol.SignatureHelpContext{
+		TriggerKind:         protocol.SignatureHelpTriggerKindContentChange,
+		IsRetrigger:         true,
+		ActiveSignatureHelp: help,
+	})
+	if *help.ActiveSignature != 1 {
+		t.Errorf("expected the selected signature to stay active, got %d", *help.ActiveSignature)
+	}
+	if active := provider.contexts[1].ActiveSignatureHelp; active == nil || len(active.Signatures[1].Parameters) != 2 {
+		t.Errorf("expected the active signature help to be passed on, got %+v", active)
+	}
+
+	// A client not sending the active signature help gets the last one
+	signatureHelp(t, handler, &protocol.SignatureHelpContext{
+		TriggerKind: protocol.SignatureHelpTriggerKindContentChange,
+		IsRetrigger: true,
+	})
+	if active := provider.contexts[2].ActiveSignatureHelp; active == nil || *active.ActiveSignature != 1 {
+		t.Errorf("expected the last signature help returned, got %+v", active)
+	}
+}
+
+func TestProtocolToCoreSignatureHelpParameterOffsets(t *testing.T) {
+	var help protocol.SignatureHelp
+	err := json.Unmarshal([]byte(`{"signatures":[{"label":"f(🙂 int, b string)","documentation":{"kind":"markdown","value":"Does f."},"parameters":[{"label":[2,8]},{"label":"b string"}]}]}`), &help)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := ProtocolToCoreSignatureHelp(help).Signatures[0]
+	if signature.Documentation != "Does f." {
+		t.Errorf("expected the markup value, got %q", signature.Documentation)
+	}
+	// 🙂 is 2 UTF-16 code units long
+	if signature.Parameters[0].Label != "🙂 int" || signature.Parameters[1].Label != "b string" {
+		t.Errorf("unexpected parameter labels %q %q", signature.Parameters[0].Label, signature.Parameters[1].Label)
+	}
+}
@@ -0,0 +1,150 @@
+package adapter_3_16
+
+import (
+	"github.com/SCKelemen/lsp/core"
+	protocol "github.com/SCKelemen/lsp/protocol"
+)
+
+// CoreToProtocolWorkspaceEdit converts a core workspace edit to protocol.
+// Text edits of each document are converted with the document's content,
+// which contentFor returns by URI; a workspace edit often changes documents
+// that are not open, so contentFor typically falls back to reading the file.
+//
+// LSP Spec: https://microsoft.github.io/language-server-protocol/specifications/lsp/3.16/specification/#workspaceEdit
+func CoreToProtocolWorkspaceEdit(edit core.WorkspaceEdit, contentFor func(uri string) string) protocol.WorkspaceEdit {
+	var result protocol.WorkspaceEdit
+
+	if edit.Changes != nil {
+		result.Changes = make(map[protocol.DocumentUri][]protocol.TextEdit, len(edit.Changes))
+		for uri, edits := range edit.Changes {
+			result.Changes[protocol.DocumentUri(uri)] = CoreToProtocolTextEdits(edits, contentFor(uri))
+		}
+	}
+
+	for _, change := range edit.DocumentChanges {
+		if converted := coreToProtocolDocumentChange(change, contentFor); converted != nil {
+			result.DocumentChanges = append(result.DocumentChanges, converted)
+		}
+	}
+
+	if edit.ChangeAnnotations != nil {
+		result.ChangeAnnotations = make(map[protocol.ChangeAnnotationIdentifier]protocol.ChangeAnnotation, len(edit.ChangeAnnotations))
+		for id, annotation := range edit.ChangeAnnotations {
+			result.ChangeAnnotations[id] = CoreToProtocolChangeAnnotation(annotation)
+		}
+	}
+
+	return result
+}
+
+// CoreToProtocolChangeAnnotation converts a core change annotation to protocol.
+func CoreToProtocolChangeAnnotation(annotation core.ChangeAnnotation) protocol.ChangeAnnotation {
+	result := protocol.ChangeAnnotation{
+		Label:       annotation.Label,
+		Description: optionalString(annotation.Description),
+	}
+	if annotation.NeedsConfirmation {
+		result.NeedsConfirmation = &protocol.True
+	}
+	return result
+}
+
+// coreToProtocolDocumentChange converts one entry of
+// core.WorkspaceEdit.DocumentChanges, given by value or pointer.
+// Unknown types are dropped.
+func coreToProtocolDocumentChange(change any, contentFor func(uri string) string) any {
+	switch c := change.(type) {
+	case core.TextDocumentEdit:
+		return coreToProtocolTextDocumentEdit(c, contentFor(c.TextDocument.URI))
+	case *core.TextDocumentEdit:
+		return coreToProtocolTextDocumentEdit(*c, contentFor(c.TextDocument.URI))
+	case core.CreateFile:
+		return coreToProtocolCreateFile(c)
+	case *core.CreateFile:
+		return coreToProtocolCreateFile(*c)
+	case core.RenameFile:
+		return coreToProtocolRenameFile(c)
+	case *core.RenameFile:
+		return coreToProtocolRenameFile(*c)
+	case core.DeleteFile:
+		return coreToProtocolDeleteFile(c)
+	case *core.DeleteFile:
+		return coreToProtocolDeleteFile(*c)
+	}
+	return nil
+}
+
+func coreToProtocolTextDocumentEdit(edit core.TextDocumentEdit, content string) protocol.TextDocumentEdit {
+	result := protocol.TextDocumentEdit{
+		TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: protocol.TextDocumentIdentifier{
+				URI: protocol.DocumentUri(edit.TextDocument.URI),
+			},
+		},
+		Edits: make([]any, len(edit.Edits)),
+	}
+	if edit.TextDocument.Version != nil {
+		version := protocol.Integer(*edit.TextDocument.Version)
+		result.TextDocument.Version = &version
+	}
+	for i, textEdit := range edit.Edits {
+		result.Edits[i] = CoreToProtocolTextEdit(textEdit, content)
+	}
+	for _, annotated := range edit.AnnotatedEdits {
+		textEdit := CoreToProtocolTextEdit(annotated.TextEdit, content)
+		if annotated.AnnotationID == nil {
+			result.Edits = append(result.Edits, textEdit)
+			continue
+		}
+		result.Edits = append(result.Edits, protocol.AnnotatedTextEdit{
+			TextEdit:     textEdit,
+			AnnotationID: *annotated.AnnotationID,
+		})
+	}
+	return result
+}
+
+func coreToProtocolCreateFile(op core.CreateFile) protocol.CreateFile {
+	result := protocol.CreateFile{Kind: "create", URI: protocol.DocumentUri(op.URI)}
+	if op.Options != nil {
+		result.Options = &protocol.CreateFileOptions{
+			Overwrite:      optionalBool(op.Options.Overwrite),
+			IgnoreIfExists: optionalBool(op.Options.IgnoreIfExists),
+		}
+	}
+	return result
+}
+
+func coreToProtocolRenameFile(op core.RenameFile) protocol.RenameFile {
+	result := protocol.RenameFile{
+		Kind:   "rename",
+		OldURI: protocol.DocumentUri(op.OldURI),
+		NewURI: protocol.DocumentUri(op.NewURI),
+	}
+	if op.Options != nil {
+		result.Options = &protocol.RenameFileOptions{
+			Overwrite:      optionalBool(op.Options.Overwrite),
+			IgnoreIfExists: optionalBool(op.Options.IgnoreIfExists),
+		}
+	}
+	return result
+}
+
+func coreToProtocolDeleteFile(op core.DeleteFile) protocol.DeleteFile {
+	result := protocol.DeleteFile{Kind: "delete", URI: protocol.DocumentUri(op.URI)}
+	if op.Options != nil {
+		result.Options = &protocol.DeleteFileOptions{
+			Recursive:         optionalBool(op.Options.Recursive),
+			IgnoreIfNotExists: optionalBool(op.Options.IgnoreIfNotExists),
+		}
+	}
+	return result
+}
+
+// optionalBool returns nil for false, leaving the field out.
+func optionalBool(value bool) *bool {
+	if !value {
+		return nil
+	}
+	return &protocol.True
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// LineIndexCacheName is the name LineIndexes registers under.
+const LineIndexCacheName = "line-index"
+
+// LineIndexes caches a core.LineIndex per document, rebuilding it only when
+// the content changes. Its memory is managed by a Manager.
+type LineIndexes struct {
+	mu      sync.Mutex
+	entries map[string]*lineIndexEntry
+	manager *Manager
+}
+
+type lineIndexEntry struct {
+	content string
+	index   *core.LineIndex
+}
+
+// NewLineIndexes creates a line index cache registered with m.
+func NewLineIndexes(m *Manager) *LineIndexes {
+	c := &LineIndexes{
+		entries: make(map[string]*lineIndexEntry),
+		manager: m,
+	}
+	m.Register(LineIndexCacheName, c)
+	return c
+}
+
+// Get returns the line index for the given version of a document.
+func (c *LineIndexes) Get(uri, content string) *core.LineIndex {
+	c.mu.Lock()
+	if e, ok := c.entries[uri]; ok && e.content == content {
+		c.mu.Unlock()
+		c.manager.Hit(LineIndexCacheName, uri)
+		return e.index
+	}
+	c.mu.Unlock()
+
+	index := core.NewLineIndex(content)
+
+	c.mu.Lock()
+	c.entries[uri] = &lineIndexEntry{content: content, index: index}
+	c.mu.Unlock()
+
+	// The cached content is kept alive by the entry, so it counts too
+	c.manager.Add(LineIndexCacheName, uri, int64(len(content))+index.Size())
+	return index
+}
+
+// Forget drops the line index of a document.
+func (c *LineIndexes) Forget(uri string) {
+	c.mu.Lock()
+	delete(c.entries, uri)
+	c.mu.Unlock()
+	c.manager.Remove(LineIndexCacheName, uri)
+}
+
+// Evict implements Store.
+func (c *LineIndexes) Evict(uri string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, uri)
+}
@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"testing"
+)
+
+func TestLineIndexes(t *testing.T) {
+	m := NewManager(0)
+	c := NewLineIndexes(m)
+
+	first := c.Get("file:///a.go", "one\ntwo\n")
+	if c.Get("file:///a.go", "one\ntwo\n") != first {
+		t.Error("expected the cached index for unchanged content")
+	}
+	if c.Get("file:///a.go", "one\n") == first {
+		t.Error("expected a new index after the content changed")
+	}
+
+	stats := m.Stats()[0]
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Entries != 1 {
+		t.Errorf("got %+v, want 1 hit, 2 misses, 1 entry", stats)
+	}
+
+	c.Forget("file:///a.go")
+	if m.Used() != 0 {
+		t.Errorf("expected no memory in use after Forget, got %d", m.Used())
+	}
+}
+
+func TestLineIndexes_EvictedByBudget(t *testing.T) {
+	m := NewManager(1)
+	c := NewLineIndexes(m)
+
+	first := c.Get("file:///a.go", "content")
+	if c.Get("file:///a.go", "content") == first {
+		t.Error("expected the index to be evicted when it exceeds the budget")
+	}
+}
@@ -0,0 +1,250 @@
+// Package cache keeps the per-document caches of a long-running server
+// within a shared memory budget.
+//
+// Caches such as parsed ASTs and line indexes hold their entries themselves
+// and report them to a Manager, keyed by document URI, with an estimated size.
+// When the total exceeds the budget, the Manager evicts the least recently
+// used entries, preferring documents that are not open in the editor, by
+// calling back into the owning cache. The Manager also counts hits, misses,
+// and evictions per cache.
+package cache
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+)
+
+// Store is a cache whose memory is managed by a Manager.
+type Store interface {
+	// Evict drops the entry for uri. It is called without the Manager's
+	// lock held, so it may call back into the Manager.
+	Evict(uri string)
+}
+
+// Stats describes one registered cache.
+type Stats struct {
+	Name      string
+	Entries   int
+	Bytes     int64
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// HitRate returns the fraction of lookups that were hits, or 0 if there
+// were no lookups.
+func (s Stats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// Manager enforces a memory budget across registered caches.
+// It is safe for concurrent use.
+type Manager struct {
+	mu      sync.Mutex
+	limit   int64
+	used    int64
+	stores  map[string]Store
+	stats   map[string]*Stats
+	entries map[entryKey]*list.Element
+	// lru orders entries from most (front) to least (back) recently used.
+	lru  *list.List
+	open map[string]bool
+}
+
+type entryKey struct {
+	cache string
+	uri   string
+}
+
+type entry struct {
+	key  entryKey
+	size int64
+}
+
+// NewManager creates a manager with a budget of limit bytes.
+// A limit of zero or less disables eviction; statistics are still kept.
+func NewManager(limit int64) *Manager {
+	return &Manager{
+		limit:   limit,
+		stores:  make(map[string]Store),
+		stats:   make(map[string]*Stats),
+		entries: make(map[entryKey]*list.Element),
+		lru:     list.New(),
+		open:    make(map[string]bool),
+	}
+}
+
+// Register adds a cache under name. Entries reported for name are evicted
+// through store.
+func (m *Manager) Register(name string, store Store) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stores[name] = store
+	if m.stats[name] == nil {
+		m.stats[name] = &Stats{Name: name}
+	}
+}
+
+// Hit records a cache hit for uri and marks its entry as recently used.
+func (m *Manager) Hit(name, uri string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.statsFor(name).Hits++
+	if elem, ok := m.entries[entryKey{name, uri}]; ok {
+		m.lru.MoveToFront(elem)
+	}
+}
+
+// Add records a cache miss and that the cache now holds an entry of size
+// bytes for uri, replacing any previous entry. If the budget is exceeded,
+// least recently used entries are evicted, including possibly this one.
+func (m *Manager) Add(name, uri string, size int64) {
+	m.mu.Lock()
+	stats := m.statsFor(name)
+	stats.Misses++
+
+	key := entryKey{name, uri}
+	if elem, ok := m.entries[key]; ok {
+		m.removeLocked(elem)
+	}
+	m.entries[key] = m.lru.PushFront(&entry{key: key, size: size})
+	m.used += size
+	stats.Entries++
+	stats.Bytes += size
+
+	victims := m.selectVictimsLocked()
+	m.mu.Unlock()
+
+	m.evict(victims)
+}
+
+// Remove records that the cache dropped its entry for uri on its own,
+// e.g. because the document was closed or deleted.
+func (m *Manager) Remove(name, uri string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if elem, ok := m.entries[entryKey{name, uri}]; ok {
+		m.removeLocked(elem)
+	}
+}
+
+// DidOpen marks a document as open. Entries of open documents are evicted
+// only when evicting closed documents is not enough.
+func (m *Manager) DidOpen(uri string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.open[uri] = true
+}
+
+// DidClose marks a document as closed, making its entries the first
+// candidates for eviction, and enforces the budget.
+func (m *Manager) DidClose(uri string) {
+	m.mu.Lock()
+	delete(m.open, uri)
+	victims := m.selectVictimsLocked()
+	m.mu.Unlock()
+
+	m.evict(victims)
+}
+
+// SetLimit changes the budget and evicts entries if needed.
+func (m *Manager) SetLimit(limit int64) {
+	m.mu.Lock()
+	m.limit = limit
+	victims := m.selectVictimsLocked()
+	m.mu.Unlock()
+
+	m.evict(victims)
+}
+
+// Used returns the total size of all tracked entries in bytes.
+func (m *Manager) Used() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.used
+}
+
+// Stats returns the statistics of every registered cache, sorted by name.
+func (m *Manager) Stats() []Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]Stats, 0, len(m.stats))
+	for _, s := range m.stats {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+func (m *Manager) statsFor(name string) *Stats {
+	s, ok := m.stats[name]
+	if !ok {
+		s = &Stats{Name: name}
+		m.stats[name] = s
+	}
+	return s
+}
+
+func (m *Manager) removeLocked(elem *list.Element) {
+	e := elem.Value.(*entry)
+	m.lru.Remove(elem)
+	delete(m.entries, e.key)
+	m.used -= e.size
+
+	stats := m.statsFor(e.key.cache)
+	stats.Entries--
+	stats.Bytes -= e.size
+}
+
+// selectVictimsLocked removes entries until the budget is met and returns
+// them for eviction. Closed documents go first, least recently used first;
+// open documents are only evicted if that is not enough.
+func (m *Manager) selectVictimsLocked() []entryKey {
+	if m.limit <= 0 || m.used <= m.limit {
+		return nil
+	}
+
+	var victims []entryKey
+	for _, evictOpen := range []bool{false, true} {
+		for elem := m.lru.Back(); elem != nil && m.used > m.limit; {
+			prev := elem.Prev()
+			e := elem.Value.(*entry)
+			if evictOpen || !m.open[e.key.uri] {
+				m.removeLocked(elem)
+				m.statsFor(e.key.cache).Evictions++
+				victims = append(victims, e.key)
+			}
+			elem = prev
+		}
+	}
+	return victims
+}
+
+// evict tells the owning stores to drop the victims. It must be called
+// without the lock held.
+func (m *Manager) evict(victims []entryKey) {
+	if len(victims) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	stores := make([]Store, len(victims))
+	for i, key := range victims {
+		stores[i] = m.stores[key.cache]
+	}
+	m.mu.Unlock()
+
+	for i, store := range stores {
+		if store != nil {
+			store.Evict(victims[i].uri)
+		}
+	}
+}
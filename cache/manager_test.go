@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+)
+
+// recordingStore records evictions.
+type recordingStore struct {
+	mu      sync.Mutex
+	evicted []string
+}
+
+func (s *recordingStore) Evict(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evicted = append(s.evicted, uri)
+}
+
+func TestManager_EvictsLeastRecentlyUsed(t *testing.T) {
+	m := NewManager(300)
+	store := &recordingStore{}
+	m.Register("ast", store)
+
+	m.Add("ast", "file:///a.go", 100)
+	m.Add("ast", "file:///b.go", 100)
+	m.Add("ast", "file:///c.go", 100)
+	m.Hit("ast", "file:///a.go") // b is now least recently used
+	m.Add("ast", "file:///d.go", 100)
+
+	if len(store.evicted) != 1 || store.evicted[0] != "file:///b.go" {
+		t.Errorf("evicted %v, want [file:///b.go]", store.evicted)
+	}
+	if used := m.Used(); used != 300 {
+		t.Errorf("used %d bytes, want 300", used)
+	}
+}
+
+func TestManager_PrefersClosedDocuments(t *testing.T) {
+	m := NewManager(200)
+	store := &recordingStore{}
+	m.Register("ast", store)
+
+	m.DidOpen("file:///open.go")
+	m.Add("ast", "file:///open.go", 100)
+	m.Add("ast", "file:///closed.go", 100)
+	m.Add("ast", "file:///new.go", 100)
+
+	if len(store.evicted) != 1 || store.evicted[0] != "file:///closed.go" {
+		t.Errorf("evicted %v, want [file:///closed.go]", store.evicted)
+	}
+
+	// Closing the document makes it evictable
+	m.DidClose("file:///open.go")
+	m.SetLimit(100)
+	if len(store.evicted) != 2 || store.evicted[1] != "file:///open.go" {
+		t.Errorf("evicted %v, want open.go evicted after closing", store.evicted)
+	}
+}
+
+func TestManager_EvictsOpenDocumentsAsLastResort(t *testing.T) {
+	m := NewManager(150)
+	store := &recordingStore{}
+	m.Register("ast", store)
+
+	m.DidOpen("file:///a.go")
+	m.DidOpen("file:///b.go")
+	m.Add("ast", "file:///a.go", 100)
+	m.Add("ast", "file:///b.go", 100)
+
+	if len(store.evicted) != 1 || store.evicted[0] != "file:///a.go" {
+		t.Errorf("evicted %v, want [file:///a.go]", store.evicted)
+	}
+}
+
+func TestManager_Stats(t *testing.T) {
+	m := NewManager(0)
+	m.Register("ast", &recordingStore{})
+	m.Register("line-index", &recordingStore{})
+
+	m.Add("ast", "file:///a.go", 10)
+	m.Hit("ast", "file:///a.go")
+	m.Hit("ast", "file:///a.go")
+	m.Hit("ast", "file:///a.go")
+	m.Add("ast", "file:///a.go", 20) // replaces the entry
+	m.Remove("line-index", "file:///missing.go")
+
+	stats := m.Stats()
+	if len(stats) != 2 || stats[0].Name != "ast" || stats[1].Name != "line-index" {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	ast := stats[0]
+	if ast.Hits != 3 || ast.Misses != 2 || ast.Entries != 1 || ast.Bytes != 20 {
+		t.Errorf("got %+v, want 3 hits, 2 misses, 1 entry of 20 bytes", ast)
+	}
+	if rate := ast.HitRate(); rate != 0.6 {
+		t.Errorf("got hit rate %v, want 0.6", rate)
+	}
+	if rate := stats[1].HitRate(); rate != 0 {
+		t.Errorf("got hit rate %v for unused cache, want 0", rate)
+	}
+}
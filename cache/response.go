@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/internal/textdocument"
+	"github.com/SCKelemen/lsp/protocol"
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+// DefaultResponseTTLs are the methods cached by NewResponseCache, with
+// lifetimes short enough to only absorb repeated requests, like hovers
+// re-requested as the mouse jitters.
+var DefaultResponseTTLs = map[string]time.Duration{
+	protocol.MethodTextDocumentHover:      2 * time.Second,
+	protocol.MethodTextDocumentCompletion: 500 * time.Millisecond,
+}
+
+// ResponseCache is a handler middleware answering repeated requests with
+// the response to the first one.
+//
+// Responses are keyed by method, params (which include the position), and
+// the document version last opened or changed. Entries of a document are
+// dropped when it is opened, changed, or closed, whatever the spelling of
+// its URI (see uri.Normalize), and every entry is dropped when the
+// configuration or watched files change. Errors are not cached.
+type ResponseCache struct {
+	// Handler handles the messages.
+	Handler lsp.Handler
+
+	// TTLs are the lifetimes of the responses of the cached methods.
+	// Methods missing from it are not cached.
+	TTLs map[string]time.Duration
+
+	// Now returns the current time, time.Now if nil.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	versions  map[string]int32                          // by normalized document URI
+	responses map[string]map[responseKey]cachedResponse // by normalized document URI
+	hits      uint64
+	misses    uint64
+}
+
+type responseKey struct {
+	method  string
+	version int32
+	params  string
+}
+
+type cachedResponse struct {
+	result  any
+	expires time.Time
+}
+
+// NewResponseCache creates a cache of the methods in DefaultResponseTTLs.
+func NewResponseCache(handler lsp.Handler) *ResponseCache {
+	ttls := make(map[string]time.Duration, len(DefaultResponseTTLs))
+	for method, ttl := range DefaultResponseTTLs {
+		ttls[method] = ttl
+	}
+	return &ResponseCache{
+		Handler: handler,
+		TTLs:    ttls,
+	}
+}
+
+// Stats returns the number of requests answered from the cache and the
+// number of cacheable requests passed to the handler.
+func (c *ResponseCache) Stats() (hits, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// ([lsp.Handler] interface)
+func (c *ResponseCache) Handle(context *lsp.Context) (any, bool, bool, error) {
+	if protocol.ClientNotifications[context.Method] {
+		c.invalidate(context.Method, context.Params)
+		return c.Handler.Handle(context)
+	}
+
+	ttl := c.TTLs[context.Method]
+	uri := uripkg.Normalize(textdocument.URI(context.Params))
+	if ttl <= 0 || uri == "" {
+		return c.Handler.Handle(context)
+	}
+
+	now := c.now()
+	c.mu.Lock()
+	key := responseKey{method: context.Method, version: c.versions[uri], params: compact(context.Params)}
+	if cached, ok := c.responses[uri][key]; ok && now.Before(cached.expires) {
+		c.hits++
+		c.mu.Unlock()
+		return cached.result, true, true, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	result, validMethod, validParams, err := c.Handler.Handle(context)
+	if validMethod && validParams && err == nil {
+		c.mu.Lock()
+		// A change handled meanwhile makes the response stale
+		if c.versions[uri] == key.version {
+			c.store(uri, key, cachedResponse{result: result, expires: now.Add(ttl)}, now)
+		}
+		c.mu.Unlock()
+	}
+	return result, validMethod, validParams, err
+}
+
+// store adds a response, dropping the expired responses of the document.
+func (c *ResponseCache) store(uri string, key responseKey, response cachedResponse, now time.Time) {
+	if c.responses == nil {
+		c.responses = make(map[string]map[responseKey]cachedResponse)
+	}
+	responses := c.responses[uri]
+	if responses == nil {
+		responses = make(map[responseKey]cachedResponse)
+		c.responses[uri] = responses
+	}
+	for k, cached := range responses {
+		if !now.Before(cached.expires) {
+			delete(responses, k)
+		}
+	}
+	responses[key] = response
+}
+
+// invalidate drops the responses made stale by a notification.
+func (c *ResponseCache) invalidate(method string, params json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch method {
+	case protocol.MethodTextDocumentDidOpen, protocol.MethodTextDocumentDidChange:
+		var document struct {
+			TextDocument struct {
+				URI     string `json:"uri"`
+				Version int32  `json:"version"`
+			} `json:"textDocument"`
+		}
+		json.Unmarshal(params, &document)
+		if uri := uripkg.Normalize(document.TextDocument.URI); uri != "" {
+			if c.versions == nil {
+				c.versions = make(map[string]int32)
+			}
+			c.versions[uri] = document.TextDocument.Version
+			delete(c.responses, uri)
+		}
+	case protocol.MethodTextDocumentDidClose:
+		uri := uripkg.Normalize(textdocument.URI(params))
+		delete(c.versions, uri)
+		delete(c.responses, uri)
+	case protocol.MethodWorkspaceDidChangeConfiguration, protocol.MethodWorkspaceDidChangeWatchedFiles:
+		c.responses = nil
+	}
+}
+
+func (c *ResponseCache) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// compact returns params without insignificant whitespace, so requests
+// differing only in formatting share responses.
+func compact(params json.RawMessage) string {
+	var buffer bytes.Buffer
+	if json.Compact(&buffer, params) != nil {
+		return string(params)
+	}
+	return buffer.String()
+}
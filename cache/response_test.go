@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/protocol"
+)
+
+// countingHandler answers requests with the number of requests handled.
+type countingHandler struct {
+	calls int
+	err   error
+}
+
+func (h *countingHandler) Handle(context *lsp.Context) (any, bool, bool, error) {
+	if protocol.ClientNotifications[context.Method] {
+		return nil, true, true, nil
+	}
+	h.calls++
+	if h.err != nil {
+		return nil, true, true, h.err
+	}
+	return h.calls, true, true, nil
+}
+
+func TestResponseCache(t *testing.T) {
+	const hover = `{"textDocument":{"uri":"file:///a.go"},"position":{"line":1,"character":2}}`
+	const otherHover = `{"textDocument":{"uri":"file:///a.go"},"position":{"line":1,"character":3}}`
+	const otherDocument = `{"textDocument":{"uri":"file:///b.go"},"position":{"line":1,"character":2}}`
+	const change = `{"textDocument":{"uri":"file:///a.go","version":2},"contentChanges":[]}`
+
+	type step struct {
+		method  string
+		params  string
+		advance time.Duration
+	}
+	tests := []struct {
+		name      string
+		steps     []step
+		wantCalls int
+	}{
+		{name: "repeated", steps: []step{
+			{method: protocol.MethodTextDocumentHover, params: hover},
+			{method: protocol.MethodTextDocumentHover, params: hover},
+		}, wantCalls: 1},
+		{name: "whitespace", steps: []step{
+			{method: protocol.MethodTextDocumentHover, params: hover},
+			{method: protocol.MethodTextDocumentHover, params: "  " + hover},
+		}, wantCalls: 1},
+		{name: "other position", steps: []step{
+			{method: protocol.MethodTextDocumentHover, params: hover},
+			{method: protocol.MethodTextDocumentHover, params: otherHover},
+		}, wantCalls: 2},
+		{name: "expired", steps: []step{
+			{method: protocol.MethodTextDocumentHover, params: hover},
+			{method: protocol.MethodTextDocumentHover, params: hover, advance: 3 * time.Second},
+		}, wantCalls: 2},
+		{name: "changed", steps: []step{
+			{method: protocol.MethodTextDocumentHover, params: hover},
+			{method: protocol.MethodTextDocumentDidChange, params: change},
+			{method: protocol.MethodTextDocumentHover, params: hover},
+		}, wantCalls: 2},
+		{name: "changed under another spelling", steps: []step{
+			{method: protocol.MethodTextDocumentHover, params: `{"textDocument":{"uri":"file:///a%2Ego"},"position":{"line":1,"character":2}}`},
+			{method: protocol.MethodTextDocumentDidChange, params: change},
+			{method: protocol.MethodTextDocumentHover, params: `{"textDocument":{"uri":"file:///a%2Ego"},"position":{"line":1,"character":2}}`},
+		}, wantCalls: 2},
+		{name: "closed under another spelling", steps: []step{
+			{method: protocol.MethodTextDocumentHover, params: hover},
+			{method: protocol.MethodTextDocumentDidClose, params: `{"textDocument":{"uri":"file:///a%2Ego"}}`},
+			{method: protocol.MethodTextDocumentHover, params: hover},
+		}, wantCalls: 2},
+		{name: "other document changed", steps: []step{
+			{method: protocol.MethodTextDocumentHover, params: otherDocument},
+			{method: protocol.MethodTextDocumentDidChange, params: change},
+			{method: protocol.MethodTextDocumentHover, params: otherDocument},
+		}, wantCalls: 1},
+		{name: "configuration changed", steps: []step{
+			{method: protocol.MethodTextDocumentHover, params: hover},
+			{method: protocol.MethodWorkspaceDidChangeConfiguration, params: `{"settings":{}}`},
+			{method: protocol.MethodTextDocumentHover, params: hover},
+		}, wantCalls: 2},
+		{name: "not cached", steps: []step{
+			{method: protocol.MethodTextDocumentDefinition, params: hover},
+			{method: protocol.MethodTextDocumentDefinition, params: hover},
+		}, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &countingHandler{}
+			now := time.Unix(0, 0)
+			cache := NewResponseCache(handler)
+			cache.Now = func() time.Time { return now }
+
+			var last any
+			for _, step := range tt.steps {
+				now = now.Add(step.advance)
+				result, validMethod, validParams, err := cache.Handle(&lsp.Context{Method: step.method, Params: json.RawMessage(step.params)})
+				if !validMethod || !validParams || err != nil {
+					t.Fatalf("%s: %t %t %v", step.method, validMethod, validParams, err)
+				}
+				if result != nil {
+					last = result
+				}
+			}
+			if handler.calls != tt.wantCalls {
+				t.Errorf("handled %d requests, want %d", handler.calls, tt.wantCalls)
+			}
+			if last != tt.wantCalls {
+				t.Errorf("last result %v, want %d", last, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestResponseCache_Errors(t *testing.T) {
+	handler := &countingHandler{err: errors.New("failed")}
+	cache := NewResponseCache(handler)
+	params := json.RawMessage(`{"textDocument":{"uri":"file:///a.go"},"position":{"line":0,"character":0}}`)
+
+	for range 2 {
+		cache.Handle(&lsp.Context{Method: protocol.MethodTextDocumentHover, Params: params})
+	}
+	if handler.calls != 2 {
+		t.Errorf("handled %d requests, want 2", handler.calls)
+	}
+	if hits, misses := cache.Stats(); hits != 0 || misses != 2 {
+		t.Errorf("stats %d hits, %d misses, want 0 and 2", hits, misses)
+	}
+}
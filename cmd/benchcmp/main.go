@@ -0,0 +1,78 @@
+// Command benchcmp compares two saved `go test -bench -benchmem` outputs and
+// exits with status 1 if any benchmark got slower or allocates more than the
+// threshold allows.
+//
+// Usage:
+//
+//	benchcmp [-threshold 0.1] old.txt new.txt
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/SCKelemen/lsp/internal/benchcmp"
+)
+
+func main() {
+	threshold := flag.Float64("threshold", 0.1, "allowed relative increase before a benchmark counts as a regression")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: benchcmp [-threshold 0.1] old.txt new.txt")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	old, err := parseFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	current, err := parseFile(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "benchmark\told ns/op\tnew ns/op\tdelta\told allocs\tnew allocs\tdelta\t")
+
+	regressed := false
+	for _, c := range benchcmp.Compare(old, current) {
+		mark := ""
+		if c.Regressed(*threshold) {
+			mark = " !"
+			regressed = true
+		}
+		fmt.Fprintf(w, "%s%s\t%.0f\t%.0f\t%+.1f%%\t%.0f\t%.0f\t%+.1f%%\t\n",
+			c.Name, mark,
+			c.Old.NsPerOp, c.New.NsPerOp, c.NsDelta*100,
+			c.Old.AllocsPerOp, c.New.AllocsPerOp, c.AllocsDelta*100)
+	}
+	w.Flush()
+
+	if regressed {
+		fmt.Fprintf(os.Stderr, "regression above %.0f%% (marked with !)\n", *threshold*100)
+		os.Exit(1)
+	}
+}
+
+func parseFile(path string) (map[string]*benchcmp.Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	results, err := benchcmp.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return results, nil
+}
@@ -0,0 +1,135 @@
+// Command lsp-replay replays the client side of a recorded LSP session
+// against a server, reporting latencies and responses that differ from the
+// recording. It exits with status 1 if any response differs or fails.
+//
+// Record a session by setting Server.Recorder, then replay it against a
+// server build that speaks LSP over stdio:
+//
+//	lsp-replay [-timeout 5m] session.jsonl my-server [args...]
+package main
+
+import (
+	contextpkg "context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"text/tabwriter"
+	"time"
+
+	"github.com/SCKelemen/lsp/replay"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+func main() {
+	timeout := flag.Duration("timeout", 5*time.Minute, "maximum duration of the replay")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: lsp-replay [-timeout 5m] session.jsonl server [args...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	entries, err := readLog(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	command := exec.Command(flag.Arg(1), flag.Args()[2:]...)
+	command.Stderr = os.Stderr
+	stdin, err := command.StdinPipe()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	stdout, err := command.StdoutPipe()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := command.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	context, cancel := contextpkg.WithTimeout(contextpkg.Background(), *timeout)
+	defer cancel()
+
+	stream := jsonrpc2.NewBufferedStream(pipe{stdout, stdin}, jsonrpc2.VSCodeObjectCodec{})
+	results, err := replay.Replay(context, stream, entries)
+	command.Process.Kill()
+	command.Wait()
+
+	if !report(results) || err != nil {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
+}
+
+// report prints the results and returns false if any request failed or
+// differs from the recording.
+func report(results []replay.Result) bool {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "method\trecorded\treplayed\tresult\t")
+
+	ok := true
+	var recorded, replayed time.Duration
+	for _, r := range results {
+		status := "same"
+		switch {
+		case r.Err != nil:
+			status = "error: " + r.Err.Error()
+			ok = false
+		case r.Mismatch:
+			status = "differs"
+			ok = false
+		}
+		recorded += r.Recorded
+		replayed += r.Duration
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", r.Method, formatDuration(r.Recorded), formatDuration(r.Duration), status)
+	}
+	fmt.Fprintf(w, "total\t%s\t%s\t\t\n", formatDuration(recorded), formatDuration(replayed))
+	w.Flush()
+	return ok
+}
+
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(time.Microsecond).String()
+}
+
+func readLog(path string) ([]replay.Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return replay.ReadLog(file)
+}
+
+// pipe joins the server's stdout and stdin into one stream.
+type pipe struct {
+	io.ReadCloser
+	writer io.WriteCloser
+}
+
+// ([io.Writer] interface)
+func (self pipe) Write(p []byte) (int, error) {
+	return self.writer.Write(p)
+}
+
+// ([io.Closer] interface)
+func (self pipe) Close() error {
+	self.writer.Close()
+	return self.ReadCloser.Close()
+}
@@ -6,7 +6,10 @@ import (
 )
 
 type NotifyFunc func(method string, params any)
-type CallFunc func(method string, params any, result any)
+
+// CallFunc sends a request to the client and decodes its result into
+// result. It returns the error of the call, e.g. the client's *jsonrpc2.Error.
+type CallFunc func(method string, params any, result any) error
 
 type Context struct {
 	Method  string
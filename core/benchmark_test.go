@@ -0,0 +1,115 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// benchmarkDocument returns a document with the given number of lines mixing
+// ASCII, 3-byte (CJK), and 4-byte (emoji, surrogate pair in UTF-16) characters.
+func benchmarkDocument(lines int) string {
+	var b strings.Builder
+	for i := 0; i < lines; i++ {
+		switch i % 3 {
+		case 0:
+			b.WriteString("func example() { return value + other }\n")
+		case 1:
+			b.WriteString("\tmessage := \"你好世界 hello 世界\"\n")
+		default:
+			b.WriteString("\t// emoji 😀 in a comment 🎉 line\n")
+		}
+	}
+	return b.String()
+}
+
+func BenchmarkPositionToByteOffset(b *testing.B) {
+	content := benchmarkDocument(10000)
+	pos := Position{Line: 9000, Character: 12}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		PositionToByteOffset(content, pos)
+	}
+}
+
+func BenchmarkByteOffsetToPosition(b *testing.B) {
+	content := benchmarkDocument(10000)
+	offset := len(content) - 20
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ByteOffsetToPosition(content, offset)
+	}
+}
+
+func BenchmarkUTF8ToUTF16Offset(b *testing.B) {
+	content := benchmarkDocument(10000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		UTF8ToUTF16Offset(content, 9001, 20)
+	}
+}
+
+func BenchmarkUTF16ToUTF8Offset(b *testing.B) {
+	content := benchmarkDocument(10000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		UTF16ToUTF8Offset(content, 9002, 20)
+	}
+}
+
+func BenchmarkDocumentApplyEdit(b *testing.B) {
+	content := benchmarkDocument(10000)
+	edit := Range{
+		Start: Position{Line: 5000, Character: 5},
+		End:   Position{Line: 5000, Character: 12},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		doc := NewDocument("file:///bench.go", content, 1)
+		doc.ApplyEdit(edit, "renamed")
+	}
+}
+
+func BenchmarkWordAt(b *testing.B) {
+	content := benchmarkDocument(10000)
+	pos := Position{Line: 9000, Character: 12}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		WordAt(content, pos)
+	}
+}
+
+// BenchmarkLineIndexOffset is the indexed counterpart of
+// BenchmarkPositionToByteOffset; building the index is not included.
+func BenchmarkLineIndexOffset(b *testing.B) {
+	idx := NewLineIndex(benchmarkDocument(10000))
+	pos := Position{Line: 9000, Character: 12}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx.Offset(pos)
+	}
+}
+
+func BenchmarkNewLineIndex(b *testing.B) {
+	content := benchmarkDocument(10000)
+
+	b.SetBytes(int64(len(content)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewLineIndex(content)
+	}
+}
@@ -1,5 +1,7 @@
 package core
 
+import "encoding/json"
+
 // CodeFixContext provides context for code fix providers.
 type CodeFixContext struct {
 	// URI is the document URI.
@@ -41,9 +43,28 @@ type CodeFixProvider interface {
 	ProvideCodeFixes(ctx CodeFixContext) []CodeAction
 }
 
+// CodeActionResolveProvider resolves additional details for a code action.
+// This is used for lazy computation of edits, which a request for the code
+// actions of a range would otherwise compute for every action offered.
+type CodeActionResolveProvider interface {
+	// ResolveCodeAction resolves additional details for a code action,
+	// typically its Edit. This is called when the user picks the action.
+	ResolveCodeAction(action CodeAction) CodeAction
+}
+
 // CodeFixRegistry manages multiple code fix providers.
 type CodeFixRegistry struct {
 	providers []CodeFixProvider
+	selectors []DocumentSelector
+
+	// Languages resolves the language id of documents for selectors that
+	// filter by language. If nil, such filters match no document.
+	Languages *LanguageRegistry
+
+	// Ranking, if set, orders the collected code actions, like
+	// DefaultCodeActionRanking. If nil, they are in the order of their
+	// providers.
+	Ranking *CodeActionRanking
 }
 
 // NewCodeFixRegistry creates a new code fix registry.
@@ -55,20 +76,108 @@ func NewCodeFixRegistry() *CodeFixRegistry {
 
 // Register adds a code fix provider to the registry.
 func (r *CodeFixRegistry) Register(provider CodeFixProvider) {
+	r.RegisterFor(nil, provider)
+}
+
+// RegisterFor adds a code fix provider that is only asked for documents
+// matching selector. A nil selector matches every document.
+func (r *CodeFixRegistry) RegisterFor(selector DocumentSelector, provider CodeFixProvider) {
 	r.providers = append(r.providers, provider)
+	r.selectors = append(r.selectors, selector)
 }
 
 // ProvideCodeFixes collects code fixes from all registered providers.
 func (r *CodeFixRegistry) ProvideCodeFixes(ctx CodeFixContext) []CodeAction {
 	var actions []CodeAction
-	for _, provider := range r.providers {
-		if fixes := provider.ProvideCodeFixes(ctx); len(fixes) > 0 {
-			actions = append(actions, fixes...)
+	for i, provider := range r.providers {
+		if !selects(r.selectors[i], r.Languages, ctx.URI) {
+			continue
+		}
+		fixes := provider.ProvideCodeFixes(ctx)
+		if _, ok := provider.(CodeActionResolveProvider); ok {
+			for j := range fixes {
+				fixes[j].Data = codeActionData{Provider: i, Data: fixes[j].Data}
+			}
 		}
+		actions = append(actions, fixes...)
+	}
+	if r.Ranking != nil {
+		actions = r.Ranking.Rank(ctx, actions)
 	}
 	return actions
 }
 
+// ResolveCodeAction resolves an action with the provider that returned it,
+// for codeAction/resolve. Actions of providers that do not resolve them
+// are returned as they are.
+//
+// The properties the provider leaves unset keep their values, so an action
+// with both a Command and an Edit resolved lazily keeps its command. Clients
+// apply the edit first and then execute the command, which may thus rely on
+// the edit, e.g. to move the cursor into the code it inserted.
+func (r *CodeFixRegistry) ResolveCodeAction(action CodeAction) CodeAction {
+	index, data, ok := untagCodeActionData(action.Data)
+	if !ok || index < 0 || index >= len(r.providers) {
+		return action
+	}
+	resolver, ok := r.providers[index].(CodeActionResolveProvider)
+	if !ok {
+		return action
+	}
+
+	action.Data = data
+	resolved := resolver.ResolveCodeAction(action)
+	if resolved.Title == "" {
+		resolved.Title = action.Title
+	}
+	if resolved.Kind == nil {
+		resolved.Kind = action.Kind
+	}
+	if resolved.Diagnostics == nil {
+		resolved.Diagnostics = action.Diagnostics
+	}
+	if resolved.Edit == nil {
+		resolved.Edit = action.Edit
+	}
+	if resolved.Command == nil {
+		resolved.Command = action.Command
+	}
+	if resolved.Data == nil {
+		resolved.Data = action.Data
+	}
+	resolved.IsPreferred = resolved.IsPreferred || action.IsPreferred
+	resolved.Data = codeActionData{Provider: index, Data: resolved.Data}
+	return resolved
+}
+
+// codeActionData wraps the Data of the actions of providers that resolve
+// them, so that ResolveCodeAction knows the provider to ask.
+type codeActionData struct {
+	Provider int `json:"codeFixProvider"`
+	Data     any `json:"data,omitempty"`
+}
+
+// untagCodeActionData returns the index of the provider and the data of an
+// action returned by a CodeFixRegistry, also once the data went through
+// JSON to the client and back.
+func untagCodeActionData(data any) (int, any, bool) {
+	if tagged, ok := data.(codeActionData); ok {
+		return tagged.Provider, tagged.Data, true
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return 0, nil, false
+	}
+	var tagged struct {
+		Provider *int `json:"codeFixProvider"`
+		Data     any  `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &tagged); err != nil || tagged.Provider == nil {
+		return 0, nil, false
+	}
+	return *tagged.Provider, tagged.Data, true
+}
+
 // DiagnosticProvider provides diagnostics for a document.
 // Implementations work directly with core types (UTF-8 offsets).
 type DiagnosticProvider interface {
@@ -80,6 +189,19 @@ type DiagnosticProvider interface {
 // DiagnosticRegistry manages multiple diagnostic providers.
 type DiagnosticRegistry struct {
 	providers []DiagnosticProvider
+	selectors []DocumentSelector
+
+	// Languages resolves the language id of documents for selectors that
+	// filter by language. If nil, such filters match no document.
+	Languages *LanguageRegistry
+
+	// Codes, if set, fills in the CodeDescription of diagnostics with a
+	// documented code.
+	Codes *DiagnosticCodeRegistry
+
+	// Suppressor, if set, drops the diagnostics suppressed by comment
+	// directives, like //nolint.
+	Suppressor *Suppressor
 }
 
 // NewDiagnosticRegistry creates a new diagnostic registry.
@@ -91,32 +213,48 @@ func NewDiagnosticRegistry() *DiagnosticRegistry {
 
 // Register adds a diagnostic provider to the registry.
 func (r *DiagnosticRegistry) Register(provider DiagnosticProvider) {
+	r.RegisterFor(nil, provider)
+}
+
+// RegisterFor adds a diagnostic provider that is only asked for documents
+// matching selector. A nil selector matches every document.
+func (r *DiagnosticRegistry) RegisterFor(selector DocumentSelector, provider DiagnosticProvider) {
 	r.providers = append(r.providers, provider)
+	r.selectors = append(r.selectors, selector)
 }
 
 // ProvideDiagnostics collects diagnostics from all registered providers.
 func (r *DiagnosticRegistry) ProvideDiagnostics(uri, content string) []Diagnostic {
 	var diagnostics []Diagnostic
-	for _, provider := range r.providers {
+	for i, provider := range r.providers {
+		if !selects(r.selectors[i], r.Languages, uri) {
+			continue
+		}
 		if diags := provider.ProvideDiagnostics(uri, content); len(diags) > 0 {
 			diagnostics = append(diagnostics, diags...)
 		}
 	}
+	if r.Suppressor != nil {
+		diagnostics = r.Suppressor.Filter(content, diagnostics)
+	}
+	if r.Codes != nil {
+		r.Codes.Describe(diagnostics)
+	}
 	return diagnostics
 }
 
+// selects reports whether a provider registered for selector handles the
+// document. A nil selector selects every document.
+func selects(selector DocumentSelector, languages *LanguageRegistry, uri string) bool {
+	return selector == nil || selector.Matches(uri, languages.LanguageID(uri))
+}
+
 // FoldingRangeProvider provides folding ranges for a document.
 type FoldingRangeProvider interface {
 	// ProvideFoldingRanges returns folding ranges for the given document.
 	ProvideFoldingRanges(uri, content string) []FoldingRange
 }
 
-// DocumentSymbolProvider provides document symbols.
-type DocumentSymbolProvider interface {
-	// ProvideDocumentSymbols returns document symbols for the given document.
-	ProvideDocumentSymbols(uri, content string) []DocumentSymbol
-}
-
 // DefinitionProvider provides go-to-definition locations.
 type DefinitionProvider interface {
 	// ProvideDefinition returns definition locations for the position.
@@ -124,6 +262,16 @@ type DefinitionProvider interface {
 	ProvideDefinition(uri, content string, position Position) []Location
 }
 
+// DefinitionLinkProvider provides go-to-definition as location links, which
+// also carry the range of the reference and the full range of the
+// declaration. Servers answer with links when the client supports them
+// (linkSupport) and with plain locations otherwise.
+type DefinitionLinkProvider interface {
+	// ProvideDefinitionLinks returns definition links for the position.
+	// Returns nil if no definition is found.
+	ProvideDefinitionLinks(uri, content string, position Position) []LocationLink
+}
+
 // HoverInfo contains hover information for a position.
 type HoverInfo struct {
 	// Contents is the hover content (markdown or plain text).
@@ -205,6 +353,15 @@ type WorkspaceSymbolProvider interface {
 	ProvideWorkspaceSymbols(query string) []WorkspaceSymbol
 }
 
+// WorkspaceSymbolResolveProvider resolves additional details for a workspace symbol.
+// This is used for lazy resolution of details like signatures, which a query
+// matching many symbols would otherwise compute for all of them.
+type WorkspaceSymbolResolveProvider interface {
+	// ResolveWorkspaceSymbol resolves additional details for a workspace symbol.
+	// This is called when the user selects the symbol in a picker.
+	ResolveWorkspaceSymbol(symbol WorkspaceSymbol) WorkspaceSymbol
+}
+
 // InlayHintsProvider provides inlay hints for a document.
 // Inlay hints show inline annotations like parameter names or inferred types.
 type InlayHintsProvider interface {
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"sort"
+	"strings"
+)
+
+// CodeActionRanking orders the code actions collected by a
+// CodeFixRegistry, which otherwise come in the order their providers were
+// registered.
+type CodeActionRanking struct {
+	// CursorFixesFirst puts the quick fixes of the diagnostics under the
+	// cursor first, then the other quick fixes, then the other actions.
+	CursorFixesFirst bool
+
+	// PreferSingleFixes marks a quick fix as preferred when it is the only
+	// one resolving one of its diagnostics, so that clients apply it with
+	// their auto fix command. Disabled fixes are not marked, and do not
+	// count as fixes.
+	PreferSingleFixes bool
+
+	// SortByTitle orders the actions of the same rank by title, rather
+	// than by the order of their providers.
+	SortByTitle bool
+}
+
+// DefaultCodeActionRanking applies all the ranking rules.
+var DefaultCodeActionRanking = CodeActionRanking{
+	CursorFixesFirst:  true,
+	PreferSingleFixes: true,
+	SortByTitle:       true,
+}
+
+// Ranks of code actions with CursorFixesFirst
+const (
+	codeActionRankCursorFix = iota
+	codeActionRankQuickFix
+	codeActionRankOther
+)
+
+// Rank returns the actions in ranked order. The actions are not modified.
+func (r CodeActionRanking) Rank(ctx CodeFixContext, actions []CodeAction) []CodeAction {
+	ranked := append([]CodeAction(nil), actions...)
+	if r.PreferSingleFixes {
+		preferSingleFixes(ranked)
+	}
+
+	rank := func(action CodeAction) int {
+		if !r.CursorFixesFirst {
+			return 0
+		}
+		if !isQuickFix(action) {
+			return codeActionRankOther
+		}
+		for _, diagnostic := range action.Diagnostics {
+			if diagnostic.Range.Contains(ctx.Range.Start) {
+				return codeActionRankCursorFix
+			}
+		}
+		return codeActionRankQuickFix
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		ri, rj := rank(ranked[i]), rank(ranked[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return r.SortByTitle && ranked[i].Title < ranked[j].Title
+	})
+	return ranked
+}
+
+// diagnosticKey identifies a diagnostic among the diagnostics of code
+// actions, which are copies of the diagnostics of the request.
+type diagnosticKey struct {
+	rng     Range
+	code    DiagnosticCode
+	source  string
+	message string
+}
+
+func keyOfDiagnostic(diagnostic Diagnostic) diagnosticKey {
+	key := diagnosticKey{rng: diagnostic.Range, source: diagnostic.Source, message: diagnostic.Message}
+	if diagnostic.Code != nil {
+		key.code = *diagnostic.Code
+	}
+	return key
+}
+
+// preferSingleFixes marks the quick fixes that are the only fix of one of
+// their diagnostics as preferred.
+func preferSingleFixes(actions []CodeAction) {
+	fixes := make(map[diagnosticKey]int)
+	for _, action := range actions {
+		if isQuickFix(action) && action.Disabled == nil {
+			for _, diagnostic := range action.Diagnostics {
+				fixes[keyOfDiagnostic(diagnostic)]++
+			}
+		}
+	}
+	for i, action := range actions {
+		if !isQuickFix(action) || action.Disabled != nil {
+			continue
+		}
+		for _, diagnostic := range action.Diagnostics {
+			if fixes[keyOfDiagnostic(diagnostic)] == 1 {
+				actions[i].IsPreferred = true
+				break
+			}
+		}
+	}
+}
+
+// isQuickFix reports whether an action is a quick fix, of kind quickfix
+// or a subkind like quickfix.import.
+func isQuickFix(action CodeAction) bool {
+	if action.Kind == nil {
+		return false
+	}
+	kind := string(*action.Kind)
+	return kind == string(CodeActionKindQuickFix) || strings.HasPrefix(kind, string(CodeActionKindQuickFix)+".")
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+type fixedCodeActions []CodeAction
+
+func (p fixedCodeActions) ProvideCodeFixes(ctx CodeFixContext) []CodeAction {
+	return p
+}
+
+func TestCodeActionRanking(t *testing.T) {
+	quickFix := CodeActionKindQuickFix
+	importFix := CodeActionKind("quickfix.import")
+	extract := CodeActionKindRefactorExtract
+
+	atCursor := Diagnostic{Message: "undefined: strings", Range: Range{Start: Position{Line: 3, Character: 1}, End: Position{Line: 3, Character: 8}}}
+	elsewhere := Diagnostic{Message: "unused variable", Range: Range{Start: Position{Line: 9}, End: Position{Line: 9, Character: 5}}}
+
+	registry := NewCodeFixRegistry()
+	registry.Ranking = &DefaultCodeActionRanking
+	registry.Register(fixedCodeActions{
+		{Title: "Extract function", Kind: &extract},
+		{Title: "Remove variable", Kind: &quickFix, Diagnostics: []Diagnostic{elsewhere}},
+	})
+	registry.Register(fixedCodeActions{
+		{Title: "Import strings", Kind: &importFix, Diagnostics: []Diagnostic{atCursor}},
+		{Title: "Create variable strings", Kind: &quickFix, Diagnostics: []Diagnostic{atCursor}},
+		{Title: "Add a disabled fix", Kind: &quickFix, Diagnostics: []Diagnostic{atCursor}, Disabled: &CodeActionDisabled{Reason: "read-only"}},
+	})
+
+	actions := registry.ProvideCodeFixes(CodeFixContext{
+		URI:   "file:///main.go",
+		Range: Range{Start: Position{Line: 3, Character: 4}, End: Position{Line: 3, Character: 4}},
+	})
+	var titles []string
+	for _, action := range actions {
+		titles = append(titles, action.Title)
+	}
+	want := "Add a disabled fix,Create variable strings,Import strings,Remove variable,Extract function"
+	if got := strings.Join(titles, ","); got != want {
+		t.Errorf("actions = %s, want %s", got, want)
+	}
+
+	preferred := map[string]bool{}
+	for _, action := range actions {
+		preferred[action.Title] = action.IsPreferred
+	}
+	// Two fixes resolve the diagnostic at the cursor, one the other
+	if preferred["Import strings"] || preferred["Create variable strings"] || preferred["Add a disabled fix"] {
+		t.Errorf("preferred = %v, want no fix of the diagnostic at the cursor preferred", preferred)
+	}
+	if !preferred["Remove variable"] {
+		t.Error("the only fix of a diagnostic is not preferred")
+	}
+}
+
+func TestCodeActionRankingOptions(t *testing.T) {
+	quickFix := CodeActionKindQuickFix
+	diagnostic := Diagnostic{Message: "unused import", Range: Range{End: Position{Character: 10}}}
+	actions := []CodeAction{
+		{Title: "b"},
+		{Title: "a", Kind: &quickFix, Diagnostics: []Diagnostic{diagnostic}},
+	}
+
+	// Without rules, the order and the actions are kept
+	ranked := CodeActionRanking{}.Rank(CodeFixContext{}, actions)
+	if ranked[0].Title != "b" || ranked[1].IsPreferred {
+		t.Errorf("ranked = %+v, want the actions unchanged", ranked)
+	}
+
+	ranked = CodeActionRanking{SortByTitle: true, PreferSingleFixes: true}.Rank(CodeFixContext{}, actions)
+	if ranked[0].Title != "a" || !ranked[0].IsPreferred {
+		t.Errorf("ranked = %+v, want the preferred fix a first", ranked)
+	}
+	if actions[1].IsPreferred {
+		t.Error("Rank modified the actions")
+	}
+}
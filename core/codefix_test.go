@@ -0,0 +1,68 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// extractingProvider offers to extract a function, computing the edit when
+// the action is resolved, and then renaming the function with a command.
+type extractingProvider struct{}
+
+func (extractingProvider) ProvideCodeFixes(ctx CodeFixContext) []CodeAction {
+	kind := CodeActionKindRefactorExtract
+	return []CodeAction{{
+		Title:   "Extract function",
+		Kind:    &kind,
+		Command: &Command{Title: "Rename", Command: "editor.rename", Arguments: []interface{}{ctx.URI}},
+		Data:    "newFunction",
+	}}
+}
+
+func (extractingProvider) ResolveCodeAction(action CodeAction) CodeAction {
+	name, _ := action.Data.(string)
+	return CodeAction{
+		Edit: &WorkspaceEdit{Changes: map[string][]TextEdit{
+			"file:///a.go": {{NewText: "func " + name + "() {}\n"}},
+		}},
+	}
+}
+
+func TestCodeFixRegistry_ResolveCodeAction(t *testing.T) {
+	registry := NewCodeFixRegistry()
+	registry.Register(fixedCodeActions{{Title: "Remove variable", Data: "unresolved"}})
+	registry.Register(extractingProvider{})
+
+	actions := registry.ProvideCodeFixes(CodeFixContext{URI: "file:///a.go"})
+	if len(actions) != 2 {
+		t.Fatalf("expected 2 actions, got %+v", actions)
+	}
+	if actions[0].Data != "unresolved" {
+		t.Errorf("expected the data of actions not resolved to be left alone, got %v", actions[0].Data)
+	}
+	if resolved := registry.ResolveCodeAction(actions[0]); resolved.Edit != nil || resolved.Data != "unresolved" {
+		t.Errorf("expected an action of a provider not resolving to be returned as is, got %+v", resolved)
+	}
+
+	// Round trip the action through JSON, as the client does
+	raw, _ := json.Marshal(actions[1].Data)
+	action := actions[1]
+	if err := json.Unmarshal(raw, &action.Data); err != nil {
+		t.Fatal(err)
+	}
+	resolved := registry.ResolveCodeAction(action)
+	if resolved.Edit == nil || resolved.Edit.Changes["file:///a.go"][0].NewText != "func newFunction() {}\n" {
+		t.Fatalf("expected the edit of the provider's data, got %+v", resolved.Edit)
+	}
+	if resolved.Command == nil || resolved.Command.Command != "editor.rename" {
+		t.Errorf("expected the follow-up command to be kept, got %+v", resolved.Command)
+	}
+	if resolved.Title != "Extract function" || resolved.Kind == nil || *resolved.Kind != CodeActionKindRefactorExtract {
+		t.Errorf("expected the title and kind to be kept, got %q %v", resolved.Title, resolved.Kind)
+	}
+
+	// The resolved action can be resolved again
+	if again := registry.ResolveCodeAction(resolved); again.Edit == nil {
+		t.Errorf("expected the resolved action to still resolve, got %+v", again)
+	}
+}
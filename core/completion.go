@@ -49,6 +49,32 @@ const (
 	InsertTextFormatSnippet InsertTextFormat = 2
 )
 
+// InsertTextMode defines how whitespace and indentation are handled when
+// a completion item is inserted.
+type InsertTextMode int
+
+const (
+	// InsertTextModeAsIs means the insert text is inserted as is, the
+	// client not adjusting its whitespace.
+	InsertTextModeAsIs InsertTextMode = 1
+	// InsertTextModeAdjustIndentation means the client indents the lines
+	// after the first like the line the text is inserted on, so
+	// multi-line insert texts only indent relative to their first line.
+	InsertTextModeAdjustIndentation InsertTextMode = 2
+)
+
+// CompletionItemLabelDetails are additional details of the label of a
+// completion item, so the label itself can stay short.
+type CompletionItemLabelDetails struct {
+	// Detail is shown directly after the label, without spacing, like
+	// the signature of a function: "(s string) int".
+	Detail string
+
+	// Description is shown after Detail, like the package of a symbol or
+	// the path of a file.
+	Description string
+}
+
 // CompletionTriggerKind defines how a completion was triggered.
 type CompletionTriggerKind int
 
@@ -66,6 +92,9 @@ type CompletionItem struct {
 	// Label is the text shown in the completion list.
 	Label string
 
+	// LabelDetails are shown less prominently after the label.
+	LabelDetails *CompletionItemLabelDetails
+
 	// Kind is the type of completion item.
 	Kind *CompletionItemKind
 
@@ -96,6 +125,10 @@ type CompletionItem struct {
 	// InsertTextFormat indicates how to interpret the insert text.
 	InsertTextFormat *InsertTextFormat
 
+	// InsertTextMode indicates how the client handles the indentation of
+	// the insert text. If nil, the client default is used.
+	InsertTextMode *InsertTextMode
+
 	// TextEdit is the edit to apply when selecting this item.
 	TextEdit *TextEdit
 
@@ -133,6 +166,10 @@ type CompletionContext struct {
 	// Position is where completion was requested (UTF-8 offset).
 	Position Position
 
+	// Version is the document version the request was made against.
+	// Providers that cache results use it to detect stale entries.
+	Version int
+
 	// TriggerKind indicates how completion was triggered.
 	TriggerKind CompletionTriggerKind
 
@@ -147,6 +184,18 @@ type CompletionProvider interface {
 	ProvideCompletions(ctx CompletionContext) *CompletionList
 }
 
+// CompletionRefineProvider narrows an earlier incomplete result instead of
+// recomputing it from scratch.
+// When a provider returns IsIncomplete: true, the client re-requests completion
+// with CompletionTriggerKindTriggerForIncompleteCompletions as the user keeps
+// typing. Providers implementing this interface receive the list they returned
+// for the shorter prefix at the same position.
+type CompletionRefineProvider interface {
+	// RefineCompletions returns completions for ctx given the previous list.
+	// Returns nil or empty list if no completions remain.
+	RefineCompletions(ctx CompletionContext, previous *CompletionList) *CompletionList
+}
+
 // CompletionItemResolveProvider resolves additional details for a completion item.
 type CompletionItemResolveProvider interface {
 	// ResolveCompletionItem resolves additional details for a completion item.
@@ -190,6 +239,18 @@ type SignatureHelp struct {
 	ActiveParameter *int
 }
 
+// SignatureHelpTriggerKind defines how signature help was triggered.
+type SignatureHelpTriggerKind int
+
+const (
+	// SignatureHelpTriggerKindInvoked means signature help was explicitly requested.
+	SignatureHelpTriggerKindInvoked SignatureHelpTriggerKind = 1
+	// SignatureHelpTriggerKindTriggerCharacter means signature help was triggered by a character.
+	SignatureHelpTriggerKindTriggerCharacter SignatureHelpTriggerKind = 2
+	// SignatureHelpTriggerKindContentChange means signature help was triggered by the cursor moving or the document changing.
+	SignatureHelpTriggerKindContentChange SignatureHelpTriggerKind = 3
+)
+
 // SignatureHelpContext provides context for signature help.
 type SignatureHelpContext struct {
 	// URI is the document URI.
@@ -201,11 +262,55 @@ type SignatureHelpContext struct {
 	// Position is where signature help was requested (UTF-8 offset).
 	Position Position
 
+	// TriggerKind indicates how signature help was triggered.
+	TriggerKind SignatureHelpTriggerKind
+
 	// TriggerCharacter is the character that triggered signature help (if any).
 	TriggerCharacter string
 
-	// IsRetrigger indicates if this is a retrigger.
+	// IsRetrigger indicates if this is a retrigger: signature help was
+	// already showing when it was triggered, e.g. as the user types the
+	// arguments of a call.
 	IsRetrigger bool
+
+	// ActiveSignatureHelp is the signature help showing when retriggered,
+	// with the signature the user selected, e.g. with the up and down keys,
+	// as ActiveSignature.
+	ActiveSignatureHelp *SignatureHelp
+}
+
+// PreserveActiveSignature makes the signature the user selected, when
+// signature help is retriggered, the active signature of help again, if
+// help still offers it. Signatures are matched by label, so providers can
+// call it on the help they computed afresh.
+func PreserveActiveSignature(ctx SignatureHelpContext, help *SignatureHelp) {
+	label, ok := selectedSignature(ctx)
+	if help == nil || !ok {
+		return
+	}
+	for i, signature := range help.Signatures {
+		if signature.Label == label {
+			help.ActiveSignature = &i
+			return
+		}
+	}
+}
+
+// selectedSignature returns the label of the signature the user selected,
+// if signature help is retriggered.
+func selectedSignature(ctx SignatureHelpContext) (string, bool) {
+	if !ctx.IsRetrigger || ctx.ActiveSignatureHelp == nil {
+		return "", false
+	}
+	previous := ctx.ActiveSignatureHelp
+	active := 0
+	if previous.ActiveSignature != nil {
+		active = *previous.ActiveSignature
+	}
+	if active < 0 || active >= len(previous.Signatures) {
+		return "", false
+	}
+	return previous.Signatures[active].Label, true
 }
 
 // SignatureHelpProvider provides signature help.
@@ -0,0 +1,48 @@
+package core
+
+import "testing"
+
+func TestPreserveActiveSignature(t *testing.T) {
+	one, two := 0, 1
+	previous := &SignatureHelp{
+		Signatures: []SignatureInformation{
+			{Label: "Println(a ...any)"},
+			{Label: "Println(w io.Writer, a ...any)"},
+		},
+		ActiveSignature: &two,
+	}
+
+	tests := []struct {
+		name   string
+		ctx    SignatureHelpContext
+		expect int
+	}{
+		{"retrigger", SignatureHelpContext{IsRetrigger: true, ActiveSignatureHelp: previous}, 0},
+		{"not a retrigger", SignatureHelpContext{ActiveSignatureHelp: previous}, 1},
+		{"nothing showing", SignatureHelpContext{IsRetrigger: true}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The signatures in another order, as computed afresh
+			help := &SignatureHelp{
+				Signatures: []SignatureInformation{
+					{Label: "Println(w io.Writer, a ...any)"},
+					{Label: "Println(a ...any)"},
+				},
+				ActiveSignature: &two,
+			}
+			PreserveActiveSignature(tt.ctx, help)
+			if *help.ActiveSignature != tt.expect {
+				t.Errorf("expected active signature %d, got %d", tt.expect, *help.ActiveSignature)
+			}
+		})
+	}
+
+	// A selected signature no longer offered leaves help alone
+	help := &SignatureHelp{Signatures: []SignatureInformation{{Label: "Print(a ...any)"}}, ActiveSignature: &one}
+	PreserveActiveSignature(SignatureHelpContext{IsRetrigger: true, ActiveSignatureHelp: previous}, help)
+	if *help.ActiveSignature != 0 {
+		t.Errorf("expected active signature 0, got %d", *help.ActiveSignature)
+	}
+	PreserveActiveSignature(SignatureHelpContext{IsRetrigger: true, ActiveSignatureHelp: previous}, nil)
+}
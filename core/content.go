@@ -0,0 +1,72 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TextDocumentContentProvider provides the content of virtual, read-only
+// documents under a custom URI scheme, such as decompiled stubs or generated
+// previews. Clients request the content with workspace/textDocumentContent
+// when they open a URI with a scheme the server registered.
+//
+// LSP Specification: https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#workspace_textDocumentContent
+type TextDocumentContentProvider interface {
+	// ProvideTextDocumentContent returns the content of the document.
+	// Returns an error if the document does not exist.
+	ProvideTextDocumentContent(uri string) (string, error)
+}
+
+// TextDocumentContentRegistry routes content requests to providers by URI
+// scheme. It is safe for concurrent use.
+type TextDocumentContentRegistry struct {
+	mu        sync.RWMutex
+	providers map[string]TextDocumentContentProvider
+}
+
+// NewTextDocumentContentRegistry creates a new content registry.
+func NewTextDocumentContentRegistry() *TextDocumentContentRegistry {
+	return &TextDocumentContentRegistry{
+		providers: make(map[string]TextDocumentContentProvider),
+	}
+}
+
+// Register sets the provider for a URI scheme, like "gostub".
+func (r *TextDocumentContentRegistry) Register(scheme string, provider TextDocumentContentProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[strings.ToLower(scheme)] = provider
+}
+
+// Schemes returns the registered schemes in sorted order, as announced in
+// the server's textDocumentContent capability.
+func (r *TextDocumentContentRegistry) Schemes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schemes := make([]string, 0, len(r.providers))
+	for scheme := range r.providers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// ProvideTextDocumentContent returns the content of a document from the
+// provider registered for its scheme.
+func (r *TextDocumentContentRegistry) ProvideTextDocumentContent(uri string) (string, error) {
+	scheme, _, ok := strings.Cut(uri, ":")
+	if !ok {
+		return "", fmt.Errorf("invalid document URI: %s", uri)
+	}
+
+	r.mu.RLock()
+	provider, ok := r.providers[strings.ToLower(scheme)]
+	r.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("no content provider for scheme %q", scheme)
+	}
+	return provider.ProvideTextDocumentContent(uri)
+}
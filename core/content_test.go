@@ -0,0 +1,49 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type staticContent map[string]string
+
+func (s staticContent) ProvideTextDocumentContent(uri string) (string, error) {
+	content, ok := s[uri]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return content, nil
+}
+
+func TestTextDocumentContentRegistry(t *testing.T) {
+	registry := NewTextDocumentContentRegistry()
+	registry.Register("preview", staticContent{"preview:/a.md": "# A"})
+	registry.Register("Stub", staticContent{"stub:/b.go": "package b"})
+
+	if got, want := registry.Schemes(), []string{"preview", "stub"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Schemes() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		uri     string
+		want    string
+		wantErr bool
+	}{
+		{uri: "preview:/a.md", want: "# A"},
+		{uri: "STUB:/b.go", wantErr: true}, // scheme matches, provider has no such URI
+		{uri: "stub:/b.go", want: "package b"},
+		{uri: "file:///a.go", wantErr: true},
+		{uri: "no-scheme", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := registry.ProvideTextDocumentContent(tt.uri)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ProvideTextDocumentContent(%q) error = %v, wantErr %v", tt.uri, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ProvideTextDocumentContent(%q) = %q, want %q", tt.uri, got, tt.want)
+		}
+	}
+}
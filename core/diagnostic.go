@@ -182,3 +182,22 @@ func (d Diagnostic) HasTag(tag DiagnosticTag) bool {
 	}
 	return false
 }
+
+// AddRelated adds related information pointing at r in the document at
+// uri, like the declaration of a name reported as unused.
+func (d *Diagnostic) AddRelated(uri string, r Range, message string) {
+	d.RelatedInformation = append(d.RelatedInformation, DiagnosticRelatedInformation{
+		Location: Location{URI: uri, Range: r},
+		Message:  message,
+	})
+}
+
+// LinkDiagnostics cross-references two diagnostics reported about the same
+// problem in different places, like "x redeclared" and "other declaration
+// of x", or a declaration and its use: each gets related information
+// pointing at the other, with the other's message. aURI and bURI are the
+// documents of a and b.
+func LinkDiagnostics(aURI string, a *Diagnostic, bURI string, b *Diagnostic) {
+	a.AddRelated(bURI, b.Range, b.Message)
+	b.AddRelated(aURI, a.Range, a.Message)
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"strings"
+	"sync"
+)
+
+// ExplainDiagnosticCommand is the command of the code actions offered by
+// DiagnosticCodeRegistry. Its arguments are the source and the code of the
+// diagnostic; servers execute it by showing DiagnosticCodeRegistry.Explain,
+// e.g. with window/showMessage, or by opening the URL with
+// window/showDocument.
+const ExplainDiagnosticCommand = "diagnostic.explain"
+
+// DiagnosticCodeInfo documents a diagnostic code.
+type DiagnosticCodeInfo struct {
+	// Source is the source of the diagnostics with the code, like
+	// "go/parser". If empty, the code is documented for every source.
+	Source string
+
+	// Code is the diagnostic code, as returned by DiagnosticCode.String.
+	Code string
+
+	// Title is a one-line summary of the problem.
+	Title string
+
+	// Explanation describes the problem and how to fix it, in Markdown.
+	Explanation string
+
+	// URL is the documentation of the code. It becomes the CodeDescription
+	// of diagnostics with the code.
+	URL string
+}
+
+// DiagnosticCodeRegistry maps diagnostic codes to their documentation. Set
+// it as the Codes of a DiagnosticRegistry to fill in the CodeDescription
+// of diagnostics; as a CodeFixProvider it offers to explain documented
+// diagnostics. It is safe for concurrent use.
+type DiagnosticCodeRegistry struct {
+	mu    sync.RWMutex
+	codes map[diagnosticCodeKey]DiagnosticCodeInfo
+}
+
+type diagnosticCodeKey struct {
+	source string
+	code   string
+}
+
+// NewDiagnosticCodeRegistry creates a registry documenting the given codes.
+func NewDiagnosticCodeRegistry(codes ...DiagnosticCodeInfo) *DiagnosticCodeRegistry {
+	r := &DiagnosticCodeRegistry{codes: make(map[diagnosticCodeKey]DiagnosticCodeInfo)}
+	for _, info := range codes {
+		r.Register(info)
+	}
+	return r
+}
+
+// Register documents a code, replacing the documentation of the same code
+// and source.
+func (r *DiagnosticCodeRegistry) Register(info DiagnosticCodeInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codes[diagnosticCodeKey{info.Source, info.Code}] = info
+}
+
+// Lookup returns the documentation of a code from a source, falling back
+// to the documentation of the code for every source.
+func (r *DiagnosticCodeRegistry) Lookup(source, code string) (DiagnosticCodeInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if info, ok := r.codes[diagnosticCodeKey{source, code}]; ok {
+		return info, true
+	}
+	info, ok := r.codes[diagnosticCodeKey{"", code}]
+	return info, ok
+}
+
+// lookupDiagnostic returns the documentation of the code of a diagnostic.
+func (r *DiagnosticCodeRegistry) lookupDiagnostic(diagnostic Diagnostic) (DiagnosticCodeInfo, bool) {
+	if diagnostic.Code == nil {
+		return DiagnosticCodeInfo{}, false
+	}
+	return r.Lookup(diagnostic.Source, diagnostic.Code.String())
+}
+
+// Describe sets the CodeDescription of the diagnostics whose code has a
+// documentation URL, unless they have one.
+func (r *DiagnosticCodeRegistry) Describe(diagnostics []Diagnostic) {
+	for i := range diagnostics {
+		if diagnostics[i].CodeDescription != nil {
+			continue
+		}
+		if info, ok := r.lookupDiagnostic(diagnostics[i]); ok && info.URL != "" {
+			diagnostics[i].CodeDescription = &CodeDescription{HRef: info.URL}
+		}
+	}
+}
+
+// Explain returns the documentation of a code from a source as Markdown,
+// for hovers and for executing ExplainDiagnosticCommand.
+func (r *DiagnosticCodeRegistry) Explain(source, code string) (string, bool) {
+	info, ok := r.Lookup(source, code)
+	if !ok {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.WriteString("**" + info.Code + "**")
+	if info.Title != "" {
+		b.WriteString(": " + info.Title)
+	}
+	if info.Explanation != "" {
+		b.WriteString("\n\n" + info.Explanation)
+	}
+	if info.URL != "" {
+		b.WriteString("\n\n[Documentation](" + info.URL + ")")
+	}
+	return b.String(), true
+}
+
+// ProvideCodeFixes offers an "Explain" action running
+// ExplainDiagnosticCommand for each documented diagnostic in the context.
+func (r *DiagnosticCodeRegistry) ProvideCodeFixes(ctx CodeFixContext) []CodeAction {
+	var actions []CodeAction
+	for _, diagnostic := range ctx.Diagnostics {
+		info, ok := r.lookupDiagnostic(diagnostic)
+		if !ok {
+			continue
+		}
+		kind := CodeActionKindQuickFix
+		actions = append(actions, CodeAction{
+			Title:       "Explain " + info.Code,
+			Kind:        &kind,
+			Diagnostics: []Diagnostic{diagnostic},
+			Command: &Command{
+				Title:     "Explain " + info.Code,
+				Command:   ExplainDiagnosticCommand,
+				Arguments: []interface{}{diagnostic.Source, info.Code},
+			},
+		})
+	}
+	return actions
+}
+
+// DiagnosticHoverProvider explains the documented diagnostics at the hover
+// position.
+type DiagnosticHoverProvider struct {
+	Codes *DiagnosticCodeRegistry
+
+	// Diagnostics provides the diagnostics of documents, such as the
+	// DiagnosticRegistry publishing them.
+	Diagnostics DiagnosticProvider
+}
+
+// ProvideHover returns the explanations of the documented diagnostics
+// whose range contains position.
+func (p *DiagnosticHoverProvider) ProvideHover(uri, content string, position Position) *HoverInfo {
+	var explanations []string
+	var hoverRange *Range
+	for _, diagnostic := range p.Diagnostics.ProvideDiagnostics(uri, content) {
+		if !diagnostic.Range.Contains(position) || diagnostic.Code == nil {
+			continue
+		}
+		if explanation, ok := p.Codes.Explain(diagnostic.Source, diagnostic.Code.String()); ok {
+			explanations = append(explanations, explanation)
+			r := diagnostic.Range
+			hoverRange = &r
+		}
+	}
+	if len(explanations) == 0 {
+		return nil
+	}
+	return &HoverInfo{Contents: strings.Join(explanations, "\n\n---\n\n"), Range: hoverRange}
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiagnosticCodeRegistry(t *testing.T) {
+	codes := NewDiagnosticCodeRegistry(
+		DiagnosticCodeInfo{Code: "unused", Title: "Unused variable", URL: "https://example.com/unused"},
+		DiagnosticCodeInfo{Source: "vet", Code: "unused", Title: "Unused result"},
+	)
+
+	if info, ok := codes.Lookup("vet", "unused"); !ok || info.Title != "Unused result" {
+		t.Errorf("expected the source-specific documentation, got %+v", info)
+	}
+	if info, ok := codes.Lookup("compiler", "unused"); !ok || info.Title != "Unused variable" {
+		t.Errorf("expected the documentation for every source, got %+v", info)
+	}
+	if _, ok := codes.Lookup("compiler", "other"); ok {
+		t.Error("expected no documentation for an unknown code")
+	}
+
+	unused := NewStringCode("unused")
+	other := NewIntCode(42)
+	registry := NewDiagnosticRegistry()
+	registry.Codes = codes
+	registry.Register(fixedDiagnostics{
+		{Message: "x declared and not used", Source: "compiler", Code: &unused, Range: Range{End: Position{Character: 5}}},
+		{Message: "other", Code: &other},
+		{Message: "described", Code: &unused, CodeDescription: &CodeDescription{HRef: "https://example.com/own"}},
+	})
+
+	diagnostics := registry.ProvideDiagnostics("file:///main.go", "")
+	if d := diagnostics[0].CodeDescription; d == nil || d.HRef != "https://example.com/unused" {
+		t.Errorf("expected the documentation URL, got %+v", d)
+	}
+	if diagnostics[1].CodeDescription != nil {
+		t.Errorf("expected no description for an undocumented code, got %+v", diagnostics[1].CodeDescription)
+	}
+	if diagnostics[2].CodeDescription.HRef != "https://example.com/own" {
+		t.Errorf("expected an existing description to be kept, got %+v", diagnostics[2].CodeDescription)
+	}
+
+	actions := codes.ProvideCodeFixes(CodeFixContext{Diagnostics: diagnostics})
+	if len(actions) != 2 {
+		t.Fatalf("expected an action per documented diagnostic, got %+v", actions)
+	}
+	if command := actions[0].Command; command == nil || command.Command != ExplainDiagnosticCommand ||
+		command.Arguments[0] != "compiler" || command.Arguments[1] != "unused" {
+		t.Errorf("unexpected command %+v", actions[0].Command)
+	}
+
+	hover := (&DiagnosticHoverProvider{Codes: codes, Diagnostics: registry}).
+		ProvideHover("file:///main.go", "", Position{Character: 2})
+	if hover == nil || !strings.HasPrefix(hover.Contents, "**unused**: Unused variable") ||
+		!strings.Contains(hover.Contents, "(https://example.com/unused)") {
+		t.Errorf("unexpected hover %+v", hover)
+	}
+}
+
+type fixedDiagnostics []Diagnostic
+
+func (p fixedDiagnostics) ProvideDiagnostics(uri, content string) []Diagnostic {
+	return append([]Diagnostic(nil), p...)
+}
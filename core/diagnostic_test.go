@@ -11,3 +11,21 @@ func TestDiagnosticCodeString(t *testing.T) {
 		t.Fatalf("expected string diagnostic code to stringify as E_BAD, got %q", got)
 	}
 }
+
+func TestLinkDiagnostics(t *testing.T) {
+	redeclared := Diagnostic{Message: "x redeclared", Range: Range{Start: Position{Line: 5}, End: Position{Line: 5, Character: 1}}}
+	other := Diagnostic{Message: "other declaration of x", Range: Range{Start: Position{Line: 2}, End: Position{Line: 2, Character: 1}}}
+
+	LinkDiagnostics("file:///b.go", &redeclared, "file:///a.go", &other)
+
+	if len(redeclared.RelatedInformation) != 1 || len(other.RelatedInformation) != 1 {
+		t.Fatalf("expected one related information each, got %+v and %+v", redeclared.RelatedInformation, other.RelatedInformation)
+	}
+	if related := redeclared.RelatedInformation[0]; related.Location.URI != "file:///a.go" ||
+		related.Location.Range != other.Range || related.Message != "other declaration of x" {
+		t.Errorf("unexpected related information %+v", related)
+	}
+	if related := other.RelatedInformation[0]; related.Location.URI != "file:///b.go" || related.Message != "x redeclared" {
+		t.Errorf("unexpected related information %+v", related)
+	}
+}
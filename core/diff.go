@@ -0,0 +1,88 @@
+package core
+
+import "strings"
+
+// maxDiffCells bounds the work of DiffEdits: when the changed lines of both
+// versions would need a larger table, they are replaced in one edit.
+const maxDiffCells = 4 << 20
+
+// DiffEdits returns the edits turning before into after, replacing whole
+// lines. Lines that did not change are not touched, so the editor keeps
+// the cursor, selections, and markers on them, unlike with a single edit
+// replacing the document. It returns nil if the texts are equal.
+func DiffEdits(before, after string) []TextEdit {
+	if before == after {
+		return nil
+	}
+	a := strings.SplitAfter(before, "\n")
+	b := strings.SplitAfter(after, "\n")
+
+	// Byte offsets of the lines of before, and of its end
+	offsets := make([]int, len(a)+1)
+	for i, line := range a {
+		offsets[i+1] = offsets[i] + len(line)
+	}
+
+	// Common prefix and suffix
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a = a[prefix : len(a)-suffix]
+	b = b[prefix : len(b)-suffix]
+
+	edit := func(from, to int, lines []string) TextEdit {
+		return TextEdit{
+			Range: Range{
+				Start: ByteOffsetToPosition(before, offsets[prefix+from]),
+				End:   ByteOffsetToPosition(before, offsets[prefix+to]),
+			},
+			NewText: strings.Join(lines, ""),
+		}
+	}
+	if len(a) == 0 || len(b) == 0 || (len(a)+1)*(len(b)+1) > maxDiffCells {
+		return []TextEdit{edit(0, len(a), b)}
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	width := len(b) + 1
+	lcs := make([]int32, (len(a)+1)*width)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else {
+				lcs[i*width+j] = max(lcs[(i+1)*width+j], lcs[i*width+j+1])
+			}
+		}
+	}
+
+	// Walk the table, turning each run of changed lines into an edit
+	var edits []TextEdit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if i < len(a) && j < len(b) && a[i] == b[j] {
+			i++
+			j++
+			continue
+		}
+		fromA, fromB := i, j
+		for i < len(a) || j < len(b) {
+			if i < len(a) && j < len(b) && a[i] == b[j] {
+				break
+			}
+			if j == len(b) || (i < len(a) && lcs[(i+1)*width+j] >= lcs[i*width+j+1]) {
+				i++
+			} else {
+				j++
+			}
+		}
+		edits = append(edits, edit(fromA, i, b[fromB:j]))
+	}
+	return edits
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// applyEdits applies non-overlapping edits in document order.
+func applyEdits(t *testing.T, content string, edits []TextEdit) string {
+	t.Helper()
+	var builder strings.Builder
+	last := 0
+	for _, edit := range edits {
+		start := PositionToByteOffset(content, edit.Range.Start)
+		end := PositionToByteOffset(content, edit.Range.End)
+		if start < last || end < start {
+			t.Fatalf("edits overlap or are out of order: %+v", edits)
+		}
+		builder.WriteString(content[last:start])
+		builder.WriteString(edit.NewText)
+		last = end
+	}
+	builder.WriteString(content[last:])
+	return builder.String()
+}
+
+func TestDiffEdits(t *testing.T) {
+	tests := []struct {
+		name      string
+		before    string
+		after     string
+		wantEdits int
+	}{
+		{name: "equal", before: "a\nb\n", after: "a\nb\n", wantEdits: 0},
+		{name: "one line", before: "a\nb  \nc\n", after: "a\nb\nc\n", wantEdits: 1},
+		{name: "two hunks", before: "a \nb\nc\nd \n", after: "a\nb\nc\nd\n", wantEdits: 2},
+		{name: "insert", before: "a\nc\n", after: "a\nb\nc\n", wantEdits: 1},
+		{name: "delete", before: "a\nb\nc\n", after: "a\nc\n", wantEdits: 1},
+		{name: "final newline added", before: "a\nb", after: "a\nb\n", wantEdits: 1},
+		{name: "final newline removed", before: "a\nb\n", after: "a\nb", wantEdits: 1},
+		{name: "from empty", before: "", after: "a\n", wantEdits: 1},
+		{name: "to empty", before: "a\n", after: "", wantEdits: 1},
+		{name: "reordered", before: "import (\n\t\"os\"\n\t\"fmt\"\n)\n", after: "import (\n\t\"fmt\"\n\t\"os\"\n)\n", wantEdits: 2},
+		{name: "multibyte", before: "é = 1\nü  = 2\n", after: "é = 1\nü = 2\n", wantEdits: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits := DiffEdits(tt.before, tt.after)
+			if len(edits) != tt.wantEdits {
+				t.Errorf("got %d edits %+v, want %d", len(edits), edits, tt.wantEdits)
+			}
+			if got := applyEdits(t, tt.before, edits); got != tt.after {
+				t.Errorf("applying the edits gives %q, want %q", got, tt.after)
+			}
+		})
+	}
+}
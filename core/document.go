@@ -2,6 +2,8 @@ package core
 
 import (
 	"sync"
+
+	uripkg "github.com/SCKelemen/lsp/uri"
 )
 
 // Document represents a text document with its content and metadata.
@@ -29,6 +31,14 @@ func (d *Document) GetContent() string {
 	return d.Content
 }
 
+// GetContentAndVersion returns the current content of the document and
+// its version, read together.
+func (d *Document) GetContentAndVersion() (string, int) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.Content, d.Version
+}
+
 // SetContent updates the document content and increments the version.
 func (d *Document) SetContent(content string) {
 	d.mu.Lock()
@@ -60,9 +70,40 @@ func (d *Document) ApplyEdit(r Range, newText string) {
 
 // DocumentManager manages a collection of documents.
 // This is useful for LSP server implementations that need to track open documents.
+// Documents are looked up by normalized URI (see uri.Normalize), so different
+// spellings of the same URI find the same document.
 type DocumentManager struct {
 	documents map[string]*Document
 	mu        sync.RWMutex
+
+	watchers []func(DocumentEvent)
+}
+
+// DocumentEventKind is the kind of change reported to document watchers.
+type DocumentEventKind int
+
+const (
+	// DocumentOpened means the document was opened.
+	DocumentOpened DocumentEventKind = iota + 1
+
+	// DocumentChanged means the content of the document changed.
+	DocumentChanged
+
+	// DocumentClosed means the document was closed. Its content is the
+	// last content it had.
+	DocumentClosed
+)
+
+// DocumentEvent describes a change of a document in a DocumentManager.
+type DocumentEvent struct {
+	Kind DocumentEventKind
+
+	// URI is the URI the document was opened with.
+	URI string
+
+	// Content and Version are those of the document after the change.
+	Content string
+	Version int
 }
 
 // NewDocumentManager creates a new document manager.
@@ -74,11 +115,12 @@ func NewDocumentManager() *DocumentManager {
 
 // Open adds or updates a document in the manager.
 func (dm *DocumentManager) Open(uri, content string, version int) *Document {
+	doc := NewDocument(uri, content, version)
 	dm.mu.Lock()
-	defer dm.mu.Unlock()
+	dm.documents[uripkg.Normalize(uri)] = doc
+	dm.mu.Unlock()
 
-	doc := NewDocument(uri, content, version)
-	dm.documents[uri] = doc
+	dm.notify(DocumentOpened, doc)
 	return doc
 }
 
@@ -86,21 +128,34 @@ func (dm *DocumentManager) Open(uri, content string, version int) *Document {
 func (dm *DocumentManager) Get(uri string) (*Document, bool) {
 	dm.mu.RLock()
 	defer dm.mu.RUnlock()
-	doc, ok := dm.documents[uri]
+	doc, ok := dm.documents[uripkg.Normalize(uri)]
 	return doc, ok
 }
 
+// Len returns the number of open documents.
+func (dm *DocumentManager) Len() int {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return len(dm.documents)
+}
+
 // Close removes a document from the manager.
 func (dm *DocumentManager) Close(uri string) {
 	dm.mu.Lock()
-	defer dm.mu.Unlock()
-	delete(dm.documents, uri)
+	key := uripkg.Normalize(uri)
+	doc, ok := dm.documents[key]
+	delete(dm.documents, key)
+	dm.mu.Unlock()
+
+	if ok {
+		dm.notify(DocumentClosed, doc)
+	}
 }
 
 // Update updates a document's content.
 func (dm *DocumentManager) Update(uri, content string) bool {
 	dm.mu.RLock()
-	doc, ok := dm.documents[uri]
+	doc, ok := dm.documents[uripkg.Normalize(uri)]
 	dm.mu.RUnlock()
 
 	if !ok {
@@ -108,6 +163,7 @@ func (dm *DocumentManager) Update(uri, content string) bool {
 	}
 
 	doc.SetContent(content)
+	dm.notify(DocumentChanged, doc)
 	return true
 }
 
@@ -115,7 +171,7 @@ func (dm *DocumentManager) Update(uri, content string) bool {
 // Returns empty string if document not found.
 func (dm *DocumentManager) GetContent(uri string) string {
 	dm.mu.RLock()
-	doc, ok := dm.documents[uri]
+	doc, ok := dm.documents[uripkg.Normalize(uri)]
 	dm.mu.RUnlock()
 	if !ok {
 		return ""
@@ -131,9 +187,37 @@ func (dm *DocumentManager) ApplyEdit(uri string, r Range, newText string) bool {
 	}
 
 	doc.ApplyEdit(r, newText)
+	dm.notify(DocumentChanged, doc)
 	return true
 }
 
+// Watch registers fn to be called after a document is opened, changed
+// through the manager, or closed. fn is called synchronously, without the
+// manager's lock held, so it may call back into the manager. Changes made
+// directly on a Document are not reported.
+func (dm *DocumentManager) Watch(fn func(DocumentEvent)) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.watchers = append(dm.watchers, fn)
+}
+
+// notify reports a change of doc to the watchers.
+func (dm *DocumentManager) notify(kind DocumentEventKind, doc *Document) {
+	dm.mu.RLock()
+	watchers := dm.watchers
+	dm.mu.RUnlock()
+	if len(watchers) == 0 {
+		return
+	}
+
+	doc.mu.RLock()
+	event := DocumentEvent{Kind: kind, URI: doc.URI, Content: doc.Content, Version: doc.Version}
+	doc.mu.RUnlock()
+	for _, fn := range watchers {
+		fn(event)
+	}
+}
+
 func clampPosition(pos Position) Position {
 	if pos.Line < 0 {
 		pos.Line = 0
@@ -0,0 +1,293 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+// ErrSnapshotConflict is returned when checking or committing an edit
+// computed from a DocumentSnapshot whose documents changed since.
+var ErrSnapshotConflict = errors.New("documents changed since the snapshot")
+
+var errUncommittableEdit = errors.New("cannot commit an edit with resource operations or overlapping text edits")
+
+// DocumentSnapshot is a consistent view of documents of a DocumentManager,
+// pinned for the duration of an operation spanning several of them, like a
+// rename or a search for references. Edits arriving meanwhile change the
+// documents of the manager, not those of the snapshot, so the operation
+// never sees one document before an edit and another after it.
+//
+// Pinning is copy-on-write: content is immutable, so the snapshot shares
+// it with the documents until they change. It is safe for concurrent use.
+type DocumentSnapshot struct {
+	manager   *DocumentManager
+	documents map[string]pinnedDocument
+}
+
+// pinnedDocument is a document as a snapshot pinned it.
+type pinnedDocument struct {
+	document *Document
+	uri      string
+	content  string
+	version  int
+}
+
+// Snapshot pins the documents at uris, or every open document if there
+// are none. URIs of documents that are not open are left out.
+func (dm *DocumentManager) Snapshot(uris ...string) *DocumentSnapshot {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	var keys []string
+	if len(uris) == 0 {
+		for key := range dm.documents {
+			keys = append(keys, key)
+		}
+	}
+	for _, uri := range uris {
+		keys = append(keys, uripkg.Normalize(uri))
+	}
+
+	// Hold every document at once, so that the view is of one moment
+	documents, unlock := dm.lockDocuments(keys, false)
+	defer unlock()
+	snapshot := &DocumentSnapshot{manager: dm, documents: make(map[string]pinnedDocument, len(documents))}
+	for key, doc := range documents {
+		snapshot.documents[key] = pinnedDocument{document: doc, uri: doc.URI, content: doc.Content, version: doc.Version}
+	}
+	return snapshot
+}
+
+// lockDocuments locks the open documents at keys, for writing or reading,
+// and returns them by key with the function unlocking them. Documents are
+// locked in the order of their keys, so that operations locking several
+// never wait for each other in a cycle. The lock of the manager must be
+// held.
+func (dm *DocumentManager) lockDocuments(keys []string, write bool) (map[string]*Document, func()) {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+
+	documents := make(map[string]*Document, len(sorted))
+	var locked []*Document
+	for _, key := range sorted {
+		doc, ok := dm.documents[key]
+		if !ok || documents[key] != nil {
+			continue
+		}
+		if write {
+			doc.mu.Lock()
+		} else {
+			doc.mu.RLock()
+		}
+		documents[key] = doc
+		locked = append(locked, doc)
+	}
+	return documents, func() {
+		for _, doc := range locked {
+			if write {
+				doc.mu.Unlock()
+			} else {
+				doc.mu.RUnlock()
+			}
+		}
+	}
+}
+
+// URIs returns the URIs of the documents of the snapshot, sorted.
+func (s *DocumentSnapshot) URIs() []string {
+	uris := make([]string, 0, len(s.documents))
+	for _, pinned := range s.documents {
+		uris = append(uris, pinned.uri)
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+// Get returns a copy of a document as the snapshot pinned it.
+func (s *DocumentSnapshot) Get(uri string) (*Document, bool) {
+	pinned, ok := s.documents[uripkg.Normalize(uri)]
+	if !ok {
+		return nil, false
+	}
+	return NewDocument(pinned.uri, pinned.content, pinned.version), true
+}
+
+// GetContent returns the content of a document as the snapshot pinned it,
+// or an empty string if it did not. It can serve as the contentFor of
+// functions converting edits.
+func (s *DocumentSnapshot) GetContent(uri string) string {
+	return s.documents[uripkg.Normalize(uri)].content
+}
+
+// Changed returns the URIs of the documents of the snapshot that changed,
+// or were closed or reopened, in the manager since, sorted. Results
+// computed from the snapshot, like references, are stale if it is not
+// empty.
+func (s *DocumentSnapshot) Changed() []string {
+	keys := make([]string, 0, len(s.documents))
+	for key := range s.documents {
+		keys = append(keys, key)
+	}
+
+	s.manager.mu.RLock()
+	defer s.manager.mu.RUnlock()
+	documents, unlock := s.manager.lockDocuments(keys, false)
+	defer unlock()
+
+	var changed []string
+	for key, pinned := range s.documents {
+		if documents[key] != pinned.document || pinned.document.Version != pinned.version {
+			changed = append(changed, pinned.uri)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Check reports whether an edit computed from the snapshot can still be
+// applied: it returns an error wrapping ErrSnapshotConflict if a document
+// the edit changes changed in the manager since, or is open but was not
+// pinned by the snapshot, so that the edit was not computed from its
+// content. Documents that are not open, whose edits were computed from
+// disk, are not checked.
+//
+// Servers check the edit of an operation before sending it to the client,
+// e.g. with workspace/applyEdit, and recompute it on a conflict.
+func (s *DocumentSnapshot) Check(edit *WorkspaceEdit) error {
+	uris := editedURIs(edit)
+	s.manager.mu.RLock()
+	defer s.manager.mu.RUnlock()
+	documents, unlock := s.manager.lockDocuments(normalizedKeys(uris), false)
+	defer unlock()
+	return s.check(uris, documents)
+}
+
+// check returns the conflicts of the documents at uris, given the open
+// documents among them, by key, locked.
+func (s *DocumentSnapshot) check(uris []string, documents map[string]*Document) error {
+	var conflicts []string
+	for _, uri := range uris {
+		key := uripkg.Normalize(uri)
+		doc, open := documents[key]
+		pinned, ok := s.documents[key]
+		switch {
+		case !ok && open:
+			conflicts = append(conflicts, uri)
+		case ok && (doc != pinned.document || doc.Version != pinned.version):
+			conflicts = append(conflicts, uri)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%w: %s", ErrSnapshotConflict, strings.Join(conflicts, ", "))
+	}
+	return nil
+}
+
+// Commit applies an edit computed from the snapshot to the open documents
+// of the manager, if Check finds no conflict. The documents are changed
+// together: no change made through the manager comes between the check and
+// the edit, and none sees some of the documents edited and others not.
+// Watchers are notified of the changed documents afterwards.
+//
+// Commit is for tools whose manager is the record of the documents, like a
+// command-line refactoring. Language servers send edits to the client
+// instead, which applies them and reports the changes back.
+//
+// Edits with resource operations, like renaming a file, and edits of the
+// same document that overlap are rejected, and documents that are not open
+// are left alone.
+func (s *DocumentSnapshot) Commit(edit *WorkspaceEdit) error {
+	builder := NewWorkspaceEditBuilder()
+	if !builder.AddWorkspaceEdit(edit) {
+		return errUncommittableEdit
+	}
+	uris := editedURIs(edit)
+	for _, uri := range uris {
+		edits := builder.Edits(uri)
+		for i := 1; i < len(edits); i++ {
+			if RangesOverlap(edits[i-1].Range, edits[i].Range) {
+				return errUncommittableEdit
+			}
+		}
+	}
+
+	dm := s.manager
+	dm.mu.RLock()
+	documents, unlock := dm.lockDocuments(normalizedKeys(uris), true)
+	if err := s.check(uris, documents); err != nil {
+		unlock()
+		dm.mu.RUnlock()
+		return err
+	}
+	var edited []*Document
+	for _, uri := range uris {
+		if doc, ok := documents[uripkg.Normalize(uri)]; ok {
+			doc.Content = applyTextEdits(doc.Content, builder.Edits(uri))
+			doc.Version++
+			edited = append(edited, doc)
+		}
+	}
+	unlock()
+	dm.mu.RUnlock()
+
+	for _, doc := range edited {
+		dm.notify(DocumentChanged, doc)
+	}
+	return nil
+}
+
+// normalizedKeys returns the keys of the documents at uris.
+func normalizedKeys(uris []string) []string {
+	keys := make([]string, len(uris))
+	for i, uri := range uris {
+		keys[i] = uripkg.Normalize(uri)
+	}
+	return keys
+}
+
+// editedURIs returns the URIs of the documents whose text an edit
+// changes, sorted.
+func editedURIs(edit *WorkspaceEdit) []string {
+	if edit == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var uris []string
+	add := func(uri string) {
+		if !seen[uri] {
+			seen[uri] = true
+			uris = append(uris, uri)
+		}
+	}
+	for uri := range edit.Changes {
+		add(uri)
+	}
+	for _, change := range edit.DocumentChanges {
+		switch c := change.(type) {
+		case TextDocumentEdit:
+			add(c.TextDocument.URI)
+		case *TextDocumentEdit:
+			add(c.TextDocument.URI)
+		}
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+// applyTextEdits applies non-overlapping edits, in document order, to
+// content.
+func applyTextEdits(content string, edits []TextEdit) string {
+	for i := len(edits) - 1; i >= 0; i-- {
+		start := PositionToByteOffset(content, clampPosition(edits[i].Range.Start))
+		end := PositionToByteOffset(content, clampPosition(edits[i].Range.End))
+		if end < start {
+			continue
+		}
+		content = content[:start] + edits[i].NewText + content[end:]
+	}
+	return content
+}
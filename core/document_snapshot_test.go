@@ -0,0 +1,131 @@
+package core
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func renameFooEdit(uris ...string) *WorkspaceEdit {
+	edit := &WorkspaceEdit{Changes: make(map[string][]TextEdit)}
+	for _, uri := range uris {
+		edit.Changes[uri] = []TextEdit{{
+			Range:   Range{Start: Position{Line: 0, Character: 5}, End: Position{Line: 0, Character: 8}},
+			NewText: "bar",
+		}}
+	}
+	return edit
+}
+
+func TestDocumentSnapshotIsolation(t *testing.T) {
+	dm := NewDocumentManager()
+	dm.Open("file:///a.go", "func foo()", 1)
+	dm.Open("file:///b.go", "call foo()", 1)
+	dm.Open("file:///c.go", "other", 1)
+
+	snapshot := dm.Snapshot("file:///a.go", "file:///b.go", "file:///missing.go")
+	if uris := snapshot.URIs(); len(uris) != 2 || uris[0] != "file:///a.go" || uris[1] != "file:///b.go" {
+		t.Fatalf("expected the open documents asked for, got %v", uris)
+	}
+
+	dm.Update("file:///b.go", "call foo(1)")
+	if content := snapshot.GetContent("file:///b.go"); content != "call foo()" {
+		t.Errorf("expected the pinned content, got %q", content)
+	}
+	if doc, ok := snapshot.Get("file:///b.go"); !ok || doc.Version != 1 {
+		t.Errorf("expected the pinned version, got %+v", doc)
+	}
+	if changed := snapshot.Changed(); len(changed) != 1 || changed[0] != "file:///b.go" {
+		t.Errorf("expected b.go changed, got %v", changed)
+	}
+
+	dm.Close("file:///a.go")
+	if changed := snapshot.Changed(); len(changed) != 2 {
+		t.Errorf("expected a closed document to be changed, got %v", changed)
+	}
+
+	if all := dm.Snapshot(); len(all.URIs()) != 2 {
+		t.Errorf("expected every open document, got %v", all.URIs())
+	}
+}
+
+func TestDocumentSnapshotCheck(t *testing.T) {
+	dm := NewDocumentManager()
+	dm.Open("file:///a.go", "func foo()", 1)
+	dm.Open("file:///b.go", "call foo()", 1)
+	snapshot := dm.Snapshot("file:///a.go")
+
+	if err := snapshot.Check(renameFooEdit("file:///a.go", "file:///closed.go")); err != nil {
+		t.Errorf("expected no conflict, got %v", err)
+	}
+	if err := snapshot.Check(renameFooEdit("file:///b.go")); !errors.Is(err, ErrSnapshotConflict) {
+		t.Errorf("expected a conflict for an open document not pinned, got %v", err)
+	}
+
+	dm.ApplyEdit("file:///a.go", Range{End: Position{Character: 4}}, "fn")
+	err := snapshot.Check(renameFooEdit("file:///a.go"))
+	if !errors.Is(err, ErrSnapshotConflict) || err.Error() != "documents changed since the snapshot: file:///a.go" {
+		t.Errorf("expected a conflict for a changed document, got %v", err)
+	}
+}
+
+func TestDocumentSnapshotCommit(t *testing.T) {
+	dm := NewDocumentManager()
+	dm.Open("file:///a.go", "func foo()", 1)
+	dm.Open("file:///b.go", "call foo()", 1)
+	var events []DocumentEvent
+	dm.Watch(func(event DocumentEvent) { events = append(events, event) })
+
+	snapshot := dm.Snapshot("file:///a.go", "file:///b.go")
+	if err := snapshot.Commit(renameFooEdit("file:///a.go", "file:///b.go")); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := dm.GetContent("file:///a.go"), dm.GetContent("file:///b.go"); a != "func bar()" || b != "call bar()" {
+		t.Errorf("expected both documents renamed, got %q %q", a, b)
+	}
+	if len(events) != 2 || events[0].Version != 2 {
+		t.Errorf("expected the watchers notified of both documents, got %+v", events)
+	}
+
+	// The snapshot is now stale, including for its own commit
+	if err := snapshot.Commit(renameFooEdit("file:///a.go")); !errors.Is(err, ErrSnapshotConflict) {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+	if content := dm.GetContent("file:///a.go"); content != "func bar()" {
+		t.Errorf("expected a conflicting commit to change nothing, got %q", content)
+	}
+
+	overlapping := &WorkspaceEdit{Changes: map[string][]TextEdit{"file:///a.go": {
+		{Range: Range{End: Position{Character: 4}}, NewText: "fn"},
+		{Range: Range{Start: Position{Character: 2}, End: Position{Character: 6}}, NewText: "x"},
+	}}}
+	if err := dm.Snapshot().Commit(overlapping); err == nil {
+		t.Error("expected overlapping edits to be rejected")
+	}
+}
+
+func TestDocumentSnapshotConcurrentEdits(t *testing.T) {
+	dm := NewDocumentManager()
+	dm.Open("file:///a.go", "func foo()", 1)
+	dm.Open("file:///b.go", "call foo()", 1)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			dm.Update("file:///b.go", "call foo()")
+			dm.Update("file:///a.go", "func foo()")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			snapshot := dm.Snapshot()
+			if err := snapshot.Commit(renameFooEdit("file:///a.go", "file:///b.go")); err != nil && !errors.Is(err, ErrSnapshotConflict) {
+				t.Error(err)
+			}
+		}
+	}()
+	wg.Wait()
+}
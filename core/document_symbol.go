@@ -0,0 +1,155 @@
+package core
+
+import (
+	"sort"
+	"strings"
+)
+
+// DocumentSymbolProvider provides the outline of a document.
+//
+// Symbols form a hierarchy: the range of a symbol contains the ranges of
+// its children, and its selection range, typically the name. Top-level
+// symbols and the children of a symbol are returned in document order.
+// Ranges use UTF-8 byte offsets, as everywhere in core.
+type DocumentSymbolProvider interface {
+	// ProvideDocumentSymbols returns document symbols for the given document.
+	// Returns nil if the document has none or cannot be analyzed.
+	ProvideDocumentSymbols(uri, content string) []DocumentSymbol
+}
+
+// DocumentSymbolRegistry manages multiple document symbol providers, such
+// as one per language.
+type DocumentSymbolRegistry struct {
+	providers []DocumentSymbolProvider
+	selectors []DocumentSelector
+
+	// Languages resolves the language id of documents for selectors that
+	// filter by language. If nil, such filters match no document.
+	Languages *LanguageRegistry
+}
+
+// NewDocumentSymbolRegistry creates a new document symbol registry.
+func NewDocumentSymbolRegistry() *DocumentSymbolRegistry {
+	return &DocumentSymbolRegistry{
+		providers: make([]DocumentSymbolProvider, 0),
+	}
+}
+
+// Register adds a document symbol provider to the registry.
+func (r *DocumentSymbolRegistry) Register(provider DocumentSymbolProvider) {
+	r.RegisterFor(nil, provider)
+}
+
+// RegisterFor adds a document symbol provider that is only asked for
+// documents matching selector. A nil selector matches every document.
+func (r *DocumentSymbolRegistry) RegisterFor(selector DocumentSelector, provider DocumentSymbolProvider) {
+	r.providers = append(r.providers, provider)
+	r.selectors = append(r.selectors, selector)
+}
+
+// ProvideDocumentSymbols collects the symbols of all providers handling
+// the document. The top-level symbols of several providers are merged in
+// document order.
+func (r *DocumentSymbolRegistry) ProvideDocumentSymbols(uri, content string) []DocumentSymbol {
+	var symbols []DocumentSymbol
+	providers := 0
+	for i, provider := range r.providers {
+		if !selects(r.selectors[i], r.Languages, uri) {
+			continue
+		}
+		if provided := provider.ProvideDocumentSymbols(uri, content); len(provided) > 0 {
+			symbols = append(symbols, provided...)
+			providers++
+		}
+	}
+	if providers > 1 {
+		sort.SliceStable(symbols, func(i, j int) bool {
+			return ComparePositions(symbols[i].Range.Start, symbols[j].Range.Start) < 0
+		})
+	}
+	return symbols
+}
+
+// FlattenDocumentSymbols returns the symbols of a document and all their
+// descendants as a flat list, parents before their children, for clients
+// without hierarchical document symbol support. The container name of a
+// child is the name of its parent, and its location is its full range.
+func FlattenDocumentSymbols(uri string, symbols []DocumentSymbol) []WorkspaceSymbol {
+	var flat []WorkspaceSymbol
+	var walk func(symbols []DocumentSymbol, container string)
+	walk = func(symbols []DocumentSymbol, container string) {
+		for _, symbol := range symbols {
+			tags := symbol.Tags
+			if symbol.Deprecated && !hasSymbolTag(tags, SymbolTagDeprecated) {
+				tags = append(append([]SymbolTag(nil), tags...), SymbolTagDeprecated)
+			}
+			flat = append(flat, WorkspaceSymbol{
+				Name:          symbol.Name,
+				Kind:          symbol.Kind,
+				Tags:          tags,
+				ContainerName: container,
+				Location:      Location{URI: uri, Range: symbol.Range},
+			})
+			walk(symbol.Children, symbol.Name)
+		}
+	}
+	walk(symbols, "")
+	return flat
+}
+
+func hasSymbolTag(tags []SymbolTag, tag SymbolTag) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// SymbolPath returns the chain of symbols enclosing position, outermost
+// first, such as the package, type, and method a cursor is in, for
+// breadcrumbs. Returns nil if no symbol encloses position.
+func SymbolPath(symbols []DocumentSymbol, position Position) []DocumentSymbol {
+	var path []DocumentSymbol
+	for {
+		found := false
+		for _, symbol := range symbols {
+			if symbol.Range.Contains(position) {
+				path = append(path, symbol)
+				symbols = symbol.Children
+				found = true
+				break
+			}
+		}
+		if !found {
+			return path
+		}
+	}
+}
+
+// FormatSymbolPath joins the names of the symbols of a path, such as
+// "main › Server › Serve" with separator " › ".
+func FormatSymbolPath(path []DocumentSymbol, separator string) string {
+	names := make([]string, len(path))
+	for i, symbol := range path {
+		names[i] = symbol.Name
+	}
+	return strings.Join(names, separator)
+}
+
+// BreadcrumbProvider provides the symbol path at a position from the
+// symbols of a DocumentSymbolProvider, for breadcrumb UIs and for status
+// bar or hover integrations.
+type BreadcrumbProvider struct {
+	Symbols DocumentSymbolProvider
+}
+
+// ProvideBreadcrumbs returns the symbols enclosing position, outermost
+// first. The symbols are returned without their children.
+func (p *BreadcrumbProvider) ProvideBreadcrumbs(uri, content string, position Position) []DocumentSymbol {
+	path := SymbolPath(p.Symbols.ProvideDocumentSymbols(uri, content), position)
+	for i := range path {
+		path[i].Children = nil
+	}
+	return path
+}
@@ -0,0 +1,123 @@
+package core
+
+import "testing"
+
+type fixedDocumentSymbolProvider []DocumentSymbol
+
+func (p fixedDocumentSymbolProvider) ProvideDocumentSymbols(uri, content string) []DocumentSymbol {
+	return p
+}
+
+func symbolOnLine(name string, line int, children ...DocumentSymbol) DocumentSymbol {
+	r := Range{Start: Position{Line: line}, End: Position{Line: line + len(children), Character: 1}}
+	return DocumentSymbol{Name: name, Kind: SymbolKindFunction, Range: r, SelectionRange: r, Children: children}
+}
+
+func TestDocumentSymbolRegistry(t *testing.T) {
+	registry := NewDocumentSymbolRegistry()
+	registry.Languages = NewLanguageRegistry(Language{ID: "go", Extensions: []string{".go"}})
+	registry.RegisterFor(DocumentSelector{{Language: "go"}}, fixedDocumentSymbolProvider{symbolOnLine("b", 5), symbolOnLine("d", 9)})
+	registry.Register(fixedDocumentSymbolProvider{symbolOnLine("a", 1), symbolOnLine("c", 7)})
+
+	names := func(uri string) []string {
+		var result []string
+		for _, symbol := range registry.ProvideDocumentSymbols(uri, "") {
+			result = append(result, symbol.Name)
+		}
+		return result
+	}
+
+	if got := names("file:///main.go"); len(got) != 4 || got[0] != "a" || got[1] != "b" || got[2] != "c" || got[3] != "d" {
+		t.Errorf("expected merged symbols in document order, got %v", got)
+	}
+	if got := names("file:///README.md"); len(got) != 2 || got[0] != "a" {
+		t.Errorf("expected only the unrestricted provider for Markdown, got %v", got)
+	}
+}
+
+func TestFlattenDocumentSymbols(t *testing.T) {
+	method := symbolOnLine("Area", 3)
+	method.Deprecated = true
+	symbols := []DocumentSymbol{
+		symbolOnLine("Shape", 1, symbolOnLine("Kind", 2), method),
+		symbolOnLine("main", 6),
+	}
+
+	flat := FlattenDocumentSymbols("file:///shape.go", symbols)
+
+	expected := []struct {
+		name      string
+		container string
+	}{
+		{"Shape", ""},
+		{"Kind", "Shape"},
+		{"Area", "Shape"},
+		{"main", ""},
+	}
+	if len(flat) != len(expected) {
+		t.Fatalf("expected %d symbols, got %+v", len(expected), flat)
+	}
+	for i, e := range expected {
+		if flat[i].Name != e.name || flat[i].ContainerName != e.container {
+			t.Errorf("symbol %d = %s in %q, expected %s in %q", i, flat[i].Name, flat[i].ContainerName, e.name, e.container)
+		}
+		if flat[i].Location.URI != "file:///shape.go" {
+			t.Errorf("symbol %d has URI %s", i, flat[i].Location.URI)
+		}
+	}
+	if flat[0].Location.Range != symbols[0].Range {
+		t.Errorf("expected the location to be the full range, got %v", flat[0].Location.Range)
+	}
+	if len(flat[2].Tags) != 1 || flat[2].Tags[0] != SymbolTagDeprecated {
+		t.Errorf("expected a deprecated symbol to be tagged, got %v", flat[2].Tags)
+	}
+}
+
+func TestSymbolPath(t *testing.T) {
+	lines := func(start, end int) Range {
+		return Range{Start: Position{Line: start}, End: Position{Line: end, Character: 1}}
+	}
+	symbols := []DocumentSymbol{{
+		Name:  "main",
+		Kind:  SymbolKindPackage,
+		Range: lines(0, 20),
+		Children: []DocumentSymbol{
+			{Name: "Server", Kind: SymbolKindStruct, Range: lines(2, 10), Children: []DocumentSymbol{
+				{Name: "Addr", Kind: SymbolKindField, Range: lines(3, 3)},
+				{Name: "Serve", Kind: SymbolKindMethod, Range: lines(5, 9)},
+			}},
+			{Name: "run", Kind: SymbolKindFunction, Range: lines(12, 15)},
+		},
+	}}
+
+	tests := []struct {
+		position Position
+		expected string
+	}{
+		{Position{Line: 7, Character: 4}, "main › Server › Serve"},
+		{Position{Line: 4}, "main › Server"},
+		{Position{Line: 9, Character: 1}, "main › Server › Serve"},
+		{Position{Line: 13}, "main › run"},
+		{Position{Line: 11}, "main"},
+		{Position{Line: 30}, ""},
+	}
+	for _, tt := range tests {
+		if got := FormatSymbolPath(SymbolPath(symbols, tt.position), " › "); got != tt.expected {
+			t.Errorf("SymbolPath at %v = %q, expected %q", tt.position, got, tt.expected)
+		}
+	}
+
+	breadcrumbs := (&BreadcrumbProvider{Symbols: fixedDocumentSymbolProvider(symbols)}).
+		ProvideBreadcrumbs("file:///main.go", "", Position{Line: 7})
+	if len(breadcrumbs) != 3 || breadcrumbs[2].Kind != SymbolKindMethod {
+		t.Fatalf("unexpected breadcrumbs %+v", breadcrumbs)
+	}
+	for _, symbol := range breadcrumbs {
+		if symbol.Children != nil {
+			t.Errorf("expected %s without children", symbol.Name)
+		}
+	}
+	if len(symbols[0].Children) != 2 {
+		t.Error("expected the provider's symbols to be left alone")
+	}
+}
@@ -33,3 +33,53 @@ func TestDocumentManagerUpdateIncrementsVersion(t *testing.T) {
 		t.Fatalf("expected version 8, got %d", doc.Version)
 	}
 }
+
+func TestDocumentManagerNormalizesURIs(t *testing.T) {
+	dm := NewDocumentManager()
+	dm.Open("file:///c%3A/src/main.go", "package main", 1)
+
+	doc, ok := dm.Get("file:///C:/src/main.go")
+	if !ok {
+		t.Fatal("expected lookup with a different spelling of the URI to succeed")
+	}
+	if doc.URI != "file:///c%3A/src/main.go" {
+		t.Fatalf("expected document to keep the URI it was opened with, got %q", doc.URI)
+	}
+
+	dm.Close("file:///c:/src/main.go")
+	if _, ok := dm.Get("file:///c%3A/src/main.go"); ok {
+		t.Fatal("expected document to be closed")
+	}
+}
+
+func TestDocumentManagerWatch(t *testing.T) {
+	dm := NewDocumentManager()
+	var events []DocumentEvent
+	dm.Watch(func(event DocumentEvent) {
+		// Watchers may call back into the manager
+		dm.GetContent(event.URI)
+		events = append(events, event)
+	})
+
+	dm.Open("file:///test.txt", "a", 1)
+	dm.Update("file:///test.txt", "ab")
+	dm.ApplyEdit("file:///test.txt", Range{Start: Position{Character: 2}, End: Position{Character: 2}}, "c")
+	dm.Close("file:///test.txt")
+	dm.Close("file:///test.txt")
+	dm.Update("file:///test.txt", "ignored")
+
+	want := []DocumentEvent{
+		{Kind: DocumentOpened, URI: "file:///test.txt", Content: "a", Version: 1},
+		{Kind: DocumentChanged, URI: "file:///test.txt", Content: "ab", Version: 2},
+		{Kind: DocumentChanged, URI: "file:///test.txt", Content: "abc", Version: 3},
+		{Kind: DocumentClosed, URI: "file:///test.txt", Content: "abc", Version: 3},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got events %+v, want %+v", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, events[i], want[i])
+		}
+	}
+}
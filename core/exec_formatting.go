@@ -0,0 +1,135 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+// DefaultFormatterTimeout is how long ExecFormattingProvider waits for a
+// formatter by default.
+const DefaultFormatterTimeout = 10 * time.Second
+
+// ExecFormattingProvider formats documents with an external formatter, like
+// goimports, prettier, or black, that reads the document on stdin and
+// writes it formatted on stdout. The changed lines are returned as edits.
+//
+// Arguments may contain placeholders replaced for each document:
+//   - {path}: the path of the document, e.g. for prettier's --stdin-filepath
+//   - {dir}: the directory of the document
+//   - {tabSize}: the tab size of the formatting options
+//   - {insertSpaces}: "true" or "false" from the formatting options
+//
+// Formatters run code of the workspace, like configuration files and
+// plugins, so they only run when Trust trusts it.
+type ExecFormattingProvider struct {
+	// Command is the formatter, looked up in PATH if it has no directory.
+	Command string
+	Args    []string
+
+	// Dir is the working directory of the formatter. If empty, it runs in
+	// the directory of the document, where formatters look for their
+	// configuration.
+	Dir string
+
+	// Env are variables set for the formatter, as "KEY=value".
+	Env []string
+
+	// InheritEnv passes the environment of the server to the formatter,
+	// overridden by Env.
+	InheritEnv bool
+
+	// Timeout is how long the formatter may run, 0 for no limit.
+	Timeout time.Duration
+
+	// Trust, when set, must trust the workspace for the formatter to run.
+	Trust *WorkspaceTrust
+}
+
+// NewExecFormattingProvider creates a provider running command with args,
+// with the environment of the server and DefaultFormatterTimeout, e.g.
+//
+//	NewExecFormattingProvider("prettier", "--stdin-filepath", "{path}")
+func NewExecFormattingProvider(command string, args ...string) *ExecFormattingProvider {
+	return &ExecFormattingProvider{
+		Command:    command,
+		Args:       args,
+		InheritEnv: true,
+		Timeout:    DefaultFormatterTimeout,
+	}
+}
+
+// ProvideFormatting formats a document, returning no edits if the
+// formatter fails.
+func (p *ExecFormattingProvider) ProvideFormatting(uri, content string, options FormattingOptions) []TextEdit {
+	edits, err := p.Format(context.Background(), uri, content, options)
+	if err != nil {
+		return nil
+	}
+	return edits
+}
+
+// Format formats a document. Errors of the formatter include its output on
+// stderr, which usually explains them, like syntax errors.
+func (p *ExecFormattingProvider) Format(ctx context.Context, uri, content string, options FormattingOptions) ([]TextEdit, error) {
+	if err := p.Trust.Check(); err != nil {
+		return nil, err
+	}
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	path, _ := uripkg.ToPath(uri)
+	replacer := strings.NewReplacer(
+		"{path}", path,
+		"{dir}", filepath.Dir(path),
+		"{tabSize}", strconv.Itoa(options.TabSize),
+		"{insertSpaces}", strconv.FormatBool(options.InsertSpaces),
+	)
+	args := make([]string, len(p.Args))
+	for i, arg := range p.Args {
+		args[i] = replacer.Replace(arg)
+	}
+
+	command := exec.CommandContext(ctx, p.Command, args...)
+	command.Dir = p.Dir
+	if command.Dir == "" && path != "" {
+		command.Dir = filepath.Dir(path)
+		if _, err := os.Stat(command.Dir); err != nil {
+			// Unsaved documents may be in directories not created yet
+			command.Dir = ""
+		}
+	}
+	command.Env = p.Env
+	if p.InheritEnv {
+		command.Env = append(os.Environ(), p.Env...)
+	} else if command.Env == nil {
+		// A nil Env would inherit the environment
+		command.Env = []string{}
+	}
+	command.Stdin = strings.NewReader(content)
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+
+	if err := command.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("%s: timed out after %s", p.Command, p.Timeout)
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%s: %w: %s", p.Command, err, message)
+		}
+		return nil, fmt.Errorf("%s: %w", p.Command, err)
+	}
+	return DiffEdits(content, stdout.String()), nil
+}
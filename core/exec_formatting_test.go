@@ -0,0 +1,128 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestExecFormatterHelper is the formatter run by the tests, a copy of the
+// test binary. It is skipped when run as a test.
+func TestExecFormatterHelper(t *testing.T) {
+	if os.Getenv("CORE_FORMATTER_HELPER") != "1" {
+		t.Skip("run as a formatter")
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	input, _ := io.ReadAll(os.Stdin)
+	switch args[1] {
+	case "trim":
+		lines := strings.Split(string(input), "\n")
+		for i := range lines {
+			lines[i] = strings.TrimRight(lines[i], " ")
+		}
+		fmt.Print(strings.Join(lines, "\n"))
+	case "args":
+		fmt.Println(strings.Join(args[2:], " "))
+	case "env":
+		fmt.Println(os.Getenv("FORMAT_STYLE"))
+	case "fail":
+		fmt.Fprintln(os.Stderr, "1:3: syntax error")
+		os.Exit(2)
+	case "sleep":
+		time.Sleep(10 * time.Second)
+	}
+	os.Exit(0)
+}
+
+func helperFormatter(mode string, args ...string) *ExecFormattingProvider {
+	provider := NewExecFormattingProvider(os.Args[0], append([]string{"-test.run=TestExecFormatterHelper", "--", mode}, args...)...)
+	provider.Env = []string{"CORE_FORMATTER_HELPER=1"}
+	return provider
+}
+
+func TestExecFormattingProvider(t *testing.T) {
+	dir := t.TempDir()
+	uri := "file://" + dir + "/a.txt"
+	content := "a  \nb\nc \n"
+
+	t.Run("edits", func(t *testing.T) {
+		edits, err := helperFormatter("trim").Format(context.Background(), uri, content, FormattingOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(edits) != 2 {
+			t.Errorf("got %d edits, want 2 for the changed lines", len(edits))
+		}
+		if got := applyEdits(t, content, edits); got != "a\nb\nc\n" {
+			t.Errorf("formatted %q", got)
+		}
+	})
+
+	t.Run("placeholders", func(t *testing.T) {
+		edits, err := helperFormatter("args", "{path}", "{dir}", "{tabSize}", "{insertSpaces}").Format(context.Background(), uri, "", FormattingOptions{TabSize: 2, InsertSpaces: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := fmt.Sprintf("%s/a.txt %s 2 true\n", dir, dir)
+		if got := applyEdits(t, "", edits); got != want {
+			t.Errorf("got args %q, want %q", got, want)
+		}
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv("FORMAT_STYLE", "inherited")
+		provider := helperFormatter("env")
+		provider.Env = append(provider.Env, "FORMAT_STYLE=set")
+		edits, _ := provider.Format(context.Background(), uri, "", FormattingOptions{})
+		if got := applyEdits(t, "", edits); got != "set\n" {
+			t.Errorf("Env: got %q", got)
+		}
+
+		provider = helperFormatter("env")
+		provider.InheritEnv = false
+		edits, _ = provider.Format(context.Background(), uri, "", FormattingOptions{})
+		if got := applyEdits(t, "", edits); got != "\n" {
+			t.Errorf("not inherited: got %q", got)
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		provider := helperFormatter("fail")
+		_, err := provider.Format(context.Background(), uri, content, FormattingOptions{})
+		if err == nil || !strings.Contains(err.Error(), "syntax error") {
+			t.Errorf("err = %v, want the formatter's stderr", err)
+		}
+		if edits := provider.ProvideFormatting(uri, content, FormattingOptions{}); edits != nil {
+			t.Errorf("got edits %+v for a failed formatter", edits)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		provider := helperFormatter("sleep")
+		provider.Timeout = 50 * time.Millisecond
+		start := time.Now()
+		_, err := provider.Format(context.Background(), uri, content, FormattingOptions{})
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("err = %v, want a timeout", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("took %s", elapsed)
+		}
+	})
+
+	t.Run("untrusted", func(t *testing.T) {
+		provider := helperFormatter("trim")
+		provider.Trust = NewWorkspaceTrust(false)
+		if _, err := provider.Format(context.Background(), uri, content, FormattingOptions{}); !errors.Is(err, ErrUntrustedWorkspace) {
+			t.Errorf("err = %v, want ErrUntrustedWorkspace", err)
+		}
+	})
+}
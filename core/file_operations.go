@@ -0,0 +1,319 @@
+package core
+
+import "strings"
+
+// FileCreate describes a file or folder created by the user in the editor.
+type FileCreate struct {
+	// URI is the location of the created file or folder.
+	URI string
+}
+
+// FileRename describes a file or folder renamed or moved by the user in the
+// editor. When a folder is renamed, only the folder is reported, not its
+// children.
+type FileRename struct {
+	// OldURI is the original location of the file or folder.
+	OldURI string
+
+	// NewURI is the new location of the file or folder.
+	NewURI string
+}
+
+// FileDelete describes a file or folder deleted by the user in the editor.
+type FileDelete struct {
+	// URI is the location of the deleted file or folder.
+	URI string
+}
+
+// FileChangeType is the kind of change of a watched file.
+type FileChangeType int
+
+const (
+	// FileChangeTypeCreated means the file was created.
+	FileChangeTypeCreated FileChangeType = 1
+
+	// FileChangeTypeChanged means the file was changed.
+	FileChangeTypeChanged FileChangeType = 2
+
+	// FileChangeTypeDeleted means the file was deleted.
+	FileChangeTypeDeleted FileChangeType = 3
+)
+
+// FileEvent describes a change of a watched file, reported by the client's
+// file watcher with workspace/didChangeWatchedFiles. Unlike the file
+// operations, these include changes made outside the editor, such as by
+// git or a code generator.
+type FileEvent struct {
+	// URI is the location of the file.
+	URI string
+
+	// Type is the kind of change.
+	Type FileChangeType
+}
+
+// FileOperationPatternKind restricts a file operation filter to files or
+// folders.
+type FileOperationPatternKind string
+
+const (
+	// FileOperationPatternKindFile matches files only.
+	FileOperationPatternKindFile FileOperationPatternKind = "file"
+
+	// FileOperationPatternKindFolder matches folders only.
+	FileOperationPatternKindFolder FileOperationPatternKind = "folder"
+)
+
+// FileOperationFilter describes the files a server wants to hear about.
+// Clients only send file operations matching one of the server's filters.
+//
+// LSP Specification: https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#fileOperationFilter
+type FileOperationFilter struct {
+	// Scheme is a URI scheme, like "file". Empty matches any scheme.
+	Scheme string
+
+	// Glob is a glob pattern matched against the path, like "**/*.go".
+	// See MatchGlob for the syntax. Empty matches any path.
+	Glob string
+
+	// Matches restricts the filter to files or folders. Empty matches both.
+	Matches FileOperationPatternKind
+
+	// IgnoreCase matches the glob pattern ignoring case.
+	IgnoreCase bool
+}
+
+// MatchesURI returns true if the URI matches the filter's scheme and glob.
+// Matches is not checked: whether a URI is a folder is known to the client,
+// which applies it before sending the operation.
+func (f FileOperationFilter) MatchesURI(uri string) bool {
+	scheme, filePath := splitURI(uri)
+	if f.Scheme != "" && !strings.EqualFold(f.Scheme, scheme) {
+		return false
+	}
+	if f.Glob == "" {
+		return true
+	}
+	if f.IgnoreCase {
+		return MatchGlob(strings.ToLower(f.Glob), strings.ToLower(filePath))
+	}
+	return MatchGlob(f.Glob, filePath)
+}
+
+// FileOperation identifies one of the workspace file operation requests and
+// notifications.
+type FileOperation int
+
+const (
+	FileOperationWillCreate FileOperation = iota
+	FileOperationDidCreate
+	FileOperationWillRename
+	FileOperationDidRename
+	FileOperationWillDelete
+	FileOperationDidDelete
+)
+
+// handledBy reports whether handler implements the interface for op.
+func (op FileOperation) handledBy(handler any) bool {
+	var ok bool
+	switch op {
+	case FileOperationWillCreate:
+		_, ok = handler.(WillCreateFilesProvider)
+	case FileOperationDidCreate:
+		_, ok = handler.(DidCreateFilesHandler)
+	case FileOperationWillRename:
+		_, ok = handler.(WillRenameFilesProvider)
+	case FileOperationDidRename:
+		_, ok = handler.(DidRenameFilesHandler)
+	case FileOperationWillDelete:
+		_, ok = handler.(WillDeleteFilesProvider)
+	case FileOperationDidDelete:
+		_, ok = handler.(DidDeleteFilesHandler)
+	}
+	return ok
+}
+
+// WillCreateFilesProvider returns edits to apply before files are created,
+// e.g. to fill a new file with a package clause.
+type WillCreateFilesProvider interface {
+	// WillCreateFiles returns a workspace edit for the created files.
+	// Returns nil if no edits are needed.
+	WillCreateFiles(files []FileCreate) *WorkspaceEdit
+}
+
+// WillRenameFilesProvider returns edits to apply before files are renamed,
+// e.g. to fix imports of a moved file.
+type WillRenameFilesProvider interface {
+	// WillRenameFiles returns a workspace edit for the renamed files.
+	// Returns nil if no edits are needed.
+	WillRenameFiles(files []FileRename) *WorkspaceEdit
+}
+
+// WillDeleteFilesProvider returns edits to apply before files are deleted,
+// e.g. to remove references to a deleted file.
+type WillDeleteFilesProvider interface {
+	// WillDeleteFiles returns a workspace edit for the deleted files.
+	// Returns nil if no edits are needed.
+	WillDeleteFiles(files []FileDelete) *WorkspaceEdit
+}
+
+// DidCreateFilesHandler is notified after files were created, e.g. to add
+// them to an index.
+type DidCreateFilesHandler interface {
+	DidCreateFiles(files []FileCreate)
+}
+
+// DidRenameFilesHandler is notified after files were renamed.
+type DidRenameFilesHandler interface {
+	DidRenameFiles(files []FileRename)
+}
+
+// DidDeleteFilesHandler is notified after files were deleted.
+type DidDeleteFilesHandler interface {
+	DidDeleteFiles(files []FileDelete)
+}
+
+// FileOperationRegistry routes workspace file operations to the providers
+// and handlers interested in them.
+type FileOperationRegistry struct {
+	handlers []any
+	filters  [][]FileOperationFilter
+}
+
+// NewFileOperationRegistry creates a new file operation registry.
+func NewFileOperationRegistry() *FileOperationRegistry {
+	return &FileOperationRegistry{}
+}
+
+// Register adds a handler implementing any of the Will*FilesProvider and
+// Did*FilesHandler interfaces. It only receives files matching one of
+// filters; nil filters match every file.
+func (r *FileOperationRegistry) Register(filters []FileOperationFilter, handler any) {
+	r.handlers = append(r.handlers, handler)
+	r.filters = append(r.filters, filters)
+}
+
+// WillCreateFiles collects and merges the edits of all providers.
+func (r *FileOperationRegistry) WillCreateFiles(files []FileCreate) *WorkspaceEdit {
+	var edits []*WorkspaceEdit
+	for i, handler := range r.handlers {
+		if provider, ok := handler.(WillCreateFilesProvider); ok {
+			if matched := filterFiles(files, r.filters[i], fileCreateURIs); len(matched) > 0 {
+				edits = append(edits, provider.WillCreateFiles(matched))
+			}
+		}
+	}
+	return MergeWorkspaceEdits(edits...)
+}
+
+// WillRenameFiles collects and merges the edits of all providers.
+// A rename matches a filter if its old or its new URI does.
+func (r *FileOperationRegistry) WillRenameFiles(files []FileRename) *WorkspaceEdit {
+	var edits []*WorkspaceEdit
+	for i, handler := range r.handlers {
+		if provider, ok := handler.(WillRenameFilesProvider); ok {
+			if matched := filterFiles(files, r.filters[i], fileRenameURIs); len(matched) > 0 {
+				edits = append(edits, provider.WillRenameFiles(matched))
+			}
+		}
+	}
+	return MergeWorkspaceEdits(edits...)
+}
+
+// WillDeleteFiles collects and merges the edits of all providers.
+func (r *FileOperationRegistry) WillDeleteFiles(files []FileDelete) *WorkspaceEdit {
+	var edits []*WorkspaceEdit
+	for i, handler := range r.handlers {
+		if provider, ok := handler.(WillDeleteFilesProvider); ok {
+			if matched := filterFiles(files, r.filters[i], fileDeleteURIs); len(matched) > 0 {
+				edits = append(edits, provider.WillDeleteFiles(matched))
+			}
+		}
+	}
+	return MergeWorkspaceEdits(edits...)
+}
+
+// DidCreateFiles notifies all handlers.
+func (r *FileOperationRegistry) DidCreateFiles(files []FileCreate) {
+	for i, handler := range r.handlers {
+		if h, ok := handler.(DidCreateFilesHandler); ok {
+			if matched := filterFiles(files, r.filters[i], fileCreateURIs); len(matched) > 0 {
+				h.DidCreateFiles(matched)
+			}
+		}
+	}
+}
+
+// DidRenameFiles notifies all handlers.
+func (r *FileOperationRegistry) DidRenameFiles(files []FileRename) {
+	for i, handler := range r.handlers {
+		if h, ok := handler.(DidRenameFilesHandler); ok {
+			if matched := filterFiles(files, r.filters[i], fileRenameURIs); len(matched) > 0 {
+				h.DidRenameFiles(matched)
+			}
+		}
+	}
+}
+
+// DidDeleteFiles notifies all handlers.
+func (r *FileOperationRegistry) DidDeleteFiles(files []FileDelete) {
+	for i, handler := range r.handlers {
+		if h, ok := handler.(DidDeleteFilesHandler); ok {
+			if matched := filterFiles(files, r.filters[i], fileDeleteURIs); len(matched) > 0 {
+				h.DidDeleteFiles(matched)
+			}
+		}
+	}
+}
+
+// Filters returns the filters to announce in the server capabilities for
+// op. A handler registered with nil filters contributes a filter matching
+// every file. Returns nil if no handler handles op.
+func (r *FileOperationRegistry) Filters(op FileOperation) []FileOperationFilter {
+	var result []FileOperationFilter
+	for i, handler := range r.handlers {
+		if !op.handledBy(handler) {
+			continue
+		}
+		if r.filters[i] == nil {
+			result = append(result, FileOperationFilter{Glob: "**/*"})
+		} else {
+			result = append(result, r.filters[i]...)
+		}
+	}
+	return result
+}
+
+// Handles reports whether any registered handler handles op.
+func (r *FileOperationRegistry) Handles(op FileOperation) bool {
+	for _, handler := range r.handlers {
+		if op.handledBy(handler) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterFiles returns the files with a URI matching any of filters.
+// nil filters match every file.
+func filterFiles[T any](files []T, filters []FileOperationFilter, uris func(T) []string) []T {
+	if filters == nil {
+		return files
+	}
+	var matched []T
+	for _, file := range files {
+	filterLoop:
+		for _, filter := range filters {
+			for _, uri := range uris(file) {
+				if filter.MatchesURI(uri) {
+					matched = append(matched, file)
+					break filterLoop
+				}
+			}
+		}
+	}
+	return matched
+}
+
+func fileCreateURIs(f FileCreate) []string { return []string{f.URI} }
+func fileRenameURIs(f FileRename) []string { return []string{f.OldURI, f.NewURI} }
+func fileDeleteURIs(f FileDelete) []string { return []string{f.URI} }
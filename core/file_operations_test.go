@@ -0,0 +1,117 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+type importFixer struct {
+	renamed []FileRename
+	deleted []FileDelete
+}
+
+func (f *importFixer) WillRenameFiles(files []FileRename) *WorkspaceEdit {
+	f.renamed = append(f.renamed, files...)
+	return &WorkspaceEdit{
+		Changes: map[string][]TextEdit{
+			"file:///ws/main.go": {{NewText: "\"example.com/" + files[0].NewURI + "\""}},
+		},
+	}
+}
+
+func (f *importFixer) DidDeleteFiles(files []FileDelete) {
+	f.deleted = append(f.deleted, files...)
+}
+
+type headerWriter struct{}
+
+func (headerWriter) WillRenameFiles(files []FileRename) *WorkspaceEdit {
+	return &WorkspaceEdit{
+		Changes: map[string][]TextEdit{
+			"file:///ws/main.go": {{NewText: "// header"}},
+		},
+		ChangeAnnotations: map[string]ChangeAnnotation{"header": {Label: "Header"}},
+	}
+}
+
+func TestFileOperationFilterMatchesURI(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter FileOperationFilter
+		uri    string
+		want   bool
+	}{
+		{"empty filter", FileOperationFilter{}, "file:///ws/a.go", true},
+		{"glob", FileOperationFilter{Glob: "**/*.go"}, "file:///ws/pkg/a.go", true},
+		{"glob mismatch", FileOperationFilter{Glob: "**/*.go"}, "file:///ws/a.txt", false},
+		{"scheme", FileOperationFilter{Scheme: "file"}, "untitled:a.go", false},
+		{"case sensitive", FileOperationFilter{Glob: "**/*.go"}, "file:///ws/A.GO", false},
+		{"ignore case", FileOperationFilter{Glob: "**/*.go", IgnoreCase: true}, "file:///ws/A.GO", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.MatchesURI(tt.uri); got != tt.want {
+				t.Errorf("MatchesURI(%q) = %v, want %v", tt.uri, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileOperationRegistry(t *testing.T) {
+	fixer := &importFixer{}
+	registry := NewFileOperationRegistry()
+	registry.Register([]FileOperationFilter{{Glob: "**/*.go"}}, fixer)
+	registry.Register(nil, headerWriter{})
+
+	renames := []FileRename{
+		{OldURI: "file:///ws/a.go", NewURI: "file:///ws/b.go"},
+		{OldURI: "file:///ws/a.txt", NewURI: "file:///ws/b.txt"},
+	}
+	edit := registry.WillRenameFiles(renames)
+	if edit == nil {
+		t.Fatal("expected a merged edit")
+	}
+	if len(fixer.renamed) != 1 || fixer.renamed[0] != renames[0] {
+		t.Errorf("provider got %v, want only the .go rename", fixer.renamed)
+	}
+	if got := len(edit.Changes["file:///ws/main.go"]); got != 2 {
+		t.Errorf("expected edits of both providers to be merged, got %d", got)
+	}
+	if _, ok := edit.ChangeAnnotations["header"]; !ok {
+		t.Error("expected change annotations to be merged")
+	}
+
+	if edit := registry.WillCreateFiles([]FileCreate{{URI: "file:///ws/c.go"}}); edit != nil {
+		t.Errorf("expected no edit without create providers, got %+v", edit)
+	}
+
+	registry.DidDeleteFiles([]FileDelete{{URI: "file:///ws/a.txt"}, {URI: "file:///ws/c.go"}})
+	if want := []FileDelete{{URI: "file:///ws/c.go"}}; !reflect.DeepEqual(fixer.deleted, want) {
+		t.Errorf("handler got %v, want %v", fixer.deleted, want)
+	}
+
+	if !registry.Handles(FileOperationWillRename) || registry.Handles(FileOperationDidRename) {
+		t.Error("Handles reported the wrong operations")
+	}
+	wantFilters := []FileOperationFilter{{Glob: "**/*.go"}, {Glob: "**/*"}}
+	if got := registry.Filters(FileOperationWillRename); !reflect.DeepEqual(got, wantFilters) {
+		t.Errorf("Filters() = %v, want %v", got, wantFilters)
+	}
+	if got := registry.Filters(FileOperationWillDelete); got != nil {
+		t.Errorf("expected no filters for unhandled operation, got %v", got)
+	}
+}
+
+func TestMergeWorkspaceEdits(t *testing.T) {
+	if MergeWorkspaceEdits() != nil || MergeWorkspaceEdits(nil, &WorkspaceEdit{}) != nil {
+		t.Error("expected nil for empty edits")
+	}
+
+	merged := MergeWorkspaceEdits(
+		&WorkspaceEdit{DocumentChanges: []interface{}{CreateFile{URI: "file:///a"}}},
+		&WorkspaceEdit{DocumentChanges: []interface{}{DeleteFile{URI: "file:///b"}}},
+	)
+	if len(merged.DocumentChanges) != 2 {
+		t.Errorf("expected document changes in order, got %v", merged.DocumentChanges)
+	}
+}
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// FixAllSettings configure a FixAllProvider, e.g. from the configuration
+// of the server.
+type FixAllSettings struct {
+	// Rules are the diagnostics fixed, by code, like "no-println", or by
+	// source, like "rules". Fixing is opt-in: the diagnostics of rules
+	// not set to true are left alone.
+	Rules map[string]bool `json:"rules,omitempty"`
+
+	// OnSave makes WillSaveWaitUntil fix the document when it is saved.
+	OnSave bool `json:"onSave,omitempty"`
+}
+
+// enabled reports whether the fixes of a diagnostic are opted in.
+func (s *FixAllSettings) enabled(diagnostic Diagnostic) bool {
+	if diagnostic.Code != nil && s.Rules[diagnostic.Code.String()] {
+		return true
+	}
+	return diagnostic.Source != "" && s.Rules[diagnostic.Source]
+}
+
+// FixAllProvider implements source.fixAll: it applies the fixes of every
+// auto-fixable diagnostic of a document at once, as a code action and on
+// save.
+//
+// A diagnostic is auto-fixable when Fixes offers a quick fix for it that
+// edits without running a command, is not disabled, and is either
+// preferred or its only fix. The fixes are composed with a
+// WorkspaceEditBuilder: a fix conflicting with one composed before is left
+// out, to be applied by the next fix all once the others are, and so are
+// fixes editing other documents.
+type FixAllProvider struct {
+	// Diagnostics finds the diagnostics to fix.
+	Diagnostics DiagnosticProvider
+
+	// Fixes provides their quick fixes.
+	Fixes CodeFixProvider
+
+	settings atomic.Pointer[FixAllSettings]
+}
+
+// NewFixAllProvider creates a provider fixing the diagnostics of
+// diagnostics with the quick fixes of fixes, for the rules opted in with
+// settings.
+func NewFixAllProvider(diagnostics DiagnosticProvider, fixes CodeFixProvider, settings FixAllSettings) *FixAllProvider {
+	p := &FixAllProvider{Diagnostics: diagnostics, Fixes: fixes}
+	p.SetSettings(settings)
+	return p
+}
+
+// Settings returns the settings of the provider.
+func (p *FixAllProvider) Settings() FixAllSettings {
+	if settings := p.settings.Load(); settings != nil {
+		return *settings
+	}
+	return FixAllSettings{}
+}
+
+// SetSettings replaces the settings of the provider, e.g. when the
+// configuration changes.
+func (p *FixAllProvider) SetSettings(settings FixAllSettings) {
+	p.settings.Store(&settings)
+}
+
+// FixAll returns the edit fixing the auto-fixable diagnostics of a
+// document that are opted in, or nil if there are none.
+func (p *FixAllProvider) FixAll(uri, content string) *WorkspaceEdit {
+	settings := p.Settings()
+	var diagnostics []Diagnostic
+	for _, diagnostic := range p.Diagnostics.ProvideDiagnostics(uri, content) {
+		if settings.enabled(diagnostic) {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	if len(diagnostics) == 0 {
+		return nil
+	}
+
+	actions := p.Fixes.ProvideCodeFixes(CodeFixContext{
+		URI:         uri,
+		Content:     content,
+		Range:       Range{End: ByteOffsetToPosition(content, len(content))},
+		Diagnostics: diagnostics,
+		Only:        []CodeActionKind{CodeActionKindQuickFix},
+		TriggerKind: CodeActionTriggerKindAutomatic,
+	})
+
+	// Count the fixes of each diagnostic, for diagnostics whose only fix
+	// is not marked preferred
+	fixes := make(map[diagnosticKey]int)
+	for _, action := range actions {
+		if autoFix(action) {
+			for _, diagnostic := range action.Diagnostics {
+				fixes[keyOfDiagnostic(diagnostic)]++
+			}
+		}
+	}
+
+	builder := NewWorkspaceEditBuilder()
+	fixed := make(map[diagnosticKey]bool)
+	for _, action := range actions {
+		if !autoFix(action) || len(action.Diagnostics) == 0 || !editsOnly(action.Edit, uri) {
+			continue
+		}
+		key := keyOfDiagnostic(action.Diagnostics[0])
+		if fixed[key] || (!action.IsPreferred && fixes[key] > 1) {
+			continue
+		}
+		if builder.AddWorkspaceEdit(action.Edit) {
+			for _, diagnostic := range action.Diagnostics {
+				fixed[keyOfDiagnostic(diagnostic)] = true
+			}
+		}
+	}
+	return builder.Build()
+}
+
+// ([CodeFixProvider] interface)
+func (p *FixAllProvider) ProvideCodeFixes(ctx CodeFixContext) []CodeAction {
+	if !fixAllRequested(ctx.Only) {
+		return nil
+	}
+	edit := p.FixAll(ctx.URI, ctx.Content)
+	if edit == nil {
+		return nil
+	}
+	kind := CodeActionKindSourceFixAll
+	return []CodeAction{{
+		Title: "Fix all auto-fixable problems",
+		Kind:  &kind,
+		Edit:  edit,
+	}}
+}
+
+// WillSaveWaitUntil returns the edits fixing a document about to be
+// saved, for the textDocument/willSaveWaitUntil request, if OnSave is set.
+func (p *FixAllProvider) WillSaveWaitUntil(uri, content string) []TextEdit {
+	if !p.Settings().OnSave {
+		return nil
+	}
+	edit := p.FixAll(uri, content)
+	if edit == nil {
+		return nil
+	}
+	return edit.Changes[uri]
+}
+
+// autoFix reports whether an action can be applied without the user: an
+// enabled quick fix editing without a command.
+func autoFix(action CodeAction) bool {
+	return isQuickFix(action) && action.Disabled == nil && action.Edit != nil && action.Command == nil
+}
+
+// editsOnly reports whether a workspace edit only edits the text of the
+// document uri.
+func editsOnly(edit *WorkspaceEdit, uri string) bool {
+	for changed := range edit.Changes {
+		if changed != uri {
+			return false
+		}
+	}
+	for _, change := range edit.DocumentChanges {
+		switch c := change.(type) {
+		case TextDocumentEdit:
+			if c.TextDocument.URI != uri {
+				return false
+			}
+		case *TextDocumentEdit:
+			if c.TextDocument.URI != uri {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// fixAllRequested reports whether source.fixAll actions were requested:
+// if only is empty, or names it or a parent of it.
+func fixAllRequested(only []CodeActionKind) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, requested := range only {
+		kind := string(CodeActionKindSourceFixAll)
+		if string(requested) == kind || strings.HasPrefix(kind, string(requested)+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// wordProblems reports each word of a document as a problem coded by the
+// word, with fixes upper-casing it.
+type wordProblems struct {
+	// fixes returns the fixes of the problem of a word
+	fixes func(word string, diagnostic Diagnostic, edit TextEdit) []CodeAction
+}
+
+func (p *wordProblems) ProvideDiagnostics(uri, content string) []Diagnostic {
+	var diagnostics []Diagnostic
+	offset := 0
+	for _, word := range strings.Fields(content) {
+		start := strings.Index(content[offset:], word) + offset
+		offset = start + len(word)
+		code := NewStringCode(word)
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:   Range{Start: ByteOffsetToPosition(content, start), End: ByteOffsetToPosition(content, offset)},
+			Code:    &code,
+			Source:  "words",
+			Message: word,
+		})
+	}
+	return diagnostics
+}
+
+func (p *wordProblems) ProvideCodeFixes(ctx CodeFixContext) []CodeAction {
+	var actions []CodeAction
+	for _, diagnostic := range ctx.Diagnostics {
+		word := diagnostic.Code.String()
+		edit := TextEdit{Range: diagnostic.Range, NewText: strings.ToUpper(word)}
+		actions = append(actions, p.fixes(word, diagnostic, edit)...)
+	}
+	return actions
+}
+
+func TestFixAllProvider(t *testing.T) {
+	quickFix := CodeActionKindQuickFix
+	fix := func(uri string, diagnostic Diagnostic, preferred bool, edits ...TextEdit) CodeAction {
+		return CodeAction{
+			Title:       "Fix",
+			Kind:        &quickFix,
+			Diagnostics: []Diagnostic{diagnostic},
+			IsPreferred: preferred,
+			Edit:        &WorkspaceEdit{Changes: map[string][]TextEdit{uri: edits}},
+		}
+	}
+	problems := &wordProblems{fixes: func(word string, diagnostic Diagnostic, edit TextEdit) []CodeAction {
+		switch word {
+		case "preferred", "opted":
+			return []CodeAction{fix("file:///a.txt", diagnostic, false, TextEdit{Range: edit.Range, NewText: "?"}), fix("file:///a.txt", diagnostic, true, edit)}
+		case "only":
+			return []CodeAction{fix("file:///a.txt", diagnostic, false, edit)}
+		case "ambiguous":
+			return []CodeAction{fix("file:///a.txt", diagnostic, false, edit), fix("file:///a.txt", diagnostic, false, edit)}
+		case "command":
+			action := fix("file:///a.txt", diagnostic, true, edit)
+			action.Command = &Command{Title: "Then", Command: "then"}
+			return []CodeAction{action}
+		case "conflicting":
+			// Also edits the start of the document, which the fix of
+			// preferred does
+			return []CodeAction{fix("file:///a.txt", diagnostic, true, edit, TextEdit{Range: Range{End: Position{Character: 3}}, NewText: "x"})}
+		case "elsewhere":
+			return []CodeAction{fix("file:///b.txt", diagnostic, true, edit)}
+		}
+		return nil
+	}}
+	settings := FixAllSettings{Rules: map[string]bool{"preferred": true, "only": true, "ambiguous": true, "command": true, "conflicting": true, "elsewhere": true}}
+	provider := NewFixAllProvider(problems, problems, settings)
+
+	uri := "file:///a.txt"
+	content := "preferred only ambiguous command conflicting elsewhere opted"
+	edit := provider.FixAll(uri, content)
+	if edit == nil {
+		t.Fatal("nothing fixed")
+	}
+	var fixed []string
+	for _, e := range edit.Changes[uri] {
+		fixed = append(fixed, e.NewText)
+	}
+	if got := strings.Join(fixed, " "); got != "PREFERRED ONLY" {
+		t.Errorf("fixed %q, want the preferred and only fixes", got)
+	}
+
+	actions := provider.ProvideCodeFixes(CodeFixContext{URI: uri, Content: content, Only: []CodeActionKind{CodeActionKindSource}})
+	if len(actions) != 1 || *actions[0].Kind != CodeActionKindSourceFixAll || len(actions[0].Edit.Changes[uri]) != 2 {
+		t.Errorf("actions = %+v, want a source.fixAll action", actions)
+	}
+	if actions := provider.ProvideCodeFixes(CodeFixContext{URI: uri, Content: content, Only: []CodeActionKind{CodeActionKindQuickFix}}); len(actions) != 0 {
+		t.Errorf("quick fixes = %+v, want none", actions)
+	}
+
+	// Fixing on save is opt-in too
+	if edits := provider.WillSaveWaitUntil(uri, content); edits != nil {
+		t.Errorf("edits on save = %+v, want none without OnSave", edits)
+	}
+	settings.OnSave = true
+	settings.Rules = map[string]bool{"words": true}
+	provider.SetSettings(settings)
+	if edits := provider.WillSaveWaitUntil(uri, content); len(edits) != 3 || edits[2].NewText != "OPTED" {
+		t.Errorf("edits on save = %+v, want the rules of the source", edits)
+	}
+}
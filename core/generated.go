@@ -0,0 +1,110 @@
+package core
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// ContentKind classifies the content of a document by whether a person
+// wrote and maintains it.
+type ContentKind int
+
+const (
+	// ContentSource is content written by a person.
+	ContentSource ContentKind = iota
+
+	// ContentGenerated is the output of a code generator, marked with a
+	// comment like "// Code generated by stringer. DO NOT EDIT.".
+	ContentGenerated
+
+	// ContentMinified is code minified into very long lines.
+	ContentMinified
+
+	// ContentBinary is not text.
+	ContentBinary
+)
+
+func (k ContentKind) String() string {
+	switch k {
+	case ContentGenerated:
+		return "generated"
+	case ContentMinified:
+		return "minified"
+	case ContentBinary:
+		return "binary"
+	}
+	return "source"
+}
+
+const (
+	// binarySniffLength is how much of a document is checked for binary
+	// data, as git does.
+	binarySniffLength = 8000
+
+	// generatedMarkerLines is how many lines from the top a generated
+	// marker may appear on.
+	generatedMarkerLines = 30
+
+	// minifiedMinLength and minifiedLineLength are the size from which a
+	// document can count as minified, and the average line length above
+	// which it does.
+	minifiedMinLength  = 1024
+	minifiedLineLength = 500
+)
+
+// ClassifyContent returns the kind of the content of a document:
+//   - binary when its beginning has a NUL byte or is not valid UTF-8
+//   - generated when one of its first lines has the marker of the Go
+//     convention, "Code generated ... DO NOT EDIT", or "@generated", used
+//     by other tools
+//   - minified when its lines are over 500 bytes long on average
+//
+// Otherwise it is source.
+func ClassifyContent(content string) ContentKind {
+	if isBinary(content) {
+		return ContentBinary
+	}
+	if isGenerated(content) {
+		return ContentGenerated
+	}
+	if len(content) >= minifiedMinLength && len(content)/(strings.Count(content, "\n")+1) > minifiedLineLength {
+		return ContentMinified
+	}
+	return ContentSource
+}
+
+func isBinary(content string) bool {
+	sniff := content[:min(len(content), binarySniffLength)]
+	if strings.IndexByte(sniff, 0) >= 0 {
+		return true
+	}
+	// A prefix of the content may end within a character
+	truncated := len(sniff) < len(content)
+	for i := 0; i < len(sniff); {
+		r, size := utf8.DecodeRuneInString(sniff[i:])
+		if r == utf8.RuneError && size == 1 && !(truncated && len(sniff)-i < utf8.UTFMax) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+func isGenerated(content string) bool {
+	remaining := content
+	for range generatedMarkerLines {
+		line, rest, found := strings.Cut(remaining, "\n")
+		line = strings.TrimSpace(line)
+		if strings.Contains(line, "@generated") {
+			return true
+		}
+		if i := strings.Index(line, "Code generated "); i >= 0 && strings.Contains(line[i:], "DO NOT EDIT") {
+			return true
+		}
+		if !found {
+			break
+		}
+		remaining = rest
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassifyContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    ContentKind
+	}{
+		{name: "source", content: "package a\n\nfunc f() {}\n", want: ContentSource},
+		{name: "empty", content: "", want: ContentSource},
+		{name: "go generated", content: "// Code generated by stringer -type=Kind; DO NOT EDIT.\n\npackage a\n", want: ContentGenerated},
+		{name: "generated after license", content: "// Copyright 2024\n\n// Code generated by protoc-gen-go. DO NOT EDIT.\npackage a\n", want: ContentGenerated},
+		{name: "at generated", content: "/**\n * @generated SignedSource<<abc>>\n */\n", want: ContentGenerated},
+		{name: "marker too deep", content: strings.Repeat("x\n", 40) + "// Code generated by hand. DO NOT EDIT.\n", want: ContentSource},
+		{name: "mentions generation", content: "// Code generated files are skipped\n", want: ContentSource},
+		{name: "minified", content: "var a=1;" + strings.Repeat("function f(){return 1};", 100), want: ContentMinified},
+		{name: "long but not minified", content: strings.Repeat("short line\n", 200), want: ContentSource},
+		{name: "nul", content: "PK\x03\x04\x00\x00", want: ContentBinary},
+		{name: "invalid utf-8", content: "caf\xe9 au lait", want: ContentBinary},
+		{name: "utf-8 cut at the sniff length", content: strings.Repeat("abcdefghi\n", 800)[:binarySniffLength-1] + "é", want: ContentSource},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyContent(tt.content); got != tt.want {
+				t.Errorf("ClassifyContent() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
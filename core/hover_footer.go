@@ -0,0 +1,181 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Commands of the links of HoverFooterProvider, from the references view
+// built into VS Code. Their arguments are the URI of the document and the
+// hovered position.
+const (
+	DefaultReferencesCommand      = "references-view.findReferences"
+	DefaultImplementationsCommand = "references-view.findImplementations"
+)
+
+// DefaultHoverFooterTimeout is how long a HoverFooterProvider waits for
+// the references of a symbol to be counted.
+const DefaultHoverFooterTimeout = 50 * time.Millisecond
+
+// HoverFooterProvider appends a footer of links to the hovers of Provider,
+// like
+//
+//	3 references · Go to definition · Find implementations
+//
+// The references are counted in the background: a hover waits for the
+// count up to Timeout and otherwise links to "Find references", and the
+// count finished meanwhile shows on the next hover of the symbol. Counts
+// are kept until the document changes.
+//
+// The links run commands with command URIs, which clients only follow in
+// trusted hovers, like VS Code with MarkdownString.isTrusted.
+type HoverFooterProvider struct {
+	Provider HoverProvider
+
+	// References, if set, counts the references of hovered symbols,
+	// without their declaration.
+	References ReferencesProvider
+
+	// Definition, if set, links to the definition of hovered symbols.
+	Definition DefinitionProvider
+
+	// Implementations adds a link finding the implementations of hovered
+	// symbols.
+	Implementations bool
+
+	// ReferencesCommand and ImplementationsCommand are the commands of the
+	// links. If empty, DefaultReferencesCommand and
+	// DefaultImplementationsCommand are used.
+	ReferencesCommand      string
+	ImplementationsCommand string
+
+	// Timeout is how long hovers wait for counts. If zero,
+	// DefaultHoverFooterTimeout is used.
+	Timeout time.Duration
+
+	mu     sync.Mutex
+	counts map[string]*documentReferenceCounts // by URI
+}
+
+// documentReferenceCounts holds the reference counts of the symbols of a
+// version of a document, by the offset of the symbol.
+type documentReferenceCounts struct {
+	content string
+	counts  map[int]*referenceCount
+}
+
+// referenceCount is the count of the references of a symbol, once done is
+// closed.
+type referenceCount struct {
+	done  chan struct{}
+	count int
+}
+
+func (p *HoverFooterProvider) ProvideHover(uri, content string, position Position) *HoverInfo {
+	hover := p.Provider.ProvideHover(uri, content, position)
+	if hover == nil {
+		return nil
+	}
+	word, r := WordAt(content, position)
+	if word == "" {
+		return hover
+	}
+	// The symbol is identified by where its word starts
+	start := r.Start
+
+	var links []string
+	if p.References != nil {
+		label := "Find references"
+		if count, ok := p.referenceCount(uri, content, start); ok {
+			label = pluralReferences(count)
+		}
+		links = append(links, commandLink(label, commandOrDefault(p.ReferencesCommand, DefaultReferencesCommand), uri, content, start))
+	}
+	if p.Definition != nil {
+		if locations := p.Definition.ProvideDefinition(uri, content, start); len(locations) > 0 {
+			location := locations[0]
+			links = append(links, fmt.Sprintf("[Go to definition](%s#L%d)", location.URI, location.Range.Start.Line+1))
+		}
+	}
+	if p.Implementations {
+		links = append(links, commandLink("Find implementations", commandOrDefault(p.ImplementationsCommand, DefaultImplementationsCommand), uri, content, start))
+	}
+	if len(links) == 0 {
+		return hover
+	}
+
+	withFooter := *hover
+	withFooter.Contents += "\n\n---\n\n" + strings.Join(links, " · ")
+	return &withFooter
+}
+
+// referenceCount returns the number of references of the symbol at
+// position, counting them in the background if they are not counted yet.
+// ok is false if they were not counted within the timeout.
+func (p *HoverFooterProvider) referenceCount(uri, content string, position Position) (int, bool) {
+	offset := PositionToByteOffset(content, position)
+
+	p.mu.Lock()
+	if p.counts == nil {
+		p.counts = make(map[string]*documentReferenceCounts)
+	}
+	document := p.counts[uri]
+	if document == nil || document.content != content {
+		// Counts of other versions are stale
+		document = &documentReferenceCounts{content: content, counts: make(map[int]*referenceCount)}
+		p.counts[uri] = document
+	}
+	count := document.counts[offset]
+	if count == nil {
+		count = &referenceCount{done: make(chan struct{})}
+		document.counts[offset] = count
+		go func() {
+			defer close(count.done)
+			count.count = len(p.References.FindReferences(uri, content, position, ReferenceContext{}))
+		}()
+	}
+	p.mu.Unlock()
+
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = DefaultHoverFooterTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-count.done:
+		return count.count, true
+	case <-timer.C:
+		return 0, false
+	}
+}
+
+func commandOrDefault(command, defaultCommand string) string {
+	if command == "" {
+		return defaultCommand
+	}
+	return command
+}
+
+// pluralReferences returns "1 reference" or "n references".
+func pluralReferences(count int) string {
+	if count == 1 {
+		return "1 reference"
+	}
+	return fmt.Sprintf("%d references", count)
+}
+
+// commandLink returns a Markdown link running command with the URI of a
+// document and a position, as a command URI. The position is encoded in
+// UTF-16 code units, like positions sent to clients.
+func commandLink(label, command, uri, content string, position Position) string {
+	args, _ := json.Marshal([]any{uri, map[string]int{
+		"line":      position.Line,
+		"character": UTF8ToUTF16Offset(content, position.Line, position.Character),
+	}})
+	return fmt.Sprintf("[%s](command:%s?%s)", label, command, url.QueryEscape(string(args)))
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fixedHover string
+
+func (h fixedHover) ProvideHover(uri, content string, position Position) *HoverInfo {
+	return &HoverInfo{Contents: string(h)}
+}
+
+// slowReferences finds two references once release is closed.
+type slowReferences struct {
+	release chan struct{}
+}
+
+func (r slowReferences) FindReferences(uri, content string, position Position, context ReferenceContext) []Location {
+	<-r.release
+	return []Location{{URI: uri}, {URI: uri}}
+}
+
+type fixedDefinition []Location
+
+func (d fixedDefinition) ProvideDefinition(uri, content string, position Position) []Location {
+	return d
+}
+
+func TestHoverFooterProvider(t *testing.T) {
+	references := slowReferences{release: make(chan struct{})}
+	provider := &HoverFooterProvider{
+		Provider:        fixedHover("func Run()"),
+		References:      references,
+		Definition:      fixedDefinition{{URI: "file:///run.go", Range: Range{Start: Position{Line: 9}}}},
+		Implementations: true,
+		Timeout:         time.Millisecond,
+	}
+	content := "// é\nx := Run()\n"
+	position := Position{Line: 1, Character: 7}
+
+	hover := provider.ProvideHover("file:///main.go", content, position)
+	footer, _ := url.QueryUnescape(hover.Contents)
+	if !strings.HasPrefix(footer, "func Run()\n\n---\n\n[Find references](command:references-view.findReferences?") {
+		t.Errorf("hover before the count = %q, want a Find references link", footer)
+	}
+	if !strings.Contains(footer, `["file:///main.go",{"character":5,"line":1}]`) {
+		t.Errorf("hover = %q, want the arguments of the start of the word", footer)
+	}
+	if !strings.Contains(footer, " · [Go to definition](file:///run.go#L10) · [Find implementations](command:references-view.findImplementations?") {
+		t.Errorf("hover = %q, want the definition and implementations links", footer)
+	}
+
+	close(references.release)
+	provider.Timeout = time.Second
+	hover = provider.ProvideHover("file:///main.go", content, position)
+	if !strings.Contains(hover.Contents, "[2 references](command:") {
+		t.Errorf("hover after the count = %q, want 2 references", hover.Contents)
+	}
+
+	if hover := (&HoverFooterProvider{Provider: fixedHover("x")}).ProvideHover("file:///main.go", content, position); hover.Contents != "x" {
+		t.Errorf("hover without links = %q, want it unchanged", hover.Contents)
+	}
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"path"
+	"strings"
+	"sync"
+)
+
+// Language describes a language a server supports.
+type Language struct {
+	// ID is the language id clients send in didOpen, like "go".
+	ID string
+
+	// Extensions are file extensions including the dot, like ".go".
+	Extensions []string
+
+	// Filenames are exact file names, like "Makefile" or "go.mod".
+	Filenames []string
+
+	// Regions are the markers of foldable regions in the language, if any.
+	Regions RegionMarkers
+}
+
+// RegionMarkers are the markers starting and ending a foldable region,
+// like "#region" and "#endregion". The text after the start marker names
+// the region.
+type RegionMarkers struct {
+	Start string
+	End   string
+}
+
+// LanguageRegistry maps documents to languages. Clients send a document's
+// language id when they open it; the registry finds the language of
+// documents known only by URI, e.g. when routing providers in a
+// DiagnosticRegistry or CodeFixRegistry. It is safe for concurrent use.
+type LanguageRegistry struct {
+	mu        sync.RWMutex
+	languages []Language
+}
+
+// NewLanguageRegistry creates a registry holding the given languages.
+func NewLanguageRegistry(languages ...Language) *LanguageRegistry {
+	return &LanguageRegistry{languages: languages}
+}
+
+// Register adds a language, replacing one with the same id.
+func (r *LanguageRegistry) Register(language Language) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.languages {
+		if existing.ID == language.ID {
+			r.languages[i] = language
+			return
+		}
+	}
+	r.languages = append(r.languages, language)
+}
+
+// Lookup returns the language with the given id.
+func (r *LanguageRegistry) Lookup(id string) (Language, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, language := range r.languages {
+		if language.ID == id {
+			return language, true
+		}
+	}
+	return Language{}, false
+}
+
+// ForURI returns the language of a document by its file name, then by its
+// extension.
+func (r *LanguageRegistry) ForURI(uri string) (Language, bool) {
+	_, documentPath := splitURI(uri)
+	name := path.Base(documentPath)
+	extension := path.Ext(name)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, language := range r.languages {
+		for _, filename := range language.Filenames {
+			if filename == name {
+				return language, true
+			}
+		}
+	}
+	for _, language := range r.languages {
+		for _, ext := range language.Extensions {
+			if extension != "" && strings.EqualFold(ext, extension) {
+				return language, true
+			}
+		}
+	}
+	return Language{}, false
+}
+
+// LanguageID returns the id of the language of a document, or "" if it is
+// unknown or the registry is nil.
+func (r *LanguageRegistry) LanguageID(uri string) string {
+	if r == nil {
+		return ""
+	}
+	language, _ := r.ForURI(uri)
+	return language.ID
+}
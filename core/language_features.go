@@ -31,6 +31,16 @@ type FoldingRange struct {
 
 	// Kind describes the kind of the folding range (comment, region, imports).
 	Kind *FoldingRangeKind
+
+	// CollapsedText is the text clients show for the folded range, like
+	// the name of a region. If empty, clients choose the text.
+	CollapsedText string
+
+	// Collapsed hints that the range should be folded when the document is
+	// opened, like a license header. LSP has no such property, so it is not
+	// sent to clients; servers act on it themselves, for example by asking
+	// a client that supports it to fold the range with a command.
+	Collapsed bool
 }
 
 // TextEdit represents a textual edit to a document.
@@ -59,6 +69,10 @@ type TextDocumentEdit struct {
 
 	// Edits is the list of edits to apply to the document.
 	Edits []TextEdit
+
+	// AnnotatedEdits are edits carrying a change annotation, applied
+	// together with Edits. The ranges of all edits must not overlap.
+	AnnotatedEdits []AnnotatedTextEdit
 }
 
 // VersionedTextDocumentIdentifier identifies a specific version of a text document.
@@ -141,6 +155,36 @@ type WorkspaceEdit struct {
 	ChangeAnnotations map[string]ChangeAnnotation
 }
 
+// MergeWorkspaceEdits combines edits from several providers into one.
+// Text edits for the same document are concatenated, document changes are
+// kept in order, and change annotations are merged, with later edits winning
+// on conflicting ids. Returns nil if there is nothing to apply.
+func MergeWorkspaceEdits(edits ...*WorkspaceEdit) *WorkspaceEdit {
+	var merged *WorkspaceEdit
+	for _, edit := range edits {
+		if edit == nil || (len(edit.Changes) == 0 && len(edit.DocumentChanges) == 0) {
+			continue
+		}
+		if merged == nil {
+			merged = &WorkspaceEdit{}
+		}
+		for uri, textEdits := range edit.Changes {
+			if merged.Changes == nil {
+				merged.Changes = make(map[string][]TextEdit)
+			}
+			merged.Changes[uri] = append(merged.Changes[uri], textEdits...)
+		}
+		merged.DocumentChanges = append(merged.DocumentChanges, edit.DocumentChanges...)
+		for id, annotation := range edit.ChangeAnnotations {
+			if merged.ChangeAnnotations == nil {
+				merged.ChangeAnnotations = make(map[string]ChangeAnnotation)
+			}
+			merged.ChangeAnnotations[id] = annotation
+		}
+	}
+	return merged
+}
+
 // ChangeAnnotation represents additional information about a change.
 type ChangeAnnotation struct {
 	// Label is a human-readable string describing the change.
@@ -238,6 +282,8 @@ const (
 	CodeActionKindRefactorInline CodeActionKind = "refactor.inline"
 	// CodeActionKindRefactorRewrite rewrites code.
 	CodeActionKindRefactorRewrite CodeActionKind = "refactor.rewrite"
+	// CodeActionKindRefactorMove moves code, like a declaration to another file.
+	CodeActionKindRefactorMove CodeActionKind = "refactor.move"
 	// CodeActionKindSource is a source action (e.g., organize imports).
 	CodeActionKindSource CodeActionKind = "source"
 	// CodeActionKindSourceOrganizeImports organizes imports.
@@ -333,6 +379,11 @@ type WorkspaceSymbol struct {
 	// Location is where this symbol is defined.
 	Location Location
 
+	// Detail is more detail for this symbol, like the signature of a function.
+	// It is costly to compute for every symbol of a query, so providers may leave
+	// it empty and fill it in WorkspaceSymbolResolveProvider.ResolveWorkspaceSymbol.
+	Detail string
+
 	// Data is arbitrary data preserved between workspace/symbol and workspaceSymbol/resolve.
 	Data interface{}
 }
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LargeFilePolicy decides which documents are too large for expensive
+// analysis, like parsing or semantic tokens, so that servers can fall back
+// to lightweight text-based features instead of stalling on them.
+type LargeFilePolicy struct {
+	// MaxBytes is the size above which a document is large, 0 for no limit.
+	MaxBytes int
+
+	// MaxLines is the number of lines above which a document is large, 0
+	// for no limit.
+	MaxLines int
+}
+
+// DefaultLargeFilePolicy treats documents over 1 MB or 50,000 lines as large.
+var DefaultLargeFilePolicy = LargeFilePolicy{
+	MaxBytes: 1 << 20,
+	MaxLines: 50000,
+}
+
+// Check reports whether content is large, and why, e.g.
+// "2.0 MB, over the limit of 1.0 MB".
+func (p LargeFilePolicy) Check(content string) (bool, string) {
+	if p.MaxBytes > 0 && len(content) > p.MaxBytes {
+		return true, fmt.Sprintf("%s, over the limit of %s", formatBytes(len(content)), formatBytes(p.MaxBytes))
+	}
+	if p.MaxLines > 0 && len(content) >= p.MaxLines {
+		// A document has a line more than it has line breaks, so shorter
+		// documents are not counted
+		if lines := strings.Count(content, "\n") + 1; lines > p.MaxLines {
+			return true, fmt.Sprintf("%d lines, over the limit of %d", lines, p.MaxLines)
+		}
+	}
+	return false, ""
+}
+
+// IsLarge reports whether content is large.
+func (p LargeFilePolicy) IsLarge(content string) bool {
+	large, _ := p.Check(content)
+	return large
+}
+
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
@@ -0,0 +1,32 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLargeFilePolicy(t *testing.T) {
+	policy := LargeFilePolicy{MaxBytes: 100, MaxLines: 10}
+	tests := []struct {
+		name       string
+		policy     LargeFilePolicy
+		content    string
+		want       bool
+		wantReason string
+	}{
+		{name: "small", policy: policy, content: "a\nb\n"},
+		{name: "bytes", policy: policy, content: strings.Repeat("a", 101), want: true, wantReason: "101 bytes, over the limit of 100 bytes"},
+		{name: "lines", policy: policy, content: strings.Repeat("\n", 10), want: true, wantReason: "11 lines, over the limit of 10"},
+		{name: "megabytes", policy: DefaultLargeFilePolicy, content: strings.Repeat("a", 2<<20), want: true, wantReason: "2.0 MB, over the limit of 1.0 MB"},
+		{name: "no limits", content: strings.Repeat("a\n", 1000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := tt.policy.Check(tt.content)
+			if got != tt.want || reason != tt.wantReason {
+				t.Errorf("Check() = %t, %q, want %t, %q", got, reason, tt.want, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"sort"
+	"strings"
+)
+
+// LineIndex maps between byte offsets and positions in one version of a
+// document. Building it scans the content once; conversions are then a
+// binary search instead of a scan from the start of the document, which
+// matters for providers that convert many positions per request.
+//
+// A LineIndex is immutable and safe for concurrent use. It must be rebuilt
+// when the content changes.
+type LineIndex struct {
+	// lineStarts holds the byte offset of the start of each line.
+	lineStarts []int
+	length     int
+}
+
+// NewLineIndex builds the line index for content.
+func NewLineIndex(content string) *LineIndex {
+	starts := make([]int, 1, strings.Count(content, "\n")+1)
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return &LineIndex{lineStarts: starts, length: len(content)}
+}
+
+// LineCount returns the number of lines. A document without a trailing
+// newline still counts its last line; an empty document has one line.
+func (idx *LineIndex) LineCount() int {
+	return len(idx.lineStarts)
+}
+
+// Size returns the approximate memory used by the index in bytes.
+func (idx *LineIndex) Size() int64 {
+	return int64(cap(idx.lineStarts))*8 + 32
+}
+
+// Offset converts a position to a byte offset, with the same clamping as
+// PositionToByteOffset: positions past the last line map to the end of the
+// document and characters past the end of a line map to the end of the line.
+func (idx *LineIndex) Offset(pos Position) int {
+	if pos.Line < 0 {
+		pos.Line = 0
+	}
+	if pos.Line >= len(idx.lineStarts) {
+		return idx.length
+	}
+
+	start := idx.lineStarts[pos.Line]
+	end := idx.length
+	if pos.Line+1 < len(idx.lineStarts) {
+		end = idx.lineStarts[pos.Line+1] - 1 // exclude the newline
+	}
+
+	character := pos.Character
+	if character < 0 {
+		character = 0
+	}
+	if character > end-start {
+		character = end - start
+	}
+	return start + character
+}
+
+// Position converts a byte offset to a position, clamping the offset to the
+// document like ByteOffsetToPosition.
+func (idx *LineIndex) Position(offset int) Position {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > idx.length {
+		offset = idx.length
+	}
+
+	// The line is the last one starting at or before offset
+	line := sort.SearchInts(idx.lineStarts, offset+1) - 1
+	return Position{Line: line, Character: offset - idx.lineStarts[line]}
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestLineIndex_MatchesScanningConversions(t *testing.T) {
+	documents := []string{
+		"",
+		"single line",
+		"first\nsecond\nthird",
+		"trailing newline\n",
+		"\n\n\n",
+		"windows\r\nline endings\r\n",
+		"hello 😀 world\n你好世界\nend",
+	}
+
+	for _, content := range documents {
+		idx := NewLineIndex(content)
+
+		for offset := -1; offset <= len(content)+1; offset++ {
+			if got, want := idx.Position(offset), ByteOffsetToPosition(content, offset); got != want {
+				t.Errorf("%q: Position(%d) = %v, want %v", content, offset, got, want)
+			}
+		}
+
+		for line := -1; line <= idx.LineCount()+1; line++ {
+			for character := -1; character <= 20; character++ {
+				pos := Position{Line: line, Character: character}
+				if got, want := idx.Offset(pos), PositionToByteOffset(content, pos); got != want {
+					t.Errorf("%q: Offset(%v) = %d, want %d", content, pos, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestLineIndex_LineCount(t *testing.T) {
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{"", 1},
+		{"one", 1},
+		{"one\n", 2},
+		{"one\ntwo", 2},
+	}
+
+	for _, tt := range tests {
+		if got := NewLineIndex(tt.content).LineCount(); got != tt.want {
+			t.Errorf("LineCount(%q) = %d, want %d", tt.content, got, tt.want)
+		}
+	}
+}
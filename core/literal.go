@@ -0,0 +1,193 @@
+package core
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// LiteralKind is the kind of a literal found by FindLiterals.
+type LiteralKind int
+
+const (
+	// LiteralString is a quoted string.
+	LiteralString LiteralKind = iota + 1
+
+	// LiteralNumber is a numeric constant.
+	LiteralNumber
+)
+
+// Literal is a string or numeric literal in a document.
+type Literal struct {
+	Kind LiteralKind
+
+	// Text is the literal as written, including quotes.
+	Text string
+
+	// Value is the text between the quotes of a string, without unescaping,
+	// or the text of a number.
+	Value string
+
+	// Range is the range of Text.
+	Range Range
+}
+
+// FindLiterals returns the string and numeric literals of a document in
+// order, using syntax shared by most programming, configuration, and data
+// languages:
+//   - strings in double quotes, single quotes, or backquotes; backslash
+//     escapes a quote, and only backquoted strings span lines
+//   - decimal numbers with an optional fraction and exponent, and hex,
+//     octal, and binary numbers with a 0x, 0o, or 0b prefix; underscores
+//     may separate digits
+//
+// A single quote directly after a letter or digit is an apostrophe, not the
+// start of a string, and digits that are part of an identifier (like "v2")
+// are not numbers. Comments are not recognized, so literals inside comments
+// are reported too.
+func FindLiterals(content string) []Literal {
+	var literals []Literal
+	index := NewLineIndex(content)
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		var end int
+		var kind LiteralKind
+
+		switch {
+		case c == '"' || c == '`' || (c == '\'' && !precededByWordChar(content, i, false)):
+			end = scanString(content, i)
+			kind = LiteralString
+		case isDigit(c) && !precededByWordChar(content, i, true):
+			end = scanNumber(content, i)
+			kind = LiteralNumber
+		default:
+			_, size := utf8.DecodeRuneInString(content[i:])
+			i += size
+			continue
+		}
+
+		if end < 0 {
+			// Unterminated string: skip the quote
+			i++
+			continue
+		}
+		if end == i {
+			i++
+			continue
+		}
+
+		literal := Literal{
+			Kind:  kind,
+			Text:  content[i:end],
+			Value: content[i:end],
+			Range: Range{Start: index.Position(i), End: index.Position(end)},
+		}
+		if kind == LiteralString {
+			literal.Value = content[i+1 : end-1]
+		}
+		literals = append(literals, literal)
+		i = end
+	}
+
+	return literals
+}
+
+// LiteralAt returns the literal containing pos, or directly before it.
+func LiteralAt(content string, pos Position) (Literal, bool) {
+	for _, literal := range FindLiterals(content) {
+		if ComparePositions(literal.Range.Start, pos) > 0 {
+			break
+		}
+		if ComparePositions(pos, literal.Range.End) <= 0 {
+			return literal, true
+		}
+	}
+	return Literal{}, false
+}
+
+// scanString returns the end offset of the string starting with the quote
+// at start, or -1 if it is not terminated.
+func scanString(content string, start int) int {
+	quote := content[start]
+	for i := start + 1; i < len(content); i++ {
+		switch content[i] {
+		case quote:
+			return i + 1
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case '\n':
+			if quote != '`' {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+// scanNumber returns the end offset of the number starting at start, or
+// start if the digits there are followed by letters and thus not a number.
+func scanNumber(content string, start int) int {
+	i := start
+	if content[i] == '0' && i+1 < len(content) {
+		switch content[i+1] {
+		case 'x', 'X':
+			return endOfNumber(content, start, skipDigits(content, i+2, isHexDigit))
+		case 'o', 'O', 'b', 'B':
+			return endOfNumber(content, start, skipDigits(content, i+2, isDigit))
+		}
+	}
+
+	i = skipDigits(content, i, isDigit)
+	if i+1 < len(content) && content[i] == '.' && isDigit(content[i+1]) {
+		i = skipDigits(content, i+1, isDigit)
+	}
+	if i < len(content) && (content[i] == 'e' || content[i] == 'E') {
+		j := i + 1
+		if j < len(content) && (content[j] == '+' || content[j] == '-') {
+			j++
+		}
+		if j < len(content) && isDigit(content[j]) {
+			i = skipDigits(content, j, isDigit)
+		}
+	}
+	return endOfNumber(content, start, i)
+}
+
+// endOfNumber returns end, or start if the number is directly followed by a
+// letter and thus part of a word.
+func endOfNumber(content string, start, end int) int {
+	if end < len(content) {
+		if r, _ := utf8.DecodeRuneInString(content[end:]); r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return start
+		}
+	}
+	return end
+}
+
+func skipDigits(content string, i int, digits func(byte) bool) int {
+	for i < len(content) && (digits(content[i]) || content[i] == '_') {
+		i++
+	}
+	return i
+}
+
+// precededByWordChar reports whether the character before offset is a
+// letter, digit, or underscore, or a dot if dot is set (as in "v1.2", whose
+// "2" is not a number on its own).
+func precededByWordChar(content string, offset int, dot bool) bool {
+	if offset == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(content[:offset])
+	return r == '_' || (dot && r == '.') || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
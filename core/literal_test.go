@@ -0,0 +1,75 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindLiterals(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "strings",
+			content: `a = "x\"y" + 'z' + ` + "`raw\nline`",
+			want:    []string{`"x\"y"`, `'z'`, "`raw\nline`"},
+		},
+		{
+			name:    "apostrophe",
+			content: `it's "fine"`,
+			want:    []string{`"fine"`},
+		},
+		{
+			name:    "unterminated",
+			content: "\"open\nport: 80",
+			want:    []string{"80"},
+		},
+		{
+			name:    "numbers",
+			content: "x = 1_000 + 3.14 + 2e-3 + 0xFF + 0b1010",
+			want:    []string{"1_000", "3.14", "2e-3", "0xFF", "0b1010"},
+		},
+		{
+			name:    "digits in words",
+			content: "v1.2 abc123 3rd x2",
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, literal := range FindLiterals(tt.content) {
+				got = append(got, literal.Text)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindLiterals() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLiteralAt(t *testing.T) {
+	content := "name: \"web\"\nport: 8080\n"
+
+	literal, ok := LiteralAt(content, Position{Line: 0, Character: 8})
+	if !ok || literal.Kind != LiteralString || literal.Value != "web" {
+		t.Errorf("expected string literal, got %+v %v", literal, ok)
+	}
+	wantRange := Range{Start: Position{Line: 0, Character: 6}, End: Position{Line: 0, Character: 11}}
+	if literal.Range != wantRange {
+		t.Errorf("Range = %+v, want %+v", literal.Range, wantRange)
+	}
+
+	// Directly after a number
+	literal, ok = LiteralAt(content, Position{Line: 1, Character: 10})
+	if !ok || literal.Kind != LiteralNumber || literal.Text != "8080" {
+		t.Errorf("expected number literal, got %+v %v", literal, ok)
+	}
+
+	if _, ok := LiteralAt(content, Position{Line: 0, Character: 2}); ok {
+		t.Error("expected no literal on a key")
+	}
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Overload is one definition of an overloaded name, like a constructor of
+// a class with several, or an instantiation of a generic function.
+type Overload struct {
+	// Signature is the signature of the definition.
+	Signature SignatureInformation
+
+	// Variadic reports whether the last parameter takes any number of
+	// arguments.
+	Variadic bool
+}
+
+// hasParameter reports whether the overload has a parameter at index,
+// counting the arguments of a variadic last parameter.
+func (o Overload) hasParameter(index int) bool {
+	n := len(o.Signature.Parameters)
+	return index < n || (o.Variadic && n > 0)
+}
+
+// activeParameter returns the parameter of the overload at index: the last
+// one for the extra arguments of a variadic overload.
+func (o Overload) activeParameter(index int) int {
+	if n := len(o.Signature.Parameters); o.Variadic && n > 0 && index >= n {
+		return n - 1
+	}
+	return index
+}
+
+// OverloadSet is the definitions of an overloaded name, which signature
+// help presents as several signatures, and completion as one item.
+//
+// The overloads are kept in a stable order, by number of parameters and
+// then by label, whatever the order a provider finds them in, so that the
+// index of the active signature names the same overload from one request
+// to the next. Overloads with the same label are kept once.
+type OverloadSet struct {
+	// Name is the overloaded name.
+	Name string
+
+	overloads []Overload
+}
+
+// NewOverloadSet creates the overload set of name.
+func NewOverloadSet(name string, overloads ...Overload) *OverloadSet {
+	s := &OverloadSet{Name: name}
+	s.Add(overloads...)
+	return s
+}
+
+// Add adds overloads to the set.
+func (s *OverloadSet) Add(overloads ...Overload) {
+	for _, overload := range overloads {
+		if s.Index(overload.Signature.Label) < 0 {
+			s.overloads = append(s.overloads, overload)
+		}
+	}
+	sort.SliceStable(s.overloads, func(i, j int) bool {
+		a, b := s.overloads[i].Signature, s.overloads[j].Signature
+		if len(a.Parameters) != len(b.Parameters) {
+			return len(a.Parameters) < len(b.Parameters)
+		}
+		return a.Label < b.Label
+	})
+}
+
+// Overloads returns the overloads of the set, in order.
+func (s *OverloadSet) Overloads() []Overload {
+	return append([]Overload(nil), s.overloads...)
+}
+
+// Len returns the number of overloads of the set.
+func (s *OverloadSet) Len() int {
+	return len(s.overloads)
+}
+
+// Index returns the index of the overload with a signature label, or -1.
+func (s *OverloadSet) Index(label string) int {
+	for i, overload := range s.overloads {
+		if overload.Signature.Label == label {
+			return i
+		}
+	}
+	return -1
+}
+
+// Match returns the index of the overload a call is most likely of, with
+// the cursor on the argument at activeParameter: the first one, with the
+// fewest parameters, that has a parameter there. If none has, it is the
+// first one.
+func (s *OverloadSet) Match(activeParameter int) int {
+	for i, overload := range s.overloads {
+		if overload.hasParameter(activeParameter) {
+			return i
+		}
+	}
+	return 0
+}
+
+// SignatureHelp returns the signature help of a call of the set, with the
+// cursor on the argument at activeParameter, or nil if the set is empty.
+//
+// The active signature is the overload Match returns, unless signature
+// help is retriggered while the user selected another overload that still
+// has a parameter at activeParameter: the selection is kept then.
+func (s *OverloadSet) SignatureHelp(ctx SignatureHelpContext, activeParameter int) *SignatureHelp {
+	if len(s.overloads) == 0 {
+		return nil
+	}
+
+	active := s.Match(activeParameter)
+	if label, ok := selectedSignature(ctx); ok {
+		if i := s.Index(label); i >= 0 && s.overloads[i].hasParameter(activeParameter) {
+			active = i
+		}
+	}
+
+	help := &SignatureHelp{
+		Signatures:      make([]SignatureInformation, len(s.overloads)),
+		ActiveSignature: &active,
+	}
+	for i, overload := range s.overloads {
+		signature := overload.Signature
+		parameter := overload.activeParameter(activeParameter)
+		signature.ActiveParameter = &parameter
+		help.Signatures[i] = signature
+	}
+	help.ActiveParameter = help.Signatures[active].ActiveParameter
+	return help
+}
+
+// CompletionItem returns the completion item of the set: rather than an
+// item per overload, one item for the name, with the first overload as its
+// detail and the number of the others, like "+2 overloads", as its
+// description.
+func (s *OverloadSet) CompletionItem(kind CompletionItemKind) CompletionItem {
+	item := CompletionItem{Label: s.Name, Kind: &kind}
+	if len(s.overloads) == 0 {
+		return item
+	}
+	item.Detail = s.overloads[0].Signature.Label
+	item.Documentation = s.overloads[0].Signature.Documentation
+	if others := len(s.overloads) - 1; others == 1 {
+		item.LabelDetails = &CompletionItemLabelDetails{Description: "+1 overload"}
+	} else if others > 1 {
+		item.LabelDetails = &CompletionItemLabelDetails{Description: fmt.Sprintf("+%d overloads", others)}
+	}
+	return item
+}
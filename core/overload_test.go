@@ -0,0 +1,104 @@
+package core
+
+import "testing"
+
+func overload(label string, variadic bool, parameters ...string) Overload {
+	o := Overload{Signature: SignatureInformation{Label: label}, Variadic: variadic}
+	for _, parameter := range parameters {
+		o.Signature.Parameters = append(o.Signature.Parameters, ParameterInformation{Label: parameter})
+	}
+	return o
+}
+
+func newPointSet(overloads ...Overload) *OverloadSet {
+	if len(overloads) == 0 {
+		overloads = []Overload{
+			overload("Point(x int, y int, z int)", false, "x int", "y int", "z int"),
+			overload("Point(p Point)", false, "p Point"),
+			overload("Point(x int, y int)", false, "x int", "y int"),
+			overload("Point()", false),
+		}
+	}
+	return NewOverloadSet("Point", overloads...)
+}
+
+func TestOverloadSet_Order(t *testing.T) {
+	set := newPointSet()
+	set.Add(overload("Point(p Point)", false, "p Point"))
+
+	expect := []string{"Point()", "Point(p Point)", "Point(x int, y int)", "Point(x int, y int, z int)"}
+	if set.Len() != len(expect) {
+		t.Fatalf("expected %d overloads, got %d", len(expect), set.Len())
+	}
+	for i, overload := range set.Overloads() {
+		if overload.Signature.Label != expect[i] {
+			t.Errorf("overload %d: expected %q, got %q", i, expect[i], overload.Signature.Label)
+		}
+	}
+
+	// The order does not depend on the order overloads are found in
+	reversed := set.Overloads()
+	var overloads []Overload
+	for i := len(reversed) - 1; i >= 0; i-- {
+		overloads = append(overloads, reversed[i])
+	}
+	if other := newPointSet(overloads...); other.Index("Point(x int, y int)") != set.Index("Point(x int, y int)") {
+		t.Errorf("expected a stable index")
+	}
+}
+
+func TestOverloadSet_SignatureHelp(t *testing.T) {
+	set := newPointSet()
+
+	help := set.SignatureHelp(SignatureHelpContext{}, 1)
+	if len(help.Signatures) != 4 || *help.ActiveSignature != 2 || *help.ActiveParameter != 1 {
+		t.Fatalf("expected the first overload with a second parameter, got %d %d", *help.ActiveSignature, *help.ActiveParameter)
+	}
+
+	// The user selects the overload with three parameters and types on
+	selected := 3
+	help.ActiveSignature = &selected
+	ctx := SignatureHelpContext{IsRetrigger: true, ActiveSignatureHelp: help}
+	if help := set.SignatureHelp(ctx, 1); *help.ActiveSignature != 3 {
+		t.Errorf("expected the selection to be kept, got %d", *help.ActiveSignature)
+	}
+
+	// A selection without a parameter at the cursor is not kept
+	selected = 1
+	if help := set.SignatureHelp(ctx, 2); *help.ActiveSignature != 3 {
+		t.Errorf("expected the overload with a third parameter, got %d", *help.ActiveSignature)
+	}
+
+	if help := NewOverloadSet("Point").SignatureHelp(SignatureHelpContext{}, 0); help != nil {
+		t.Errorf("expected no signature help for an empty set, got %+v", help)
+	}
+}
+
+func TestOverloadSet_Variadic(t *testing.T) {
+	set := NewOverloadSet("Printf",
+		overload("Printf(format string, a ...any)", true, "format string", "a ...any"),
+		overload("Printf(w io.Writer, format string, a ...any)", true, "w io.Writer", "format string", "a ...any"),
+	)
+	help := set.SignatureHelp(SignatureHelpContext{}, 4)
+	if *help.ActiveSignature != 0 || *help.ActiveParameter != 1 {
+		t.Errorf("expected the extra arguments on the variadic parameter, got %d %d", *help.ActiveSignature, *help.ActiveParameter)
+	}
+	if parameter := *help.Signatures[1].ActiveParameter; parameter != 2 {
+		t.Errorf("expected the variadic parameter of the second overload, got %d", parameter)
+	}
+}
+
+func TestOverloadSet_CompletionItem(t *testing.T) {
+	item := newPointSet().CompletionItem(CompletionItemKindConstructor)
+	if item.Label != "Point" || item.Detail != "Point()" || *item.Kind != CompletionItemKindConstructor {
+		t.Errorf("unexpected item %+v", item)
+	}
+	if item.LabelDetails == nil || item.LabelDetails.Description != "+3 overloads" {
+		t.Errorf("expected the number of other overloads, got %+v", item.LabelDetails)
+	}
+
+	single := NewOverloadSet("Point", overload("Point()", false)).CompletionItem(CompletionItemKindConstructor)
+	if single.LabelDetails != nil {
+		t.Errorf("expected no description for a single overload, got %+v", single.LabelDetails)
+	}
+}
@@ -0,0 +1,117 @@
+package core
+
+import "strings"
+
+// ComparePositions returns -1 if a comes before b, 1 if a comes after b,
+// and 0 if they are equal.
+func ComparePositions(a, b Position) int {
+	switch {
+	case a.Line < b.Line:
+		return -1
+	case a.Line > b.Line:
+		return 1
+	case a.Character < b.Character:
+		return -1
+	case a.Character > b.Character:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ContainsRange returns true if other lies entirely within this range.
+// A range contains itself.
+func (r Range) ContainsRange(other Range) bool {
+	return ComparePositions(r.Start, other.Start) <= 0 && ComparePositions(other.End, r.End) <= 0
+}
+
+// RangesOverlap returns true if a and b share some text. Ranges that only
+// touch (one ends where the other starts) do not overlap; an empty range
+// overlaps a range it lies strictly inside of.
+func RangesOverlap(a, b Range) bool {
+	return ComparePositions(a.Start, b.End) < 0 && ComparePositions(b.Start, a.End) < 0
+}
+
+// Union returns the smallest range containing both a and b.
+func Union(a, b Range) Range {
+	result := a
+	if ComparePositions(b.Start, result.Start) < 0 {
+		result.Start = b.Start
+	}
+	if ComparePositions(b.End, result.End) > 0 {
+		result.End = b.End
+	}
+	return result
+}
+
+// Intersection returns the range covered by both a and b. Ranges that only
+// touch intersect in an empty range. The result is false if the ranges are
+// disjoint.
+func Intersection(a, b Range) (Range, bool) {
+	result := a
+	if ComparePositions(b.Start, result.Start) > 0 {
+		result.Start = b.Start
+	}
+	if ComparePositions(b.End, result.End) < 0 {
+		result.End = b.End
+	}
+	if ComparePositions(result.Start, result.End) > 0 {
+		return Range{}, false
+	}
+	return result, true
+}
+
+// ShiftRangeByEdit returns where r ends up after edit is applied to the
+// document, e.g. to keep diagnostics or highlights in place while the user
+// types. Edits after r leave it unchanged, edits before it move it, and edits
+// within it grow or shrink it; an insertion at r's start moves r and one at
+// its end leaves it unchanged. The result is false if the edit replaces text
+// crossing r's start or end, since r no longer has a counterpart.
+func ShiftRangeByEdit(r Range, edit TextEdit) (Range, bool) {
+	return ShiftRange(r, edit.Range, InsertedRange(edit))
+}
+
+// ShiftRange is like ShiftRangeByEdit for an edit that replaced the text in
+// the range replaced with text now spanning the range inserted. Only the
+// extent of the texts matters, so swapping the two ranges maps r back to
+// where it was before the edit.
+func ShiftRange(r, replaced, inserted Range) (Range, bool) {
+	switch {
+	case ComparePositions(replaced.End, r.Start) <= 0:
+		// Before r
+		return Range{Start: shiftPosition(r.Start, replaced, inserted), End: shiftPosition(r.End, replaced, inserted)}, true
+
+	case ComparePositions(replaced.Start, r.End) >= 0:
+		// After r
+		return r, true
+
+	case r.ContainsRange(replaced):
+		return Range{Start: r.Start, End: shiftPosition(r.End, replaced, inserted)}, true
+
+	default:
+		return Range{}, false
+	}
+}
+
+// InsertedRange returns the range the new text of edit spans once the edit
+// is applied.
+func InsertedRange(edit TextEdit) Range {
+	end := edit.Range.Start
+	if newlines := strings.Count(edit.NewText, "\n"); newlines > 0 {
+		end.Line += newlines
+		end.Character = len(edit.NewText) - strings.LastIndexByte(edit.NewText, '\n') - 1
+	} else {
+		end.Character += len(edit.NewText)
+	}
+	return Range{Start: edit.Range.Start, End: end}
+}
+
+// shiftPosition returns where p ends up after the text in replaced is
+// replaced by text spanning inserted. p must not come before the end of
+// replaced.
+func shiftPosition(p Position, replaced, inserted Range) Position {
+	if p.Line == replaced.End.Line {
+		return Position{Line: inserted.End.Line, Character: inserted.End.Character + p.Character - replaced.End.Character}
+	}
+	return Position{Line: p.Line + inserted.End.Line - replaced.End.Line, Character: p.Character}
+}
@@ -0,0 +1,192 @@
+package core
+
+import "testing"
+
+func pos(line, character int) Position {
+	return Position{Line: line, Character: character}
+}
+
+func rng(startLine, startChar, endLine, endChar int) Range {
+	return Range{Start: pos(startLine, startChar), End: pos(endLine, endChar)}
+}
+
+func TestComparePositions(t *testing.T) {
+	tests := []struct {
+		a, b     Position
+		expected int
+	}{
+		{pos(0, 0), pos(0, 0), 0},
+		{pos(0, 5), pos(1, 0), -1},
+		{pos(2, 0), pos(1, 9), 1},
+		{pos(1, 3), pos(1, 4), -1},
+		{pos(1, 4), pos(1, 3), 1},
+	}
+
+	for _, tt := range tests {
+		if got := ComparePositions(tt.a, tt.b); got != tt.expected {
+			t.Errorf("ComparePositions(%s, %s) = %d, expected %d", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestRangeContainsRange(t *testing.T) {
+	outer := rng(1, 2, 3, 4)
+	tests := []struct {
+		name     string
+		inner    Range
+		expected bool
+	}{
+		{"itself", outer, true},
+		{"inside", rng(2, 0, 2, 10), true},
+		{"empty at start", rng(1, 2, 1, 2), true},
+		{"starts before", rng(1, 1, 2, 0), false},
+		{"ends after", rng(2, 0, 3, 5), false},
+		{"disjoint", rng(5, 0, 5, 1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := outer.ContainsRange(tt.inner); got != tt.expected {
+				t.Errorf("ContainsRange(%s) = %v, expected %v", tt.inner, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRangesOverlapAndIntersection(t *testing.T) {
+	tests := []struct {
+		name         string
+		a, b         Range
+		overlap      bool
+		intersection Range
+		intersects   bool
+	}{
+		{"nested", rng(0, 0, 5, 0), rng(1, 0, 2, 0), true, rng(1, 0, 2, 0), true},
+		{"partial", rng(0, 0, 2, 5), rng(1, 0, 3, 0), true, rng(1, 0, 2, 5), true},
+		{"touching", rng(0, 0, 1, 3), rng(1, 3, 2, 0), false, rng(1, 3, 1, 3), true},
+		{"disjoint", rng(0, 0, 0, 3), rng(0, 5, 0, 8), false, Range{}, false},
+		{"empty inside", rng(0, 0, 0, 10), rng(0, 4, 0, 4), true, rng(0, 4, 0, 4), true},
+		{"empty at end", rng(0, 0, 0, 10), rng(0, 10, 0, 10), false, rng(0, 10, 0, 10), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, pair := range [][2]Range{{tt.a, tt.b}, {tt.b, tt.a}} {
+				if got := RangesOverlap(pair[0], pair[1]); got != tt.overlap {
+					t.Errorf("RangesOverlap(%s, %s) = %v, expected %v", pair[0], pair[1], got, tt.overlap)
+				}
+				got, ok := Intersection(pair[0], pair[1])
+				if ok != tt.intersects || got != tt.intersection {
+					t.Errorf("Intersection(%s, %s) = %s, %v, expected %s, %v", pair[0], pair[1], got, ok, tt.intersection, tt.intersects)
+				}
+			}
+		})
+	}
+}
+
+func TestUnion(t *testing.T) {
+	tests := []struct {
+		a, b     Range
+		expected Range
+	}{
+		{rng(0, 0, 1, 0), rng(2, 0, 3, 0), rng(0, 0, 3, 0)},
+		{rng(1, 5, 1, 9), rng(1, 2, 1, 6), rng(1, 2, 1, 9)},
+		{rng(0, 0, 5, 0), rng(1, 0, 2, 0), rng(0, 0, 5, 0)},
+	}
+
+	for _, tt := range tests {
+		if got := Union(tt.a, tt.b); got != tt.expected {
+			t.Errorf("Union(%s, %s) = %s, expected %s", tt.a, tt.b, got, tt.expected)
+		}
+		if got := Union(tt.b, tt.a); got != tt.expected {
+			t.Errorf("Union(%s, %s) = %s, expected %s", tt.b, tt.a, got, tt.expected)
+		}
+	}
+}
+
+func TestShiftRangeByEdit(t *testing.T) {
+	content := "package main\n\nfunc hello() {\n\tprintln(\"hi\")\n}\n"
+	// "hello" on line 2
+	target := rng(2, 5, 2, 10)
+
+	tests := []struct {
+		name     string
+		edit     TextEdit
+		expected Range
+		ok       bool
+		// text is the expected text of the shifted range
+		text string
+	}{
+		{"insert line before", TextEdit{Range: rng(1, 0, 1, 0), NewText: "// a\n// b\n"}, rng(4, 5, 4, 10), true, "hello"},
+		{"insert before on same line", TextEdit{Range: rng(2, 0, 2, 0), NewText: "// "}, rng(2, 8, 2, 13), true, "hello"},
+		{"join with previous line", TextEdit{Range: rng(1, 0, 2, 0), NewText: ""}, rng(1, 5, 1, 10), true, "hello"},
+		{"replace before with lines", TextEdit{Range: rng(2, 0, 2, 4), NewText: "x\ny"}, rng(3, 2, 3, 7), true, "hello"},
+		{"insert at start", TextEdit{Range: rng(2, 5, 2, 5), NewText: "say_"}, rng(2, 9, 2, 14), true, "hello"},
+		{"insert at end", TextEdit{Range: rng(2, 10, 2, 10), NewText: "World"}, target, true, "hello"},
+		{"edit after", TextEdit{Range: rng(3, 0, 3, 1), NewText: "    "}, target, true, "hello"},
+		{"edit inside", TextEdit{Range: rng(2, 6, 2, 9), NewText: "ELL"}, target, true, "hELLo"},
+		{"newline inside", TextEdit{Range: rng(2, 7, 2, 7), NewText: "\n"}, rng(2, 5, 3, 3), true, "he\nllo"},
+		{"crossing start", TextEdit{Range: rng(2, 0, 2, 7), NewText: ""}, Range{}, false, ""},
+		{"crossing end", TextEdit{Range: rng(2, 8, 3, 0), NewText: ""}, Range{}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ShiftRangeByEdit(target, tt.edit)
+			if ok != tt.ok || got != tt.expected {
+				t.Fatalf("ShiftRangeByEdit = %s, %v, expected %s, %v", got, ok, tt.expected, tt.ok)
+			}
+			if !ok {
+				return
+			}
+
+			doc := NewDocument("file:///test.go", content, 1)
+			doc.ApplyEdit(tt.edit.Range, tt.edit.NewText)
+			edited := doc.GetContent()
+			text := edited[PositionToByteOffset(edited, got.Start):PositionToByteOffset(edited, got.End)]
+			if text != tt.text {
+				t.Errorf("shifted range covers %q, expected %q", text, tt.text)
+			}
+		})
+	}
+}
+
+func TestShiftEmptyRangeByInsertion(t *testing.T) {
+	cursor := rng(0, 3, 0, 3)
+	got, ok := ShiftRangeByEdit(cursor, TextEdit{Range: cursor, NewText: "ab"})
+	if !ok || got != rng(0, 5, 0, 5) {
+		t.Errorf("expected insertion at an empty range to move it, got %s, %v", got, ok)
+	}
+}
+
+func TestInsertedRange(t *testing.T) {
+	tests := []struct {
+		edit     TextEdit
+		expected Range
+	}{
+		{TextEdit{Range: rng(1, 4, 1, 8), NewText: ""}, rng(1, 4, 1, 4)},
+		{TextEdit{Range: rng(1, 4, 1, 8), NewText: "abc"}, rng(1, 4, 1, 7)},
+		{TextEdit{Range: rng(1, 4, 3, 0), NewText: "a\nbc\n"}, rng(1, 4, 3, 0)},
+		{TextEdit{Range: rng(1, 4, 1, 4), NewText: "a\nbc"}, rng(1, 4, 2, 2)},
+	}
+
+	for _, tt := range tests {
+		if got := InsertedRange(tt.edit); got != tt.expected {
+			t.Errorf("InsertedRange(%s, %q) = %s, expected %s", tt.edit.Range, tt.edit.NewText, got, tt.expected)
+		}
+	}
+}
+
+func TestShiftRangeUndo(t *testing.T) {
+	r := rng(4, 2, 4, 6)
+	edit := TextEdit{Range: rng(1, 3, 2, 5), NewText: "x\ny\nz"}
+
+	shifted, ok := ShiftRangeByEdit(r, edit)
+	if !ok {
+		t.Fatal("expected range after the edit to shift")
+	}
+	back, ok := ShiftRange(shifted, InsertedRange(edit), edit.Range)
+	if !ok || back != r {
+		t.Errorf("expected swapped ranges to undo the shift, got %s, %v", back, ok)
+	}
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ReadOnlyReason is the reason given for features disabled in read-only
+// mode.
+const ReadOnlyReason = "The server is in read-only mode, so features that edit files are disabled"
+
+// ErrReadOnly is returned for operations that would edit files in
+// read-only mode.
+var ErrReadOnly = errors.New(ReadOnlyReason)
+
+// ReadOnlyMode records whether the server may edit files, e.g. from the
+// initialization options of the client. Deployments for code review or
+// browsing turn it on: navigation keeps working, while features producing
+// edits, like rename, formatting, and code actions, are disabled.
+//
+// A nil ReadOnlyMode allows edits. It is safe for concurrent use.
+type ReadOnlyMode struct {
+	readOnly atomic.Bool
+}
+
+// NewReadOnlyMode creates a read-only mode record.
+func NewReadOnlyMode(readOnly bool) *ReadOnlyMode {
+	m := &ReadOnlyMode{}
+	m.SetReadOnly(readOnly)
+	return m
+}
+
+// ReadOnly reports whether edits are disabled.
+func (m *ReadOnlyMode) ReadOnly() bool {
+	return m != nil && m.readOnly.Load()
+}
+
+// SetReadOnly changes whether edits are disabled.
+func (m *ReadOnlyMode) SetReadOnly(readOnly bool) {
+	m.readOnly.Store(readOnly)
+}
+
+// Check returns ErrReadOnly if edits are disabled.
+func (m *ReadOnlyMode) Check() error {
+	if m.ReadOnly() {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// ReadOnlyCodeFixProvider disables the code actions of a provider that
+// edit in read-only mode, giving ReadOnlyReason as the reason, so the user
+// sees why they are not available. Actions that only run a command are
+// kept.
+type ReadOnlyCodeFixProvider struct {
+	Provider CodeFixProvider
+	Mode     *ReadOnlyMode
+}
+
+func (p *ReadOnlyCodeFixProvider) ProvideCodeFixes(ctx CodeFixContext) []CodeAction {
+	actions := p.Provider.ProvideCodeFixes(ctx)
+	if !p.Mode.ReadOnly() {
+		return actions
+	}
+	for i := range actions {
+		if actions[i].Edit != nil {
+			actions[i].Edit = nil
+			actions[i].IsPreferred = false
+			actions[i].Disabled = &CodeActionDisabled{Reason: ReadOnlyReason}
+		}
+	}
+	return actions
+}
+
+// ReadOnlyRenameProvider renames with a provider unless in read-only mode.
+// Servers answering rename requests should also report ErrReadOnly, which
+// clients show to the user, as protocol.Handler does with its ReadOnly
+// field.
+type ReadOnlyRenameProvider struct {
+	Provider RenameProvider
+	Mode     *ReadOnlyMode
+}
+
+func (p *ReadOnlyRenameProvider) ProvideRename(ctx RenameContext) *WorkspaceEdit {
+	if p.Mode.ReadOnly() {
+		return nil
+	}
+	return p.Provider.ProvideRename(ctx)
+}
+
+// PrepareRename returns the range of the provider, if it implements
+// PrepareRenameProvider, unless in read-only mode.
+func (p *ReadOnlyRenameProvider) PrepareRename(uri, content string, position Position) *Range {
+	prepare, ok := p.Provider.(PrepareRenameProvider)
+	if !ok || p.Mode.ReadOnly() {
+		return nil
+	}
+	return prepare.PrepareRename(uri, content, position)
+}
+
+// ReadOnlyFormattingProvider formats with a provider unless in read-only
+// mode. It formats ranges too if the provider implements
+// RangeFormattingProvider.
+type ReadOnlyFormattingProvider struct {
+	Provider FormattingProvider
+	Mode     *ReadOnlyMode
+}
+
+func (p *ReadOnlyFormattingProvider) ProvideFormatting(uri, content string, options FormattingOptions) []TextEdit {
+	if p.Mode.ReadOnly() {
+		return nil
+	}
+	return p.Provider.ProvideFormatting(uri, content, options)
+}
+
+func (p *ReadOnlyFormattingProvider) ProvideRangeFormatting(uri, content string, r Range, options FormattingOptions) []TextEdit {
+	ranges, ok := p.Provider.(RangeFormattingProvider)
+	if !ok || p.Mode.ReadOnly() {
+		return nil
+	}
+	return ranges.ProvideRangeFormatting(uri, content, r, options)
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+type stubRenameProvider struct{}
+
+func (stubRenameProvider) ProvideRename(ctx RenameContext) *WorkspaceEdit {
+	return &WorkspaceEdit{}
+}
+
+func (stubRenameProvider) PrepareRename(uri, content string, position Position) *Range {
+	return &Range{}
+}
+
+type stubFormattingProvider struct{}
+
+func (stubFormattingProvider) ProvideFormatting(uri, content string, options FormattingOptions) []TextEdit {
+	return []TextEdit{{NewText: "formatted"}}
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	var none *ReadOnlyMode
+	if none.ReadOnly() || none.Check() != nil {
+		t.Error("a nil read-only mode disables edits")
+	}
+
+	mode := NewReadOnlyMode(true)
+	if !mode.ReadOnly() || !errors.Is(mode.Check(), ErrReadOnly) {
+		t.Error("read-only mode allows edits")
+	}
+
+	fixes := &ReadOnlyCodeFixProvider{Provider: stubCodeFixProvider{}, Mode: mode}
+	actions := fixes.ProvideCodeFixes(CodeFixContext{})
+	if len(actions) != 2 {
+		t.Fatalf("read-only: got %d code actions, want 2", len(actions))
+	}
+	if actions[0].Disabled != nil || actions[0].Command == nil {
+		t.Errorf("read-only: command action %+v, want it kept", actions[0])
+	}
+	if actions[1].Disabled == nil || actions[1].Disabled.Reason != ReadOnlyReason || actions[1].Edit != nil {
+		t.Errorf("read-only: edit action %+v, want it disabled", actions[1])
+	}
+
+	rename := &ReadOnlyRenameProvider{Provider: stubRenameProvider{}, Mode: mode}
+	if rename.ProvideRename(RenameContext{}) != nil || rename.PrepareRename("", "", Position{}) != nil {
+		t.Error("read-only: rename is available")
+	}
+	formatting := &ReadOnlyFormattingProvider{Provider: stubFormattingProvider{}, Mode: mode}
+	if edits := formatting.ProvideFormatting("", "", FormattingOptions{}); edits != nil {
+		t.Errorf("read-only: formatting edits %v, want none", edits)
+	}
+
+	mode.SetReadOnly(false)
+	if actions := fixes.ProvideCodeFixes(CodeFixContext{}); actions[1].Disabled != nil || actions[1].Edit == nil {
+		t.Errorf("writable: edit action %+v, want it enabled", actions[1])
+	}
+	if rename.ProvideRename(RenameContext{}) == nil || rename.PrepareRename("", "", Position{}) == nil {
+		t.Error("writable: rename is not available")
+	}
+	if edits := formatting.ProvideFormatting("", "", FormattingOptions{}); len(edits) != 1 {
+		t.Errorf("writable: got %d formatting edits, want 1", len(edits))
+	}
+	if edits := formatting.ProvideRangeFormatting("", "", Range{}, FormattingOptions{}); edits != nil {
+		t.Errorf("range formatting with a provider that cannot = %v, want none", edits)
+	}
+}
@@ -0,0 +1,274 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RenameConflictAnnotation is the change annotation id marking edits that
+// conflicted with edits of another rename provider.
+const RenameConflictAnnotation = "rename-conflict"
+
+// RenameConflict describes two edits of different providers that change
+// overlapping text. The coordinator keeps the edit of the provider
+// registered first and drops the other.
+type RenameConflict struct {
+	// URI is the document both edits change.
+	URI string
+
+	// Kept is the edit applied, by the provider named KeptBy.
+	Kept   TextEdit
+	KeptBy string
+
+	// Dropped is the edit left out, by the provider named DroppedBy.
+	Dropped   TextEdit
+	DroppedBy string
+}
+
+// RenameCoordinator combines several rename providers, such as one renaming
+// Go identifiers, one updating references in comments and strings, and one
+// updating file references in Markdown, into a single RenameProvider.
+//
+// Their workspace edits are merged into one. Identical edits from several
+// providers are applied once. When edits of different providers overlap,
+// the edit of the provider registered first wins. The kept edit carries the
+// RenameConflictAnnotation change annotation, which needs confirmation, so
+// the client asks the user before applying it.
+type RenameCoordinator struct {
+	providers []RenameProvider
+	names     []string
+}
+
+// NewRenameCoordinator creates a new rename coordinator.
+func NewRenameCoordinator() *RenameCoordinator {
+	return &RenameCoordinator{}
+}
+
+// Register adds a rename provider. name identifies the provider in
+// conflicts; providers registered earlier take precedence.
+func (c *RenameCoordinator) Register(name string, provider RenameProvider) {
+	c.providers = append(c.providers, provider)
+	c.names = append(c.names, name)
+}
+
+// ProvideRename returns the merged edit of all providers, or nil if no
+// provider can rename at the position.
+func (c *RenameCoordinator) ProvideRename(ctx RenameContext) *WorkspaceEdit {
+	edit, _ := c.Rename(ctx)
+	return edit
+}
+
+// PrepareRename returns the range of the first registered provider that
+// implements PrepareRenameProvider and can rename at the position.
+func (c *RenameCoordinator) PrepareRename(uri, content string, position Position) *Range {
+	for _, provider := range c.providers {
+		if prepare, ok := provider.(PrepareRenameProvider); ok {
+			if rng := prepare.PrepareRename(uri, content, position); rng != nil {
+				return rng
+			}
+		}
+	}
+	return nil
+}
+
+// Rename returns the merged edit of all providers and the conflicts found
+// while merging.
+func (c *RenameCoordinator) Rename(ctx RenameContext) (*WorkspaceEdit, []RenameConflict) {
+	merger := renameMerger{
+		edits:    make(map[string][]providerEdit),
+		versions: make(map[string]*int),
+	}
+
+	found := false
+	for i, provider := range c.providers {
+		edit := provider.ProvideRename(ctx)
+		if edit == nil {
+			continue
+		}
+		found = true
+		merger.add(c.names[i], edit)
+	}
+	if !found {
+		return nil, nil
+	}
+	return merger.result(), merger.conflicts
+}
+
+// providerEdit is a text edit and the provider that made it.
+type providerEdit struct {
+	TextEdit
+	provider     string
+	annotationID *string
+	conflicted   []string
+}
+
+type renameMerger struct {
+	edits       map[string][]providerEdit
+	versions    map[string]*int
+	operations  []interface{}
+	annotations map[string]ChangeAnnotation
+	conflicts   []RenameConflict
+}
+
+func (m *renameMerger) add(provider string, edit *WorkspaceEdit) {
+	for uri, textEdits := range edit.Changes {
+		for _, textEdit := range textEdits {
+			m.addTextEdit(provider, uri, textEdit, nil)
+		}
+	}
+
+	for _, change := range edit.DocumentChanges {
+		var documentEdit *TextDocumentEdit
+		switch c := change.(type) {
+		case TextDocumentEdit:
+			documentEdit = &c
+		case *TextDocumentEdit:
+			documentEdit = c
+		default:
+			m.operations = append(m.operations, change)
+			continue
+		}
+
+		uri := documentEdit.TextDocument.URI
+		if m.versions[uri] == nil {
+			m.versions[uri] = documentEdit.TextDocument.Version
+		}
+		for _, textEdit := range documentEdit.Edits {
+			m.addTextEdit(provider, uri, textEdit, nil)
+		}
+		for _, annotated := range documentEdit.AnnotatedEdits {
+			m.addTextEdit(provider, uri, annotated.TextEdit, annotated.AnnotationID)
+		}
+	}
+
+	for id, annotation := range edit.ChangeAnnotations {
+		if m.annotations == nil {
+			m.annotations = make(map[string]ChangeAnnotation)
+		}
+		m.annotations[id] = annotation
+	}
+}
+
+func (m *renameMerger) addTextEdit(provider, uri string, edit TextEdit, annotationID *string) {
+	accepted := m.edits[uri]
+	for i := range accepted {
+		existing := &accepted[i]
+		if existing.TextEdit == edit {
+			// Providers agree
+			return
+		}
+		if editsOverlap(existing.Range, edit.Range) {
+			if existing.provider == provider {
+				// A provider's own edits are its responsibility
+				continue
+			}
+			existing.conflicted = append(existing.conflicted, provider)
+			m.conflicts = append(m.conflicts, RenameConflict{
+				URI:       uri,
+				Kept:      existing.TextEdit,
+				KeptBy:    existing.provider,
+				Dropped:   edit,
+				DroppedBy: provider,
+			})
+			return
+		}
+	}
+	m.edits[uri] = append(accepted, providerEdit{TextEdit: edit, provider: provider, annotationID: annotationID})
+}
+
+// result builds the merged workspace edit. Without conflicts, annotations,
+// versions, or resource operations it uses the simple Changes form, which
+// every client supports.
+func (m *renameMerger) result() *WorkspaceEdit {
+	uris := make([]string, 0, len(m.edits))
+	for uri, edits := range m.edits {
+		uris = append(uris, uri)
+		sort.SliceStable(edits, func(i, j int) bool {
+			return ComparePositions(edits[i].Range.Start, edits[j].Range.Start) < 0
+		})
+	}
+	sort.Strings(uris)
+
+	simple := len(m.conflicts) == 0 && len(m.operations) == 0 && len(m.annotations) == 0
+	for _, uri := range uris {
+		if m.versions[uri] != nil {
+			simple = false
+		}
+		for _, edit := range m.edits[uri] {
+			if edit.annotationID != nil {
+				simple = false
+			}
+		}
+	}
+
+	result := &WorkspaceEdit{}
+	if simple {
+		result.Changes = make(map[string][]TextEdit, len(uris))
+		for _, uri := range uris {
+			for _, edit := range m.edits[uri] {
+				result.Changes[uri] = append(result.Changes[uri], edit.TextEdit)
+			}
+		}
+		return result
+	}
+
+	conflictID := RenameConflictAnnotation
+	for _, uri := range uris {
+		documentEdit := TextDocumentEdit{
+			TextDocument: VersionedTextDocumentIdentifier{URI: uri, Version: m.versions[uri]},
+		}
+		for _, edit := range m.edits[uri] {
+			switch {
+			case len(edit.conflicted) > 0:
+				documentEdit.AnnotatedEdits = append(documentEdit.AnnotatedEdits, AnnotatedTextEdit{
+					TextEdit:     edit.TextEdit,
+					AnnotationID: &conflictID,
+				})
+			case edit.annotationID != nil:
+				documentEdit.AnnotatedEdits = append(documentEdit.AnnotatedEdits, AnnotatedTextEdit{
+					TextEdit:     edit.TextEdit,
+					AnnotationID: edit.annotationID,
+				})
+			default:
+				documentEdit.Edits = append(documentEdit.Edits, edit.TextEdit)
+			}
+		}
+		result.DocumentChanges = append(result.DocumentChanges, documentEdit)
+	}
+
+	// Resource operations come last, so text edits still address the
+	// documents by their old URIs
+	result.DocumentChanges = append(result.DocumentChanges, m.operations...)
+
+	if len(m.annotations) > 0 || len(m.conflicts) > 0 {
+		result.ChangeAnnotations = make(map[string]ChangeAnnotation, len(m.annotations)+1)
+		for id, annotation := range m.annotations {
+			result.ChangeAnnotations[id] = annotation
+		}
+	}
+	if len(m.conflicts) > 0 {
+		result.ChangeAnnotations[RenameConflictAnnotation] = ChangeAnnotation{
+			Label:             "Conflicting rename edits",
+			NeedsConfirmation: true,
+			Description:       conflictDescription(m.conflicts),
+		}
+	}
+	return result
+}
+
+// editsOverlap reports whether two edits change the same text, or insert
+// at the same position, so that their combined result is ambiguous.
+func editsOverlap(a, b Range) bool {
+	if RangesOverlap(a, b) {
+		return true
+	}
+	return a.Start == a.End && b.Start == b.End && a.Start == b.Start
+}
+
+func conflictDescription(conflicts []RenameConflict) string {
+	if len(conflicts) == 1 {
+		c := conflicts[0]
+		return fmt.Sprintf("%s and %s disagree on an edit in %s; the edit of %s was kept.", c.KeptBy, c.DroppedBy, c.URI, c.KeptBy)
+	}
+	return fmt.Sprintf("Rename providers disagree on %d edits; the edits of the earlier registered providers were kept.", len(conflicts))
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+type fixedRenameProvider struct {
+	edit *WorkspaceEdit
+}
+
+func (p fixedRenameProvider) ProvideRename(ctx RenameContext) *WorkspaceEdit {
+	return p.edit
+}
+
+func renameEdit(line, start, end int, text string) TextEdit {
+	return TextEdit{
+		Range: Range{
+			Start: Position{Line: line, Character: start},
+			End:   Position{Line: line, Character: end},
+		},
+		NewText: text,
+	}
+}
+
+func TestRenameCoordinatorMergesWithoutConflicts(t *testing.T) {
+	coordinator := NewRenameCoordinator()
+	coordinator.Register("go", fixedRenameProvider{&WorkspaceEdit{
+		Changes: map[string][]TextEdit{
+			"file:///a.go": {renameEdit(3, 5, 8, "Bar"), renameEdit(0, 5, 8, "Bar")},
+		},
+	}})
+	coordinator.Register("comments", fixedRenameProvider{&WorkspaceEdit{
+		Changes: map[string][]TextEdit{
+			// Same edit as the Go provider: applied once
+			"file:///a.go":      {renameEdit(0, 5, 8, "Bar"), renameEdit(2, 3, 6, "Bar")},
+			"file:///README.md": {renameEdit(1, 0, 3, "Bar")},
+		},
+	}})
+	coordinator.Register("none", fixedRenameProvider{})
+
+	edit, conflicts := coordinator.Rename(RenameContext{URI: "file:///a.go", NewName: "Bar"})
+	if len(conflicts) != 0 {
+		t.Fatalf("conflicts = %v, want none", conflicts)
+	}
+	want := map[string][]TextEdit{
+		"file:///a.go":      {renameEdit(0, 5, 8, "Bar"), renameEdit(2, 3, 6, "Bar"), renameEdit(3, 5, 8, "Bar")},
+		"file:///README.md": {renameEdit(1, 0, 3, "Bar")},
+	}
+	if !reflect.DeepEqual(edit.Changes, want) {
+		t.Errorf("Changes = %v, want %v", edit.Changes, want)
+	}
+	if edit.DocumentChanges != nil || edit.ChangeAnnotations != nil {
+		t.Errorf("expected a plain Changes edit, got %+v", edit)
+	}
+}
+
+func TestRenameCoordinatorConflicts(t *testing.T) {
+	coordinator := NewRenameCoordinator()
+	coordinator.Register("go", fixedRenameProvider{&WorkspaceEdit{
+		Changes: map[string][]TextEdit{
+			"file:///a.go": {renameEdit(0, 5, 8, "Bar")},
+		},
+	}})
+	coordinator.Register("strings", fixedRenameProvider{&WorkspaceEdit{
+		DocumentChanges: []interface{}{
+			TextDocumentEdit{
+				TextDocument: VersionedTextDocumentIdentifier{URI: "file:///a.go"},
+				Edits:        []TextEdit{renameEdit(0, 4, 9, `"Bar"`), renameEdit(1, 0, 3, "Bar")},
+			},
+			RenameFile{OldURI: "file:///foo.md", NewURI: "file:///bar.md"},
+		},
+	}})
+
+	edit, conflicts := coordinator.Rename(RenameContext{URI: "file:///a.go", NewName: "Bar"})
+	wantConflicts := []RenameConflict{{
+		URI:       "file:///a.go",
+		Kept:      renameEdit(0, 5, 8, "Bar"),
+		KeptBy:    "go",
+		Dropped:   renameEdit(0, 4, 9, `"Bar"`),
+		DroppedBy: "strings",
+	}}
+	if !reflect.DeepEqual(conflicts, wantConflicts) {
+		t.Fatalf("conflicts = %+v, want %+v", conflicts, wantConflicts)
+	}
+
+	if edit.Changes != nil {
+		t.Errorf("Changes = %v, want nil", edit.Changes)
+	}
+	if len(edit.DocumentChanges) != 2 {
+		t.Fatalf("len(DocumentChanges) = %d, want 2", len(edit.DocumentChanges))
+	}
+	documentEdit := edit.DocumentChanges[0].(TextDocumentEdit)
+	if want := []TextEdit{renameEdit(1, 0, 3, "Bar")}; !reflect.DeepEqual(documentEdit.Edits, want) {
+		t.Errorf("Edits = %v, want %v", documentEdit.Edits, want)
+	}
+	if len(documentEdit.AnnotatedEdits) != 1 ||
+		documentEdit.AnnotatedEdits[0].TextEdit != renameEdit(0, 5, 8, "Bar") ||
+		*documentEdit.AnnotatedEdits[0].AnnotationID != RenameConflictAnnotation {
+		t.Errorf("AnnotatedEdits = %+v, want the kept edit annotated", documentEdit.AnnotatedEdits)
+	}
+	if _, ok := edit.DocumentChanges[1].(RenameFile); !ok {
+		t.Errorf("DocumentChanges[1] = %T, want RenameFile last", edit.DocumentChanges[1])
+	}
+	if annotation := edit.ChangeAnnotations[RenameConflictAnnotation]; !annotation.NeedsConfirmation {
+		t.Errorf("conflict annotation = %+v, want NeedsConfirmation", annotation)
+	}
+}
+
+func TestRenameCoordinatorInsertionsConflict(t *testing.T) {
+	coordinator := NewRenameCoordinator()
+	coordinator.Register("a", fixedRenameProvider{&WorkspaceEdit{
+		Changes: map[string][]TextEdit{"file:///a.go": {renameEdit(0, 2, 2, "x")}},
+	}})
+	coordinator.Register("b", fixedRenameProvider{&WorkspaceEdit{
+		Changes: map[string][]TextEdit{"file:///a.go": {renameEdit(0, 2, 2, "y")}},
+	}})
+
+	_, conflicts := coordinator.Rename(RenameContext{URI: "file:///a.go"})
+	if len(conflicts) != 1 || conflicts[0].KeptBy != "a" {
+		t.Errorf("conflicts = %+v, want one kept by a", conflicts)
+	}
+}
+
+func TestRenameCoordinatorNoEdits(t *testing.T) {
+	coordinator := NewRenameCoordinator()
+	coordinator.Register("none", fixedRenameProvider{})
+	if edit := coordinator.ProvideRename(RenameContext{}); edit != nil {
+		t.Errorf("ProvideRename() = %+v, want nil", edit)
+	}
+}
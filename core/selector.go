@@ -0,0 +1,186 @@
+package core
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DocumentFilter denotes a document by language, URI scheme, and path
+// pattern. Empty fields match any document; a filter with all fields empty
+// matches every document.
+//
+// LSP Specification: https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#documentFilter
+type DocumentFilter struct {
+	// Language is a language id, like "go" or "typescript".
+	Language string
+
+	// Scheme is a URI scheme, like "file" or "untitled".
+	Scheme string
+
+	// Pattern is a glob pattern matched against the document's path, like
+	// "**/*.{ts,js}". See MatchGlob for the syntax.
+	Pattern string
+}
+
+// DocumentSelector is a combination of document filters. A document matches
+// the selector if it matches any of its filters.
+//
+// LSP Specification: https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#documentSelector
+type DocumentSelector []DocumentFilter
+
+// Matches returns true if the document with the given URI and language id
+// matches the filter. A filter with a Language never matches a document
+// whose language id is unknown ("").
+func (f DocumentFilter) Matches(uri, languageID string) bool {
+	if f.Language != "" && f.Language != languageID {
+		return false
+	}
+
+	scheme, documentPath := splitURI(uri)
+	if f.Scheme != "" && !strings.EqualFold(f.Scheme, scheme) {
+		return false
+	}
+	if f.Pattern != "" && !MatchGlob(f.Pattern, documentPath) {
+		return false
+	}
+	return true
+}
+
+// Matches returns true if the document matches any filter of the selector.
+// An empty selector matches no document.
+func (s DocumentSelector) Matches(uri, languageID string) bool {
+	for _, filter := range s {
+		if filter.Matches(uri, languageID) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchGlob reports whether a slash-separated path matches an LSP glob
+// pattern:
+//   - * matches any characters within a path segment
+//   - ? matches one character within a path segment
+//   - ** matches any number of path segments, including none
+//   - {a,b} matches any of the comma-separated alternatives, which may
+//     contain patterns themselves
+//   - [a-z] matches a character in a range, and [!a-z] one not in it
+//
+// A pattern without a slash matches the last segment of the path (the file
+// name) in any directory, so "*.go" behaves like "**/*.go". Invalid patterns
+// match nothing.
+func MatchGlob(pattern, filePath string) bool {
+	re := compileGlob(pattern)
+	if re == nil {
+		return false
+	}
+	if !strings.Contains(pattern, "/") {
+		filePath = path.Base(filePath)
+	}
+	return re.MatchString(filePath)
+}
+
+// globCache holds compiled glob patterns, or nil for invalid patterns.
+// Selectors are matched on every request, so patterns are only compiled once.
+var globCache sync.Map
+
+func compileGlob(pattern string) *regexp.Regexp {
+	if cached, ok := globCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp)
+	}
+
+	var re *regexp.Regexp
+	if expr, rest, ok := globToRegexp(pattern, false); ok && rest == "" {
+		re, _ = regexp.Compile("^" + expr + "$")
+	}
+	globCache.Store(pattern, re)
+	return re
+}
+
+// globToRegexp translates a glob pattern to a regular expression. Inside a
+// {} group it stops at the ',' or '}' ending the current alternative and
+// returns the rest of the pattern.
+func globToRegexp(pattern string, inGroup bool) (expr string, rest string, ok bool) {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				atStart := i == 0 || pattern[i-1] == '/'
+				i++
+				switch {
+				case atStart && i+1 < len(pattern) && pattern[i+1] == '/':
+					// "**/" matches any number of leading segments
+					b.WriteString("(?:.*/)?")
+					i++
+				default:
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+
+		case '?':
+			b.WriteString("[^/]")
+
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end == -1 {
+				return "", "", false
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+
+		case '{':
+			var alternatives []string
+			remaining := pattern[i+1:]
+			for {
+				alternative, after, ok := globToRegexp(remaining, true)
+				if !ok || after == "" {
+					return "", "", false
+				}
+				alternatives = append(alternatives, alternative)
+				if after[0] == '}' {
+					remaining = after[1:]
+					break
+				}
+				remaining = after[1:]
+			}
+			b.WriteString("(?:" + strings.Join(alternatives, "|") + ")")
+			expr, rest, ok := globToRegexp(remaining, inGroup)
+			return b.String() + expr, rest, ok
+
+		case ',', '}':
+			if inGroup {
+				return b.String(), pattern[i:], true
+			}
+			b.WriteString(regexp.QuoteMeta(string(c)))
+
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return b.String(), "", !inGroup
+}
+
+// splitURI returns the scheme and the decoded path of a URI. URIs that
+// cannot be parsed are treated as paths without a scheme.
+func splitURI(uri string) (scheme, documentPath string) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", uri
+	}
+	if u.Opaque != "" {
+		return u.Scheme, u.Opaque
+	}
+	return u.Scheme, u.Path
+}
@@ -0,0 +1,143 @@
+package core
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		matches bool
+	}{
+		{"**/*.go", "/home/me/project/main.go", true},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "/home/me/main.gox", false},
+		{"*.go", "/home/me/project/main.go", true},
+		{"*.go", "/home/me/project/main.ts", false},
+		{"/src/*.go", "/src/main.go", true},
+		{"/src/*.go", "/src/pkg/main.go", false},
+		{"/src/**/*.go", "/src/main.go", true},
+		{"/src/**/*.go", "/src/a/b/main.go", true},
+		{"/src/**", "/src/a/b/main.go", true},
+		{"**/*.{ts,js}", "/web/app.ts", true},
+		{"**/*.{ts,js}", "/web/app.js", true},
+		{"**/*.{ts,js}", "/web/app.tsx", false},
+		{"**/{cmd,internal/*}/main.go", "/repo/internal/tool/main.go", true},
+		{"**/{cmd,internal/*}/main.go", "/repo/pkg/main.go", false},
+		{"**/*.{t{s,sx},js}", "/web/app.tsx", true},
+		{"example.[0-9]", "/logs/example.1", true},
+		{"example.[0-9]", "/logs/example.a", false},
+		{"example.[!0-9]", "/logs/example.a", true},
+		{"example.[!0-9]", "/logs/example.1", false},
+		{"go.?od", "/repo/go.mod", true},
+		{"**/go.?od", "/repo/go.mod", true},
+		{"**/a+b(c).go", "/x/a+b(c).go", true},
+		{"**/*.{go", "/x/main.go", false},
+		{"**/[a-z.go", "/x/a", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.path); got != tt.matches {
+			t.Errorf("MatchGlob(%q, %q) = %v, expected %v", tt.pattern, tt.path, got, tt.matches)
+		}
+	}
+}
+
+func TestDocumentSelectorMatches(t *testing.T) {
+	selector := DocumentSelector{
+		{Language: "go", Scheme: "file"},
+		{Pattern: "**/*.mod"},
+	}
+
+	tests := []struct {
+		name     string
+		uri      string
+		language string
+		matches  bool
+	}{
+		{"go file", "file:///src/main.go", "go", true},
+		{"go untitled", "untitled:Untitled-1", "go", false},
+		{"unknown language", "file:///src/main.go", "", false},
+		{"pattern", "file:///src/go.mod", "", true},
+		{"escaped path", "file:///my%20project/go.mod", "", true},
+		{"other", "file:///src/main.py", "python", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selector.Matches(tt.uri, tt.language); got != tt.matches {
+				t.Errorf("Matches(%q, %q) = %v, expected %v", tt.uri, tt.language, got, tt.matches)
+			}
+		})
+	}
+
+	if (DocumentSelector{}).Matches("file:///a.go", "go") {
+		t.Error("expected empty selector to match nothing")
+	}
+	if !(DocumentFilter{}).Matches("untitled:x", "") {
+		t.Error("expected empty filter to match everything")
+	}
+}
+
+func TestLanguageRegistry(t *testing.T) {
+	languages := NewLanguageRegistry(
+		Language{ID: "go", Extensions: []string{".go"}},
+		Language{ID: "go.mod", Filenames: []string{"go.mod"}},
+	)
+	languages.Register(Language{ID: "markdown", Extensions: []string{".md", ".markdown"}})
+
+	tests := []struct {
+		uri      string
+		expected string
+	}{
+		{"file:///src/main.go", "go"},
+		{"file:///src/go.mod", "go.mod"},
+		{"file:///README.MD", "markdown"},
+		{"file:///Makefile", ""},
+	}
+	for _, tt := range tests {
+		if got := languages.LanguageID(tt.uri); got != tt.expected {
+			t.Errorf("LanguageID(%q) = %q, expected %q", tt.uri, got, tt.expected)
+		}
+	}
+
+	languages.Register(Language{ID: "go", Extensions: []string{".go", ".go2"}})
+	if language, ok := languages.Lookup("go"); !ok || len(language.Extensions) != 2 {
+		t.Errorf("expected registering an existing id to replace it, got %+v", language)
+	}
+
+	var none *LanguageRegistry
+	if none.LanguageID("file:///main.go") != "" {
+		t.Error("expected nil registry to know no languages")
+	}
+}
+
+type fixedDiagnosticProvider struct {
+	message string
+}
+
+func (p fixedDiagnosticProvider) ProvideDiagnostics(uri, content string) []Diagnostic {
+	return []Diagnostic{{Message: p.message}}
+}
+
+func TestDiagnosticRegistryRoutesBySelector(t *testing.T) {
+	registry := NewDiagnosticRegistry()
+	registry.Languages = NewLanguageRegistry(Language{ID: "go", Extensions: []string{".go"}})
+	registry.Register(fixedDiagnosticProvider{"all"})
+	registry.RegisterFor(DocumentSelector{{Language: "go"}}, fixedDiagnosticProvider{"go"})
+	registry.RegisterFor(DocumentSelector{{Pattern: "**/*.md"}}, fixedDiagnosticProvider{"markdown"})
+
+	messages := func(uri string) []string {
+		var result []string
+		for _, d := range registry.ProvideDiagnostics(uri, "") {
+			result = append(result, d.Message)
+		}
+		return result
+	}
+
+	if got := messages("file:///main.go"); len(got) != 2 || got[0] != "all" || got[1] != "go" {
+		t.Errorf("unexpected diagnostics for Go file: %v", got)
+	}
+	if got := messages("file:///README.md"); len(got) != 2 || got[1] != "markdown" {
+		t.Errorf("unexpected diagnostics for Markdown file: %v", got)
+	}
+}
@@ -0,0 +1,216 @@
+package core
+
+import "strings"
+
+// DefaultSuppressionDirectives are the directives a Suppressor created
+// without any recognizes.
+var DefaultSuppressionDirectives = []string{"nolint", "lsp:ignore"}
+
+// Suppressor suppresses diagnostics with inline comment directives. A
+// directive at the end of a line suppresses the diagnostics starting on
+// that line; a directive alone on a line suppresses those of the next line:
+//
+//	x := compute() //nolint
+//	//lsp:ignore unused
+//	var cache map[string]int
+//	f.Close() //nolint:errcheck,gosec // closed twice on purpose
+//
+// A directive without names suppresses every diagnostic. Names, after a
+// colon or a space and separated by commas, restrict it to diagnostics
+// whose code or source is one of them. Set it as the Suppressor of a
+// DiagnosticRegistry to filter diagnostics; as a CodeFixProvider it offers
+// to suppress diagnostics with its first directive.
+type Suppressor struct {
+	// Directives are the directives recognized, like "nolint". If empty,
+	// DefaultSuppressionDirectives are.
+	Directives []string
+
+	// CommentPrefix starts the comments of directives. If empty, it is
+	// "//".
+	CommentPrefix string
+}
+
+// NewSuppressor creates a suppressor recognizing directives, or
+// DefaultSuppressionDirectives if none are given.
+func NewSuppressor(directives ...string) *Suppressor {
+	return &Suppressor{Directives: directives}
+}
+
+func (s *Suppressor) directives() []string {
+	if len(s.Directives) == 0 {
+		return DefaultSuppressionDirectives
+	}
+	return s.Directives
+}
+
+func (s *Suppressor) commentPrefix() string {
+	if s.CommentPrefix == "" {
+		return "//"
+	}
+	return s.CommentPrefix
+}
+
+// suppressionDirective is a directive found on a line.
+type suppressionDirective struct {
+	// names are the codes and sources suppressed; none suppresses all.
+	names []string
+
+	// namesEnd is the offset in the line after the directive and its
+	// names.
+	namesEnd int
+}
+
+// parseDirective returns the directive on a line, and whether it is alone
+// on the line.
+func (s *Suppressor) parseDirective(line string) (directive suppressionDirective, alone, ok bool) {
+	prefix := s.commentPrefix()
+	for _, name := range s.directives() {
+		comment := prefix + name
+		i := strings.Index(line, comment)
+		for i >= 0 {
+			end := i + len(comment)
+			rest := line[end:]
+			// The directive must end, like "//nolint" but not "//nolintx"
+			if rest == "" || rest[0] == ':' || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r' {
+				break
+			}
+			next := strings.Index(rest, comment)
+			if next < 0 {
+				i = -1
+				break
+			}
+			i = end + next
+		}
+		if i < 0 {
+			continue
+		}
+
+		end := i + len(comment)
+		directive.namesEnd = end
+		rest := line[end:]
+		// An explanation may follow the names, like "//nolint:errcheck // why"
+		if n := strings.Index(rest, prefix); n >= 0 {
+			rest = rest[:n]
+		}
+		if rest != "" && (rest[0] == ':' || rest[0] == ' ' || rest[0] == '\t') {
+			if list := strings.TrimSpace(rest[1:]); list != "" {
+				directive.namesEnd = end + 1 + strings.Index(rest[1:], list) + len(list)
+				for _, n := range strings.Split(list, ",") {
+					if n = strings.TrimSpace(n); n != "" {
+						directive.names = append(directive.names, n)
+					}
+				}
+			}
+		}
+		return directive, strings.TrimSpace(line[:i]) == "", true
+	}
+	return suppressionDirective{}, false, false
+}
+
+// suppressions returns the directives of content by the line they apply
+// to.
+func (s *Suppressor) suppressions(content string) map[int]suppressionDirective {
+	var found map[int]suppressionDirective
+	for number, line := range strings.Split(content, "\n") {
+		directive, alone, ok := s.parseDirective(line)
+		if !ok {
+			continue
+		}
+		if found == nil {
+			found = make(map[int]suppressionDirective)
+		}
+		if alone {
+			number++
+		}
+		found[number] = directive
+	}
+	return found
+}
+
+// suppresses reports whether a directive suppresses a diagnostic.
+func (d suppressionDirective) suppresses(diagnostic Diagnostic) bool {
+	if len(d.names) == 0 {
+		return true
+	}
+	for _, name := range d.names {
+		if strings.EqualFold(name, diagnostic.Source) || diagnostic.Code != nil && strings.EqualFold(name, diagnostic.Code.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the diagnostics of content not suppressed by a directive.
+func (s *Suppressor) Filter(content string, diagnostics []Diagnostic) []Diagnostic {
+	found := s.suppressions(content)
+	if len(found) == 0 {
+		return diagnostics
+	}
+	var kept []Diagnostic
+	for _, diagnostic := range diagnostics {
+		if directive, ok := found[diagnostic.Range.Start.Line]; ok && directive.suppresses(diagnostic) {
+			continue
+		}
+		kept = append(kept, diagnostic)
+	}
+	return kept
+}
+
+// ProvideCodeFixes offers to suppress each diagnostic in the context by
+// its code, or its source if it has no code, with the first directive at
+// the end of its line. A directive already on the line gets the name added.
+func (s *Suppressor) ProvideCodeFixes(ctx CodeFixContext) []CodeAction {
+	if len(ctx.Diagnostics) == 0 {
+		return nil
+	}
+	lines := strings.Split(ctx.Content, "\n")
+
+	var actions []CodeAction
+	for _, diagnostic := range ctx.Diagnostics {
+		number := diagnostic.Range.Start.Line
+		if number < 0 || number >= len(lines) {
+			continue
+		}
+		line := strings.TrimRight(lines[number], "\r")
+
+		name := diagnostic.Source
+		if diagnostic.Code != nil {
+			name = diagnostic.Code.String()
+		}
+
+		var edit TextEdit
+		if directive, alone, ok := s.parseDirective(line); ok {
+			// One alone on the line is about the next line
+			if directive.suppresses(diagnostic) || name == "" || alone {
+				continue
+			}
+			at := Position{Line: number, Character: directive.namesEnd}
+			edit = TextEdit{Range: Range{Start: at, End: at}, NewText: "," + name}
+		} else {
+			comment := " " + s.commentPrefix() + s.directives()[0]
+			if name != "" {
+				separator := ":"
+				if strings.Contains(s.directives()[0], ":") {
+					separator = " "
+				}
+				comment += separator + name
+			}
+			at := Position{Line: number, Character: len(line)}
+			edit = TextEdit{Range: Range{Start: at, End: at}, NewText: comment}
+		}
+
+		title := "Suppress diagnostic"
+		if name != "" {
+			title = "Suppress " + name
+		}
+		title += " on this line"
+		kind := CodeActionKindQuickFix
+		actions = append(actions, CodeAction{
+			Title:       title,
+			Kind:        &kind,
+			Diagnostics: []Diagnostic{diagnostic},
+			Edit:        &WorkspaceEdit{Changes: map[string][]TextEdit{ctx.URI: {edit}}},
+		})
+	}
+	return actions
+}
@@ -0,0 +1,72 @@
+package core
+
+import "testing"
+
+func TestSuppressorFilter(t *testing.T) {
+	content := `package main
+
+func main() {
+	x := compute() //nolint
+	f.Close() //nolint:errcheck // closed twice on purpose
+	//lsp:ignore unused,shadow
+	var cache map[string]int
+	y := other() //nolintx
+	z := last() //nolint:vet
+}
+`
+	errcheck := NewStringCode("errcheck")
+	unused := NewStringCode("unused")
+	diagnostics := []Diagnostic{
+		{Message: "all", Range: Range{Start: Position{Line: 3}}},
+		{Message: "errcheck", Code: &errcheck, Range: Range{Start: Position{Line: 4}}},
+		{Message: "unused", Code: &unused, Range: Range{Start: Position{Line: 6}}},
+		{Message: "other directive", Range: Range{Start: Position{Line: 7}}},
+		{Message: "by source", Source: "vet", Range: Range{Start: Position{Line: 8}}},
+		{Message: "other code", Code: &unused, Range: Range{Start: Position{Line: 8}}},
+	}
+
+	registry := NewDiagnosticRegistry()
+	registry.Suppressor = NewSuppressor()
+	registry.Register(fixedDiagnostics(diagnostics))
+	kept := registry.ProvideDiagnostics("file:///main.go", content)
+	if len(kept) != 2 || kept[0].Message != "other directive" || kept[1].Message != "other code" {
+		t.Errorf("ProvideDiagnostics kept %+v, want the diagnostics not suppressed", kept)
+	}
+}
+
+func TestSuppressorCodeFixes(t *testing.T) {
+	content := "a := 1\nb := 2 //nolint:errcheck\nc := 3 //nolint:unused\n"
+	unused := NewStringCode("unused")
+	diagnostics := []Diagnostic{
+		{Message: "unused", Code: &unused, Range: Range{Start: Position{Line: 0}}},
+		{Message: "vet", Source: "vet", Range: Range{Start: Position{Line: 1}}},
+		{Message: "suppressed", Code: &unused, Range: Range{Start: Position{Line: 2}}},
+	}
+
+	actions := NewSuppressor().ProvideCodeFixes(CodeFixContext{URI: "file:///main.go", Content: content, Diagnostics: diagnostics})
+	if len(actions) != 2 {
+		t.Fatalf("ProvideCodeFixes = %+v, want an action per diagnostic not suppressed", actions)
+	}
+	tests := []struct {
+		title string
+		edit  TextEdit
+	}{
+		{"Suppress unused on this line", TextEdit{Range: Range{Start: Position{Line: 0, Character: 6}, End: Position{Line: 0, Character: 6}}, NewText: " //nolint:unused"}},
+		{"Suppress vet on this line", TextEdit{Range: Range{Start: Position{Line: 1, Character: 24}, End: Position{Line: 1, Character: 24}}, NewText: ",vet"}},
+	}
+	for i, tt := range tests {
+		action := actions[i]
+		if action.Title != tt.title {
+			t.Errorf("action %d title = %q, want %q", i, action.Title, tt.title)
+		}
+		edits := action.Edit.Changes["file:///main.go"]
+		if len(edits) != 1 || edits[0] != tt.edit {
+			t.Errorf("action %d edits = %+v, want %+v", i, edits, tt.edit)
+		}
+	}
+
+	ignore := NewSuppressor("lsp:ignore").ProvideCodeFixes(CodeFixContext{URI: "file:///main.go", Content: content, Diagnostics: diagnostics[:1]})
+	if got := ignore[0].Edit.Changes["file:///main.go"][0].NewText; got != " //lsp:ignore unused" {
+		t.Errorf("lsp:ignore edit = %q, want a space before the names", got)
+	}
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// UntrustedWorkspaceReason is the reason given for features disabled in an
+// untrusted workspace.
+const UntrustedWorkspaceReason = "The workspace is not trusted, so features that run code are disabled"
+
+// ErrUntrustedWorkspace is returned for operations that would run code of
+// an untrusted workspace.
+var ErrUntrustedWorkspace = errors.New(UntrustedWorkspaceReason)
+
+// WorkspaceTrust records whether the user trusts the workspace, e.g. from
+// the initialization options of the client or a custom request. Features
+// that run code of the workspace or shell out, like running tests or
+// external formatters, must check it first: opening a repository should
+// not be enough to run its code.
+//
+// A nil WorkspaceTrust trusts every workspace. It is safe for concurrent
+// use.
+type WorkspaceTrust struct {
+	untrusted atomic.Bool
+}
+
+// NewWorkspaceTrust creates a trust record.
+func NewWorkspaceTrust(trusted bool) *WorkspaceTrust {
+	t := &WorkspaceTrust{}
+	t.SetTrusted(trusted)
+	return t
+}
+
+// Trusted reports whether the workspace is trusted.
+func (t *WorkspaceTrust) Trusted() bool {
+	return t == nil || !t.untrusted.Load()
+}
+
+// SetTrusted changes whether the workspace is trusted. A nil WorkspaceTrust
+// has nowhere to record it, so calling SetTrusted on one panics rather than
+// keep trusting the workspace.
+func (t *WorkspaceTrust) SetTrusted(trusted bool) {
+	if t == nil {
+		panic("core: SetTrusted on a nil *WorkspaceTrust")
+	}
+	t.untrusted.Store(!trusted)
+}
+
+// Check returns ErrUntrustedWorkspace if the workspace is not trusted.
+func (t *WorkspaceTrust) Check() error {
+	if !t.Trusted() {
+		return ErrUntrustedWorkspace
+	}
+	return nil
+}
+
+// TrustedCodeLensProvider provides the code lenses of a provider whose
+// commands run code, like running tests, only in a trusted workspace.
+type TrustedCodeLensProvider struct {
+	Provider CodeLensProvider
+	Trust    *WorkspaceTrust
+}
+
+func (p *TrustedCodeLensProvider) ProvideCodeLenses(ctx CodeLensContext) []CodeLens {
+	if !p.Trust.Trusted() {
+		return nil
+	}
+	return p.Provider.ProvideCodeLenses(ctx)
+}
+
+// TrustedCodeFixProvider disables the code actions of a provider that run
+// a command in an untrusted workspace, giving UntrustedWorkspaceReason as
+// the reason, so the user sees why they are not available. Actions that
+// only edit are kept.
+type TrustedCodeFixProvider struct {
+	Provider CodeFixProvider
+	Trust    *WorkspaceTrust
+}
+
+func (p *TrustedCodeFixProvider) ProvideCodeFixes(ctx CodeFixContext) []CodeAction {
+	actions := p.Provider.ProvideCodeFixes(ctx)
+	if p.Trust.Trusted() {
+		return actions
+	}
+	for i := range actions {
+		if actions[i].Command != nil {
+			actions[i].Command = nil
+			actions[i].Edit = nil
+			actions[i].IsPreferred = false
+			actions[i].Disabled = &CodeActionDisabled{Reason: UntrustedWorkspaceReason}
+		}
+	}
+	return actions
+}
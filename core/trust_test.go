@@ -0,0 +1,68 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+type stubCodeLensProvider struct{}
+
+func (stubCodeLensProvider) ProvideCodeLenses(ctx CodeLensContext) []CodeLens {
+	return []CodeLens{{Command: &Command{Title: "Run TestA", Command: "go.test.run"}}}
+}
+
+type stubCodeFixProvider struct{}
+
+func (stubCodeFixProvider) ProvideCodeFixes(ctx CodeFixContext) []CodeAction {
+	return []CodeAction{
+		{Title: "Run go generate", Command: &Command{Command: "go.generate"}, IsPreferred: true},
+		{Title: "Remove unused import", Edit: &WorkspaceEdit{}},
+	}
+}
+
+func TestWorkspaceTrust(t *testing.T) {
+	var none *WorkspaceTrust
+	if !none.Trusted() || none.Check() != nil {
+		t.Error("a nil trust does not trust the workspace")
+	}
+
+	trust := NewWorkspaceTrust(false)
+	if trust.Trusted() || !errors.Is(trust.Check(), ErrUntrustedWorkspace) {
+		t.Error("untrusted workspace is trusted")
+	}
+
+	lenses := &TrustedCodeLensProvider{Provider: stubCodeLensProvider{}, Trust: trust}
+	if got := lenses.ProvideCodeLenses(CodeLensContext{}); len(got) != 0 {
+		t.Errorf("untrusted: got %d code lenses, want 0", len(got))
+	}
+
+	fixes := &TrustedCodeFixProvider{Provider: stubCodeFixProvider{}, Trust: trust}
+	actions := fixes.ProvideCodeFixes(CodeFixContext{})
+	if len(actions) != 2 {
+		t.Fatalf("untrusted: got %d code actions, want 2", len(actions))
+	}
+	if actions[0].Disabled == nil || actions[0].Disabled.Reason != UntrustedWorkspaceReason || actions[0].Command != nil || actions[0].IsPreferred {
+		t.Errorf("untrusted: command action %+v, want it disabled", actions[0])
+	}
+	if actions[1].Disabled != nil || actions[1].Edit == nil {
+		t.Errorf("untrusted: edit action %+v, want it kept", actions[1])
+	}
+
+	trust.SetTrusted(true)
+	if got := lenses.ProvideCodeLenses(CodeLensContext{}); len(got) != 1 {
+		t.Errorf("trusted: got %d code lenses, want 1", len(got))
+	}
+	if actions := fixes.ProvideCodeFixes(CodeFixContext{}); actions[0].Disabled != nil || actions[0].Command == nil {
+		t.Errorf("trusted: command action %+v, want it enabled", actions[0])
+	}
+}
+
+func TestWorkspaceTrustSetTrustedNil(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("SetTrusted on a nil trust did not panic")
+		}
+	}()
+	var none *WorkspaceTrust
+	none.SetTrusted(false)
+}
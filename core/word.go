@@ -0,0 +1,100 @@
+package core
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/SCKelemen/unicode/uax29"
+)
+
+// WordAt returns the word at the given position and its range.
+//
+// Words are found with Unicode word boundaries (UAX #29). Word boundaries never
+// span a line break, so only the line containing pos is scanned; the cost does
+// not grow with the size of the document.
+//
+// If the cursor is not on a word but directly after one (e.g. at the end of an
+// identifier being typed), that word is returned. A segment counts as a word if
+// it contains a letter, digit, or underscore. If there is no word at pos, or
+// pos is beyond the end of the document, WordAt returns "" and an empty Range.
+func WordAt(content string, pos Position) (string, Range) {
+	lineStart, lineEnd, ok := lineBounds(content, pos.Line)
+	if !ok {
+		return "", Range{}
+	}
+	line := content[lineStart:lineEnd]
+
+	offset := pos.Character
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(line) {
+		offset = len(line)
+	}
+
+	breaks := uax29.FindWordBreaks(line)
+
+	// Find the segment containing the cursor and the one ending at it
+	var prevStart, prevEnd int
+	hasPrev := false
+	for i := 0; i+1 < len(breaks); i++ {
+		start, end := breaks[i], breaks[i+1]
+		if offset >= start && offset < end {
+			if isWordSegment(line[start:end]) {
+				return line[start:end], lineRange(pos.Line, start, end)
+			}
+			break
+		}
+		if end == offset {
+			prevStart, prevEnd, hasPrev = start, end, true
+		}
+	}
+
+	if hasPrev && isWordSegment(line[prevStart:prevEnd]) {
+		return line[prevStart:prevEnd], lineRange(pos.Line, prevStart, prevEnd)
+	}
+
+	return "", Range{}
+}
+
+// lineBounds returns the byte offsets of the start and end (excluding the
+// newline) of the given zero-based line.
+func lineBounds(content string, line int) (start, end int, ok bool) {
+	if line < 0 {
+		return 0, 0, false
+	}
+	for current := 0; current < line; current++ {
+		next := strings.IndexByte(content[start:], '\n')
+		if next == -1 {
+			return 0, 0, false
+		}
+		start += next + 1
+	}
+
+	end = strings.IndexByte(content[start:], '\n')
+	if end == -1 {
+		end = len(content)
+	} else {
+		end += start
+	}
+	return start, end, true
+}
+
+// lineRange returns the range of bytes start to end on the given line.
+func lineRange(line, start, end int) Range {
+	return Range{
+		Start: Position{Line: line, Character: start},
+		End:   Position{Line: line, Character: end},
+	}
+}
+
+// isWordSegment reports whether a word-break segment is a word rather than
+// whitespace or punctuation.
+func isWordSegment(segment string) bool {
+	for _, r := range segment {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return true
+		}
+	}
+	return false
+}
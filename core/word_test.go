@@ -0,0 +1,131 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestWordAt(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		pos       Position
+		wantWord  string
+		wantRange Range
+	}{
+		{
+			name:      "inside word",
+			content:   "func calculateSum() {}",
+			pos:       Position{Line: 0, Character: 10},
+			wantWord:  "calculateSum",
+			wantRange: Range{Start: Position{Line: 0, Character: 5}, End: Position{Line: 0, Character: 17}},
+		},
+		{
+			name:      "start of word",
+			content:   "var value = 1",
+			pos:       Position{Line: 0, Character: 4},
+			wantWord:  "value",
+			wantRange: Range{Start: Position{Line: 0, Character: 4}, End: Position{Line: 0, Character: 9}},
+		},
+		{
+			name:      "directly after word",
+			content:   "x := valu",
+			pos:       Position{Line: 0, Character: 9},
+			wantWord:  "valu",
+			wantRange: Range{Start: Position{Line: 0, Character: 5}, End: Position{Line: 0, Character: 9}},
+		},
+		{
+			name:      "before punctuation after word",
+			content:   "print(x)",
+			pos:       Position{Line: 0, Character: 5},
+			wantWord:  "print",
+			wantRange: Range{Start: Position{Line: 0, Character: 0}, End: Position{Line: 0, Character: 5}},
+		},
+		{
+			// UAX #29 keeps letters joined by "." together
+			name:      "dotted word",
+			content:   "fmt.Println(x)",
+			pos:       Position{Line: 0, Character: 1},
+			wantWord:  "fmt.Println",
+			wantRange: Range{Start: Position{Line: 0, Character: 0}, End: Position{Line: 0, Character: 11}},
+		},
+		{
+			name:     "whitespace between words",
+			content:  "a  b",
+			pos:      Position{Line: 0, Character: 2},
+			wantWord: "",
+		},
+		{
+			name:     "punctuation",
+			content:  "x = y + z",
+			pos:      Position{Line: 0, Character: 6},
+			wantWord: "",
+		},
+		{
+			name:      "later line",
+			content:   "package main\n\nfunc main() {\n\tresult := 42\n}",
+			pos:       Position{Line: 3, Character: 3},
+			wantWord:  "result",
+			wantRange: Range{Start: Position{Line: 3, Character: 1}, End: Position{Line: 3, Character: 7}},
+		},
+		{
+			name:      "end of line before newline",
+			content:   "first\nsecond",
+			pos:       Position{Line: 0, Character: 5},
+			wantWord:  "first",
+			wantRange: Range{Start: Position{Line: 0, Character: 0}, End: Position{Line: 0, Character: 5}},
+		},
+		{
+			name:      "CRLF line ending",
+			content:   "first\r\nsecond",
+			pos:       Position{Line: 0, Character: 5},
+			wantWord:  "first",
+			wantRange: Range{Start: Position{Line: 0, Character: 0}, End: Position{Line: 0, Character: 5}},
+		},
+		{
+			name:      "non-ASCII word",
+			content:   "x := naïve + 1",
+			pos:       Position{Line: 0, Character: 7},
+			wantWord:  "naïve",
+			wantRange: Range{Start: Position{Line: 0, Character: 5}, End: Position{Line: 0, Character: 11}},
+		},
+		{
+			// UAX #29 treats each ideograph as its own word
+			name:      "ideographs",
+			content:   "x := 你好",
+			pos:       Position{Line: 0, Character: 9},
+			wantWord:  "好",
+			wantRange: Range{Start: Position{Line: 0, Character: 8}, End: Position{Line: 0, Character: 11}},
+		},
+		{
+			name:      "character beyond end of line",
+			content:   "name\nother",
+			pos:       Position{Line: 0, Character: 40},
+			wantWord:  "name",
+			wantRange: Range{Start: Position{Line: 0, Character: 0}, End: Position{Line: 0, Character: 4}},
+		},
+		{
+			name:     "line beyond end of document",
+			content:  "var x",
+			pos:      Position{Line: 10, Character: 0},
+			wantWord: "",
+		},
+		{
+			name:     "empty document",
+			content:  "",
+			pos:      Position{Line: 0, Character: 0},
+			wantWord: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			word, r := WordAt(tt.content, tt.pos)
+			if word != tt.wantWord {
+				t.Errorf("got word %q, want %q", word, tt.wantWord)
+			}
+			if r != tt.wantRange {
+				t.Errorf("got range %v, want %v", r, tt.wantRange)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package core
+
+import "sort"
+
+// WorkspaceEditBuilder composes the text edits of several changes, like the
+// fixes of several diagnostics, into one workspace edit without conflicts.
+// A change is added whole or not at all: if one of its edits overlaps an
+// edit added before, the change is rejected, so the edit built never
+// applies half of a change. Edits identical to one added before are
+// applied once.
+type WorkspaceEditBuilder struct {
+	edits map[string][]TextEdit
+}
+
+// NewWorkspaceEditBuilder creates an empty builder.
+func NewWorkspaceEditBuilder() *WorkspaceEditBuilder {
+	return &WorkspaceEditBuilder{edits: make(map[string][]TextEdit)}
+}
+
+// Add adds a change of the document uri, reporting whether it was added
+// rather than rejected for conflicting with the changes added before.
+func (b *WorkspaceEditBuilder) Add(uri string, edits ...TextEdit) bool {
+	return b.add(map[string][]TextEdit{uri: edits})
+}
+
+// AddWorkspaceEdit adds the text edits of a workspace edit as one change,
+// in its Changes or DocumentChanges form, reporting whether it was added.
+// Edits with resource operations, like renaming a file, are rejected, as
+// their text edits cannot be checked against the others.
+func (b *WorkspaceEditBuilder) AddWorkspaceEdit(edit *WorkspaceEdit) bool {
+	if edit == nil {
+		return true
+	}
+	changes := make(map[string][]TextEdit)
+	for uri, edits := range edit.Changes {
+		changes[uri] = append(changes[uri], edits...)
+	}
+	for _, change := range edit.DocumentChanges {
+		var documentEdit *TextDocumentEdit
+		switch c := change.(type) {
+		case TextDocumentEdit:
+			documentEdit = &c
+		case *TextDocumentEdit:
+			documentEdit = c
+		default:
+			return false
+		}
+		uri := documentEdit.TextDocument.URI
+		changes[uri] = append(changes[uri], documentEdit.Edits...)
+		for _, annotated := range documentEdit.AnnotatedEdits {
+			changes[uri] = append(changes[uri], annotated.TextEdit)
+		}
+	}
+	return b.add(changes)
+}
+
+func (b *WorkspaceEditBuilder) add(changes map[string][]TextEdit) bool {
+	added := make(map[string][]TextEdit, len(changes))
+	for uri, edits := range changes {
+	edit:
+		for _, edit := range edits {
+			for _, existing := range b.edits[uri] {
+				if existing == edit {
+					continue edit
+				}
+				if editsOverlap(existing.Range, edit.Range) {
+					return false
+				}
+			}
+			added[uri] = append(added[uri], edit)
+		}
+	}
+	for uri, edits := range added {
+		b.edits[uri] = append(b.edits[uri], edits...)
+	}
+	return true
+}
+
+// Edits returns the edits added for the document uri, in document order.
+func (b *WorkspaceEditBuilder) Edits(uri string) []TextEdit {
+	edits := append([]TextEdit(nil), b.edits[uri]...)
+	sort.SliceStable(edits, func(i, j int) bool {
+		return ComparePositions(edits[i].Range.Start, edits[j].Range.Start) < 0
+	})
+	return edits
+}
+
+// Build returns the workspace edit of the changes added, or nil if there
+// are none.
+func (b *WorkspaceEditBuilder) Build() *WorkspaceEdit {
+	if len(b.edits) == 0 {
+		return nil
+	}
+	edit := &WorkspaceEdit{Changes: make(map[string][]TextEdit, len(b.edits))}
+	for uri := range b.edits {
+		edit.Changes[uri] = b.Edits(uri)
+	}
+	return edit
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWorkspaceEditBuilder(t *testing.T) {
+	edit := func(line, start, end int, text string) TextEdit {
+		return TextEdit{Range: Range{Start: Position{Line: line, Character: start}, End: Position{Line: line, Character: end}}, NewText: text}
+	}
+
+	b := NewWorkspaceEditBuilder()
+	if b.Build() != nil {
+		t.Error("expected no edit from an empty builder")
+	}
+	if !b.Add("file:///a.go", edit(2, 0, 3, "two"), edit(0, 0, 3, "one")) {
+		t.Fatal("first change rejected")
+	}
+	// A change overlapping an edit is rejected whole
+	if b.Add("file:///a.go", edit(4, 0, 1, "x"), edit(2, 2, 5, "y")) {
+		t.Error("overlapping change added")
+	}
+	// Identical edits are applied once
+	if !b.Add("file:///a.go", edit(0, 0, 3, "one"), edit(1, 0, 0, "inserted")) {
+		t.Error("change repeating an edit rejected")
+	}
+	if !b.AddWorkspaceEdit(&WorkspaceEdit{DocumentChanges: []interface{}{
+		TextDocumentEdit{TextDocument: VersionedTextDocumentIdentifier{URI: "file:///b.go"}, Edits: []TextEdit{edit(0, 0, 0, "b")}},
+	}}) {
+		t.Error("document change rejected")
+	}
+	if b.AddWorkspaceEdit(&WorkspaceEdit{DocumentChanges: []interface{}{RenameFile{OldURI: "file:///b.go", NewURI: "file:///c.go"}}}) {
+		t.Error("resource operation added")
+	}
+
+	want := &WorkspaceEdit{Changes: map[string][]TextEdit{
+		"file:///a.go": {edit(0, 0, 3, "one"), edit(1, 0, 0, "inserted"), edit(2, 0, 3, "two")},
+		"file:///b.go": {edit(0, 0, 0, "b")},
+	}}
+	if got := b.Build(); !reflect.DeepEqual(got, want) {
+		t.Errorf("built %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,209 @@
+package examples
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/imports"
+)
+
+// GoPackageMember is an exported declaration of a Go package.
+type GoPackageMember struct {
+	Name       string
+	Kind       core.CompletionItemKind
+	Detail     string
+	Deprecated bool
+}
+
+// GoPackageIndex maps import paths to the exported members of the packages.
+type GoPackageIndex map[string][]GoPackageMember
+
+// StdlibPackageIndex indexes a few commonly used standard library packages.
+var StdlibPackageIndex = GoPackageIndex{
+	"errors": {
+		{Name: "New", Kind: core.CompletionItemKindFunction, Detail: "func(text string) error"},
+		{Name: "Is", Kind: core.CompletionItemKindFunction, Detail: "func(err, target error) bool"},
+		{Name: "As", Kind: core.CompletionItemKindFunction, Detail: "func(err error, target any) bool"},
+		{Name: "Join", Kind: core.CompletionItemKindFunction, Detail: "func(errs ...error) error"},
+	},
+	"fmt": {
+		{Name: "Errorf", Kind: core.CompletionItemKindFunction, Detail: "func(format string, a ...any) error"},
+		{Name: "Println", Kind: core.CompletionItemKindFunction, Detail: "func(a ...any) (n int, err error)"},
+		{Name: "Printf", Kind: core.CompletionItemKindFunction, Detail: "func(format string, a ...any) (n int, err error)"},
+		{Name: "Sprintf", Kind: core.CompletionItemKindFunction, Detail: "func(format string, a ...any) string"},
+	},
+	"os": {
+		{Name: "Args", Kind: core.CompletionItemKindVariable, Detail: "[]string"},
+		{Name: "Exit", Kind: core.CompletionItemKindFunction, Detail: "func(code int)"},
+		{Name: "Getenv", Kind: core.CompletionItemKindFunction, Detail: "func(key string) string"},
+		{Name: "ReadFile", Kind: core.CompletionItemKindFunction, Detail: "func(name string) ([]byte, error)"},
+		{Name: "WriteFile", Kind: core.CompletionItemKindFunction, Detail: "func(name string, data []byte, perm FileMode) error"},
+	},
+	"path/filepath": {
+		{Name: "Base", Kind: core.CompletionItemKindFunction, Detail: "func(path string) string"},
+		{Name: "Dir", Kind: core.CompletionItemKindFunction, Detail: "func(path string) string"},
+		{Name: "Join", Kind: core.CompletionItemKindFunction, Detail: "func(elem ...string) string"},
+	},
+	"strconv": {
+		{Name: "Atoi", Kind: core.CompletionItemKindFunction, Detail: "func(s string) (int, error)"},
+		{Name: "Itoa", Kind: core.CompletionItemKindFunction, Detail: "func(i int) string"},
+		{Name: "Quote", Kind: core.CompletionItemKindFunction, Detail: "func(s string) string"},
+	},
+	"strings": {
+		{Name: "Builder", Kind: core.CompletionItemKindStruct, Detail: "struct"},
+		{Name: "Contains", Kind: core.CompletionItemKindFunction, Detail: "func(s, substr string) bool"},
+		{Name: "HasPrefix", Kind: core.CompletionItemKindFunction, Detail: "func(s, prefix string) bool"},
+		{Name: "Join", Kind: core.CompletionItemKindFunction, Detail: "func(elems []string, sep string) string"},
+		{Name: "Split", Kind: core.CompletionItemKindFunction, Detail: "func(s, sep string) []string"},
+		{Name: "Title", Kind: core.CompletionItemKindFunction, Detail: "func(s string) string", Deprecated: true},
+		{Name: "TrimSpace", Kind: core.CompletionItemKindFunction, Detail: "func(s string) string"},
+	},
+	"time": {
+		{Name: "Duration", Kind: core.CompletionItemKindClass, Detail: "int64"},
+		{Name: "Now", Kind: core.CompletionItemKindFunction, Detail: "func() Time"},
+		{Name: "Second", Kind: core.CompletionItemKindConstant, Detail: "Duration"},
+		{Name: "Since", Kind: core.CompletionItemKindFunction, Detail: "func(t Time) Duration"},
+	},
+}
+
+// packagesNamed returns the import paths of the indexed packages with the
+// given package name, sorted.
+func (index GoPackageIndex) packagesNamed(name string) []string {
+	var paths []string
+	for path := range index {
+		if path[strings.LastIndexByte(path, '/')+1:] == name {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// PackageMemberCompletionProvider completes the members of packages after
+// a dot, like "strings.", from a package index. When the file does not
+// import the package yet, accepting an item also adds the import, through
+// the item's AdditionalTextEdits.
+type PackageMemberCompletionProvider struct {
+	// Index lists the packages to complete. If nil, StdlibPackageIndex.
+	Index GoPackageIndex
+}
+
+func (p *PackageMemberCompletionProvider) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
+	if !strings.HasSuffix(ctx.URI, ".go") {
+		return nil
+	}
+	index := p.Index
+	if index == nil {
+		index = StdlibPackageIndex
+	}
+
+	nameStart, cursor := completionWord(ctx.Content, ctx.Position)
+	if nameStart == 0 || ctx.Content[nameStart-1] != '.' {
+		return nil
+	}
+	pkgEnd := nameStart - 1
+	pkgStart, _ := completionWord(ctx.Content, core.ByteOffsetToPosition(ctx.Content, pkgEnd))
+	if pkgStart == pkgEnd || (pkgStart > 0 && ctx.Content[pkgStart-1] == '.') {
+		return nil
+	}
+	pkgName := ctx.Content[pkgStart:pkgEnd]
+	typed := ctx.Content[nameStart:cursor]
+
+	// Parse without the dot and the member being typed, which leaves the
+	// package name as an expression
+	content := ctx.Content[:pkgEnd] + ctx.Content[cursor:]
+	fset, f, err := parseGoFile(ctx.URI+"#members", content)
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	imported := false
+	if path := imports.NewFile(content, fset, f).Path(pkgName); path != "" {
+		paths = []string{path}
+		imported = true
+	} else {
+		// A local declaration hides packages of the same name
+		info := typeCheckGoFile(fset, f)
+		for ident, obj := range info.Uses {
+			if ident.Name == pkgName && fset.Position(ident.Pos()).Offset == pkgStart && obj != nil {
+				return nil
+			}
+		}
+		paths = index.packagesNamed(pkgName)
+	}
+
+	var items []core.CompletionItem
+	for _, path := range paths {
+		var importEdits []core.TextEdit
+		if !imported {
+			importEdits = imports.NewFile(content, fset, f).Add(path, "")
+		}
+		for _, member := range index[path] {
+			if !strings.HasPrefix(strings.ToLower(member.Name), strings.ToLower(typed)) {
+				continue
+			}
+			kind := member.Kind
+			detail := member.Detail
+			if !imported {
+				detail += " (import " + path + ")"
+			}
+			items = append(items, core.CompletionItem{
+				Label: member.Name,
+				Kind:  &kind,
+				Tags:  deprecatedCompletionTags(member.Deprecated),
+				LabelDetails: &core.CompletionItemLabelDetails{
+					Detail:      labelDetail(member.Detail),
+					Description: path,
+				},
+				Detail:              detail,
+				AdditionalTextEdits: importEdits,
+			})
+		}
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+	return &core.CompletionList{Items: items}
+}
+
+// unimportedPackageItems returns completion items for the indexed packages
+// whose name starts with prefix and that the file does not import. Each
+// item adds the import when accepted.
+func unimportedPackageItems(uri, content string, index GoPackageIndex, prefix string, declared func(name string) bool) []core.CompletionItem {
+	fset, f, err := parseGoFile(uri, content)
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	for path := range index {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	file := imports.NewFile(content, fset, f)
+	var items []core.CompletionItem
+	for _, path := range paths {
+		name := path[strings.LastIndexByte(path, '/')+1:]
+		if !strings.HasPrefix(name, prefix) || declared(name) {
+			continue
+		}
+		edits := file.Add(path, "")
+		if edits == nil {
+			continue
+		}
+		kind := core.CompletionItemKindModule
+		items = append(items, core.CompletionItem{
+			Label:               name,
+			Kind:                &kind,
+			LabelDetails:        &core.CompletionItemLabelDetails{Description: path},
+			Detail:              "import " + strconv.Quote(path),
+			AdditionalTextEdits: edits,
+		})
+	}
+	return items
+}
@@ -0,0 +1,182 @@
+package examples
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+func TestPackageMemberCompletionProvider(t *testing.T) {
+	provider := &PackageMemberCompletionProvider{}
+
+	tests := []struct {
+		name       string
+		content    string
+		wantLabels []string
+		wantImport string
+	}{
+		{
+			name: "unimported package",
+			content: `package main
+
+import "os"
+
+func main() {
+	strings.Tr|
+	_ = os.Args
+}
+`,
+			wantLabels: []string{"TrimSpace"},
+			wantImport: `package main
+
+import "os"
+import "strings"
+`,
+		},
+		{
+			name: "imported package",
+			content: `package main
+
+import (
+	"fmt"
+)
+
+func main() {
+	fmt.|
+}
+`,
+			wantLabels: []string{"Errorf", "Println", "Printf", "Sprintf"},
+		},
+		{
+			name: "nested package",
+			content: `package main
+
+func main() {
+	filepath.J|
+}
+`,
+			wantLabels: []string{"Join"},
+			wantImport: `package main
+
+import "path/filepath"
+
+func main() {`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset := strings.Index(tt.content, "|")
+			content := tt.content[:offset] + tt.content[offset+1:]
+			list := provider.ProvideCompletions(core.CompletionContext{
+				URI:      "file:///members_" + strings.ReplaceAll(tt.name, " ", "_") + ".go",
+				Content:  content,
+				Position: core.ByteOffsetToPosition(content, offset),
+			})
+			if list == nil {
+				t.Fatal("expected completions, got nil")
+			}
+
+			var labels []string
+			for _, item := range list.Items {
+				labels = append(labels, item.Label)
+				if tt.wantImport == "" && len(item.AdditionalTextEdits) != 0 {
+					t.Errorf("%s: unexpected import edits %v", item.Label, item.AdditionalTextEdits)
+				}
+				if tt.wantImport != "" {
+					if result := applyTextEdits(content, item.AdditionalTextEdits); !strings.HasPrefix(result, tt.wantImport) {
+						t.Errorf("%s: after import edits:\n%s\nwant prefix:\n%s", item.Label, result, tt.wantImport)
+					}
+				}
+			}
+			if strings.Join(labels, ",") != strings.Join(tt.wantLabels, ",") {
+				t.Errorf("labels = %v, want %v", labels, tt.wantLabels)
+			}
+		})
+	}
+}
+
+func TestPackageMemberCompletionProviderLocalShadows(t *testing.T) {
+	content := "package main\n\nfunc main() {\n\tvar strings []string\n\tstrings.\n}\n"
+	list := (&PackageMemberCompletionProvider{}).ProvideCompletions(core.CompletionContext{
+		URI:      "file:///members_shadow.go",
+		Content:  content,
+		Position: core.Position{Line: 4, Character: 9},
+	})
+	if list != nil {
+		t.Errorf("expected no package members for a local variable, got %v", list.Items)
+	}
+}
+
+func TestSymbolCompletionProviderPackages(t *testing.T) {
+	content := "package main\n\nfunc main() {\n\tstr\n}\n"
+	provider := &SymbolCompletionProvider{Packages: StdlibPackageIndex}
+	list := provider.ProvideCompletions(core.CompletionContext{
+		URI:      "file:///symbols_packages.go",
+		Content:  content,
+		Position: core.Position{Line: 3, Character: 4},
+	})
+	if list == nil {
+		t.Fatal("expected completions, got nil")
+	}
+
+	var found []string
+	for _, item := range list.Items {
+		if len(item.AdditionalTextEdits) == 1 {
+			found = append(found, item.Label)
+		}
+	}
+	if strings.Join(found, ",") != "strconv,strings" {
+		t.Errorf("auto-import items = %v, want strconv and strings", found)
+	}
+}
+
+func TestPackageMemberCompletionProviderDeprecated(t *testing.T) {
+	content := "package main\n\nimport \"strings\"\n\nfunc main() {\n\tstrings.T\n}\n"
+	list := (&PackageMemberCompletionProvider{}).ProvideCompletions(core.CompletionContext{
+		URI:      "file:///members_deprecated.go",
+		Content:  content,
+		Position: core.Position{Line: 5, Character: 10},
+	})
+	if list == nil {
+		t.Fatal("expected completions, got nil")
+	}
+
+	for _, item := range list.Items {
+		deprecated := len(item.Tags) == 1 && item.Tags[0] == core.CompletionItemTagDeprecated
+		if deprecated != (item.Label == "Title") {
+			t.Errorf("%s: tags = %v", item.Label, item.Tags)
+		}
+	}
+}
+
+func TestPackageMemberCompletionProviderLabelDetails(t *testing.T) {
+	content := "package main\n\nfunc main() {\n\tos.\n}\n"
+	list := (&PackageMemberCompletionProvider{}).ProvideCompletions(core.CompletionContext{
+		URI:      "file:///members_details.go",
+		Content:  content,
+		Position: core.Position{Line: 3, Character: 4},
+	})
+	if list == nil {
+		t.Fatal("expected completions, got nil")
+	}
+
+	want := map[string]core.CompletionItemLabelDetails{
+		"Args":     {Detail: " []string", Description: "os"},
+		"ReadFile": {Detail: "(name string) ([]byte, error)", Description: "os"},
+	}
+	for _, item := range list.Items {
+		details, ok := want[item.Label]
+		if !ok {
+			continue
+		}
+		if item.LabelDetails == nil || *item.LabelDetails != details {
+			t.Errorf("%s: label details = %+v, want %+v", item.Label, item.LabelDetails, details)
+		}
+		delete(want, item.Label)
+	}
+	if len(want) != 0 {
+		t.Errorf("missing items %v", want)
+	}
+}
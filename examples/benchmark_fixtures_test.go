@@ -0,0 +1,17 @@
+package examples
+
+import (
+	"fmt"
+	"strings"
+)
+
+// benchmarkGoSource returns a Go file with n small functions.
+// Each function is about 95 bytes.
+func benchmarkGoSource(n int) string {
+	var b strings.Builder
+	b.WriteString("package bench\n\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "// Func%d does work.\nfunc Func%d(a int, b string) (int, error) {\n\tx := a + %d\n\treturn x, nil\n}\n\n", i, i, i)
+	}
+	return b.String()
+}
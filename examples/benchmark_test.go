@@ -0,0 +1,80 @@
+package examples
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/SCKelemen/unicode/uax29"
+)
+
+// BenchmarkFindWordBreaks measures a full-document word-break scan, which
+// several providers run just to find the word at the cursor. The scan is
+// superlinear in document size, so sizes up to about 20KB are compared; larger
+// documents take seconds per scan.
+func BenchmarkFindWordBreaks(b *testing.B) {
+	for _, funcs := range []int{50, 100, 200} {
+		content := benchmarkGoSource(funcs)
+
+		b.Run(fmt.Sprintf("%dKB", len(content)/1024), func(b *testing.B) {
+			b.SetBytes(int64(len(content)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				uax29.FindWordBreaks(content)
+			}
+		})
+	}
+}
+
+func BenchmarkWorkspaceSymbolIndexFile(b *testing.B) {
+	content := benchmarkGoSource(500)
+	provider := NewGoWorkspaceSymbolProvider("/workspace")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		provider.IndexFile(fmt.Sprintf("file:///workspace/file%d.go", i%100), content)
+	}
+}
+
+func BenchmarkWorkspaceSymbolQuery(b *testing.B) {
+	provider := NewGoWorkspaceSymbolProvider("/workspace")
+	content := benchmarkGoSource(500)
+	for i := 0; i < 100; i++ {
+		provider.IndexFile(fmt.Sprintf("file:///workspace/file%d.go", i), content)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		provider.ProvideWorkspaceSymbols("Func42")
+	}
+}
+
+// BenchmarkWorkspaceSymbolQueryLarge queries a monorepo-sized index of
+// 125,000 symbols in 250 packages, with and without a result limit.
+func BenchmarkWorkspaceSymbolQueryLarge(b *testing.B) {
+	provider := NewGoWorkspaceSymbolProvider("/workspace")
+	content := benchmarkGoSource(500)
+	for i := 0; i < 250; i++ {
+		provider.IndexFile(fmt.Sprintf("file:///workspace/pkg%d/file.go", i), content)
+	}
+
+	for _, bench := range []struct {
+		name  string
+		query string
+		limit int
+	}{
+		{name: "match", query: "Func42"},
+		{name: "match-limit-100", query: "Func42", limit: 100},
+		{name: "everything-limit-100", query: "", limit: 100},
+		{name: "no-match", query: "Missing"},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			provider.Limit = bench.limit
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				provider.ProvideWorkspaceSymbols(bench.query)
+			}
+		})
+	}
+}
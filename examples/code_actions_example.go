@@ -3,11 +3,10 @@ package examples
 import (
 	"fmt"
 	"go/ast"
-	"go/parser"
-	"go/token"
 	"strings"
 
 	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/imports"
 )
 
 // UnusedImportProvider provides code actions to remove unused imports.
@@ -23,7 +22,7 @@ func (p *UnusedImportProvider) ProvideCodeFixes(ctx core.CodeFixContext) []core.
 	}
 
 	// Find unused imports
-	unused := p.findUnusedImports(ctx.Content)
+	unused := p.findUnusedImports(ctx.URI, ctx.Content)
 	if len(unused) == 0 {
 		return nil
 	}
@@ -46,15 +45,14 @@ func (p *UnusedImportProvider) ProvideCodeFixes(ctx core.CodeFixContext) []core.
 }
 
 type importInfo struct {
-	Path      string
-	StartLine int
-	EndLine   int
+	Path string
 }
 
-func (p *UnusedImportProvider) findUnusedImports(content string) []importInfo {
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", content, parser.ImportsOnly)
-	if err != nil {
+func (p *UnusedImportProvider) findUnusedImports(uri, content string) []importInfo {
+	// The shared parse covers the whole file; errors after the import block
+	// still leave the imports in the partial AST.
+	_, f, _ := parseGoFile(uri, content)
+	if f == nil {
 		return nil
 	}
 
@@ -66,14 +64,7 @@ func (p *UnusedImportProvider) findUnusedImports(content string) []importInfo {
 		// Simple heuristic: check if package name appears in code
 		pkgName := p.getPackageName(imp, importPath)
 		if !p.isImportUsed(content, pkgName) {
-			pos := fset.Position(imp.Pos())
-			end := fset.Position(imp.End())
-
-			unused = append(unused, importInfo{
-				Path:      importPath,
-				StartLine: pos.Line - 1, // 0-based
-				EndLine:   end.Line - 1,
-			})
+			unused = append(unused, importInfo{Path: importPath})
 		}
 	}
 
@@ -103,27 +94,23 @@ func (p *UnusedImportProvider) isImportUsed(content string, pkgName string) bool
 	return false
 }
 
-func (p *UnusedImportProvider) createRemovalEdit(uri, content string, unused []importInfo) *core.WorkspaceEdit {
-	var edits []core.TextEdit
-
-	lines := strings.Split(content, "\n")
+// removalEdits returns the edits removing imports, shared with organizing
+// imports and auto-import completion through the imports package.
+func (p *UnusedImportProvider) removalEdits(uri, content string, unused []importInfo) []core.TextEdit {
+	fset, f, _ := parseGoFile(uri, content)
+	file := imports.NewFile(content, fset, f)
 
+	var edits []core.TextEdit
 	for _, imp := range unused {
-		// Remove the entire line including newline
-		if imp.StartLine < len(lines) {
-			edits = append(edits, core.TextEdit{
-				Range: core.Range{
-					Start: core.Position{Line: imp.StartLine, Character: 0},
-					End:   core.Position{Line: imp.StartLine + 1, Character: 0},
-				},
-				NewText: "",
-			})
-		}
+		edits = append(edits, file.Remove(imp.Path)...)
 	}
+	return edits
+}
 
+func (p *UnusedImportProvider) createRemovalEdit(uri, content string, unused []importInfo) *core.WorkspaceEdit {
 	return &core.WorkspaceEdit{
 		Changes: map[string][]core.TextEdit{
-			uri: edits,
+			uri: p.removalEdits(uri, content, unused),
 		},
 	}
 }
@@ -136,15 +123,7 @@ func (p *UnusedImportProvider) createSingleRemovalAction(uri, content string, im
 		Kind:  &kind,
 		Edit: &core.WorkspaceEdit{
 			Changes: map[string][]core.TextEdit{
-				uri: {
-					{
-						Range: core.Range{
-							Start: core.Position{Line: imp.StartLine, Character: 0},
-							End:   core.Position{Line: imp.StartLine + 1, Character: 0},
-						},
-						NewText: "",
-					},
-				},
+				uri: p.removalEdits(uri, content, []importInfo{imp}),
 			},
 		},
 	}
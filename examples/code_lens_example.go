@@ -3,8 +3,6 @@ package examples
 import (
 	"fmt"
 	"go/ast"
-	"go/parser"
-	"go/token"
 	"regexp"
 	"strings"
 
@@ -22,8 +20,7 @@ func (p *TestRunnerCodeLensProvider) ProvideCodeLenses(ctx core.CodeLensContext)
 
 	var lenses []core.CodeLens
 
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", ctx.Content, parser.ParseComments)
+	fset, f, err := parseGoFile(ctx.URI, ctx.Content)
 	if err != nil {
 		return nil
 	}
@@ -85,8 +82,7 @@ func (p *ReferenceCountCodeLensProvider) ProvideCodeLenses(ctx core.CodeLensCont
 
 	var lenses []core.CodeLens
 
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", ctx.Content, parser.ParseComments)
+	fset, f, err := parseGoFile(ctx.URI, ctx.Content)
 	if err != nil {
 		return nil
 	}
@@ -99,11 +95,12 @@ func (p *ReferenceCountCodeLensProvider) ProvideCodeLenses(ctx core.CodeLensCont
 				continue
 			}
 
-			// Count references (simplified - just count occurrences)
-			count := strings.Count(ctx.Content, d.Name.Name) - 1 // -1 for declaration
-
+			// Count the references in this document, unless counted elsewhere
+			var count int
 			if p.ReferenceCounter != nil {
 				count = p.ReferenceCounter(ctx.URI, d.Name.Name)
+			} else {
+				count = referenceCount(ctx.URI, ctx.Content, fset.Position(d.Name.Pos()).Offset)
 			}
 
 			pos := fset.Position(d.Name.Pos())
@@ -130,10 +127,11 @@ func (p *ReferenceCountCodeLensProvider) ProvideCodeLenses(ctx core.CodeLensCont
 						continue
 					}
 
-					count := strings.Count(ctx.Content, ts.Name.Name) - 1
-
+					var count int
 					if p.ReferenceCounter != nil {
 						count = p.ReferenceCounter(ctx.URI, ts.Name.Name)
+					} else {
+						count = referenceCount(ctx.URI, ctx.Content, fset.Position(ts.Name.Pos()).Offset)
 					}
 
 					pos := fset.Position(ts.Name.Pos())
@@ -160,6 +158,19 @@ func (p *ReferenceCountCodeLensProvider) ProvideCodeLenses(ctx core.CodeLensCont
 	return lenses
 }
 
+// referenceCount returns the number of references in a document to the
+// symbol declared at offset, without the declaration.
+func referenceCount(uri, content string, offset int) int {
+	_, occurrences := findOccurrences(uri, content, core.ByteOffsetToPosition(content, offset))
+	count := 0
+	for _, o := range occurrences {
+		if !o.declaration {
+			count++
+		}
+	}
+	return count
+}
+
 // TODOCodeLensProvider shows actionable items for TODO comments.
 // This helps developers track and manage TODO items in code.
 type TODOCodeLensProvider struct{}
@@ -234,8 +245,7 @@ func (p *LazyCodeLensProvider) ProvideCodeLenses(ctx core.CodeLensContext) []cor
 
 	var lenses []core.CodeLens
 
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", ctx.Content, parser.ParseComments)
+	fset, f, err := parseGoFile(ctx.URI, ctx.Content)
 	if err != nil {
 		return nil
 	}
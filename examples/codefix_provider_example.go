@@ -263,6 +263,9 @@ func (s *ProviderBasedServer) TextDocumentCodeAction(
 		Diagnostics: coreDiagnostics,
 		Only:        convertCodeActionKinds(params.Context.Only),
 	}
+	if params.Context.TriggerKind != nil {
+		ctx.TriggerKind = core.CodeActionTriggerKind(*params.Context.TriggerKind)
+	}
 
 	// Get code actions using providers (core types)
 	coreActions := s.codeFixRegistry.ProvideCodeFixes(ctx)
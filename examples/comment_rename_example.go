@@ -0,0 +1,241 @@
+package examples
+
+import (
+	"go/scanner"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// Change annotations of the edits made by GoCommentRenameProvider. Clients
+// show each annotation as a group the user can accept or reject.
+const (
+	RenameInCommentsAnnotation = "rename-in-comments"
+	RenameInStringsAnnotation  = "rename-in-strings"
+)
+
+// GoCommentRenameProvider updates the old name of a renamed Go identifier
+// where it appears in comments and string literals of the package, such as
+// "// NewServer creates a Server." or an error message.
+//
+// Matches must be whole words. A match differing from the old name only in
+// the case of its first letter, like "Server" for "server" at the start of
+// a sentence, is replaced by the new name with the same first letter case.
+//
+// Text in comments and strings may only happen to look like the name, so
+// the edits carry change annotations needing confirmation. Renaming in
+// comments and strings is opt-in: register the provider next to a
+// GoRenameProvider with a core.RenameCoordinator, or use
+// NewGoRenameWithComments.
+type GoCommentRenameProvider struct {
+	// PackageFiles returns the content of every file in the package of the
+	// file at uri, by URI. The file being renamed in comes from the rename
+	// context and need not be included. If nil, only that file is updated.
+	PackageFiles func(uri string) map[string]string
+
+	// Strings also updates string literals. Comments are always updated.
+	Strings bool
+}
+
+// NewGoRenameWithComments returns a rename provider renaming Go identifiers
+// and, as edits needing confirmation, their occurrences in the comments and
+// strings of the package.
+func NewGoRenameWithComments(packageFiles func(uri string) map[string]string) *core.RenameCoordinator {
+	coordinator := core.NewRenameCoordinator()
+	coordinator.Register("go", &GoRenameProvider{})
+	coordinator.Register("comments", &GoCommentRenameProvider{PackageFiles: packageFiles, Strings: true})
+	return coordinator
+}
+
+// PrepareRename accepts the same positions as GoRenameProvider.
+func (p *GoCommentRenameProvider) PrepareRename(uri, content string, position core.Position) *core.Range {
+	return (&GoRenameProvider{}).PrepareRename(uri, content, position)
+}
+
+func (p *GoCommentRenameProvider) ProvideRename(ctx core.RenameContext) *core.WorkspaceEdit {
+	renameRange := p.PrepareRename(ctx.URI, ctx.Content, ctx.Position)
+	if renameRange == nil {
+		return nil
+	}
+	startOffset := core.PositionToByteOffset(ctx.Content, renameRange.Start)
+	endOffset := core.PositionToByteOffset(ctx.Content, renameRange.End)
+	if startOffset < 0 || endOffset > len(ctx.Content) {
+		return nil
+	}
+	oldName := ctx.Content[startOffset:endOffset]
+	if oldName == ctx.NewName {
+		return nil
+	}
+
+	files := map[string]string{}
+	if p.PackageFiles != nil {
+		for uri, content := range p.PackageFiles(ctx.URI) {
+			files[uri] = content
+		}
+	}
+	files[ctx.URI] = ctx.Content
+
+	uris := make([]string, 0, len(files))
+	for uri := range files {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+
+	commentsID := RenameInCommentsAnnotation
+	stringsID := RenameInStringsAnnotation
+	var changes []interface{}
+	used := map[string]bool{}
+
+	for _, uri := range uris {
+		content := files[uri]
+		var edits []core.AnnotatedTextEdit
+		for _, text := range scanCommentsAndStrings(content, p.Strings) {
+			id := &commentsID
+			if text.tok == token.STRING {
+				id = &stringsID
+			}
+			for _, match := range findNameInText(text.lit, oldName) {
+				offset := text.offset + match
+				matched := content[offset : offset+len(oldName)]
+				edits = append(edits, core.AnnotatedTextEdit{
+					TextEdit: core.TextEdit{
+						Range: core.Range{
+							Start: core.ByteOffsetToPosition(content, offset),
+							End:   core.ByteOffsetToPosition(content, offset+len(oldName)),
+						},
+						NewText: matchFirstLetterCase(ctx.NewName, oldName, matched),
+					},
+					AnnotationID: id,
+				})
+				used[*id] = true
+			}
+		}
+		if len(edits) > 0 {
+			changes = append(changes, core.TextDocumentEdit{
+				TextDocument:   core.VersionedTextDocumentIdentifier{URI: uri},
+				AnnotatedEdits: edits,
+			})
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	annotations := map[string]core.ChangeAnnotation{}
+	if used[commentsID] {
+		annotations[commentsID] = core.ChangeAnnotation{
+			Label:             "Rename in comments",
+			NeedsConfirmation: true,
+			Description:       "Occurrences of " + oldName + " in comments",
+		}
+	}
+	if used[stringsID] {
+		annotations[stringsID] = core.ChangeAnnotation{
+			Label:             "Rename in strings",
+			NeedsConfirmation: true,
+			Description:       "Occurrences of " + oldName + " in string literals",
+		}
+	}
+	return &core.WorkspaceEdit{DocumentChanges: changes, ChangeAnnotations: annotations}
+}
+
+// commentOrString is a comment or string literal token and its offset.
+type commentOrString struct {
+	tok    token.Token
+	lit    string
+	offset int
+}
+
+// scanCommentsAndStrings returns the comments, and the string literals if
+// withStrings is set, of Go source. Scanning tokens instead of parsing finds
+// them in files with syntax errors too.
+func scanCommentsAndStrings(src string, withStrings bool) []commentOrString {
+	file := addScanFile(len(src))
+	defer removeScanFile(file)
+
+	buf := borrowSource(src)
+	defer returnSource(buf)
+
+	var s scanner.Scanner
+	s.Init(file, *buf, nil, scanner.ScanComments)
+
+	var result []commentOrString
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.COMMENT || (tok == token.STRING && withStrings) {
+			offset := file.Offset(pos)
+			// The scanner strips carriage returns from raw strings and
+			// comments; the source text keeps offsets exact.
+			end := offset + len(lit)
+			if end > len(src) {
+				end = len(src)
+			}
+			if tok == token.COMMENT && strings.HasPrefix(lit, "/*") {
+				if i := strings.Index(src[offset:], "*/"); i >= 0 {
+					end = offset + i + 2
+				}
+			}
+			result = append(result, commentOrString{tok: tok, lit: src[offset:end], offset: offset})
+		}
+	}
+	return result
+}
+
+// findNameInText returns the byte offsets of the whole-word occurrences of
+// name in text, allowing a different case of its first letter.
+func findNameInText(text, name string) []int {
+	first, size := utf8.DecodeRuneInString(name)
+	rest := name[size:]
+
+	var offsets []int
+	for i := 0; i < len(text); {
+		r, n := utf8.DecodeRuneInString(text[i:])
+		if unicode.ToLower(r) == unicode.ToLower(first) &&
+			strings.HasPrefix(text[i+n:], rest) &&
+			!isNameRuneBefore(text, i) &&
+			!isNameRuneAt(text, i+n+len(rest)) &&
+			n == size {
+			offsets = append(offsets, i)
+			i += n + len(rest)
+			continue
+		}
+		i += n
+	}
+	return offsets
+}
+
+func isNameRuneBefore(text string, offset int) bool {
+	r, _ := utf8.DecodeLastRuneInString(text[:offset])
+	return offset > 0 && isNameRune(r)
+}
+
+func isNameRuneAt(text string, offset int) bool {
+	r, _ := utf8.DecodeRuneInString(text[offset:])
+	return offset < len(text) && isNameRune(r)
+}
+
+func isNameRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// matchFirstLetterCase returns newName, with the case of its first letter
+// changed if matched differs from oldName in the case of its first letter.
+func matchFirstLetterCase(newName, oldName, matched string) string {
+	if matched == oldName {
+		return newName
+	}
+	m, _ := utf8.DecodeRuneInString(matched)
+	r, size := utf8.DecodeRuneInString(newName)
+	if unicode.IsUpper(m) {
+		return string(unicode.ToUpper(r)) + newName[size:]
+	}
+	return string(unicode.ToLower(r)) + newName[size:]
+}
@@ -0,0 +1,108 @@
+package examples
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+func TestGoCommentRenameProvider(t *testing.T) {
+	content := `package server
+
+// server handles requests. Server is not safe for concurrent use.
+type server struct{}
+
+// newServer creates a server; see serverless and server_test.
+func newServer() *server {
+	println("server started")
+	return &server{}
+}
+`
+	other := `package server
+
+/* A server, or two servers. */
+var _ = "server"
+`
+
+	tests := []struct {
+		name     string
+		strings  bool
+		wantMain []string
+	}{
+		{"comments", false, []string{"client", "Client", "client"}},
+		{"comments and strings", true, []string{"client", "Client", "client", "client"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &GoCommentRenameProvider{
+				PackageFiles: func(uri string) map[string]string {
+					return map[string]string{"file:///other.go": other}
+				},
+				Strings: tt.strings,
+			}
+			edit := provider.ProvideRename(core.RenameContext{
+				URI:      "file:///main.go",
+				Content:  content,
+				Position: core.Position{Line: 3, Character: 6},
+				NewName:  "client",
+			})
+			if edit == nil {
+				t.Fatal("expected an edit, got nil")
+			}
+
+			got := map[string][]string{}
+			for _, change := range edit.DocumentChanges {
+				documentEdit := change.(core.TextDocumentEdit)
+				if len(documentEdit.Edits) != 0 {
+					t.Errorf("%s: unannotated edits %v", documentEdit.TextDocument.URI, documentEdit.Edits)
+				}
+				for _, e := range documentEdit.AnnotatedEdits {
+					got[documentEdit.TextDocument.URI] = append(got[documentEdit.TextDocument.URI], e.NewText)
+					if !edit.ChangeAnnotations[*e.AnnotationID].NeedsConfirmation {
+						t.Errorf("annotation %q does not need confirmation", *e.AnnotationID)
+					}
+				}
+			}
+			if !reflect.DeepEqual(got["file:///main.go"], tt.wantMain) {
+				t.Errorf("main.go edits = %v, want %v", got["file:///main.go"], tt.wantMain)
+			}
+			wantOther := []string{"client"}
+			if tt.strings {
+				wantOther = append(wantOther, "client")
+			}
+			if !reflect.DeepEqual(got["file:///other.go"], wantOther) {
+				t.Errorf("other.go edits = %v, want %v", got["file:///other.go"], wantOther)
+			}
+			if _, ok := edit.ChangeAnnotations[RenameInStringsAnnotation]; ok != tt.strings {
+				t.Errorf("strings annotation present = %v, want %v", ok, tt.strings)
+			}
+		})
+	}
+}
+
+func TestNewGoRenameWithComments(t *testing.T) {
+	content := `package main
+
+// oldName does things.
+func oldName() {}
+
+func main() { oldName() }
+`
+	edit := NewGoRenameWithComments(nil).ProvideRename(core.RenameContext{
+		URI:      "file:///main.go",
+		Content:  content,
+		Position: core.Position{Line: 3, Character: 6},
+		NewName:  "newName",
+	})
+	if edit == nil || len(edit.DocumentChanges) != 1 {
+		t.Fatalf("expected one document edit, got %+v", edit)
+	}
+	documentEdit := edit.DocumentChanges[0].(core.TextDocumentEdit)
+	if len(documentEdit.Edits) != 2 {
+		t.Errorf("identifier edits = %v, want 2", documentEdit.Edits)
+	}
+	if len(documentEdit.AnnotatedEdits) != 1 || *documentEdit.AnnotatedEdits[0].AnnotationID != RenameInCommentsAnnotation {
+		t.Errorf("annotated edits = %+v, want the comment edit", documentEdit.AnnotatedEdits)
+	}
+}
@@ -3,12 +3,13 @@ package examples
 import (
 	"fmt"
 	"go/ast"
-	"go/parser"
-	"go/token"
+	"go/types"
 	"strings"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/SCKelemen/lsp/core"
-	"github.com/SCKelemen/unicode/uax29"
+	"github.com/SCKelemen/lsp/gomod"
 )
 
 // KeywordCompletionProvider provides keyword completions for a language.
@@ -36,29 +37,11 @@ func NewGoKeywordCompletionProvider() *KeywordCompletionProvider {
 
 func (p *KeywordCompletionProvider) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
 	// Get the word being typed using Unicode word boundaries
-	offset := core.PositionToByteOffset(ctx.Content, ctx.Position)
-	if offset < 0 {
+	prefix, ok := completionPrefix(ctx.Content, ctx.Position)
+	if !ok {
 		return nil
 	}
 
-	// Get all word boundaries
-	breaks := uax29.FindWordBreaks(ctx.Content)
-
-	// Find which word we're in
-	prefix := ""
-	if len(breaks) > 0 {
-		for i := 0; i < len(breaks)-1; i++ {
-			start := breaks[i]
-			end := breaks[i+1]
-
-			// Check if cursor is within or at the end of this word
-			if offset >= start && offset <= end {
-				prefix = ctx.Content[start:offset]
-				break
-			}
-		}
-	}
-
 	// Treat whitespace-only prefix as empty (show all completions)
 	prefix = strings.TrimSpace(prefix)
 
@@ -138,29 +121,11 @@ func NewGoSnippetProvider() *SnippetCompletionProvider {
 
 func (p *SnippetCompletionProvider) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
 	// Get the word being typed using Unicode word boundaries
-	offset := core.PositionToByteOffset(ctx.Content, ctx.Position)
-	if offset < 0 {
+	prefix, ok := completionPrefix(ctx.Content, ctx.Position)
+	if !ok {
 		return nil
 	}
 
-	// Get all word boundaries
-	breaks := uax29.FindWordBreaks(ctx.Content)
-
-	// Find which word we're in
-	prefix := ""
-	if len(breaks) > 0 {
-		for i := 0; i < len(breaks)-1; i++ {
-			start := breaks[i]
-			end := breaks[i+1]
-
-			// Check if cursor is within or at the end of this word
-			if offset >= start && offset <= end {
-				prefix = ctx.Content[start:offset]
-				break
-			}
-		}
-	}
-
 	// Treat whitespace-only prefix as empty (show all completions)
 	prefix = strings.TrimSpace(prefix)
 
@@ -177,6 +142,7 @@ func (p *SnippetCompletionProvider) ProvideCompletions(ctx core.CompletionContex
 				Detail:           snippet.Description,
 				InsertText:       snippet.Body,
 				InsertTextFormat: &format,
+				InsertTextMode:   snippetInsertTextMode(snippet.Body),
 				Documentation:    fmt.Sprintf("Snippet: %s\n\n%s", snippet.Prefix, snippet.Description),
 			})
 		}
@@ -194,55 +160,60 @@ func (p *SnippetCompletionProvider) ProvideCompletions(ctx core.CompletionContex
 
 // SymbolCompletionProvider provides completions based on symbols in scope.
 // This uses AST parsing to find available identifiers.
-type SymbolCompletionProvider struct{}
+type SymbolCompletionProvider struct {
+	// Packages, if set, also completes the names of these packages when
+	// the file does not import them yet. Accepting such an item adds the
+	// import.
+	Packages GoPackageIndex
+}
 
 func (p *SymbolCompletionProvider) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
 	if !strings.HasSuffix(ctx.URI, ".go") {
 		return nil
 	}
 
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", ctx.Content, parser.ParseComments)
+	_, f, err := parseGoFile(ctx.URI, ctx.Content)
 	if err != nil {
 		// If parsing fails, try partial completion
 		return nil
 	}
 
 	// Get the word being typed using Unicode word boundaries
-	offset := core.PositionToByteOffset(ctx.Content, ctx.Position)
-	if offset < 0 {
+	prefix, ok := completionPrefix(ctx.Content, ctx.Position)
+	if !ok {
 		return nil
 	}
-
-	// Get all word boundaries
-	breaks := uax29.FindWordBreaks(ctx.Content)
-
-	// Find which word we're in
-	prefix := ""
-	if len(breaks) > 0 {
-		for i := 0; i < len(breaks)-1; i++ {
-			start := breaks[i]
-			end := breaks[i+1]
-
-			// Check if cursor is within or at the end of this word
-			if offset >= start && offset <= end {
-				prefix = strings.ToLower(ctx.Content[start:offset])
-				break
-			}
-		}
-	}
+	prefix = strings.ToLower(prefix)
 
 	// Treat whitespace-only prefix as empty (show all completions)
 	prefix = strings.TrimSpace(prefix)
 
 	// Collect all identifiers in scope
 	symbols := make(map[string]core.CompletionItemKind)
+	signatures := make(map[string]string) // of functions, by name
+	deprecated := make(map[string]bool)
 
 	ast.Inspect(f, func(n ast.Node) bool {
 		switch node := n.(type) {
 		case *ast.FuncDecl:
 			if node.Name.Name != "_" {
 				symbols[node.Name.Name] = core.CompletionItemKindFunction
+				signatures[node.Name.Name] = labelDetail(types.ExprString(node.Type))
+				deprecated[node.Name.Name] = isDeprecatedDoc(node.Doc)
+			}
+		case *ast.GenDecl:
+			for _, spec := range node.Specs {
+				if !isDeprecatedDoc(declSpecDoc(node, spec)) {
+					continue
+				}
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					deprecated[spec.Name.Name] = true
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						deprecated[name.Name] = true
+					}
+				}
 			}
 		case *ast.TypeSpec:
 			if node.Name.Name != "_" {
@@ -273,10 +244,23 @@ func (p *SymbolCompletionProvider) ProvideCompletions(ctx core.CompletionContext
 			items = append(items, core.CompletionItem{
 				Label: name,
 				Kind:  &kindCopy,
+				Tags:  deprecatedCompletionTags(deprecated[name]),
+				LabelDetails: &core.CompletionItemLabelDetails{
+					Detail:      signatures[name],
+					Description: f.Name.Name,
+				},
 			})
 		}
 	}
 
+	if p.Packages != nil && prefix != "" {
+		declared := func(name string) bool {
+			_, ok := symbols[name]
+			return ok
+		}
+		items = append(items, unimportedPackageItems(ctx.URI, ctx.Content, p.Packages, prefix, declared)...)
+	}
+
 	if len(items) == 0 {
 		return nil
 	}
@@ -291,6 +275,10 @@ func (p *SymbolCompletionProvider) ProvideCompletions(ctx core.CompletionContext
 type ImportCompletionProvider struct {
 	// AvailablePackages is a list of available packages
 	AvailablePackages []string
+
+	// Resolver, when set, adds the modules of the workspace and the modules
+	// they require, with their versions.
+	Resolver *gomod.Resolver
 }
 
 func NewGoImportCompletionProvider() *ImportCompletionProvider {
@@ -304,29 +292,11 @@ func NewGoImportCompletionProvider() *ImportCompletionProvider {
 }
 
 func (p *ImportCompletionProvider) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
-	// Check if we're inside an import statement
-	offset := core.PositionToByteOffset(ctx.Content, ctx.Position)
-	if offset < 0 {
-		return nil
-	}
-
-	// Simple heuristic: check if "import" appears before the cursor
-	beforeCursor := ctx.Content[:offset]
-	if !strings.Contains(beforeCursor, "import") {
+	prefix, ok := importPathPrefix(ctx.Content, ctx.Position)
+	if !ok {
 		return nil
 	}
 
-	// Find the current word
-	start := offset
-	for start > 0 && ctx.Content[start-1] != '"' && ctx.Content[start-1] != '\n' {
-		start--
-	}
-
-	prefix := ""
-	if start < offset {
-		prefix = ctx.Content[start:offset]
-	}
-
 	// Filter packages by prefix
 	var items []core.CompletionItem
 	for _, pkg := range p.AvailablePackages {
@@ -340,6 +310,27 @@ func (p *ImportCompletionProvider) ProvideCompletions(ctx core.CompletionContext
 			})
 		}
 	}
+	if p.Resolver != nil {
+		modules, _ := p.Resolver.Modules()
+		for _, module := range modules {
+			if !strings.Contains(module.Path, prefix) {
+				continue
+			}
+			detail := "module " + module.Version
+			if module.Main {
+				detail = "workspace module"
+			} else if module.Version == "" {
+				detail = "module (replaced)"
+			}
+			kind := core.CompletionItemKindModule
+			items = append(items, core.CompletionItem{
+				Label:      module.Path,
+				Kind:       &kind,
+				Detail:     detail,
+				InsertText: module.Path,
+			})
+		}
+	}
 
 	if len(items) == 0 {
 		return nil
@@ -351,6 +342,102 @@ func (p *ImportCompletionProvider) ProvideCompletions(ctx core.CompletionContext
 	}
 }
 
+// RefineCompletions narrows the previous package list to the longer prefix.
+// Matching is by substring, so a longer prefix only ever removes packages.
+func (p *ImportCompletionProvider) RefineCompletions(ctx core.CompletionContext, previous *core.CompletionList) *core.CompletionList {
+	prefix, ok := importPathPrefix(ctx.Content, ctx.Position)
+	if !ok {
+		return nil
+	}
+
+	var items []core.CompletionItem
+	for _, item := range previous.Items {
+		if strings.Contains(item.Label, prefix) {
+			items = append(items, item)
+		}
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	return &core.CompletionList{
+		IsIncomplete: true,
+		Items:        items,
+	}
+}
+
+// labelDetail returns the label detail of a completion item from the type
+// of its symbol: the signature of a function type like "func(s string)
+// int", as "(s string) int", or the type after a space.
+func labelDetail(typ string) string {
+	if signature, ok := strings.CutPrefix(typ, "func"); ok && (strings.HasPrefix(signature, "(") || strings.HasPrefix(signature, "[")) {
+		return signature
+	}
+	return " " + typ
+}
+
+// deprecatedCompletionTags returns the tags of a completion item for a
+// deprecated symbol, or nil.
+func deprecatedCompletionTags(deprecated bool) []core.CompletionItemTag {
+	if !deprecated {
+		return nil
+	}
+	return []core.CompletionItemTag{core.CompletionItemTagDeprecated}
+}
+
+// snippetInsertTextMode returns adjustIndentation for multi-line snippet
+// bodies, whose lines are indented relative to the first line, so clients
+// indent them like the line the snippet is inserted on. It returns nil for
+// single-line bodies.
+func snippetInsertTextMode(body string) *core.InsertTextMode {
+	if !strings.Contains(body, "\n") {
+		return nil
+	}
+	mode := core.InsertTextModeAdjustIndentation
+	return &mode
+}
+
+// completionPrefix returns the part of the word at pos that is before the cursor.
+// ok is false when pos is beyond the last line of the document.
+func completionPrefix(content string, pos core.Position) (prefix string, ok bool) {
+	if pos.Line > strings.Count(content, "\n") {
+		return "", false
+	}
+
+	word, r := core.WordAt(content, pos)
+	if word == "" {
+		return "", true
+	}
+	end := pos.Character - r.Start.Character
+	if end > len(word) {
+		end = len(word)
+	}
+	return word[:end], true
+}
+
+// importPathPrefix returns the part of the import path typed before pos.
+// ok is false when pos is not inside an import statement.
+func importPathPrefix(content string, pos core.Position) (prefix string, ok bool) {
+	offset := core.PositionToByteOffset(content, pos)
+	if offset < 0 {
+		return "", false
+	}
+
+	// Simple heuristic: check if "import" appears before the cursor
+	if !strings.Contains(content[:offset], "import") {
+		return "", false
+	}
+
+	// Find the current word
+	start := offset
+	for start > 0 && content[start-1] != '"' && content[start-1] != '\n' {
+		start--
+	}
+
+	return content[start:offset], true
+}
+
 // LazyCompletionProvider demonstrates lazy resolution of completion items.
 // The initial items have minimal information, and details are resolved on demand.
 type LazyCompletionProvider struct {
@@ -395,8 +482,30 @@ func (p *LazyCompletionProvider) ResolveCompletionItem(item core.CompletionItem)
 }
 
 // CompositeCompletionProvider combines multiple completion providers.
+//
+// It remembers the per-provider results of the last request for each document,
+// tagged with the document version and the position anchor (the start of the
+// word being completed). When the client re-triggers an incomplete list at the
+// same anchor, providers that returned complete lists are not asked again,
+// providers that implement core.CompletionRefineProvider refine their previous
+// list, and only the remaining incomplete providers recompute from scratch.
 type CompositeCompletionProvider struct {
 	Providers []core.CompletionProvider
+
+	mu    sync.Mutex
+	cache map[string]*completionCacheEntry
+}
+
+// completionCacheEntry holds the results of the last completion request for a document.
+type completionCacheEntry struct {
+	version int
+	anchor  core.Position
+	prefix  string
+	// before and after are the document text around the word, used to
+	// confirm that a newer version only differs by what was typed.
+	before string
+	after  string
+	lists  []*core.CompletionList
 }
 
 func NewCompositeCompletionProvider(providers ...core.CompletionProvider) *CompositeCompletionProvider {
@@ -406,11 +515,37 @@ func NewCompositeCompletionProvider(providers ...core.CompletionProvider) *Compo
 }
 
 func (p *CompositeCompletionProvider) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
+	start, end := completionWord(ctx.Content, ctx.Position)
+	key := &completionCacheEntry{
+		version: ctx.Version,
+		anchor:  core.ByteOffsetToPosition(ctx.Content, start),
+		prefix:  ctx.Content[start:end],
+		before:  ctx.Content[:start],
+		after:   ctx.Content[end:],
+	}
+
+	var lists []*core.CompletionList
+	if entry := p.cached(ctx, key); entry != nil {
+		lists = p.refine(ctx, entry)
+	} else {
+		lists = make([]*core.CompletionList, len(p.Providers))
+		for i, provider := range p.Providers {
+			lists[i] = provider.ProvideCompletions(ctx)
+		}
+	}
+
+	p.mu.Lock()
+	if p.cache == nil {
+		p.cache = make(map[string]*completionCacheEntry)
+	}
+	key.lists = lists
+	p.cache[ctx.URI] = key
+	p.mu.Unlock()
+
 	var allItems []core.CompletionItem
 	isIncomplete := false
 
-	for _, provider := range p.Providers {
-		list := provider.ProvideCompletions(ctx)
+	for _, list := range lists {
 		if list != nil {
 			allItems = append(allItems, list.Items...)
 			if list.IsIncomplete {
@@ -429,6 +564,86 @@ func (p *CompositeCompletionProvider) ProvideCompletions(ctx core.CompletionCont
 	}
 }
 
+// Invalidate drops cached results for a document, e.g. when it is closed.
+func (p *CompositeCompletionProvider) Invalidate(uri string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.cache, uri)
+}
+
+// cached returns the cache entry that the request can refine, or nil.
+//
+// Only re-triggers for incomplete lists are refined, and the word must start
+// at the same anchor with a prefix that has grown. Typing the prefix bumps the
+// document version, so the entry is usually one or more versions behind; that
+// is safe because the text before the anchor and after the cursor must also be
+// unchanged, i.e. the only edits since the entry was made are to the word
+// itself. An older version (e.g. after the document was reopened) never matches.
+func (p *CompositeCompletionProvider) cached(ctx core.CompletionContext, key *completionCacheEntry) *completionCacheEntry {
+	if ctx.TriggerKind != core.CompletionTriggerKindTriggerForIncompleteCompletions {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.cache[ctx.URI]
+	if !ok || len(entry.lists) != len(p.Providers) {
+		return nil
+	}
+	if entry.anchor != key.anchor || key.version < entry.version || !strings.HasPrefix(key.prefix, entry.prefix) {
+		return nil
+	}
+	if entry.before != key.before || entry.after != key.after {
+		return nil
+	}
+	return entry
+}
+
+// refine derives new per-provider results from a cache entry.
+func (p *CompositeCompletionProvider) refine(ctx core.CompletionContext, entry *completionCacheEntry) []*core.CompletionList {
+	lists := make([]*core.CompletionList, len(p.Providers))
+	for i, provider := range p.Providers {
+		previous := entry.lists[i]
+
+		switch {
+		case previous == nil || !previous.IsIncomplete:
+			// Complete lists are filtered by the client as the user types
+			lists[i] = previous
+		case isRefiner(provider):
+			lists[i] = provider.(core.CompletionRefineProvider).RefineCompletions(ctx, previous)
+		default:
+			lists[i] = provider.ProvideCompletions(ctx)
+		}
+	}
+	return lists
+}
+
+func isRefiner(provider core.CompletionProvider) bool {
+	_, ok := provider.(core.CompletionRefineProvider)
+	return ok
+}
+
+// completionWord returns the byte offsets of the start of the word being
+// completed and of the cursor.
+func completionWord(content string, pos core.Position) (start, end int) {
+	offset := core.PositionToByteOffset(content, pos)
+	if offset < 0 {
+		offset = len(content)
+	}
+
+	start = offset
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(content[:start])
+		if !isWordChar(r) {
+			break
+		}
+		start -= size
+	}
+
+	return start, offset
+}
+
 // Example usage in CLI tool
 func CLICompletionExample() {
 	content := `package main
@@ -1,10 +1,14 @@
 package examples
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
 
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/adapter"
 	"github.com/SCKelemen/lsp/core"
+	protocol "github.com/SCKelemen/lsp/protocol"
 )
 
 // TestKeywordCompletionProvider tests keyword completions.
@@ -132,6 +136,9 @@ func TestSnippetCompletionProvider(t *testing.T) {
 					if item.InsertTextFormat == nil || *item.InsertTextFormat != core.InsertTextFormatSnippet {
 						t.Error("expected snippet insert format")
 					}
+					if item.InsertTextMode == nil || *item.InsertTextMode != core.InsertTextModeAdjustIndentation {
+						t.Error("expected multi-line snippet to adjust indentation")
+					}
 					if strings.Contains(item.Label, "for loop") {
 						foundFor = true
 					}
@@ -227,6 +234,9 @@ func main() {
 						if item.Kind == nil || *item.Kind != core.CompletionItemKindFunction {
 							t.Error("expected function kind")
 						}
+						if item.LabelDetails == nil || item.LabelDetails.Detail != "(a, b int) int" || item.LabelDetails.Description != "main" {
+							t.Errorf("expected the signature and package as label details, got %+v", item.LabelDetails)
+						}
 					}
 				}
 				if !found {
@@ -358,6 +368,41 @@ func main() {
 }
 
 // TestImportCompletionProvider tests import completions.
+func TestSymbolCompletionProviderDeprecated(t *testing.T) {
+	content := `package main
+
+// Deprecated: Use NewRetry.
+func NewRetryPolicy() {}
+
+func NewRetry() {}
+
+// Deprecated: Use NewRetry.
+var NewRetryDefault = 3
+
+func main() {
+	NewR
+}`
+	list := (&SymbolCompletionProvider{}).ProvideCompletions(core.CompletionContext{
+		URI:      "file:///deprecated.go",
+		Content:  content,
+		Position: core.Position{Line: 11, Character: 5},
+	})
+	if list == nil {
+		t.Fatal("expected completion list, got nil")
+	}
+
+	deprecated := map[string]bool{}
+	for _, item := range list.Items {
+		deprecated[item.Label] = len(item.Tags) == 1 && item.Tags[0] == core.CompletionItemTagDeprecated
+	}
+	want := map[string]bool{"NewRetryPolicy": true, "NewRetry": false, "NewRetryDefault": true}
+	for label, wantDeprecated := range want {
+		if deprecated[label] != wantDeprecated {
+			t.Errorf("%s deprecated = %v, want %v", label, deprecated[label], wantDeprecated)
+		}
+	}
+}
+
 func TestImportCompletionProvider(t *testing.T) {
 	provider := NewGoImportCompletionProvider()
 
@@ -617,6 +662,116 @@ func main() {
 	}
 }
 
+// countingCompletionProvider records how often it is asked for completions.
+type countingCompletionProvider struct {
+	base     core.CompletionProvider
+	provides int
+	refines  int
+}
+
+func (p *countingCompletionProvider) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
+	p.provides++
+	return p.base.ProvideCompletions(ctx)
+}
+
+// refiningCompletionProvider adds core.CompletionRefineProvider to a counting provider.
+type refiningCompletionProvider struct {
+	*countingCompletionProvider
+}
+
+func (p refiningCompletionProvider) RefineCompletions(ctx core.CompletionContext, previous *core.CompletionList) *core.CompletionList {
+	p.refines++
+	return p.base.(core.CompletionRefineProvider).RefineCompletions(ctx, previous)
+}
+
+// TestCompositeCompletionProvider_IncompleteRefinement tests that re-triggers for
+// incomplete lists reuse earlier results instead of recomputing them.
+func TestCompositeCompletionProvider_IncompleteRefinement(t *testing.T) {
+	keywords := &countingCompletionProvider{base: NewGoKeywordCompletionProvider()}
+	imports := refiningCompletionProvider{&countingCompletionProvider{base: NewGoImportCompletionProvider()}}
+	provider := NewCompositeCompletionProvider(keywords, imports)
+
+	request := func(content string, character int, version int, kind core.CompletionTriggerKind) *core.CompletionList {
+		return provider.ProvideCompletions(core.CompletionContext{
+			URI:         "file:///test.go",
+			Content:     content,
+			Position:    core.Position{Line: 0, Character: character},
+			Version:     version,
+			TriggerKind: kind,
+		})
+	}
+
+	first := request(`import "s`, 9, 1, core.CompletionTriggerKindInvoked)
+	if first == nil || !first.IsIncomplete {
+		t.Fatal("expected incomplete list from import provider")
+	}
+
+	second := request(`import "st`, 10, 2, core.CompletionTriggerKindTriggerForIncompleteCompletions)
+	if second == nil {
+		t.Fatal("expected refined completion list")
+	}
+	if keywords.provides != 1 {
+		t.Errorf("complete provider was asked %d times, want 1", keywords.provides)
+	}
+	if imports.provides != 1 || imports.refines != 1 {
+		t.Errorf("import provider provides=%d refines=%d, want 1 and 1", imports.provides, imports.refines)
+	}
+	for _, item := range second.Items {
+		if item.Kind != nil && *item.Kind == core.CompletionItemKindModule && !strings.Contains(item.Label, "st") {
+			t.Errorf("refined list contains non-matching package %q", item.Label)
+		}
+	}
+
+	// A new explicit invocation always recomputes
+	request(`import "st`, 10, 2, core.CompletionTriggerKindInvoked)
+	if keywords.provides != 2 || imports.provides != 2 {
+		t.Errorf("expected recompute on invoke, got keyword=%d import=%d", keywords.provides, imports.provides)
+	}
+
+	// An older version (document reopened) is not refined
+	request(`import "str`, 11, 1, core.CompletionTriggerKindTriggerForIncompleteCompletions)
+	if imports.provides != 3 {
+		t.Errorf("expected recompute for older version, got %d provides", imports.provides)
+	}
+
+	// After invalidation the cache is not used
+	provider.Invalidate("file:///test.go")
+	request(`import "stri`, 12, 4, core.CompletionTriggerKindTriggerForIncompleteCompletions)
+	if imports.provides != 4 {
+		t.Errorf("expected recompute after invalidation, got %d provides", imports.provides)
+	}
+
+	// A newer version with edits outside the word is not refined
+	request(`import "strin`+"\n// x", 13, 5, core.CompletionTriggerKindTriggerForIncompleteCompletions)
+	if imports.provides != 5 || keywords.provides != 5 {
+		t.Errorf("expected recompute after edit elsewhere, got keyword=%d import=%d", keywords.provides, imports.provides)
+	}
+}
+
+// TestImportCompletionProvider_RefineOutsideImport tests that refinement keeps
+// the import-context check of ProvideCompletions.
+func TestImportCompletionProvider_RefineOutsideImport(t *testing.T) {
+	provider := NewGoImportCompletionProvider()
+
+	previous := provider.ProvideCompletions(core.CompletionContext{
+		URI:      "file:///test.go",
+		Content:  `import "s`,
+		Position: core.Position{Line: 0, Character: 9},
+	})
+	if previous == nil {
+		t.Fatal("expected import completions")
+	}
+
+	refined := provider.RefineCompletions(core.CompletionContext{
+		URI:      "file:///test.go",
+		Content:  "package main\n\nvar name = \"s",
+		Position: core.Position{Line: 2, Character: 13},
+	}, previous)
+	if refined != nil {
+		t.Errorf("expected no completions outside an import, got %d items", len(refined.Items))
+	}
+}
+
 // TestCompletion_EdgeCases tests edge cases for completions.
 func TestCompletion_EdgeCases(t *testing.T) {
 	provider := NewGoKeywordCompletionProvider()
@@ -675,3 +830,43 @@ func TestCompletion_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// TestCompositeCompletionProvider_DocumentVersions tests that the versions of
+// the documents reach the completion cache through the adapter, so a
+// document reopened at an older version is not refined from the cache.
+func TestCompositeCompletionProvider_DocumentVersions(t *testing.T) {
+	imports := refiningCompletionProvider{&countingCompletionProvider{base: NewGoImportCompletionProvider()}}
+	documents := core.NewDocumentManager()
+	handler := &protocol.Handler{}
+	handler.SetInitialized(true)
+	adapter_3_16.SetCompletionHandlers(handler, NewCompositeCompletionProvider(imports), documents)
+
+	request := func(character int, kind core.CompletionTriggerKind) {
+		params, _ := json.Marshal(protocol.CompletionParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.go"},
+				Position:     protocol.Position{Line: 0, Character: protocol.UInteger(character)},
+			},
+			Context: &protocol.CompletionContext{TriggerKind: protocol.CompletionTriggerKind(kind)},
+		})
+		if _, _, _, err := handler.Handle(&lsp.Context{Method: string(protocol.MethodTextDocumentCompletion), Params: params}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	documents.Open("file:///test.go", `import "s`, 1)
+	request(9, core.CompletionTriggerKindInvoked)
+	documents.Update("file:///test.go", `import "st`)
+	request(10, core.CompletionTriggerKindTriggerForIncompleteCompletions)
+	if imports.provides != 1 || imports.refines != 1 {
+		t.Fatalf("provides=%d refines=%d, want the newer version refined", imports.provides, imports.refines)
+	}
+
+	// Reopened, the document is back at version 1
+	documents.Close("file:///test.go")
+	documents.Open("file:///test.go", `import "str`, 1)
+	request(11, core.CompletionTriggerKindTriggerForIncompleteCompletions)
+	if imports.provides != 2 {
+		t.Errorf("provides=%d, want the older version to miss the cache", imports.provides)
+	}
+}
@@ -0,0 +1,375 @@
+package examples
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/gobuild"
+)
+
+// UndefinedCode is the diagnostic code of references to undefined
+// identifiers, fields, and methods reported by GoTypeCheckDiagnosticProvider.
+const UndefinedCode = "undefined"
+
+// GoTypeCheckDiagnosticProvider reports the errors of the Go type checker.
+//
+// Each file is checked on its own and imports are not loaded, so this only
+// suits single-file packages; a real server type-checks whole packages.
+// Errors about imported packages are suppressed by the checker. Follow-up
+// errors pointing at another place, like the other declaration of a
+// redeclared name, become related information.
+type GoTypeCheckDiagnosticProvider struct{}
+
+func (p *GoTypeCheckDiagnosticProvider) ProvideDiagnostics(uri, content string) []core.Diagnostic {
+	return p.ProvidePlatformDiagnostics(gobuild.DefaultPlatform(), uri, content)
+}
+
+// ProvidePlatformDiagnostics checks a file with the type sizes of a
+// platform, so gobuild.DiagnosticProvider can check it for each platform
+// it is built on: constants overflowing int on 32-bit architectures only
+// fail there.
+func (p *GoTypeCheckDiagnosticProvider) ProvidePlatformDiagnostics(platform gobuild.Platform, uri, content string) []core.Diagnostic {
+	if !strings.HasSuffix(uri, ".go") {
+		return nil
+	}
+	fset, f, err := parseGoFile(uri, content)
+	if err != nil {
+		return nil
+	}
+	_, _, errs := typeCheckGoFileSizes(fset, f, types.SizesFor("gc", platform.GOARCH))
+
+	var diagnostics []core.Diagnostic
+	for _, typeErr := range errs {
+		offset := fset.Position(typeErr.Pos).Offset
+		r := core.Range{
+			Start: core.ByteOffsetToPosition(content, offset),
+			End:   core.ByteOffsetToPosition(content, offset+identifierLength(content[offset:])),
+		}
+		// The checker continues an error with indented ones, like
+		// "\tother declaration of x" after "x redeclared in this block"
+		if strings.HasPrefix(typeErr.Msg, "\t") && len(diagnostics) > 0 {
+			diagnostics[len(diagnostics)-1].AddRelated(uri, r, strings.TrimPrefix(typeErr.Msg, "\t"))
+			continue
+		}
+		severity := core.SeverityError
+		diagnostic := core.Diagnostic{
+			Range:    r,
+			Severity: &severity,
+			Source:   "go/types",
+			Message:  typeErr.Msg,
+		}
+		if strings.HasPrefix(typeErr.Msg, "undefined: ") || strings.Contains(typeErr.Msg, " undefined (") {
+			code := core.NewStringCode(UndefinedCode)
+			diagnostic.Code = &code
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return diagnostics
+}
+
+// CreateFunctionProvider offers a quick fix for calls of undefined functions
+// and methods reported by GoTypeCheckDiagnosticProvider: it generates a stub
+// after the declaration containing the call.
+//
+// Parameter types are those of the arguments at the call site, and result
+// types come from where the result is used: the variables it is assigned
+// to, the results of the enclosing function when returned, or the
+// parameter of the call it is passed to. Unknown types become any.
+type CreateFunctionProvider struct{}
+
+func (p *CreateFunctionProvider) ProvideCodeFixes(ctx core.CodeFixContext) []core.CodeAction {
+	if !strings.HasSuffix(ctx.URI, ".go") {
+		return nil
+	}
+
+	var actions []core.CodeAction
+	for _, diag := range ctx.Diagnostics {
+		if diag.Code == nil || diag.Code.StringValue != UndefinedCode {
+			continue
+		}
+		if action, ok := p.createFunction(ctx, diag); ok {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+func (p *CreateFunctionProvider) createFunction(ctx core.CodeFixContext, diag core.Diagnostic) (core.CodeAction, bool) {
+	fset, f, err := parseGoFile(ctx.URI, ctx.Content)
+	if err != nil {
+		return core.CodeAction{}, false
+	}
+	start := core.PositionToByteOffset(ctx.Content, diag.Range.Start)
+	end := core.PositionToByteOffset(ctx.Content, diag.Range.End)
+	path := goNodesEnclosing(fset, f, start, end)
+	if len(path) < 3 {
+		return core.CodeAction{}, false
+	}
+	ident, ok := path[len(path)-1].(*ast.Ident)
+	if !ok {
+		return core.CodeAction{}, false
+	}
+	info, pkg, _ := typeCheckGoFileErrors(fset, f)
+
+	// name(...) or x.name(...)
+	var call *ast.CallExpr
+	var receiver string
+	callIndex := len(path) - 2
+	if selector, ok := path[callIndex].(*ast.SelectorExpr); ok && selector.Sel == ident {
+		receiver = localReceiverType(info, pkg, selector.X)
+		if receiver == "" {
+			return core.CodeAction{}, false
+		}
+		callIndex--
+	}
+	call, ok = path[callIndex].(*ast.CallExpr)
+	if !ok || ast.Unparen(call.Fun) != path[callIndex+1] {
+		return core.CodeAction{}, false
+	}
+
+	qualifier := func(other *types.Package) string {
+		if other == pkg {
+			return ""
+		}
+		return other.Name()
+	}
+	params := stubParams(info, call, qualifier)
+	results := stubResults(info, path[:callIndex+1], qualifier)
+
+	var stub strings.Builder
+	stub.WriteString("\n\nfunc ")
+	kind := "function"
+	if receiver != "" {
+		kind = "method"
+		r, _ := utf8.DecodeRuneInString(receiver)
+		fmt.Fprintf(&stub, "(%c *%s) ", unicode.ToLower(r), receiver)
+	}
+	fmt.Fprintf(&stub, "%s(%s)", ident.Name, strings.Join(params, ", "))
+	switch len(results) {
+	case 0:
+	case 1:
+		stub.WriteString(" " + results[0])
+	default:
+		stub.WriteString(" (" + strings.Join(results, ", ") + ")")
+	}
+	stub.WriteString(" {\n\tpanic(\"unimplemented\")\n}")
+
+	// After the top-level declaration containing the call
+	insert := len(ctx.Content)
+	for _, decl := range f.Decls {
+		if decl.Pos() <= call.Pos() && call.End() <= decl.End() {
+			insert = fset.Position(decl.End()).Offset
+		}
+	}
+	pos := core.ByteOffsetToPosition(ctx.Content, insert)
+
+	quickFix := core.CodeActionKindQuickFix
+	return core.CodeAction{
+		Title:       fmt.Sprintf("Create %s %s", kind, ident.Name),
+		Kind:        &quickFix,
+		Diagnostics: []core.Diagnostic{diag},
+		IsPreferred: true,
+		Edit: &core.WorkspaceEdit{
+			Changes: map[string][]core.TextEdit{
+				ctx.URI: {{Range: core.Range{Start: pos, End: pos}, NewText: stub.String()}},
+			},
+		},
+	}, true
+}
+
+// localReceiverType returns the name of the type of x if it is a named
+// type, or a pointer to one, declared in pkg.
+func localReceiverType(info *types.Info, pkg *types.Package, x ast.Expr) string {
+	t := info.Types[x].Type
+	if pointer, ok := t.(*types.Pointer); ok {
+		t = pointer.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() != pkg || named.Obj().Parent() != pkg.Scope() {
+		return ""
+	}
+	if _, ok := named.Underlying().(*types.Interface); ok {
+		return ""
+	}
+	return named.Obj().Name()
+}
+
+// stubParams returns the parameters of a function accepting the arguments
+// of call, named after the arguments where possible.
+func stubParams(info *types.Info, call *ast.CallExpr, qualifier types.Qualifier) []string {
+	used := map[string]bool{}
+	params := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		name := argumentName(arg)
+		if name == "" || used[name] || token.IsKeyword(name) {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		used[name] = true
+		params[i] = name + " " + stubTypeString(info.Types[arg].Type, qualifier)
+	}
+	return params
+}
+
+// stubResults returns the result types of a call from the context it is
+// used in. path leads from the file to the call.
+func stubResults(info *types.Info, path []ast.Node, qualifier types.Qualifier) []string {
+	call := path[len(path)-1].(*ast.CallExpr)
+	var parent ast.Node
+	for i := len(path) - 2; i >= 0; i-- {
+		if _, ok := path[i].(*ast.ParenExpr); !ok {
+			parent = path[i]
+			break
+		}
+	}
+
+	typesOf := func(exprs []ast.Expr) []string {
+		results := make([]string, len(exprs))
+		for i, expr := range exprs {
+			results[i] = stubTypeString(info.Types[expr].Type, qualifier)
+		}
+		return results
+	}
+
+	switch parent := parent.(type) {
+	case *ast.ExprStmt, *ast.GoStmt, *ast.DeferStmt:
+		return nil
+	case *ast.AssignStmt:
+		if len(parent.Rhs) != 1 {
+			return []string{"any"}
+		}
+		if parent.Tok == token.DEFINE {
+			// The variables are being declared by the call
+			return typesOf(make([]ast.Expr, len(parent.Lhs)))
+		}
+		return typesOf(parent.Lhs)
+	case *ast.ValueSpec:
+		if parent.Type != nil {
+			t := stubTypeString(info.Types[parent.Type].Type, qualifier)
+			return []string{t}
+		}
+		if len(parent.Values) == 1 {
+			return typesOf(make([]ast.Expr, len(parent.Names)))
+		}
+	case *ast.ReturnStmt:
+		signature := enclosingSignature(info, path)
+		if signature == nil {
+			break
+		}
+		resultTypes := signature.Results()
+		if len(parent.Results) == 1 && resultTypes.Len() > 1 {
+			results := make([]string, resultTypes.Len())
+			for i := range results {
+				results[i] = stubTypeString(resultTypes.At(i).Type(), qualifier)
+			}
+			return results
+		}
+		for i, result := range parent.Results {
+			if result == call && i < resultTypes.Len() {
+				return []string{stubTypeString(resultTypes.At(i).Type(), qualifier)}
+			}
+		}
+	case *ast.IfStmt, *ast.ForStmt:
+		return []string{"bool"}
+	case *ast.UnaryExpr:
+		if parent.Op == token.NOT {
+			return []string{"bool"}
+		}
+	case *ast.BinaryExpr:
+		if parent.Op == token.LAND || parent.Op == token.LOR {
+			return []string{"bool"}
+		}
+		// The type of the other operand
+		other := parent.X
+		if ast.Unparen(other) == call {
+			other = parent.Y
+		}
+		return []string{stubTypeString(info.Types[other].Type, qualifier)}
+	case *ast.CallExpr:
+		signature, ok := info.Types[parent.Fun].Type.(*types.Signature)
+		if !ok {
+			break
+		}
+		for i, arg := range parent.Args {
+			if arg == call && i < signature.Params().Len() {
+				return []string{stubTypeString(signature.Params().At(i).Type(), qualifier)}
+			}
+		}
+	}
+	return []string{"any"}
+}
+
+// enclosingSignature returns the signature of the innermost function on path.
+func enclosingSignature(info *types.Info, path []ast.Node) *types.Signature {
+	for i := len(path) - 1; i >= 0; i-- {
+		switch fn := path[i].(type) {
+		case *ast.FuncDecl:
+			if obj, ok := info.Defs[fn.Name].(*types.Func); ok {
+				return obj.Type().(*types.Signature)
+			}
+			return nil
+		case *ast.FuncLit:
+			signature, _ := info.Types[fn].Type.(*types.Signature)
+			return signature
+		}
+	}
+	return nil
+}
+
+// stubTypeString returns Go source for t, or any if it is unknown.
+func stubTypeString(t types.Type, qualifier types.Qualifier) string {
+	if t == nil || t == types.Typ[types.Invalid] {
+		return "any"
+	}
+	t = types.Default(t)
+	if basic, ok := t.(*types.Basic); ok && basic.Kind() == types.UntypedNil {
+		return "any"
+	}
+	return types.TypeString(t, qualifier)
+}
+
+// argumentName returns a parameter name suggested by an argument: the name
+// of a variable, or of the field or function it comes from.
+func argumentName(arg ast.Expr) string {
+	switch arg := ast.Unparen(arg).(type) {
+	case *ast.Ident:
+		if arg.Name == "nil" || arg.Name == "true" || arg.Name == "false" || arg.Name == "_" {
+			return ""
+		}
+		return arg.Name
+	case *ast.SelectorExpr:
+		return lowerFirst(arg.Sel.Name)
+	case *ast.UnaryExpr:
+		return argumentName(arg.X)
+	case *ast.StarExpr:
+		return argumentName(arg.X)
+	}
+	return ""
+}
+
+// lowerFirst lowercases the first letter of s.
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
+
+// identifierLength returns the length of the identifier s starts with, or 1
+// to give other errors a visible range.
+func identifierLength(s string) int {
+	n := 0
+	for i, r := range s {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		n = i + utf8.RuneLen(r)
+	}
+	if n == 0 && len(s) > 0 {
+		return 1
+	}
+	return n
+}
@@ -0,0 +1,171 @@
+package examples
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/gobuild"
+)
+
+func TestCreateFunctionProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name: "function",
+			content: `package main
+
+func run(name string, p *Point) (int, error) {
+	n, err := parse(name, 42, p.X, nil)
+	if err != nil {
+		return 0, err
+	}
+	if valid(n) {
+		return n, nil
+	}
+	return compute(n)
+}
+
+type Point struct{ X float64 }
+`,
+			want: `	return compute(n)
+}
+
+func compute(n any) (int, error) {
+	panic("unimplemented")
+}
+
+type Point struct{ X float64 }
+`,
+		},
+		{
+			name: "method",
+			content: `package main
+
+type Point struct{ X float64 }
+
+func main() {
+	p := &Point{}
+	p.Move(1.5, "up")
+}
+`,
+			want: `	p.Move(1.5, "up")
+}
+
+func (p *Point) Move(arg0 float64, arg1 string) {
+	panic("unimplemented")
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri := "file:///create_" + tt.name + ".go"
+			diagnostics := (&GoTypeCheckDiagnosticProvider{}).ProvideDiagnostics(uri, tt.content)
+
+			var undefined []core.Diagnostic
+			for _, diag := range diagnostics {
+				if diag.Code != nil && diag.Code.StringValue == UndefinedCode {
+					undefined = append(undefined, diag)
+				}
+			}
+			if len(undefined) == 0 {
+				t.Fatalf("no undefined diagnostics in %v", diagnostics)
+			}
+
+			actions := (&CreateFunctionProvider{}).ProvideCodeFixes(core.CodeFixContext{
+				URI:         uri,
+				Content:     tt.content,
+				Diagnostics: undefined,
+			})
+			if len(actions) != len(undefined) {
+				t.Fatalf("got %d actions for %d diagnostics: %v", len(actions), len(undefined), actions)
+			}
+
+			// The last undefined call is the one the case checks
+			action := actions[len(actions)-1]
+			result := applyTextEdits(tt.content, action.Edit.Changes[uri])
+			if !strings.Contains(result, tt.want) {
+				t.Errorf("result does not contain\n%s\ngot:\n%s", tt.want, result)
+			}
+		})
+	}
+}
+
+func TestCreateFunctionProviderSignatures(t *testing.T) {
+	content := `package main
+
+func run(name string, p *Point) (int, error) {
+	n, err := parse(name, 42, p.X, nil)
+	if valid(n) {
+		return n, err
+	}
+	return 0, nil
+}
+
+type Point struct{ X float64 }
+`
+	uri := "file:///create_signatures.go"
+	diagnostics := (&GoTypeCheckDiagnosticProvider{}).ProvideDiagnostics(uri, content)
+	actions := (&CreateFunctionProvider{}).ProvideCodeFixes(core.CodeFixContext{
+		URI:         uri,
+		Content:     content,
+		Diagnostics: diagnostics,
+	})
+
+	want := map[string]string{
+		"Create function parse": "func parse(name string, arg1 int, x float64, arg3 any) (any, any)",
+		"Create function valid": "func valid(n any) bool",
+	}
+	for _, action := range actions {
+		signature, ok := want[action.Title]
+		if !ok {
+			t.Errorf("unexpected action %q", action.Title)
+			continue
+		}
+		if text := action.Edit.Changes[uri][0].NewText; !strings.Contains(text, signature) {
+			t.Errorf("%s: stub %q does not contain %q", action.Title, text, signature)
+		}
+		delete(want, action.Title)
+	}
+	for title := range want {
+		t.Errorf("missing action %q", title)
+	}
+}
+
+func TestGoTypeCheckDiagnosticProviderRelatedInformation(t *testing.T) {
+	content := "package main\n\nfunc run() {}\n\nfunc run() {}\n"
+	uri := "file:///redeclared.go"
+	diagnostics := (&GoTypeCheckDiagnosticProvider{}).ProvideDiagnostics(uri, content)
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected one diagnostic, got %+v", diagnostics)
+	}
+	related := diagnostics[0].RelatedInformation
+	if len(related) != 1 {
+		t.Fatalf("expected the other declaration as related information, got %+v", related)
+	}
+	if related[0].Message != "other declaration of run" || related[0].Location.URI != uri ||
+		related[0].Location.Range.Start.Line != 2 || related[0].Location.Range.Start.Character != 5 {
+		t.Errorf("unexpected related information %+v", related[0])
+	}
+}
+
+func TestGoTypeCheckDiagnosticProviderPlatforms(t *testing.T) {
+	content := "package main\n\nvar big int = 1 << 40\n"
+	provider := gobuild.NewDiagnosticProvider(&GoTypeCheckDiagnosticProvider{},
+		gobuild.Platform{GOOS: "linux", GOARCH: "amd64"},
+		gobuild.Platform{GOOS: "linux", GOARCH: "386"},
+	)
+
+	diagnostics := provider.ProvideDiagnostics("file:///main.go", content)
+	if len(diagnostics) != 1 {
+		t.Fatalf("got %d diagnostics, want 1: %+v", len(diagnostics), diagnostics)
+	}
+	if !strings.Contains(diagnostics[0].Message, "overflows") || !strings.HasSuffix(diagnostics[0].Message, "(linux/386)") {
+		t.Errorf("message = %q, want an overflow on linux/386 only", diagnostics[0].Message)
+	}
+}
@@ -0,0 +1,257 @@
+package examples
+
+import (
+	"go/ast"
+	"go/doc/comment"
+	"regexp"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// DocCommentProvider assists with writing Go doc comments.
+//
+// On an empty "//" line directly above a declaration it completes the
+// idiomatic start of the doc comment, the declared name. On a declaration
+// with a doc comment it offers "Reformat doc comment", which normalizes the
+// comment the way gofmt does since Go 1.19 (headings, lists, code blocks,
+// and link definitions) and wraps paragraphs at Width.
+type DocCommentProvider struct {
+	// Width is the column at which paragraphs are wrapped, counting the
+	// "// " prefix. If zero, paragraphs are not wrapped.
+	Width int
+}
+
+// docCommentLine matches the beginning of a "//" line up to the cursor:
+// the indentation, and the word being typed.
+var docCommentLine = regexp.MustCompile(`^([ \t]*)// ?(\w*)$`)
+
+func (p *DocCommentProvider) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
+	if !strings.HasSuffix(ctx.URI, ".go") {
+		return nil
+	}
+	offset := core.PositionToByteOffset(ctx.Content, ctx.Position)
+	if offset < 0 {
+		return nil
+	}
+	lineStart := strings.LastIndexByte(ctx.Content[:offset], '\n') + 1
+	lineEnd := len(ctx.Content)
+	if i := strings.IndexByte(ctx.Content[offset:], '\n'); i >= 0 {
+		lineEnd = offset + i
+	}
+	match := docCommentLine.FindStringSubmatch(ctx.Content[lineStart:offset])
+	if match == nil || strings.TrimSpace(ctx.Content[offset:lineEnd]) != "" {
+		return nil
+	}
+
+	fset, f, err := parseGoFile(ctx.URI, ctx.Content)
+	if err != nil {
+		return nil
+	}
+	// The doc comment ends on the line before the declaration
+	var name string
+	line := int(ctx.Position.Line) + 1
+	for _, group := range f.Comments {
+		if fset.Position(group.Pos()).Offset <= lineStart && fset.Position(group.End()).Offset >= offset {
+			line = fset.Position(group.End()).Line
+		}
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if name != "" || n == nil {
+			return false
+		}
+		if n.Pos().IsValid() && fset.Position(n.Pos()).Line == line+1 {
+			name = declaredName(n)
+		}
+		return true
+	})
+	if name == "" || !strings.HasPrefix(name, match[2]) {
+		return nil
+	}
+
+	kind := core.CompletionItemKindSnippet
+	format := core.InsertTextFormatSnippet
+	start := lineStart + len(match[1]) + len("//")
+	return &core.CompletionList{Items: []core.CompletionItem{{
+		Label:            name + " ...",
+		Kind:             &kind,
+		Detail:           "doc comment",
+		FilterText:       "// " + name,
+		InsertTextFormat: &format,
+		TextEdit: &core.TextEdit{
+			Range: core.Range{
+				Start: core.ByteOffsetToPosition(ctx.Content, start),
+				End:   ctx.Position,
+			},
+			NewText: " " + name + " ${1:...}",
+		},
+	}}}
+}
+
+// declaredName returns the name a declaration node declares, or "".
+func declaredName(n ast.Node) string {
+	switch n := n.(type) {
+	case *ast.FuncDecl:
+		return n.Name.Name
+	case *ast.GenDecl:
+		if n.Lparen.IsValid() || len(n.Specs) != 1 {
+			return ""
+		}
+		return declaredName(n.Specs[0])
+	case *ast.TypeSpec:
+		return n.Name.Name
+	case *ast.ValueSpec:
+		return n.Names[0].Name
+	case *ast.Field:
+		if len(n.Names) > 0 {
+			return n.Names[0].Name
+		}
+	}
+	return ""
+}
+
+func (p *DocCommentProvider) ProvideCodeFixes(ctx core.CodeFixContext) []core.CodeAction {
+	if !strings.HasSuffix(ctx.URI, ".go") || !codeActionKindRequested(ctx.Only, core.CodeActionKindRefactorRewrite) {
+		return nil
+	}
+	fset, f, err := parseGoFile(ctx.URI, ctx.Content)
+	if err != nil {
+		return nil
+	}
+	offset := core.PositionToByteOffset(ctx.Content, ctx.Range.Start)
+	if offset < 0 {
+		return nil
+	}
+
+	// The doc comment of the innermost declaration containing the cursor,
+	// or of the one the cursor is in the doc comment of
+	var doc *ast.CommentGroup
+	ast.Inspect(f, func(n ast.Node) bool {
+		var group *ast.CommentGroup
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			group = n.Doc
+		case *ast.GenDecl:
+			group = n.Doc
+		case *ast.TypeSpec:
+			group = n.Doc
+		case *ast.ValueSpec:
+			group = n.Doc
+		case *ast.Field:
+			group = n.Doc
+		default:
+			return n != nil
+		}
+		if group == nil {
+			return true
+		}
+		if fset.Position(group.Pos()).Offset <= offset && offset <= fset.Position(n.End()).Offset {
+			doc = group
+		}
+		return true
+	})
+	if doc == nil || strings.HasPrefix(doc.List[0].Text, "/*") {
+		return nil
+	}
+
+	start := fset.Position(doc.Pos()).Offset
+	end := fset.Position(doc.End()).Offset
+	indent := lineIndent(ctx.Content, start)
+	formatted := p.formatDocComment(doc, indent)
+	if formatted == "" || formatted == ctx.Content[start:end] {
+		return nil
+	}
+
+	kind := core.CodeActionKindRefactorRewrite
+	return []core.CodeAction{{
+		Title: "Reformat doc comment",
+		Kind:  &kind,
+		Edit: &core.WorkspaceEdit{Changes: map[string][]core.TextEdit{ctx.URI: {{
+			Range: core.Range{
+				Start: core.ByteOffsetToPosition(ctx.Content, start),
+				End:   core.ByteOffsetToPosition(ctx.Content, end),
+			},
+			NewText: formatted,
+		}}}},
+	}}
+}
+
+// docDirective matches directive comments like "//go:generate", which are
+// kept at the end of the doc comment as gofmt does.
+var docDirective = regexp.MustCompile(`^//(line |extern |export |[a-z0-9]+:[a-z0-9])`)
+
+// formatDocComment returns the doc comment formatted with go/doc/comment,
+// each line after the first indented by indent, or "" if it has no text.
+func (p *DocCommentProvider) formatDocComment(doc *ast.CommentGroup, indent string) string {
+	var directives []string
+	for _, c := range doc.List {
+		if docDirective.MatchString(c.Text) {
+			directives = append(directives, c.Text)
+		}
+	}
+
+	var parser comment.Parser
+	var printer comment.Printer
+	text := strings.TrimSuffix(string(printer.Comment(parser.Parse(doc.Text()))), "\n")
+	if text == "" {
+		return ""
+	}
+	// The printer leaves out the comment markers
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		switch {
+		case line == "":
+			lines[i] = "//"
+		case strings.HasPrefix(line, "\t"):
+			lines[i] = "//" + line
+		default:
+			lines[i] = "// " + line
+		}
+	}
+	if p.Width > 0 {
+		lines = wrapDocParagraphs(lines, p.Width)
+	}
+	if len(directives) > 0 {
+		lines = append(append(lines, "//"), directives...)
+	}
+	return strings.Join(lines, "\n"+indent)
+}
+
+// wrapDocParagraphs rewraps the paragraphs of formatted doc comment lines
+// at width. Headings, lists, code blocks, and link definitions are kept
+// as they are.
+func wrapDocParagraphs(lines []string, width int) []string {
+	var result []string
+	for i := 0; i < len(lines); {
+		// A paragraph is a run of "// text" lines between blank lines
+		j := i
+		for j < len(lines) && strings.HasPrefix(lines[j], "// ") && !strings.HasPrefix(lines[j], "//  ") {
+			j++
+		}
+		block := lines[i:j]
+		if len(block) == 0 {
+			result = append(result, lines[i])
+			i++
+			continue
+		}
+		if strings.HasPrefix(block[0], "// # ") || strings.HasPrefix(block[0], "// [") && strings.Contains(block[0], "]: ") {
+			result = append(result, block...)
+		} else {
+			var words []string
+			for _, line := range block {
+				words = append(words, strings.Fields(line[len("// "):])...)
+			}
+			line := "//"
+			for _, word := range words {
+				if line != "//" && len(line)+1+len(word) > width {
+					result = append(result, line)
+					line = "//"
+				}
+				line += " " + word
+			}
+			result = append(result, line)
+		}
+		i = j
+	}
+	return result
+}
@@ -0,0 +1,162 @@
+package examples
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+func TestDocCommentProviderCompletion(t *testing.T) {
+	provider := &DocCommentProvider{}
+
+	tests := []struct {
+		name     string
+		content  string
+		wantText string
+	}{
+		{
+			name:     "function",
+			content:  "package main\n\n//|\nfunc Divide(a, b int) int { return a / b }\n",
+			wantText: "// Divide ${1:...}",
+		},
+		{
+			name:     "typed prefix",
+			content:  "package main\n\n// Po|\ntype Point struct{}\n",
+			wantText: "// Point ${1:...}",
+		},
+		{
+			name:     "struct field",
+			content:  "package main\n\ntype Point struct {\n\t//|\n\tX int\n}\n",
+			wantText: "\t// X ${1:...}",
+		},
+		{
+			name:     "above existing comment",
+			content:  "package main\n\n//|\n// Deprecated: use Max.\nvar Limit = 10\n",
+			wantText: "// Limit ${1:...}",
+		},
+		{
+			name:    "not above a declaration",
+			content: "package main\n\n//|\n\nfunc main() {}\n",
+		},
+		{
+			name:    "prefix does not match",
+			content: "package main\n\n// The|\nfunc Divide() {}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset := strings.Index(tt.content, "|")
+			content := tt.content[:offset] + tt.content[offset+1:]
+			list := provider.ProvideCompletions(core.CompletionContext{
+				URI:      "file:///doc_" + strings.ReplaceAll(tt.name, " ", "_") + ".go",
+				Content:  content,
+				Position: core.ByteOffsetToPosition(content, offset),
+			})
+			if tt.wantText == "" {
+				if list != nil {
+					t.Errorf("expected no completions, got %v", list.Items)
+				}
+				return
+			}
+			if list == nil || len(list.Items) != 1 {
+				t.Fatalf("expected one completion, got %v", list)
+			}
+			result := applyTextEdits(content, []core.TextEdit{*list.Items[0].TextEdit})
+			line := strings.Split(result, "\n")[strings.Count(content[:offset], "\n")]
+			if line != tt.wantText {
+				t.Errorf("line after accepting = %q, want %q", line, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestDocCommentProviderReformat(t *testing.T) {
+	tests := []struct {
+		name    string
+		width   int
+		content string
+		want    string
+	}{
+		{
+			name:  "normalize",
+			width: 0,
+			content: `package main
+
+// Run runs the commands.
+// Steps:
+//  * parse
+//  * execute
+//go:noinline
+func Run() {}
+`,
+			want: `package main
+
+// Run runs the commands.
+// Steps:
+//   - parse
+//   - execute
+//
+//go:noinline
+func Run() {}
+`,
+		},
+		{
+			name:  "wrap",
+			width: 30,
+			content: `package main
+
+type Point struct {
+	// X is the horizontal coordinate of the point, in pixels.
+	//
+	//	p.X = 10
+	X int
+}
+`,
+			want: `package main
+
+type Point struct {
+	// X is the horizontal
+	// coordinate of the point, in
+	// pixels.
+	//
+	//	p.X = 10
+	X int
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset := strings.Index(tt.content, "\n//") + 3
+			if tt.name == "wrap" {
+				offset = strings.Index(tt.content, "X int")
+			}
+			position := core.ByteOffsetToPosition(tt.content, offset)
+			actions := (&DocCommentProvider{Width: tt.width}).ProvideCodeFixes(core.CodeFixContext{
+				URI:     "file:///doc_reformat_" + tt.name + ".go",
+				Content: tt.content,
+				Range:   core.Range{Start: position, End: position},
+			})
+			if len(actions) != 1 {
+				t.Fatalf("expected one action, got %v", actions)
+			}
+			result := applyTextEdits(tt.content, actions[0].Edit.Changes["file:///doc_reformat_"+tt.name+".go"])
+			if result != tt.want {
+				t.Errorf("result:\n%s\nwant:\n%s", result, tt.want)
+			}
+
+			// Formatting is idempotent
+			again := (&DocCommentProvider{Width: tt.width}).ProvideCodeFixes(core.CodeFixContext{
+				URI:     "file:///doc_reformat_" + tt.name + "_again.go",
+				Content: result,
+				Range:   core.Range{Start: position, End: position},
+			})
+			if len(again) != 0 {
+				t.Errorf("expected no action on the formatted comment, got %v", again)
+			}
+		})
+	}
+}
@@ -1,50 +1,171 @@
 package examples
 
 import (
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"net/url"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/gomod"
+	uripkg "github.com/SCKelemen/lsp/uri"
+	"github.com/SCKelemen/lsp/workspace"
 )
 
-// URLLinkProvider finds HTTP/HTTPS URLs in documents.
+// DefaultLinkSchemes are the URL schemes links are created for unless a
+// provider is configured otherwise. Schemes like javascript: run code when
+// followed, so they are never linked by default.
+var DefaultLinkSchemes = []string{"http", "https", "mailto", "vscode"}
+
+// URLLinkProvider finds URLs in documents.
 // This is useful for making URLs in comments and strings clickable.
-type URLLinkProvider struct{}
+// The tooltip of a link is the domain it leads to.
+//
+// In Go files, only comments and string literals are searched, so code is
+// never taken for a URL. A URL continuing into an escape sequence, like
+// "https://example.com/?a=1\u0026b=2", is not linked rather than linked
+// cut short. Nor is one running into an identifier, like "xhttps://", and
+// trailing punctuation is not part of the link.
+type URLLinkProvider struct {
+	// Schemes are the URL schemes to link, like "https". If nil,
+	// DefaultLinkSchemes are used.
+	Schemes []string
+}
 
 func (p *URLLinkProvider) ProvideDocumentLinks(uri, content string) []core.DocumentLink {
 	var links []core.DocumentLink
 
-	// Simple URL regex (matches http:// and https://)
-	urlRegex := regexp.MustCompile(`https?://[^\s<>"{}|\\^\[\]` + "`" + `]+`)
-	matches := urlRegex.FindAllStringIndex(content, -1)
+	urlRegex := linkSchemesRegex(p.Schemes)
 
-	for _, match := range matches {
-		start := match[0]
-		end := match[1]
-
-		url := content[start:end]
-		startPos := core.ByteOffsetToPosition(content, start)
-		endPos := core.ByteOffsetToPosition(content, end)
-
-		links = append(links, core.DocumentLink{
-			Range: core.Range{
-				Start: startPos,
-				End:   endPos,
-			},
-			Target: &url,
-		})
+	// The regions of content to search
+	regions := [][2]int{{0, len(content)}}
+	if strings.HasSuffix(uri, ".go") {
+		regions = regions[:0]
+		for _, region := range scanCommentsAndStrings(content, true) {
+			regions = append(regions, [2]int{region.offset, region.offset + len(region.lit)})
+		}
+	}
+
+	for _, region := range regions {
+		text := content[region[0]:region[1]]
+		for _, match := range urlRegex.FindAllStringIndex(text, -1) {
+			start := region[0] + match[0]
+			end := region[0] + match[1]
+
+			if r, _ := utf8.DecodeLastRuneInString(content[:start]); isIdentRune(r) {
+				// Part of an identifier
+				continue
+			}
+			if partiallyEscaped(content, end) {
+				continue
+			}
+			end = start + len(strings.TrimRight(content[start:end], ".,;:!?'"))
+
+			url := content[start:end]
+			startPos := core.ByteOffsetToPosition(content, start)
+			endPos := core.ByteOffsetToPosition(content, end)
+
+			links = append(links, core.DocumentLink{
+				Range: core.Range{
+					Start: startPos,
+					End:   endPos,
+				},
+				Target:  &url,
+				Tooltip: urlTooltip(url),
+			})
+		}
 	}
 
 	return links
 }
 
+// linkSchemesRegex returns the regular expression matching URLs with the
+// schemes, or DefaultLinkSchemes if schemes is nil.
+func linkSchemesRegex(schemes []string) *regexp.Regexp {
+	if schemes == nil {
+		schemes = DefaultLinkSchemes
+	}
+	quoted := make([]string, len(schemes))
+	for i, scheme := range schemes {
+		quoted[i] = regexp.QuoteMeta(scheme)
+	}
+	return regexp.MustCompile(`(?i)(?:` + strings.Join(quoted, "|") + `):[^\s<>"{}|\\^\[\]` + "`" + `]+`)
+}
+
+// linkSchemeAllowed reports whether a link target is relative or has one
+// of the schemes, or one of DefaultLinkSchemes if schemes is nil.
+func linkSchemeAllowed(target string, schemes []string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" {
+		return true
+	}
+	if schemes == nil {
+		schemes = DefaultLinkSchemes
+	}
+	for _, scheme := range schemes {
+		if strings.EqualFold(u.Scheme, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// partiallyEscaped reports whether the URL ending at end continues into an
+// escape sequence, like \u0026 in a string, that stands for part of it.
+// Escapes of whitespace and quotes end a URL.
+func partiallyEscaped(content string, end int) bool {
+	if end+1 >= len(content) || content[end] != '\\' {
+		return false
+	}
+	return !strings.ContainsRune(`ntr"'\`, rune(content[end+1]))
+}
+
+// isIdentRune reports whether r can be part of an identifier.
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// urlTooltip returns the tooltip of a link to an HTTP/HTTPS URL: its
+// domain, like "pkg.go.dev", or nil if target is not such a URL.
+func urlTooltip(target string) *string {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil
+	}
+	domain := u.Hostname()
+	return &domain
+}
+
 // GoImportLinkProvider finds Go import statements and creates file links.
 // This makes import paths clickable in Go source files.
+//
+// The tooltip of a link is the synopsis of the package documentation,
+// which means reading the package, so links are returned without one and
+// ResolveDocumentLink fills it in when the user hovers the link.
 type GoImportLinkProvider struct {
+	// Resolver, when set, resolves imports from go.mod and go.work to the
+	// directories of the workspace modules, replacements, and the module
+	// cache. ModulePath and SourceRoot are then not needed.
+	Resolver *gomod.Resolver
+
 	// ModulePath is the base module path (e.g., "github.com/user/repo")
 	ModulePath string
 	// SourceRoot is the file system path to the source root
 	SourceRoot string
+
+	// Guard, when set, restricts the package directories read for
+	// tooltips. Links outside of it are kept, since packages in the module
+	// cache are worth following.
+	Guard *workspace.Guard
 }
 
 func (p *GoImportLinkProvider) ProvideDocumentLinks(uri, content string) []core.DocumentLink {
@@ -92,6 +213,9 @@ func (p *GoImportLinkProvider) ProvideDocumentLinks(uri, content string) []core.
 							End:   endPos,
 						},
 						Target: &target,
+						Data: map[string]interface{}{
+							"importPath": importPath,
+						},
 					})
 				}
 			}
@@ -103,18 +227,75 @@ func (p *GoImportLinkProvider) ProvideDocumentLinks(uri, content string) []core.
 	return links
 }
 
+// ResolveDocumentLink sets the tooltip of an import link to the synopsis
+// of the package documentation, like "Package errors provides simple
+// error handling primitives.", read from the package directory if the link
+// leads to one. Otherwise, or if the package has no documentation, the
+// tooltip is the import path.
+func (p *GoImportLinkProvider) ResolveDocumentLink(link core.DocumentLink) core.DocumentLink {
+	data, _ := link.Data.(map[string]interface{})
+	importPath, _ := data["importPath"].(string)
+	if link.Tooltip != nil || importPath == "" {
+		return link
+	}
+
+	tooltip := importPath
+	if link.Target != nil && uripkg.IsFile(*link.Target) {
+		if dir, err := p.Guard.Path(*link.Target); err == nil {
+			if synopsis := packageSynopsis(dir); synopsis != "" {
+				tooltip = synopsis
+			}
+		}
+	}
+	link.Tooltip = &tooltip
+	return link
+}
+
+// packageSynopsis returns the first sentence of the package documentation
+// of the Go files in dir, leaving out tests, or "" if there is none.
+func packageSynopsis(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		// Only the package clause and its comment are needed
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil || f.Doc == nil {
+			continue
+		}
+		pkg := &doc.Package{Name: f.Name.Name}
+		if synopsis := pkg.Synopsis(f.Doc.Text()); synopsis != "" {
+			return synopsis
+		}
+	}
+	return ""
+}
+
 func (p *GoImportLinkProvider) importPathToURI(importPath string) string {
-	// Convert module-relative path to file URI
-	// This is a simplified example - a real implementation would:
-	// 1. Check if it's a relative import (./ or ../)
-	// 2. Look in GOPATH/pkg/mod for external dependencies
-	// 3. Handle standard library paths
+	// Convert the import path to a file URI, through the module files if
+	// there is a resolver, otherwise relative to ModulePath
+	if p.Resolver != nil {
+		dir, version, err := p.Resolver.ResolveImport(importPath)
+		if err == nil {
+			return uripkg.FromPath(dir)
+		}
+		if version != "" {
+			// Known but not downloaded
+			return "https://pkg.go.dev/" + importPath + "@" + version
+		}
+	}
 
 	if strings.HasPrefix(importPath, p.ModulePath) {
 		// Module-relative import
 		relPath := strings.TrimPrefix(importPath, p.ModulePath)
 		relPath = strings.TrimPrefix(relPath, "/")
-		return "file://" + p.SourceRoot + "/" + relPath
+		return uripkg.FromPath(filepath.Join(p.SourceRoot, relPath))
 	}
 
 	// External module - would need to resolve from go.mod
@@ -123,9 +304,15 @@ func (p *GoImportLinkProvider) importPathToURI(importPath string) string {
 
 // FilePathLinkProvider finds file paths in documents.
 // This is useful for making file references in comments or strings clickable.
+// The tooltip of a link is the path of the file relative to the workspace
+// root, or its absolute path if it is outside of the workspace.
 type FilePathLinkProvider struct {
 	// WorkspaceRoot is the root directory of the workspace
 	WorkspaceRoot string
+
+	// Guard, when set, leaves out links to files outside of it, like
+	// "../../etc/passwd".
+	Guard *workspace.Guard
 }
 
 func (p *FilePathLinkProvider) ProvideDocumentLinks(uri, content string) []core.DocumentLink {
@@ -147,24 +334,25 @@ func (p *FilePathLinkProvider) ProvideDocumentLinks(uri, content string) []core.
 			endPos := core.ByteOffsetToPosition(content, end)
 
 			// Convert to file URI
-			var target string
-			if strings.HasPrefix(path, "/") {
-				// Absolute path
-				target = "file://" + path
-			} else if strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") {
-				// Relative path - resolve against workspace root
-				target = "file://" + p.WorkspaceRoot + "/" + path
-			} else {
-				// Just a filename - assume it's in the same directory
-				target = "file://" + p.WorkspaceRoot + "/" + path
+			filePath := path
+			if !strings.HasPrefix(path, "/") {
+				// Relative path or just a filename - resolve against workspace root
+				filePath = filepath.Join(p.WorkspaceRoot, path)
+			}
+			if p.Guard != nil {
+				if _, err := p.Guard.CheckPath(filePath); err != nil {
+					continue
+				}
 			}
+			target := uripkg.FromPath(filePath)
 
 			links = append(links, core.DocumentLink{
 				Range: core.Range{
 					Start: startPos,
 					End:   endPos,
 				},
-				Target: &target,
+				Target:  &target,
+				Tooltip: p.pathTooltip(filePath),
 			})
 		}
 	}
@@ -172,9 +360,26 @@ func (p *FilePathLinkProvider) ProvideDocumentLinks(uri, content string) []core.
 	return links
 }
 
+// pathTooltip returns the tooltip of a link to the file at filePath.
+func (p *FilePathLinkProvider) pathTooltip(filePath string) *string {
+	tooltip := filepath.Clean(filePath)
+	if p.WorkspaceRoot != "" {
+		if rel, err := filepath.Rel(p.WorkspaceRoot, filePath); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			tooltip = rel
+		}
+	}
+	return &tooltip
+}
+
 // MarkdownLinkProvider finds markdown-style links [text](url).
 // This is useful for markdown and documentation files.
-type MarkdownLinkProvider struct{}
+// Links to HTTP/HTTPS URLs have their domain as tooltip. Links with a
+// scheme not allowed, like [x](javascript:...), are left out.
+type MarkdownLinkProvider struct {
+	// Schemes are the URL schemes to link, like "https"; relative links
+	// are always linked. If nil, DefaultLinkSchemes are used.
+	Schemes []string
+}
 
 func (p *MarkdownLinkProvider) ProvideDocumentLinks(uri, content string) []core.DocumentLink {
 	var links []core.DocumentLink
@@ -190,6 +395,9 @@ func (p *MarkdownLinkProvider) ProvideDocumentLinks(uri, content string) []core.
 			end := match[5]
 
 			url := content[start:end]
+			if !linkSchemeAllowed(url, p.Schemes) {
+				continue
+			}
 			startPos := core.ByteOffsetToPosition(content, start)
 			endPos := core.ByteOffsetToPosition(content, end)
 
@@ -198,7 +406,8 @@ func (p *MarkdownLinkProvider) ProvideDocumentLinks(uri, content string) []core.
 					Start: startPos,
 					End:   endPos,
 				},
-				Target: &url,
+				Target:  &url,
+				Tooltip: urlTooltip(url),
 			})
 		}
 	}
@@ -208,6 +417,7 @@ func (p *MarkdownLinkProvider) ProvideDocumentLinks(uri, content string) []core.
 
 // CompositeDocumentLinkProvider combines multiple link providers.
 // This allows detecting multiple types of links in a single document.
+// Links are resolved by the provider that returned them.
 type CompositeDocumentLinkProvider struct {
 	Providers []core.DocumentLinkProvider
 }
@@ -221,8 +431,14 @@ func NewCompositeDocumentLinkProvider(providers ...core.DocumentLinkProvider) *C
 func (p *CompositeDocumentLinkProvider) ProvideDocumentLinks(uri, content string) []core.DocumentLink {
 	var allLinks []core.DocumentLink
 
-	for _, provider := range p.Providers {
+	for i, provider := range p.Providers {
 		links := provider.ProvideDocumentLinks(uri, content)
+		if _, ok := provider.(core.DocumentLinkResolveProvider); ok {
+			// Remember which provider resolves the link
+			for j := range links {
+				links[j].Data = map[string]interface{}{"provider": i, "data": links[j].Data}
+			}
+		}
 		if links != nil {
 			allLinks = append(allLinks, links...)
 		}
@@ -231,6 +447,34 @@ func (p *CompositeDocumentLinkProvider) ProvideDocumentLinks(uri, content string
 	return allLinks
 }
 
+// ResolveDocumentLink resolves a link with the provider that returned it,
+// if that provider resolves links.
+func (p *CompositeDocumentLinkProvider) ResolveDocumentLink(link core.DocumentLink) core.DocumentLink {
+	data, ok := link.Data.(map[string]interface{})
+	if !ok {
+		return link
+	}
+	// The index is a float64 once the data went through JSON
+	var index int
+	switch i := data["provider"].(type) {
+	case int:
+		index = i
+	case float64:
+		index = int(i)
+	default:
+		return link
+	}
+	if index < 0 || index >= len(p.Providers) {
+		return link
+	}
+	resolver, ok := p.Providers[index].(core.DocumentLinkResolveProvider)
+	if !ok {
+		return link
+	}
+	link.Data = data["data"]
+	return resolver.ResolveDocumentLink(link)
+}
+
 // Example usage in CLI tool
 func CLIDocumentLinksExample() {
 	content := `package main
@@ -268,7 +512,11 @@ func main() {
 		if link.Target != nil {
 			target = *link.Target
 		}
-		println("  -", link.Range.String(), "->", target)
+		tooltip := ""
+		if resolved := provider.ResolveDocumentLink(link); resolved.Tooltip != nil {
+			tooltip = " (" + *resolved.Tooltip + ")"
+		}
+		println("  -", link.Range.String(), "->", target+tooltip)
 	}
 }
 
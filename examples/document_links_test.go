@@ -1,10 +1,15 @@
 package examples
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/gomod"
+	uripkg "github.com/SCKelemen/lsp/uri"
+	"github.com/SCKelemen/lsp/workspace"
 )
 
 // TestURLLinkProvider tests URL link detection.
@@ -91,6 +96,78 @@ func main() {}`,
 	}
 }
 
+// TestURLLinkProvider_Safety tests that URLs are only linked where they
+// are whole, and with allowed schemes.
+func TestURLLinkProvider_Safety(t *testing.T) {
+	tests := []struct {
+		name     string
+		uri      string
+		content  string
+		schemes  []string
+		wantURLs []string
+	}{
+		{
+			name:     "code is not searched in Go",
+			uri:      "file:///main.go",
+			content:  "package main\n\nfunc main() {\nhttp://example.com\n\tgoto http\n}\n",
+			wantURLs: nil,
+		},
+		{
+			name:     "partially escaped string",
+			uri:      "file:///main.go",
+			content:  "package main\n\nconst a = \"https://example.com/?a=1\\u0026b=2\"\nconst b = \"see https://example.com/b\\n\"\n",
+			wantURLs: []string{"https://example.com/b"},
+		},
+		{
+			name:     "inside an identifier",
+			uri:      "file:///notes.txt",
+			content:  "xhttps://example.com and https://example.com/ok",
+			wantURLs: []string{"https://example.com/ok"},
+		},
+		{
+			name:     "trailing punctuation",
+			uri:      "file:///notes.txt",
+			content:  "See https://example.com/docs. Or mailto:team@example.com, or vscode://file/a.go!",
+			wantURLs: []string{"https://example.com/docs", "mailto:team@example.com", "vscode://file/a.go"},
+		},
+		{
+			name:     "dangerous schemes",
+			uri:      "file:///notes.txt",
+			content:  "javascript:alert(1) data:text/html,x file:///etc/passwd",
+			wantURLs: nil,
+		},
+		{
+			name:     "configured schemes",
+			uri:      "file:///notes.txt",
+			content:  "https://example.com file:///etc/hosts",
+			schemes:  []string{"file"},
+			wantURLs: []string{"file:///etc/hosts"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &URLLinkProvider{Schemes: tt.schemes}
+			var got []string
+			for _, link := range provider.ProvideDocumentLinks(tt.uri, tt.content) {
+				got = append(got, *link.Target)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.wantURLs, " ") {
+				t.Errorf("links = %q, want %q", got, tt.wantURLs)
+			}
+		})
+	}
+
+	markdown := "[ok](https://example.com) [relative](./guide.md) [bad](javascript:alert(1))"
+	var got []string
+	for _, link := range (&MarkdownLinkProvider{}).ProvideDocumentLinks("file:///README.md", markdown) {
+		got = append(got, *link.Target)
+	}
+	if want := "https://example.com ./guide.md"; strings.Join(got, " ") != want {
+		t.Errorf("markdown links = %q, want %s", got, want)
+	}
+}
+
 // TestGoImportLinkProvider tests Go import link detection.
 func TestGoImportLinkProvider(t *testing.T) {
 	tests := []struct {
@@ -196,6 +273,34 @@ func TestGoImportLinkProvider_NonGoFile(t *testing.T) {
 	}
 }
 
+// TestGoImportLinkProvider_Resolver tests links resolved from go.mod.
+func TestGoImportLinkProvider_Resolver(t *testing.T) {
+	root := t.TempDir()
+	goMod := "module example.com/app\n\nrequire github.com/pkg/errors v0.9.1\n"
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte(goMod), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "internal", "util"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	resolver := gomod.NewResolver(root)
+	resolver.ModCache = filepath.Join(root, "modcache")
+	provider := &GoImportLinkProvider{Resolver: resolver}
+
+	content := "package main\n\nimport (\n\t\"example.com/app/internal/util\"\n\t\"github.com/pkg/errors\"\n)\n"
+	links := provider.ProvideDocumentLinks("file:///test.go", content)
+	if len(links) != 2 {
+		t.Fatalf("got %d links, want 2", len(links))
+	}
+	if want := uripkg.FromPath(filepath.Join(root, "internal", "util")); *links[0].Target != want {
+		t.Errorf("workspace package link = %q, want %q", *links[0].Target, want)
+	}
+	// Not in the module cache
+	if want := "https://pkg.go.dev/github.com/pkg/errors@v0.9.1"; *links[1].Target != want {
+		t.Errorf("module link = %q, want %q", *links[1].Target, want)
+	}
+}
+
 // TestFilePathLinkProvider tests file path link detection.
 func TestFilePathLinkProvider(t *testing.T) {
 	tests := []struct {
@@ -279,6 +384,23 @@ func TestFilePathLinkProvider(t *testing.T) {
 	}
 }
 
+func TestFilePathLinkProvider_Guard(t *testing.T) {
+	root := t.TempDir()
+	provider := &FilePathLinkProvider{WorkspaceRoot: root, Guard: workspace.NewGuard(root)}
+	content := `load("./config/app.yaml")
+load("../../etc/passwd")
+load("/etc/hosts")
+load("./../secret.txt")
+`
+	links := provider.ProvideDocumentLinks("file:///test.go", content)
+	if len(links) != 1 {
+		t.Fatalf("got %d links, want 1: %v", len(links), links)
+	}
+	if want := uripkg.FromPath(filepath.Join(root, "config", "app.yaml")); *links[0].Target != want {
+		t.Errorf("target = %s, want %s", *links[0].Target, want)
+	}
+}
+
 // TestMarkdownLinkProvider tests markdown link detection.
 func TestMarkdownLinkProvider(t *testing.T) {
 	tests := []struct {
@@ -432,6 +554,62 @@ import "github.com/user/repo/pkg"
 	}
 }
 
+// TestDocumentLinkTooltips tests the tooltips of links, resolved lazily
+// for imports.
+func TestDocumentLinkTooltips(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "util"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	doc := "// Package util has helpers. It has no dependencies.\npackage util\n"
+	if err := os.WriteFile(filepath.Join(root, "util", "doc.go"), []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	content := `package main
+
+import (
+	"example.com/app/util"
+	"example.com/app/missing"
+)
+
+// See https://pkg.go.dev/std and "./docs/guide.md"
+`
+	provider := NewCompositeDocumentLinkProvider(
+		&URLLinkProvider{},
+		&FilePathLinkProvider{WorkspaceRoot: root},
+		&GoImportLinkProvider{ModulePath: "example.com/app", SourceRoot: root},
+	)
+
+	tooltips := make(map[string]string)
+	for _, link := range provider.ProvideDocumentLinks("file:///main.go", content) {
+		start := core.PositionToByteOffset(content, link.Range.Start)
+		end := core.PositionToByteOffset(content, link.Range.End)
+		text := content[start:end]
+		if strings.HasPrefix(text, "example.com/") && link.Tooltip != nil {
+			t.Errorf("import link %s has tooltip %q before resolve", text, *link.Tooltip)
+		}
+		link = provider.ResolveDocumentLink(link)
+		if link.Tooltip == nil {
+			t.Errorf("link %s has no tooltip", text)
+			continue
+		}
+		tooltips[text] = *link.Tooltip
+	}
+
+	want := map[string]string{
+		"example.com/app/util":    "Package util has helpers.",
+		"example.com/app/missing": "example.com/app/missing",
+		"https://pkg.go.dev/std":  "pkg.go.dev",
+		"./docs/guide.md":         filepath.Join("docs", "guide.md"),
+	}
+	for text, tooltip := range want {
+		if tooltips[text] != tooltip {
+			t.Errorf("tooltip of %s = %q, want %q", text, tooltips[text], tooltip)
+		}
+	}
+}
+
 // TestDocumentLinks_EdgeCases tests edge cases.
 func TestDocumentLinks_EdgeCases(t *testing.T) {
 	tests := []struct {
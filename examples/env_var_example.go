@@ -0,0 +1,275 @@
+package examples
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// EnvVarProvider provides highlights, hover, go-to-definition, and
+// diagnostics for references to environment variables, like ${PORT} or
+// ${PORT:-8080}, in .env files, YAML files like docker-compose.yml, and
+// shell scripts.
+//
+// Variables are defined by Environment, a snapshot of the environment the
+// files will be used in, and by the lines like PORT=8080 of EnvFiles and
+// of the document itself if it is a .env file. References to undefined
+// variables without a default are reported.
+type EnvVarProvider struct {
+	// Environment is the snapshot of the environment variables, by name.
+	Environment map[string]string
+
+	// EnvFiles holds the content of .env files by URI. Definitions jump to
+	// the lines defining variables there.
+	EnvFiles map[string]string
+}
+
+var (
+	// ${NAME}, with an optional operator like :- and a word after it
+	envReferenceRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:?[-=?+][^}]*)?\}`)
+
+	// NAME=value, optionally exported
+	envDefinitionRegex = regexp.MustCompile(`^\s*(?:export\s+)?([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+)
+
+// envReference is a reference to a variable found by envReferences.
+type envReference struct {
+	// start and end are the byte offsets of the name
+	start, end int
+	name       string
+
+	// fallback is set for references that expand to something when the
+	// variable is unset, like ${NAME:-default} and ${NAME:+alternate}
+	fallback bool
+}
+
+// envDefinition is the definition of a variable in a .env file.
+type envDefinition struct {
+	uri   string
+	line  int
+	start int // byte offset of the name in its line
+	name  string
+	value string
+}
+
+// isEnvFile reports whether uri is a .env file, like ".env" or
+// ".env.local".
+func isEnvFile(uri string) bool {
+	name := path.Base(uri)
+	return name == ".env" || strings.HasPrefix(name, ".env.") || strings.HasSuffix(name, ".env")
+}
+
+// supports reports whether documents at uri can reference variables.
+func (p *EnvVarProvider) supports(uri string) bool {
+	switch path.Ext(uri) {
+	case ".yaml", ".yml", ".sh", ".bash", ".zsh":
+		return true
+	}
+	return isEnvFile(uri)
+}
+
+// envReferences returns the references to variables in content, leaving
+// out comment lines.
+func envReferences(content string) []envReference {
+	var references []envReference
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			for _, match := range envReferenceRegex.FindAllStringSubmatchIndex(line, -1) {
+				operator := ""
+				if match[4] >= 0 {
+					operator = strings.TrimPrefix(line[match[4]:match[5]], ":")
+				}
+				references = append(references, envReference{
+					start:    offset + match[2],
+					end:      offset + match[3],
+					name:     line[match[2]:match[3]],
+					fallback: operator != "" && operator[0] != '?',
+				})
+			}
+		}
+		offset += len(line)
+	}
+	return references
+}
+
+// envDefinitions returns the variables a .env file defines.
+func envDefinitions(uri, content string) []envDefinition {
+	var definitions []envDefinition
+	for i, line := range strings.Split(content, "\n") {
+		match := envDefinitionRegex.FindStringSubmatchIndex(line)
+		if match == nil {
+			continue
+		}
+		value := strings.TrimSpace(line[match[4]:match[5]])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		definitions = append(definitions, envDefinition{
+			uri:   uri,
+			line:  i,
+			start: match[2],
+			name:  line[match[2]:match[3]],
+			value: value,
+		})
+	}
+	return definitions
+}
+
+// definitions returns the definitions of name in the .env files, and in
+// the document if it is one, in order of URI.
+func (p *EnvVarProvider) definitions(uri, content, name string) []envDefinition {
+	files := make(map[string]string, len(p.EnvFiles)+1)
+	for fileURI, fileContent := range p.EnvFiles {
+		files[fileURI] = fileContent
+	}
+	if isEnvFile(uri) {
+		files[uri] = content
+	}
+	uris := make([]string, 0, len(files))
+	for fileURI := range files {
+		uris = append(uris, fileURI)
+	}
+	sort.Strings(uris)
+
+	var found []envDefinition
+	for _, fileURI := range uris {
+		for _, definition := range envDefinitions(fileURI, files[fileURI]) {
+			if definition.name == name {
+				found = append(found, definition)
+			}
+		}
+	}
+	return found
+}
+
+// nameAt returns the name of the variable referenced or, in a .env file,
+// defined at position.
+func (p *EnvVarProvider) nameAt(uri, content string, position core.Position) (name string, nameRange core.Range, ok bool) {
+	offset := core.PositionToByteOffset(content, position)
+	for _, reference := range envReferences(content) {
+		if reference.start <= offset && offset <= reference.end {
+			return reference.name, envRange(content, reference.start, reference.end), true
+		}
+	}
+	if isEnvFile(uri) {
+		for _, definition := range envDefinitions(uri, content) {
+			if definition.line == position.Line && definition.start <= position.Character && position.Character <= definition.start+len(definition.name) {
+				return definition.name, envDefinitionRange(definition), true
+			}
+		}
+	}
+	return "", core.Range{}, false
+}
+
+func (p *EnvVarProvider) ProvideDocumentHighlights(ctx core.DocumentHighlightContext) []core.DocumentHighlight {
+	if !p.supports(ctx.URI) {
+		return nil
+	}
+	name, _, ok := p.nameAt(ctx.URI, ctx.Content, ctx.Position)
+	if !ok {
+		return nil
+	}
+
+	var highlights []core.DocumentHighlight
+	if isEnvFile(ctx.URI) {
+		for _, definition := range envDefinitions(ctx.URI, ctx.Content) {
+			if definition.name == name {
+				kind := core.DocumentHighlightKindWrite
+				highlights = append(highlights, core.DocumentHighlight{Range: envDefinitionRange(definition), Kind: &kind})
+			}
+		}
+	}
+	for _, reference := range envReferences(ctx.Content) {
+		if reference.name == name {
+			kind := core.DocumentHighlightKindRead
+			highlights = append(highlights, core.DocumentHighlight{Range: envRange(ctx.Content, reference.start, reference.end), Kind: &kind})
+		}
+	}
+	return highlights
+}
+
+// ProvideHover shows the value of the variable at position: the value in
+// Environment, which the process running with the files would see, or else
+// the value of its first definition in a .env file.
+func (p *EnvVarProvider) ProvideHover(uri, content string, position core.Position) *core.HoverInfo {
+	if !p.supports(uri) {
+		return nil
+	}
+	name, nameRange, ok := p.nameAt(uri, content, position)
+	if !ok {
+		return nil
+	}
+
+	text := fmt.Sprintf("`%s` is not defined", name)
+	if value, ok := p.Environment[name]; ok {
+		text = fmt.Sprintf("`%s` = `%s` (environment)", name, value)
+	} else if definitions := p.definitions(uri, content, name); len(definitions) > 0 {
+		text = fmt.Sprintf("`%s` = `%s` (%s)", name, definitions[0].value, path.Base(definitions[0].uri))
+	}
+	return &core.HoverInfo{Contents: text, Range: &nameRange}
+}
+
+// ProvideDefinition returns the lines of the .env files defining the
+// variable at position.
+func (p *EnvVarProvider) ProvideDefinition(uri, content string, position core.Position) []core.Location {
+	if !p.supports(uri) {
+		return nil
+	}
+	name, _, ok := p.nameAt(uri, content, position)
+	if !ok {
+		return nil
+	}
+	var locations []core.Location
+	for _, definition := range p.definitions(uri, content, name) {
+		locations = append(locations, core.Location{URI: definition.uri, Range: envDefinitionRange(definition)})
+	}
+	return locations
+}
+
+// ProvideDiagnostics reports references to variables defined neither in
+// Environment nor in a .env file, unless they have a default.
+func (p *EnvVarProvider) ProvideDiagnostics(uri, content string) []core.Diagnostic {
+	if !p.supports(uri) {
+		return nil
+	}
+	var diagnostics []core.Diagnostic
+	for _, reference := range envReferences(content) {
+		if reference.fallback {
+			continue
+		}
+		if _, ok := p.Environment[reference.name]; ok || len(p.definitions(uri, content, reference.name)) > 0 {
+			continue
+		}
+		severity := core.SeverityWarning
+		code := core.NewStringCode("undefined-env-var")
+		diagnostics = append(diagnostics, core.Diagnostic{
+			Range:    envRange(content, reference.start, reference.end),
+			Severity: &severity,
+			Code:     &code,
+			Source:   "env",
+			Message:  fmt.Sprintf("Environment variable %s is not defined", reference.name),
+		})
+	}
+	return diagnostics
+}
+
+// envRange returns the range between byte offsets of content.
+func envRange(content string, start, end int) core.Range {
+	return core.Range{
+		Start: core.ByteOffsetToPosition(content, start),
+		End:   core.ByteOffsetToPosition(content, end),
+	}
+}
+
+// envDefinitionRange returns the range of the name of a definition.
+func envDefinitionRange(definition envDefinition) core.Range {
+	return core.Range{
+		Start: core.Position{Line: definition.line, Character: definition.start},
+		End:   core.Position{Line: definition.line, Character: definition.start + len(definition.name)},
+	}
+}
@@ -0,0 +1,110 @@
+package examples
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+const envComposeSource = `services:
+  web:
+    image: app:${TAG}
+    ports:
+      - "${PORT:-8080}:80"
+    environment:
+      DATABASE_URL: ${DATABASE_URL}
+      # API_KEY: ${API_KEY}
+      SECRET: ${SECRET:?secret is required}
+`
+
+func TestEnvVarProvider(t *testing.T) {
+	provider := &EnvVarProvider{
+		Environment: map[string]string{"TAG": "v1.2.0"},
+		EnvFiles: map[string]string{
+			"file:///project/.env": "# Local settings\nexport DATABASE_URL=\"postgres://localhost/app\"\nPORT=3000\n",
+		},
+	}
+	uri := "file:///project/docker-compose.yml"
+	at := func(marker string) core.Position {
+		return core.ByteOffsetToPosition(envComposeSource, strings.Index(envComposeSource, marker)+2)
+	}
+
+	hovers := map[string]string{
+		"${TAG}":          "`TAG` = `v1.2.0` (environment)",
+		"${PORT":          "`PORT` = `3000` (.env)",
+		"${DATABASE_URL}": "`DATABASE_URL` = `postgres://localhost/app` (.env)",
+		"${SECRET":        "`SECRET` is not defined",
+	}
+	for marker, want := range hovers {
+		hover := provider.ProvideHover(uri, envComposeSource, at(marker))
+		if hover == nil {
+			t.Errorf("no hover on %s", marker)
+			continue
+		}
+		if hover.Contents != want {
+			t.Errorf("hover on %s = %q, want %q", marker, hover.Contents, want)
+		}
+	}
+	if hover := provider.ProvideHover(uri, envComposeSource, at("${API_KEY}")); hover != nil {
+		t.Errorf("hover in a comment = %q, want none", hover.Contents)
+	}
+
+	locations := provider.ProvideDefinition(uri, envComposeSource, at("${DATABASE_URL}"))
+	want := core.Location{
+		URI:   "file:///project/.env",
+		Range: core.Range{Start: core.Position{Line: 1, Character: 7}, End: core.Position{Line: 1, Character: 19}},
+	}
+	if len(locations) != 1 || locations[0] != want {
+		t.Errorf("definition = %v, want %v", locations, want)
+	}
+	if locations := provider.ProvideDefinition(uri, envComposeSource, at("${TAG}")); locations != nil {
+		t.Errorf("definition of an environment variable = %v, want none", locations)
+	}
+
+	diagnostics := provider.ProvideDiagnostics(uri, envComposeSource)
+	if len(diagnostics) != 1 || !strings.Contains(diagnostics[0].Message, "SECRET") {
+		t.Fatalf("diagnostics = %v, want one for SECRET", diagnostics)
+	}
+	if diagnostics[0].Code == nil || diagnostics[0].Code.String() != "undefined-env-var" {
+		t.Errorf("diagnostic code = %v, want undefined-env-var", diagnostics[0].Code)
+	}
+
+	if diagnostics := provider.ProvideDiagnostics("file:///project/main.go", envComposeSource); diagnostics != nil {
+		t.Errorf("diagnostics in a Go file = %v, want none", diagnostics)
+	}
+}
+
+func TestEnvVarProvider_EnvFile(t *testing.T) {
+	provider := &EnvVarProvider{}
+	uri := "file:///project/.env.local"
+	content := "HOST=localhost\nURL=http://${HOST}:${PORT}\nPORT=80\nBACKUP_URL=${HOST}\n"
+
+	highlights := provider.ProvideDocumentHighlights(core.DocumentHighlightContext{
+		URI:      uri,
+		Content:  content,
+		Position: core.Position{Line: 0, Character: 1},
+	})
+	var kinds []core.DocumentHighlightKind
+	for _, highlight := range highlights {
+		kinds = append(kinds, *highlight.Kind)
+	}
+	wantKinds := []core.DocumentHighlightKind{core.DocumentHighlightKindWrite, core.DocumentHighlightKindRead, core.DocumentHighlightKindRead}
+	if len(kinds) != len(wantKinds) {
+		t.Fatalf("highlight kinds = %v, want %v", kinds, wantKinds)
+	}
+	for i := range kinds {
+		if kinds[i] != wantKinds[i] {
+			t.Errorf("highlight %d kind = %v, want %v", i, kinds[i], wantKinds[i])
+		}
+	}
+
+	// Variables defined later in the same file are defined too
+	if diagnostics := provider.ProvideDiagnostics(uri, content); len(diagnostics) != 0 {
+		t.Errorf("diagnostics = %v, want none", diagnostics)
+	}
+	locations := provider.ProvideDefinition(uri, content, core.Position{Line: 1, Character: 22})
+	if len(locations) != 1 || locations[0].URI != uri || locations[0].Range.Start.Line != 2 {
+		t.Errorf("definition of PORT = %v, want line 2", locations)
+	}
+}
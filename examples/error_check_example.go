@@ -0,0 +1,377 @@
+package examples
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// ErrorCheckProvider provides the Go refactorings for the most common
+// editing chore: checking errors.
+//
+// "Add error check" applies to a call returning an error, like
+// "x, err := f()" or "f()". It inserts
+//
+//	if err != nil {
+//		return 0, "", err
+//	}
+//
+// returning the zero values of the enclosing function's other results, or
+// panics if that function does not return an error.
+//
+// "Unwrap error check to must()" is the inverse for "x, err := f()"
+// followed by such a check: it rewrites both to "x := must(f())" and adds
+// a generic must helper to the file if it has none.
+type ErrorCheckProvider struct{}
+
+func (p *ErrorCheckProvider) ProvideCodeFixes(ctx core.CodeFixContext) []core.CodeAction {
+	if !strings.HasSuffix(ctx.URI, ".go") || !codeActionKindRequested(ctx.Only, core.CodeActionKindRefactorRewrite) {
+		return nil
+	}
+
+	fset, f, err := parseGoFile(ctx.URI, ctx.Content)
+	if err != nil {
+		return nil
+	}
+	offset := core.PositionToByteOffset(ctx.Content, ctx.Range.Start)
+	if offset < 0 {
+		return nil
+	}
+	stmt := goStatementAt(fset, f, offset)
+	if stmt == nil {
+		return nil
+	}
+
+	info := typeCheckGoFile(fset, f)
+	kind := core.CodeActionKindRefactorRewrite
+	var actions []core.CodeAction
+
+	if edits := p.addErrorCheck(ctx.Content, fset, f, info, stmt); edits != nil {
+		actions = append(actions, core.CodeAction{
+			Title: "Add error check",
+			Kind:  &kind,
+			Edit:  &core.WorkspaceEdit{Changes: map[string][]core.TextEdit{ctx.URI: edits}},
+		})
+	}
+	if edits := p.unwrapToMust(ctx.Content, fset, f, info, stmt); edits != nil {
+		actions = append(actions, core.CodeAction{
+			Title: "Unwrap error check to must()",
+			Kind:  &kind,
+			Edit:  &core.WorkspaceEdit{Changes: map[string][]core.TextEdit{ctx.URI: edits}},
+		})
+	}
+	return actions
+}
+
+// addErrorCheck returns the edits adding an error check after the statement.
+func (p *ErrorCheckProvider) addErrorCheck(content string, fset *token.FileSet, f *ast.File, info *types.Info, stmt *goStatement) []core.TextEdit {
+	indent := lineIndent(content, fset.Position(stmt.stmt().Pos()).Offset)
+
+	switch s := stmt.stmt().(type) {
+	case *ast.AssignStmt:
+		// x, err := f()
+		if len(s.Rhs) != 1 {
+			return nil
+		}
+		if _, ok := s.Rhs[0].(*ast.CallExpr); !ok {
+			return nil
+		}
+		errIdent, ok := s.Lhs[len(s.Lhs)-1].(*ast.Ident)
+		if !ok || errIdent.Name == "_" || !isErrorVariable(info, errIdent) {
+			return nil
+		}
+		if next := stmt.next(); next != nil && checksError(next, errIdent.Name) {
+			return nil
+		}
+
+		check := "\n" + indent + "if " + errIdent.Name + " != nil {\n" +
+			indent + "\t" + errorReturn(info, stmt.fn, errIdent.Name) + "\n" +
+			indent + "}"
+		end := core.ByteOffsetToPosition(content, fset.Position(s.End()).Offset)
+		return []core.TextEdit{{Range: core.Range{Start: end, End: end}, NewText: check}}
+
+	case *ast.ExprStmt:
+		// f()
+		call, ok := s.X.(*ast.CallExpr)
+		if !ok {
+			return nil
+		}
+		results := callResults(info, call)
+		if len(results) == 0 || !isErrorType(results[len(results)-1]) {
+			return nil
+		}
+
+		// Declaring err in the if statement cannot clash with another err
+		lhs := strings.Repeat("_, ", len(results)-1) + "err"
+		start := fset.Position(s.Pos()).Offset
+		end := fset.Position(s.End()).Offset
+		check := "if " + lhs + " := " + content[start:end] + "; err != nil {\n" +
+			indent + "\t" + errorReturn(info, stmt.fn, "err") + "\n" +
+			indent + "}"
+		return []core.TextEdit{{
+			Range: core.Range{
+				Start: core.ByteOffsetToPosition(content, start),
+				End:   core.ByteOffsetToPosition(content, end),
+			},
+			NewText: check,
+		}}
+	}
+	return nil
+}
+
+// unwrapToMust returns the edits replacing "x, err := f()" and the error
+// check following it with "x := must(f())". The statement may be either.
+func (p *ErrorCheckProvider) unwrapToMust(content string, fset *token.FileSet, f *ast.File, info *types.Info, stmt *goStatement) []core.TextEdit {
+	assignIndex := stmt.index
+	if _, ok := stmt.stmt().(*ast.IfStmt); ok {
+		assignIndex--
+	}
+	if assignIndex < 0 || assignIndex+1 >= len(stmt.list) {
+		return nil
+	}
+	assign, ok := stmt.list[assignIndex].(*ast.AssignStmt)
+	if !ok || assign.Tok != token.DEFINE || len(assign.Lhs) != 2 || len(assign.Rhs) != 1 {
+		return nil
+	}
+	if _, ok := assign.Rhs[0].(*ast.CallExpr); !ok {
+		return nil
+	}
+	errIdent, ok := assign.Lhs[1].(*ast.Ident)
+	if !ok || errIdent.Name == "_" {
+		return nil
+	}
+	check := stmt.list[assignIndex+1]
+	if !checksError(check, errIdent.Name) {
+		return nil
+	}
+
+	// err must be declared here and used by the check only
+	errObj := info.Defs[errIdent]
+	if errObj == nil {
+		return nil
+	}
+	for ident, obj := range info.Uses {
+		if obj == errObj && (ident.Pos() < check.Pos() || ident.Pos() >= check.End()) {
+			return nil
+		}
+	}
+
+	value := types.ExprString(assign.Lhs[0])
+	tok := " := "
+	if value == "_" {
+		tok = " = "
+	}
+	callStart := fset.Position(assign.Rhs[0].Pos()).Offset
+	callEnd := fset.Position(assign.Rhs[0].End()).Offset
+	edits := []core.TextEdit{{
+		Range: core.Range{
+			Start: core.ByteOffsetToPosition(content, fset.Position(assign.Pos()).Offset),
+			End:   core.ByteOffsetToPosition(content, fset.Position(check.End()).Offset),
+		},
+		NewText: value + tok + "must(" + content[callStart:callEnd] + ")",
+	}}
+
+	if !declaresFunc(f, "must") {
+		end := core.ByteOffsetToPosition(content, len(content))
+		helper := "\n// must panics if err is not nil and returns v otherwise.\n" +
+			"func must[T any](v T, err error) T {\n" +
+			"\tif err != nil {\n" +
+			"\t\tpanic(err)\n" +
+			"\t}\n" +
+			"\treturn v\n" +
+			"}\n"
+		if !strings.HasSuffix(content, "\n") {
+			helper = "\n" + helper
+		}
+		edits = append(edits, core.TextEdit{Range: core.Range{Start: end, End: end}, NewText: helper})
+	}
+	return edits
+}
+
+// declaresFunc reports whether the file declares a function named name.
+func declaresFunc(f *ast.File, name string) bool {
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// goStatement is a statement in a statement list and the function
+// containing it.
+type goStatement struct {
+	list  []ast.Stmt
+	index int
+	fn    *ast.FuncType
+}
+
+func (s *goStatement) stmt() ast.Stmt {
+	return s.list[s.index]
+}
+
+func (s *goStatement) next() ast.Stmt {
+	if s.index+1 < len(s.list) {
+		return s.list[s.index+1]
+	}
+	return nil
+}
+
+// goStatementAt returns the innermost statement of a function body
+// containing the byte offset, or nil.
+func goStatementAt(fset *token.FileSet, f *ast.File, offset int) *goStatement {
+	tokFile := fset.File(f.Pos())
+	if tokFile == nil || offset > tokFile.Size() {
+		return nil
+	}
+	pos := tokFile.Pos(offset)
+
+	var (
+		found *goStatement
+		funcs []*ast.FuncType
+		stack []ast.Node
+	)
+	ast.Inspect(f, func(n ast.Node) bool {
+		if n == nil {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			switch top.(type) {
+			case *ast.FuncDecl, *ast.FuncLit:
+				funcs = funcs[:len(funcs)-1]
+			}
+			return true
+		}
+		if pos < n.Pos() || pos > n.End() {
+			return false
+		}
+		stack = append(stack, n)
+
+		var list []ast.Stmt
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			funcs = append(funcs, n.Type)
+		case *ast.FuncLit:
+			funcs = append(funcs, n.Type)
+		case *ast.BlockStmt:
+			list = n.List
+		case *ast.CaseClause:
+			list = n.Body
+		case *ast.CommClause:
+			list = n.Body
+		}
+		for i, s := range list {
+			if s.Pos() <= pos && pos <= s.End() && len(funcs) > 0 {
+				found = &goStatement{list: list, index: i, fn: funcs[len(funcs)-1]}
+				break
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// isErrorVariable reports whether ident has the error type. Without type
+// information, a variable named err is assumed to be one.
+func isErrorVariable(info *types.Info, ident *ast.Ident) bool {
+	obj := info.Defs[ident]
+	if obj == nil {
+		obj = info.Uses[ident]
+	}
+	if obj == nil || obj.Type() == types.Typ[types.Invalid] {
+		return ident.Name == "err"
+	}
+	return isErrorType(obj.Type())
+}
+
+// callResults returns the result types of a call, or nil if they are unknown.
+func callResults(info *types.Info, call *ast.CallExpr) []types.Type {
+	tv, ok := info.Types[call]
+	if !ok || tv.Type == nil {
+		return nil
+	}
+	if tuple, ok := tv.Type.(*types.Tuple); ok {
+		results := make([]types.Type, tuple.Len())
+		for i := range results {
+			results[i] = tuple.At(i).Type()
+		}
+		return results
+	}
+	if tv.Type == types.Typ[types.Invalid] {
+		return nil
+	}
+	return []types.Type{tv.Type}
+}
+
+// checksError reports whether stmt is "if err != nil { ... }" for errName.
+func checksError(stmt ast.Stmt, errName string) bool {
+	ifStmt, ok := stmt.(*ast.IfStmt)
+	if !ok || ifStmt.Init != nil || ifStmt.Else != nil {
+		return false
+	}
+	cond, ok := ifStmt.Cond.(*ast.BinaryExpr)
+	if !ok || cond.Op != token.NEQ {
+		return false
+	}
+	x, ok1 := cond.X.(*ast.Ident)
+	y, ok2 := cond.Y.(*ast.Ident)
+	return ok1 && ok2 && x.Name == errName && y.Name == "nil"
+}
+
+// errorReturn returns the statement handing errName to the caller of fn:
+// a return of the zero values of its other results and the error, or a
+// panic if fn does not return an error.
+func errorReturn(info *types.Info, fn *ast.FuncType, errName string) string {
+	if fn.Results == nil || len(fn.Results.List) == 0 {
+		return "panic(" + errName + ")"
+	}
+
+	var resultTypes []ast.Expr
+	for _, field := range fn.Results.List {
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			resultTypes = append(resultTypes, field.Type)
+		}
+	}
+
+	last := resultTypes[len(resultTypes)-1]
+	if !isErrorType(info.Types[last].Type) && types.ExprString(last) != "error" {
+		return "panic(" + errName + ")"
+	}
+
+	values := make([]string, 0, len(resultTypes))
+	for _, expr := range resultTypes[:len(resultTypes)-1] {
+		values = append(values, zeroValue(expr, info.Types[expr].Type))
+	}
+	values = append(values, errName)
+	return "return " + strings.Join(values, ", ")
+}
+
+// lineIndent returns the leading whitespace of the line containing offset.
+func lineIndent(content string, offset int) string {
+	start := strings.LastIndexByte(content[:offset], '\n') + 1
+	end := start
+	for end < len(content) && (content[end] == ' ' || content[end] == '\t') {
+		end++
+	}
+	return content[start:end]
+}
+
+// codeActionKindRequested reports whether actions of kind were requested:
+// only is empty, or contains kind or a parent kind of it.
+func codeActionKindRequested(only []core.CodeActionKind, kind core.CodeActionKind) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, requested := range only {
+		if requested == kind || strings.HasPrefix(string(kind), string(requested)+".") {
+			return true
+		}
+	}
+	return false
+}
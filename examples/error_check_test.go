@@ -0,0 +1,196 @@
+package examples
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+func TestErrorCheckProvider(t *testing.T) {
+	provider := &ErrorCheckProvider{}
+
+	tests := []struct {
+		name     string
+		content  string
+		position core.Position
+		title    string
+		want     string
+	}{
+		{
+			name: "assignment",
+			content: `package main
+
+type Point struct{ X, Y int }
+
+func load() (*Point, error) { return nil, nil }
+
+func run() (Point, int, string, []byte, error) {
+	p, err := load()
+	return *p, 0, "", nil, nil
+}
+`,
+			position: core.Position{Line: 7, Character: 2},
+			title:    "Add error check",
+			want: `	p, err := load()
+	if err != nil {
+		return Point{}, 0, "", nil, err
+	}
+	return *p, 0, "", nil, nil`,
+		},
+		{
+			name: "call statement",
+			content: `package main
+
+func save(s string) (int, error) { return 0, nil }
+
+func run() error {
+	save("x")
+	return nil
+}
+`,
+			position: core.Position{Line: 5, Character: 2},
+			title:    "Add error check",
+			want: `	if _, err := save("x"); err != nil {
+		return err
+	}`,
+		},
+		{
+			name: "no error result",
+			content: `package main
+
+import "os"
+
+func main() {
+	f, err := os.Open("x")
+	_ = f
+}
+`,
+			position: core.Position{Line: 5, Character: 2},
+			title:    "Add error check",
+			want: `	f, err := os.Open("x")
+	if err != nil {
+		panic(err)
+	}`,
+		},
+		{
+			name: "unwrap to must",
+			content: `package main
+
+import "os"
+
+func main() {
+	f, err := os.Open("x")
+	if err != nil {
+		panic(err)
+	}
+	_ = f
+}
+`,
+			position: core.Position{Line: 6, Character: 2},
+			title:    "Unwrap error check to must()",
+			want: `	f := must(os.Open("x"))
+	_ = f
+}
+
+// must panics if err is not nil and returns v otherwise.
+func must[T any](v T, err error) T {`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions := provider.ProvideCodeFixes(core.CodeFixContext{
+				URI:     "file:///" + strings.ReplaceAll(tt.name, " ", "_") + ".go",
+				Content: tt.content,
+				Range:   core.Range{Start: tt.position, End: tt.position},
+			})
+
+			var action *core.CodeAction
+			for i := range actions {
+				if actions[i].Title == tt.title {
+					action = &actions[i]
+				}
+			}
+			if action == nil {
+				t.Fatalf("no %q action in %v", tt.title, actions)
+			}
+			for _, edits := range action.Edit.Changes {
+				result := applyTextEdits(tt.content, edits)
+				if !strings.Contains(result, tt.want) {
+					t.Errorf("result does not contain\n%s\ngot:\n%s", tt.want, result)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorCheckProviderNoAction(t *testing.T) {
+	provider := &ErrorCheckProvider{}
+
+	tests := []struct {
+		name     string
+		content  string
+		position core.Position
+	}{
+		{
+			name: "already checked",
+			content: `package main
+
+func load() (int, error) { return 0, nil }
+
+func run() error {
+	n, err := load()
+	if err != nil {
+		return err
+	}
+	err = nil
+	_ = n
+	return err
+}
+`,
+			position: core.Position{Line: 5, Character: 2},
+		},
+		{
+			name: "no error",
+			content: `package main
+
+func count() int { return 0 }
+
+func run() {
+	count()
+}
+`,
+			position: core.Position{Line: 5, Character: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions := provider.ProvideCodeFixes(core.CodeFixContext{
+				URI:     "file:///" + strings.ReplaceAll(tt.name, " ", "_") + ".go",
+				Content: tt.content,
+				Range:   core.Range{Start: tt.position, End: tt.position},
+			})
+			if len(actions) != 0 {
+				t.Errorf("expected no actions, got %v", actions)
+			}
+		})
+	}
+}
+
+// applyTextEdits applies non-overlapping edits to content, last edit first
+// so earlier offsets stay valid.
+func applyTextEdits(content string, edits []core.TextEdit) string {
+	sorted := append([]core.TextEdit(nil), edits...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return core.ComparePositions(sorted[i].Range.Start, sorted[j].Range.Start) > 0
+	})
+	for _, edit := range sorted {
+		start := core.PositionToByteOffset(content, edit.Range.Start)
+		end := core.PositionToByteOffset(content, edit.Range.End)
+		content = content[:start] + edit.NewText + content[end:]
+	}
+	return content
+}
@@ -2,9 +2,9 @@ package examples
 
 import (
 	"go/ast"
-	"go/parser"
 	"go/token"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/SCKelemen/lsp/core"
@@ -18,8 +18,7 @@ func (p *GoFoldingProvider) ProvideFoldingRanges(uri, content string) []core.Fol
 		return nil
 	}
 
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	fset, f, err := parseGoFile(uri, content)
 	if err != nil {
 		return nil
 	}
@@ -48,16 +47,41 @@ func (p *GoFoldingProvider) getImportFolding(f *ast.File, fset *token.FileSet) *
 	first := fset.Position(f.Imports[0].Pos())
 	last := fset.Position(f.Imports[len(f.Imports)-1].End())
 
+	// A single import group folds from "import (" to ")", shown as
+	// "import (… 2 packages …)"
+	collapsedText := ""
+	if decl := importGroup(f); decl != nil {
+		first = fset.Position(decl.Lparen)
+		last = fset.Position(decl.Rparen)
+		collapsedText = "… " + pluralize(len(f.Imports), "package") + " …)"
+	}
+
 	if first.Line == last.Line {
 		return nil
 	}
 
 	kind := core.FoldingRangeKindImports
 	return &core.FoldingRange{
-		StartLine: first.Line - 1,
-		EndLine:   last.Line - 1,
-		Kind:      &kind,
+		StartLine:     first.Line - 1,
+		EndLine:       last.Line - 1,
+		Kind:          &kind,
+		CollapsedText: collapsedText,
+	}
+}
+
+// importGroup returns the import declaration of the file if it is the only
+// one and is parenthesized.
+func importGroup(f *ast.File) *ast.GenDecl {
+	var group *ast.GenDecl
+	for _, decl := range f.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+			if group != nil || !d.Lparen.IsValid() {
+				return nil
+			}
+			group = d
+		}
 	}
+	return group
 }
 
 func (p *GoFoldingProvider) getCommentFolding(f *ast.File, fset *token.FileSet) []core.FoldingRange {
@@ -107,11 +131,13 @@ func (p *GoFoldingProvider) getFunctionFolding(f *ast.File, fset *token.FileSet)
 			return true
 		}
 
+		// Shown as "func main() {… 12 lines …}"
 		startChar := start.Column - 1
 		ranges = append(ranges, core.FoldingRange{
 			StartLine:      start.Line - 1,
 			StartCharacter: &startChar,
 			EndLine:        end.Line - 1,
+			CollapsedText:  "{… " + pluralize(end.Line-start.Line-1, "line") + " …}",
 		})
 
 		return true
@@ -152,13 +178,26 @@ func (p *BraceFoldingProvider) ProvideFoldingRanges(uri, content string) []core.
 	return ranges
 }
 
-// IndentFoldingProvider provides indentation-based folding.
+// IndentFoldingProvider provides indentation-based folding, for languages
+// like Python, YAML, and HCL.
+//
+// A line followed by more indented lines folds up to the last of them.
+// Indentation is measured in columns, with tabs advancing to the next tab
+// stop, so tabs and spaces can be mixed. Blank lines do not end a block:
+// a block continues past them if a more indented line follows, and ends on
+// its last non-blank line.
 type IndentFoldingProvider struct {
+	// TabSize is the width of a tab stop in columns. If zero,
+	// DefaultTabSize is used.
 	TabSize int
 }
 
+// DefaultTabSize is the tab width IndentFoldingProvider uses unless
+// configured otherwise.
+const DefaultTabSize = 4
+
 func NewIndentFoldingProvider() *IndentFoldingProvider {
-	return &IndentFoldingProvider{TabSize: 4}
+	return &IndentFoldingProvider{TabSize: DefaultTabSize}
 }
 
 func (p *IndentFoldingProvider) ProvideFoldingRanges(uri, content string) []core.FoldingRange {
@@ -167,45 +206,39 @@ func (p *IndentFoldingProvider) ProvideFoldingRanges(uri, content string) []core
 	lines := strings.Split(content, "\n")
 	stack := []indentBlock{}
 
-	for lineNum, line := range lines {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-
-		indent := p.getIndentLevel(line)
-
-		// Pop blocks with greater or equal indentation
+	// closeBlocks ends the open blocks at least as indented as indent at
+	// the last non-blank line
+	lastLine := -1
+	closeBlocks := func(indent int) {
 		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
 			block := stack[len(stack)-1]
 			stack = stack[:len(stack)-1]
 
-			if lineNum-1 > block.startLine {
+			if lastLine > block.startLine {
 				ranges = append(ranges, core.FoldingRange{
 					StartLine: block.startLine,
-					EndLine:   lineNum - 1,
+					EndLine:   lastLine,
 				})
 			}
 		}
+	}
+
+	for lineNum, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
 
+		indent := p.indentWidth(line)
+		closeBlocks(indent)
 		stack = append(stack, indentBlock{
 			startLine: lineNum,
 			indent:    indent,
 		})
+		lastLine = lineNum
 	}
 
 	// Handle remaining blocks
-	endLine := len(lines) - 1
-	for len(stack) > 0 {
-		block := stack[len(stack)-1]
-		stack = stack[:len(stack)-1]
-
-		if endLine > block.startLine {
-			ranges = append(ranges, core.FoldingRange{
-				StartLine: block.startLine,
-				EndLine:   endLine,
-			})
-		}
-	}
+	closeBlocks(0)
 
 	return ranges
 }
@@ -215,24 +248,38 @@ type indentBlock struct {
 	indent    int
 }
 
-func (p *IndentFoldingProvider) getIndentLevel(line string) int {
-	spaces := 0
+// indentWidth returns the width of the indentation of a line in columns.
+func (p *IndentFoldingProvider) indentWidth(line string) int {
+	tabSize := p.TabSize
+	if tabSize <= 0 {
+		tabSize = DefaultTabSize
+	}
+
+	width := 0
 	for _, ch := range line {
 		if ch == ' ' {
-			spaces++
+			width++
 		} else if ch == '\t' {
-			spaces += p.TabSize
+			width += tabSize - width%tabSize
 		} else {
 			break
 		}
 	}
-	return spaces / p.TabSize
+	return width
 }
 
 // RegionFoldingProvider provides region-based folding.
+//
+// The text after the start marker names the region, like "Utilities" in
+// "// #region Utilities", and becomes the collapsed text of the range.
 type RegionFoldingProvider struct {
 	StartMarker string
 	EndMarker   string
+
+	// Languages, if set, provides the markers of each language; see
+	// core.Language.Regions. StartMarker and EndMarker apply to documents
+	// of languages without markers.
+	Languages *core.LanguageRegistry
 }
 
 func NewRegionFoldingProvider(startMarker, endMarker string) *RegionFoldingProvider {
@@ -243,27 +290,37 @@ func NewRegionFoldingProvider(startMarker, endMarker string) *RegionFoldingProvi
 }
 
 func (p *RegionFoldingProvider) ProvideFoldingRanges(uri, content string) []core.FoldingRange {
+	startMarker, endMarker := p.markers(uri)
+	if startMarker == "" || endMarker == "" {
+		return nil
+	}
+
 	var ranges []core.FoldingRange
 
 	lines := strings.Split(content, "\n")
-	stack := []int{}
+	type region struct {
+		line int
+		name string
+	}
+	stack := []region{}
 
 	kind := core.FoldingRangeKindRegion
 
 	for lineNum, line := range lines {
 		trimmed := strings.TrimSpace(line)
 
-		if strings.Contains(trimmed, p.StartMarker) {
-			stack = append(stack, lineNum)
-		} else if strings.Contains(trimmed, p.EndMarker) {
+		if i := strings.Index(trimmed, startMarker); i >= 0 {
+			stack = append(stack, region{line: lineNum, name: regionName(trimmed[i+len(startMarker):])})
+		} else if strings.Contains(trimmed, endMarker) {
 			if len(stack) > 0 {
-				startLine := stack[len(stack)-1]
+				start := stack[len(stack)-1]
 				stack = stack[:len(stack)-1]
 
 				ranges = append(ranges, core.FoldingRange{
-					StartLine: startLine,
-					EndLine:   lineNum,
-					Kind:      &kind,
+					StartLine:     start.line,
+					EndLine:       lineNum,
+					Kind:          &kind,
+					CollapsedText: start.name,
 				})
 			}
 		}
@@ -272,6 +329,144 @@ func (p *RegionFoldingProvider) ProvideFoldingRanges(uri, content string) []core
 	return ranges
 }
 
+// markers returns the region markers for the document at uri.
+func (p *RegionFoldingProvider) markers(uri string) (start, end string) {
+	if p.Languages != nil {
+		if language, ok := p.Languages.ForURI(uri); ok && language.Regions.Start != "" {
+			return language.Regions.Start, language.Regions.End
+		}
+	}
+	return p.StartMarker, p.EndMarker
+}
+
+// regionName returns the name of a region from the text after its start
+// marker, without the end of a block comment.
+func regionName(text string) string {
+	text = strings.TrimSpace(text)
+	for _, closer := range []string{"*/", "-->"} {
+		text = strings.TrimSpace(strings.TrimSuffix(text, closer))
+	}
+	return text
+}
+
+// DefaultHeaderCommentLines is the number of lines a comment at the top of
+// a file needs for HeaderCommentFoldingProvider to fold it, unless
+// configured otherwise.
+const DefaultHeaderCommentLines = 3
+
+// HeaderCommentFoldingProvider folds the comment block at the top of a
+// file, like a license header, in any language: a block comment in /* */
+// or <!-- -->, or consecutive line comments starting with //, #, --, or ;.
+// A shebang line before it is skipped.
+//
+// License and copyright headers are shown folded as their first line, and
+// with Collapse set are hinted to be folded when the file is opened.
+type HeaderCommentFoldingProvider struct {
+	// MinLines is the number of lines a header needs to be folded. If
+	// zero, DefaultHeaderCommentLines is used.
+	MinLines int
+
+	// Collapse hints that license headers start folded; see
+	// core.FoldingRange.Collapsed.
+	Collapse bool
+}
+
+func (p *HeaderCommentFoldingProvider) ProvideFoldingRanges(uri, content string) []core.FoldingRange {
+	lines := strings.Split(content, "\n")
+
+	start := 0
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "#!") {
+		start++
+	}
+	for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	if start == len(lines) {
+		return nil
+	}
+
+	end, ok := headerCommentEnd(lines, start)
+	minLines := p.MinLines
+	if minLines == 0 {
+		minLines = DefaultHeaderCommentLines
+	}
+	if !ok || end-start+1 < minLines {
+		return nil
+	}
+
+	kind := core.FoldingRangeKindComment
+	header := core.FoldingRange{
+		StartLine: start,
+		EndLine:   end,
+		Kind:      &kind,
+	}
+	text := strings.ToLower(strings.Join(lines[start:end+1], "\n"))
+	if strings.Contains(text, "copyright") || strings.Contains(text, "license") {
+		header.CollapsedText = commentLineText(lines, start, end)
+		header.Collapsed = p.Collapse
+	}
+	return []core.FoldingRange{header}
+}
+
+// headerCommentEnd returns the last line of the comment starting at line
+// start, if one does.
+func headerCommentEnd(lines []string, start int) (int, bool) {
+	first := strings.TrimSpace(lines[start])
+	for _, block := range [][2]string{{"/*", "*/"}, {"<!--", "-->"}} {
+		if !strings.HasPrefix(first, block[0]) {
+			continue
+		}
+		for i := start; i < len(lines); i++ {
+			text := lines[i]
+			if i == start {
+				text = strings.TrimSpace(text)[len(block[0]):]
+			}
+			if strings.Contains(text, block[1]) {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+
+	for _, prefix := range []string{"//", "#", "--", ";"} {
+		if !isLineComment(first, prefix) {
+			continue
+		}
+		end := start
+		for end+1 < len(lines) && isLineComment(strings.TrimSpace(lines[end+1]), prefix) {
+			end++
+		}
+		return end, true
+	}
+	return 0, false
+}
+
+// isLineComment reports whether a trimmed line is a comment starting with
+// prefix. The prefix must be followed by a space, the end of the line, or
+// more of its first character, so that lines like "#include" are code.
+func isLineComment(line, prefix string) bool {
+	if !strings.HasPrefix(line, prefix) {
+		return false
+	}
+	rest := line[len(prefix):]
+	return rest == "" || rest[0] == ' ' || rest[0] == '\t' || rest[0] == prefix[0]
+}
+
+// commentLineText returns the first line of a comment between lines start
+// and end with text, without comment markers, like "Copyright 2024 The
+// Authors".
+func commentLineText(lines []string, start, end int) string {
+	for _, line := range lines[start : end+1] {
+		text := strings.TrimSpace(line)
+		text = strings.TrimLeft(text, "/*#-;<!")
+		text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(text, "*/"), "-->"))
+		if text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
 // CompositeFoldingProvider combines multiple folding providers.
 type CompositeFoldingProvider struct {
 	providers []core.FoldingRangeProvider
@@ -317,8 +512,8 @@ func (p *CompositeFoldingProvider) deduplicateRanges(ranges []core.FoldingRange)
 
 		// Skip if identical (same start and end line)
 		if curr.StartLine == prev.StartLine && curr.EndLine == prev.EndLine {
-			// Prefer one with a kind
-			if curr.Kind != nil && prev.Kind == nil {
+			// Prefer one with a kind, then one with collapsed text
+			if curr.Kind != nil && prev.Kind == nil || (curr.Kind != nil) == (prev.Kind != nil) && curr.CollapsedText != "" && prev.CollapsedText == "" {
 				result[len(result)-1] = curr
 			}
 			continue
@@ -329,3 +524,11 @@ func (p *CompositeFoldingProvider) deduplicateRanges(ranges []core.FoldingRange)
 
 	return result
 }
+
+// pluralize returns "1 noun" or "n nouns".
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
@@ -1,6 +1,8 @@
 package examples
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 	"testing"
 
@@ -166,6 +168,46 @@ func main() {
 	}
 }
 
+// TestGoFoldingProvider_CollapsedText tests the text shown for folded ranges.
+func TestGoFoldingProvider_CollapsedText(t *testing.T) {
+	content := `package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	fmt.Println(strings.ToUpper("hello"))
+	os.Exit(0)
+}
+`
+
+	ranges := (&GoFoldingProvider{}).ProvideFoldingRanges("file:///collapsed.go", content)
+	if len(ranges) != 2 {
+		t.Fatalf("got %d ranges, want 2", len(ranges))
+	}
+
+	imports := ranges[0]
+	if imports.StartLine != 2 || imports.EndLine != 6 {
+		t.Errorf("imports fold: got lines %d-%d, want 2-6", imports.StartLine, imports.EndLine)
+	}
+	if imports.CollapsedText != "… 3 packages …)" {
+		t.Errorf("imports fold: got collapsed text %q", imports.CollapsedText)
+	}
+	if body := ranges[1]; body.CollapsedText != "{… 2 lines …}" {
+		t.Errorf("function fold: got collapsed text %q", body.CollapsedText)
+	}
+
+	// Imports outside a single group keep no text
+	content = "package main\n\nimport \"fmt\"\nimport \"os\"\n"
+	ranges = (&GoFoldingProvider{}).ProvideFoldingRanges("file:///collapsed_ungrouped.go", content)
+	if len(ranges) != 1 || ranges[0].CollapsedText != "" {
+		t.Errorf("expected one fold without collapsed text, got %+v", ranges)
+	}
+}
+
 // TestGoFoldingProvider_Unicode tests with Unicode content.
 func TestGoFoldingProvider_Unicode(t *testing.T) {
 	content := `package main
@@ -301,7 +343,7 @@ func TestIndentFoldingProvider(t *testing.T) {
     value: 1
   child2:
     value: 2`,
-			wantCount: 2, // Adjusted - algorithm may fold differently
+			wantCount: 3,
 		},
 		{
 			name: "No indentation",
@@ -327,6 +369,86 @@ line 3`,
 	}
 }
 
+// TestIndentFoldingProvider_Languages tests the ranges of indentation
+// folding in languages that rely on it.
+func TestIndentFoldingProvider_Languages(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		content string
+		tabSize int
+		want    string
+	}{
+		{
+			name: "YAML",
+			uri:  "file:///config.yaml",
+			content: `server:
+  port: 8080
+  tls:
+    cert: a.pem
+    key: a.key
+logging:
+  level: debug
+`,
+			want: "[0-4 2-4 5-6]",
+		},
+		{
+			name: "Python with blank lines in blocks",
+			uri:  "file:///main.py",
+			content: `class Greeter:
+    def greet(self):
+        name = "world"
+
+        return name
+
+
+    def wave(self):
+        pass
+
+
+def main():
+    Greeter().greet()
+`,
+			want: "[0-8 1-4 7-8 11-12]",
+		},
+		{
+			name: "HCL",
+			uri:  "file:///main.tf",
+			content: `resource "aws_instance" "web" {
+  ami = "ami-123"
+  tags = {
+    Name = "web"
+  }
+}
+`,
+			want: "[0-4 2-3]",
+		},
+		{
+			name:    "mixed tabs and spaces",
+			uri:     "file:///main.py",
+			content: "def f():\n\tif x:\n\t    y()\n        z()\n\treturn\n",
+			tabSize: 8,
+			want:    "[0-4 1-2]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &IndentFoldingProvider{TabSize: tt.tabSize}
+			ranges := provider.ProvideFoldingRanges(tt.uri, tt.content)
+			sort.Slice(ranges, func(i, j int) bool { return ranges[i].StartLine < ranges[j].StartLine })
+
+			var got []string
+			for _, r := range ranges {
+				got = append(got, fmt.Sprintf("%d-%d", r.StartLine, r.EndLine))
+			}
+			if fmt.Sprint(got) != tt.want {
+				t.Errorf("ranges = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
 // TestRegionFoldingProvider tests region-based folding.
 func TestRegionFoldingProvider(t *testing.T) {
 	tests := []struct {
@@ -415,6 +537,58 @@ code here
 	}
 }
 
+// TestRegionFoldingProviderNames tests region names and per-language markers.
+func TestRegionFoldingProviderNames(t *testing.T) {
+	provider := &RegionFoldingProvider{
+		StartMarker: "#region",
+		EndMarker:   "#endregion",
+		Languages: core.NewLanguageRegistry(
+			core.Language{ID: "go", Extensions: []string{".go"}, Regions: core.RegionMarkers{Start: "//region", End: "//endregion"}},
+			core.Language{ID: "css", Extensions: []string{".css"}, Regions: core.RegionMarkers{Start: "/* #region", End: "/* #endregion"}},
+		),
+	}
+
+	tests := []struct {
+		name      string
+		uri       string
+		content   string
+		wantNames []string
+	}{
+		{
+			name:      "fallback markers",
+			uri:       "file:///test.cs",
+			content:   "#region Utilities\ncode\n#endregion\n#region\ncode\n#endregion",
+			wantNames: []string{"Utilities", ""},
+		},
+		{
+			name:      "language markers",
+			uri:       "file:///main.go",
+			content:   "//region Helpers\nfunc helper() {}\n//endregion\n// #region Ignored\n// #endregion",
+			wantNames: []string{"Helpers"},
+		},
+		{
+			name:      "block comment",
+			uri:       "file:///style.css",
+			content:   "/* #region Buttons */\n.button {}\n/* #endregion */",
+			wantNames: []string{"Buttons"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges := provider.ProvideFoldingRanges(tt.uri, tt.content)
+			if len(ranges) != len(tt.wantNames) {
+				t.Fatalf("got %d ranges, want %d", len(ranges), len(tt.wantNames))
+			}
+			for i, r := range ranges {
+				if r.CollapsedText != tt.wantNames[i] {
+					t.Errorf("range %d: got collapsed text %q, want %q", i, r.CollapsedText, tt.wantNames[i])
+				}
+			}
+		})
+	}
+}
+
 // TestCompositeFoldingProvider tests combining multiple providers.
 func TestCompositeFoldingProvider(t *testing.T) {
 	content := `package main
@@ -502,6 +676,91 @@ func TestCompositeFoldingProvider_Deduplication(t *testing.T) {
 	}
 }
 
+// TestHeaderCommentFoldingProvider tests folding the comment at the top of
+// files.
+func TestHeaderCommentFoldingProvider(t *testing.T) {
+	tests := []struct {
+		name          string
+		uri           string
+		content       string
+		wantLines     string
+		wantCollapsed string
+	}{
+		{
+			name:          "Go license",
+			uri:           "file:///main.go",
+			content:       "// Copyright 2024 The Authors.\n// Use of this source code is governed by a BSD-style\n// license that can be found in the LICENSE file.\n\npackage main\n",
+			wantLines:     "0-2",
+			wantCollapsed: "Copyright 2024 The Authors.",
+		},
+		{
+			name:          "block comment after a shebang",
+			uri:           "file:///run.js",
+			content:       "#!/usr/bin/env node\n/*\n * Copyright (c) Example\n * MIT License\n */\nconsole.log(1)\n",
+			wantLines:     "1-4",
+			wantCollapsed: "Copyright (c) Example",
+		},
+		{
+			name:      "other header comment",
+			uri:       "file:///tool.py",
+			content:   "# Tool does things.\n#\n# Usage: tool [flags]\nimport os\n",
+			wantLines: "0-2",
+		},
+		{
+			name:    "short header",
+			uri:     "file:///main.go",
+			content: "// Package main runs.\npackage main\n",
+		},
+		{
+			name:    "preprocessor directives are code",
+			uri:     "file:///main.c",
+			content: "#include <stdio.h>\n#include <stdlib.h>\n#include <string.h>\n",
+		},
+		{
+			name:    "unterminated block comment",
+			uri:     "file:///main.css",
+			content: "/* Copyright\n license\n more\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &HeaderCommentFoldingProvider{Collapse: true}
+			ranges := provider.ProvideFoldingRanges(tt.uri, tt.content)
+			if tt.wantLines == "" {
+				if len(ranges) != 0 {
+					t.Errorf("got ranges %+v, want none", ranges)
+				}
+				return
+			}
+			if len(ranges) != 1 {
+				t.Fatalf("got %d ranges, want 1", len(ranges))
+			}
+			r := ranges[0]
+			if got := fmt.Sprintf("%d-%d", r.StartLine, r.EndLine); got != tt.wantLines {
+				t.Errorf("lines = %s, want %s", got, tt.wantLines)
+			}
+			if r.Kind == nil || *r.Kind != core.FoldingRangeKindComment {
+				t.Errorf("kind = %v, want comment", r.Kind)
+			}
+			if r.CollapsedText != tt.wantCollapsed {
+				t.Errorf("collapsed text = %q, want %q", r.CollapsedText, tt.wantCollapsed)
+			}
+			if r.Collapsed != (tt.wantCollapsed != "") {
+				t.Errorf("collapsed = %v, want it for license headers only", r.Collapsed)
+			}
+		})
+	}
+
+	// The header wins over the same comment folded by the Go provider
+	content := tests[0].content
+	composite := NewCompositeFoldingProvider(&GoFoldingProvider{}, &HeaderCommentFoldingProvider{})
+	ranges := composite.ProvideFoldingRanges("file:///main.go", content)
+	if len(ranges) != 1 || ranges[0].CollapsedText == "" {
+		t.Errorf("composite ranges = %+v, want the license header", ranges)
+	}
+}
+
 // TestFoldingEdgeCases tests edge cases.
 func TestFoldingEdgeCases(t *testing.T) {
 	tests := []struct {
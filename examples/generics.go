@@ -0,0 +1,102 @@
+package examples
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// DefaultMaxConstraintTerms is the number of terms of a union constraint
+// a TypeParamRenderer shows unless configured otherwise.
+const DefaultMaxConstraintTerms = 4
+
+// TypeParamRenderer renders the type parameters of generic Go functions
+// and types, like "[K comparable, V any]", in hovers, signature help, and
+// symbol details.
+//
+// Long union constraints, like those listing every integer type, are
+// abbreviated after MaxConstraintTerms terms:
+//
+//	[T ~int | ~int8 | ~int16 | ~int32 | …]
+type TypeParamRenderer struct {
+	// MaxConstraintTerms is the number of terms of a union shown before
+	// the rest are abbreviated. If zero, DefaultMaxConstraintTerms is
+	// used; if negative, all terms are shown.
+	MaxConstraintTerms int
+}
+
+// TypeParams renders a type parameter list, or returns "" if list is nil,
+// rendering types with typeString.
+func (r TypeParamRenderer) TypeParams(list *ast.FieldList, typeString func(ast.Expr) string) string {
+	if list == nil || len(list.List) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, field := range list.List {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		for j, name := range field.Names {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(name.Name)
+		}
+		b.WriteByte(' ')
+		b.WriteString(r.Constraint(field.Type, typeString))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// Constraint renders a type constraint: a type, an approximation like
+// ~int, a union, or an interface made of one of them.
+func (r TypeParamRenderer) Constraint(expr ast.Expr, typeString func(ast.Expr) string) string {
+	if it, ok := expr.(*ast.InterfaceType); ok && it.Methods != nil && len(it.Methods.List) == 1 && len(it.Methods.List[0].Names) == 0 {
+		return "interface{" + r.Constraint(it.Methods.List[0].Type, typeString) + "}"
+	}
+
+	var terms []string
+	for _, term := range unionTerms(expr) {
+		if tilde, ok := term.(*ast.UnaryExpr); ok && tilde.Op == token.TILDE {
+			terms = append(terms, "~"+typeString(tilde.X))
+		} else {
+			terms = append(terms, typeString(term))
+		}
+	}
+	limit := r.MaxConstraintTerms
+	if limit == 0 {
+		limit = DefaultMaxConstraintTerms
+	}
+	if limit > 0 && len(terms) > limit {
+		terms = append(terms[:limit], "…")
+	}
+	return strings.Join(terms, " | ")
+}
+
+// unionTerms returns the terms of a union like ~int | ~string, or expr
+// alone if it is not a union.
+func unionTerms(expr ast.Expr) []ast.Expr {
+	if union, ok := expr.(*ast.BinaryExpr); ok && union.Op == token.OR {
+		return append(unionTerms(union.X), unionTerms(union.Y)...)
+	}
+	return []ast.Expr{expr}
+}
+
+// instantiatedTypeString renders an instantiated generic type, like
+// Map[string, int], from the expressions of an *ast.IndexExpr or an
+// *ast.IndexListExpr.
+func instantiatedTypeString(generic ast.Expr, args []ast.Expr, typeString func(ast.Expr) string) string {
+	var b strings.Builder
+	b.WriteString(typeString(generic))
+	b.WriteByte('[')
+	for i, arg := range args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(typeString(arg))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
@@ -0,0 +1,115 @@
+package examples
+
+import (
+	"go/ast"
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+const genericsSource = `package main
+
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+// Map maps keys to values.
+type Map[K comparable, V any] map[K]V
+
+// Sum adds the numbers.
+func Sum[N ~int | ~int8 | ~int16 | ~int32 | ~int64 | ~float64](numbers ...N) N {
+	var total N
+	return total
+}
+
+// Keys returns the keys of m.
+func Keys[K comparable, V any](m Map[K, V]) []K { return nil }
+
+var counts Map[string, int]
+
+func main() {
+	_ = Keys(counts)
+	_ = Sum(1, 2)
+}
+`
+
+func TestTypeParamRenderer(t *testing.T) {
+	_, f, err := parseGoFile("file:///generics.go", genericsSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum *ast.FuncDecl
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.Name == "Sum" {
+			sum = fn
+		}
+	}
+	typeString := (&GoSymbolProvider{}).exprToString
+
+	tests := []struct {
+		max  int
+		want string
+	}{
+		{0, "[N ~int | ~int8 | ~int16 | ~int32 | …]"},
+		{2, "[N ~int | ~int8 | …]"},
+		{-1, "[N ~int | ~int8 | ~int16 | ~int32 | ~int64 | ~float64]"},
+	}
+	for _, tt := range tests {
+		renderer := TypeParamRenderer{MaxConstraintTerms: tt.max}
+		if got := renderer.TypeParams(sum.Type.TypeParams, typeString); got != tt.want {
+			t.Errorf("MaxConstraintTerms %d: TypeParams = %q, want %q", tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestGenericsRendering(t *testing.T) {
+	uri := "file:///generics.go"
+	at := func(marker string) core.Position {
+		return core.ByteOffsetToPosition(genericsSource, strings.Index(genericsSource, marker))
+	}
+
+	hover := func(marker string) string {
+		t.Helper()
+		info := (&SimpleHoverProvider{}).ProvideHover(uri, genericsSource, at(marker))
+		if info == nil {
+			t.Fatalf("no hover on %s", marker)
+		}
+		return info.Contents
+	}
+	if got := hover("Keys(counts)"); !strings.Contains(got, "func Keys[K comparable, V any](m Map[K, V]) []K") {
+		t.Errorf("hover on Keys =\n%s", got)
+	}
+	if got := hover("counts)"); !strings.Contains(got, "var counts Map[string, int]") {
+		t.Errorf("hover on counts =\n%s", got)
+	}
+	if got := hover("Map[K, V]"); !strings.Contains(got, "type Map[K comparable, V any] map[K]V") {
+		t.Errorf("hover on Map =\n%s", got)
+	}
+
+	help := (&GoSignatureHelpProvider{TypeParams: TypeParamRenderer{MaxConstraintTerms: 1}}).ProvideSignatureHelp(core.SignatureHelpContext{
+		URI:      uri,
+		Content:  genericsSource,
+		Position: at("2)"),
+	})
+	if help == nil {
+		t.Fatal("no signature help in Sum(1, 2)")
+	}
+	if got, want := help.Signatures[0].Label, "Sum[N ~int | …](numbers ...N) N"; got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+
+	details := make(map[string]string)
+	for _, symbol := range (&GoSymbolProvider{}).ProvideDocumentSymbols(uri, genericsSource) {
+		details[symbol.Name] = symbol.Detail
+	}
+	for name, want := range map[string]string{
+		"Map":    "[K comparable, V any] map[K]V",
+		"Keys":   "Keys[K comparable, V any](m Map[K, V]) []K",
+		"counts": "Map[string, int]",
+	} {
+		if details[name] != want {
+			t.Errorf("detail of %s = %q, want %q", name, details[name], want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package examples
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sync"
+
+	"github.com/SCKelemen/lsp/cache"
+)
+
+// GoASTCache shares parse results between providers.
+// Hover, symbols, folding, and the other Go providers in this package all
+// parse the same document; with a shared cache a burst of requests against
+// one document version parses it only once.
+//
+// Cached ASTs are shared and must be treated as read-only.
+type GoASTCache struct {
+	mu         sync.Mutex
+	entries    map[string]*goASTCacheEntry
+	maxEntries int
+	clock      uint64
+	hits       uint64
+	misses     uint64
+	manager    *cache.Manager
+}
+
+// GoASTCacheName is the name a GoASTCache registers under with a cache.Manager.
+const GoASTCacheName = "go-ast"
+
+// goASTSizeFactor estimates the memory of a parsed file from its source
+// size. An AST with comments takes roughly 20 times the source.
+const goASTSizeFactor = 20
+
+// goASTCacheEntry is the parse result for one document.
+type goASTCacheEntry struct {
+	content string
+	fset    *token.FileSet
+	file    *ast.File
+	err     error
+	used    uint64
+}
+
+// DefaultGoASTCache is the cache used by the Go providers in this package.
+var DefaultGoASTCache = NewGoASTCache(64)
+
+// NewGoASTCache creates a cache holding at most maxEntries documents.
+// The least recently used document is evicted when the cache is full.
+func NewGoASTCache(maxEntries int) *GoASTCache {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	return &GoASTCache{
+		entries:    make(map[string]*goASTCacheEntry),
+		maxEntries: maxEntries,
+	}
+}
+
+// UseManager puts the cache under the memory budget of m. Entries still
+// count against maxEntries, and are also evicted when m needs the memory.
+func (c *GoASTCache) UseManager(m *cache.Manager) {
+	c.mu.Lock()
+	c.manager = m
+	c.mu.Unlock()
+	m.Register(GoASTCacheName, c)
+}
+
+// Parse returns the parsed file for uri, reparsing only if content changed
+// since the last call. Files are parsed with parser.ParseComments.
+// Like parser.ParseFile, a partial AST may be returned together with an error.
+//
+// Each miss parses into its own token.FileSet. A FileSet cannot be reset, and
+// positions in an AST only resolve while its file is still in the set; since
+// providers may hold a cached AST after its entry has been replaced, sets are
+// not recycled. Sharing the parse result is what removes the per-request
+// FileSet and parse; the source bytes handed to the parser come from a pool.
+func (c *GoASTCache) Parse(uri, content string) (*token.FileSet, *ast.File, error) {
+	c.mu.Lock()
+	manager := c.manager
+	if entry, ok := c.entries[uri]; ok && entry.content == content {
+		c.clock++
+		entry.used = c.clock
+		c.hits++
+		c.mu.Unlock()
+		if manager != nil {
+			manager.Hit(GoASTCacheName, uri)
+		}
+		return entry.fset, entry.file, entry.err
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	// Parse without holding the lock so other documents are not blocked
+	fset := token.NewFileSet()
+	src := borrowSource(content)
+	f, err := parser.ParseFile(fset, "", *src, parser.ParseComments)
+	returnSource(src)
+
+	c.mu.Lock()
+	var (
+		evicted    string
+		hasEvicted bool
+	)
+	if _, ok := c.entries[uri]; !ok && len(c.entries) >= c.maxEntries {
+		evicted, hasEvicted = c.evictOldest()
+	}
+	c.clock++
+	c.entries[uri] = &goASTCacheEntry{
+		content: content,
+		fset:    fset,
+		file:    f,
+		err:     err,
+		used:    c.clock,
+	}
+	c.mu.Unlock()
+
+	// The manager may call Evict, so it is only called without the lock held
+	if manager != nil {
+		if hasEvicted {
+			manager.Remove(GoASTCacheName, evicted)
+		}
+		manager.Add(GoASTCacheName, uri, int64(len(content))*goASTSizeFactor)
+	}
+
+	return fset, f, err
+}
+
+// Forget drops the cached parse result for uri, e.g. when the document is closed.
+func (c *GoASTCache) Forget(uri string) {
+	c.mu.Lock()
+	delete(c.entries, uri)
+	manager := c.manager
+	c.mu.Unlock()
+
+	if manager != nil {
+		manager.Remove(GoASTCacheName, uri)
+	}
+}
+
+// Evict implements cache.Store.
+func (c *GoASTCache) Evict(uri string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, uri)
+}
+
+// Stats returns the number of cache hits and misses so far.
+func (c *GoASTCache) Stats() (hits, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// evictOldest drops the least recently used entry and returns its URI.
+// found is false if the cache was empty.
+func (c *GoASTCache) evictOldest() (uri string, found bool) {
+	var oldest uint64
+	for candidate, entry := range c.entries {
+		if !found || entry.used < oldest {
+			uri = candidate
+			oldest = entry.used
+			found = true
+		}
+	}
+	if found {
+		delete(c.entries, uri)
+	}
+	return uri, found
+}
+
+// parseGoFile parses a Go document through DefaultGoASTCache.
+func parseGoFile(uri, content string) (*token.FileSet, *ast.File, error) {
+	return DefaultGoASTCache.Parse(uri, content)
+}
+
+// scanFileSet is shared by the token scans in this package.
+// Scans only need a token.File for the duration of the scan and do not hand
+// out positions, so each file is removed again as soon as the scan is done.
+var scanFileSet = token.NewFileSet()
+
+// addScanFile registers a file of the given size in scanFileSet.
+// Callers must pass it to removeScanFile when they are done.
+func addScanFile(size int) *token.File {
+	return scanFileSet.AddFile("", -1, size)
+}
+
+// removeScanFile removes a file added by addScanFile.
+func removeScanFile(file *token.File) {
+	scanFileSet.RemoveFile(file)
+}
+
+// goSourceBuffers recycles the byte slices handed to go/parser and go/scanner.
+// Passing a string makes the parser allocate a copy of the whole document on
+// every call; both packages copy everything they keep (identifiers, literals,
+// comments), so a buffer can be reused as soon as they return.
+var goSourceBuffers = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 16*1024)
+		return &buf
+	},
+}
+
+// borrowSource copies content into a pooled buffer.
+func borrowSource(content string) *[]byte {
+	buf := goSourceBuffers.Get().(*[]byte)
+	*buf = append((*buf)[:0], content...)
+	return buf
+}
+
+// returnSource hands a buffer back to the pool.
+// Very large buffers are dropped so one huge file does not pin memory.
+func returnSource(buf *[]byte) {
+	if cap(*buf) > 1<<20 {
+		return
+	}
+	goSourceBuffers.Put(buf)
+}
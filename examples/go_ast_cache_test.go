@@ -0,0 +1,208 @@
+package examples
+
+import (
+	"testing"
+
+	"github.com/SCKelemen/lsp/cache"
+	"github.com/SCKelemen/lsp/core"
+)
+
+func TestGoASTCache_SharesParseResults(t *testing.T) {
+	cache := NewGoASTCache(4)
+	content := "package main\n\nfunc main() {}\n"
+
+	_, first, err := cache.Parse("file:///a.go", content)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	_, second, _ := cache.Parse("file:///a.go", content)
+	if first != second {
+		t.Error("expected the same AST for unchanged content")
+	}
+
+	_, third, _ := cache.Parse("file:///a.go", content+"\nfunc other() {}\n")
+	if third == first {
+		t.Error("expected a new AST after the content changed")
+	}
+
+	hits, misses := cache.Stats()
+	if hits != 1 || misses != 2 {
+		t.Errorf("got hits=%d misses=%d, want 1 and 2", hits, misses)
+	}
+}
+
+func TestGoASTCache_ReturnsPartialASTWithError(t *testing.T) {
+	cache := NewGoASTCache(4)
+
+	_, f, err := cache.Parse("file:///broken.go", "package main\n\nfunc main() {\n\tx :=\n}\n")
+	if err == nil {
+		t.Fatal("expected a parse error")
+	}
+	if f == nil {
+		t.Fatal("expected a partial AST")
+	}
+
+	// The error is cached along with the AST
+	_, _, err = cache.Parse("file:///broken.go", "package main\n\nfunc main() {\n\tx :=\n}\n")
+	if err == nil {
+		t.Error("expected the cached parse error")
+	}
+}
+
+func TestGoASTCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewGoASTCache(2)
+	content := "package main\n"
+
+	_, a, _ := cache.Parse("file:///a.go", content)
+	cache.Parse("file:///b.go", content)
+	cache.Parse("file:///a.go", content) // a is now more recent than b
+	cache.Parse("file:///c.go", content) // evicts b
+
+	if _, again, _ := cache.Parse("file:///a.go", content); again != a {
+		t.Error("expected a.go to stay cached")
+	}
+	_, missesBefore := cache.Stats()
+	cache.Parse("file:///b.go", content)
+	if _, misses := cache.Stats(); misses != missesBefore+1 {
+		t.Error("expected b.go to have been evicted")
+	}
+}
+
+func TestGoASTCache_EvictsEmptyURI(t *testing.T) {
+	content := "package main\n"
+
+	// Map iteration order is random; repeat so both orders are exercised
+	for i := 0; i < 20; i++ {
+		cache := NewGoASTCache(2)
+		cache.Parse("", content) // oldest, evicted below
+		_, b, _ := cache.Parse("file:///b.go", content)
+		cache.Parse("file:///c.go", content)
+
+		if _, again, _ := cache.Parse("file:///b.go", content); again != b {
+			t.Fatal("expected the empty URI, not b.go, to be evicted")
+		}
+	}
+}
+
+func TestGoASTCache_Forget(t *testing.T) {
+	cache := NewGoASTCache(2)
+	content := "package main\n"
+
+	_, first, _ := cache.Parse("file:///a.go", content)
+	cache.Forget("file:///a.go")
+	if _, second, _ := cache.Parse("file:///a.go", content); second == first {
+		t.Error("expected a fresh parse after Forget")
+	}
+}
+
+// The benchmarks below compare a cold parse per request (the cache is
+// cleared each iteration) against requests served from the shared AST.
+// Run with -benchmem to see the allocation difference.
+// Document highlights do not parse, so BenchmarkHighlight has no cold case.
+
+func BenchmarkHover(b *testing.B) {
+	content := benchmarkGoSource(200)
+	uri := "file:///bench_hover.go"
+	pos := core.Position{Line: 3, Character: 6}
+	provider := &SimpleHoverProvider{}
+
+	b.Run("cold", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			DefaultGoASTCache.Forget(uri)
+			provider.ProvideHover(uri, content, pos)
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			provider.ProvideHover(uri, content, pos)
+		}
+	})
+}
+
+func BenchmarkDocumentSymbols(b *testing.B) {
+	content := benchmarkGoSource(200)
+	uri := "file:///bench_symbols.go"
+	provider := &GoSymbolProvider{}
+
+	b.Run("cold", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			DefaultGoASTCache.Forget(uri)
+			provider.ProvideDocumentSymbols(uri, content)
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			provider.ProvideDocumentSymbols(uri, content)
+		}
+	})
+}
+
+func BenchmarkSignatureHelp(b *testing.B) {
+	content := benchmarkGoSource(200) + "func main() {\n\tFunc1(1, \n}\n"
+	uri := "file:///bench_signature.go"
+	ctx := core.SignatureHelpContext{
+		URI:      uri,
+		Content:  content,
+		Position: core.ByteOffsetToPosition(content, len(content)-3),
+	}
+	provider := &GoSignatureHelpProvider{}
+
+	b.Run("cold", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			DefaultGoASTCache.Forget(uri)
+			provider.ProvideSignatureHelp(ctx)
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			provider.ProvideSignatureHelp(ctx)
+		}
+	})
+}
+
+func BenchmarkHighlight(b *testing.B) {
+	content := benchmarkGoSource(20)
+	ctx := core.DocumentHighlightContext{
+		URI:      "file:///bench_highlight.go",
+		Content:  content,
+		Position: core.Position{Line: 3, Character: 1}, // On "x"
+	}
+	provider := &SimpleHighlightProvider{}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		provider.ProvideDocumentHighlights(ctx)
+	}
+}
+
+func TestGoASTCache_UseManager(t *testing.T) {
+	content := "package main\n\nfunc main() {}\n"
+	manager := cache.NewManager(int64(len(content)) * goASTSizeFactor * 2)
+	c := NewGoASTCache(10)
+	c.UseManager(manager)
+
+	manager.DidOpen("file:///open.go")
+	_, open, _ := c.Parse("file:///open.go", content)
+	c.Parse("file:///closed.go", content)
+	c.Parse("file:///other.go", content) // over budget: closed.go goes first
+
+	if _, again, _ := c.Parse("file:///open.go", content); again != open {
+		t.Error("expected the open document to stay cached")
+	}
+	_, missesBefore := c.Stats()
+	c.Parse("file:///closed.go", content)
+	if _, misses := c.Stats(); misses != missesBefore+1 {
+		t.Error("expected the closed document to have been evicted")
+	}
+
+	stats := manager.Stats()
+	if len(stats) != 1 || stats[0].Name != GoASTCacheName || stats[0].Evictions == 0 {
+		t.Errorf("unexpected manager stats %+v", stats)
+	}
+}
@@ -0,0 +1,225 @@
+package examples
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// typeCheckGoFile type-checks a single Go file and returns the type
+// information it could resolve. Imports are not loaded, so objects of other
+// packages have invalid types, while everything declared in the file, and
+// the universe (error, int, ...), is typed. Type errors are ignored.
+func typeCheckGoFile(fset *token.FileSet, f *ast.File) *types.Info {
+	info, _, _ := typeCheckGoFileErrors(fset, f)
+	return info
+}
+
+// typeCheckGoFileErrors is typeCheckGoFile also returning the checked
+// package and the type errors, except those caused by unloaded imports.
+func typeCheckGoFileErrors(fset *token.FileSet, f *ast.File) (*types.Info, *types.Package, []types.Error) {
+	return typeCheckGoFileSizes(fset, f, nil)
+}
+
+// typeCheckGoFileSizes is typeCheckGoFileErrors with the sizes of the types
+// of a platform, which decide the value of unsafe.Sizeof and whether
+// constants overflow int. Nil sizes are those of the gc compiler on amd64.
+func typeCheckGoFileSizes(fset *token.FileSet, f *ast.File, sizes types.Sizes) (*types.Info, *types.Package, []types.Error) {
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	var errs []types.Error
+	conf := types.Config{
+		Importer: noImporter{},
+		Sizes:    sizes,
+		// Keep checking past the first error
+		Error: func(err error) {
+			if typeErr, ok := err.(types.Error); ok && !strings.Contains(typeErr.Msg, "could not import") {
+				errs = append(errs, typeErr)
+			}
+		},
+	}
+	pkg, _ := conf.Check(f.Name.Name, fset, []*ast.File{f}, info)
+	return info, pkg, errs
+}
+
+// goMember is a field or method of a type, possibly promoted from an
+// embedded field.
+type goMember struct {
+	// Object is a *types.Var for a field, and a *types.Func for a method.
+	Object types.Object
+
+	// Path names the embedded fields the member is promoted through, from
+	// the outermost; it is empty for members of the type itself.
+	Path []string
+}
+
+// goMembers returns the fields and methods selectable on a value of type
+// t, including those promoted from embedded fields, transitively. As in
+// Go, a member at a shallower depth hides those of the same name deeper,
+// and members of the same name at the same depth are ambiguous and left
+// out. Methods are those of both T and *T, as for an addressable value.
+// Members are ordered by depth, then as declared.
+func goMembers(t types.Type) []goMember {
+	type entry struct {
+		t    types.Type
+		path []string
+	}
+	var members []goMember
+	hidden := make(map[string]bool)        // names found at shallower depths
+	visited := make(map[*types.Named]bool) // at shallower depths
+
+	level := []entry{{t: t}}
+	for len(level) > 0 {
+		var found []goMember
+		count := make(map[string]int)
+		add := func(obj types.Object, path []string) {
+			if hidden[obj.Name()] || obj.Name() == "_" {
+				return
+			}
+			count[obj.Name()]++
+			found = append(found, goMember{Object: obj, Path: path})
+		}
+
+		var next []entry
+		var named []*types.Named
+		for _, e := range level {
+			typ := e.t
+			if ptr, ok := typ.(*types.Pointer); ok {
+				typ = ptr.Elem()
+			}
+			// A type embedded twice at the same depth makes its members
+			// ambiguous, while one embedded deeper again is hidden
+			if n, ok := typ.(*types.Named); ok {
+				if visited[n] {
+					continue
+				}
+				named = append(named, n)
+				for i := 0; i < n.NumMethods(); i++ {
+					add(n.Method(i), e.path)
+				}
+			}
+			switch u := typ.Underlying().(type) {
+			case *types.Struct:
+				for i := 0; i < u.NumFields(); i++ {
+					field := u.Field(i)
+					add(field, e.path)
+					if field.Embedded() {
+						path := append(append([]string(nil), e.path...), field.Name())
+						next = append(next, entry{t: field.Type(), path: path})
+					}
+				}
+			case *types.Interface:
+				for i := 0; i < u.NumMethods(); i++ {
+					add(u.Method(i), e.path)
+				}
+			}
+		}
+
+		for _, member := range found {
+			if count[member.Object.Name()] == 1 {
+				members = append(members, member)
+			}
+		}
+		for name := range count {
+			hidden[name] = true
+		}
+		for _, n := range named {
+			visited[n] = true
+		}
+		level = next
+	}
+	return members
+}
+
+// selectionPath returns the names of the embedded fields a selection goes
+// through, from the outermost, or nil if the selected member is not
+// promoted.
+func selectionPath(selection *types.Selection) []string {
+	index := selection.Index()
+	var path []string
+	t := selection.Recv()
+	for _, i := range index[:len(index)-1] {
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		st, ok := t.Underlying().(*types.Struct)
+		if !ok || i >= st.NumFields() {
+			return nil
+		}
+		field := st.Field(i)
+		path = append(path, field.Name())
+		t = field.Type()
+	}
+	return path
+}
+
+// noImporter fails every import.
+type noImporter struct{}
+
+func (noImporter) Import(path string) (*types.Package, error) {
+	return nil, fmt.Errorf("imports are not loaded: %s", path)
+}
+
+// isErrorType reports whether t is the predeclared error type.
+func isErrorType(t types.Type) bool {
+	return t != nil && types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+// zeroValue returns Go source for the zero value of the type written as
+// expr, using its type t if known. Types that cannot be resolved, such as
+// imported ones, get *new(T), which is valid for any type.
+func zeroValue(expr ast.Expr, t types.Type) string {
+	text := types.ExprString(expr)
+	if t == nil || t == types.Typ[types.Invalid] {
+		return zeroValueOfSyntax(expr, text)
+	}
+	if _, ok := t.(*types.TypeParam); ok {
+		return "*new(" + text + ")"
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return "false"
+		case u.Info()&types.IsString != 0:
+			return `""`
+		case u.Info()&types.IsNumeric != 0:
+			return "0"
+		case u.Kind() == types.Invalid:
+			return zeroValueOfSyntax(expr, text)
+		}
+		return "nil"
+	case *types.Pointer, *types.Slice, *types.Map, *types.Chan, *types.Signature, *types.Interface:
+		return "nil"
+	case *types.Struct, *types.Array:
+		return text + "{}"
+	}
+	return "*new(" + text + ")"
+}
+
+// zeroValueOfSyntax returns the zero value of a type known only by syntax.
+func zeroValueOfSyntax(expr ast.Expr, text string) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.InterfaceType:
+		return "nil"
+	case *ast.ArrayType:
+		if e.Len == nil {
+			return "nil"
+		}
+		return text + "{}"
+	case *ast.StructType:
+		return text + "{}"
+	case *ast.Ident:
+		if e.Name == "error" {
+			return "nil"
+		}
+	}
+	return "*new(" + text + ")"
+}
@@ -0,0 +1,149 @@
+package examples
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/gomod"
+)
+
+// GoModProvider provides hover and code actions for the requirements of
+// go.mod files. Hovering a required module shows its latest version, and a
+// code action updates the require line to it.
+//
+// Latest versions come from Versions, so the network access of a module
+// proxy stays in the resolver the server chooses. Without one, versions
+// are looked up offline in the module cache.
+type GoModProvider struct {
+	// Versions resolves the latest versions of modules. If nil, it is the
+	// module cache of the environment.
+	Versions gomod.VersionResolver
+}
+
+// goModRequirement is a require directive of a go.mod file, with the
+// ranges of its fields.
+type goModRequirement struct {
+	path, version           string
+	pathRange, versionRange core.Range
+}
+
+func (p *GoModProvider) versions() gomod.VersionResolver {
+	if p.Versions == nil {
+		return gomod.NewModCacheVersions()
+	}
+	return p.Versions
+}
+
+func (p *GoModProvider) ProvideHover(uri, content string, position core.Position) *core.HoverInfo {
+	if path.Base(uri) != "go.mod" {
+		return nil
+	}
+	for _, requirement := range goModRequirements(content) {
+		hoverRange := core.Range{Start: requirement.pathRange.Start, End: requirement.versionRange.End}
+		if !hoverRange.Contains(position) {
+			continue
+		}
+
+		var status string
+		latest, err := p.versions().LatestVersion(requirement.path)
+		switch {
+		case err != nil:
+			status = "Latest version unknown"
+		case gomod.CompareVersions(latest, requirement.version) > 0:
+			status = fmt.Sprintf("Latest version: `%s`", latest)
+		default:
+			status = "Up to date"
+		}
+		return &core.HoverInfo{
+			Contents: fmt.Sprintf("**%s** `%s`\n\n%s", requirement.path, requirement.version, status),
+			Range:    &hoverRange,
+		}
+	}
+	return nil
+}
+
+// ProvideCodeFixes offers to update the requirements on the lines of
+// ctx.Range that are behind their latest version.
+func (p *GoModProvider) ProvideCodeFixes(ctx core.CodeFixContext) []core.CodeAction {
+	if path.Base(ctx.URI) != "go.mod" || !codeActionKindRequested(ctx.Only, core.CodeActionKindRefactorRewrite) {
+		return nil
+	}
+
+	var actions []core.CodeAction
+	for _, requirement := range goModRequirements(ctx.Content) {
+		line := requirement.pathRange.Start.Line
+		if line < ctx.Range.Start.Line || line > ctx.Range.End.Line {
+			continue
+		}
+		latest, err := p.versions().LatestVersion(requirement.path)
+		if err != nil || gomod.CompareVersions(latest, requirement.version) <= 0 {
+			continue
+		}
+		kind := core.CodeActionKindRefactorRewrite
+		actions = append(actions, core.CodeAction{
+			Title: fmt.Sprintf("Update %s to %s", requirement.path, latest),
+			Kind:  &kind,
+			Edit: &core.WorkspaceEdit{
+				Changes: map[string][]core.TextEdit{
+					ctx.URI: {{Range: requirement.versionRange, NewText: latest}},
+				},
+			},
+		})
+	}
+	return actions
+}
+
+// goModRequirements returns the require directives of a go.mod file, in
+// blocks and on their own lines.
+func goModRequirements(content string) []goModRequirement {
+	var requirements []goModRequirement
+	inBlock := false
+	for number, line := range strings.Split(content, "\n") {
+		code, _, _ := strings.Cut(line, "//")
+		fields := strings.Fields(code)
+		searchFrom := 0
+		switch {
+		case len(fields) == 0:
+			continue
+		case inBlock && fields[0] == ")":
+			inBlock = false
+			continue
+		case !inBlock && len(fields) == 2 && fields[0] == "require" && fields[1] == "(":
+			inBlock = true
+			continue
+		case !inBlock && fields[0] == "require":
+			fields = fields[1:]
+			searchFrom = strings.Index(code, "require") + len("require")
+		case !inBlock:
+			continue
+		}
+		if len(fields) != 2 {
+			continue
+		}
+
+		// The fields are found in order, so the version is found after the
+		// path even if the path contains it
+		pathStart := searchFrom + strings.Index(code[searchFrom:], fields[0])
+		versionStart := pathStart + len(fields[0]) + strings.Index(code[pathStart+len(fields[0]):], fields[1])
+		fieldRange := func(start, length int) core.Range {
+			return core.Range{
+				Start: core.Position{Line: number, Character: start},
+				End:   core.Position{Line: number, Character: start + length},
+			}
+		}
+		modulePath := fields[0]
+		if unquoted, err := strconv.Unquote(modulePath); err == nil {
+			modulePath = unquoted
+		}
+		requirements = append(requirements, goModRequirement{
+			path:         modulePath,
+			version:      fields[1],
+			pathRange:    fieldRange(pathStart, len(fields[0])),
+			versionRange: fieldRange(versionStart, len(fields[1])),
+		})
+	}
+	return requirements
+}
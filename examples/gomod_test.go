@@ -0,0 +1,93 @@
+package examples
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/gomod"
+)
+
+const goModSource = `module example.com/app
+
+go 1.22
+
+require github.com/pkg/errors v0.9.1
+
+require (
+	github.com/gorilla/websocket v1.5.0 // indirect
+	golang.org/x/sys v0.24.0
+	example.com/unknown v1.0.0
+)
+`
+
+func TestGoModProvider_Hover(t *testing.T) {
+	provider := &GoModProvider{Versions: gomod.StaticVersions{
+		"github.com/pkg/errors":        "v0.9.1",
+		"github.com/gorilla/websocket": "v1.5.3",
+		"golang.org/x/sys":             "v0.23.0",
+	}}
+	at := func(marker string) core.Position {
+		return core.ByteOffsetToPosition(goModSource, strings.Index(goModSource, marker)+1)
+	}
+
+	tests := map[string]string{
+		"github.com/pkg/errors": "**github.com/pkg/errors** `v0.9.1`\n\nUp to date",
+		"v1.5.0":                "**github.com/gorilla/websocket** `v1.5.0`\n\nLatest version: `v1.5.3`",
+		"golang.org/x/sys":      "**golang.org/x/sys** `v0.24.0`\n\nUp to date",
+		"example.com/unknown":   "**example.com/unknown** `v1.0.0`\n\nLatest version unknown",
+	}
+	for marker, want := range tests {
+		hover := provider.ProvideHover("file:///app/go.mod", goModSource, at(marker))
+		if hover == nil {
+			t.Errorf("no hover on %s", marker)
+			continue
+		}
+		if hover.Contents != want {
+			t.Errorf("hover on %s = %q, want %q", marker, hover.Contents, want)
+		}
+	}
+	for _, marker := range []string{"module", "// indirect"} {
+		if hover := provider.ProvideHover("file:///app/go.mod", goModSource, at(marker)); hover != nil {
+			t.Errorf("hover on %s = %q, want none", marker, hover.Contents)
+		}
+	}
+	if hover := provider.ProvideHover("file:///app/deps.txt", goModSource, at("v1.5.0")); hover != nil {
+		t.Errorf("hover outside go.mod = %q, want none", hover.Contents)
+	}
+}
+
+func TestGoModProvider_CodeFixes(t *testing.T) {
+	provider := &GoModProvider{Versions: gomod.StaticVersions{
+		"github.com/pkg/errors":        "v0.9.1",
+		"github.com/gorilla/websocket": "v1.5.3",
+		"golang.org/x/sys":             "v0.23.0",
+	}}
+	wholeFile := core.Range{End: core.Position{Line: 12}}
+	actions := provider.ProvideCodeFixes(core.CodeFixContext{
+		URI:     "file:///app/go.mod",
+		Content: goModSource,
+		Range:   wholeFile,
+	})
+	if len(actions) != 1 {
+		t.Fatalf("got %d actions, want 1: %v", len(actions), actions)
+	}
+	if want := "Update github.com/gorilla/websocket to v1.5.3"; actions[0].Title != want {
+		t.Errorf("title = %q, want %q", actions[0].Title, want)
+	}
+	edits := actions[0].Edit.Changes["file:///app/go.mod"]
+	got := applyTextEdits(goModSource, edits)
+	if want := strings.Replace(goModSource, "v1.5.0 // indirect", "v1.5.3 // indirect", 1); got != want {
+		t.Errorf("updated go.mod =\n%s\nwant\n%s", got, want)
+	}
+
+	// Only the requirements on the requested lines are updated
+	line := core.ByteOffsetToPosition(goModSource, strings.Index(goModSource, "golang.org")).Line
+	if actions := provider.ProvideCodeFixes(core.CodeFixContext{
+		URI:     "file:///app/go.mod",
+		Content: goModSource,
+		Range:   core.Range{Start: core.Position{Line: line}, End: core.Position{Line: line}},
+	}); len(actions) != 0 {
+		t.Errorf("actions on an up to date line = %v, want none", actions)
+	}
+}
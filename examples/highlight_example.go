@@ -1,56 +1,31 @@
 package examples
 
 import (
+	"sort"
 	"strings"
 	"unicode"
 
 	"github.com/SCKelemen/lsp/core"
-	"github.com/SCKelemen/unicode/uax29"
 )
 
-// SimpleHighlightProvider highlights all occurrences of a word in a document.
-// This is a basic example that highlights based on exact word matching.
+// SimpleHighlightProvider highlights all occurrences of a symbol in a
+// document, as found by findOccurrences, the engine references and rename
+// use too.
 type SimpleHighlightProvider struct{}
 
 func (p *SimpleHighlightProvider) ProvideDocumentHighlights(ctx core.DocumentHighlightContext) []core.DocumentHighlight {
 	var highlights []core.DocumentHighlight
 
-	// Get the word at the cursor position
-	word := getWordAtPosition(ctx.Content, ctx.Position)
-	if word == "" {
-		return nil
-	}
+	// Highlight the occurrences of the symbol or word at the cursor
+	_, occurrences := findOccurrences(ctx.URI, ctx.Content, ctx.Position)
+	for _, o := range occurrences {
+		// Default to Text highlighting
+		kind := core.DocumentHighlightKindText
 
-	// Get all word boundaries in the content
-	breaks := uax29.FindWordBreaks(ctx.Content)
-	if len(breaks) < 2 {
-		return nil
-	}
-
-	// Find all occurrences of this word using UAX29 word boundaries
-	for i := 0; i < len(breaks)-1; i++ {
-		start := breaks[i]
-		end := breaks[i+1]
-
-		// Extract the word at this boundary
-		candidateWord := ctx.Content[start:end]
-
-		// Check if this word matches our target word
-		if candidateWord == word {
-			startPos := core.ByteOffsetToPosition(ctx.Content, start)
-			endPos := core.ByteOffsetToPosition(ctx.Content, end)
-
-			// Default to Text highlighting
-			kind := core.DocumentHighlightKindText
-
-			highlights = append(highlights, core.DocumentHighlight{
-				Range: core.Range{
-					Start: startPos,
-					End:   endPos,
-				},
-				Kind: &kind,
-			})
-		}
+		highlights = append(highlights, core.DocumentHighlight{
+			Range: o.rangeOf(ctx.Content),
+			Kind:  &kind,
+		})
 	}
 
 	return highlights
@@ -63,99 +38,189 @@ type VariableHighlightProvider struct{}
 func (p *VariableHighlightProvider) ProvideDocumentHighlights(ctx core.DocumentHighlightContext) []core.DocumentHighlight {
 	var highlights []core.DocumentHighlight
 
-	// Get the word at the cursor position
-	word := getWordAtPosition(ctx.Content, ctx.Position)
-	if word == "" {
-		return nil
-	}
+	// Highlight the occurrences of the symbol or word at the cursor
+	_, occurrences := findOccurrences(ctx.URI, ctx.Content, ctx.Position)
+	for _, o := range occurrences {
+		// Determine if this is a read or write based on context
+		kind := determineHighlightKind(ctx.Content, o.start, o.end-o.start)
 
-	// Get all word boundaries in the content
-	breaks := uax29.FindWordBreaks(ctx.Content)
-	if len(breaks) < 2 {
-		return nil
+		highlights = append(highlights, core.DocumentHighlight{
+			Range: o.rangeOf(ctx.Content),
+			Kind:  &kind,
+		})
 	}
 
-	// Find all occurrences of this word using UAX29 word boundaries
-	for i := 0; i < len(breaks)-1; i++ {
-		start := breaks[i]
-		end := breaks[i+1]
+	return highlights
+}
 
-		// Extract the word at this boundary
-		candidateWord := ctx.Content[start:end]
+// LiteralHighlightProvider highlights all occurrences of the string literal
+// or numeric constant at the cursor, which is useful in configuration and
+// data files where values, not identifiers, repeat. Strings match by their
+// value, so "web" and 'web' are occurrences of each other.
+//
+// Each kind of literal can be turned on or off, e.g. from the server's
+// configuration. When the cursor is not on an enabled literal, the request
+// is passed to Fallback, typically an identifier highlighter.
+type LiteralHighlightProvider struct {
+	// Strings enables highlighting string literals.
+	Strings bool
+
+	// Numbers enables highlighting numeric constants.
+	Numbers bool
+
+	// Fallback provides highlights when the cursor is not on an enabled
+	// literal. It may be nil.
+	Fallback core.DocumentHighlightProvider
+}
 
-		// Check if this word matches our target word
-		if candidateWord == word {
-			startPos := core.ByteOffsetToPosition(ctx.Content, start)
-			endPos := core.ByteOffsetToPosition(ctx.Content, end)
+func (p *LiteralHighlightProvider) ProvideDocumentHighlights(ctx core.DocumentHighlightContext) []core.DocumentHighlight {
+	literals := core.FindLiterals(ctx.Content)
 
-			// Determine if this is a read or write based on context
-			kind := determineHighlightKind(ctx.Content, start, end-start)
+	var target *core.Literal
+	for i := range literals {
+		literal := &literals[i]
+		if core.ComparePositions(literal.Range.Start, ctx.Position) <= 0 &&
+			core.ComparePositions(ctx.Position, literal.Range.End) <= 0 {
+			target = literal
+			break
+		}
+	}
 
-			highlights = append(highlights, core.DocumentHighlight{
-				Range: core.Range{
-					Start: startPos,
-					End:   endPos,
-				},
-				Kind: &kind,
-			})
+	if target == nil || !p.enabled(target.Kind) {
+		if p.Fallback != nil {
+			return p.Fallback.ProvideDocumentHighlights(ctx)
 		}
+		return nil
 	}
 
+	var highlights []core.DocumentHighlight
+	for _, literal := range literals {
+		if literal.Kind != target.Kind || literal.Value != target.Value {
+			continue
+		}
+		kind := core.DocumentHighlightKindText
+		highlights = append(highlights, core.DocumentHighlight{
+			Range: literal.Range,
+			Kind:  &kind,
+		})
+	}
 	return highlights
 }
 
-// Helper: Get the word at a position using Unicode word boundaries
-func getWordAtPosition(content string, pos core.Position) string {
-	offset := core.PositionToByteOffset(content, pos)
-	if offset < 0 || offset >= len(content) {
-		return ""
+func (p *LiteralHighlightProvider) enabled(kind core.LiteralKind) bool {
+	switch kind {
+	case core.LiteralString:
+		return p.Strings
+	case core.LiteralNumber:
+		return p.Numbers
 	}
+	return false
+}
 
-	// Get all word boundaries in the content
-	breaks := uax29.FindWordBreaks(content)
-	if len(breaks) < 2 {
-		return ""
-	}
+// DefaultMaxHighlights is the number of highlights CompositeHighlightProvider
+// returns by default.
+const DefaultMaxHighlights = 1000
 
-	// Helper function to check if a segment is a valid word
-	isValidWord := func(word string) bool {
-		if len(strings.TrimSpace(word)) == 0 {
-			return false // Just whitespace
-		}
+// CompositeHighlightProvider merges the highlights of several providers,
+// e.g. a read/write classifier and a plain word matcher.
+//
+// A range highlighted by several providers is returned once. Its kind is
+// Read or Write when the providers classifying it agree, and Text when they
+// disagree or none classified it. The confidence of the classification is
+// the fraction of the highlights with an agreed Read or Write kind; below
+// MinConfidence, every highlight is downgraded to Text, so that editors do
+// not show a misleading mix of writes and reads, e.g. when a heuristic
+// classifier only recognizes some of the occurrences.
+type CompositeHighlightProvider struct {
+	Providers []core.DocumentHighlightProvider
+
+	// MinConfidence is the confidence, from 0 to 1, below which every
+	// highlight is Text. 0 keeps the classification.
+	MinConfidence float64
+
+	// MaxHighlights caps the highlights returned for pathological files,
+	// keeping those nearest to the cursor. 0 for no limit.
+	MaxHighlights int
+}
 
-		// Check if it contains at least one alphanumeric character
-		for _, r := range word {
-			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r > 127 {
-				return true
+// NewCompositeHighlightProvider creates a provider returning up to
+// DefaultMaxHighlights highlights of providers.
+func NewCompositeHighlightProvider(providers ...core.DocumentHighlightProvider) *CompositeHighlightProvider {
+	return &CompositeHighlightProvider{
+		Providers:     providers,
+		MaxHighlights: DefaultMaxHighlights,
+	}
+}
+
+func (p *CompositeHighlightProvider) ProvideDocumentHighlights(ctx core.DocumentHighlightContext) []core.DocumentHighlight {
+	type merged struct {
+		rng      core.Range
+		kind     core.DocumentHighlightKind // Read or Write once classified
+		conflict bool
+	}
+	var highlights []*merged
+	byRange := make(map[core.Range]*merged)
+	for _, provider := range p.Providers {
+		for _, h := range provider.ProvideDocumentHighlights(ctx) {
+			m, ok := byRange[h.Range]
+			if !ok {
+				m = &merged{rng: h.Range}
+				byRange[h.Range] = m
+				highlights = append(highlights, m)
+			}
+			if h.Kind == nil || *h.Kind == core.DocumentHighlightKindText {
+				continue
 			}
+			if m.kind != 0 && m.kind != *h.Kind {
+				m.conflict = true
+			}
+			m.kind = *h.Kind
 		}
-		return false // Just punctuation
+	}
+	if len(highlights) == 0 {
+		return nil
 	}
 
-	// Find which word boundary the offset falls into
-	for i := 0; i < len(breaks)-1; i++ {
-		start := breaks[i]
-		end := breaks[i+1]
+	classified := 0
+	for _, m := range highlights {
+		if m.conflict {
+			m.kind = 0
+		} else if m.kind != 0 {
+			classified++
+		}
+	}
+	downgrade := float64(classified)/float64(len(highlights)) < p.MinConfidence
 
-		if offset >= start && offset < end {
-			word := content[start:end]
-			if isValidWord(word) {
-				return word
-			}
-			// If cursor is on whitespace/punctuation, try the previous word
-			if i > 0 {
-				prevStart := breaks[i-1]
-				prevEnd := breaks[i]
-				prevWord := content[prevStart:prevEnd]
-				if isValidWord(prevWord) {
-					return prevWord
-				}
-			}
-			return ""
+	if p.MaxHighlights > 0 && len(highlights) > p.MaxHighlights {
+		sort.SliceStable(highlights, func(i, j int) bool {
+			return lineDistance(highlights[i].rng, ctx.Position) < lineDistance(highlights[j].rng, ctx.Position)
+		})
+		highlights = highlights[:p.MaxHighlights]
+	}
+	sort.Slice(highlights, func(i, j int) bool {
+		return core.ComparePositions(highlights[i].rng.Start, highlights[j].rng.Start) < 0
+	})
+
+	result := make([]core.DocumentHighlight, len(highlights))
+	for i, m := range highlights {
+		kind := m.kind
+		if kind == 0 || downgrade {
+			kind = core.DocumentHighlightKindText
 		}
+		result[i] = core.DocumentHighlight{Range: m.rng, Kind: &kind}
 	}
+	return result
+}
 
-	return ""
+// lineDistance returns the number of lines between a range and a position.
+func lineDistance(rng core.Range, position core.Position) int {
+	switch {
+	case position.Line < rng.Start.Line:
+		return rng.Start.Line - position.Line
+	case position.Line > rng.End.Line:
+		return position.Line - rng.End.Line
+	}
+	return 0
 }
 
 // Helper: Check if character is part of a word
@@ -639,3 +639,151 @@ func main() {
 		})
 	}
 }
+
+// TestLiteralHighlightProvider tests highlighting of strings and numbers.
+func TestLiteralHighlightProvider(t *testing.T) {
+	content := `services:
+  web:
+    image: "nginx"
+    port: 8080
+  proxy:
+    upstream: 'nginx'
+    port: 8080
+    replicas: 80
+`
+
+	tests := []struct {
+		name      string
+		provider  *LiteralHighlightProvider
+		position  core.Position
+		wantLines []int
+	}{
+		{
+			name:      "string by value",
+			provider:  &LiteralHighlightProvider{Strings: true, Numbers: true},
+			position:  core.Position{Line: 2, Character: 12},
+			wantLines: []int{2, 5},
+		},
+		{
+			name:      "number",
+			provider:  &LiteralHighlightProvider{Strings: true, Numbers: true},
+			position:  core.Position{Line: 3, Character: 12},
+			wantLines: []int{3, 6},
+		},
+		{
+			name:      "numbers disabled",
+			provider:  &LiteralHighlightProvider{Strings: true},
+			position:  core.Position{Line: 3, Character: 12},
+			wantLines: nil,
+		},
+		{
+			name:      "fallback on identifiers",
+			provider:  &LiteralHighlightProvider{Strings: true, Fallback: &SimpleHighlightProvider{}},
+			position:  core.Position{Line: 3, Character: 5},
+			wantLines: []int{3, 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			highlights := tt.provider.ProvideDocumentHighlights(core.DocumentHighlightContext{
+				URI:      "file:///compose.yaml",
+				Content:  content,
+				Position: tt.position,
+			})
+
+			var lines []int
+			for _, h := range highlights {
+				lines = append(lines, h.Range.Start.Line)
+			}
+			if len(lines) != len(tt.wantLines) {
+				t.Fatalf("got highlights on lines %v, want %v", lines, tt.wantLines)
+			}
+			for i := range lines {
+				if lines[i] != tt.wantLines[i] {
+					t.Errorf("got highlights on lines %v, want %v", lines, tt.wantLines)
+				}
+			}
+		})
+	}
+}
+
+// fixedHighlightProvider returns the same highlights for every request.
+type fixedHighlightProvider []core.DocumentHighlight
+
+func (p fixedHighlightProvider) ProvideDocumentHighlights(ctx core.DocumentHighlightContext) []core.DocumentHighlight {
+	return p
+}
+
+// TestCompositeHighlightProvider tests merging, the Text fallback, and the cap.
+func TestCompositeHighlightProvider(t *testing.T) {
+	highlight := func(line int, kind core.DocumentHighlightKind) core.DocumentHighlight {
+		return core.DocumentHighlight{
+			Range: core.Range{Start: core.Position{Line: line}, End: core.Position{Line: line, Character: 5}},
+			Kind:  &kind,
+		}
+	}
+	const (
+		text  = core.DocumentHighlightKindText
+		read  = core.DocumentHighlightKindRead
+		write = core.DocumentHighlightKindWrite
+	)
+	classifier := fixedHighlightProvider{highlight(0, write), highlight(5, read)}
+	words := fixedHighlightProvider{highlight(0, text), highlight(5, text), highlight(10, text)}
+
+	tests := []struct {
+		name      string
+		provider  *CompositeHighlightProvider
+		wantLines []int
+		wantKinds []core.DocumentHighlightKind
+	}{
+		{
+			name:      "classified",
+			provider:  &CompositeHighlightProvider{Providers: []core.DocumentHighlightProvider{classifier, words}},
+			wantLines: []int{0, 5, 10},
+			wantKinds: []core.DocumentHighlightKind{write, read, text},
+		},
+		{
+			name:      "disagreement",
+			provider:  &CompositeHighlightProvider{Providers: []core.DocumentHighlightProvider{classifier, fixedHighlightProvider{highlight(0, read)}}},
+			wantLines: []int{0, 5},
+			wantKinds: []core.DocumentHighlightKind{text, read},
+		},
+		{
+			name:      "confident enough",
+			provider:  &CompositeHighlightProvider{Providers: []core.DocumentHighlightProvider{classifier, words}, MinConfidence: 0.6},
+			wantLines: []int{0, 5, 10},
+			wantKinds: []core.DocumentHighlightKind{write, read, text},
+		},
+		{
+			name:      "low confidence",
+			provider:  &CompositeHighlightProvider{Providers: []core.DocumentHighlightProvider{classifier, words}, MinConfidence: 0.9},
+			wantLines: []int{0, 5, 10},
+			wantKinds: []core.DocumentHighlightKind{text, text, text},
+		},
+		{
+			name:      "capped near the cursor",
+			provider:  &CompositeHighlightProvider{Providers: []core.DocumentHighlightProvider{words}, MaxHighlights: 2},
+			wantLines: []int{5, 10},
+			wantKinds: []core.DocumentHighlightKind{text, text},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			highlights := tt.provider.ProvideDocumentHighlights(core.DocumentHighlightContext{
+				URI:      "file:///test.go",
+				Position: core.Position{Line: 9},
+			})
+
+			if len(highlights) != len(tt.wantLines) {
+				t.Fatalf("got %d highlights, want %d", len(highlights), len(tt.wantLines))
+			}
+			for i, h := range highlights {
+				if h.Range.Start.Line != tt.wantLines[i] || h.Kind == nil || *h.Kind != tt.wantKinds[i] {
+					t.Errorf("highlight %d: line %d kind %v, want line %d kind %d", i, h.Range.Start.Line, h.Kind, tt.wantLines[i], tt.wantKinds[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package examples
+
+import (
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/gomod"
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+// GoImportRenameProvider rewrites import paths across the workspace when a
+// package directory is renamed or moved in the editor.
+//
+// It keeps an index of the imports of every Go file in the workspace. When
+// the client announces a directory rename with workspace/willRenameFiles, it
+// returns a WorkspaceEdit replacing every import of the old package path, and
+// of packages below it, with the new path. Register it with a
+// core.FileOperationRegistry; it also handles the did* notifications to keep
+// its index in sync.
+type GoImportRenameProvider struct {
+	// WorkspaceRoot is the root directory of the workspace, containing go.mod.
+	WorkspaceRoot string
+
+	// ModulePath is the module path of the workspace, like
+	// "github.com/user/repo". If empty, it is read from go.mod.
+	ModulePath string
+
+	// Resolver, when set, gives the modules of the workspace, like those of
+	// a go.work file: their files are indexed, and moving a package of one
+	// rewrites its imports in the others. WorkspaceRoot and ModulePath are
+	// then not needed.
+	Resolver *gomod.Resolver
+
+	mu sync.Mutex
+	// imports holds the import specs of each indexed file by URI
+	imports map[string][]goImport
+}
+
+// goImport is an import path in a file and the range of the path between
+// its quotes.
+type goImport struct {
+	path  string
+	rng   core.Range
+	quote string
+}
+
+func NewGoImportRenameProvider(workspaceRoot string) *GoImportRenameProvider {
+	return &GoImportRenameProvider{
+		WorkspaceRoot: workspaceRoot,
+		imports:       make(map[string][]goImport),
+	}
+}
+
+// GoImportRenameFilters are the file operation filters to register the
+// provider with: Go files and folders, which may contain packages.
+var GoImportRenameFilters = []core.FileOperationFilter{
+	{Scheme: "file", Glob: "**/*.go", Matches: core.FileOperationPatternKindFile},
+	{Scheme: "file", Glob: "**", Matches: core.FileOperationPatternKindFolder},
+}
+
+// IndexWorkspace indexes the imports of every Go file under WorkspaceRoot,
+// or the roots of Resolver.
+func (p *GoImportRenameProvider) IndexWorkspace() error {
+	return walkGoRoots(p.WorkspaceRoot, p.Resolver, func(path string, info fs.FileInfo) error {
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		p.IndexFile(uripkg.FromPath(path), string(content))
+		return nil
+	})
+}
+
+// IndexFile indexes the imports of a single Go file.
+// This should be called when files are opened or changed.
+func (p *GoImportRenameProvider) IndexFile(uri, content string) {
+	if !strings.HasSuffix(uri, ".go") {
+		return
+	}
+
+	var imports []goImport
+	fset := token.NewFileSet()
+	// A partial parse still yields the imports before a syntax error
+	f, _ := parser.ParseFile(fset, "", content, parser.ImportsOnly)
+	if f != nil {
+		for _, spec := range f.Imports {
+			importPath, unquoteErr := strconv.Unquote(spec.Path.Value)
+			if unquoteErr != nil {
+				continue
+			}
+			start := fset.Position(spec.Path.Pos())
+			end := fset.Position(spec.Path.End())
+			imports = append(imports, goImport{
+				path: importPath,
+				rng: core.Range{
+					// Exclude the quotes
+					Start: core.Position{Line: start.Line - 1, Character: start.Column},
+					End:   core.Position{Line: end.Line - 1, Character: end.Column - 2},
+				},
+				quote: spec.Path.Value[:1],
+			})
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.imports == nil {
+		p.imports = make(map[string][]goImport)
+	}
+	p.imports[uripkg.Normalize(uri)] = imports
+}
+
+// WillRenameFiles returns the edits rewriting imports of renamed packages.
+// Renamed files are ignored, since no import refers to a single file.
+func (p *GoImportRenameProvider) WillRenameFiles(files []core.FileRename) *core.WorkspaceEdit {
+	modulePath := p.modulePath()
+	if modulePath == "" && p.Resolver == nil {
+		return nil
+	}
+
+	changes := make(map[string][]core.TextEdit)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, file := range files {
+		oldPkg, ok := p.importPath(modulePath, file.OldURI)
+		if !ok {
+			continue
+		}
+		newPkg, ok := p.importPath(modulePath, file.NewURI)
+		if !ok || oldPkg == newPkg {
+			continue
+		}
+
+		for uri, imports := range p.imports {
+			for _, imp := range imports {
+				rewritten, ok := replacePathPrefix(imp.path, oldPkg, newPkg)
+				if !ok {
+					continue
+				}
+				newText := rewritten
+				if imp.quote == `"` {
+					// Keep the literal valid for paths needing escapes
+					newText = strings.Trim(strconv.Quote(rewritten), `"`)
+				}
+				changes[uri] = append(changes[uri], core.TextEdit{Range: imp.rng, NewText: newText})
+			}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	for uri := range changes {
+		sort.Slice(changes[uri], func(i, j int) bool {
+			return core.ComparePositions(changes[uri][i].Range.Start, changes[uri][j].Range.Start) < 0
+		})
+	}
+	return &core.WorkspaceEdit{Changes: changes}
+}
+
+// DidRenameFiles moves renamed files in the index and applies the import
+// rewrites to it, since the edits of edited closed files are not reported.
+func (p *GoImportRenameProvider) DidRenameFiles(files []core.FileRename) {
+	modulePath := p.modulePath()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, file := range files {
+		oldURI := uripkg.Normalize(file.OldURI)
+		newURI := uripkg.Normalize(file.NewURI)
+
+		moved := make(map[string][]goImport)
+		for uri, imports := range p.imports {
+			if newFileURI, ok := replacePathPrefix(uri, oldURI, newURI); ok {
+				delete(p.imports, uri)
+				moved[newFileURI] = imports
+			}
+		}
+		for uri, imports := range moved {
+			p.imports[uri] = imports
+		}
+
+		if modulePath == "" && p.Resolver == nil {
+			continue
+		}
+		oldPkg, ok1 := p.importPath(modulePath, file.OldURI)
+		newPkg, ok2 := p.importPath(modulePath, file.NewURI)
+		if !ok1 || !ok2 {
+			continue
+		}
+		for _, imports := range p.imports {
+			for i := range imports {
+				if rewritten, ok := replacePathPrefix(imports[i].path, oldPkg, newPkg); ok {
+					imports[i].path = rewritten
+				}
+			}
+		}
+	}
+}
+
+// DidDeleteFiles drops deleted files and folders from the index.
+func (p *GoImportRenameProvider) DidDeleteFiles(files []core.FileDelete) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, file := range files {
+		deleted := uripkg.Normalize(file.URI)
+		for uri := range p.imports {
+			if _, ok := replacePathPrefix(uri, deleted, ""); ok {
+				delete(p.imports, uri)
+			}
+		}
+	}
+}
+
+// DidCreateFiles indexes created Go files.
+func (p *GoImportRenameProvider) DidCreateFiles(files []core.FileCreate) {
+	for _, file := range files {
+		filePath, err := uripkg.ToPath(file.URI)
+		if err != nil {
+			continue
+		}
+		if content, err := os.ReadFile(filePath); err == nil {
+			p.IndexFile(file.URI, string(content))
+		}
+	}
+}
+
+// importPath returns the import path of the package in the directory at
+// uri, which must be inside WorkspaceRoot or a module of Resolver.
+func (p *GoImportRenameProvider) importPath(modulePath, uri string) (string, bool) {
+	dir, err := uripkg.ToPath(uri)
+	if err != nil {
+		return "", false
+	}
+	if p.Resolver != nil {
+		return p.Resolver.ImportPath(dir)
+	}
+	rel, err := filepath.Rel(p.WorkspaceRoot, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if rel == "." {
+		return modulePath, true
+	}
+	return path.Join(modulePath, filepath.ToSlash(rel)), true
+}
+
+// modulePath returns ModulePath, reading it from go.mod if it is empty.
+func (p *GoImportRenameProvider) modulePath() string {
+	if p.ModulePath != "" {
+		return p.ModulePath
+	}
+	if p.Resolver != nil {
+		// importPath uses the module of each package
+		return ""
+	}
+	return readModulePath(filepath.Join(p.WorkspaceRoot, "go.mod"))
+}
+
+// replacePathPrefix replaces the prefix oldPrefix of a slash-separated path
+// (an import path or URI) with newPrefix, if p is oldPrefix or below it.
+func replacePathPrefix(p, oldPrefix, newPrefix string) (string, bool) {
+	if p == oldPrefix {
+		return newPrefix, true
+	}
+	if strings.HasPrefix(p, oldPrefix+"/") {
+		return newPrefix + p[len(oldPrefix):], true
+	}
+	return "", false
+}
+
+// readModulePath returns the module path declared in a go.mod file, or ""
+// if it cannot be read.
+func readModulePath(goModPath string) string {
+	data, err := os.ReadFile(goModPath)
+	if err != nil {
+		return ""
+	}
+	file, err := gomod.ParseModFile(data)
+	if err != nil {
+		return ""
+	}
+	return file.Module
+}
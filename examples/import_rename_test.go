@@ -0,0 +1,147 @@
+package examples
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/gomod"
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+// TestGoImportRenameProvider tests rewriting imports when a package moves.
+func TestGoImportRenameProvider(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.mod": "module example.com/app // the app\n\ngo 1.22\n",
+		"main.go": `package main
+
+import (
+	"fmt"
+
+	"example.com/app/util"
+	strs "example.com/app/util/strings"
+	"example.com/app/utility"
+)
+`,
+		"util/util.go":            "package util\n",
+		"util/strings/strings.go": "package strings\n\nimport \"example.com/app/util\"\n",
+		"utility/utility.go":      "package utility\n",
+	})
+
+	provider := NewGoImportRenameProvider(root)
+	if err := provider.IndexWorkspace(); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := core.NewFileOperationRegistry()
+	registry.Register(GoImportRenameFilters, provider)
+
+	rename := core.FileRename{
+		OldURI: uripkg.FromPath(filepath.Join(root, "util")),
+		NewURI: uripkg.FromPath(filepath.Join(root, "internal", "util")),
+	}
+	edit := registry.WillRenameFiles([]core.FileRename{rename})
+	if edit == nil {
+		t.Fatal("expected import edits")
+	}
+
+	mainURI := uripkg.FromPath(filepath.Join(root, "main.go"))
+	mainEdits := edit.Changes[mainURI]
+	if len(mainEdits) != 2 {
+		t.Fatalf("expected 2 edits in main.go, got %+v", mainEdits)
+	}
+	wantMain := []core.TextEdit{
+		{
+			Range:   core.Range{Start: core.Position{Line: 5, Character: 2}, End: core.Position{Line: 5, Character: 22}},
+			NewText: "example.com/app/internal/util",
+		},
+		{
+			Range:   core.Range{Start: core.Position{Line: 6, Character: 7}, End: core.Position{Line: 6, Character: 35}},
+			NewText: "example.com/app/internal/util/strings",
+		},
+	}
+	for i, want := range wantMain {
+		if mainEdits[i] != want {
+			t.Errorf("edit %d = %+v, want %+v", i, mainEdits[i], want)
+		}
+	}
+
+	// Imports inside the moved directory are fixed too
+	stringsURI := uripkg.FromPath(filepath.Join(root, "util", "strings", "strings.go"))
+	if len(edit.Changes[stringsURI]) != 1 {
+		t.Errorf("expected the moved package's own import to be fixed, got %+v", edit.Changes)
+	}
+	if len(edit.Changes) != 2 {
+		t.Errorf("expected edits in 2 files, got %d", len(edit.Changes))
+	}
+
+	// After the rename, the index follows the new paths
+	registry.DidRenameFiles([]core.FileRename{rename})
+	back := registry.WillRenameFiles([]core.FileRename{{OldURI: rename.NewURI, NewURI: rename.OldURI}})
+	if back == nil || len(back.Changes[mainURI]) != 2 {
+		t.Errorf("expected the index to track the rename, got %+v", back)
+	}
+	movedURI := uripkg.FromPath(filepath.Join(root, "internal", "util", "strings", "strings.go"))
+	if len(back.Changes[movedURI]) != 1 {
+		t.Errorf("expected the moved file to be re-keyed, got %+v", back.Changes)
+	}
+}
+
+// TestGoImportRenameProviderWorkspace tests rewriting the imports of a
+// package moved in one module of a go.work workspace in the others.
+func TestGoImportRenameProviderWorkspace(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.work":          "go 1.22\n\nuse (\n\t./app\n\t./lib\n)\n",
+		"app/go.mod":       "module example.com/app\n\nrequire example.com/lib v0.1.0\n",
+		"app/main.go":      "package main\n\nimport \"example.com/lib/util\"\n",
+		"lib/go.mod":       "module example.com/lib\n",
+		"lib/util/util.go": "package util\n",
+	})
+
+	provider := &GoImportRenameProvider{Resolver: gomod.NewResolver(root)}
+	if err := provider.IndexWorkspace(); err != nil {
+		t.Fatal(err)
+	}
+	edit := provider.WillRenameFiles([]core.FileRename{{
+		OldURI: uripkg.FromPath(filepath.Join(root, "lib", "util")),
+		NewURI: uripkg.FromPath(filepath.Join(root, "lib", "internal", "util")),
+	}})
+	if edit == nil {
+		t.Fatal("expected import edits")
+	}
+	mainEdits := edit.Changes[uripkg.FromPath(filepath.Join(root, "app", "main.go"))]
+	if len(mainEdits) != 1 || mainEdits[0].NewText != "example.com/lib/internal/util" {
+		t.Errorf("edits of the other module = %+v", mainEdits)
+	}
+}
+
+// TestGoImportRenameProviderFileRename tests that renaming a file changes no imports.
+func TestGoImportRenameProviderFileRename(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.mod":       "module example.com/app\n",
+		"main.go":      "package main\n\nimport \"example.com/app/util\"\n",
+		"util/util.go": "package util\n",
+	})
+
+	provider := &GoImportRenameProvider{WorkspaceRoot: root}
+	if err := provider.IndexWorkspace(); err != nil {
+		t.Fatal(err)
+	}
+
+	edit := provider.WillRenameFiles([]core.FileRename{{
+		OldURI: uripkg.FromPath(filepath.Join(root, "util", "util.go")),
+		NewURI: uripkg.FromPath(filepath.Join(root, "util", "helpers.go")),
+	}})
+	if edit != nil {
+		t.Errorf("expected no edits for a file rename, got %+v", edit)
+	}
+
+	provider.DidDeleteFiles([]core.FileDelete{{URI: uripkg.FromPath(root)}})
+	edit = provider.WillRenameFiles([]core.FileRename{{
+		OldURI: uripkg.FromPath(filepath.Join(root, "util")),
+		NewURI: uripkg.FromPath(filepath.Join(root, "lib")),
+	}})
+	if edit != nil {
+		t.Errorf("expected deleted files to be dropped from the index, got %+v", edit)
+	}
+}
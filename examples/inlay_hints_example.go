@@ -3,7 +3,6 @@ package examples
 import (
 	"fmt"
 	"go/ast"
-	"go/parser"
 	"go/token"
 	"strings"
 
@@ -19,8 +18,7 @@ func (p *GoParameterNameInlayHintsProvider) ProvideInlayHints(uri, content strin
 		return nil
 	}
 
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	fset, f, err := parseGoFile(uri, content)
 	if err != nil {
 		return nil
 	}
@@ -123,8 +121,7 @@ func (p *GoTypeInlayHintsProvider) ProvideInlayHints(uri, content string, rng co
 		return nil
 	}
 
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	fset, f, err := parseGoFile(uri, content)
 	if err != nil {
 		return nil
 	}
@@ -0,0 +1,122 @@
+package examples
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+//go:embed keywords/*.json
+var keywordFiles embed.FS
+
+// KeywordDocs holds keyword documentation by language id and keyword.
+// Each entry is markdown describing the keyword.
+type KeywordDocs map[string]map[string]string
+
+// LoadKeywordDocs reads the keyword documentation in dir of fsys. Each file
+// named <language id>.json holds an object mapping keywords to markdown,
+// like keywords/go.json:
+//
+//	{"defer": "Defers a function call until the surrounding function returns."}
+func LoadKeywordDocs(fsys fs.FS, dir string) (KeywordDocs, error) {
+	names, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make(KeywordDocs, len(names))
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var keywords map[string]string
+		if err := json.Unmarshal(data, &keywords); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		docs[strings.TrimSuffix(path.Base(name), ".json")] = keywords
+	}
+	return docs, nil
+}
+
+// Lookup returns the documentation of keyword in language.
+func (d KeywordDocs) Lookup(language, keyword string) (string, bool) {
+	doc, ok := d[language][keyword]
+	return doc, ok
+}
+
+var (
+	builtinKeywordDocs     KeywordDocs
+	builtinKeywordDocsOnce sync.Once
+)
+
+// BuiltinKeywordDocs returns the keyword documentation shipped with the
+// examples: the complete keyword sets of Go and Python.
+func BuiltinKeywordDocs() KeywordDocs {
+	builtinKeywordDocsOnce.Do(func() {
+		docs, err := LoadKeywordDocs(keywordFiles, "keywords")
+		if err != nil {
+			// The files are embedded, so this is a build problem
+			panic(err)
+		}
+		builtinKeywordDocs = docs
+	})
+	return builtinKeywordDocs
+}
+
+// DefaultKeywordLanguages maps documents to the languages of
+// BuiltinKeywordDocs.
+var DefaultKeywordLanguages = core.NewLanguageRegistry(
+	core.Language{ID: "go", Extensions: []string{".go"}},
+	core.Language{ID: "python", Extensions: []string{".py", ".pyi"}},
+)
+
+// KeywordDocsProvider shows the documentation of the keyword under the
+// cursor. The language of a document is found with Languages, so one
+// provider serves every language in Docs.
+type KeywordDocsProvider struct {
+	// Docs holds the documentation by language and keyword.
+	Docs KeywordDocs
+
+	// Languages maps documents to language ids. If nil,
+	// DefaultKeywordLanguages is used.
+	Languages *core.LanguageRegistry
+}
+
+// NewKeywordDocsProvider creates a provider for BuiltinKeywordDocs.
+func NewKeywordDocsProvider() *KeywordDocsProvider {
+	return &KeywordDocsProvider{Docs: BuiltinKeywordDocs()}
+}
+
+func (p *KeywordDocsProvider) ProvideHover(uri, content string, position core.Position) *core.HoverInfo {
+	languages := p.Languages
+	if languages == nil {
+		languages = DefaultKeywordLanguages
+	}
+	return p.hover(languages.LanguageID(uri), content, position)
+}
+
+// hover returns the hover for the keyword at position in a document of the
+// given language.
+func (p *KeywordDocsProvider) hover(language, content string, position core.Position) *core.HoverInfo {
+	word, r := core.WordAt(content, position)
+	if word == "" {
+		return nil
+	}
+
+	doc, ok := p.Docs.Lookup(language, word)
+	if !ok {
+		return nil
+	}
+
+	return &core.HoverInfo{
+		Contents: "```" + language + "\n" + word + "\n```\n\n" + doc,
+		Range:    &r,
+	}
+}
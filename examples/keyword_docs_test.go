@@ -0,0 +1,114 @@
+package examples
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// TestBuiltinKeywordDocs tests that the shipped keyword sets are complete.
+func TestBuiltinKeywordDocs(t *testing.T) {
+	docs := BuiltinKeywordDocs()
+
+	goKeywords := strings.Fields(`break case chan const continue default defer else fallthrough
+		for func go goto if import interface map package range return select struct switch type var`)
+	if len(docs["go"]) != len(goKeywords) {
+		t.Errorf("got %d Go keywords, want %d", len(docs["go"]), len(goKeywords))
+	}
+	for _, keyword := range goKeywords {
+		if doc, ok := docs.Lookup("go", keyword); !ok || doc == "" {
+			t.Errorf("missing documentation for Go keyword %q", keyword)
+		}
+	}
+
+	if _, ok := docs.Lookup("python", "lambda"); !ok {
+		t.Error("missing documentation for Python keyword lambda")
+	}
+}
+
+// TestKeywordDocsProvider tests hover by document language.
+func TestKeywordDocsProvider(t *testing.T) {
+	provider := NewKeywordDocsProvider()
+
+	tests := []struct {
+		name         string
+		uri          string
+		content      string
+		position     core.Position
+		wantContains string
+	}{
+		{
+			name:         "go keyword",
+			uri:          "file:///main.go",
+			content:      "func main() {\n\tdefer f()\n}",
+			position:     core.Position{Line: 1, Character: 2},
+			wantContains: "```go\ndefer\n```",
+		},
+		{
+			name:         "python keyword",
+			uri:          "file:///main.py",
+			content:      "def main():\n    pass\n",
+			position:     core.Position{Line: 0, Character: 1},
+			wantContains: "Defines a function or method.",
+		},
+		{
+			name:     "keyword of another language",
+			uri:      "file:///main.py",
+			content:  "func = 1\n",
+			position: core.Position{Line: 0, Character: 1},
+		},
+		{
+			name:     "unknown language",
+			uri:      "file:///notes.txt",
+			content:  "func",
+			position: core.Position{Line: 0, Character: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hover := provider.ProvideHover(tt.uri, tt.content, tt.position)
+			if tt.wantContains == "" {
+				if hover != nil {
+					t.Errorf("expected no hover, got %q", hover.Contents)
+				}
+				return
+			}
+			if hover == nil {
+				t.Fatal("expected hover")
+			}
+			if !strings.Contains(hover.Contents, tt.wantContains) {
+				t.Errorf("hover %q does not contain %q", hover.Contents, tt.wantContains)
+			}
+		})
+	}
+}
+
+// TestLoadKeywordDocs tests loading documentation for another language.
+func TestLoadKeywordDocs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"docs/sql.json":   {Data: []byte(`{"SELECT": "Retrieves rows."}`)},
+		"docs/readme.txt": {Data: []byte("ignored")},
+	}
+
+	docs, err := LoadKeywordDocs(fsys, "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &KeywordDocsProvider{
+		Docs:      docs,
+		Languages: core.NewLanguageRegistry(core.Language{ID: "sql", Extensions: []string{".sql"}}),
+	}
+	hover := provider.ProvideHover("file:///q.sql", "SELECT 1", core.Position{Line: 0, Character: 2})
+	if hover == nil || !strings.Contains(hover.Contents, "Retrieves rows.") {
+		t.Errorf("unexpected hover %+v", hover)
+	}
+
+	fsys["docs/bad.json"] = &fstest.MapFile{Data: []byte("{")}
+	if _, err := LoadKeywordDocs(fsys, "docs"); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
@@ -0,0 +1,371 @@
+package examples
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/imports"
+	protocol "github.com/SCKelemen/lsp/protocol"
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+// MoveDeclarationCommand is the command moving a top-level declaration to
+// another file of its package. Its arguments are the URI of the Go file,
+// the name of the declaration, or "Type.Method" for methods, and
+// optionally the URI of the file to move it to.
+const MoveDeclarationCommand = "go.moveDeclaration"
+
+// GoMoveDeclarationProvider offers refactor.move actions moving the
+// top-level declaration under the cursor, with its doc comment, to
+// another file of the package. The action carries a
+// MoveDeclarationCommand; when workspace/executeCommand arrives, the server
+// runs it with ExecuteCommand, which asks the user for the file with
+// Choose, and sends the resulting edit to the client with
+// workspace/applyEdit.
+//
+// The file is one of the files of the package, or a new file named after
+// the declaration, created with a CreateFile operation. The imports the
+// declaration uses are added to the file, and removed from the file it
+// leaves when nothing else uses them.
+//
+// The action is not offered when code actions are requested automatically,
+// as for light bulbs, only when the user asks for refactorings.
+type GoMoveDeclarationProvider struct {
+	// PackageFiles returns the content of the Go files in the directory
+	// of the file at uri, including it, by URI. If nil, the files are
+	// read from disk.
+	PackageFiles func(uri string) map[string]string
+
+	// Choose asks the user to pick one of options, like the function
+	// returned by ShowMessageRequestChooser. ok is false if the user
+	// cancels.
+	Choose func(message string, options []string) (choice string, ok bool)
+}
+
+func (p *GoMoveDeclarationProvider) ProvideCodeFixes(ctx core.CodeFixContext) []core.CodeAction {
+	if !strings.HasSuffix(ctx.URI, ".go") || ctx.TriggerKind == core.CodeActionTriggerKindAutomatic ||
+		!codeActionKindRequested(ctx.Only, core.CodeActionKindRefactorMove) {
+		return nil
+	}
+	fset, f, err := parseGoFile(ctx.URI, ctx.Content)
+	if err != nil {
+		return nil
+	}
+	offset := core.PositionToByteOffset(ctx.Content, ctx.Range.Start)
+
+	for _, decl := range f.Decls {
+		name := movableDeclName(decl)
+		if name == "" || offset < fset.Position(decl.Pos()).Offset || offset > fset.Position(declHeaderEnd(decl)).Offset {
+			continue
+		}
+		kind := core.CodeActionKindRefactorMove
+		title := "Move " + name + " to another file"
+		return []core.CodeAction{{
+			Title: title,
+			Kind:  &kind,
+			Command: &core.Command{
+				Title:     title,
+				Command:   MoveDeclarationCommand,
+				Arguments: []interface{}{ctx.URI, name},
+			},
+		}}
+	}
+	return nil
+}
+
+// movableDeclName returns the name of a declaration that can be moved: a
+// function, "Type.Method" for a method, or the name of the only type,
+// constant, or variable of a declaration. It returns "" for the others.
+func movableDeclName(decl ast.Decl) string {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		return testedFuncName(decl)
+	case *ast.GenDecl:
+		if decl.Tok == token.IMPORT || len(decl.Specs) != 1 {
+			return ""
+		}
+		switch spec := decl.Specs[0].(type) {
+		case *ast.TypeSpec:
+			return spec.Name.Name
+		case *ast.ValueSpec:
+			if len(spec.Names) == 1 {
+				return spec.Names[0].Name
+			}
+		}
+	}
+	return ""
+}
+
+// declHeaderEnd returns the end of the part of a declaration the action is
+// offered on: the signature of a function, or the whole declaration.
+func declHeaderEnd(decl ast.Decl) token.Pos {
+	if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+		return fn.Body.Lbrace
+	}
+	return decl.End()
+}
+
+// ExecuteCommand runs MoveDeclarationCommand and returns the edit moving
+// the declaration, or nil if the user cancels the choice of the file.
+func (p *GoMoveDeclarationProvider) ExecuteCommand(command string, arguments []interface{}) (*core.WorkspaceEdit, error) {
+	if command != MoveDeclarationCommand {
+		return nil, fmt.Errorf("unknown command %q", command)
+	}
+	if len(arguments) != 2 && len(arguments) != 3 {
+		return nil, fmt.Errorf("%s expects a URI, a declaration name, and optionally a target URI", command)
+	}
+	uri, _ := arguments[0].(string)
+	name, _ := arguments[1].(string)
+	if uri == "" || name == "" {
+		return nil, fmt.Errorf("%s expects a URI, a declaration name, and optionally a target URI", command)
+	}
+
+	readFiles := p.PackageFiles
+	if readFiles == nil {
+		readFiles = goPackageFilesOnDisk
+	}
+	files := readFiles(uri)
+	content, ok := "", false
+	for fileURI, fileContent := range files {
+		if uripkg.Equal(fileURI, uri) {
+			content, ok = fileContent, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s: file not found", uri)
+	}
+	fset, f, err := parseGoFile(uri, content)
+	if err != nil {
+		return nil, err
+	}
+	var decl ast.Decl
+	for _, d := range f.Decls {
+		if movableDeclName(d) == name {
+			decl = d
+			break
+		}
+	}
+	if decl == nil {
+		return nil, fmt.Errorf("declaration %s not found in %s", name, uri)
+	}
+
+	// The files of the same package, with tests staying in test files
+	isTest := strings.HasSuffix(uri, "_test.go")
+	var targets []string
+	for fileURI, fileContent := range files {
+		if uripkg.Equal(fileURI, uri) || strings.HasSuffix(fileURI, "_test.go") != isTest {
+			continue
+		}
+		if _, target, err := parseGoFile(fileURI, fileContent); err == nil && target.Name.Name == f.Name.Name {
+			targets = append(targets, fileURI)
+		}
+	}
+	sort.Strings(targets)
+	newURI := uri[:strings.LastIndexByte(uri, '/')+1] + strings.ToLower(strings.ReplaceAll(name, ".", "_"))
+	if isTest {
+		newURI += "_test"
+	}
+	newURI += ".go"
+	if _, exists := files[newURI]; exists {
+		newURI = ""
+	}
+
+	var target string
+	if len(arguments) == 3 {
+		target, _ = arguments[2].(string)
+		if target == "" || target != newURI && !containsString(targets, target) {
+			return nil, fmt.Errorf("%v is not a file of package %s", arguments[2], f.Name.Name)
+		}
+	} else {
+		if p.Choose == nil {
+			return nil, fmt.Errorf("%s: no way to choose the file to move %s to", command, name)
+		}
+		options := make([]string, 0, len(targets)+1)
+		for _, targetURI := range targets {
+			options = append(options, path.Base(targetURI))
+		}
+		if newURI != "" {
+			options = append(options, "New file "+path.Base(newURI))
+		}
+		choice, ok := p.Choose("Move "+name+" to", options)
+		if !ok {
+			return nil, nil
+		}
+		for i, option := range options {
+			if option != choice {
+				continue
+			}
+			if i < len(targets) {
+				target = targets[i]
+			} else {
+				target = newURI
+			}
+		}
+		if target == "" {
+			return nil, fmt.Errorf("%q is not one of the files", choice)
+		}
+	}
+
+	return moveDeclEdit(uri, content, fset, f, decl, target, files[target])
+}
+
+// moveDeclEdit returns the edit moving decl from the file at uri to the
+// file at target, with content targetContent, or a new file if
+// targetContent is empty.
+func moveDeclEdit(uri, content string, fset *token.FileSet, f *ast.File, decl ast.Decl, target, targetContent string) (*core.WorkspaceEdit, error) {
+	// The declaration in whole lines, with its doc comment
+	pos := decl.Pos()
+	if doc := declDoc(decl); doc != nil {
+		pos = doc.Pos()
+	}
+	start := strings.LastIndexByte(content[:fset.Position(pos).Offset], '\n') + 1
+	end := chunkEnd(content, fset.Position(decl.End()).Offset)
+	text := content[start:end]
+
+	// Delete the lines along with one of the blank lines around them
+	deleteStart, deleteEnd := start, end
+	if deleteEnd < len(content) {
+		deleteEnd++
+	}
+	if strings.HasPrefix(content[deleteEnd:], "\n") {
+		deleteEnd++
+	} else if deleteStart >= 2 && content[deleteStart-2:deleteStart] == "\n\n" {
+		deleteStart--
+	}
+
+	// The imports of the file that the declaration uses
+	used := map[string]bool{}
+	usedElsewhere := map[string]bool{}
+	for _, d := range f.Decls {
+		ast.Inspect(d, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if x, ok := sel.X.(*ast.Ident); ok && x.Obj == nil {
+					if d == decl {
+						used[x.Name] = true
+					} else {
+						usedElsewhere[x.Name] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+	fileImports := imports.NewFile(content, fset, f)
+	var moved []imports.Import
+	var edits []core.TextEdit
+	for _, imp := range fileImports.Imports() {
+		if !used[imp.Name()] {
+			continue
+		}
+		moved = append(moved, imp)
+		if !usedElsewhere[imp.Name()] {
+			edits = append(edits, fileImports.Remove(imp.Path)...)
+		}
+	}
+	edits = append(edits, core.TextEdit{
+		Range: core.Range{
+			Start: core.ByteOffsetToPosition(content, deleteStart),
+			End:   core.ByteOffsetToPosition(content, deleteEnd),
+		},
+	})
+	changes := []interface{}{core.TextDocumentEdit{
+		TextDocument: core.VersionedTextDocumentIdentifier{URI: uri},
+		Edits:        edits,
+	}}
+
+	if targetContent == "" {
+		var file strings.Builder
+		fmt.Fprintf(&file, "package %s\n\n", f.Name.Name)
+		if len(moved) > 0 {
+			file.WriteString("import (\n")
+			for _, imp := range moved {
+				file.WriteString("\t")
+				if imp.Alias != "" {
+					file.WriteString(imp.Alias + " ")
+				}
+				file.WriteString(strconv.Quote(imp.Path) + "\n")
+			}
+			file.WriteString(")\n\n")
+		}
+		file.WriteString(text + "\n")
+		return &core.WorkspaceEdit{DocumentChanges: append(changes,
+			core.CreateFile{URI: target, Options: &core.CreateFileOptions{IgnoreIfExists: true}},
+			core.TextDocumentEdit{
+				TextDocument: core.VersionedTextDocumentIdentifier{URI: target},
+				Edits:        []core.TextEdit{{NewText: file.String()}},
+			},
+		)}, nil
+	}
+
+	targetFset, targetFile, err := parseGoFile(target, targetContent)
+	if err != nil {
+		return nil, err
+	}
+	var targetEdits []core.TextEdit
+	targetImports := imports.NewFile(targetContent, targetFset, targetFile)
+	for _, imp := range moved {
+		targetEdits = append(targetEdits, targetImports.Add(imp.Path, imp.Alias)...)
+	}
+	targetEnd := core.ByteOffsetToPosition(targetContent, len(targetContent))
+	separator := "\n"
+	if !strings.HasSuffix(targetContent, "\n") {
+		separator = "\n\n"
+	}
+	targetEdits = append(targetEdits, core.TextEdit{
+		Range:   core.Range{Start: targetEnd, End: targetEnd},
+		NewText: separator + text + "\n",
+	})
+	return &core.WorkspaceEdit{DocumentChanges: append(changes, core.TextDocumentEdit{
+		TextDocument: core.VersionedTextDocumentIdentifier{URI: target},
+		Edits:        targetEdits,
+	})}, nil
+}
+
+// declDoc returns the doc comment of a declaration, or nil.
+func declDoc(decl ast.Decl) *ast.CommentGroup {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		return decl.Doc
+	case *ast.GenDecl:
+		return decl.Doc
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ShowMessageRequestChooser returns a function asking the user to pick an
+// option with a window/showMessageRequest to the client of context, for
+// GoMoveDeclarationProvider.Choose.
+func ShowMessageRequestChooser(context *lsp.Context) func(message string, options []string) (string, bool) {
+	return func(message string, options []string) (string, bool) {
+		actions := make([]protocol.MessageActionItem, len(options))
+		for i, option := range options {
+			actions[i] = protocol.MessageActionItem{Title: option}
+		}
+		var choice *protocol.MessageActionItem
+		context.Call(protocol.ServerWindowShowMessageRequest, protocol.ShowMessageRequestParams{
+			Type:    protocol.MessageTypeInfo,
+			Message: message,
+			Actions: actions,
+		}, &choice)
+		if choice == nil {
+			return "", false
+		}
+		return choice.Title, true
+	}
+}
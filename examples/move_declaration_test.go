@@ -0,0 +1,183 @@
+package examples
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+const moveSource = `package store
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Store stores values.
+type Store struct{ m map[string]string }
+
+// Key normalizes a key.
+func Key(key string) string {
+	return strings.ToLower(key)
+}
+
+var (
+	a = 1
+	b = 2
+)
+
+func (s *Store) String() string { return fmt.Sprint(s.m) }
+`
+
+const moveTarget = `package store
+
+import "fmt"
+
+func dump(s *Store) { fmt.Println(s) }
+`
+
+func movePackageFiles(uri string) map[string]string {
+	return map[string]string{
+		"file:///store/store.go":      moveSource,
+		"file:///store/dump.go":       moveTarget,
+		"file:///store/store_test.go": "package store\n",
+		"file:///store/other.go":      "package other\n",
+	}
+}
+
+func TestGoMoveDeclarationProviderActions(t *testing.T) {
+	provider := &GoMoveDeclarationProvider{}
+	actionAt := func(marker string, trigger core.CodeActionTriggerKind) []core.CodeAction {
+		pos := core.ByteOffsetToPosition(moveSource, strings.Index(moveSource, marker))
+		return provider.ProvideCodeFixes(core.CodeFixContext{
+			URI:         "file:///store/store.go",
+			Content:     moveSource,
+			Range:       core.Range{Start: pos, End: pos},
+			TriggerKind: trigger,
+		})
+	}
+
+	actions := actionAt("Key(key", core.CodeActionTriggerKindInvoked)
+	if len(actions) != 1 || actions[0].Title != "Move Key to another file" || actions[0].Command == nil ||
+		actions[0].Command.Command != MoveDeclarationCommand || actions[0].Kind == nil || *actions[0].Kind != core.CodeActionKindRefactorMove {
+		t.Fatalf("actions = %+v, want Move Key", actions)
+	}
+	if actions := actionAt("String() string", core.CodeActionTriggerKindInvoked); len(actions) != 1 || actions[0].Command.Arguments[1] != "Store.String" {
+		t.Errorf("actions = %+v, want Move Store.String", actions)
+	}
+	if actions := actionAt("return strings", core.CodeActionTriggerKindInvoked); len(actions) != 0 {
+		t.Errorf("actions = %+v in a function body, want none", actions)
+	}
+	if actions := actionAt("a = 1", core.CodeActionTriggerKindInvoked); len(actions) != 0 {
+		t.Errorf("actions = %+v in a group of declarations, want none", actions)
+	}
+	if actions := actionAt("Key(key", core.CodeActionTriggerKindAutomatic); len(actions) != 0 {
+		t.Errorf("actions = %+v when triggered automatically, want none", actions)
+	}
+}
+
+func TestGoMoveDeclarationProviderExisting(t *testing.T) {
+	var options []string
+	provider := &GoMoveDeclarationProvider{
+		PackageFiles: movePackageFiles,
+		Choose: func(message string, choices []string) (string, bool) {
+			options = choices
+			return "dump.go", true
+		},
+	}
+	edit, err := provider.ExecuteCommand(MoveDeclarationCommand, []interface{}{"file:///store/store.go", "Key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(options, ",") != "dump.go,New file key.go" {
+		t.Errorf("options = %v, want the files of the package and a new file", options)
+	}
+	if len(edit.DocumentChanges) != 2 {
+		t.Fatalf("document changes = %+v, want edits of two files", edit.DocumentChanges)
+	}
+
+	source := applyTextEdits(moveSource, edit.DocumentChanges[0].(core.TextDocumentEdit).Edits)
+	wantSource := `package store
+
+import (
+	"fmt"
+)
+
+// Store stores values.
+type Store struct{ m map[string]string }
+
+var (
+	a = 1
+	b = 2
+)
+
+func (s *Store) String() string { return fmt.Sprint(s.m) }
+`
+	if source != wantSource {
+		t.Errorf("source =\n%s\nwant\n%s", source, wantSource)
+	}
+
+	targetEdit := edit.DocumentChanges[1].(core.TextDocumentEdit)
+	if targetEdit.TextDocument.URI != "file:///store/dump.go" {
+		t.Errorf("target = %s, want dump.go", targetEdit.TextDocument.URI)
+	}
+	target := applyTextEdits(moveTarget, targetEdit.Edits)
+	wantTarget := `package store
+
+import "fmt"
+import "strings"
+
+func dump(s *Store) { fmt.Println(s) }
+
+// Key normalizes a key.
+func Key(key string) string {
+	return strings.ToLower(key)
+}
+`
+	if target != wantTarget {
+		t.Errorf("target =\n%s\nwant\n%s", target, wantTarget)
+	}
+
+	// Canceling the choice moves nothing
+	provider.Choose = func(string, []string) (string, bool) { return "", false }
+	if edit, err := provider.ExecuteCommand(MoveDeclarationCommand, []interface{}{"file:///store/store.go", "Key"}); edit != nil || err != nil {
+		t.Errorf("ExecuteCommand = %+v, %v after canceling, want nothing", edit, err)
+	}
+}
+
+func TestGoMoveDeclarationProviderNewFile(t *testing.T) {
+	provider := &GoMoveDeclarationProvider{PackageFiles: movePackageFiles}
+	edit, err := provider.ExecuteCommand(MoveDeclarationCommand, []interface{}{"file:///store/store.go", "Store.String", "file:///store/store_string.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edit.DocumentChanges) != 3 {
+		t.Fatalf("document changes = %+v, want a source edit, a file creation, and its content", edit.DocumentChanges)
+	}
+	if create, ok := edit.DocumentChanges[1].(core.CreateFile); !ok || create.URI != "file:///store/store_string.go" {
+		t.Errorf("document change = %+v, want the creation of store_string.go", edit.DocumentChanges[1])
+	}
+	content := applyTextEdits("", edit.DocumentChanges[2].(core.TextDocumentEdit).Edits)
+	want := `package store
+
+import (
+	"fmt"
+)
+
+func (s *Store) String() string { return fmt.Sprint(s.m) }
+`
+	if content != want {
+		t.Errorf("new file =\n%s\nwant\n%s", content, want)
+	}
+
+	// Only the method used fmt, and the source ends without a blank line
+	source := applyTextEdits(moveSource, edit.DocumentChanges[0].(core.TextDocumentEdit).Edits)
+	if strings.Contains(source, `"fmt"`) || !strings.Contains(source, `"strings"`) || !strings.HasSuffix(source, "\tb = 2\n)\n") {
+		t.Errorf("source =\n%s", source)
+	}
+
+	if _, err := provider.ExecuteCommand(MoveDeclarationCommand, []interface{}{"file:///store/store.go", "Key", "file:///store/other.go"}); err == nil {
+		t.Error("moving to a file of another package succeeded")
+	}
+}
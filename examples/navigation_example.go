@@ -2,24 +2,26 @@ package examples
 
 import (
 	"go/ast"
-	"go/parser"
 	"go/token"
+	"go/types"
 	"strings"
 
 	"github.com/SCKelemen/lsp/core"
-	"github.com/SCKelemen/unicode/uax29"
 )
 
 // SimpleHoverProvider provides hover information for Go code.
-type SimpleHoverProvider struct{}
+type SimpleHoverProvider struct {
+	// TypeParams renders the type parameters of generic functions and
+	// types.
+	TypeParams TypeParamRenderer
+}
 
 func (p *SimpleHoverProvider) ProvideHover(uri, content string, position core.Position) *core.HoverInfo {
 	if !strings.HasSuffix(uri, ".go") {
 		return nil
 	}
 
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	fset, f, err := parseGoFile(uri, content)
 	if err != nil {
 		return nil
 	}
@@ -31,9 +33,58 @@ func (p *SimpleHoverProvider) ProvideHover(uri, content string, position core.Po
 		return nil
 	}
 
+	if ident, ok := node.(*ast.Ident); ok {
+		if hover := p.hoverForSelector(f, fset, ident); hover != nil {
+			return hover
+		}
+	}
 	return p.generateHover(f, fset, node, content)
 }
 
+// hoverForSelector returns the hover of the field or method selected by
+// ident, as in x.Name, noting the embedded fields it is promoted through,
+// or nil if ident selects nothing of a known type.
+func (p *SimpleHoverProvider) hoverForSelector(f *ast.File, fset *token.FileSet, ident *ast.Ident) *core.HoverInfo {
+	var selector *ast.SelectorExpr
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok && sel.Sel == ident {
+			selector = sel
+		}
+		return selector == nil
+	})
+	if selector == nil {
+		return nil
+	}
+	info, pkg, _ := typeCheckGoFileErrors(fset, f)
+	selection := info.Selections[selector]
+	if selection == nil {
+		return nil
+	}
+
+	var hoverText strings.Builder
+	hoverText.WriteString("```go\n")
+	hoverText.WriteString(types.ObjectString(selection.Obj(), types.RelativeTo(pkg)))
+	hoverText.WriteString("\n```")
+	if path := selectionPath(selection); len(path) > 0 {
+		hoverText.WriteString("\n\nPromoted through embedded `")
+		hoverText.WriteString(strings.Join(path, "."))
+		hoverText.WriteString("`")
+	}
+
+	identStart := fset.Position(ident.Pos())
+	identEnd := fset.Position(ident.End())
+
+	r := core.Range{
+		Start: core.Position{Line: identStart.Line - 1, Character: identStart.Column - 1},
+		End:   core.Position{Line: identEnd.Line - 1, Character: identEnd.Column - 1},
+	}
+
+	return &core.HoverInfo{
+		Contents: hoverText.String(),
+		Range:    &r,
+	}
+}
+
 func (p *SimpleHoverProvider) findNodeAtOffset(f *ast.File, fset *token.FileSet, offset int) ast.Node {
 	var found ast.Node
 	var foundSize int = -1
@@ -97,6 +148,7 @@ func (p *SimpleHoverProvider) hoverForIdent(f *ast.File, fset *token.FileSet, id
 			if node.Name.Name == ident.Name {
 				hoverText.WriteString("type ")
 				hoverText.WriteString(node.Name.Name)
+				hoverText.WriteString(p.TypeParams.TypeParams(node.TypeParams, p.exprString))
 				hoverText.WriteString(" ")
 				hoverText.WriteString(p.exprString(node.Type))
 				found = true
@@ -176,6 +228,7 @@ func (p *SimpleHoverProvider) hoverForType(fset *token.FileSet, typeSpec *ast.Ty
 	hoverText.WriteString("```go\n")
 	hoverText.WriteString("type ")
 	hoverText.WriteString(typeSpec.Name.Name)
+	hoverText.WriteString(p.TypeParams.TypeParams(typeSpec.TypeParams, p.exprString))
 	hoverText.WriteString(" ")
 	hoverText.WriteString(p.exprString(typeSpec.Type))
 	hoverText.WriteString("\n```")
@@ -211,6 +264,7 @@ func (p *SimpleHoverProvider) funcSignature(fn *ast.FuncDecl) string {
 	}
 
 	sig.WriteString(fn.Name.Name)
+	sig.WriteString(p.TypeParams.TypeParams(fn.Type.TypeParams, p.exprString))
 	sig.WriteString("(")
 
 	if fn.Type.Params != nil {
@@ -268,6 +322,10 @@ func (p *SimpleHoverProvider) exprString(expr ast.Expr) string {
 		return "map[" + p.exprString(e.Key) + "]" + p.exprString(e.Value)
 	case *ast.SelectorExpr:
 		return p.exprString(e.X) + "." + e.Sel.Name
+	case *ast.IndexExpr:
+		return instantiatedTypeString(e.X, []ast.Expr{e.Index}, p.exprString)
+	case *ast.IndexListExpr:
+		return instantiatedTypeString(e.X, e.Indices, p.exprString)
 	case *ast.FuncType:
 		return "func(...)"
 	default:
@@ -283,8 +341,7 @@ func (p *SimpleDefinitionProvider) ProvideDefinition(uri, content string, positi
 		return nil
 	}
 
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", content, 0)
+	fset, f, err := parseGoFile(uri, content)
 	if err != nil {
 		return nil
 	}
@@ -371,116 +428,11 @@ func (p *SimpleDefinitionProvider) findDefinition(f *ast.File, fset *token.FileS
 	}
 }
 
-// MarkedStringHoverProvider provides hover with marked strings.
+// MarkedStringHoverProvider provides hover with marked strings for Go
+// keywords. It treats every document as Go; use KeywordDocsProvider to
+// serve several languages.
 type MarkedStringHoverProvider struct{}
 
 func (p *MarkedStringHoverProvider) ProvideHover(uri, content string, position core.Position) *core.HoverInfo {
-	// Simple example: hover over specific keywords
-	lines := strings.Split(content, "\n")
-	if position.Line >= len(lines) {
-		return nil
-	}
-
-	line := lines[position.Line]
-	if position.Character >= len(line) {
-		return nil
-	}
-
-	// Find word at position
-	word := p.getWordAtPosition(line, position.Character)
-	if word == "" {
-		return nil
-	}
-
-	// Provide hover for Go keywords
-	hoverText := p.getKeywordHover(word)
-	if hoverText == "" {
-		return nil
-	}
-
-	// Find word range using UAX29
-	breaks := uax29.FindWordBreaks(line)
-	wordStart := position.Character
-	wordEnd := position.Character
-
-	for i := 0; i < len(breaks)-1; i++ {
-		start := breaks[i]
-		end := breaks[i+1]
-
-		if position.Character >= start && position.Character < end {
-			wordStart = start
-			wordEnd = end
-			break
-		}
-	}
-
-	r := core.Range{
-		Start: core.Position{Line: position.Line, Character: wordStart},
-		End:   core.Position{Line: position.Line, Character: wordEnd},
-	}
-
-	return &core.HoverInfo{
-		Contents: hoverText,
-		Range:    &r,
-	}
-}
-
-func (p *MarkedStringHoverProvider) getWordAtPosition(line string, pos int) string {
-	if pos >= len(line) {
-		return ""
-	}
-
-	// Get all word boundaries in the line using UAX29
-	breaks := uax29.FindWordBreaks(line)
-	if len(breaks) < 2 {
-		return ""
-	}
-
-	// Find which word boundary the position falls into
-	for i := 0; i < len(breaks)-1; i++ {
-		start := breaks[i]
-		end := breaks[i+1]
-
-		if pos >= start && pos < end {
-			// Check if this segment is actually a word (not whitespace or punctuation)
-			word := line[start:end]
-			if len(strings.TrimSpace(word)) == 0 {
-				return "" // Just whitespace
-			}
-
-			// Check if it contains at least one alphanumeric character
-			hasAlphaNum := false
-			for _, r := range word {
-				if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r > 127 {
-					hasAlphaNum = true
-					break
-				}
-			}
-			if !hasAlphaNum {
-				return "" // Just punctuation
-			}
-
-			return word
-		}
-	}
-
-	return ""
-}
-
-func (p *MarkedStringHoverProvider) getKeywordHover(word string) string {
-	keywords := map[string]string{
-		"func":      "```go\nfunc\n```\n\nDefines a function",
-		"var":       "```go\nvar\n```\n\nDeclares a variable",
-		"const":     "```go\nconst\n```\n\nDeclares a constant",
-		"type":      "```go\ntype\n```\n\nDefines a type",
-		"interface": "```go\ninterface\n```\n\nDefines an interface type",
-		"struct":    "```go\nstruct\n```\n\nDefines a struct type",
-		"package":   "```go\npackage\n```\n\nDeclares the package name",
-		"import":    "```go\nimport\n```\n\nImports packages",
-		"return":    "```go\nreturn\n```\n\nReturns from a function",
-		"if":        "```go\nif\n```\n\nConditional statement",
-		"for":       "```go\nfor\n```\n\nLoop statement",
-	}
-
-	return keywords[word]
+	return NewKeywordDocsProvider().hover("go", content, position)
 }
@@ -712,3 +712,23 @@ func main() {
 		}
 	}
 }
+
+func TestSimpleHoverProvider_PromotedMembers(t *testing.T) {
+	content := strings.Replace(embeddingSource, "_ = u.", "_ = u.ID", 1)
+	hover := func(marker string) string {
+		t.Helper()
+		position := core.ByteOffsetToPosition(content, strings.Index(content, marker)+len("u."))
+		info := (&SimpleHoverProvider{}).ProvideHover("file:///main.go", content, position)
+		if info == nil {
+			t.Fatalf("no hover on %s", marker)
+		}
+		return info.Contents
+	}
+
+	if got, want := hover("u.Log"), "```go\nfunc (*Logger).Log(msg string)\n```\n\nPromoted through embedded `Base.Logger`"; got != want {
+		t.Errorf("hover =\n%s\nwant\n%s", got, want)
+	}
+	if got, want := hover("u.ID"), "```go\nfield ID int\n```\n\nPromoted through embedded `Base`"; got != want {
+		t.Errorf("hover =\n%s\nwant\n%s", got, want)
+	}
+}
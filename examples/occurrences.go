@@ -0,0 +1,230 @@
+package examples
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/unicode/uax29"
+)
+
+// occurrence is where a symbol occurs in a document, as byte offsets.
+type occurrence struct {
+	start, end int
+
+	// declaration is set where the symbol is declared. Word occurrences
+	// are never declarations.
+	declaration bool
+}
+
+// occurrenceSymbol is the symbol whose occurrences findOccurrences found.
+type occurrenceSymbol struct {
+	name string
+
+	// local is set for symbols only visible in their file, like local
+	// variables and imported package names. The same name in another
+	// file is another symbol.
+	local bool
+}
+
+// findOccurrences is the occurrence engine shared by highlights,
+// references, rename, and reference counts, so that they agree on what an
+// occurrence of a symbol is. It returns the symbol at position and its
+// occurrences in the document, in order.
+//
+// In a Go file that parses, the occurrences of an identifier are those
+// referring to the same object, as resolved by typeCheckGoFile, so a
+// shadowed variable and a field of the same name are other symbols.
+// Identifiers the type checker cannot resolve, like the members of
+// imported packages, match the identifiers of the same name that are not
+// resolved either. Elsewhere, like in other languages, in comments, and in
+// Go files with syntax errors, occurrences are the words equal to the word
+// at position, by Unicode word boundaries.
+func findOccurrences(uri, content string, position core.Position) (occurrenceSymbol, []occurrence) {
+	if strings.HasSuffix(uri, ".go") {
+		if symbol, occurrences, ok := goOccurrences(uri, content, position); ok {
+			return symbol, occurrences
+		}
+	}
+	word, _ := core.WordAt(content, position)
+	if word == "" {
+		return occurrenceSymbol{}, nil
+	}
+	return occurrenceSymbol{name: word}, wordOccurrences(content, word)
+}
+
+// findNameOccurrences returns the occurrences of a symbol found with
+// findOccurrences in another document: the identifiers of the name in a Go
+// file that parses, and the words equal to it otherwise. Local symbols do
+// not occur in other documents.
+func findNameOccurrences(uri, content string, symbol occurrenceSymbol) []occurrence {
+	if symbol.local || symbol.name == "" {
+		return nil
+	}
+	if !strings.HasSuffix(uri, ".go") {
+		return wordOccurrences(content, symbol.name)
+	}
+	fset, f, err := parseGoFile(uri, content)
+	if err != nil {
+		return wordOccurrences(content, symbol.name)
+	}
+	declarations := goDeclarationOffsets(uri, content)
+	var occurrences []occurrence
+	ast.Inspect(f, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ident.Name == symbol.name {
+			start := fset.Position(ident.Pos()).Offset
+			occurrences = append(occurrences, occurrence{
+				start:       start,
+				end:         start + len(ident.Name),
+				declaration: declarations[start],
+			})
+		}
+		return true
+	})
+	return occurrences
+}
+
+// goOccurrences finds the occurrences of the identifier at position in a
+// Go file. ok is false if the file does not parse or position is not on an
+// identifier.
+func goOccurrences(uri, content string, position core.Position) (symbol occurrenceSymbol, occurrences []occurrence, ok bool) {
+	fset, f, err := parseGoFile(uri, content)
+	if err != nil {
+		return occurrenceSymbol{}, nil, false
+	}
+	offset := core.PositionToByteOffset(content, position)
+	if offset < 0 {
+		return occurrenceSymbol{}, nil, false
+	}
+
+	// The identifier under the cursor, or else the one just before it
+	var target *ast.Ident
+	ast.Inspect(f, func(n ast.Node) bool {
+		if ident, isIdent := n.(*ast.Ident); isIdent {
+			start := fset.Position(ident.Pos()).Offset
+			end := start + len(ident.Name)
+			if start <= offset && offset < end || target == nil && offset == end {
+				target = ident
+			}
+		}
+		return true
+	})
+	if target == nil || target.Name == "_" || target == f.Name {
+		return occurrenceSymbol{}, nil, false
+	}
+
+	info := typeCheckGoFile(fset, f)
+	objectOf := func(ident *ast.Ident) types.Object {
+		if obj := info.Defs[ident]; obj != nil {
+			return obj
+		}
+		return info.Uses[ident]
+	}
+	obj := objectOf(target)
+
+	ast.Inspect(f, func(n ast.Node) bool {
+		ident, isIdent := n.(*ast.Ident)
+		if !isIdent || ident.Name != target.Name || ident == f.Name || objectOf(ident) != obj {
+			return true
+		}
+		start := fset.Position(ident.Pos()).Offset
+		occurrences = append(occurrences, occurrence{
+			start:       start,
+			end:         start + len(ident.Name),
+			declaration: info.Defs[ident] != nil,
+		})
+		return true
+	})
+	return occurrenceSymbol{name: target.Name, local: isLocalObject(obj)}, occurrences, true
+}
+
+// isLocalObject reports whether an object is only visible in its file:
+// declared in a function or a file scope, or a label. Fields and methods
+// have no scope and are not local, nor are unresolved identifiers.
+func isLocalObject(obj types.Object) bool {
+	if obj == nil || obj.Pkg() == nil {
+		return false
+	}
+	if _, ok := obj.(*types.Label); ok {
+		return true
+	}
+	return obj.Parent() != nil && obj.Parent() != obj.Pkg().Scope()
+}
+
+// wordOccurrences returns the words of content equal to word, by Unicode
+// word boundaries.
+func wordOccurrences(content, word string) []occurrence {
+	breaks := uax29.FindWordBreaks(content)
+	var occurrences []occurrence
+	for i := 0; i+1 < len(breaks); i++ {
+		if content[breaks[i]:breaks[i+1]] == word {
+			occurrences = append(occurrences, occurrence{start: breaks[i], end: breaks[i+1]})
+		}
+	}
+	return occurrences
+}
+
+// rangeOf returns the range of an occurrence in content.
+func (o occurrence) rangeOf(content string) core.Range {
+	return core.Range{
+		Start: core.ByteOffsetToPosition(content, o.start),
+		End:   core.ByteOffsetToPosition(content, o.end),
+	}
+}
+
+// goDeclarationOffsets returns the offsets of the identifiers a Go file
+// declares: the names of declarations, fields, parameters, and labels, and
+// the variables of := assignments. It returns nil for other files and for
+// Go files that do not parse.
+func goDeclarationOffsets(uri, content string) map[int]bool {
+	if !strings.HasSuffix(uri, ".go") {
+		return nil
+	}
+	fset, f, err := parseGoFile(uri, content)
+	if err != nil {
+		return nil
+	}
+
+	offsets := make(map[int]bool)
+	declare := func(exprs ...ast.Expr) {
+		for _, expr := range exprs {
+			if ident, ok := expr.(*ast.Ident); ok && ident.Name != "_" {
+				offsets[fset.Position(ident.Pos()).Offset] = true
+			}
+		}
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			declare(n.Name)
+		case *ast.TypeSpec:
+			declare(n.Name)
+		case *ast.ValueSpec:
+			for _, name := range n.Names {
+				declare(name)
+			}
+		case *ast.Field:
+			for _, name := range n.Names {
+				declare(name)
+			}
+		case *ast.ImportSpec:
+			if n.Name != nil {
+				declare(n.Name)
+			}
+		case *ast.LabeledStmt:
+			declare(n.Label)
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				declare(n.Lhs...)
+			}
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				declare(n.Key, n.Value)
+			}
+		}
+		return true
+	})
+	return offsets
+}
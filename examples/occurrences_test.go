@@ -0,0 +1,101 @@
+package examples
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+const occurrencesSource = `package main
+
+// Count counts, see count.
+func Count(items []string) int {
+	count := 0
+	for range items {
+		count++
+	}
+	{
+		count := "shadowed"
+		_ = count
+	}
+	return count
+}
+
+func main() { println(Count(nil)) }
+`
+
+// occurrenceLines returns the lines of ranges, in order.
+func occurrenceLines(ranges []core.Range) []int {
+	var lines []int
+	for _, r := range ranges {
+		lines = append(lines, r.Start.Line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+func TestOccurrencesAgreeAcrossFeatures(t *testing.T) {
+	uri := "file:///main.go"
+	position := core.ByteOffsetToPosition(occurrencesSource, strings.Index(occurrencesSource, "count := 0"))
+
+	var highlighted []core.Range
+	for _, h := range (&SimpleHighlightProvider{}).ProvideDocumentHighlights(core.DocumentHighlightContext{URI: uri, Content: occurrencesSource, Position: position}) {
+		highlighted = append(highlighted, h.Range)
+	}
+	var referenced []core.Range
+	for _, location := range (&SimpleReferencesProvider{}).FindReferences(uri, occurrencesSource, position, core.ReferenceContext{IncludeDeclaration: true}) {
+		referenced = append(referenced, location.Range)
+	}
+	var renamed []core.Range
+	for _, edit := range (&GoRenameProvider{}).ProvideRename(core.RenameContext{URI: uri, Content: occurrencesSource, Position: position, NewName: "n"}).Changes[uri] {
+		renamed = append(renamed, edit.Range)
+	}
+
+	// The shadowing variable and the comment are not occurrences
+	want := "[4 6 12]"
+	for name, ranges := range map[string][]core.Range{"highlights": highlighted, "references": referenced, "rename": renamed} {
+		if got := fmt.Sprint(occurrenceLines(ranges)); got != want {
+			t.Errorf("%s on lines %s, want %s", name, got, want)
+		}
+	}
+
+	// Counted references leave out the declaration
+	if count := referenceCount(uri, occurrencesSource, strings.Index(occurrencesSource, "Count(items")); count != 1 {
+		t.Errorf("references of Count = %d, want 1", count)
+	}
+}
+
+func TestOccurrencesAcrossFiles(t *testing.T) {
+	provider := &MultiFileRenameProvider{
+		Files: map[string]string{
+			"file:///main.go":  occurrencesSource,
+			"file:///other.go": "package main\n\nfunc other() int {\n\tcount := Count(nil)\n\treturn count\n}\n",
+			"file:///README":   "Count counts the items.\n",
+		},
+	}
+	rename := func(marker string) map[string]int {
+		edit := provider.ProvideRename(core.RenameContext{
+			URI:      "file:///main.go",
+			Content:  occurrencesSource,
+			Position: core.ByteOffsetToPosition(occurrencesSource, strings.Index(occurrencesSource, marker)),
+			NewName:  "x",
+		})
+		counts := make(map[string]int)
+		for uri, edits := range edit.Changes {
+			counts[uri] = len(edits)
+		}
+		return counts
+	}
+
+	// A local variable is another symbol in other files
+	if counts := rename("count := 0"); len(counts) != 1 || counts["file:///main.go"] != 3 {
+		t.Errorf("edits = %v, want 3 in main.go only", counts)
+	}
+	// A package-level function occurs by name in the package and in text
+	if counts := rename("Count(items"); counts["file:///main.go"] != 2 || counts["file:///other.go"] != 1 || counts["file:///README"] != 1 {
+		t.Errorf("edits = %v, want 2 in main.go, 1 in other.go, and 1 in README", counts)
+	}
+}
@@ -0,0 +1,121 @@
+package examples
+
+import (
+	"errors"
+	"go/scanner"
+	"go/token"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// Diagnostic codes of the syntax errors reported by
+// GoParseDiagnosticsProvider.
+const (
+	// ExpectedTokenCode is the code of errors about a missing or unexpected
+	// token, like "expected ';', found 'EOF'".
+	ExpectedTokenCode = "expected-token"
+
+	// IllegalCharacterCode is the code of characters that cannot appear in
+	// Go source outside literals and comments.
+	IllegalCharacterCode = "illegal-character"
+
+	// UnterminatedCode is the code of string, rune, and comment literals
+	// that are not terminated.
+	UnterminatedCode = "unterminated"
+
+	// SyntaxErrorCode is the code of other syntax errors.
+	SyntaxErrorCode = "syntax"
+)
+
+// GoParseDiagnosticsProvider reports the syntax errors of Go files.
+//
+// The other Go providers in this package give up on files that do not
+// parse; this provider tells the user why. Each error covers the token it
+// is reported at, or is empty at the end of a line or the file. Register
+// it with a core.DiagnosticRegistry to report errors as the user types:
+// the parse is shared with the other providers through DefaultGoASTCache.
+type GoParseDiagnosticsProvider struct{}
+
+func (p *GoParseDiagnosticsProvider) ProvideDiagnostics(uri, content string) []core.Diagnostic {
+	if !strings.HasSuffix(uri, ".go") {
+		return nil
+	}
+	_, _, err := parseGoFile(uri, content)
+	var errs scanner.ErrorList
+	if !errors.As(err, &errs) {
+		return nil
+	}
+
+	tokenEnds := scanTokenEnds(content)
+	severity := core.SeverityError
+	diagnostics := make([]core.Diagnostic, 0, len(errs))
+	for _, e := range errs {
+		start := min(e.Pos.Offset, len(content))
+		end, ok := tokenEnds[start]
+		if !ok {
+			// Inside a token, like a bad escape in a string
+			_, size := utf8.DecodeRuneInString(content[start:])
+			end = start + size
+		}
+		code := core.NewStringCode(parseErrorCode(e.Msg))
+		diagnostics = append(diagnostics, core.Diagnostic{
+			Range: core.Range{
+				Start: core.ByteOffsetToPosition(content, start),
+				End:   core.ByteOffsetToPosition(content, end),
+			},
+			Severity: &severity,
+			Code:     &code,
+			Source:   "go/parser",
+			Message:  e.Msg,
+		})
+	}
+	return diagnostics
+}
+
+// scanTokenEnds returns the end offsets of the tokens of Go source by their
+// start offsets. Automatic semicolons and the end of the file are empty.
+func scanTokenEnds(src string) map[int]int {
+	file := addScanFile(len(src))
+	defer removeScanFile(file)
+
+	buf := borrowSource(src)
+	defer returnSource(buf)
+
+	var s scanner.Scanner
+	s.Init(file, *buf, nil, 0)
+
+	ends := make(map[int]int)
+	for {
+		pos, tok, lit := s.Scan()
+		offset := file.Offset(pos)
+		switch {
+		case tok == token.EOF:
+			ends[offset] = offset
+			return ends
+		case tok == token.SEMICOLON && lit != ";":
+			ends[offset] = offset
+		case lit != "":
+			// The scanner strips carriage returns from raw strings
+			ends[offset] = min(offset+len(lit), len(src))
+		default:
+			ends[offset] = offset + len(tok.String())
+		}
+	}
+}
+
+// parseErrorCode returns the diagnostic code for a go/parser or go/scanner
+// error message.
+func parseErrorCode(msg string) string {
+	switch {
+	case strings.HasPrefix(msg, "expected ") || strings.Contains(msg, ", expected "):
+		return ExpectedTokenCode
+	case strings.HasPrefix(msg, "illegal character"):
+		return IllegalCharacterCode
+	case strings.HasSuffix(msg, "not terminated"):
+		return UnterminatedCode
+	default:
+		return SyntaxErrorCode
+	}
+}
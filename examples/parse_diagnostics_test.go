@@ -0,0 +1,80 @@
+package examples
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGoParseDiagnosticsProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantText string // text covered by the first diagnostic, on one line
+		wantCode string
+		wantLine int
+	}{
+		{
+			name:     "missing operand",
+			content:  "package main\n\nfunc main() {\n\tx := 1 +\n}\n",
+			wantText: "}",
+			wantCode: ExpectedTokenCode,
+			wantLine: 4,
+		},
+		{
+			name:     "missing condition",
+			content:  "package main\n\nfunc main() {\n\tif x := 1; {\n\t}\n}\n",
+			wantText: ";",
+			wantCode: SyntaxErrorCode,
+			wantLine: 3,
+		},
+		{
+			name:     "illegal character",
+			content:  "package main\n\nvar s = 1 # 2\n",
+			wantText: "#",
+			wantCode: IllegalCharacterCode,
+			wantLine: 2,
+		},
+		{
+			name:     "unterminated string",
+			content:  "package main\n\nvar s = \"héllo\n",
+			wantText: "\"héllo",
+			wantCode: UnterminatedCode,
+			wantLine: 2,
+		},
+		{
+			name:     "missing closing brace",
+			content:  "package main\n\nfunc main() {\n",
+			wantText: "",
+			wantCode: ExpectedTokenCode,
+			wantLine: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri := "file:///parse_" + strings.ReplaceAll(tt.name, " ", "_") + ".go"
+			diagnostics := (&GoParseDiagnosticsProvider{}).ProvideDiagnostics(uri, tt.content)
+			if len(diagnostics) == 0 {
+				t.Fatal("expected diagnostics")
+			}
+			d := diagnostics[0]
+			if d.Code == nil || d.Code.StringValue != tt.wantCode {
+				t.Errorf("%s: got code %v, want %s", d.Message, d.Code, tt.wantCode)
+			}
+			if d.Range.Start.Line != tt.wantLine {
+				t.Errorf("%s: got line %d, want %d", d.Message, d.Range.Start.Line, tt.wantLine)
+			}
+			if d.Range.End.Line != d.Range.Start.Line {
+				t.Fatalf("%s: expected a range on one line, got %v", d.Message, d.Range)
+			}
+			line := strings.Split(tt.content, "\n")[d.Range.Start.Line]
+			if got := line[d.Range.Start.Character:d.Range.End.Character]; got != tt.wantText {
+				t.Errorf("%s: covers %q, want %q", d.Message, got, tt.wantText)
+			}
+		})
+	}
+
+	if diagnostics := (&GoParseDiagnosticsProvider{}).ProvideDiagnostics("file:///parse_ok.go", "package main\n"); diagnostics != nil {
+		t.Errorf("expected no diagnostics for a valid file, got %v", diagnostics)
+	}
+}
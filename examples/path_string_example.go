@@ -0,0 +1,217 @@
+package examples
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+	uripkg "github.com/SCKelemen/lsp/uri"
+	"github.com/SCKelemen/lsp/workspace"
+)
+
+// PathStringProvider provides hover, go-to-definition, and completion for
+// file system paths in string literals, in any language, like
+// "./config/app.yaml" or "testdata/input.txt". It complements
+// FilePathLinkProvider, which makes the same paths clickable.
+//
+// Paths starting with ./ or ../ are relative to the directory of the
+// document, other relative paths to WorkspaceRoot. Hover tells whether the
+// path exists and its size, definition jumps to the file, and completion
+// offers the files and directories of the directory being typed, as found
+// by a workspace.Walker, so ignored files are not offered. Absolute paths
+// and paths leaving the workspace are not completed.
+type PathStringProvider struct {
+	// WorkspaceRoot is the root directory of the workspace. If empty,
+	// relative paths are relative to the directory of the document.
+	WorkspaceRoot string
+
+	// Guard, when set, restricts the paths looked up to its roots, so
+	// hovering "../../etc/passwd" does not tell whether it exists.
+	Guard *workspace.Guard
+}
+
+func (p *PathStringProvider) ProvideHover(uri, content string, position core.Position) *core.HoverInfo {
+	path, valueRange, ok := p.pathAt(content, position)
+	if !ok {
+		return nil
+	}
+	target, err := p.Guard.CheckPath(p.resolve(uri, path))
+	if err != nil {
+		return nil
+	}
+
+	var description string
+	info, err := os.Stat(target)
+	switch {
+	case err != nil:
+		description = "does not exist"
+	case info.IsDir():
+		description = "is a directory"
+	default:
+		description = "is a file of " + formatFileSize(info.Size())
+	}
+	return &core.HoverInfo{
+		Contents: fmt.Sprintf("`%s` %s", path, description),
+		Range:    &valueRange,
+	}
+}
+
+func (p *PathStringProvider) ProvideDefinition(uri, content string, position core.Position) []core.Location {
+	path, _, ok := p.pathAt(content, position)
+	if !ok {
+		return nil
+	}
+	target, err := p.Guard.CheckPath(p.resolve(uri, path))
+	if err != nil {
+		return nil
+	}
+	if info, err := os.Stat(target); err != nil || info.IsDir() {
+		return nil
+	}
+	return []core.Location{{URI: uripkg.FromPath(target)}}
+}
+
+func (p *PathStringProvider) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
+	literal, ok := core.LiteralAt(ctx.Content, ctx.Position)
+	if !ok || literal.Kind != core.LiteralString {
+		return nil
+	}
+	valueStart := core.PositionToByteOffset(ctx.Content, literal.Range.Start) + 1
+	cursor := core.PositionToByteOffset(ctx.Content, ctx.Position)
+	if cursor < valueStart || cursor > valueStart+len(literal.Value) {
+		return nil
+	}
+
+	// Complete the segment after the last slash typed, in the directory
+	// before it
+	typed := ctx.Content[valueStart:cursor]
+	slash := strings.LastIndex(typed, "/")
+	if slash < 0 || strings.Contains(typed, "://") || strings.ContainsAny(typed, " \t") {
+		return nil
+	}
+	dir := p.resolve(ctx.URI, typed[:slash+1])
+	prefix := typed[slash+1:]
+
+	// Walking is recursive, so only directories of the workspace are
+	// walked, not the root of the file system
+	if filepath.IsAbs(typed) {
+		return nil
+	}
+	if p.WorkspaceRoot != "" {
+		if rel, err := filepath.Rel(p.WorkspaceRoot, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	if _, err := p.Guard.CheckPath(dir); err != nil {
+		return nil
+	}
+
+	// The walk starts at the workspace root, so that the ignore files
+	// of the directories above dir apply
+	walkRoot := p.WorkspaceRoot
+	if walkRoot == "" {
+		walkRoot = dir
+	}
+	segments := make(map[string]bool) // by name, whether a directory
+	walker := workspace.NewWalker(walkRoot)
+	walker.Guard = p.Guard
+	err := walker.Walk(func(path string, info fs.FileInfo) error {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+		name, _, isDir := strings.Cut(filepath.ToSlash(rel), "/")
+		if strings.HasPrefix(name, prefix) {
+			segments[name] = segments[name] || isDir
+		}
+		return nil
+	})
+	if err != nil || len(segments) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(segments))
+	for name := range segments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	segmentRange := core.Range{
+		Start: core.ByteOffsetToPosition(ctx.Content, valueStart+slash+1),
+		End:   ctx.Position,
+	}
+	items := make([]core.CompletionItem, 0, len(names))
+	for _, name := range names {
+		kind := core.CompletionItemKindFile
+		text := name
+		if segments[name] {
+			kind = core.CompletionItemKindFolder
+			text += "/"
+		}
+		items = append(items, core.CompletionItem{
+			Label:    name,
+			Kind:     &kind,
+			TextEdit: &core.TextEdit{Range: segmentRange, NewText: text},
+		})
+	}
+	return &core.CompletionList{Items: items}
+}
+
+// pathAt returns the path in the string literal at position, and the
+// range of the path, if the literal holds one.
+func (p *PathStringProvider) pathAt(content string, position core.Position) (string, core.Range, bool) {
+	literal, ok := core.LiteralAt(content, position)
+	if !ok || literal.Kind != core.LiteralString || !looksLikePath(literal.Value) {
+		return "", core.Range{}, false
+	}
+	start := core.PositionToByteOffset(content, literal.Range.Start) + 1
+	return literal.Value, core.Range{
+		Start: core.ByteOffsetToPosition(content, start),
+		End:   core.ByteOffsetToPosition(content, start+len(literal.Value)),
+	}, true
+}
+
+// looksLikePath reports whether a string is a file system path rather
+// than text: it has no spaces, is not a URL, and has a slash or a file
+// extension.
+func looksLikePath(s string) bool {
+	if s == "" || strings.ContainsAny(s, " \t\n") || strings.Contains(s, "://") {
+		return false
+	}
+	return strings.Contains(s, "/") || filePathRegexp.MatchString(s)
+}
+
+// filePathRegexp matches a file name with an extension, like "main.go".
+var filePathRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]+\.[a-zA-Z0-9]+$`)
+
+// resolve returns the file system path of a path in the document at uri.
+func (p *PathStringProvider) resolve(uri, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	base := p.WorkspaceRoot
+	if strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") || base == "" {
+		if documentPath, err := uripkg.ToPath(uri); err == nil {
+			base = filepath.Dir(documentPath)
+		}
+	}
+	return filepath.Join(base, filepath.FromSlash(path))
+}
+
+// formatFileSize returns a file size for people, like "1.5 KB".
+func formatFileSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	case n == 1:
+		return "1 byte"
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
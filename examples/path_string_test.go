@@ -0,0 +1,107 @@
+package examples
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+func TestPathStringProvider(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"config/app.yaml": "name: app\n",
+		"config/db.yaml":  "",
+		"config/env/dev":  "",
+		"config/skip.log": "",
+		".gitignore":      "*.log\n",
+		"src/main.py":     "",
+	})
+	uri := uripkg.FromPath(filepath.Join(root, "src", "main.py"))
+	content := `load("config/app.yaml")
+load("../config/db.yaml")
+load("config/missing.yaml")
+print("hello world")
+load("config/")
+`
+	provider := &PathStringProvider{WorkspaceRoot: root}
+	at := func(marker string, delta int) core.Position {
+		return core.ByteOffsetToPosition(content, strings.Index(content, marker)+delta)
+	}
+
+	hovers := map[string]string{
+		"config/app.yaml":     "`config/app.yaml` is a file of 10 bytes",
+		"../config/db.yaml":   "`../config/db.yaml` is a file of 0 bytes",
+		"config/missing.yaml": "`config/missing.yaml` does not exist",
+		"config/\"":           "`config/` is a directory",
+	}
+	for marker, want := range hovers {
+		hover := provider.ProvideHover(uri, content, at(marker, 1))
+		if hover == nil {
+			t.Errorf("no hover on %s", marker)
+			continue
+		}
+		if hover.Contents != want {
+			t.Errorf("hover on %s = %q, want %q", marker, hover.Contents, want)
+		}
+	}
+	if hover := provider.ProvideHover(uri, content, at("hello", 1)); hover != nil {
+		t.Errorf("hover on text = %q, want none", hover.Contents)
+	}
+
+	locations := provider.ProvideDefinition(uri, content, at("../config/db.yaml", 1))
+	if want := uripkg.FromPath(filepath.Join(root, "config", "db.yaml")); len(locations) != 1 || locations[0].URI != want {
+		t.Errorf("definition = %v, want %s", locations, want)
+	}
+	if locations := provider.ProvideDefinition(uri, content, at("config/missing.yaml", 1)); locations != nil {
+		t.Errorf("definition of a missing file = %v, want none", locations)
+	}
+}
+
+func TestPathStringProvider_Completion(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"config/app.yaml": "",
+		"config/env/dev":  "",
+		"config/skip.log": "",
+		".gitignore":      "*.log\n",
+	})
+	provider := &PathStringProvider{WorkspaceRoot: root}
+	complete := func(content string) []string {
+		t.Helper()
+		list := provider.ProvideCompletions(core.CompletionContext{
+			URI:      uripkg.FromPath(filepath.Join(root, "main.js")),
+			Content:  content,
+			Position: core.ByteOffsetToPosition(content, strings.Index(content, `")`)),
+		})
+		if list == nil {
+			return nil
+		}
+		var texts []string
+		for _, item := range list.Items {
+			texts = append(texts, item.TextEdit.NewText)
+		}
+		return texts
+	}
+
+	if got := strings.Join(complete(`open("config/")`), " "); got != "app.yaml env/" {
+		t.Errorf("completions = %s, want app.yaml env/ without ignored files", got)
+	}
+	if got := strings.Join(complete(`open("./config/e")`), " "); got != "env/" {
+		t.Errorf("completions = %s, want env/", got)
+	}
+	for _, content := range []string{`open("config")`, `open("/etc/")`, `open("../")`, `open("https://")`} {
+		if got := complete(content); got != nil {
+			t.Errorf("completions in %s = %v, want none", content, got)
+		}
+	}
+}
+
+func TestFormatFileSize(t *testing.T) {
+	tests := map[int64]string{0: "0 bytes", 1: "1 byte", 1536: "1.5 KB", 3 << 20: "3.0 MB"}
+	for n, want := range tests {
+		if got := formatFileSize(n); got != want {
+			t.Errorf("formatFileSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
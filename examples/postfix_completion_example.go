@@ -0,0 +1,261 @@
+package examples
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// PostfixTemplate expands an expression followed by a dot and the template
+// name, like "items.for", into a snippet built around the expression.
+type PostfixTemplate struct {
+	// Name is typed after the dot, like "for".
+	Name string
+
+	// Description is shown as the item detail.
+	Description string
+
+	// Body is the snippet replacing the expression. ${expr} stands for
+	// the expression.
+	Body string
+
+	// Applies reports whether the template fits an expression of type t.
+	// t is nil if the type is unknown, and the template is offered anyway.
+	// If Applies is nil, the template fits any expression.
+	Applies func(t types.Type) bool
+}
+
+// PostfixCompletionProvider provides postfix completions for Go.
+//
+// Typing "items.for" offers "for i := range items {}"; typing "err.if"
+// offers the error check returning the zero values of the enclosing
+// function's other results. The expression and the dot are deleted with
+// additional text edits, and the snippet replaces the template name.
+type PostfixCompletionProvider struct {
+	Templates []PostfixTemplate
+}
+
+// NewGoPostfixCompletionProvider creates a provider with the Go templates.
+func NewGoPostfixCompletionProvider() *PostfixCompletionProvider {
+	return &PostfixCompletionProvider{Templates: GoPostfixTemplates}
+}
+
+// GoPostfixTemplates are the default Go postfix templates.
+var GoPostfixTemplates = []PostfixTemplate{
+	{Name: "for", Description: "for range loop", Body: "for ${1:i} := range ${expr} {\n\t$0\n}", Applies: isRangeable},
+	{Name: "forr", Description: "for range loop with values", Body: "for ${1:i}, ${2:v} := range ${expr} {\n\t$0\n}", Applies: isRangeable},
+	{Name: "if", Description: "if statement", Body: "if ${expr} {\n\t$0\n}", Applies: isBoolean},
+	{Name: "not", Description: "negate", Body: "!${expr}", Applies: isBoolean},
+	{Name: "nil", Description: "nil check", Body: "if ${expr} == nil {\n\t$0\n}", Applies: isNillable},
+	{Name: "nn", Description: "not nil check", Body: "if ${expr} != nil {\n\t$0\n}", Applies: isNillable},
+	{Name: "var", Description: "declare variable", Body: "${1:v} := ${expr}"},
+	{Name: "ret", Description: "return", Body: "return ${expr}"},
+	{Name: "len", Description: "length", Body: "len(${expr})", Applies: hasLength},
+}
+
+func (p *PostfixCompletionProvider) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
+	if !strings.HasSuffix(ctx.URI, ".go") {
+		return nil
+	}
+
+	nameStart, cursor := completionWord(ctx.Content, ctx.Position)
+	if nameStart == 0 || ctx.Content[nameStart-1] != '.' {
+		return nil
+	}
+	exprEnd := nameStart - 1
+	exprStart := postfixExprStart(ctx.Content, exprEnd)
+	if exprStart == exprEnd {
+		return nil
+	}
+	if r, _ := utf8.DecodeRuneInString(ctx.Content[exprStart:]); unicode.IsDigit(r) {
+		// A number like 1.5
+		return nil
+	}
+	expr := ctx.Content[exprStart:exprEnd]
+	typed := ctx.Content[nameStart:cursor]
+
+	exprType, isPackage, errorReturn := p.analyze(ctx, exprStart, exprEnd, cursor)
+	if isPackage {
+		// fmt. completes package members
+		return nil
+	}
+
+	deleteExpr := core.TextEdit{
+		Range: core.Range{
+			Start: core.ByteOffsetToPosition(ctx.Content, exprStart),
+			End:   core.ByteOffsetToPosition(ctx.Content, nameStart),
+		},
+	}
+	nameRange := core.Range{
+		Start: core.ByteOffsetToPosition(ctx.Content, nameStart),
+		End:   core.ByteOffsetToPosition(ctx.Content, cursor),
+	}
+
+	templates := p.Templates
+	if isErrorType(exprType) || (exprType == nil && expr == "err") {
+		templates = append([]PostfixTemplate{{
+			Name:        "if",
+			Description: "error check",
+			Body:        "if ${expr} != nil {\n\t" + errorReturn + "\n}",
+		}}, templates...)
+	}
+
+	var items []core.CompletionItem
+	seen := map[string]bool{}
+	for _, template := range templates {
+		if seen[template.Name] || !strings.HasPrefix(template.Name, typed) {
+			continue
+		}
+		if template.Applies != nil && exprType != nil && !template.Applies(exprType) {
+			continue
+		}
+		seen[template.Name] = true
+
+		kind := core.CompletionItemKindSnippet
+		format := core.InsertTextFormatSnippet
+		body := strings.ReplaceAll(template.Body, "${expr}", escapeSnippet(expr))
+		items = append(items, core.CompletionItem{
+			Label:               template.Name,
+			Kind:                &kind,
+			Detail:              template.Description,
+			Documentation:       strings.ReplaceAll(template.Body, "${expr}", expr),
+			FilterText:          template.Name,
+			SortText:            "~" + template.Name, // after members of expr
+			InsertTextFormat:    &format,
+			InsertTextMode:      snippetInsertTextMode(body),
+			TextEdit:            &core.TextEdit{Range: nameRange, NewText: body},
+			AdditionalTextEdits: []core.TextEdit{deleteExpr},
+		})
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+	return &core.CompletionList{Items: items}
+}
+
+// analyze type-checks the document with the postfix removed and returns
+// the type of the expression, or nil if unknown, whether it names an
+// imported package, and the statement returning an error from the
+// enclosing function.
+func (p *PostfixCompletionProvider) analyze(ctx core.CompletionContext, exprStart, exprEnd, cursor int) (types.Type, bool, string) {
+	fallbackReturn := "return ${1:err}"
+
+	content := ctx.Content[:exprEnd] + ctx.Content[cursor:]
+	fset, f, err := parseGoFile(ctx.URI+"#postfix", content)
+	if err != nil {
+		return nil, false, fallbackReturn
+	}
+	info := typeCheckGoFile(fset, f)
+
+	var exprType types.Type
+	isPackage := false
+	path := goNodesEnclosing(fset, f, exprStart, exprEnd)
+	if len(path) > 0 {
+		if expr, ok := path[len(path)-1].(ast.Expr); ok &&
+			fset.Position(expr.Pos()).Offset == exprStart && fset.Position(expr.End()).Offset == exprEnd {
+			if ident, ok := expr.(*ast.Ident); ok {
+				_, isPackage = info.Uses[ident].(*types.PkgName)
+			}
+			if t := info.Types[expr].Type; t != nil && t != types.Typ[types.Invalid] {
+				exprType = t
+			}
+		}
+	}
+
+	ret := fallbackReturn
+	if stmt := goStatementAt(fset, f, exprStart); stmt != nil {
+		// \x00 marks the expression in the escaped statement
+		ret = escapeSnippet(errorReturn(info, stmt.fn, "\x00"))
+		ret = strings.ReplaceAll(ret, "\x00", "${expr}")
+	}
+	return exprType, isPackage, ret
+}
+
+// postfixExprStart returns the start of the expression ending at end: a
+// chain of identifiers, selectors, calls, index expressions, and literals.
+func postfixExprStart(content string, end int) int {
+	i := end
+	for i > 0 {
+		r, size := utf8.DecodeLastRuneInString(content[:i])
+		switch {
+		case r == ')' || r == ']' || r == '}':
+			open := matchingOpenBracket(content, i-1)
+			if open < 0 {
+				return i
+			}
+			i = open
+		case r == '"' || r == '`' || r == '\'':
+			open := strings.LastIndexByte(content[:i-1], byte(r))
+			if open < 0 || strings.Contains(content[open:i], "\n") && r != '`' {
+				return i
+			}
+			i = open
+		case r == '.' || isWordChar(r):
+			i -= size
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// matchingOpenBracket returns the offset of the bracket opening the one at
+// close, or -1.
+func matchingOpenBracket(content string, close int) int {
+	depth := 0
+	for i := close; i >= 0; i-- {
+		switch content[i] {
+		case ')', ']', '}':
+			depth++
+		case '(', '[', '{':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// escapeSnippet escapes text for literal use in a snippet.
+func escapeSnippet(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `$`, `\$`, `}`, `\}`).Replace(text)
+}
+
+func isRangeable(t types.Type) bool {
+	switch u := t.Underlying().(type) {
+	case *types.Slice, *types.Array, *types.Map, *types.Chan:
+		return true
+	case *types.Pointer:
+		_, ok := u.Elem().Underlying().(*types.Array)
+		return ok
+	case *types.Basic:
+		return u.Info()&(types.IsString|types.IsInteger) != 0
+	}
+	return false
+}
+
+func hasLength(t types.Type) bool {
+	if basic, ok := t.Underlying().(*types.Basic); ok {
+		return basic.Info()&types.IsString != 0
+	}
+	return isRangeable(t)
+}
+
+func isBoolean(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsBoolean != 0
+}
+
+func isNillable(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Pointer, *types.Slice, *types.Map, *types.Chan, *types.Signature, *types.Interface:
+		return true
+	}
+	return false
+}
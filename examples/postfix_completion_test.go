@@ -0,0 +1,116 @@
+package examples
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+func TestPostfixCompletionProvider(t *testing.T) {
+	provider := NewGoPostfixCompletionProvider()
+
+	tests := []struct {
+		name      string
+		content   string
+		wantLabel string
+		wantText  string
+		absent    []string
+	}{
+		{
+			name: "for over slice",
+			content: `package main
+
+func run(items []string) {
+	items.fo|
+}
+`,
+			wantLabel: "for",
+			wantText:  "for ${1:i} := range items {\n\t$0\n}",
+			absent:    []string{"if", "nil"},
+		},
+		{
+			name: "error check",
+			content: `package main
+
+type Point struct{ X int }
+
+func load() (Point, error) {
+	err := check()
+	err.|
+}
+
+func check() error { return nil }
+`,
+			wantLabel: "if",
+			wantText:  "if err != nil {\n\treturn Point{\\}, err\n}",
+			absent:    []string{"for"},
+		},
+		{
+			name: "call expression",
+			content: `package main
+
+func run(ok func(int) bool) {
+	ok(len("x")).i|
+}
+`,
+			wantLabel: "if",
+			wantText:  `if ok(len("x")) {` + "\n\t$0\n}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset := strings.Index(tt.content, "|")
+			content := tt.content[:offset] + tt.content[offset+1:]
+			list := provider.ProvideCompletions(core.CompletionContext{
+				URI:      "file:///postfix.go",
+				Content:  content,
+				Position: core.ByteOffsetToPosition(content, offset),
+			})
+			if list == nil {
+				t.Fatal("expected completions, got nil")
+			}
+
+			labels := map[string]core.CompletionItem{}
+			for _, item := range list.Items {
+				labels[item.Label] = item
+			}
+			item, ok := labels[tt.wantLabel]
+			if !ok {
+				t.Fatalf("no %q item in %v", tt.wantLabel, list.Items)
+			}
+			if item.TextEdit.NewText != tt.wantText {
+				t.Errorf("NewText = %q, want %q", item.TextEdit.NewText, tt.wantText)
+			}
+			if item.InsertTextMode == nil || *item.InsertTextMode != core.InsertTextModeAdjustIndentation {
+				t.Errorf("InsertTextMode = %v, want adjustIndentation for a multi-line snippet", item.InsertTextMode)
+			}
+			for _, label := range tt.absent {
+				if _, ok := labels[label]; ok {
+					t.Errorf("unexpected %q item", label)
+				}
+			}
+
+			// Accepting the item deletes the expression and the dot
+			edits := append([]core.TextEdit{{Range: item.TextEdit.Range, NewText: "<snippet>"}}, item.AdditionalTextEdits...)
+			result := applyTextEdits(content, edits)
+			line := strings.Split(result, "\n")[strings.Count(content[:offset], "\n")]
+			if strings.TrimSpace(line) != "<snippet>" {
+				t.Errorf("line after accepting = %q, want only the snippet", line)
+			}
+		})
+	}
+}
+
+func TestPostfixCompletionProviderSkipsPackages(t *testing.T) {
+	content := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.\n}\n"
+	list := NewGoPostfixCompletionProvider().ProvideCompletions(core.CompletionContext{
+		URI:      "file:///postfix_pkg.go",
+		Content:  content,
+		Position: core.Position{Line: 5, Character: 5},
+	})
+	if list != nil {
+		t.Errorf("expected no completions after a package name, got %v", list.Items)
+	}
+}
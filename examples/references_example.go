@@ -1,17 +1,15 @@
 package examples
 
 import (
-	"go/ast"
-	"go/parser"
-	"go/token"
+	"sort"
 	"strings"
 
 	"github.com/SCKelemen/lsp/core"
-	"github.com/SCKelemen/unicode/uax29"
 )
 
-// SimpleReferencesProvider finds all references to a symbol using text matching.
-// This is a basic example that works for simple identifiers across files.
+// SimpleReferencesProvider finds all references to a symbol in a document
+// with findOccurrences: the identifiers of the same object in Go files, and
+// the same words in other documents.
 type SimpleReferencesProvider struct {
 	// FileProvider is a function to get content for a URI.
 	// In a real implementation, this would come from a document store.
@@ -19,51 +17,27 @@ type SimpleReferencesProvider struct {
 }
 
 func (p *SimpleReferencesProvider) FindReferences(uri, content string, position core.Position, context core.ReferenceContext) []core.Location {
-	// Get the word at the position
-	word := getWordAtPositionForReferences(content, position)
-	if word == "" {
-		return nil
-	}
-
-	var locations []core.Location
-
-	// Find all occurrences in the current document
-	locations = append(locations, p.findOccurrencesInDocument(uri, content, word, context)...)
-
-	return locations
+	_, occurrences := findOccurrences(uri, content, position)
+	return occurrenceLocations(uri, content, occurrences, context)
 }
 
-func (p *SimpleReferencesProvider) findOccurrencesInDocument(uri, content, word string, context core.ReferenceContext) []core.Location {
+// occurrenceLocations returns the locations of the occurrences of a symbol
+// in a document, leaving out its declaration unless the context includes
+// it.
+func occurrenceLocations(uri, content string, occurrences []occurrence, context core.ReferenceContext) []core.Location {
 	var locations []core.Location
-
-	// Get all word boundaries using UAX29
-	breaks := uax29.FindWordBreaks(content)
-	if len(breaks) < 2 {
-		return nil
-	}
-
-	// Find all occurrences
-	for i := 0; i < len(breaks)-1; i++ {
-		start := breaks[i]
-		end := breaks[i+1]
-
-		candidateWord := content[start:end]
-		if candidateWord == word {
-			locations = append(locations, core.Location{
-				URI: uri,
-				Range: core.Range{
-					Start: core.ByteOffsetToPosition(content, start),
-					End:   core.ByteOffsetToPosition(content, end),
-				},
-			})
+	for _, o := range occurrences {
+		if o.declaration && !context.IncludeDeclaration {
+			continue
 		}
+		locations = append(locations, core.Location{URI: uri, Range: o.rangeOf(content)})
 	}
-
 	return locations
 }
 
-// GoReferencesProvider finds references to Go identifiers using AST parsing.
-// This provides more accurate results than text-based matching.
+// GoReferencesProvider finds references to Go identifiers using type
+// information. Unlike SimpleReferencesProvider, it finds nothing off
+// identifiers, like in comments.
 type GoReferencesProvider struct {
 	// FileProvider is a function to get content for a URI.
 	FileProvider func(uri string) (string, error)
@@ -74,155 +48,95 @@ func (p *GoReferencesProvider) FindReferences(uri, content string, position core
 		return nil
 	}
 
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", content, parser.ParseComments)
-	if err != nil {
+	// Only identifiers have references, not the words of comments
+	_, occurrences, ok := goOccurrences(uri, content, position)
+	if !ok {
 		return nil
 	}
-
-	// Find the identifier at the position
-	offset := core.PositionToByteOffset(content, position)
-	if offset < 0 {
-		return nil
-	}
-
-	var targetIdent *ast.Ident
-	ast.Inspect(f, func(n ast.Node) bool {
-		if ident, ok := n.(*ast.Ident); ok {
-			identStart := fset.Position(ident.Pos()).Offset
-			identEnd := fset.Position(ident.End()).Offset
-
-			if offset >= identStart && offset < identEnd {
-				targetIdent = ident
-				return false
-			}
-		}
-		return true
-	})
-
-	if targetIdent == nil {
-		return nil
-	}
-
-	// Find all references to this identifier
-	return p.findAllReferences(f, fset, uri, targetIdent.Name, context)
-}
-
-func (p *GoReferencesProvider) findAllReferences(f *ast.File, fset *token.FileSet, uri, name string, context core.ReferenceContext) []core.Location {
-	var locations []core.Location
-
-	ast.Inspect(f, func(n ast.Node) bool {
-		if ident, ok := n.(*ast.Ident); ok && ident.Name == name {
-			identStart := fset.Position(ident.Pos())
-			identEnd := fset.Position(ident.End())
-
-			locations = append(locations, core.Location{
-				URI: uri,
-				Range: core.Range{
-					Start: core.Position{Line: identStart.Line - 1, Character: identStart.Column - 1},
-					End:   core.Position{Line: identEnd.Line - 1, Character: identEnd.Column - 1},
-				},
-			})
-		}
-		return true
-	})
-
-	return locations
+	return occurrenceLocations(uri, content, occurrences, context)
 }
 
 // MultiFileReferencesProvider finds references across multiple files.
 type MultiFileReferencesProvider struct {
 	// Files maps URIs to their content.
 	Files map[string]string
-}
 
-func (p *MultiFileReferencesProvider) FindReferences(uri, content string, position core.Position, context core.ReferenceContext) []core.Location {
-	// Get the word at the position
-	word := getWordAtPositionForReferences(content, position)
-	if word == "" {
-		return nil
-	}
+	// Options filter the references found by FindReferences.
+	Options ReferenceOptions
+}
 
-	var locations []core.Location
+// ReferenceOptions select the files and occurrences MultiFileReferencesProvider
+// reports. The declaration is left out unless the ReferenceContext of the
+// request includes it.
+type ReferenceOptions struct {
+	// ExcludeTests skips the references in _test.go files.
+	ExcludeTests bool
 
-	// Search all files for occurrences
-	for fileURI, fileContent := range p.Files {
-		breaks := uax29.FindWordBreaks(fileContent)
-		if len(breaks) < 2 {
-			continue
-		}
+	// ExcludeGenerated skips the references in generated files, as
+	// classified by core.ClassifyContent.
+	ExcludeGenerated bool
+}
 
-		for i := 0; i < len(breaks)-1; i++ {
-			start := breaks[i]
-			end := breaks[i+1]
+// FileReferences are the references found in one file.
+type FileReferences struct {
+	URI       string
+	Locations []core.Location
+}
 
-			candidateWord := fileContent[start:end]
-			if candidateWord == word {
-				locations = append(locations, core.Location{
-					URI: fileURI,
-					Range: core.Range{
-						Start: core.ByteOffsetToPosition(fileContent, start),
-						End:   core.ByteOffsetToPosition(fileContent, end),
-					},
-				})
-			}
-		}
+func (p *MultiFileReferencesProvider) FindReferences(uri, content string, position core.Position, context core.ReferenceContext) []core.Location {
+	var locations []core.Location
+	for _, file := range p.FindReferencesByFile(uri, content, position, context, p.Options) {
+		locations = append(locations, file.Locations...)
 	}
-
 	return locations
 }
 
-// Helper function to get word at position for references
-func getWordAtPositionForReferences(content string, pos core.Position) string {
-	offset := core.PositionToByteOffset(content, pos)
-	if offset < 0 || offset >= len(content) {
-		return ""
+// FindReferencesByFile finds the references with the options of a request,
+// rather than Options, and returns them grouped by file in URI order. A
+// server honoring the partialResultToken of a textDocument/references
+// request can report each group with $/progress as it comes.
+func (p *MultiFileReferencesProvider) FindReferencesByFile(uri, content string, position core.Position, context core.ReferenceContext, options ReferenceOptions) []FileReferences {
+	symbol, occurrences := findOccurrences(uri, content, position)
+	if symbol.name == "" {
+		return nil
 	}
 
-	// Get all word boundaries
-	breaks := uax29.FindWordBreaks(content)
-	if len(breaks) < 2 {
-		return ""
+	uris := make([]string, 0, len(p.Files)+1)
+	for fileURI := range p.Files {
+		uris = append(uris, fileURI)
+	}
+	if _, ok := p.Files[uri]; !ok {
+		uris = append(uris, uri)
 	}
+	sort.Strings(uris)
 
-	// Helper function to check if a segment is a valid word
-	isValidWord := func(word string) bool {
-		if len(strings.TrimSpace(word)) == 0 {
-			return false
-		}
+	var files []FileReferences
 
-		for _, r := range word {
-			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r > 127 {
-				return true
-			}
+	// Search all files for occurrences. The requested document is searched
+	// with the symbol, the others by name.
+	for _, fileURI := range uris {
+		fileContent := content
+		if fileURI != uri {
+			fileContent = p.Files[fileURI]
+		}
+		if options.ExcludeTests && strings.HasSuffix(fileURI, "_test.go") {
+			continue
+		}
+		if options.ExcludeGenerated && core.ClassifyContent(fileContent) == core.ContentGenerated {
+			continue
 		}
-		return false
-	}
 
-	// Find which word boundary the offset falls into
-	for i := 0; i < len(breaks)-1; i++ {
-		start := breaks[i]
-		end := breaks[i+1]
+		fileOccurrences := occurrences
+		if fileURI != uri {
+			fileOccurrences = findNameOccurrences(fileURI, fileContent, symbol)
+		}
 
-		if offset >= start && offset < end {
-			word := content[start:end]
-			if isValidWord(word) {
-				return word
-			}
-			// If cursor is on whitespace/punctuation, try the previous word
-			if i > 0 {
-				prevStart := breaks[i-1]
-				prevEnd := breaks[i]
-				prevWord := content[prevStart:prevEnd]
-				if isValidWord(prevWord) {
-					return prevWord
-				}
-			}
-			return ""
+		if locations := occurrenceLocations(fileURI, fileContent, fileOccurrences, context); len(locations) > 0 {
+			files = append(files, FileReferences{URI: fileURI, Locations: locations})
 		}
 	}
 
-	return ""
+	return files
 }
 
 // Example usage in CLI tool
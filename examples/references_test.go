@@ -1,6 +1,8 @@
 package examples
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/SCKelemen/lsp/core"
@@ -340,3 +342,43 @@ func TestReferencesProvider_RangeAccuracy(t *testing.T) {
 		}
 	}
 }
+
+func TestMultiFileReferencesProviderOptions(t *testing.T) {
+	helper := "package main\n\nfunc helper() int {\n\treturn 42\n}\n"
+	provider := &MultiFileReferencesProvider{
+		Files: map[string]string{
+			"file:///helper.go":      helper,
+			"file:///main.go":        "package main\n\nfunc main() { println(helper()) }\n",
+			"file:///main_test.go":   "package main\n\nfunc TestHelper() { helper() }\n",
+			"file:///helper_gen.go":  "// Code generated by gen. DO NOT EDIT.\n\npackage main\n\nvar _ = helper()\n",
+			"file:///helper_test.go": "package main\n\nfunc helperTest(helper int) int { return helper }\n",
+		},
+	}
+	position := core.Position{Line: 2, Character: 6}
+
+	uris := func(files []FileReferences) string {
+		var uris []string
+		for _, file := range files {
+			uris = append(uris, fmt.Sprintf("%s:%d", strings.TrimPrefix(file.URI, "file:///"), len(file.Locations)))
+		}
+		return strings.Join(uris, ",")
+	}
+
+	files := provider.FindReferencesByFile("file:///helper.go", helper, position, core.ReferenceContext{IncludeDeclaration: true}, ReferenceOptions{})
+	if got, want := uris(files), "helper.go:1,helper_gen.go:1,helper_test.go:2,main.go:1,main_test.go:1"; got != want {
+		t.Errorf("references = %s, want %s", got, want)
+	}
+
+	// Without the declaration, the function and the parameter named helper
+	// are left out
+	files = provider.FindReferencesByFile("file:///helper.go", helper, position, core.ReferenceContext{}, ReferenceOptions{})
+	if got, want := uris(files), "helper_gen.go:1,helper_test.go:1,main.go:1,main_test.go:1"; got != want {
+		t.Errorf("references = %s, want %s", got, want)
+	}
+
+	provider.Options = ReferenceOptions{ExcludeTests: true, ExcludeGenerated: true}
+	refs := provider.FindReferences("file:///helper.go", helper, position, core.ReferenceContext{IncludeDeclaration: true})
+	if len(refs) != 2 || refs[0].URI != "file:///helper.go" || refs[1].URI != "file:///main.go" {
+		t.Errorf("references = %v, want helper.go and main.go", refs)
+	}
+}
@@ -2,13 +2,9 @@ package examples
 
 import (
 	"go/ast"
-	"go/parser"
-	"go/token"
-	"regexp"
 	"strings"
 
 	"github.com/SCKelemen/lsp/core"
-	"github.com/SCKelemen/unicode/uax29"
 )
 
 // SimpleRenameProvider provides basic rename functionality for simple identifiers.
@@ -16,50 +12,13 @@ import (
 type SimpleRenameProvider struct{}
 
 func (p *SimpleRenameProvider) PrepareRename(uri, content string, position core.Position) *core.Range {
-	// Find the word at the position using Unicode word boundaries
-	offset := core.PositionToByteOffset(content, position)
-	if offset < 0 || offset >= len(content) {
-		return nil
-	}
-
-	// Get all word boundaries in the content
-	breaks := uax29.FindWordBreaks(content)
-	if len(breaks) < 2 {
+	// Find the word at the position using Unicode word boundaries.
+	// Unlike completion, rename needs the cursor on the word itself.
+	word, r := core.WordAt(content, position)
+	if word == "" || position.Character >= r.End.Character {
 		return nil
 	}
-
-	// Find which word boundary the offset falls into
-	for i := 0; i < len(breaks)-1; i++ {
-		start := breaks[i]
-		end := breaks[i+1]
-
-		if offset >= start && offset < end {
-			// Check if this segment is actually a word (not whitespace or punctuation)
-			word := content[start:end]
-			if len(strings.TrimSpace(word)) == 0 {
-				return nil // Just whitespace
-			}
-
-			// Check if it contains at least one alphanumeric character
-			hasAlphaNum := false
-			for _, r := range word {
-				if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r > 127 {
-					hasAlphaNum = true
-					break
-				}
-			}
-			if !hasAlphaNum {
-				return nil // Just punctuation
-			}
-
-			return &core.Range{
-				Start: core.ByteOffsetToPosition(content, start),
-				End:   core.ByteOffsetToPosition(content, end),
-			}
-		}
-	}
-
-	return nil
+	return &r
 }
 
 func (p *SimpleRenameProvider) ProvideRename(ctx core.RenameContext) *core.WorkspaceEdit {
@@ -69,39 +28,9 @@ func (p *SimpleRenameProvider) ProvideRename(ctx core.RenameContext) *core.Works
 		return nil
 	}
 
-	// Get the current word
-	startOffset := core.PositionToByteOffset(ctx.Content, renameRange.Start)
-	endOffset := core.PositionToByteOffset(ctx.Content, renameRange.End)
-
-	if startOffset < 0 || endOffset > len(ctx.Content) {
-		return nil
-	}
-
-	oldName := ctx.Content[startOffset:endOffset]
-
-	// Find all occurrences of the word (simple case-sensitive match)
-	var edits []core.TextEdit
-
-	// Use regex to find whole word matches
-	pattern := regexp.MustCompile(`\b` + regexp.QuoteMeta(oldName) + `\b`)
-	matches := pattern.FindAllStringIndex(ctx.Content, -1)
-
-	for _, match := range matches {
-		start := match[0]
-		end := match[1]
-
-		startPos := core.ByteOffsetToPosition(ctx.Content, start)
-		endPos := core.ByteOffsetToPosition(ctx.Content, end)
-
-		edits = append(edits, core.TextEdit{
-			Range: core.Range{
-				Start: startPos,
-				End:   endPos,
-			},
-			NewText: ctx.NewName,
-		})
-	}
-
+	// Rename all occurrences of the symbol or word
+	_, occurrences := findOccurrences(ctx.URI, ctx.Content, renameRange.Start)
+	edits := occurrenceEdits(ctx.Content, occurrences, ctx.NewName)
 	if len(edits) == 0 {
 		return nil
 	}
@@ -113,6 +42,19 @@ func (p *SimpleRenameProvider) ProvideRename(ctx core.RenameContext) *core.Works
 	}
 }
 
+// occurrenceEdits returns the edits replacing occurrences in content with
+// newName.
+func occurrenceEdits(content string, occurrences []occurrence, newName string) []core.TextEdit {
+	var edits []core.TextEdit
+	for _, o := range occurrences {
+		edits = append(edits, core.TextEdit{
+			Range:   o.rangeOf(content),
+			NewText: newName,
+		})
+	}
+	return edits
+}
+
 // GoRenameProvider provides rename functionality for Go identifiers.
 // This uses AST parsing to ensure we only rename actual identifiers.
 type GoRenameProvider struct {
@@ -126,8 +68,7 @@ func (p *GoRenameProvider) PrepareRename(uri, content string, position core.Posi
 		return nil
 	}
 
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	fset, f, err := parseGoFile(uri, content)
 	if err != nil {
 		return nil
 	}
@@ -183,41 +124,12 @@ func (p *GoRenameProvider) ProvideRename(ctx core.RenameContext) *core.Workspace
 		return nil
 	}
 
-	// Get the old name
-	startOffset := core.PositionToByteOffset(ctx.Content, renameRange.Start)
-	endOffset := core.PositionToByteOffset(ctx.Content, renameRange.End)
-
-	if startOffset < 0 || endOffset > len(ctx.Content) {
-		return nil
-	}
-
-	oldName := ctx.Content[startOffset:endOffset]
-
-	// Parse the file to find all occurrences
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", ctx.Content, parser.ParseComments)
-	if err != nil {
+	// Rename the identifiers of the same object
+	_, occurrences, ok := goOccurrences(ctx.URI, ctx.Content, renameRange.Start)
+	if !ok {
 		return nil
 	}
-
-	var edits []core.TextEdit
-
-	// Find all identifiers with the same name
-	ast.Inspect(f, func(n ast.Node) bool {
-		if ident, ok := n.(*ast.Ident); ok && ident.Name == oldName {
-			identStart := fset.Position(ident.Pos())
-			identEnd := fset.Position(ident.End())
-
-			edits = append(edits, core.TextEdit{
-				Range: core.Range{
-					Start: core.Position{Line: identStart.Line - 1, Character: identStart.Column - 1},
-					End:   core.Position{Line: identEnd.Line - 1, Character: identEnd.Column - 1},
-				},
-				NewText: ctx.NewName,
-			})
-		}
-		return true
-	})
+	edits := occurrenceEdits(ctx.Content, occurrences, ctx.NewName)
 
 	if len(edits) == 0 {
 		return nil
@@ -238,50 +150,13 @@ type MultiFileRenameProvider struct {
 }
 
 func (p *MultiFileRenameProvider) PrepareRename(uri, content string, position core.Position) *core.Range {
-	// Use Unicode word boundary detection
-	offset := core.PositionToByteOffset(content, position)
-	if offset < 0 || offset >= len(content) {
-		return nil
-	}
-
-	// Get all word boundaries in the content
-	breaks := uax29.FindWordBreaks(content)
-	if len(breaks) < 2 {
+	// Find the word at the position using Unicode word boundaries.
+	// Unlike completion, rename needs the cursor on the word itself.
+	word, r := core.WordAt(content, position)
+	if word == "" || position.Character >= r.End.Character {
 		return nil
 	}
-
-	// Find which word boundary the offset falls into
-	for i := 0; i < len(breaks)-1; i++ {
-		start := breaks[i]
-		end := breaks[i+1]
-
-		if offset >= start && offset < end {
-			// Check if this segment is actually a word
-			word := content[start:end]
-			if len(strings.TrimSpace(word)) == 0 {
-				return nil
-			}
-
-			// Check if it contains at least one alphanumeric character
-			hasAlphaNum := false
-			for _, r := range word {
-				if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r > 127 {
-					hasAlphaNum = true
-					break
-				}
-			}
-			if !hasAlphaNum {
-				return nil
-			}
-
-			return &core.Range{
-				Start: core.ByteOffsetToPosition(content, start),
-				End:   core.ByteOffsetToPosition(content, end),
-			}
-		}
-	}
-
-	return nil
+	return &r
 }
 
 func (p *MultiFileRenameProvider) ProvideRename(ctx core.RenameContext) *core.WorkspaceEdit {
@@ -290,41 +165,21 @@ func (p *MultiFileRenameProvider) ProvideRename(ctx core.RenameContext) *core.Wo
 		return nil
 	}
 
-	startOffset := core.PositionToByteOffset(ctx.Content, renameRange.Start)
-	endOffset := core.PositionToByteOffset(ctx.Content, renameRange.End)
-
-	if startOffset < 0 || endOffset > len(ctx.Content) {
-		return nil
-	}
-
-	oldName := ctx.Content[startOffset:endOffset]
-
-	// Create a workspace edit with changes across multiple files
+	// Rename the symbol in the requested document, and its name in the
+	// others
+	symbol, occurrences := findOccurrences(ctx.URI, ctx.Content, renameRange.Start)
 	changes := make(map[string][]core.TextEdit)
-
-	// Search all files for occurrences
+	if edits := occurrenceEdits(ctx.Content, occurrences, ctx.NewName); len(edits) > 0 {
+		changes[ctx.URI] = edits
+	}
 	for uri, content := range p.Files {
-		pattern := regexp.MustCompile(`\b` + regexp.QuoteMeta(oldName) + `\b`)
-		matches := pattern.FindAllStringIndex(content, -1)
-
-		if len(matches) > 0 {
-			var fileEdits []core.TextEdit
-			for _, match := range matches {
-				start := match[0]
-				end := match[1]
-
-				fileEdits = append(fileEdits, core.TextEdit{
-					Range: core.Range{
-						Start: core.ByteOffsetToPosition(content, start),
-						End:   core.ByteOffsetToPosition(content, end),
-					},
-					NewText: ctx.NewName,
-				})
-			}
-			changes[uri] = fileEdits
+		if uri == ctx.URI {
+			continue
+		}
+		if edits := occurrenceEdits(content, findNameOccurrences(uri, content, symbol), ctx.NewName); len(edits) > 0 {
+			changes[uri] = edits
 		}
 	}
-
 	if len(changes) == 0 {
 		return nil
 	}
@@ -443,6 +298,7 @@ func main() {
 // 		return nil, nil
 // 	}
 //
-// 	// Convert back to protocol
-// 	return adapter_3_16.CoreToProtocolWorkspaceEdit(*coreEdit), nil
+// 	// Convert back to protocol, using each edited document's content
+// 	edit := adapter_3_16.CoreToProtocolWorkspaceEdit(*coreEdit, s.documents.GetContent)
+// 	return &edit, nil
 // }
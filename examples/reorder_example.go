@@ -0,0 +1,324 @@
+package examples
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// GoReorderProvider offers refactor.rewrite actions moving whole
+// declarations, with their comments:
+//
+//   - "Sort struct fields" with the cursor in a struct type, sorting its
+//     fields by name within each group of fields separated by blank lines
+//   - "Reorder declarations" with the cursor on a top-level declaration,
+//     putting them in the conventional order: constants, variables, then
+//     each type followed by its constructors and methods, then the other
+//     functions
+//
+// Comments are moved with the declaration after them. The actions are only
+// offered when they change the order.
+type GoReorderProvider struct{}
+
+func (p *GoReorderProvider) ProvideCodeFixes(ctx core.CodeFixContext) []core.CodeAction {
+	if !strings.HasSuffix(ctx.URI, ".go") || !codeActionKindRequested(ctx.Only, core.CodeActionKindRefactorRewrite) {
+		return nil
+	}
+
+	fset, f, err := parseGoFile(ctx.URI, ctx.Content)
+	if err != nil {
+		return nil
+	}
+	start := core.PositionToByteOffset(ctx.Content, ctx.Range.Start)
+	end := core.PositionToByteOffset(ctx.Content, ctx.Range.End)
+	path := goNodesEnclosing(fset, f, start, end)
+	if len(path) < 2 {
+		return nil
+	}
+
+	kind := core.CodeActionKindRefactorRewrite
+	var actions []core.CodeAction
+	add := func(title string, edit *core.TextEdit) {
+		if edit != nil {
+			actions = append(actions, core.CodeAction{
+				Title: title,
+				Kind:  &kind,
+				Edit:  &core.WorkspaceEdit{Changes: map[string][]core.TextEdit{ctx.URI: {*edit}}},
+			})
+		}
+	}
+
+	for i := len(path) - 1; i >= 0; i-- {
+		if st, ok := path[i].(*ast.StructType); ok {
+			add("Sort struct fields", p.sortFields(ctx.Content, fset, st))
+			break
+		}
+	}
+
+	// Outside of function bodies
+	inBody := false
+	for _, n := range path {
+		if fn, ok := n.(*ast.FuncDecl); ok && fn.Body != nil && fn.Body.Pos() < fset.File(f.Pos()).Pos(start) {
+			inBody = true
+		}
+	}
+	if !inBody {
+		add("Reorder declarations", p.reorderDecls(ctx.Content, fset, f))
+	}
+	return actions
+}
+
+// sourceChunk is the source of a declaration or field, with its comments,
+// in whole lines.
+type sourceChunk struct {
+	start, end int // end is before the newline ending the chunk
+	name       string
+}
+
+// chunkEnd returns the offset of the end of the line containing offset.
+func chunkEnd(content string, offset int) int {
+	if n := strings.IndexByte(content[offset:], '\n'); n >= 0 {
+		return offset + n
+	}
+	return len(content)
+}
+
+// sortFields returns the edit sorting the fields of a struct type, or nil
+// if they are sorted or share lines.
+func (p *GoReorderProvider) sortFields(content string, fset *token.FileSet, st *ast.StructType) *core.TextEdit {
+	fields := st.Fields.List
+	if len(fields) < 2 {
+		return nil
+	}
+
+	var chunks []sourceChunk
+	for i, field := range fields {
+		pos := field.Pos()
+		if field.Doc != nil {
+			pos = field.Doc.Pos()
+		}
+		start := fset.Position(pos).Offset
+		start = strings.LastIndexByte(content[:start], '\n') + 1
+		if i == 0 && start <= fset.Position(st.Fields.Opening).Offset ||
+			i > 0 && start <= chunks[i-1].end {
+			return nil // on the line of the brace or another field
+		}
+		end := chunkEnd(content, fset.Position(field.End()).Offset)
+		if fset.Position(st.Fields.Closing).Offset < end {
+			return nil
+		}
+		chunks = append(chunks, sourceChunk{start: start, end: end, name: fieldName(field)})
+	}
+	return reorderChunks(content, chunks, func(group []sourceChunk) []sourceChunk {
+		sorted := append([]sourceChunk(nil), group...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return strings.ToLower(sorted[i].name) < strings.ToLower(sorted[j].name)
+		})
+		return sorted
+	})
+}
+
+// fieldName returns the name of a field, or of the type of an embedded
+// field.
+func fieldName(field *ast.Field) string {
+	if len(field.Names) > 0 {
+		return field.Names[0].Name
+	}
+	t := field.Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	switch t := t.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	}
+	return ""
+}
+
+// reorderChunks returns the edit replacing chunks with the reordered
+// chunks of each group of consecutive lines, or nil if the order does not
+// change. The text between groups, like blank lines, is kept.
+func reorderChunks(content string, chunks []sourceChunk, reorder func([]sourceChunk) []sourceChunk) *core.TextEdit {
+	var text strings.Builder
+	changed := false
+	groupStart := 0
+	for i := range chunks {
+		last := i == len(chunks)-1
+		if !last && chunks[i+1].start == chunks[i].end+1 {
+			continue
+		}
+		group := chunks[groupStart : i+1]
+		for j, chunk := range reorder(group) {
+			if j > 0 {
+				text.WriteByte('\n')
+			}
+			text.WriteString(content[chunk.start:chunk.end])
+			changed = changed || chunk != group[j]
+		}
+		if !last {
+			text.WriteString(content[chunks[i].end:chunks[i+1].start])
+		}
+		groupStart = i + 1
+	}
+	if !changed {
+		return nil
+	}
+	return &core.TextEdit{
+		Range: core.Range{
+			Start: core.ByteOffsetToPosition(content, chunks[0].start),
+			End:   core.ByteOffsetToPosition(content, chunks[len(chunks)-1].end),
+		},
+		NewText: text.String(),
+	}
+}
+
+// Ranks of declarations in the conventional order
+const (
+	declRankConst = iota
+	declRankVar
+	declRankType
+	declRankFunc
+)
+
+// reorderDecls returns the edit putting the declarations after the imports
+// in the conventional order, or nil if they are in it.
+func (p *GoReorderProvider) reorderDecls(content string, fset *token.FileSet, f *ast.File) *core.TextEdit {
+	// Imports stay first, along with the package clause
+	var decls []ast.Decl
+	for _, decl := range f.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			if len(decls) > 0 {
+				return nil
+			}
+			continue
+		}
+		decls = append(decls, decl)
+	}
+	if len(decls) < 2 {
+		return nil
+	}
+
+	// Comments before a declaration, even separated by blank lines, move
+	// with it
+	prevEnd := fset.Position(f.Name.End()).Offset
+	if len(f.Decls) > len(decls) {
+		prevEnd = fset.Position(f.Decls[len(f.Decls)-len(decls)-1].End()).Offset
+	}
+	prevEnd = chunkEnd(content, prevEnd)
+
+	type ranked struct {
+		chunk sourceChunk
+		rank  int
+		typ   string // the type of a type declaration, or of a constructor or method
+		index int
+	}
+	var chunks []ranked
+	typeIndex := make(map[string]int) // by type name, the index of its declaration
+	for i, decl := range decls {
+		start := prevEnd + len(content[prevEnd:]) - len(strings.TrimLeft(content[prevEnd:], " \t\r\n"))
+		start = strings.LastIndexByte(content[:start], '\n') + 1
+		end := chunkEnd(content, fset.Position(decl.End()).Offset)
+		r := ranked{chunk: sourceChunk{start: start, end: end}, index: i}
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			switch decl.Tok {
+			case token.CONST:
+				r.rank = declRankConst
+			case token.VAR:
+				r.rank = declRankVar
+			case token.TYPE:
+				r.rank = declRankType
+				for _, spec := range decl.Specs {
+					typeIndex[spec.(*ast.TypeSpec).Name.Name] = i
+				}
+			}
+		case *ast.FuncDecl:
+			r.rank = declRankFunc
+			r.typ = funcDeclType(decl)
+		}
+		chunks = append(chunks, r)
+		prevEnd = end
+	}
+
+	// Constructors and methods sort along their type, after it
+	key := func(r ranked) (int, int, int) {
+		if r.rank == declRankFunc {
+			if i, ok := typeIndex[r.typ]; ok {
+				return declRankType, i, 1
+			}
+		}
+		if r.rank == declRankType {
+			return declRankType, r.index, 0
+		}
+		return r.rank, 0, 0
+	}
+	sorted := append([]ranked(nil), chunks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, ti, mi := key(sorted[i])
+		rj, tj, mj := key(sorted[j])
+		if ri != rj {
+			return ri < rj
+		}
+		if ti != tj {
+			return ti < tj
+		}
+		return mi < mj
+	})
+
+	changed := false
+	var text strings.Builder
+	for i, r := range sorted {
+		if i > 0 {
+			text.WriteString("\n\n")
+		}
+		text.WriteString(content[r.chunk.start:r.chunk.end])
+		changed = changed || r.index != i
+	}
+	if !changed {
+		return nil
+	}
+	return &core.TextEdit{
+		Range: core.Range{
+			Start: core.ByteOffsetToPosition(content, chunks[0].chunk.start),
+			End:   core.ByteOffsetToPosition(content, chunks[len(chunks)-1].chunk.end),
+		},
+		NewText: text.String(),
+	}
+}
+
+// funcDeclType returns the type a function belongs to: the receiver type
+// of a method, or the type returned by a constructor named New..., or "".
+func funcDeclType(fn *ast.FuncDecl) string {
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		return receiverTypeName(fn.Recv.List[0].Type)
+	}
+	if !strings.HasPrefix(fn.Name.Name, "New") || fn.Type.Results == nil || len(fn.Type.Results.List) == 0 {
+		return ""
+	}
+	return receiverTypeName(fn.Type.Results.List[0].Type)
+}
+
+// receiverTypeName returns the name of a type like T, *T, or T[K].
+func receiverTypeName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package examples
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// reorderAction returns the content after applying the action titled
+// title, offered with the cursor after the first occurrence of marker.
+func reorderAction(t *testing.T, content, marker, title string) (string, bool) {
+	t.Helper()
+	offset := strings.Index(content, marker) + len(marker)
+	pos := core.ByteOffsetToPosition(content, offset)
+	actions := (&GoReorderProvider{}).ProvideCodeFixes(core.CodeFixContext{
+		URI:     "file:///reorder.go",
+		Content: content,
+		Range:   core.Range{Start: pos, End: pos},
+	})
+	for _, action := range actions {
+		if action.Title == title {
+			return applyTextEdits(content, action.Edit.Changes["file:///reorder.go"]), true
+		}
+	}
+	return "", false
+}
+
+func TestGoReorderProviderSortFields(t *testing.T) {
+	content := `package server
+
+type Config struct {
+	// Port is the port to listen on.
+	Port int ` + "`json:\"port\"`" + `
+	Host string // without scheme
+	*Logger
+
+	Write time.Duration // of responses
+	Read  time.Duration
+}
+`
+	got, ok := reorderAction(t, content, "Po", "Sort struct fields")
+	if !ok {
+		t.Fatal("Sort struct fields not offered")
+	}
+	want := `package server
+
+type Config struct {
+	Host string // without scheme
+	*Logger
+	// Port is the port to listen on.
+	Port int ` + "`json:\"port\"`" + `
+
+	Read  time.Duration
+	Write time.Duration // of responses
+}
+`
+	if got != want {
+		t.Errorf("sorted fields =\n%s\nwant\n%s", got, want)
+	}
+
+	if _, ok := reorderAction(t, want, "Ho", "Sort struct fields"); ok {
+		t.Error("Sort struct fields offered for sorted fields")
+	}
+	if _, ok := reorderAction(t, "package p\n\ntype T struct{ b, a int }\n", "b", "Sort struct fields"); ok {
+		t.Error("Sort struct fields offered for fields on one line")
+	}
+}
+
+func TestGoReorderProviderReorderDecls(t *testing.T) {
+	content := `package store
+
+import "errors"
+
+// Get returns a value.
+func (s *Store) Get(key string) string { return s.m[key] }
+
+func helper() {}
+
+// Store stores values.
+type Store struct{ m map[string]string }
+
+// ErrMissing is returned for missing keys.
+var ErrMissing = errors.New("missing")
+
+// NewStore creates a store.
+func NewStore() *Store { return &Store{} }
+
+const size = 10
+`
+	got, ok := reorderAction(t, content, "func hel", "Reorder declarations")
+	if !ok {
+		t.Fatal("Reorder declarations not offered")
+	}
+	want := `package store
+
+import "errors"
+
+const size = 10
+
+// ErrMissing is returned for missing keys.
+var ErrMissing = errors.New("missing")
+
+// Store stores values.
+type Store struct{ m map[string]string }
+
+// Get returns a value.
+func (s *Store) Get(key string) string { return s.m[key] }
+
+// NewStore creates a store.
+func NewStore() *Store { return &Store{} }
+
+func helper() {}
+`
+	if got != want {
+		t.Errorf("reordered declarations =\n%s\nwant\n%s", got, want)
+	}
+
+	if _, ok := reorderAction(t, want, "func hel", "Reorder declarations"); ok {
+		t.Error("Reorder declarations offered for ordered declarations")
+	}
+	if _, ok := reorderAction(t, content, "return s.m", "Reorder declarations"); ok {
+		t.Error("Reorder declarations offered in a function body")
+	}
+}
@@ -0,0 +1,126 @@
+package examples
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// SearchReplaceCommand is the command replacing the matches of a regular
+// expression across the workspace. Its arguments are the pattern, in the
+// syntax of the regexp package, and the replacement, in which $1 or
+// ${name} stand for the text of a capture group as in
+// regexp.Regexp.Expand.
+const SearchReplaceCommand = "workspace.searchReplace"
+
+// ExecuteCommand runs SearchReplaceCommand over Files and returns the edit
+// to send to the client with workspace/applyEdit.
+//
+// The edit is a preview: the replacements of each file carry a change
+// annotation that needs confirmation, so clients like VS Code show a diff
+// of the changes, file by file, before applying them.
+func (p *MultiFileRenameProvider) ExecuteCommand(command string, arguments []interface{}) (*core.WorkspaceEdit, error) {
+	if command != SearchReplaceCommand {
+		return nil, fmt.Errorf("unknown command %q", command)
+	}
+	if len(arguments) != 2 {
+		return nil, fmt.Errorf("%s expects a pattern and a replacement", command)
+	}
+	pattern, ok := arguments[0].(string)
+	replacement, ok2 := arguments[1].(string)
+	if !ok || !ok2 || pattern == "" {
+		return nil, fmt.Errorf("%s expects a pattern and a replacement", command)
+	}
+	return p.SearchReplace(pattern, replacement)
+}
+
+// SearchReplace returns the edit replacing the matches of pattern in Files
+// with replacement, expanding its capture groups. It returns nil if
+// nothing matches, and an error if pattern is not a valid regular
+// expression. Empty matches are not replaced.
+func (p *MultiFileRenameProvider) SearchReplace(pattern, replacement string) (*core.WorkspaceEdit, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	changes := p.replaceMatches(re, func(content string, match []int) string {
+		return string(re.ExpandString(nil, replacement, content, match))
+	})
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	uris := make([]string, 0, len(changes))
+	for uri := range changes {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+
+	edit := &core.WorkspaceEdit{
+		ChangeAnnotations: make(map[string]core.ChangeAnnotation, len(uris)),
+	}
+	for _, uri := range uris {
+		// Annotations are per file, so each file can be confirmed or
+		// left out on its own
+		id := uri
+		documentEdit := core.TextDocumentEdit{
+			TextDocument: core.VersionedTextDocumentIdentifier{URI: uri},
+		}
+		for _, textEdit := range changes[uri] {
+			documentEdit.AnnotatedEdits = append(documentEdit.AnnotatedEdits, core.AnnotatedTextEdit{
+				TextEdit:     textEdit,
+				AnnotationID: &id,
+			})
+		}
+		edit.DocumentChanges = append(edit.DocumentChanges, documentEdit)
+		edit.ChangeAnnotations[id] = core.ChangeAnnotation{
+			Label:             fmt.Sprintf("%s in %s", pluralReplacements(len(changes[uri])), path.Base(uri)),
+			NeedsConfirmation: true,
+			Description:       fmt.Sprintf("Replace /%s/ with %q", pattern, replacement),
+		}
+	}
+	return edit, nil
+}
+
+// replaceMatches returns, by URI, the edits replacing the matches of
+// pattern in Files with the text replace returns for them. A match is
+// given as the submatch indexes of regexp.FindAllStringSubmatchIndex.
+// Empty matches are skipped.
+func (p *MultiFileRenameProvider) replaceMatches(pattern *regexp.Regexp, replace func(content string, match []int) string) map[string][]core.TextEdit {
+	changes := make(map[string][]core.TextEdit)
+
+	// Search all files for occurrences
+	for uri, content := range p.Files {
+		var fileEdits []core.TextEdit
+		for _, match := range pattern.FindAllStringSubmatchIndex(content, -1) {
+			start := match[0]
+			end := match[1]
+			if start == end {
+				continue
+			}
+
+			fileEdits = append(fileEdits, core.TextEdit{
+				Range: core.Range{
+					Start: core.ByteOffsetToPosition(content, start),
+					End:   core.ByteOffsetToPosition(content, end),
+				},
+				NewText: replace(content, match),
+			})
+		}
+		if len(fileEdits) > 0 {
+			changes[uri] = fileEdits
+		}
+	}
+	return changes
+}
+
+// pluralReplacements returns "1 replacement" or "n replacements".
+func pluralReplacements(count int) string {
+	if count == 1 {
+		return "1 replacement"
+	}
+	return fmt.Sprintf("%d replacements", count)
+}
@@ -0,0 +1,59 @@
+package examples
+
+import (
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+func TestMultiFileRenameProviderSearchReplace(t *testing.T) {
+	main := "package main\n\nfunc main() {\n\tlog.Printf(\"start\")\n\tlog.Printf(\"stop\")\n}\n"
+	provider := &MultiFileRenameProvider{
+		Files: map[string]string{
+			"file:///work/main.go": main,
+			"file:///work/util.go": "package main\n\nfunc debug() { log.Printf(\"debug\") }\n",
+			"file:///work/doc.go":  "package main\n",
+		},
+	}
+
+	edit, err := provider.ExecuteCommand(SearchReplaceCommand, []interface{}{`log\.Printf\("(\w+)"\)`, `slog.Info("$1")`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edit == nil || len(edit.DocumentChanges) != 2 {
+		t.Fatalf("edit = %+v, want edits of main.go and util.go", edit)
+	}
+
+	documentEdit := edit.DocumentChanges[0].(core.TextDocumentEdit)
+	if documentEdit.TextDocument.URI != "file:///work/main.go" || len(documentEdit.Edits) != 0 {
+		t.Fatalf("document edit = %+v, want the annotated edits of main.go", documentEdit)
+	}
+	var edits []core.TextEdit
+	for _, annotated := range documentEdit.AnnotatedEdits {
+		if annotated.AnnotationID == nil {
+			t.Fatalf("edit %+v has no annotation", annotated)
+		}
+		annotation := edit.ChangeAnnotations[*annotated.AnnotationID]
+		if annotation.Label != "2 replacements in main.go" || !annotation.NeedsConfirmation {
+			t.Errorf("annotation = %+v, want a confirmed preview of main.go", annotation)
+		}
+		edits = append(edits, annotated.TextEdit)
+	}
+	want := "package main\n\nfunc main() {\n\tslog.Info(\"start\")\n\tslog.Info(\"stop\")\n}\n"
+	if got := applyTextEdits(main, edits); got != want {
+		t.Errorf("main.go =\n%s\nwant\n%s", got, want)
+	}
+	if label := edit.ChangeAnnotations["file:///work/util.go"].Label; label != "1 replacement in util.go" {
+		t.Errorf("label = %q, want 1 replacement in util.go", label)
+	}
+
+	if edit, err := provider.SearchReplace(`fmt\.`, "log."); edit != nil || err != nil {
+		t.Errorf("SearchReplace = %+v, %v without matches, want nothing", edit, err)
+	}
+	if _, err := provider.SearchReplace(`log\.(`, ""); err == nil {
+		t.Error("SearchReplace succeeded with an invalid pattern")
+	}
+	if _, err := provider.ExecuteCommand(SearchReplaceCommand, []interface{}{"log"}); err == nil {
+		t.Error("ExecuteCommand succeeded without a replacement")
+	}
+}
@@ -2,7 +2,6 @@ package examples
 
 import (
 	"go/ast"
-	"go/parser"
 	"go/token"
 	"strings"
 
@@ -18,8 +17,7 @@ func (p *GoSelectionRangeProvider) ProvideSelectionRanges(uri, content string, p
 		return nil
 	}
 
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	fset, f, err := parseGoFile(uri, content)
 	if err != nil {
 		return nil
 	}
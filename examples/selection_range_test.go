@@ -71,7 +71,7 @@ type Person struct {
 			current = &ranges[0]
 			for current != nil && current.Parent != nil {
 				parent := current.Parent
-				if !rangeContains(parent.Range, current.Range) {
+				if !parent.Range.ContainsRange(current.Range) {
 					t.Errorf("parent range %+v does not contain child range %+v", parent.Range, current.Range)
 				}
 				current = parent
@@ -119,23 +119,3 @@ func main() {
 		}
 	}
 }
-
-func rangeContains(outer, inner core.Range) bool {
-	// Outer starts before or at inner start
-	if outer.Start.Line > inner.Start.Line {
-		return false
-	}
-	if outer.Start.Line == inner.Start.Line && outer.Start.Character > inner.Start.Character {
-		return false
-	}
-
-	// Outer ends after or at inner end
-	if outer.End.Line < inner.End.Line {
-		return false
-	}
-	if outer.End.Line == inner.End.Line && outer.End.Character < inner.End.Character {
-		return false
-	}
-
-	return true
-}
@@ -0,0 +1,101 @@
+package examples
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// SelectorCompletionProvider completes the fields and methods of a value
+// after a dot, like "req.", using the types of the file. Members promoted
+// from embedded fields are completed too, transitively, with the path of
+// embedded fields they come through as their label description, and after
+// the members of the type itself.
+//
+// Only types declared in the file are known; members of imported types,
+// and package members like "strings.", are left to other providers.
+type SelectorCompletionProvider struct{}
+
+func (p *SelectorCompletionProvider) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
+	if !strings.HasSuffix(ctx.URI, ".go") {
+		return nil
+	}
+
+	nameStart, cursor := completionWord(ctx.Content, ctx.Position)
+	if nameStart == 0 || ctx.Content[nameStart-1] != '.' {
+		return nil
+	}
+	exprEnd := nameStart - 1
+	exprStart := postfixExprStart(ctx.Content, exprEnd)
+	if exprStart == exprEnd {
+		return nil
+	}
+	if r, _ := utf8.DecodeRuneInString(ctx.Content[exprStart:]); unicode.IsDigit(r) {
+		// A number like 1.5
+		return nil
+	}
+	typed := ctx.Content[nameStart:cursor]
+
+	// Type-check without the dot and the member being typed, which leaves
+	// the value as an expression
+	content := ctx.Content[:exprEnd] + ctx.Content[cursor:]
+	fset, f, err := parseGoFile(ctx.URI+"#selector", content)
+	if err != nil {
+		return nil
+	}
+	info, pkg, _ := typeCheckGoFileErrors(fset, f)
+
+	path := goNodesEnclosing(fset, f, exprStart, exprEnd)
+	if len(path) == 0 {
+		return nil
+	}
+	expr, ok := path[len(path)-1].(ast.Expr)
+	if !ok || fset.Position(expr.Pos()).Offset != exprStart || fset.Position(expr.End()).Offset != exprEnd {
+		return nil
+	}
+	tv, ok := info.Types[expr]
+	if !ok || tv.Type == nil || tv.Type == types.Typ[types.Invalid] || tv.IsType() {
+		return nil
+	}
+
+	qualifier := types.RelativeTo(pkg)
+	nameRange := core.Range{
+		Start: core.ByteOffsetToPosition(ctx.Content, nameStart),
+		End:   core.ByteOffsetToPosition(ctx.Content, cursor),
+	}
+	var items []core.CompletionItem
+	for _, member := range goMembers(tv.Type) {
+		name := member.Object.Name()
+		if !strings.HasPrefix(name, typed) {
+			continue
+		}
+		kind := core.CompletionItemKindField
+		if _, ok := member.Object.(*types.Func); ok {
+			kind = core.CompletionItemKindMethod
+		}
+		typ := types.TypeString(member.Object.Type(), qualifier)
+		details := &core.CompletionItemLabelDetails{Detail: labelDetail(typ)}
+		if len(member.Path) > 0 {
+			details.Description = strings.Join(member.Path, ".")
+		}
+		items = append(items, core.CompletionItem{
+			Label:        name,
+			Kind:         &kind,
+			Detail:       typ,
+			LabelDetails: details,
+			// Members of the type itself first, then by depth
+			SortText: fmt.Sprintf("%d%s", len(member.Path), name),
+			TextEdit: &core.TextEdit{Range: nameRange, NewText: name},
+		})
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+	return &core.CompletionList{Items: items}
+}
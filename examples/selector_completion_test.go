@@ -0,0 +1,101 @@
+package examples
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+const embeddingSource = `package main
+
+type Logger struct{ Prefix string }
+
+func (l *Logger) Log(msg string) {}
+
+type Base struct {
+	*Logger
+	ID   int
+	Name string
+}
+
+func (b Base) Describe() string { return b.Name }
+
+type Named struct{ Name string }
+
+type User struct {
+	Base
+	Named
+	Email string
+}
+
+func main() {
+	var u User
+	u.Log("hello")
+	_ = u.
+}
+`
+
+func TestSelectorCompletionProvider(t *testing.T) {
+	content := embeddingSource
+	cursor := strings.Index(content, "_ = u.") + len("_ = u.")
+	list := (&SelectorCompletionProvider{}).ProvideCompletions(core.CompletionContext{
+		URI:      "file:///main.go",
+		Content:  content,
+		Position: core.ByteOffsetToPosition(content, cursor),
+	})
+	if list == nil {
+		t.Fatal("no completions")
+	}
+
+	items := map[string]core.CompletionItem{}
+	for _, item := range list.Items {
+		items[item.Label] = item
+	}
+	want := map[string]string{
+		"Email":    "",
+		"Base":     "",
+		"Named":    "",
+		"Logger":   "Base",
+		"ID":       "Base",
+		"Describe": "Base",
+		"Prefix":   "Base.Logger",
+		"Log":      "Base.Logger",
+	}
+	for label, path := range want {
+		item, ok := items[label]
+		if !ok {
+			t.Errorf("missing %s", label)
+			continue
+		}
+		if item.LabelDetails == nil || item.LabelDetails.Description != path {
+			t.Errorf("%s: label details = %+v, want description %q", label, item.LabelDetails, path)
+		}
+	}
+	// Name is both Base.Name and Named.Name, at the same depth
+	if _, ok := items["Name"]; ok {
+		t.Error("ambiguous Name completed")
+	}
+	if len(items) != len(want) {
+		t.Errorf("got %d items, want %d", len(items), len(want))
+	}
+
+	if log := items["Log"]; *log.Kind != core.CompletionItemKindMethod || log.LabelDetails.Detail != "(msg string)" {
+		t.Errorf("Log = %+v, want a method with its signature", log)
+	}
+	if email, log := items["Email"], items["Log"]; email.SortText >= log.SortText {
+		t.Errorf("Email sorts as %q after the promoted Log as %q", email.SortText, log.SortText)
+	}
+
+	// Types and packages have no completions here
+	for _, source := range []string{"package main\n\ntype T struct{ A int }\n\nvar _ = T.", "package main\n\nimport \"strings\"\n\nvar _ = strings."} {
+		list := (&SelectorCompletionProvider{}).ProvideCompletions(core.CompletionContext{
+			URI:      "file:///main.go",
+			Content:  source,
+			Position: core.ByteOffsetToPosition(source, len(source)),
+		})
+		if list != nil {
+			t.Errorf("completions %+v in %q, want none", list.Items, source)
+		}
+	}
+}
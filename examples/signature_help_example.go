@@ -2,7 +2,7 @@ package examples
 
 import (
 	"go/ast"
-	"go/parser"
+	"go/scanner"
 	"go/token"
 	"strings"
 
@@ -11,31 +11,29 @@ import (
 
 // GoSignatureHelpProvider provides signature help for Go function calls.
 // Shows function signatures and highlights the active parameter as you type.
-type GoSignatureHelpProvider struct{}
+type GoSignatureHelpProvider struct {
+	// TypeParams renders the type parameters of generic functions in
+	// signature labels.
+	TypeParams TypeParamRenderer
+}
 
 func (p *GoSignatureHelpProvider) ProvideSignatureHelp(ctx core.SignatureHelpContext) *core.SignatureHelp {
 	if !strings.HasSuffix(ctx.URI, ".go") {
 		return nil
 	}
 
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", ctx.Content, parser.ParseComments)
-	if err != nil {
+	// In-progress code rarely parses cleanly; the parser still returns a
+	// partial AST that is good enough to find declarations.
+	fset, f, _ := parseGoFile(ctx.URI, ctx.Content)
+	if f == nil {
 		return nil
 	}
 
-	// Find the function call at the position
 	offset := core.PositionToByteOffset(ctx.Content, ctx.Position)
-	callExpr := p.findCallExprAtPosition(f, fset, ctx.Content, offset)
-	if callExpr == nil {
-		return nil
-	}
 
-	// Find the function declaration
-	var funcName string
-	if ident, ok := callExpr.Fun.(*ast.Ident); ok {
-		funcName = ident.Name
-	} else {
+	// Find the innermost call around the cursor and the argument it is on
+	funcName, activeParam, ok := p.findCallSite(f, fset, ctx.Content, offset)
+	if !ok {
 		return nil
 	}
 
@@ -50,8 +48,10 @@ func (p *GoSignatureHelpProvider) ProvideSignatureHelp(ctx core.SignatureHelpCon
 		return nil
 	}
 
-	// Determine active parameter based on cursor position
-	activeParam := p.determineActiveParameter(callExpr, fset, ctx.Content, offset)
+	// Extra arguments to a variadic function all belong to the last parameter
+	if n := len(sigInfo.Parameters); n > 0 && activeParam >= n && isVariadic(funcDecl) {
+		activeParam = n - 1
+	}
 
 	return &core.SignatureHelp{
 		Signatures:      []core.SignatureInformation{*sigInfo},
@@ -60,7 +60,37 @@ func (p *GoSignatureHelpProvider) ProvideSignatureHelp(ctx core.SignatureHelpCon
 	}
 }
 
-// findCallExprAtPosition finds the function call expression at the given offset
+// findCallSite returns the name of the function called by the innermost call
+// enclosing offset and the index of the argument the cursor is on.
+// It prefers the AST and falls back to scanner-based bracket matching when the
+// call is too incomplete to appear in the (partial) AST.
+func (p *GoSignatureHelpProvider) findCallSite(f *ast.File, fset *token.FileSet, content string, offset int) (string, int, bool) {
+	if call := p.findCallExprAtPosition(f, fset, content, offset); call != nil {
+		if name, indexed := calleeName(call.Fun); name != "" {
+			if indexed && !p.isGenericFunc(f, name) {
+				// handlers[i](x) calls an element, not handlers
+				return "", 0, false
+			}
+			lparen := fset.Position(call.Lparen).Offset
+			return name, p.determineActiveParameter(content, lparen, offset), true
+		}
+	}
+
+	frames := scanOpenBrackets(content[:offset])
+	for i := len(frames) - 1; i >= 0; i-- {
+		if frames[i].tok == token.LPAREN && frames[i].callee != "" {
+			if frames[i].indexed && !p.isGenericFunc(f, frames[i].callee) {
+				return "", 0, false
+			}
+			return frames[i].callee, frames[i].commas, true
+		}
+	}
+
+	return "", 0, false
+}
+
+// findCallExprAtPosition finds the innermost function call expression whose
+// parentheses enclose the given offset
 func (p *GoSignatureHelpProvider) findCallExprAtPosition(f *ast.File, fset *token.FileSet, content string, offset int) *ast.CallExpr {
 	var result *ast.CallExpr
 
@@ -70,26 +100,17 @@ func (p *GoSignatureHelpProvider) findCallExprAtPosition(f *ast.File, fset *toke
 		}
 
 		call, ok := n.(*ast.CallExpr)
-		if !ok {
+		if !ok || !call.Rparen.IsValid() {
 			return true
 		}
 
 		// Check if the cursor is within the function call
-		callStart := fset.Position(call.Lparen)
-		callEnd := fset.Position(call.Rparen)
-
-		startOffset := core.PositionToByteOffset(content, core.Position{
-			Line:      callStart.Line - 1,
-			Character: callStart.Column - 1,
-		})
-		endOffset := core.PositionToByteOffset(content, core.Position{
-			Line:      callEnd.Line - 1,
-			Character: callEnd.Column - 1,
-		})
-
-		if startOffset <= offset && offset <= endOffset {
+		startOffset := fset.Position(call.Lparen).Offset
+		endOffset := fset.Position(call.Rparen).Offset
+
+		if startOffset <= offset && offset <= endOffset && endOffset <= len(content) {
+			// Keep descending: a nested call in the arguments wins
 			result = call
-			return false
 		}
 
 		return true
@@ -98,6 +119,43 @@ func (p *GoSignatureHelpProvider) findCallExprAtPosition(f *ast.File, fset *toke
 	return result
 }
 
+// calleeName returns the function or method name of a call target.
+// Brackets, as in Map[int](...), are unwrapped, and indexed reports that
+// there were some: they are type arguments only if the name is generic.
+func calleeName(fun ast.Expr) (name string, indexed bool) {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		return f.Name, false
+	case *ast.SelectorExpr:
+		return f.Sel.Name, false
+	case *ast.IndexExpr:
+		name, _ = calleeName(f.X)
+		return name, true
+	case *ast.IndexListExpr:
+		name, _ = calleeName(f.X)
+		return name, true
+	default:
+		return "", false
+	}
+}
+
+// isGenericFunc reports whether name is a function declared with type
+// parameters.
+func (p *GoSignatureHelpProvider) isGenericFunc(f *ast.File, name string) bool {
+	funcDecl := p.findFuncDecl(f, name)
+	return funcDecl != nil && funcDecl.Type.TypeParams.NumFields() > 0
+}
+
+// isVariadic reports whether the last parameter of funcDecl is variadic.
+func isVariadic(funcDecl *ast.FuncDecl) bool {
+	params := funcDecl.Type.Params
+	if params == nil || len(params.List) == 0 {
+		return false
+	}
+	_, ok := params.List[len(params.List)-1].Type.(*ast.Ellipsis)
+	return ok
+}
+
 // findFuncDecl finds the function declaration with the given name
 func (p *GoSignatureHelpProvider) findFuncDecl(f *ast.File, name string) *ast.FuncDecl {
 	var found *ast.FuncDecl
@@ -124,6 +182,7 @@ func (p *GoSignatureHelpProvider) buildSignatureInfo(funcDecl *ast.FuncDecl, fse
 	// Build signature label
 	var label strings.Builder
 	label.WriteString(funcDecl.Name.Name)
+	label.WriteString(p.TypeParams.TypeParams(funcDecl.Type.TypeParams, p.typeToString))
 	label.WriteString("(")
 
 	var params []core.ParameterInformation
@@ -211,6 +270,10 @@ func (p *GoSignatureHelpProvider) typeToString(expr ast.Expr) string {
 		return "map[" + p.typeToString(t.Key) + "]" + p.typeToString(t.Value)
 	case *ast.SelectorExpr:
 		return p.typeToString(t.X) + "." + t.Sel.Name
+	case *ast.IndexExpr:
+		return instantiatedTypeString(t.X, []ast.Expr{t.Index}, p.typeToString)
+	case *ast.IndexListExpr:
+		return instantiatedTypeString(t.X, t.Indices, p.typeToString)
 	case *ast.FuncType:
 		return "func(...)"
 	case *ast.InterfaceType:
@@ -245,41 +308,122 @@ func (p *GoSignatureHelpProvider) extractDocumentation(funcDecl *ast.FuncDecl) s
 	return doc.String()
 }
 
-// determineActiveParameter figures out which parameter the cursor is on
-func (p *GoSignatureHelpProvider) determineActiveParameter(call *ast.CallExpr, fset *token.FileSet, content string, offset int) int {
-	if len(call.Args) == 0 {
+// determineActiveParameter figures out which parameter the cursor is on by
+// counting the commas between the call's opening paren and the cursor.
+// Commas inside nested calls, composite literals, strings, and comments are
+// not argument separators and are skipped.
+func (p *GoSignatureHelpProvider) determineActiveParameter(content string, lparen, offset int) int {
+	if offset <= lparen+1 {
 		return 0
 	}
 
-	// Count commas before the cursor position to determine parameter index
-	paramIndex := 0
+	frames := scanOpenBrackets(content[lparen:offset])
+	if len(frames) == 0 {
+		return 0
+	}
+	return frames[0].commas
+}
 
-	for i, arg := range call.Args {
-		argStart := fset.Position(arg.Pos())
-		startOffset := core.PositionToByteOffset(content, core.Position{
-			Line:      argStart.Line - 1,
-			Character: argStart.Column - 1,
-		})
+// bracketFrame is a bracket that is still open at the end of a scan.
+type bracketFrame struct {
+	// tok is LPAREN, LBRACK, or LBRACE.
+	tok token.Token
+	// callee is the identifier before an LPAREN that opens a call, if any.
+	// For an LBRACK it is the identifier being indexed or instantiated.
+	callee string
+	// commas is the number of commas seen directly inside this bracket.
+	commas int
+	// indexed marks a call whose callee is followed by brackets, which
+	// are type arguments or an index.
+	indexed bool
+	// receiver marks the LPAREN of a method receiver.
+	receiver bool
+}
 
-		if offset < startOffset {
-			return paramIndex
+// scanOpenBrackets tokenizes src with go/scanner and returns the stack of
+// brackets left open at its end, outermost first. Working on tokens rather
+// than AST nodes keeps this usable on code that does not parse yet.
+func scanOpenBrackets(src string) []bracketFrame {
+	file := addScanFile(len(src))
+	defer removeScanFile(file)
+
+	buf := borrowSource(src)
+	defer returnSource(buf)
+
+	var s scanner.Scanner
+	s.Init(file, *buf, nil, 0)
+
+	var (
+		stack []bracketFrame
+		prev  token.Token
+		ident string
+		// declared is set when ident names a function being declared
+		// rather than one being called.
+		declared bool
+		// afterReceiver is set right after a method receiver closes.
+		afterReceiver bool
+		// indexed is set when brackets follow ident.
+		indexed bool
+	)
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
 		}
 
-		// If we're past this argument, move to next parameter
-		argEnd := fset.Position(arg.End())
-		endOffset := core.PositionToByteOffset(content, core.Position{
-			Line:      argEnd.Line - 1,
-			Character: argEnd.Column - 1,
-		})
+		// Automatically inserted semicolons (lit == "\n") do not separate
+		// a callee from its paren, but real tokens do.
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
 
-		if offset >= startOffset && offset <= endOffset {
-			return i
+		nameIsDeclared := prev == token.FUNC || afterReceiver
+		afterReceiver = false
+
+		switch tok {
+		case token.LPAREN, token.LBRACK, token.LBRACE:
+			frame := bracketFrame{tok: tok}
+			switch {
+			case tok == token.LPAREN && prev == token.FUNC:
+				// Receiver or parameters of a function literal
+				frame.receiver = true
+			case prev == token.IDENT && !declared:
+				frame.callee = ident
+				frame.indexed = indexed
+			}
+			stack = append(stack, frame)
+		case token.RPAREN, token.RBRACK, token.RBRACE:
+			if len(stack) == 0 {
+				break
+			}
+			closed := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			switch {
+			case closed.receiver:
+				afterReceiver = true
+			case tok == token.RBRACK && closed.callee != "":
+				// Skip back over type arguments: in Map[int](...) the
+				// call belongs to Map. Whether they are type arguments
+				// or an index is left to the caller.
+				ident = closed.callee
+				indexed = true
+				prev = token.IDENT
+				continue
+			}
+		case token.COMMA:
+			if len(stack) > 0 {
+				stack[len(stack)-1].commas++
+			}
+		case token.IDENT:
+			ident = lit
+			indexed = false
+			declared = nameIsDeclared
 		}
 
-		paramIndex = i + 1
+		prev = tok
 	}
 
-	return paramIndex
+	return stack
 }
 
 func intPtr(i int) *int {
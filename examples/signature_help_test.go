@@ -201,6 +201,30 @@ func main() {
 }`,
 			position: core.Position{Line: 3, Character: 15}, // Inside "UnknownFunc("
 		},
+		{
+			name: "call of an indexed element",
+			content: `package main
+
+func handlers(name string) {}
+
+func main() {
+	handlers := []func(int){}
+	handlers[0](1)
+}`,
+			position: core.Position{Line: 6, Character: 13}, // On "1"
+		},
+		{
+			name: "unparsable call of an indexed element",
+			content: `package main
+
+func handlers(name string) {}
+
+func main() {
+	handlers := []func(int){}
+	handlers[0](
+}`,
+			position: core.Position{Line: 6, Character: 13}, // After "handlers[0]("
+		},
 		{
 			name: "non-Go file",
 			content: `some text
@@ -331,3 +355,218 @@ func main() {
 		t.Errorf("documentation doesn't contain expected text: %q", sig.Documentation)
 	}
 }
+
+func TestGoSignatureHelpProvider_CallSiteTracking(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		position  core.Position
+		wantLabel string
+		wantParam int
+	}{
+		{
+			name: "nested call uses innermost signature",
+			content: `package main
+
+func Add(a int, b int) int { return a + b }
+func Mul(x int, y int) int { return x * y }
+
+func main() {
+	Add(Mul(1, 2), 3)
+}`,
+			position:  core.Position{Line: 6, Character: 12}, // After "Mul(1, "
+			wantLabel: "Mul(x int, y int) int",
+			wantParam: 1,
+		},
+		{
+			name: "outer call after nested call",
+			content: `package main
+
+func Add(a int, b int) int { return a + b }
+func Mul(x int, y int) int { return x * y }
+
+func main() {
+	Add(Mul(1, 2), 3)
+}`,
+			position:  core.Position{Line: 6, Character: 17}, // On "3"
+			wantLabel: "Add(a int, b int) int",
+			wantParam: 1,
+		},
+		{
+			name: "commas inside composite literal are ignored",
+			content: `package main
+
+func Send(items []int, urgent bool) {}
+
+func main() {
+	Send([]int{1, 2, 3}, true)
+}`,
+			position:  core.Position{Line: 5, Character: 22}, // On "true"
+			wantLabel: "Send(items []int, urgent bool)",
+			wantParam: 1,
+		},
+		{
+			name: "commas inside string literal are ignored",
+			content: `package main
+
+func Log(msg string, level int) {}
+
+func main() {
+	Log("a, b, c", 2)
+}`,
+			position:  core.Position{Line: 5, Character: 17}, // On "2"
+			wantLabel: "Log(msg string, level int)",
+			wantParam: 1,
+		},
+		{
+			name: "variadic arguments map to the last parameter",
+			content: `package main
+
+func Printf(format string, args ...any) {}
+
+func main() {
+	Printf("%d %d", 1, 2, 3)
+}`,
+			position:  core.Position{Line: 5, Character: 24}, // On "3"
+			wantLabel: "Printf(format string, args ...any)",
+			wantParam: 1,
+		},
+		{
+			name: "method call",
+			content: `package main
+
+type Calc struct{}
+
+func (c Calc) Sub(a int, b int) int { return a - b }
+
+func main() {
+	var c Calc
+	c.Sub(5, 3)
+}`,
+			position:  core.Position{Line: 8, Character: 10}, // On "3"
+			wantLabel: "Sub(a int, b int) int",
+			wantParam: 1,
+		},
+		{
+			name: "func-typed last parameter is not variadic",
+			content: `package main
+
+func Apply(x int, fn func(int) int) int { return fn(x) }
+
+func main() {
+	Apply(1, double, 3)
+}`,
+			position:  core.Position{Line: 5, Character: 19}, // On "3"
+			wantLabel: "Apply(x int, fn func(...)) int",
+			wantParam: 2,
+		},
+		{
+			name: "generic call with explicit type arguments",
+			content: `package main
+
+func Add(a int, b int) int { return a + b }
+func Map[T any](x T, y T) T { return x }
+
+func main() {
+	Add(Map[int](1, 2), 3)
+}`,
+			position:  core.Position{Line: 6, Character: 17}, // On "2"
+			wantLabel: "Map[T any](x T, y T) T",
+			wantParam: 1,
+		},
+		{
+			name: "unparsable generic call",
+			content: `package main
+
+func Add(a int, b int) int { return a + b }
+func Map[T any](x T, y T) T { return x }
+
+func main() {
+	Add(Map[int](1, 
+}`,
+			position:  core.Position{Line: 6, Character: 17}, // After "Map[int](1, "
+			wantLabel: "Map[T any](x T, y T) T",
+			wantParam: 1,
+		},
+		{
+			name: "unparsable in-progress call",
+			content: `package main
+
+func Add(a int, b int) int { return a + b }
+
+func main() {
+	Add(1, 
+}`,
+			position:  core.Position{Line: 5, Character: 8}, // After "Add(1, "
+			wantLabel: "Add(a int, b int) int",
+			wantParam: 1,
+		},
+	}
+
+	provider := &GoSignatureHelpProvider{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			help := provider.ProvideSignatureHelp(core.SignatureHelpContext{
+				URI:      "file:///test.go",
+				Content:  tt.content,
+				Position: tt.position,
+			})
+
+			if help == nil || len(help.Signatures) == 0 {
+				t.Fatal("expected signature help")
+			}
+			if got := help.Signatures[0].Label; got != tt.wantLabel {
+				t.Errorf("got label %q, want %q", got, tt.wantLabel)
+			}
+			if help.ActiveParameter == nil {
+				t.Fatal("expected active parameter")
+			}
+			if *help.ActiveParameter != tt.wantParam {
+				t.Errorf("got active parameter %d, want %d", *help.ActiveParameter, tt.wantParam)
+			}
+		})
+	}
+}
+
+func TestGoSignatureHelpProvider_DeclarationInProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		position core.Position
+	}{
+		{
+			name: "function declaration",
+			content: `package main
+
+func Add(a int, b int) int { return a + b }
+
+func Add(a int, `,
+			position: core.Position{Line: 4, Character: 16},
+		},
+		{
+			name: "method declaration",
+			content: `package main
+
+type Calc struct{}
+
+func Sub(a int, b int) int { return a - b }
+
+func (c Calc) Sub(a int, `,
+			position: core.Position{Line: 6, Character: 25},
+		},
+	}
+
+	provider := &GoSignatureHelpProvider{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			help := provider.ProvideSignatureHelp(core.SignatureHelpContext{
+				URI:      "file:///test.go",
+				Content:  tt.content,
+				Position: tt.position,
+			})
+			if help != nil {
+				t.Errorf("expected no signature help inside a declaration, got %q", help.Signatures[0].Label)
+			}
+		})
+	}
+}
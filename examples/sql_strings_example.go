@@ -0,0 +1,405 @@
+package examples
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// SQL in Go strings is an embedded language: the string literal is a
+// virtual SQL document inside the Go document. The providers below find the
+// SQL strings, work on their virtual documents like on any SQL file, and
+// map positions between the two, so completions and diagnostics land on the
+// right bytes of the literal even across escapes like \n and \".
+
+// DefaultSQLQueryFuncs are the functions taking SQL, by name, with the index
+// of their query argument, like the methods of database/sql.
+var DefaultSQLQueryFuncs = map[string]int{
+	"Exec":            0,
+	"ExecContext":     1,
+	"Prepare":         0,
+	"PrepareContext":  1,
+	"Query":           0,
+	"QueryContext":    1,
+	"QueryRow":        0,
+	"QueryRowContext": 1,
+}
+
+// SQLString is an SQL string in a Go document: a string literal tagged with
+// a /* sql */ comment, like
+//
+//	query := /* sql */ `SELECT name FROM users`
+//
+// or passed as the query to one of the query functions.
+type SQLString struct {
+	// Range is the range of the literal in the Go document, quotes
+	// included.
+	Range core.Range
+
+	// Content is the virtual SQL document: the value of the literal.
+	Content string
+
+	host string
+
+	// offsets holds the offset in host of each byte of Content, and of the
+	// closing quote. The bytes of an escape map to its backslash.
+	offsets []int
+}
+
+// HostPosition returns the position in the Go document of a position in
+// the SQL document.
+func (s *SQLString) HostPosition(pos core.Position) core.Position {
+	offset := core.PositionToByteOffset(s.Content, pos)
+	if offset < 0 || offset >= len(s.offsets) {
+		offset = len(s.offsets) - 1
+	}
+	return core.ByteOffsetToPosition(s.host, s.offsets[offset])
+}
+
+// HostRange returns the range in the Go document of a range in the SQL
+// document.
+func (s *SQLString) HostRange(r core.Range) core.Range {
+	return core.Range{Start: s.HostPosition(r.Start), End: s.HostPosition(r.End)}
+}
+
+// VirtualPosition returns the position in the SQL document of a position
+// in the Go document. ok is false outside the quotes of the literal.
+func (s *SQLString) VirtualPosition(pos core.Position) (core.Position, bool) {
+	offset := core.PositionToByteOffset(s.host, pos)
+	if offset < s.offsets[0] || offset > s.offsets[len(s.offsets)-1] {
+		return core.Position{}, false
+	}
+	// Offsets inside an escape belong to the escape's first byte
+	i := sort.SearchInts(s.offsets, offset)
+	if i < len(s.offsets) && s.offsets[i] > offset {
+		i--
+		for i > 0 && s.offsets[i-1] == s.offsets[i] {
+			i--
+		}
+	}
+	return core.ByteOffsetToPosition(s.Content, i), true
+}
+
+// FindSQLStrings returns the SQL strings of a Go document. queryFuncs maps
+// the names of functions taking SQL to the index of their query argument;
+// if nil, DefaultSQLQueryFuncs is used.
+func FindSQLStrings(uri, content string, queryFuncs map[string]int) []*SQLString {
+	fset, f, err := parseGoFile(uri, content)
+	if err != nil {
+		return nil
+	}
+	if queryFuncs == nil {
+		queryFuncs = DefaultSQLQueryFuncs
+	}
+	tokenFile := fset.File(f.Pos())
+
+	// Offsets where a /* sql */ comment ends
+	tags := make(map[int]bool)
+	for _, group := range f.Comments {
+		for _, comment := range group.List {
+			text := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(comment.Text, "/*"), "*/"))
+			if strings.HasPrefix(comment.Text, "/*") && strings.EqualFold(text, "sql") {
+				tags[tokenFile.Offset(comment.End())] = true
+			}
+		}
+	}
+
+	seen := make(map[*ast.BasicLit]bool)
+	var literals []*ast.BasicLit
+	add := func(lit *ast.BasicLit) {
+		if !seen[lit] {
+			seen[lit] = true
+			literals = append(literals, lit)
+		}
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BasicLit:
+			if n.Kind != token.STRING {
+				return true
+			}
+			start := tokenFile.Offset(n.Pos())
+			for start > 0 && (content[start-1] == ' ' || content[start-1] == '\t') {
+				start--
+			}
+			if tags[start] {
+				add(n)
+			}
+		case *ast.CallExpr:
+			var name string
+			switch fun := n.Fun.(type) {
+			case *ast.Ident:
+				name = fun.Name
+			case *ast.SelectorExpr:
+				name = fun.Sel.Name
+			}
+			index, ok := queryFuncs[name]
+			if !ok || index >= len(n.Args) {
+				return true
+			}
+			if lit, ok := n.Args[index].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				add(lit)
+			}
+		}
+		return true
+	})
+
+	var strs []*SQLString
+	for _, lit := range literals {
+		start := tokenFile.Offset(lit.Pos())
+		sqlContent, offsets, ok := unquoteWithOffsets(lit.Value, start)
+		if !ok {
+			continue
+		}
+		strs = append(strs, &SQLString{
+			Range: core.Range{
+				Start: core.ByteOffsetToPosition(content, start),
+				End:   core.ByteOffsetToPosition(content, start+len(lit.Value)),
+			},
+			Content: sqlContent,
+			host:    content,
+			offsets: offsets,
+		})
+	}
+	sort.Slice(strs, func(i, j int) bool {
+		return strs[i].offsets[0] < strs[j].offsets[0]
+	})
+	return strs
+}
+
+// unquoteWithOffsets unquotes a Go string literal starting at offset start
+// of its document, and returns the offsets of the bytes of its value, and
+// of its closing quote.
+func unquoteWithOffsets(lit string, start int) (string, []int, bool) {
+	if len(lit) < 2 {
+		return "", nil, false
+	}
+	var value strings.Builder
+	var offsets []int
+	body := lit[1 : len(lit)-1]
+
+	if lit[0] == '`' {
+		for i := 0; i < len(body); i++ {
+			// Raw strings drop carriage returns
+			if body[i] == '\r' {
+				continue
+			}
+			value.WriteByte(body[i])
+			offsets = append(offsets, start+1+i)
+		}
+		return value.String(), append(offsets, start+len(lit)-1), true
+	}
+
+	pos := 0
+	for len(body) > 0 {
+		r, multibyte, tail, err := strconv.UnquoteChar(body, lit[0])
+		if err != nil {
+			return "", nil, false
+		}
+		var buf [utf8.UTFMax]byte
+		n := 1
+		if r < utf8.RuneSelf || multibyte {
+			n = utf8.EncodeRune(buf[:], r)
+		} else {
+			buf[0] = byte(r)
+		}
+		value.Write(buf[:n])
+		for range n {
+			offsets = append(offsets, start+1+pos)
+		}
+		pos += len(body) - len(tail)
+		body = tail
+	}
+	return value.String(), append(offsets, start+len(lit)-1), true
+}
+
+// sqlStringAt returns the SQL string containing a position of a Go document.
+func sqlStringAt(strs []*SQLString, pos core.Position) (*SQLString, core.Position, bool) {
+	for _, s := range strs {
+		if virtual, ok := s.VirtualPosition(pos); ok {
+			return s, virtual, true
+		}
+	}
+	return nil, core.Position{}, false
+}
+
+// sqlKeywords are the keywords completed in SQL strings.
+var sqlKeywords = []string{
+	"ALL", "AND", "AS", "ASC", "BETWEEN", "BY", "CASE", "COUNT", "CREATE",
+	"DELETE", "DESC", "DISTINCT", "DROP", "ELSE", "END", "EXISTS", "FROM",
+	"GROUP", "HAVING", "IN", "INNER", "INSERT", "INTO", "IS", "JOIN", "LEFT",
+	"LIKE", "LIMIT", "NOT", "NULL", "OFFSET", "ON", "OR", "ORDER", "OUTER",
+	"RETURNING", "RIGHT", "SELECT", "SET", "TABLE", "THEN", "UNION", "UPDATE",
+	"VALUES", "WHEN", "WHERE", "WITH",
+}
+
+// GoSQLCompletionProvider completes SQL keywords in the SQL strings of Go
+// documents. Keywords are inserted in the case of the typed prefix.
+type GoSQLCompletionProvider struct {
+	// QueryFuncs are the functions taking SQL; if nil,
+	// DefaultSQLQueryFuncs is used.
+	QueryFuncs map[string]int
+}
+
+func (p *GoSQLCompletionProvider) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
+	if !strings.HasSuffix(ctx.URI, ".go") {
+		return nil
+	}
+	s, virtual, ok := sqlStringAt(FindSQLStrings(ctx.URI, ctx.Content, p.QueryFuncs), ctx.Position)
+	if !ok {
+		return nil
+	}
+
+	// The prefix is found in the SQL document, and replaced in the Go one
+	prefix, _ := completionPrefix(s.Content, virtual)
+	replace := s.HostRange(core.Range{
+		Start: core.Position{Line: virtual.Line, Character: virtual.Character - len(prefix)},
+		End:   virtual,
+	})
+	lower := prefix != "" && unicode.IsLower(rune(prefix[0]))
+
+	kind := core.CompletionItemKindKeyword
+	var items []core.CompletionItem
+	for _, keyword := range sqlKeywords {
+		if !strings.HasPrefix(keyword, strings.ToUpper(prefix)) {
+			continue
+		}
+		text := keyword
+		if lower {
+			text = strings.ToLower(keyword)
+		}
+		items = append(items, core.CompletionItem{
+			Label:    text,
+			Kind:     &kind,
+			Detail:   "SQL",
+			TextEdit: &core.TextEdit{Range: replace, NewText: text},
+		})
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return &core.CompletionList{Items: items}
+}
+
+// sqlStatements are the keywords starting SQL statements.
+var sqlStatements = map[string]bool{
+	"ALTER": true, "BEGIN": true, "COMMIT": true, "CREATE": true, "DELETE": true,
+	"DROP": true, "EXPLAIN": true, "INSERT": true, "REPLACE": true,
+	"ROLLBACK": true, "SELECT": true, "TRUNCATE": true, "UPDATE": true,
+	"VALUES": true, "WITH": true,
+}
+
+// GoSQLDiagnosticProvider checks the SQL strings of Go documents for
+// unterminated string literals, unbalanced parentheses, and statements not
+// starting with a statement keyword. Diagnostics are computed on the SQL
+// documents and mapped to the Go document.
+type GoSQLDiagnosticProvider struct {
+	// QueryFuncs are the functions taking SQL; if nil,
+	// DefaultSQLQueryFuncs is used.
+	QueryFuncs map[string]int
+}
+
+func (p *GoSQLDiagnosticProvider) ProvideDiagnostics(uri, content string) []core.Diagnostic {
+	if !strings.HasSuffix(uri, ".go") {
+		return nil
+	}
+
+	var diagnostics []core.Diagnostic
+	for _, s := range FindSQLStrings(uri, content, p.QueryFuncs) {
+		for _, d := range checkSQL(s.Content) {
+			d.Range = s.HostRange(d.Range)
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	return diagnostics
+}
+
+// checkSQL returns the diagnostics of an SQL document.
+func checkSQL(sql string) []core.Diagnostic {
+	var diagnostics []core.Diagnostic
+	report := func(start, end int, severity core.DiagnosticSeverity, message string) {
+		diagnostics = append(diagnostics, core.Diagnostic{
+			Range: core.Range{
+				Start: core.ByteOffsetToPosition(sql, start),
+				End:   core.ByteOffsetToPosition(sql, end),
+			},
+			Severity: &severity,
+			Source:   "sql",
+			Message:  message,
+		})
+	}
+
+	// The first word, skipping comments
+	rest := sql
+	for {
+		trimmed := strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if strings.HasPrefix(trimmed, "--") {
+			_, trimmed, _ = strings.Cut(trimmed, "\n")
+		}
+		if trimmed == rest {
+			break
+		}
+		rest = trimmed
+	}
+	wordStart := len(sql) - len(rest)
+	wordEnd := wordStart
+	for wordEnd < len(sql) && (sql[wordEnd] == '_' || unicode.IsLetter(rune(sql[wordEnd]))) {
+		wordEnd++
+	}
+	if word := sql[wordStart:wordEnd]; word != "" && !sqlStatements[strings.ToUpper(word)] {
+		report(wordStart, wordEnd, core.SeverityWarning, fmt.Sprintf("%q does not start an SQL statement", word))
+	}
+
+	var parens []int
+	for i := 0; i < len(sql); i++ {
+		switch sql[i] {
+		case '-':
+			if strings.HasPrefix(sql[i:], "--") {
+				n := strings.IndexByte(sql[i:], '\n')
+				if n < 0 {
+					n = len(sql) - i
+				}
+				i += n
+			}
+		case '\'', '"':
+			// Quotes are escaped by doubling them
+			quote := sql[i]
+			end := -1
+			for j := i + 1; j < len(sql); j++ {
+				if sql[j] == quote {
+					if j+1 < len(sql) && sql[j+1] == quote {
+						j++
+						continue
+					}
+					end = j
+					break
+				}
+			}
+			if end < 0 {
+				report(i, len(sql), core.SeverityError, "unterminated quoted string")
+				i = len(sql)
+				break
+			}
+			i = end
+		case '(':
+			parens = append(parens, i)
+		case ')':
+			if len(parens) == 0 {
+				report(i, i+1, core.SeverityError, "unexpected )")
+				continue
+			}
+			parens = parens[:len(parens)-1]
+		}
+	}
+	for _, open := range parens {
+		report(open, open+1, core.SeverityError, "unclosed (")
+	}
+	return diagnostics
+}
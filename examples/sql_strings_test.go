@@ -0,0 +1,100 @@
+package examples
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+const testSQLSource = `package store
+
+func names(db *sql.DB) {
+	query := /* sql */ ` + "`" + `
+		SELECT name FROM users
+		WHERE (id = 1` + "`" + `
+	db.QueryContext(ctx, "SELECT \"name\" FROM t WHERE x = 'a")
+	db.Exec("UPSERT t")
+	label := "not sql"
+}
+`
+
+func TestFindSQLStrings(t *testing.T) {
+	strs := FindSQLStrings("file:///store.go", testSQLSource, nil)
+	if len(strs) != 3 {
+		t.Fatalf("FindSQLStrings found %d strings, want 3", len(strs))
+	}
+	if !strings.HasPrefix(strings.TrimSpace(strs[0].Content), "SELECT name") {
+		t.Errorf("tagged string content = %q", strs[0].Content)
+	}
+	if want := `SELECT "name" FROM t WHERE x = 'a`; strs[1].Content != want {
+		t.Errorf("query argument content = %q, want %q", strs[1].Content, want)
+	}
+
+	// The \" escape is a byte longer than the quote it stands for in the
+	// SQL document
+	s := strs[1]
+	host := core.Position{Line: 6, Character: 32}
+	virtual, ok := s.VirtualPosition(host)
+	if !ok || virtual != (core.Position{Line: 0, Character: 8}) {
+		t.Errorf("VirtualPosition(%v) = %v, %v, want 0:8", host, virtual, ok)
+	}
+	if got := s.HostPosition(virtual); got != host {
+		t.Errorf("HostPosition(%v) = %v, want %v", virtual, got, host)
+	}
+	if _, ok := s.VirtualPosition(core.Position{Line: 6, Character: 17}); ok {
+		t.Error("VirtualPosition of a position before the string is ok")
+	}
+}
+
+func TestGoSQLCompletionProvider(t *testing.T) {
+	content := strings.Replace(testSQLSource, "WHERE (id", "WHERE id = 2 or", 1)
+	content = strings.Replace(content, "SELECT name FROM users", "SELECT name fr", 1)
+	pos := core.Position{Line: 4, Character: 16}
+
+	list := (&GoSQLCompletionProvider{}).ProvideCompletions(core.CompletionContext{
+		URI:      "file:///store.go",
+		Content:  content,
+		Position: pos,
+	})
+	if list == nil || len(list.Items) != 1 {
+		t.Fatalf("ProvideCompletions = %+v, want one item", list)
+	}
+	item := list.Items[0]
+	if item.Label != "from" || item.TextEdit == nil {
+		t.Fatalf("item = %+v, want from with an edit", item)
+	}
+	if want := (core.Range{Start: core.Position{Line: 4, Character: 14}, End: pos}); item.TextEdit.Range != want {
+		t.Errorf("edit range = %v, want %v", item.TextEdit.Range, want)
+	}
+
+	outside := (&GoSQLCompletionProvider{}).ProvideCompletions(core.CompletionContext{
+		URI:      "file:///store.go",
+		Content:  content,
+		Position: core.Position{Line: 9, Character: 16},
+	})
+	if outside != nil {
+		t.Errorf("ProvideCompletions in a string that is not SQL = %+v, want nil", outside)
+	}
+}
+
+func TestGoSQLDiagnosticProvider(t *testing.T) {
+	diagnostics := (&GoSQLDiagnosticProvider{}).ProvideDiagnostics("file:///store.go", testSQLSource)
+
+	want := []struct {
+		message string
+		r       core.Range
+	}{
+		{"unclosed (", core.Range{Start: core.Position{Line: 5, Character: 8}, End: core.Position{Line: 5, Character: 9}}},
+		{"unterminated quoted string", core.Range{Start: core.Position{Line: 6, Character: 56}, End: core.Position{Line: 6, Character: 58}}},
+		{`"UPSERT" does not start an SQL statement`, core.Range{Start: core.Position{Line: 7, Character: 10}, End: core.Position{Line: 7, Character: 16}}},
+	}
+	if len(diagnostics) != len(want) {
+		t.Fatalf("ProvideDiagnostics = %+v, want %d diagnostics", diagnostics, len(want))
+	}
+	for i, w := range want {
+		if diagnostics[i].Message != w.message || diagnostics[i].Range != w.r {
+			t.Errorf("diagnostic %d = %q at %v, want %q at %v", i, diagnostics[i].Message, diagnostics[i].Range, w.message, w.r)
+		}
+	}
+}
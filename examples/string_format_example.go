@@ -0,0 +1,269 @@
+package examples
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/imports"
+)
+
+// StringFormatProvider converts between string concatenation and
+// fmt.Sprintf in Go code.
+//
+// With a concatenation chain like "Hello, " + name + "!" selected, or the
+// cursor in it, it offers fmt.Sprintf("Hello, %s!", name), importing fmt
+// if needed. With the cursor in an fmt.Sprintf call using only %s verbs
+// with string arguments, it offers the concatenation, removing the fmt
+// import if nothing else uses it.
+type StringFormatProvider struct{}
+
+func (p *StringFormatProvider) ProvideCodeFixes(ctx core.CodeFixContext) []core.CodeAction {
+	if !strings.HasSuffix(ctx.URI, ".go") || !codeActionKindRequested(ctx.Only, core.CodeActionKindRefactorRewrite) {
+		return nil
+	}
+
+	fset, f, err := parseGoFile(ctx.URI, ctx.Content)
+	if err != nil {
+		return nil
+	}
+	start := core.PositionToByteOffset(ctx.Content, ctx.Range.Start)
+	end := core.PositionToByteOffset(ctx.Content, ctx.Range.End)
+	path := goNodesEnclosing(fset, f, start, end)
+	if path == nil {
+		return nil
+	}
+	info := typeCheckGoFile(fset, f)
+
+	kind := core.CodeActionKindRefactorRewrite
+	var actions []core.CodeAction
+	if edits := p.toSprintf(ctx.Content, fset, f, info, path); edits != nil {
+		actions = append(actions, core.CodeAction{
+			Title: "Convert to fmt.Sprintf",
+			Kind:  &kind,
+			Edit:  &core.WorkspaceEdit{Changes: map[string][]core.TextEdit{ctx.URI: edits}},
+		})
+	}
+	if edits := p.toConcatenation(ctx.Content, fset, f, info, path); edits != nil {
+		actions = append(actions, core.CodeAction{
+			Title: "Convert to string concatenation",
+			Kind:  &kind,
+			Edit:  &core.WorkspaceEdit{Changes: map[string][]core.TextEdit{ctx.URI: edits}},
+		})
+	}
+	return actions
+}
+
+// toSprintf returns the edits converting the concatenation chain enclosing
+// the selection to an fmt.Sprintf call.
+func (p *StringFormatProvider) toSprintf(content string, fset *token.FileSet, f *ast.File, info *types.Info, path []ast.Node) []core.TextEdit {
+	// The outermost + of the innermost chain around the selection
+	var chain *ast.BinaryExpr
+	for i := len(path) - 1; i >= 0; i-- {
+		binary, ok := path[i].(*ast.BinaryExpr)
+		if !ok || binary.Op != token.ADD {
+			if chain != nil {
+				break
+			}
+			continue
+		}
+		chain = binary
+	}
+	if chain == nil {
+		return nil
+	}
+
+	operands := flattenConcatenation(chain)
+	var format strings.Builder
+	var args []string
+	hasLiteral := false
+	for _, operand := range operands {
+		if lit, ok := operand.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			value, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				return nil
+			}
+			format.WriteString(strings.ReplaceAll(value, "%", "%%"))
+			hasLiteral = true
+			continue
+		}
+		if t := info.Types[operand].Type; t != nil && !isStringType(t) && t != types.Typ[types.Invalid] {
+			// Numeric addition
+			return nil
+		}
+		format.WriteString("%s")
+		args = append(args, goSource(content, fset, operand))
+	}
+	if !hasLiteral || len(args) == 0 {
+		return nil
+	}
+
+	file := imports.NewFile(content, fset, f)
+	fmtName := file.Name("fmt")
+	var edits []core.TextEdit
+	if fmtName == "" {
+		fmtName = "fmt"
+		edits = append(edits, file.Add("fmt", "")...)
+	}
+	call := fmtName + ".Sprintf(" + strconv.Quote(format.String()) + ", " + strings.Join(args, ", ") + ")"
+	edits = append(edits, replaceNodeEdit(content, fset, chain, call))
+	return edits
+}
+
+// toConcatenation returns the edits converting the fmt.Sprintf call
+// enclosing the selection to a concatenation chain.
+func (p *StringFormatProvider) toConcatenation(content string, fset *token.FileSet, f *ast.File, info *types.Info, path []ast.Node) []core.TextEdit {
+	file := imports.NewFile(content, fset, f)
+	fmtName := file.Name("fmt")
+	if fmtName == "" {
+		return nil
+	}
+
+	var call *ast.CallExpr
+	for i := len(path) - 1; i >= 0 && call == nil; i-- {
+		if c, ok := path[i].(*ast.CallExpr); ok && isSelector(c.Fun, fmtName, "Sprintf") {
+			call = c
+		}
+	}
+	if call == nil || len(call.Args) == 0 || call.Ellipsis.IsValid() {
+		return nil
+	}
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return nil
+	}
+	format, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return nil
+	}
+
+	var parts []string
+	args := call.Args[1:]
+	var text strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			text.WriteByte(format[i])
+			continue
+		}
+		if i+1 >= len(format) {
+			return nil
+		}
+		i++
+		switch format[i] {
+		case '%':
+			text.WriteByte('%')
+			continue
+		case 's', 'v':
+		default:
+			// Other verbs and flags format rather than insert
+			return nil
+		}
+		if len(args) == 0 || !isStringType(info.Types[args[0]].Type) {
+			return nil
+		}
+		if text.Len() > 0 {
+			parts = append(parts, strconv.Quote(text.String()))
+			text.Reset()
+		}
+		arg := goSource(content, fset, args[0])
+		if binary, ok := args[0].(*ast.BinaryExpr); ok && binary.Op.Precedence() < token.ADD.Precedence() {
+			arg = "(" + arg + ")"
+		}
+		parts = append(parts, arg)
+		args = args[1:]
+	}
+	if len(args) > 0 {
+		return nil
+	}
+	if text.Len() > 0 || len(parts) == 0 {
+		parts = append(parts, strconv.Quote(text.String()))
+	}
+
+	edits := []core.TextEdit{replaceNodeEdit(content, fset, call, strings.Join(parts, " + "))}
+	if countSelectorsOf(f, fmtName) == 1 {
+		edits = append(edits, file.Remove("fmt")...)
+	}
+	return edits
+}
+
+// flattenConcatenation returns the operands of a chain of + expressions.
+// Parenthesized sub-chains are kept as single operands.
+func flattenConcatenation(expr ast.Expr) []ast.Expr {
+	if binary, ok := expr.(*ast.BinaryExpr); ok && binary.Op == token.ADD {
+		return append(flattenConcatenation(binary.X), flattenConcatenation(binary.Y)...)
+	}
+	return []ast.Expr{expr}
+}
+
+// isStringType reports whether t is a string type.
+func isStringType(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsString != 0
+}
+
+// isSelector reports whether expr is x.sel.
+func isSelector(expr ast.Expr, x, sel string) bool {
+	selector, ok := expr.(*ast.SelectorExpr)
+	if !ok || selector.Sel.Name != sel {
+		return false
+	}
+	ident, ok := selector.X.(*ast.Ident)
+	return ok && ident.Name == x
+}
+
+// countSelectorsOf counts the selector expressions x.Something in a file,
+// the uses of an imported package named x.
+func countSelectorsOf(f *ast.File, x string) int {
+	count := 0
+	ast.Inspect(f, func(n ast.Node) bool {
+		if selector, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := selector.X.(*ast.Ident); ok && ident.Name == x {
+				count++
+			}
+		}
+		return true
+	})
+	return count
+}
+
+// goNodesEnclosing returns the nodes containing the byte range, outermost
+// first, or nil if the range is outside of the file.
+func goNodesEnclosing(fset *token.FileSet, f *ast.File, start, end int) []ast.Node {
+	tokFile := fset.File(f.Pos())
+	if tokFile == nil || start < 0 || end < start || end > tokFile.Size() {
+		return nil
+	}
+	startPos, endPos := tokFile.Pos(start), tokFile.Pos(end)
+
+	var path []ast.Node
+	ast.Inspect(f, func(n ast.Node) bool {
+		if n == nil || startPos < n.Pos() || endPos > n.End() {
+			return false
+		}
+		path = append(path, n)
+		return true
+	})
+	return path
+}
+
+// goSource returns the source text of a node.
+func goSource(content string, fset *token.FileSet, node ast.Node) string {
+	return content[fset.Position(node.Pos()).Offset:fset.Position(node.End()).Offset]
+}
+
+// replaceNodeEdit returns an edit replacing the source text of a node.
+func replaceNodeEdit(content string, fset *token.FileSet, node ast.Node, newText string) core.TextEdit {
+	return core.TextEdit{
+		Range: core.Range{
+			Start: core.ByteOffsetToPosition(content, fset.Position(node.Pos()).Offset),
+			End:   core.ByteOffsetToPosition(content, fset.Position(node.End()).Offset),
+		},
+		NewText: newText,
+	}
+}
@@ -0,0 +1,160 @@
+package examples
+
+import (
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+func TestStringFormatProvider(t *testing.T) {
+	provider := &StringFormatProvider{}
+
+	tests := []struct {
+		name    string
+		content string
+		rng     core.Range
+		title   string
+		want    string
+	}{
+		{
+			name: "concatenation adds import",
+			content: `package main
+
+func greet(name string, n int) string {
+	return "Hello, " + name + "! 100%"
+}
+`,
+			rng:   core.Range{Start: core.Position{Line: 3, Character: 20}, End: core.Position{Line: 3, Character: 24}},
+			title: "Convert to fmt.Sprintf",
+			want: `package main
+
+import "fmt"
+
+func greet(name string, n int) string {
+	return fmt.Sprintf("Hello, %s! 100%%", name)
+}
+`,
+		},
+		{
+			name: "concatenation joins import group",
+			content: `package main
+
+import (
+	"os"
+	"strings"
+)
+
+func path(dir string) string {
+	return dir + "/" + strings.ToLower(os.Args[0])
+}
+`,
+			rng:   core.Range{Start: core.Position{Line: 8, Character: 12}, End: core.Position{Line: 8, Character: 12}},
+			title: "Convert to fmt.Sprintf",
+			want: `package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+func path(dir string) string {
+	return fmt.Sprintf("%s/%s", dir, strings.ToLower(os.Args[0]))
+}
+`,
+		},
+		{
+			name: "sprintf removes import",
+			content: `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func path(dir, name string) string {
+	_ = os.Args
+	return fmt.Sprintf("%s/%v.go", dir, name)
+}
+`,
+			rng:   core.Range{Start: core.Position{Line: 9, Character: 12}, End: core.Position{Line: 9, Character: 12}},
+			title: "Convert to string concatenation",
+			want: `package main
+
+import (
+	"os"
+)
+
+func path(dir, name string) string {
+	_ = os.Args
+	return dir + "/" + name + ".go"
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri := "file:///format_test_" + tt.name + ".go"
+			actions := provider.ProvideCodeFixes(core.CodeFixContext{URI: uri, Content: tt.content, Range: tt.rng})
+			var action *core.CodeAction
+			for i := range actions {
+				if actions[i].Title == tt.title {
+					action = &actions[i]
+				}
+			}
+			if action == nil {
+				t.Fatalf("no %q action in %v", tt.title, actions)
+			}
+			if got := applyTextEdits(tt.content, action.Edit.Changes[uri]); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringFormatProviderNoAction(t *testing.T) {
+	provider := &StringFormatProvider{}
+
+	tests := []struct {
+		name    string
+		content string
+		pos     core.Position
+	}{
+		{
+			name: "numeric addition",
+			content: `package main
+
+func sum(a, b int) int {
+	return a + b + 1
+}
+`,
+			pos: core.Position{Line: 3, Character: 10},
+		},
+		{
+			name: "formatting verb",
+			content: `package main
+
+import "fmt"
+
+func label(n int) string {
+	return fmt.Sprintf("%d items", n)
+}
+`,
+			pos: core.Position{Line: 5, Character: 12},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions := provider.ProvideCodeFixes(core.CodeFixContext{
+				URI:     "file:///format_none_" + tt.name + ".go",
+				Content: tt.content,
+				Range:   core.Range{Start: tt.pos, End: tt.pos},
+			})
+			if len(actions) != 0 {
+				t.Errorf("expected no actions, got %v", actions)
+			}
+		})
+	}
+}
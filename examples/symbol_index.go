@@ -0,0 +1,355 @@
+package examples
+
+import (
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// symbolIndexShards is the number of shards of a symbolIndex.
+const symbolIndexShards = 64
+
+// symbolIndex holds workspace symbols by file, sharded by package
+// directory so that queries scan the shards in parallel and updates only
+// lock the shard of the file they change.
+type symbolIndex struct {
+	shards [symbolIndexShards]symbolShard
+}
+
+// symbolShard holds the symbols of the files of some packages.
+type symbolShard struct {
+	mu    sync.RWMutex
+	files map[string]*indexedFile
+}
+
+// indexedFile holds the symbols of a file with their names lowercased once
+// at indexing time rather than on every query. The names are kept apart
+// from the symbols so that scans read contiguous memory.
+type indexedFile struct {
+	// mask is the union of masks; a query whose bytes are not all in it
+	// matches nothing in the file
+	mask    uint64
+	masks   []uint64
+	names   []string
+	symbols []core.WorkspaceSymbol
+
+	// signatures are the declared types of the symbols, like
+	// "func(s string) error", telling whether a symbol changed
+	signatures []string
+
+	// stamp identifies the content of the file on disk it was indexed
+	// from, zero if it was indexed from a buffer
+	stamp fileStamp
+}
+
+// fileStamp is the modification time and size of a file, which change
+// whenever its content does.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// stampOf returns the stamp of a file from its info.
+func stampOf(info fs.FileInfo) fileStamp {
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// equal reports whether two stamps are of the same content, never for
+// files indexed from a buffer.
+func (s fileStamp) equal(other fileStamp) bool {
+	return !s.modTime.IsZero() && s.modTime.Equal(other.modTime) && s.size == other.size
+}
+
+// byteMask returns a set of the bytes of s, folded to 64 bits. If a string
+// contains another, its mask includes the other's.
+func byteMask(s string) uint64 {
+	var mask uint64
+	for i := 0; i < len(s); i++ {
+		mask |= 1 << (s[i] & 63)
+	}
+	return mask
+}
+
+// shard returns the shard of the file with the normalized URI key: the
+// files of a package share a shard.
+func (x *symbolIndex) shard(key string) *symbolShard {
+	h := fnv.New32a()
+	h.Write([]byte(path.Dir(key)))
+	return &x.shards[h.Sum32()%symbolIndexShards]
+}
+
+// set replaces the symbols of a file and their signatures, indexed from
+// the content with stamp, returning the file it replaced, if any.
+func (x *symbolIndex) set(key string, symbols []core.WorkspaceSymbol, signatures []string, stamp fileStamp) *indexedFile {
+	indexed := &indexedFile{
+		masks:      make([]uint64, len(symbols)),
+		names:      make([]string, len(symbols)),
+		symbols:    symbols,
+		signatures: signatures,
+		stamp:      stamp,
+	}
+	for i, symbol := range symbols {
+		indexed.names[i] = strings.ToLower(symbol.Name)
+		indexed.masks[i] = byteMask(indexed.names[i])
+		indexed.mask |= indexed.masks[i]
+	}
+
+	s := x.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.files == nil {
+		s.files = make(map[string]*indexedFile)
+	}
+	previous := s.files[key]
+	s.files[key] = indexed
+	return previous
+}
+
+// stamp returns the stamp of an indexed file.
+func (x *symbolIndex) stamp(key string) (fileStamp, bool) {
+	s := x.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	file, ok := s.files[key]
+	if !ok {
+		return fileStamp{}, false
+	}
+	return file.stamp, true
+}
+
+// each calls fn with every indexed file by key, holding the lock of its
+// shard.
+func (x *symbolIndex) each(fn func(key string, file *indexedFile)) {
+	for i := range x.shards {
+		s := &x.shards[i]
+		s.mu.RLock()
+		for key, file := range s.files {
+			fn(key, file)
+		}
+		s.mu.RUnlock()
+	}
+}
+
+// remove drops a file from the index, returning it if it was indexed.
+func (x *symbolIndex) remove(key string) *indexedFile {
+	s := x.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.files[key]
+	delete(s.files, key)
+	return previous
+}
+
+// removeUnder drops the file with the normalized URI prefix, or the files
+// below it if it is a folder, returning the dropped files by key.
+func (x *symbolIndex) removeUnder(prefix string) map[string]*indexedFile {
+	removed := make(map[string]*indexedFile)
+	for i := range x.shards {
+		s := &x.shards[i]
+		s.mu.Lock()
+		for key, file := range s.files {
+			if _, ok := replacePathPrefix(key, prefix, ""); ok {
+				removed[key] = file
+				delete(s.files, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+	return removed
+}
+
+// count returns the number of indexed files and symbols.
+func (x *symbolIndex) count() (files, symbols int) {
+	for i := range x.shards {
+		s := &x.shards[i]
+		s.mu.RLock()
+		files += len(s.files)
+		for _, file := range s.files {
+			symbols += len(file.symbols)
+		}
+		s.mu.RUnlock()
+	}
+	return files, symbols
+}
+
+// query returns the symbols whose name contains query, ignoring case. If
+// limit is positive, at most limit symbols are returned, and the scan stops
+// once that many are found.
+func (x *symbolIndex) query(query string, limit int) []core.WorkspaceSymbol {
+	lower := strings.ToLower(query)
+
+	var found atomic.Int64
+	full := func() bool {
+		return limit > 0 && found.Load() >= int64(limit)
+	}
+
+	// Workers take shards in turn until all are scanned or enough symbols
+	// are found
+	var next atomic.Int32
+	results := make([][]core.WorkspaceSymbol, symbolIndexShards)
+	workers := min(runtime.GOMAXPROCS(0), symbolIndexShards)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= symbolIndexShards || full() {
+					return
+				}
+				results[i] = x.shards[i].query(lower, byteMask(lower), full, &found)
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for _, matches := range results {
+		total += len(matches)
+	}
+	if limit > 0 {
+		total = min(total, limit)
+	}
+	if total == 0 {
+		return nil
+	}
+	symbols := make([]core.WorkspaceSymbol, 0, total)
+	for _, matches := range results {
+		symbols = append(symbols, matches[:min(len(matches), total-len(symbols))]...)
+	}
+	return symbols
+}
+
+// query returns the symbols of the shard whose lowercased name contains
+// lower, whose byte mask is mask, checking full after each file.
+func (s *symbolShard) query(lower string, mask uint64, full func() bool, found *atomic.Int64) []core.WorkspaceSymbol {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matches []core.WorkspaceSymbol
+	for _, file := range s.files {
+		if file.mask&mask != mask {
+			continue
+		}
+		n := len(matches)
+		for i, name := range file.names {
+			if file.masks[i]&mask == mask && strings.Contains(name, lower) {
+				matches = append(matches, file.symbols[i])
+			}
+		}
+		if len(matches) == n {
+			continue
+		}
+		found.Add(int64(len(matches) - n))
+		if full() {
+			break
+		}
+	}
+	return matches
+}
+
+// signatureOf returns the signature of an indexed symbol of the file with
+// the normalized URI key, found by name, kind, and position. ok is false
+// if the symbol is not indexed, as when its file changed since the query
+// that returned it.
+func (x *symbolIndex) signatureOf(key string, symbol core.WorkspaceSymbol) (signature string, ok bool) {
+	s := x.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	file := s.files[key]
+	if file == nil {
+		return "", false
+	}
+	for i, indexed := range file.symbols {
+		if indexed.Name == symbol.Name && indexed.Kind == symbol.Kind && indexed.Location.Range.Start == symbol.Location.Range.Start {
+			return file.signature(i), true
+		}
+	}
+	return "", false
+}
+
+// SymbolsChangedEvent reports the symbols of a file that changed
+// materially when it was re-indexed: declared, removed, or given another
+// signature or tags. Symbols that only moved are not reported, so
+// dependents like code lenses, outline caches, and incremental exports can
+// refresh just the files whose symbols they rely on.
+type SymbolsChangedEvent struct {
+	// URI is the URI of the file.
+	URI string
+
+	// Added and Removed are the symbols declared and no longer declared.
+	Added   []core.WorkspaceSymbol
+	Removed []core.WorkspaceSymbol
+
+	// Changed are the symbols with another signature or tags, as they are
+	// now.
+	Changed []core.WorkspaceSymbol
+}
+
+// empty reports whether the event reports no change.
+func (e SymbolsChangedEvent) empty() bool {
+	return len(e.Added) == 0 && len(e.Removed) == 0 && len(e.Changed) == 0
+}
+
+// symbolIdentity identifies a symbol across versions of its file.
+type symbolIdentity struct {
+	container, name string
+	kind            core.SymbolKind
+}
+
+// diffSymbols returns the material changes from the symbols of before to
+// those of after. Either may be nil. Symbols of the same identity, like
+// the init functions of a file, are paired in order.
+func diffSymbols(uri string, before, after *indexedFile) SymbolsChangedEvent {
+	event := SymbolsChangedEvent{URI: uri}
+	previous := make(map[symbolIdentity][]int)
+	if before != nil {
+		for i, symbol := range before.symbols {
+			id := symbolIdentity{symbol.ContainerName, symbol.Name, symbol.Kind}
+			previous[id] = append(previous[id], i)
+		}
+	}
+	if after != nil {
+		for i, symbol := range after.symbols {
+			id := symbolIdentity{symbol.ContainerName, symbol.Name, symbol.Kind}
+			if len(previous[id]) == 0 {
+				event.Added = append(event.Added, symbol)
+				continue
+			}
+			j := previous[id][0]
+			previous[id] = previous[id][1:]
+			if after.signature(i) != before.signature(j) || !slices.Equal(symbol.Tags, before.symbols[j].Tags) {
+				event.Changed = append(event.Changed, symbol)
+			}
+		}
+	}
+	if before != nil {
+		// The symbols left unpaired, in their order in the file
+		var removed []int
+		for _, indexes := range previous {
+			removed = append(removed, indexes...)
+		}
+		slices.Sort(removed)
+		for _, i := range removed {
+			event.Removed = append(event.Removed, before.symbols[i])
+		}
+	}
+	return event
+}
+
+// signature returns the signature of the i-th symbol, if known.
+func (f *indexedFile) signature(i int) string {
+	if i < len(f.signatures) {
+		return f.signatures[i]
+	}
+	return ""
+}
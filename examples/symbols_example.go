@@ -2,7 +2,6 @@ package examples
 
 import (
 	"go/ast"
-	"go/parser"
 	"go/token"
 	"strings"
 
@@ -10,30 +9,159 @@ import (
 )
 
 // GoSymbolProvider provides document symbols for Go source files.
-type GoSymbolProvider struct{}
+type GoSymbolProvider struct {
+	// TypeParams renders the type parameters of generic functions and
+	// types in symbol details.
+	TypeParams TypeParamRenderer
+}
 
 func (p *GoSymbolProvider) ProvideDocumentSymbols(uri, content string) []core.DocumentSymbol {
 	if !strings.HasSuffix(uri, ".go") {
 		return nil
 	}
 
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	fset, f, err := parseGoFile(uri, content)
 	if err != nil {
 		return nil
 	}
 
 	var symbols []core.DocumentSymbol
 
+	// The members of the enums of the types declared here are nested in
+	// their type rather than listed with the constant blocks
+	enums := goEnumBlocks(f)
+	nested := make(map[*ast.GenDecl]bool)
+	for _, typeName := range goDeclaredTypes(f) {
+		for _, block := range enums[typeName] {
+			nested[block] = true
+		}
+	}
+
 	for _, decl := range f.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && nested[gen] {
+			continue
+		}
 		if symbol := p.declToSymbol(decl, fset); symbol != nil {
 			symbols = append(symbols, *symbol)
 		}
 	}
+	p.nestEnumMembers(symbols, enums, fset)
 
 	return symbols
 }
 
+// nestEnumMembers makes the symbols of the types of enums, at the top
+// level or in a group of types, Enum symbols with their constants as
+// EnumMember children. The range of a type extends over its constants.
+func (p *GoSymbolProvider) nestEnumMembers(symbols []core.DocumentSymbol, enums map[string][]*ast.GenDecl, fset *token.FileSet) {
+	for i := range symbols {
+		symbol := &symbols[i]
+		if symbol.Kind == core.SymbolKindClass && symbol.Name == "types" && symbol.Detail == "" {
+			p.nestEnumMembers(symbol.Children, enums, fset)
+			continue
+		}
+		blocks := enums[symbol.Name]
+		if symbol.Kind != core.SymbolKindClass || len(blocks) == 0 {
+			continue
+		}
+
+		symbol.Kind = core.SymbolKindEnum
+		for _, block := range blocks {
+			for _, spec := range block.Specs {
+				members := p.valueSpecToSymbols(spec.(*ast.ValueSpec), token.CONST, fset)
+				for _, member := range members {
+					if member.Name == "_" {
+						continue
+					}
+					member.Kind = core.SymbolKindEnumMember
+					member.Tags = deprecatedSymbolTags(declSpecDoc(block, spec))
+					symbol.Children = append(symbol.Children, member)
+				}
+			}
+
+			start := fset.Position(block.Pos())
+			end := fset.Position(block.End())
+			blockRange := core.Range{
+				Start: core.Position{Line: start.Line - 1, Character: start.Column - 1},
+				End:   core.Position{Line: end.Line - 1, Character: end.Column - 1},
+			}
+			if core.ComparePositions(blockRange.Start, symbol.Range.Start) < 0 {
+				symbol.Range.Start = blockRange.Start
+			}
+			if core.ComparePositions(blockRange.End, symbol.Range.End) > 0 {
+				symbol.Range.End = blockRange.End
+			}
+		}
+	}
+}
+
+// goEnumBlocks returns the Go enums of a file, the constant blocks
+// enumerating the values of a type with iota, like
+//
+//	const (
+//		Red Color = iota
+//		Green
+//	)
+//
+// by the name of the type. In an enum block, the first constant has the
+// type and a value using iota, and the others repeat it or have the type.
+func goEnumBlocks(f *ast.File) map[string][]*ast.GenDecl {
+	enums := make(map[string][]*ast.GenDecl)
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST || !gen.Lparen.IsValid() || len(gen.Specs) == 0 {
+			continue
+		}
+		first := gen.Specs[0].(*ast.ValueSpec)
+		typeName, ok := first.Type.(*ast.Ident)
+		if !ok || !usesIota(first.Values) {
+			continue
+		}
+		enum := true
+		for _, spec := range gen.Specs[1:] {
+			spec := spec.(*ast.ValueSpec)
+			if ident, ok := spec.Type.(*ast.Ident); ok && ident.Name == typeName.Name {
+				continue
+			}
+			if spec.Type != nil || len(spec.Values) > 0 {
+				enum = false
+				break
+			}
+		}
+		if enum {
+			enums[typeName.Name] = append(enums[typeName.Name], gen)
+		}
+	}
+	return enums
+}
+
+// usesIota reports whether one of values refers to iota.
+func usesIota(values []ast.Expr) bool {
+	found := false
+	for _, value := range values {
+		ast.Inspect(value, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok && ident.Name == "iota" {
+				found = true
+			}
+			return !found
+		})
+	}
+	return found
+}
+
+// goDeclaredTypes returns the names of the package-level types of a file.
+func goDeclaredTypes(f *ast.File) []string {
+	var names []string
+	for _, decl := range f.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.TYPE {
+			for _, spec := range gen.Specs {
+				names = append(names, spec.(*ast.TypeSpec).Name.Name)
+			}
+		}
+	}
+	return names
+}
+
 func (p *GoSymbolProvider) declToSymbol(decl ast.Decl, fset *token.FileSet) *core.DocumentSymbol {
 	switch d := decl.(type) {
 	case *ast.FuncDecl:
@@ -67,6 +195,7 @@ func (p *GoSymbolProvider) funcToSymbol(fn *ast.FuncDecl, fset *token.FileSet) *
 		Name:   name,
 		Detail: detail,
 		Kind:   kind,
+		Tags:   deprecatedSymbolTags(fn.Doc),
 		Range: core.Range{
 			Start: core.Position{Line: start.Line - 1, Character: start.Column - 1},
 			End:   core.Position{Line: end.Line - 1, Character: end.Column - 1},
@@ -83,6 +212,7 @@ func (p *GoSymbolProvider) funcToSymbol(fn *ast.FuncDecl, fset *token.FileSet) *
 func (p *GoSymbolProvider) getFunctionSignature(fn *ast.FuncDecl) string {
 	var sig strings.Builder
 	sig.WriteString(fn.Name.Name)
+	sig.WriteString(p.TypeParams.TypeParams(fn.Type.TypeParams, p.exprToString))
 	sig.WriteString("(")
 
 	if fn.Type.Params != nil {
@@ -128,12 +258,19 @@ func (p *GoSymbolProvider) genDeclToSymbol(gen *ast.GenDecl, fset *token.FileSet
 	var symbols []core.DocumentSymbol
 
 	for _, spec := range gen.Specs {
+		var specSymbols []core.DocumentSymbol
 		switch s := spec.(type) {
 		case *ast.TypeSpec:
-			symbols = append(symbols, p.typeSpecToSymbols(s, fset)...)
+			specSymbols = p.typeSpecToSymbols(s, fset)
 		case *ast.ValueSpec:
-			symbols = append(symbols, p.valueSpecToSymbols(s, gen.Tok, fset)...)
+			specSymbols = p.valueSpecToSymbols(s, gen.Tok, fset)
 		}
+		if tags := deprecatedSymbolTags(declSpecDoc(gen, spec)); tags != nil {
+			for i := range specSymbols {
+				specSymbols[i].Tags = tags
+			}
+		}
+		symbols = append(symbols, specSymbols...)
 	}
 
 	if len(symbols) == 1 {
@@ -192,9 +329,14 @@ func (p *GoSymbolProvider) typeSpecToSymbols(spec *ast.TypeSpec, fset *token.Fil
 		kind = core.SymbolKindInterface
 	}
 
+	detail := p.exprToString(spec.Type)
+	if typeParams := p.TypeParams.TypeParams(spec.TypeParams, p.exprToString); typeParams != "" {
+		detail = typeParams + " " + detail
+	}
+
 	symbol := core.DocumentSymbol{
 		Name:   spec.Name.Name,
-		Detail: p.exprToString(spec.Type),
+		Detail: detail,
 		Kind:   kind,
 		Range: core.Range{
 			Start: core.Position{Line: typeStart.Line - 1, Character: typeStart.Column - 1},
@@ -274,6 +416,7 @@ func (p *GoSymbolProvider) getStructFields(st *ast.StructType, fset *token.FileS
 				Name:   name.Name,
 				Detail: p.exprToString(field.Type),
 				Kind:   core.SymbolKindField,
+				Tags:   deprecatedSymbolTags(field.Doc),
 				Range: core.Range{
 					Start: core.Position{Line: fieldStart.Line - 1, Character: fieldStart.Column - 1},
 					End:   core.Position{Line: fieldEnd.Line - 1, Character: fieldEnd.Column - 1},
@@ -307,6 +450,7 @@ func (p *GoSymbolProvider) getInterfaceMethods(it *ast.InterfaceType, fset *toke
 				Name:   name.Name,
 				Detail: p.exprToString(method.Type),
 				Kind:   core.SymbolKindMethod,
+				Tags:   deprecatedSymbolTags(method.Doc),
 				Range: core.Range{
 					Start: core.Position{Line: methodStart.Line - 1, Character: methodStart.Column - 1},
 					End:   core.Position{Line: methodEnd.Line - 1, Character: methodEnd.Column - 1},
@@ -338,6 +482,10 @@ func (p *GoSymbolProvider) exprToString(expr ast.Expr) string {
 		return "map[" + p.exprToString(e.Key) + "]" + p.exprToString(e.Value)
 	case *ast.SelectorExpr:
 		return p.exprToString(e.X) + "." + e.Sel.Name
+	case *ast.IndexExpr:
+		return instantiatedTypeString(e.X, []ast.Expr{e.Index}, p.exprToString)
+	case *ast.IndexListExpr:
+		return instantiatedTypeString(e.X, e.Indices, p.exprToString)
 	case *ast.StructType:
 		return "struct{...}"
 	case *ast.InterfaceType:
@@ -348,3 +496,36 @@ func (p *GoSymbolProvider) exprToString(expr ast.Expr) string {
 		return "..."
 	}
 }
+
+// isDeprecatedDoc reports whether a doc comment has a paragraph starting
+// with "Deprecated: ", the Go convention for deprecated declarations.
+func isDeprecatedDoc(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, paragraph := range strings.Split(doc.Text(), "\n\n") {
+		if strings.HasPrefix(paragraph, "Deprecated: ") {
+			return true
+		}
+	}
+	return false
+}
+
+// deprecatedSymbolTags returns the tags of a symbol with the doc comment:
+// SymbolTagDeprecated if it is deprecated, or nil.
+func deprecatedSymbolTags(doc *ast.CommentGroup) []core.SymbolTag {
+	if !isDeprecatedDoc(doc) {
+		return nil
+	}
+	return []core.SymbolTag{core.SymbolTagDeprecated}
+}
+
+// declSpecDoc returns the doc comment of a type, const, or var spec: its
+// own, or else the one of its declaration, which documents every spec of a
+// group.
+func declSpecDoc(gen *ast.GenDecl, spec ast.Spec) *ast.CommentGroup {
+	if doc := specDoc(spec); doc != nil {
+		return doc
+	}
+	return gen.Doc
+}
@@ -610,3 +610,109 @@ func (c *Counter) Increment() {
 		t.Errorf("got %d methods, want 2", methodCount)
 	}
 }
+
+func TestGoSymbolProvider_Deprecated(t *testing.T) {
+	content := `package main
+
+// Old does nothing.
+//
+// Deprecated: Use New instead.
+func Old() {}
+
+// New does nothing.
+func New() {}
+
+// Deprecated: Use the Mode type.
+const (
+	ModeA = 1
+	ModeB = 2
+)
+
+type Config struct {
+	// Deprecated: Use Timeout.
+	Wait int
+	Timeout int
+}
+`
+	symbols := (&GoSymbolProvider{}).ProvideDocumentSymbols("file:///deprecated.go", content)
+
+	var deprecated []string
+	for _, symbol := range core.FlattenDocumentSymbols("file:///deprecated.go", symbols) {
+		if len(symbol.Tags) == 1 && symbol.Tags[0] == core.SymbolTagDeprecated {
+			deprecated = append(deprecated, symbol.Name)
+		}
+	}
+	if got := strings.Join(deprecated, ","); got != "Old,ModeA,ModeB,Wait" {
+		t.Errorf("deprecated symbols = %s, want Old,ModeA,ModeB,Wait", got)
+	}
+}
+
+func TestGoSymbolProvider_Enums(t *testing.T) {
+	content := `package main
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+	// Deprecated: Use Green.
+	Lime
+)
+
+type (
+	Weekday int
+	Name    string
+)
+
+const (
+	_ Weekday = iota
+	Monday
+	Tuesday Weekday = 2
+)
+
+// Imported types have no enum symbol
+const (
+	ReadOnly os.FileMode = iota
+	ReadWrite
+)
+
+// Untyped iota blocks are plain constants
+const (
+	A = iota
+	B
+)
+`
+	symbols := (&GoSymbolProvider{}).ProvideDocumentSymbols("file:///enums.go", content)
+
+	kinds := map[core.SymbolKind]string{
+		core.SymbolKindClass:      "Class",
+		core.SymbolKindConstant:   "Constant",
+		core.SymbolKindEnum:       "Enum",
+		core.SymbolKindEnumMember: "EnumMember",
+	}
+	var outline []string
+	var describe func(prefix string, symbols []core.DocumentSymbol)
+	describe = func(prefix string, symbols []core.DocumentSymbol) {
+		for _, symbol := range symbols {
+			outline = append(outline, prefix+symbol.Name+":"+kinds[symbol.Kind])
+			describe(prefix+symbol.Name+".", symbol.Children)
+		}
+	}
+	describe("", symbols)
+	want := "Color:Enum,Color.Red:EnumMember,Color.Green:EnumMember,Color.Lime:EnumMember," +
+		"types:Class,types.Weekday:Enum,types.Weekday.Monday:EnumMember,types.Weekday.Tuesday:EnumMember,types.Name:Class," +
+		"constants:Constant,constants.ReadOnly:Constant,constants.ReadWrite:Constant," +
+		"constants:Constant,constants.A:Constant,constants.B:Constant"
+	if got := strings.Join(outline, ","); got != want {
+		t.Errorf("outline =\n%s\nwant\n%s", got, want)
+	}
+
+	// The range of an enum covers its constants, and their tags are kept
+	color := symbols[0]
+	if color.Range.Start.Line != 2 || color.Range.End.Line != 9 {
+		t.Errorf("range of Color = %v, want lines 2 to 9", color.Range)
+	}
+	if lime := color.Children[2]; len(lime.Tags) != 1 || lime.Tags[0] != core.SymbolTagDeprecated {
+		t.Errorf("tags of Lime = %v, want deprecated", lime.Tags)
+	}
+}
@@ -0,0 +1,518 @@
+package examples
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// Go templates, of text/template and html/template, are documents with
+// actions between {{ and }}. The providers below find actions with a small
+// scanner rather than the template parser, so they keep working while a
+// template is being edited and does not parse.
+
+// templateExtensions are the file extensions of Go templates.
+var templateExtensions = map[string]bool{
+	".tmpl":   true,
+	".gotmpl": true,
+	".gohtml": true,
+	".tpl":    true,
+}
+
+// isGoTemplate reports whether the document uri is a Go template.
+func isGoTemplate(uri string) bool {
+	return templateExtensions[path.Ext(uri)]
+}
+
+// templateAction is an action of a template, like {{if .Ready}}.
+type templateAction struct {
+	start, end int // offsets of the delimiters, end is after }}
+
+	// body is the text between the delimiters and trim markers
+	bodyStart, bodyEnd int
+
+	// keyword is the keyword starting the action, like "if" or "end", or
+	// "" for pipelines
+	keyword string
+
+	// name is the template name of a define, block, or template action,
+	// unquoted, and nameStart and nameEnd the offsets of its literal
+	name               string
+	nameStart, nameEnd int
+
+	comment bool
+}
+
+// templateKeywords are the keywords starting actions.
+var templateKeywords = map[string]bool{
+	"block": true, "break": true, "continue": true, "define": true, "else": true,
+	"end": true, "if": true, "range": true, "template": true, "with": true,
+}
+
+// scanTemplateActions returns the actions of a template, in order. An action
+// without its closing delimiter ends the scan.
+func scanTemplateActions(content string) []templateAction {
+	var actions []templateAction
+	offset := 0
+	for {
+		i := strings.Index(content[offset:], "{{")
+		if i < 0 {
+			return actions
+		}
+		action := templateAction{start: offset + i}
+		action.bodyStart = action.start + 2
+		if strings.HasPrefix(content[action.bodyStart:], "- ") {
+			action.bodyStart += 2
+		}
+
+		end := templateActionEnd(content, action.bodyStart)
+		if end < 0 {
+			return actions
+		}
+		action.end = end
+		action.bodyEnd = end - 2
+		if action.bodyEnd-2 >= action.bodyStart && content[action.bodyEnd-2:action.bodyEnd] == " -" {
+			action.bodyEnd -= 2
+		}
+
+		body := content[action.bodyStart:action.bodyEnd]
+		trimmed := strings.TrimLeft(body, " \t\r\n")
+		action.comment = strings.HasPrefix(trimmed, "/*")
+		if !action.comment {
+			word := trimmed
+			if n := strings.IndexAny(word, " \t\r\n"); n >= 0 {
+				word = word[:n]
+			}
+			if templateKeywords[word] {
+				action.keyword = word
+			}
+			switch action.keyword {
+			case "define", "block", "template":
+				argStart := action.bodyStart + len(body) - len(trimmed) + len(word)
+				rest := content[argStart:action.bodyEnd]
+				literalStart := argStart + len(rest) - len(strings.TrimLeft(rest, " \t\r\n"))
+				if literal, err := strconv.QuotedPrefix(content[literalStart:action.bodyEnd]); err == nil {
+					action.name, _ = strconv.Unquote(literal)
+					action.nameStart, action.nameEnd = literalStart, literalStart+len(literal)
+				}
+			}
+		}
+
+		actions = append(actions, action)
+		offset = action.end
+	}
+}
+
+// templateActionEnd returns the offset after the }} closing an action whose
+// body starts at offset, skipping string literals and comments, or -1.
+func templateActionEnd(content string, offset int) int {
+	for i := offset; i < len(content); i++ {
+		switch c := content[i]; c {
+		case '}':
+			if strings.HasPrefix(content[i:], "}}") {
+				return i + 2
+			}
+		case '"', '\'':
+			for i++; i < len(content) && content[i] != c && content[i] != '\n'; i++ {
+				if content[i] == '\\' {
+					i++
+				}
+			}
+		case '`':
+			n := strings.IndexByte(content[i+1:], '`')
+			if n < 0 {
+				return -1
+			}
+			i += n + 1
+		case '/':
+			if strings.HasPrefix(content[i:], "/*") {
+				n := strings.Index(content[i+2:], "*/")
+				if n < 0 {
+					return -1
+				}
+				i += n + 3
+			}
+		}
+	}
+	return -1
+}
+
+// templateBlock is an action opening a block, like {{range}}, with the
+// {{end}} closing it.
+type templateBlock struct {
+	open, end templateAction
+}
+
+// templateBlocks returns the blocks of the actions, in the order of their
+// {{end}}. Unclosed blocks and stray {{end}}s are ignored.
+func templateBlocks(actions []templateAction) []templateBlock {
+	var blocks []templateBlock
+	var stack []templateAction
+	for _, action := range actions {
+		switch action.keyword {
+		case "if", "range", "with", "define", "block":
+			stack = append(stack, action)
+		case "end":
+			if len(stack) > 0 {
+				blocks = append(blocks, templateBlock{open: stack[len(stack)-1], end: action})
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	return blocks
+}
+
+// GoTemplateFoldingProvider provides folding ranges for Go templates: blocks
+// from their opening action to their {{end}}, which stays visible, and
+// comments spanning several lines.
+type GoTemplateFoldingProvider struct{}
+
+func (p *GoTemplateFoldingProvider) ProvideFoldingRanges(uri, content string) []core.FoldingRange {
+	if !isGoTemplate(uri) {
+		return nil
+	}
+
+	actions := scanTemplateActions(content)
+	var ranges []core.FoldingRange
+	for _, block := range templateBlocks(actions) {
+		start := core.ByteOffsetToPosition(content, block.open.start)
+		end := core.ByteOffsetToPosition(content, block.end.start)
+		if end.Line-1 <= start.Line {
+			continue
+		}
+		r := core.FoldingRange{StartLine: start.Line, EndLine: end.Line - 1}
+		if block.open.keyword == "define" || block.open.keyword == "block" {
+			kind := core.FoldingRangeKindRegion
+			r.Kind = &kind
+		}
+		ranges = append(ranges, r)
+	}
+
+	for _, action := range actions {
+		if !action.comment {
+			continue
+		}
+		start := core.ByteOffsetToPosition(content, action.start)
+		end := core.ByteOffsetToPosition(content, action.end)
+		if end.Line > start.Line {
+			kind := core.FoldingRangeKindComment
+			ranges = append(ranges, core.FoldingRange{StartLine: start.Line, EndLine: end.Line, Kind: &kind})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].StartLine < ranges[j].StartLine
+	})
+	return ranges
+}
+
+// GoTemplateSymbolProvider provides the templates defined by a Go template
+// with {{define}} and {{block}} as document symbols. Blocks inside a
+// definition are its children.
+type GoTemplateSymbolProvider struct{}
+
+func (p *GoTemplateSymbolProvider) ProvideDocumentSymbols(uri, content string) []core.DocumentSymbol {
+	if !isGoTemplate(uri) {
+		return nil
+	}
+
+	var definitions []templateBlock
+	for _, block := range templateBlocks(scanTemplateActions(content)) {
+		if (block.open.keyword == "define" || block.open.keyword == "block") && block.open.name != "" {
+			definitions = append(definitions, block)
+		}
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].open.start < definitions[j].open.start
+	})
+	return templateSymbols(content, definitions)
+}
+
+// templateSymbols returns the symbols of definitions sorted by offset,
+// nesting the definitions inside others.
+func templateSymbols(content string, definitions []templateBlock) []core.DocumentSymbol {
+	var symbols []core.DocumentSymbol
+	for len(definitions) > 0 {
+		def := definitions[0]
+		inner := 1
+		for inner < len(definitions) && definitions[inner].open.start < def.end.end {
+			inner++
+		}
+
+		kind := core.SymbolKindFunction
+		if def.open.keyword == "block" {
+			kind = core.SymbolKindNamespace
+		}
+		symbols = append(symbols, core.DocumentSymbol{
+			Name:   def.open.name,
+			Detail: def.open.keyword,
+			Kind:   kind,
+			Range: core.Range{
+				Start: core.ByteOffsetToPosition(content, def.open.start),
+				End:   core.ByteOffsetToPosition(content, def.end.end),
+			},
+			SelectionRange: core.Range{
+				Start: core.ByteOffsetToPosition(content, def.open.nameStart),
+				End:   core.ByteOffsetToPosition(content, def.open.nameEnd),
+			},
+			Children: templateSymbols(content, definitions[1:inner]),
+		})
+		definitions = definitions[inner:]
+	}
+	return symbols
+}
+
+// templateActionAt returns the offset where the body of the action at offset
+// starts. The action may be unclosed, like while its pipeline is typed. ok
+// is false outside actions and in comments.
+func templateActionAt(content string, offset int) (bodyStart int, ok bool) {
+	actions := scanTemplateActions(content)
+	scanned := 0
+	for _, action := range actions {
+		if offset >= action.bodyStart && offset <= action.bodyEnd {
+			return action.bodyStart, !action.comment
+		}
+		scanned = action.end
+	}
+	if offset < scanned {
+		return 0, false
+	}
+	i := strings.LastIndex(content[scanned:offset], "{{")
+	if i < 0 {
+		return 0, false
+	}
+	bodyStart = scanned + i + 2
+	if strings.HasPrefix(content[bodyStart:], "- ") {
+		bodyStart += 2
+	}
+	if offset < bodyStart || strings.HasPrefix(strings.TrimLeft(content[bodyStart:offset], " \t\r\n"), "/*") {
+		return 0, false
+	}
+	return bodyStart, true
+}
+
+// templateBuiltins documents the functions predefined by text/template and
+// html/template.
+var templateBuiltins = map[string]string{
+	"and":      "`and x y ...`\n\nReturns the boolean AND of its arguments: the first empty argument or the last argument. Evaluation stops at the first empty argument.",
+	"call":     "`call fn args...`\n\nReturns the result of calling the first argument, which must be a function, with the remaining arguments as parameters.",
+	"html":     "`html args...`\n\nReturns the escaped HTML equivalent of the textual representation of its arguments. Not available in html/template, which escapes automatically.",
+	"index":    "`index x 1 2 3`\n\nReturns the result of indexing its first argument by the following arguments, like `x[1][2][3]`. Each indexed item must be a map, slice, or array.",
+	"slice":    "`slice x 1 2`\n\nReturns the result of slicing its first argument by the remaining arguments, like `x[1:2]`. The first argument must be a string, slice, or array.",
+	"js":       "`js args...`\n\nReturns the escaped JavaScript equivalent of the textual representation of its arguments.",
+	"len":      "`len x`\n\nReturns the integer length of its argument.",
+	"not":      "`not x`\n\nReturns the boolean negation of its single argument.",
+	"or":       "`or x y ...`\n\nReturns the boolean OR of its arguments: the first non-empty argument or the last argument. Evaluation stops at the first non-empty argument.",
+	"print":    "`print args...`\n\nAn alias for fmt.Sprint.",
+	"printf":   "`printf format args...`\n\nAn alias for fmt.Sprintf.",
+	"println":  "`println args...`\n\nAn alias for fmt.Sprintln.",
+	"urlquery": "`urlquery args...`\n\nReturns the escaped value of the textual representation of its arguments in a form suitable for embedding in a URL query. Not available in html/template.",
+	"eq":       "`eq arg1 arg2 ...`\n\nReturns the boolean truth of `arg1 == arg2`, or of arg1 being equal to any of the following arguments.",
+	"ne":       "`ne arg1 arg2`\n\nReturns the boolean truth of `arg1 != arg2`.",
+	"lt":       "`lt arg1 arg2`\n\nReturns the boolean truth of `arg1 < arg2`.",
+	"le":       "`le arg1 arg2`\n\nReturns the boolean truth of `arg1 <= arg2`.",
+	"gt":       "`gt arg1 arg2`\n\nReturns the boolean truth of `arg1 > arg2`.",
+	"ge":       "`ge arg1 arg2`\n\nReturns the boolean truth of `arg1 >= arg2`.",
+}
+
+// templateKeywordDocs documents the keywords of actions.
+var templateKeywordDocs = map[string]string{
+	"if":       "`{{if pipeline}} T1 {{else}} T0 {{end}}`\n\nIf the value of the pipeline is not empty, T1 is executed, otherwise T0.",
+	"else":     "`{{else}}`, `{{else if pipeline}}`, `{{else with pipeline}}`\n\nStarts the alternative of an if, range, or with action.",
+	"range":    "`{{range pipeline}} T1 {{else}} T0 {{end}}`\n\nThe value of the pipeline must be an array, slice, map, iterator, channel, or integer. T1 is executed for each element with dot set to it, or T0 if there are none.",
+	"with":     "`{{with pipeline}} T1 {{else}} T0 {{end}}`\n\nIf the value of the pipeline is not empty, dot is set to it and T1 is executed, otherwise T0.",
+	"define":   "`{{define \"name\"}} T {{end}}`\n\nDefines the template named name.",
+	"block":    "`{{block \"name\" pipeline}} T {{end}}`\n\nDefines the template named name and executes it in place, a shorthand for define followed by template. Other templates may redefine it.",
+	"template": "`{{template \"name\" pipeline}}`\n\nExecutes the template named name with dot set to the value of the pipeline, or nil without one.",
+	"end":      "`{{end}}`\n\nEnds an if, range, with, define, or block action.",
+	"break":    "`{{break}}`\n\nEnds the innermost range loop early.",
+	"continue": "`{{continue}}`\n\nStops the current iteration of the innermost range loop and starts the next one.",
+}
+
+// templateWordAt returns the function or keyword at pos in an action of a
+// template, or "" for other words, like fields and variables.
+func templateWordAt(content string, pos core.Position) (string, core.Range) {
+	word, r := core.WordAt(content, pos)
+	if word == "" {
+		return "", core.Range{}
+	}
+	start := core.PositionToByteOffset(content, r.Start)
+	if _, ok := templateActionAt(content, start); !ok {
+		return "", core.Range{}
+	}
+	if start > 0 && (content[start-1] == '.' || content[start-1] == '$') {
+		return "", core.Range{}
+	}
+	return word, r
+}
+
+// templateFuncSignature returns the signature of a function of a FuncMap,
+// like "func(string) string".
+func templateFuncSignature(fn any) string {
+	if fn == nil {
+		return ""
+	}
+	return reflect.TypeOf(fn).String()
+}
+
+// GoTemplateHoverProvider documents the keywords and predefined functions of
+// Go templates, and shows the signatures of the functions in Funcs.
+type GoTemplateHoverProvider struct {
+	// Funcs are the functions added to the templates, like with
+	// template.Funcs. A text/template or html/template FuncMap can be
+	// assigned.
+	Funcs map[string]any
+}
+
+func (p *GoTemplateHoverProvider) ProvideHover(uri, content string, position core.Position) *core.HoverInfo {
+	if !isGoTemplate(uri) {
+		return nil
+	}
+
+	word, r := templateWordAt(content, position)
+	if word == "" {
+		return nil
+	}
+
+	var contents string
+	if fn, ok := p.Funcs[word]; ok {
+		// Functions added to templates replace the predefined ones
+		contents = fmt.Sprintf("```go\n%s %s\n```", word, templateFuncSignature(fn))
+	} else if doc, ok := templateBuiltins[word]; ok {
+		contents = doc
+	} else if doc, ok := templateKeywordDocs[word]; ok {
+		contents = doc
+	} else {
+		return nil
+	}
+	return &core.HoverInfo{Contents: contents, Range: &r}
+}
+
+// GoTemplateCompletionProvider completes the functions of pipelines in Go
+// templates, predefined or in Funcs, and keywords at the start of actions.
+type GoTemplateCompletionProvider struct {
+	// Funcs are the functions added to the templates, like with
+	// template.Funcs.
+	Funcs map[string]any
+}
+
+func (p *GoTemplateCompletionProvider) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
+	if !isGoTemplate(ctx.URI) {
+		return nil
+	}
+
+	offset := core.PositionToByteOffset(ctx.Content, ctx.Position)
+	if offset < 0 {
+		return nil
+	}
+	bodyStart, ok := templateActionAt(ctx.Content, offset)
+	if !ok {
+		return nil
+	}
+	prefix, ok := completionPrefix(ctx.Content, ctx.Position)
+	if !ok {
+		return nil
+	}
+	prefixStart := offset - len(prefix)
+	if prefixStart > 0 && (ctx.Content[prefixStart-1] == '.' || ctx.Content[prefixStart-1] == '$') {
+		return nil
+	}
+
+	var items []core.CompletionItem
+	if strings.TrimSpace(ctx.Content[bodyStart:prefixStart]) == "" {
+		kind := core.CompletionItemKindKeyword
+		for _, keyword := range sortedKeys(templateKeywordDocs) {
+			if strings.HasPrefix(keyword, prefix) {
+				items = append(items, core.CompletionItem{Label: keyword, Kind: &kind, Documentation: templateKeywordDocs[keyword]})
+			}
+		}
+	}
+
+	kind := core.CompletionItemKindFunction
+	for _, name := range sortedKeys(p.Funcs) {
+		if strings.HasPrefix(name, prefix) {
+			items = append(items, core.CompletionItem{Label: name, Kind: &kind, Detail: templateFuncSignature(p.Funcs[name])})
+		}
+	}
+	for _, name := range sortedKeys(templateBuiltins) {
+		if _, ok := p.Funcs[name]; !ok && strings.HasPrefix(name, prefix) {
+			items = append(items, core.CompletionItem{Label: name, Kind: &kind, Detail: "builtin", Documentation: templateBuiltins[name]})
+		}
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+	return &core.CompletionList{Items: items}
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// templateErrorLine matches the line number and message of template parse
+// errors, like "template: page.tmpl:3: unexpected EOF".
+var templateErrorLine = regexp.MustCompile(`^(\d+):(?:\d+:)? (.*)$`)
+
+// GoTemplateDiagnosticProvider reports the errors of parsing Go templates,
+// like unclosed actions and calls of undefined functions.
+type GoTemplateDiagnosticProvider struct {
+	// Funcs are the functions added to the templates, like with
+	// template.Funcs. Calls of other functions are errors.
+	Funcs map[string]any
+}
+
+func (p *GoTemplateDiagnosticProvider) ProvideDiagnostics(uri, content string) []core.Diagnostic {
+	if !isGoTemplate(uri) {
+		return nil
+	}
+
+	// The parser only checks that functions are defined
+	builtins := make(map[string]any, len(templateBuiltins))
+	for name := range templateBuiltins {
+		builtins[name] = true
+	}
+	name := path.Base(uri)
+	_, err := parse.Parse(name, content, "", "", p.Funcs, builtins)
+	if err == nil {
+		return nil
+	}
+
+	message := strings.TrimPrefix(err.Error(), "template: "+name+":")
+	line := 0
+	if m := templateErrorLine.FindStringSubmatch(message); m != nil {
+		line, _ = strconv.Atoi(m[1])
+		line-- // the parser counts lines from 1
+		message = m[2]
+	}
+
+	lines := strings.Split(content, "\n")
+	if line < 0 || line >= len(lines) {
+		line = len(lines) - 1
+	}
+	text := strings.TrimRight(lines[line], "\r")
+	indent := len(text) - len(strings.TrimLeft(text, " \t"))
+	severity := core.SeverityError
+	return []core.Diagnostic{{
+		Range: core.Range{
+			Start: core.Position{Line: line, Character: indent},
+			End:   core.Position{Line: line, Character: len(text)},
+		},
+		Severity: &severity,
+		Source:   "template",
+		Message:  message,
+	}}
+}
@@ -0,0 +1,181 @@
+package examples
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+const testTemplate = `{{define "page"}}
+<html>
+  {{- /* The title
+         of the page */ -}}
+  <title>{{.Title | upper}}</title>
+  {{range .Items}}
+    {{if .Visible}}
+      <li>{{printf "%s}}" .Name}}</li>
+    {{end}}
+  {{end}}
+  {{block "footer" .}}
+    <footer>{{.Footer}}</footer>
+  {{end}}
+</html>
+{{end}}
+`
+
+func TestGoTemplateFoldingProvider(t *testing.T) {
+	provider := &GoTemplateFoldingProvider{}
+	ranges := provider.ProvideFoldingRanges("file:///page.tmpl", testTemplate)
+
+	type fold struct {
+		start, end int
+		kind       string
+	}
+	var got []fold
+	for _, r := range ranges {
+		kind := ""
+		if r.Kind != nil {
+			kind = string(*r.Kind)
+		}
+		got = append(got, fold{r.StartLine, r.EndLine, kind})
+	}
+	want := []fold{
+		{0, 13, "region"},
+		{2, 3, "comment"},
+		{5, 8, ""},
+		{6, 7, ""},
+		{10, 11, "region"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ProvideFoldingRanges = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("range %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	if ranges := provider.ProvideFoldingRanges("file:///page.go", testTemplate); ranges != nil {
+		t.Errorf("ProvideFoldingRanges of a Go file = %v, want nil", ranges)
+	}
+}
+
+func TestGoTemplateSymbolProvider(t *testing.T) {
+	provider := &GoTemplateSymbolProvider{}
+	symbols := provider.ProvideDocumentSymbols("file:///page.tmpl", testTemplate)
+	if len(symbols) != 1 || symbols[0].Name != "page" || symbols[0].Detail != "define" {
+		t.Fatalf("ProvideDocumentSymbols = %+v, want the page definition", symbols)
+	}
+	page := symbols[0]
+	if page.Range.Start.Line != 0 || page.Range.End.Line != 14 {
+		t.Errorf("page range = %v, want lines 0 to 14", page.Range)
+	}
+	if want := (core.Range{Start: core.Position{Line: 0, Character: 9}, End: core.Position{Line: 0, Character: 15}}); page.SelectionRange != want {
+		t.Errorf("page selection range = %v, want %v", page.SelectionRange, want)
+	}
+	if len(page.Children) != 1 || page.Children[0].Name != "footer" || page.Children[0].Detail != "block" {
+		t.Errorf("page children = %+v, want the footer block", page.Children)
+	}
+}
+
+func TestGoTemplateHoverProvider(t *testing.T) {
+	provider := &GoTemplateHoverProvider{Funcs: map[string]any{"upper": strings.ToUpper}}
+
+	tests := []struct {
+		name string
+		pos  core.Position
+		want string
+	}{
+		{"builtin", core.Position{Line: 7, Character: 15}, "fmt.Sprintf"},
+		{"func", core.Position{Line: 4, Character: 25}, "upper func(string) string"},
+		{"keyword", core.Position{Line: 5, Character: 5}, "{{range pipeline}}"},
+		{"field", core.Position{Line: 6, Character: 12}, ""},
+		{"text", core.Position{Line: 4, Character: 4}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hover := provider.ProvideHover("file:///page.tmpl", testTemplate, tt.pos)
+			if tt.want == "" {
+				if hover != nil {
+					t.Errorf("ProvideHover = %q, want nil", hover.Contents)
+				}
+				return
+			}
+			if hover == nil || !strings.Contains(hover.Contents, tt.want) {
+				t.Errorf("ProvideHover = %+v, want contents with %q", hover, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoTemplateCompletionProvider(t *testing.T) {
+	provider := &GoTemplateCompletionProvider{Funcs: map[string]any{
+		"upper": strings.ToUpper,
+		"print": strings.TrimSpace,
+	}}
+
+	complete := func(content string) []string {
+		t.Helper()
+		offset := strings.Index(content, "^")
+		content = content[:offset] + content[offset+1:]
+		list := provider.ProvideCompletions(core.CompletionContext{
+			URI:      "file:///page.tmpl",
+			Content:  content,
+			Position: core.ByteOffsetToPosition(content, offset),
+		})
+		if list == nil {
+			return nil
+		}
+		var labels []string
+		for _, item := range list.Items {
+			labels = append(labels, item.Label+":"+item.Detail)
+		}
+		return labels
+	}
+
+	if got, want := strings.Join(complete("<p>{{.Name | up^}}</p>"), " "), "upper:func(string) string"; got != want {
+		t.Errorf("completions of a FuncMap function = %q, want %q", got, want)
+	}
+	if got, want := strings.Join(complete("{{pri^"), " "), "print:func(string) string printf:builtin println:builtin"; got != want {
+		t.Errorf("completions in an unclosed action = %q, want %q", got, want)
+	}
+	if got, want := strings.Join(complete("{{- e^}}"), " "), "else: end: eq:builtin"; got != want {
+		t.Errorf("completions at the start of an action = %q, want %q", got, want)
+	}
+	if got := complete("<p>pri^</p>"); got != nil {
+		t.Errorf("completions outside actions = %q, want none", got)
+	}
+	if got := complete("{{.pri^}}"); got != nil {
+		t.Errorf("completions of a field = %q, want none", got)
+	}
+}
+
+func TestGoTemplateDiagnosticProvider(t *testing.T) {
+	provider := &GoTemplateDiagnosticProvider{Funcs: map[string]any{"upper": strings.ToUpper}}
+
+	if diagnostics := provider.ProvideDiagnostics("file:///page.tmpl", testTemplate); len(diagnostics) != 0 {
+		t.Errorf("ProvideDiagnostics of a valid template = %+v, want none", diagnostics)
+	}
+
+	tests := []struct {
+		content string
+		line    int
+		message string
+	}{
+		{"<p>\n  {{lower .Name}}\n</p>\n", 1, `function "lower" not defined`},
+		{"{{if .Ready}}\n<p>ready</p>\n", 2, "unexpected EOF"},
+		{"{{range .Items}}\n{{end}}\n{{end}}\n", 2, "unexpected {{end}}"},
+	}
+	for _, tt := range tests {
+		diagnostics := provider.ProvideDiagnostics("file:///page.tmpl", tt.content)
+		if len(diagnostics) != 1 {
+			t.Errorf("ProvideDiagnostics(%q) = %+v, want one diagnostic", tt.content, diagnostics)
+			continue
+		}
+		d := diagnostics[0]
+		if d.Range.Start.Line != tt.line || !strings.Contains(d.Message, tt.message) {
+			t.Errorf("ProvideDiagnostics(%q) = %q on line %d, want %q on line %d", tt.content, d.Message, d.Range.Start.Line, tt.message, tt.line)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package examples
+
+import (
+	"fmt"
+	"go/token"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// TerminalColorProvider provides colors for the string literals of Go
+// terminal UIs: hex colors, as lipgloss.Color("#FF5F87") takes them, and
+// ANSI escape codes selecting a 256-color or 24-bit foreground or
+// background, like "\x1b[38;5;205m".
+//
+// Picking a color rewrites the literal in its own notation: a hex literal
+// becomes another hex literal, or the ANSI 256-color index lipgloss also
+// accepts, and an escape code another escape code for the same layer.
+type TerminalColorProvider struct{}
+
+var (
+	// A whole literal holding a hex color: "#F87", "#FF5F87"
+	hexLiteralRegex = regexp.MustCompile("^([\"`])#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})[\"`]$")
+
+	// Escape codes setting the foreground (38) or background (48) color
+	// from the 256-color palette, ESC[38;5;Nm, or in 24 bits,
+	// ESC[38;2;R;G;Bm, with ESC written as an escape sequence
+	ansiColorRegex = regexp.MustCompile(`(\\x1[bB]|\\033|\\u001[bB])\[([34]8);(?:5;(\d{1,3})|2;(\d{1,3});(\d{1,3});(\d{1,3}))m`)
+)
+
+// ProvideDocumentColors finds the colors in the string literals of a Go
+// file. The file is scanned rather than parsed, so colors are found while
+// it has syntax errors too.
+func (p *TerminalColorProvider) ProvideDocumentColors(uri, content string) []core.ColorInformation {
+	if !strings.HasSuffix(uri, ".go") {
+		return nil
+	}
+
+	var colors []core.ColorInformation
+	add := func(start, end int, color core.Color) {
+		colors = append(colors, core.ColorInformation{
+			Range: core.Range{
+				Start: core.ByteOffsetToPosition(content, start),
+				End:   core.ByteOffsetToPosition(content, end),
+			},
+			Color: color,
+		})
+	}
+
+	for _, literal := range scanCommentsAndStrings(content, true) {
+		if literal.tok != token.STRING {
+			continue
+		}
+		if match := hexLiteralRegex.FindStringSubmatch(literal.lit); match != nil {
+			if color, ok := (&ColorProvider{}).parseHexColor(match[2]); ok {
+				add(literal.offset, literal.offset+len(literal.lit), color)
+			}
+			continue
+		}
+		// Raw strings do not interpret escape sequences
+		if literal.lit[0] != '"' {
+			continue
+		}
+		for _, match := range ansiColorRegex.FindAllStringSubmatchIndex(literal.lit, -1) {
+			if color, ok := ansiEscapeColor(literal.lit, match); ok {
+				add(literal.offset+match[0], literal.offset+match[1], color)
+			}
+		}
+	}
+	return colors
+}
+
+// ProvideColorPresentations rewrites the literal or escape code at rng, as
+// returned by ProvideDocumentColors, to color.
+func (p *TerminalColorProvider) ProvideColorPresentations(uri, content string, color core.Color, rng core.Range) []core.ColorPresentation {
+	start := core.PositionToByteOffset(content, rng.Start)
+	end := core.PositionToByteOffset(content, rng.End)
+	if start < 0 || end > len(content) || start >= end {
+		return nil
+	}
+	text := content[start:end]
+	r, g, b := colorBytes(color)
+
+	var replacements []string
+	if match := hexLiteralRegex.FindStringSubmatch(text); match != nil {
+		quote := match[1]
+		replacements = []string{
+			fmt.Sprintf("%s#%02X%02X%02X%s", quote, r, g, b, quote),
+			fmt.Sprintf("%s%d%s", quote, nearestANSI256(r, g, b), quote),
+		}
+	} else if match := ansiColorRegex.FindStringSubmatch(text); match != nil && match[0] == text {
+		escape, layer := match[1], match[2]
+		replacements = []string{
+			fmt.Sprintf("%s[%s;5;%dm", escape, layer, nearestANSI256(r, g, b)),
+			fmt.Sprintf("%s[%s;2;%d;%d;%dm", escape, layer, r, g, b),
+		}
+	}
+
+	var presentations []core.ColorPresentation
+	for _, replacement := range replacements {
+		presentations = append(presentations, core.ColorPresentation{
+			Label:    replacement,
+			TextEdit: &core.TextEdit{Range: rng, NewText: replacement},
+		})
+	}
+	return presentations
+}
+
+// ansiEscapeColor returns the color an escape code matched by
+// ansiColorRegex in s selects.
+func ansiEscapeColor(s string, match []int) (core.Color, bool) {
+	group := func(i int) int {
+		n, _ := strconv.Atoi(s[match[2*i]:match[2*i+1]])
+		return n
+	}
+	if match[6] >= 0 {
+		index := group(3)
+		if index > 255 {
+			return core.Color{}, false
+		}
+		r, g, b := ansi256RGB(index)
+		return colorFromBytes(r, g, b), true
+	}
+	r, g, b := group(4), group(5), group(6)
+	if r > 255 || g > 255 || b > 255 {
+		return core.Color{}, false
+	}
+	return colorFromBytes(r, g, b), true
+}
+
+// ansi16 are the colors of the first 16 entries of the 256-color palette,
+// as xterm shows them by default. Terminal themes usually redefine them.
+var ansi16 = [16][3]int{
+	{0x00, 0x00, 0x00}, {0x80, 0x00, 0x00}, {0x00, 0x80, 0x00}, {0x80, 0x80, 0x00},
+	{0x00, 0x00, 0x80}, {0x80, 0x00, 0x80}, {0x00, 0x80, 0x80}, {0xC0, 0xC0, 0xC0},
+	{0x80, 0x80, 0x80}, {0xFF, 0x00, 0x00}, {0x00, 0xFF, 0x00}, {0xFF, 0xFF, 0x00},
+	{0x00, 0x00, 0xFF}, {0xFF, 0x00, 0xFF}, {0x00, 0xFF, 0xFF}, {0xFF, 0xFF, 0xFF},
+}
+
+// ansiCubeLevels are the values of each component in the 6×6×6 color cube
+// of the 256-color palette.
+var ansiCubeLevels = [6]int{0x00, 0x5F, 0x87, 0xAF, 0xD7, 0xFF}
+
+// ansi256RGB returns the color of an entry of the 256-color palette: the
+// 16 system colors, the 6×6×6 color cube, and 24 shades of gray.
+func ansi256RGB(index int) (r, g, b int) {
+	switch {
+	case index < 16:
+		c := ansi16[index]
+		return c[0], c[1], c[2]
+	case index < 232:
+		i := index - 16
+		return ansiCubeLevels[i/36], ansiCubeLevels[i/6%6], ansiCubeLevels[i%6]
+	default:
+		gray := 8 + 10*(index-232)
+		return gray, gray, gray
+	}
+}
+
+// nearestANSI256 returns the entry of the 256-color palette closest to a
+// color. The 16 system colors are left out, since their colors depend on
+// the terminal's theme.
+func nearestANSI256(r, g, b int) int {
+	best, bestDistance := 16, math.MaxInt
+	for index := 16; index < 256; index++ {
+		pr, pg, pb := ansi256RGB(index)
+		distance := (pr-r)*(pr-r) + (pg-g)*(pg-g) + (pb-b)*(pb-b)
+		if distance < bestDistance {
+			best, bestDistance = index, distance
+		}
+	}
+	return best
+}
+
+// colorBytes returns the components of an opaque color in [0, 255].
+func colorBytes(color core.Color) (r, g, b int) {
+	component := func(c float64) int {
+		return int(math.Round(math.Max(0, math.Min(1, c)) * 255))
+	}
+	return component(color.Red), component(color.Green), component(color.Blue)
+}
+
+// colorFromBytes returns the opaque color with components in [0, 255].
+func colorFromBytes(r, g, b int) core.Color {
+	return core.Color{
+		Red:   float64(r) / 255.0,
+		Green: float64(g) / 255.0,
+		Blue:  float64(b) / 255.0,
+		Alpha: 1,
+	}
+}
@@ -0,0 +1,94 @@
+package examples
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+const terminalColorSource = `package main
+
+var (
+	pink   = lipgloss.Color("#FF5F87")
+	muted  = lipgloss.Color(` + "`#888`" + `)
+	banner = "\x1b[38;5;196mERROR\x1b[0m on \033[48;2;0;0;255mblue"
+	raw    = ` + "`\\x1b[38;5;196m`" + `
+	other  = "#FF5F87 is pink"
+)
+`
+
+func TestTerminalColorProvider(t *testing.T) {
+	provider := &TerminalColorProvider{}
+	colors := provider.ProvideDocumentColors("file:///ui.go", terminalColorSource)
+
+	want := []struct {
+		text  string
+		color core.Color
+	}{
+		{`"#FF5F87"`, core.Color{Red: 1, Green: 0x5F / 255.0, Blue: 0x87 / 255.0, Alpha: 1}},
+		{"`#888`", core.Color{Red: 0x88 / 255.0, Green: 0x88 / 255.0, Blue: 0x88 / 255.0, Alpha: 1}},
+		{`\x1b[38;5;196m`, core.Color{Red: 1, Alpha: 1}},
+		{`\033[48;2;0;0;255m`, core.Color{Blue: 1, Alpha: 1}},
+	}
+	if len(colors) != len(want) {
+		t.Fatalf("got %d colors, want %d: %v", len(colors), len(want), colors)
+	}
+	for i, info := range colors {
+		start := core.PositionToByteOffset(terminalColorSource, info.Range.Start)
+		end := core.PositionToByteOffset(terminalColorSource, info.Range.End)
+		if text := terminalColorSource[start:end]; text != want[i].text {
+			t.Errorf("color %d is on %s, want %s", i, text, want[i].text)
+		}
+		if info.Color != want[i].color {
+			t.Errorf("color of %s = %+v, want %+v", want[i].text, info.Color, want[i].color)
+		}
+	}
+
+	if colors := provider.ProvideDocumentColors("file:///ui.css", terminalColorSource); colors != nil {
+		t.Errorf("colors in a CSS file = %v, want none", colors)
+	}
+}
+
+func TestTerminalColorProvider_Presentations(t *testing.T) {
+	provider := &TerminalColorProvider{}
+	colors := provider.ProvideDocumentColors("file:///ui.go", terminalColorSource)
+	if len(colors) < 4 {
+		t.Fatalf("got %d colors, want 4", len(colors))
+	}
+	green := core.Color{Green: 1, Alpha: 1}
+
+	tests := []struct {
+		name  string
+		color core.ColorInformation
+		want  []string
+	}{
+		{"hex literal", colors[0], []string{`"#00FF00"`, `"46"`}},
+		{"raw hex literal", colors[1], []string{"`#00FF00`", "`46`"}},
+		{"256-color escape", colors[2], []string{`\x1b[38;5;46m`, `\x1b[38;2;0;255;0m`}},
+		{"24-bit background escape", colors[3], []string{`\033[48;5;46m`, `\033[48;2;0;255;0m`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			presentations := provider.ProvideColorPresentations("file:///ui.go", terminalColorSource, green, tt.color.Range)
+			var got []string
+			for _, presentation := range presentations {
+				if presentation.TextEdit == nil || presentation.TextEdit.Range != tt.color.Range {
+					t.Errorf("presentation %s does not replace the color", presentation.Label)
+				}
+				got = append(got, presentation.TextEdit.NewText)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("presentations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNearestANSI256(t *testing.T) {
+	for index := 16; index < 256; index++ {
+		if got := nearestANSI256(ansi256RGB(index)); got != index {
+			t.Errorf("nearestANSI256 of color %d = %d", index, got)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package examples
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/imports"
+)
+
+// GenerateTestCommand is the command generating a table-driven test. Its
+// arguments are the URI of the Go file and the name of the function, or
+// "Type.Method" for methods.
+const GenerateTestCommand = "go.generateTest"
+
+// TestGenerationProvider offers "Generate table-driven test" on function
+// declarations. The code action carries a GenerateTestCommand; the server
+// runs it with ExecuteCommand when workspace/executeCommand arrives and
+// sends the resulting edit to the client with workspace/applyEdit.
+//
+// The test goes into the _test.go file next to the source file: a new file
+// is created with a CreateFile operation, and an existing one is appended
+// to, adding the imports the test needs.
+type TestGenerationProvider struct {
+	// ReadFile returns the content of a file and whether it exists.
+	ReadFile func(uri string) (string, bool)
+}
+
+func (p *TestGenerationProvider) ProvideCodeFixes(ctx core.CodeFixContext) []core.CodeAction {
+	if !strings.HasSuffix(ctx.URI, ".go") || strings.HasSuffix(ctx.URI, "_test.go") ||
+		!codeActionKindRequested(ctx.Only, core.CodeActionKindSource) {
+		return nil
+	}
+	fset, f, err := parseGoFile(ctx.URI, ctx.Content)
+	if err != nil {
+		return nil
+	}
+	offset := core.PositionToByteOffset(ctx.Content, ctx.Range.Start)
+	if offset < 0 {
+		return nil
+	}
+
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || fn.Type.TypeParams != nil {
+			continue
+		}
+		// The action applies on the signature, not in the body
+		if offset < fset.Position(fn.Pos()).Offset || offset > fset.Position(fn.Body.Lbrace).Offset {
+			continue
+		}
+		name := testedFuncName(fn)
+		if name == "" || name == "main" || name == "init" {
+			return nil
+		}
+		kind := core.CodeActionKindSource
+		title := "Generate table-driven test for " + name
+		return []core.CodeAction{{
+			Title: title,
+			Kind:  &kind,
+			Command: &core.Command{
+				Title:     title,
+				Command:   GenerateTestCommand,
+				Arguments: []interface{}{ctx.URI, name},
+			},
+		}}
+	}
+	return nil
+}
+
+// ExecuteCommand runs GenerateTestCommand and returns the edit creating or
+// extending the test file.
+func (p *TestGenerationProvider) ExecuteCommand(command string, arguments []interface{}) (*core.WorkspaceEdit, error) {
+	if command != GenerateTestCommand {
+		return nil, fmt.Errorf("unknown command %q", command)
+	}
+	if len(arguments) != 2 {
+		return nil, fmt.Errorf("%s expects a URI and a function name", command)
+	}
+	uri, _ := arguments[0].(string)
+	name, _ := arguments[1].(string)
+	if uri == "" || name == "" {
+		return nil, fmt.Errorf("%s expects a URI and a function name", command)
+	}
+
+	content, ok := p.ReadFile(uri)
+	if !ok {
+		return nil, fmt.Errorf("%s: file not found", uri)
+	}
+	fset, f, err := parseGoFile(uri, content)
+	if err != nil {
+		return nil, err
+	}
+	var fn *ast.FuncDecl
+	for _, decl := range f.Decls {
+		if d, ok := decl.(*ast.FuncDecl); ok && testedFuncName(d) == name {
+			fn = d
+			break
+		}
+	}
+	if fn == nil {
+		return nil, fmt.Errorf("function %s not found in %s", name, uri)
+	}
+
+	testName := "Test" + strings.ReplaceAll(name, ".", "_")
+	test, testImports := generateTableTest(content, fset, f, fn, testName)
+	testURI := strings.TrimSuffix(uri, ".go") + "_test.go"
+
+	testContent, exists := p.ReadFile(testURI)
+	if !exists {
+		var file strings.Builder
+		fmt.Fprintf(&file, "package %s\n\nimport (\n", f.Name.Name)
+		for _, path := range testImports {
+			fmt.Fprintf(&file, "\t%s\n", strconv.Quote(path))
+		}
+		file.WriteString(")\n\n" + test)
+		return &core.WorkspaceEdit{DocumentChanges: []interface{}{
+			core.CreateFile{URI: testURI, Options: &core.CreateFileOptions{IgnoreIfExists: true}},
+			core.TextDocumentEdit{
+				TextDocument: core.VersionedTextDocumentIdentifier{URI: testURI},
+				Edits:        []core.TextEdit{{NewText: file.String()}},
+			},
+		}}, nil
+	}
+
+	testFset, testFile, err := parseGoFile(testURI, testContent)
+	if err != nil {
+		return nil, err
+	}
+	if declaresFunc(testFile, testName) {
+		return nil, fmt.Errorf("%s already declares %s", testURI, testName)
+	}
+	var edits []core.TextEdit
+	testFileImports := imports.NewFile(testContent, testFset, testFile)
+	for _, path := range testImports {
+		edits = append(edits, testFileImports.Add(path, "")...)
+	}
+	end := core.ByteOffsetToPosition(testContent, len(testContent))
+	separator := "\n"
+	if !strings.HasSuffix(testContent, "\n") {
+		separator = "\n\n"
+	}
+	edits = append(edits, core.TextEdit{Range: core.Range{Start: end, End: end}, NewText: separator + test})
+	return &core.WorkspaceEdit{DocumentChanges: []interface{}{
+		core.TextDocumentEdit{
+			TextDocument: core.VersionedTextDocumentIdentifier{URI: testURI},
+			Edits:        edits,
+		},
+	}}, nil
+}
+
+// testedFuncName returns the name of a function, or "Type.Method" for a
+// method.
+func testedFuncName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	recv := fn.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	ident, ok := recv.(*ast.Ident)
+	if !ok {
+		// Generic receivers are not supported
+		return ""
+	}
+	return ident.Name + "." + fn.Name.Name
+}
+
+// testField is a field of the test table.
+type testField struct {
+	name, typ string
+}
+
+// generateTableTest returns the source of a table-driven test for fn and
+// the import paths it needs, sorted.
+func generateTableTest(content string, fset *token.FileSet, f *ast.File, fn *ast.FuncDecl, testName string) (string, []string) {
+	packages := map[string]bool{}
+	typeOf := func(expr ast.Expr) string {
+		ast.Inspect(expr, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if x, ok := sel.X.(*ast.Ident); ok {
+					packages[x.Name] = true
+				}
+			}
+			return true
+		})
+		return goSource(content, fset, expr)
+	}
+
+	// Field names must not clash with the names the test uses
+	used := map[string]bool{"name": true, "tt": true, "t": true, "tests": true, "got": true, "err": true, "want": true, "wantErr": true}
+	fieldName := func(name string, index int) string {
+		if name == "" || name == "_" {
+			name = fmt.Sprintf("arg%d", index)
+		}
+		for used[name] {
+			name += "Arg"
+		}
+		used[name] = true
+		return name
+	}
+
+	var fields []testField
+	var receiver *testField
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		receiver = &testField{name: fieldName("receiver", 0), typ: typeOf(fn.Recv.List[0].Type)}
+		fields = append(fields, *receiver)
+	}
+	var args []string
+	index := 0
+	for _, param := range fn.Type.Params.List {
+		// A variadic ...T parameter is a []T field passed with tt.field...
+		ellipsis, variadic := param.Type.(*ast.Ellipsis)
+		var typeText string
+		if variadic {
+			typeText = "[]" + typeOf(ellipsis.Elt)
+		} else {
+			typeText = typeOf(param.Type)
+		}
+		names := param.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{nil}
+		}
+		for _, n := range names {
+			name := ""
+			if n != nil {
+				name = n.Name
+			}
+			field := testField{name: fieldName(name, index), typ: typeText}
+			fields = append(fields, field)
+			arg := "tt." + field.name
+			if variadic {
+				arg += "..."
+			}
+			args = append(args, arg)
+			index++
+		}
+	}
+
+	// Results are compared to want, want1, ..., and a final error to wantErr
+	var results []testField
+	returnsError := false
+	if fn.Type.Results != nil {
+		for _, result := range fn.Type.Results.List {
+			count := len(result.Names)
+			if count == 0 {
+				count = 1
+			}
+			for i := 0; i < count; i++ {
+				results = append(results, testField{typ: typeOf(result.Type)})
+			}
+		}
+		if last := len(results) - 1; last >= 0 && results[last].typ == "error" {
+			results = results[:last]
+			returnsError = true
+		}
+		for i := range results {
+			results[i].name = "want"
+			if i > 0 {
+				results[i].name += strconv.Itoa(i)
+			}
+		}
+	}
+	needsReflect := false
+	for _, result := range results {
+		if !isComparableTypeSyntax(result.typ) {
+			needsReflect = true
+		}
+	}
+
+	var test strings.Builder
+	fmt.Fprintf(&test, "func %s(t *testing.T) {\n", testName)
+	test.WriteString("\ttests := []struct {\n\t\tname string\n")
+	for _, field := range fields {
+		fmt.Fprintf(&test, "\t\t%s %s\n", field.name, field.typ)
+	}
+	for _, result := range results {
+		fmt.Fprintf(&test, "\t\t%s %s\n", result.name, result.typ)
+	}
+	if returnsError {
+		test.WriteString("\t\twantErr bool\n")
+	}
+	test.WriteString("\t}{\n\t\t// TODO: add test cases.\n\t}\n\n")
+	test.WriteString("\tfor _, tt := range tests {\n\t\tt.Run(tt.name, func(t *testing.T) {\n")
+
+	call := fn.Name.Name + "(" + strings.Join(args, ", ") + ")"
+	if receiver != nil {
+		call = "tt." + receiver.name + "." + call
+	}
+	var gots []string
+	for i := range results {
+		got := "got"
+		if i > 0 {
+			got += strconv.Itoa(i)
+		}
+		gots = append(gots, got)
+	}
+	if returnsError {
+		gots = append(gots, "err")
+	}
+	display := testedFuncName(fn) + "()"
+	if len(gots) == 0 {
+		fmt.Fprintf(&test, "\t\t\t%s\n", call)
+	} else {
+		fmt.Fprintf(&test, "\t\t\t%s := %s\n", strings.Join(gots, ", "), call)
+	}
+	if returnsError {
+		fmt.Fprintf(&test, "\t\t\tif (err != nil) != tt.wantErr {\n\t\t\t\tt.Fatalf(\"%s error = %%v, wantErr %%v\", err, tt.wantErr)\n\t\t\t}\n", display)
+	}
+	for i, result := range results {
+		condition := fmt.Sprintf("%s != tt.%s", gots[i], result.name)
+		if !isComparableTypeSyntax(result.typ) {
+			condition = fmt.Sprintf("!reflect.DeepEqual(%s, tt.%s)", gots[i], result.name)
+		}
+		fmt.Fprintf(&test, "\t\t\tif %s {\n\t\t\t\tt.Errorf(\"%s %s = %%v, want %%v\", %s, tt.%s)\n\t\t\t}\n",
+			condition, display, gots[i], gots[i], result.name)
+	}
+	test.WriteString("\t\t})\n\t}\n}\n")
+	source := test.String()
+	if formatted, err := format.Source([]byte(source)); err == nil {
+		source = string(formatted)
+	}
+
+	testImports := []string{"testing"}
+	if needsReflect {
+		testImports = append(testImports, "reflect")
+	}
+	file := imports.NewFile(content, fset, f)
+	for name := range packages {
+		if path := file.Path(name); path != "" {
+			testImports = append(testImports, path)
+		}
+	}
+	sort.Strings(testImports)
+	return source, testImports
+}
+
+// isComparableTypeSyntax reports whether values of the type written as typ
+// can be compared with !=. Only predeclared basic types and pointers are
+// recognized; other types are compared with reflect.DeepEqual.
+func isComparableTypeSyntax(typ string) bool {
+	if strings.HasPrefix(typ, "*") {
+		return true
+	}
+	switch typ {
+	case "bool", "string", "byte", "rune", "uintptr",
+		"int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64", "complex64", "complex128":
+		return true
+	}
+	return false
+}
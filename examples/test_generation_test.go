@@ -0,0 +1,144 @@
+package examples
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+const testGenerationSource = `package calc
+
+import "bytes"
+
+// Divide divides a by b.
+func Divide(a, b int) (int, error) {
+	return a / b, nil
+}
+
+type Buffer struct{}
+
+func (w *Buffer) Write(dst *bytes.Buffer, parts ...string) []string {
+	return parts
+}
+
+func main() {}
+`
+
+func TestTestGenerationProviderCodeAction(t *testing.T) {
+	provider := &TestGenerationProvider{}
+	tests := []struct {
+		name      string
+		line      int
+		wantTitle string
+	}{
+		{name: "function", line: 5, wantTitle: "Generate table-driven test for Divide"},
+		{name: "method", line: 11, wantTitle: "Generate table-driven test for Buffer.Write"},
+		{name: "body", line: 6},
+		{name: "main", line: 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position := core.Position{Line: tt.line, Character: 1}
+			actions := provider.ProvideCodeFixes(core.CodeFixContext{
+				URI:     "file:///calc/calc.go",
+				Content: testGenerationSource,
+				Range:   core.Range{Start: position, End: position},
+			})
+			if tt.wantTitle == "" {
+				if len(actions) != 0 {
+					t.Errorf("expected no actions, got %v", actions)
+				}
+				return
+			}
+			if len(actions) != 1 || actions[0].Title != tt.wantTitle {
+				t.Fatalf("actions = %v, want %q", actions, tt.wantTitle)
+			}
+			command := actions[0].Command
+			if command == nil || command.Command != GenerateTestCommand || len(command.Arguments) != 2 {
+				t.Errorf("unexpected command %+v", command)
+			}
+		})
+	}
+}
+
+func TestTestGenerationProviderNewFile(t *testing.T) {
+	files := map[string]string{"file:///calc/calc.go": testGenerationSource}
+	provider := &TestGenerationProvider{ReadFile: func(uri string) (string, bool) {
+		content, ok := files[uri]
+		return content, ok
+	}}
+
+	edit, err := provider.ExecuteCommand(GenerateTestCommand, []interface{}{"file:///calc/calc.go", "Buffer.Write"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edit.DocumentChanges) != 2 {
+		t.Fatalf("expected a file creation and an edit, got %+v", edit.DocumentChanges)
+	}
+	create, ok := edit.DocumentChanges[0].(core.CreateFile)
+	if !ok || create.URI != "file:///calc/calc_test.go" {
+		t.Fatalf("expected calc_test.go to be created, got %+v", edit.DocumentChanges[0])
+	}
+	content := edit.DocumentChanges[1].(core.TextDocumentEdit).Edits[0].NewText
+
+	for _, want := range []string{
+		"package calc\n\nimport (\n\t\"bytes\"\n\t\"reflect\"\n\t\"testing\"\n)\n",
+		"func TestBuffer_Write(t *testing.T) {",
+		"\t\treceiver *Buffer\n\t\tdst      *bytes.Buffer\n\t\tparts    []string\n\t\twant     []string\n",
+		"got := tt.receiver.Write(tt.dst, tt.parts...)",
+		"if !reflect.DeepEqual(got, tt.want) {",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("generated test lacks %q:\n%s", want, content)
+		}
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "calc_test.go", content, 0); err != nil {
+		t.Errorf("generated test does not parse: %v\n%s", err, content)
+	}
+}
+
+func TestTestGenerationProviderExistingFile(t *testing.T) {
+	files := map[string]string{
+		"file:///calc/calc.go":      testGenerationSource,
+		"file:///calc/calc_test.go": "package calc\n\nimport (\n\t\"testing\"\n)\n\nfunc TestOther(t *testing.T) {}\n",
+	}
+	provider := &TestGenerationProvider{ReadFile: func(uri string) (string, bool) {
+		content, ok := files[uri]
+		return content, ok
+	}}
+
+	edit, err := provider.ExecuteCommand(GenerateTestCommand, []interface{}{"file:///calc/calc.go", "Divide"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edit.DocumentChanges) != 1 {
+		t.Fatalf("expected a single document edit, got %+v", edit.DocumentChanges)
+	}
+	result := applyTextEdits(files["file:///calc/calc_test.go"], edit.DocumentChanges[0].(core.TextDocumentEdit).Edits)
+
+	for _, want := range []string{
+		"func TestOther(t *testing.T) {}\n\nfunc TestDivide(t *testing.T) {",
+		"\t\ta       int\n\t\tb       int\n\t\twant    int\n\t\twantErr bool\n",
+		"got, err := Divide(tt.a, tt.b)",
+		"if got != tt.want {",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("test file lacks %q:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "reflect") {
+		t.Errorf("unexpected reflect import:\n%s", result)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "calc_test.go", result, 0); err != nil {
+		t.Errorf("test file does not parse: %v\n%s", err, result)
+	}
+
+	files["file:///calc/calc_test.go"] = result
+	if _, err := provider.ExecuteCommand(GenerateTestCommand, []interface{}{"file:///calc/calc.go", "Divide"}); err == nil {
+		t.Error("expected an error generating the test twice")
+	}
+}
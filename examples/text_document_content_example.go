@@ -0,0 +1,180 @@
+package examples
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/workspace"
+)
+
+// GoStubScheme is the URI scheme of the virtual documents served by
+// GoStubContentProvider.
+const GoStubScheme = "gostub"
+
+// GoStubURI returns the stub URI for a Go file URI, e.g.
+// "file:///src/a.go" becomes "gostub:///src/a.go".
+func GoStubURI(fileURI string) string {
+	_, rest, _ := strings.Cut(fileURI, ":")
+	return GoStubScheme + ":" + rest
+}
+
+// GoStubContentProvider serves the exported API of a Go file as a read-only
+// virtual document: unexported declarations and function bodies are
+// stripped, like a decompiled stub. Clients open it with a "gostub:" URI
+// built by GoStubURI.
+type GoStubContentProvider struct {
+	// Documents holds open documents; their content is preferred over the
+	// file on disk. It may be nil.
+	Documents *core.DocumentManager
+
+	// Guard, when set, restricts the files on disk stubs are served for,
+	// since any URI can be requested.
+	Guard *workspace.Guard
+}
+
+func (p *GoStubContentProvider) ProvideTextDocumentContent(uri string) (string, error) {
+	if !strings.HasPrefix(uri, GoStubScheme+":") {
+		return "", fmt.Errorf("not a %s URI: %s", GoStubScheme, uri)
+	}
+	fileURI := "file:" + strings.TrimPrefix(uri, GoStubScheme+":")
+
+	content, err := p.source(fileURI)
+	if err != nil {
+		return "", err
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+
+	stripGoFile(file)
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gostub from " + fileURI + ". DO NOT EDIT.\n\n")
+	if err := format.Node(&buf, fset, file); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// source returns the content of fileURI from the open documents, falling
+// back to the file on disk.
+func (p *GoStubContentProvider) source(fileURI string) (string, error) {
+	if p.Documents != nil {
+		if doc, ok := p.Documents.Get(fileURI); ok {
+			return doc.GetContent(), nil
+		}
+	}
+
+	data, err := p.Guard.ReadFile(fileURI)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// stripGoFile removes unexported declarations and function bodies.
+// Comments not attached to a remaining declaration are dropped too.
+func stripGoFile(file *ast.File) {
+	var decls []ast.Decl
+	var comments []*ast.CommentGroup
+	if file.Doc != nil {
+		comments = append(comments, file.Doc)
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() || (d.Recv != nil && !exportedReceiver(d.Recv)) {
+				continue
+			}
+			d.Body = nil
+			if d.Doc != nil {
+				comments = append(comments, d.Doc)
+			}
+			decls = append(decls, d)
+
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				decls = append(decls, d)
+				continue
+			}
+			var specs []ast.Spec
+			for _, spec := range d.Specs {
+				if exportedSpec(spec) {
+					specs = append(specs, spec)
+					if doc := specDoc(spec); doc != nil {
+						comments = append(comments, doc)
+					}
+				}
+			}
+			if len(specs) == 0 {
+				continue
+			}
+			d.Specs = specs
+			if d.Doc != nil {
+				comments = append(comments, d.Doc)
+			}
+			decls = append(decls, d)
+		}
+	}
+
+	file.Decls = decls
+	file.Comments = comments
+}
+
+// exportedReceiver reports whether a method receiver's base type is exported.
+func exportedReceiver(recv *ast.FieldList) bool {
+	if len(recv.List) == 0 {
+		return false
+	}
+	expr := recv.List[0].Type
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		case *ast.Ident:
+			return t.IsExported()
+		default:
+			return false
+		}
+	}
+}
+
+// exportedSpec reports whether a type, const, or var spec declares an
+// exported name. Unexported names in a value spec are kept with it.
+func exportedSpec(spec ast.Spec) bool {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Name.IsExported()
+	case *ast.ValueSpec:
+		for _, name := range s.Names {
+			if name.IsExported() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func specDoc(spec ast.Spec) *ast.CommentGroup {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Doc
+	case *ast.ValueSpec:
+		return s.Doc
+	}
+	return nil
+}
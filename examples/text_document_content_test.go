@@ -0,0 +1,122 @@
+package examples
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+	uripkg "github.com/SCKelemen/lsp/uri"
+	"github.com/SCKelemen/lsp/workspace"
+)
+
+const stubSource = `// Package shapes has shapes.
+package shapes
+
+import "math"
+
+// Circle is a circle.
+type Circle struct {
+	Radius float64
+}
+
+type point struct{ x, y float64 }
+
+// Area returns the area.
+func (c Circle) Area() float64 {
+	return math.Pi * c.Radius * c.Radius
+}
+
+func (p point) norm() float64 { return 0 }
+
+// helper is unexported.
+func helper() {}
+
+// MaxRadius limits circles.
+const MaxRadius = 10
+`
+
+// TestGoStubContentProvider tests stub generation from open documents.
+func TestGoStubContentProvider(t *testing.T) {
+	docs := core.NewDocumentManager()
+	docs.Open("file:///src/shapes.go", stubSource, 1)
+	provider := &GoStubContentProvider{Documents: docs}
+
+	got, err := provider.ProvideTextDocumentContent(GoStubURI("file:///src/shapes.go"))
+	if err != nil {
+		t.Fatalf("ProvideTextDocumentContent failed: %v", err)
+	}
+
+	wantContains := []string{
+		"DO NOT EDIT",
+		"type Circle struct",
+		"// Area returns the area.",
+		"func (c Circle) Area() float64\n",
+		"const MaxRadius = 10",
+	}
+	for _, want := range wantContains {
+		if !strings.Contains(got, want) {
+			t.Errorf("stub missing %q:\n%s", want, got)
+		}
+	}
+
+	wantMissing := []string{"point", "helper", "math.Pi", "norm"}
+	for _, missing := range wantMissing {
+		if strings.Contains(got, missing) {
+			t.Errorf("stub should not contain %q:\n%s", missing, got)
+		}
+	}
+}
+
+// TestGoStubContentProviderFromDisk tests reading files that are not open.
+func TestGoStubContentProviderFromDisk(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shapes.go")
+	if err := os.WriteFile(path, []byte(stubSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := core.NewTextDocumentContentRegistry()
+	registry.Register(GoStubScheme, &GoStubContentProvider{})
+
+	got, err := registry.ProvideTextDocumentContent(GoStubURI(uripkg.FromPath(path)))
+	if err != nil {
+		t.Fatalf("ProvideTextDocumentContent failed: %v", err)
+	}
+	if !strings.Contains(got, "func (c Circle) Area() float64") {
+		t.Errorf("unexpected stub:\n%s", got)
+	}
+
+	if _, err := registry.ProvideTextDocumentContent(GoStubURI(uripkg.FromPath(filepath.Join(dir, "missing.go")))); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestGoStubContentProviderGuard(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"ws/shapes.go", "private/shapes.go"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(stubSource), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := filepath.Join(dir, "ws")
+	provider := &GoStubContentProvider{Guard: workspace.NewGuard(root)}
+
+	if _, err := provider.ProvideTextDocumentContent(GoStubURI(uripkg.FromPath(filepath.Join(root, "shapes.go")))); err != nil {
+		t.Errorf("stub of a workspace file failed: %v", err)
+	}
+	for _, uri := range []string{
+		uripkg.FromPath(filepath.Join(dir, "private", "shapes.go")),
+		uripkg.FromPath(root) + "/../private/shapes.go",
+	} {
+		if _, err := provider.ProvideTextDocumentContent(GoStubURI(uri)); !errors.Is(err, workspace.ErrOutsideWorkspace) {
+			t.Errorf("stub of %s: error = %v, want ErrOutsideWorkspace", uri, err)
+		}
+	}
+}
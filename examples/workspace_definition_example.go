@@ -0,0 +1,336 @@
+package examples
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/gobuild"
+	"github.com/SCKelemen/lsp/gomod"
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+// GoWorkspaceDefinitionProvider provides go-to-definition for Go code
+// across files, where SimpleDefinitionProvider only looks in the current
+// file.
+//
+// Identifiers declared in the file resolve through the type checker.
+// References to members of imported packages, like "util.Helper", resolve
+// through the workspace symbol index. Package-level names declared in
+// another file of the same package resolve through the index as well,
+// falling back to parsing the files of the package.
+type GoWorkspaceDefinitionProvider struct {
+	// Symbols is the workspace symbol index. If nil, only the current
+	// package is searched.
+	Symbols *GoWorkspaceSymbolProvider
+
+	// PackageFiles returns the content of every file in the package of the
+	// file at uri, by URI. If nil, files are read from disk.
+	PackageFiles func(uri string) map[string]string
+
+	// Resolver, when set, resolves imported packages to their directories,
+	// so packages with the same name in different modules are told apart.
+	Resolver *gomod.Resolver
+
+	// Platform, when set, skips the files of the package not built on it,
+	// like the Windows implementation of a function when analyzing for
+	// Linux.
+	Platform *gobuild.Platform
+}
+
+func (p *GoWorkspaceDefinitionProvider) ProvideDefinition(uri, content string, position core.Position) []core.Location {
+	var locations []core.Location
+	for _, link := range p.ProvideDefinitionLinks(uri, content, position) {
+		locations = append(locations, core.Location{URI: link.TargetURI, Range: link.TargetSelectionRange})
+	}
+	return locations
+}
+
+func (p *GoWorkspaceDefinitionProvider) ProvideDefinitionLinks(uri, content string, position core.Position) []core.LocationLink {
+	if !strings.HasSuffix(uri, ".go") {
+		return nil
+	}
+	fset, f, err := parseGoFile(uri, content)
+	if err != nil {
+		return nil
+	}
+	offset := core.PositionToByteOffset(content, position)
+	ident := (&SimpleDefinitionProvider{}).findIdentAtOffset(f, fset, offset)
+	if ident == nil {
+		return nil
+	}
+	origin := core.Range{
+		Start: core.ByteOffsetToPosition(content, fset.Position(ident.Pos()).Offset),
+		End:   core.ByteOffsetToPosition(content, fset.Position(ident.End()).Offset),
+	}
+	link := func(links []core.LocationLink) []core.LocationLink {
+		for i := range links {
+			links[i].OriginSelectionRange = &origin
+		}
+		return links
+	}
+
+	info := typeCheckGoFile(fset, f)
+
+	// pkg.Name
+	nodes := goNodesEnclosing(fset, f, fset.Position(ident.Pos()).Offset, fset.Position(ident.End()).Offset)
+	if len(nodes) >= 2 {
+		if selector, ok := nodes[len(nodes)-2].(*ast.SelectorExpr); ok && selector.Sel == ident {
+			if x, ok := selector.X.(*ast.Ident); ok {
+				if pkgName, ok := info.Uses[x].(*types.PkgName); ok {
+					return link(p.importedDefinition(pkgName.Imported().Path(), ident.Name))
+				}
+			}
+		}
+	}
+
+	if obj := info.ObjectOf(ident); obj != nil {
+		if !obj.Pos().IsValid() || fset.File(obj.Pos()) != fset.File(f.Pos()) {
+			// Universe scope, like int
+			return nil
+		}
+		if _, ok := obj.(*types.PkgName); ok {
+			return nil
+		}
+		start := fset.Position(obj.Pos()).Offset
+		selection := core.Range{
+			Start: core.ByteOffsetToPosition(content, start),
+			End:   core.ByteOffsetToPosition(content, start+len(obj.Name())),
+		}
+		return link([]core.LocationLink{{
+			TargetURI:            uri,
+			TargetRange:          declarationRange(content, fset, f, obj.Pos(), selection),
+			TargetSelectionRange: selection,
+		}})
+	}
+
+	// Undefined in this file: a package-level name declared in another
+	// file of the package
+	dir := path.Dir(uripkg.Normalize(uri))
+	if p.Symbols != nil {
+		var links []core.LocationLink
+		for _, symbol := range p.Symbols.symbolsNamed(ident.Name) {
+			if path.Dir(uripkg.Normalize(symbol.Location.URI)) == dir && symbol.ContainerName == f.Name.Name {
+				links = append(links, symbolLink(symbol))
+			}
+		}
+		if len(links) > 0 {
+			return link(links)
+		}
+	}
+	return link(p.packageDefinition(uri, ident.Name))
+}
+
+// importedDefinition returns the declarations of the package-level name in
+// the indexed package with the import path. Without module information, or
+// if the directory the resolver finds is not indexed, the package is the
+// indexed directory matching the most trailing elements of the import path.
+func (p *GoWorkspaceDefinitionProvider) importedDefinition(importPath, name string) []core.LocationLink {
+	if p.Symbols == nil {
+		return nil
+	}
+	pkgName := path.Base(importPath)
+
+	if p.Resolver != nil {
+		if dir, _, err := p.Resolver.ResolveImport(importPath); err == nil {
+			dirURI := uripkg.Normalize(uripkg.FromPath(dir))
+			var links []core.LocationLink
+			for _, symbol := range p.Symbols.symbolsNamed(name) {
+				if symbol.ContainerName == pkgName && path.Dir(uripkg.Normalize(symbol.Location.URI)) == dirURI {
+					links = append(links, symbolLink(symbol))
+				}
+			}
+			if len(links) > 0 {
+				return links
+			}
+		}
+	}
+
+	best := 0
+	var links []core.LocationLink
+	for _, symbol := range p.Symbols.symbolsNamed(name) {
+		if symbol.ContainerName != pkgName {
+			// A method, or a package with another name
+			continue
+		}
+		matched := matchingTrailingElements(path.Dir(uripkg.Normalize(symbol.Location.URI)), importPath)
+		if matched == 0 || matched < best {
+			continue
+		}
+		if matched > best {
+			best = matched
+			links = nil
+		}
+		links = append(links, symbolLink(symbol))
+	}
+	return links
+}
+
+// packageDefinition returns the package-level declarations of name in the
+// other files of the package of uri.
+func (p *GoWorkspaceDefinitionProvider) packageDefinition(uri, name string) []core.LocationLink {
+	var files map[string]string
+	if p.PackageFiles != nil {
+		files = p.PackageFiles(uri)
+	} else {
+		files = goPackageFilesOnDisk(uri)
+	}
+
+	var links []core.LocationLink
+	for fileURI, content := range files {
+		if uripkg.Normalize(fileURI) == uripkg.Normalize(uri) {
+			continue
+		}
+		if p.Platform != nil && !p.Platform.MatchFile(path.Base(fileURI), content) {
+			continue
+		}
+		fset, f, err := parseGoFile(fileURI, content)
+		if err != nil {
+			continue
+		}
+		ident := packageLevelIdent(f, name)
+		if ident == nil {
+			continue
+		}
+		selection := core.Range{
+			Start: core.ByteOffsetToPosition(content, fset.Position(ident.Pos()).Offset),
+			End:   core.ByteOffsetToPosition(content, fset.Position(ident.End()).Offset),
+		}
+		links = append(links, core.LocationLink{
+			TargetURI:            fileURI,
+			TargetRange:          declarationRange(content, fset, f, ident.Pos(), selection),
+			TargetSelectionRange: selection,
+		})
+	}
+	return links
+}
+
+// packageLevelIdent returns the name of the package-level declaration of
+// name in the file, or nil. Methods are not package-level.
+func packageLevelIdent(f *ast.File, name string) *ast.Ident {
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil && d.Name.Name == name {
+				return d.Name
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.Name == name {
+						return s.Name
+					}
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						if n.Name == name {
+							return n
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// declarationRange returns the range of the declaration of the name at
+// pos, with its doc comment, or selection if there is none. A spec in a
+// declaration group stands for its declaration; a spec on its own is
+// extended to the keyword.
+func declarationRange(content string, fset *token.FileSet, f *ast.File, pos token.Pos, selection core.Range) core.Range {
+	offset := fset.Position(pos).Offset
+	nodes := goNodesEnclosing(fset, f, offset, offset)
+
+	var decl ast.Node
+	var doc *ast.CommentGroup
+outer:
+	for i := len(nodes) - 1; i >= 0; i-- {
+		switch n := nodes[i].(type) {
+		case *ast.FuncDecl:
+			decl, doc = n, n.Doc
+			break outer
+		case *ast.GenDecl:
+			if !n.Lparen.IsValid() {
+				decl, doc = n, n.Doc
+			}
+			break outer
+		case *ast.TypeSpec:
+			decl, doc = n, n.Doc
+		case *ast.ValueSpec:
+			decl, doc = n, n.Doc
+		case *ast.Field:
+			decl, doc = n, n.Doc
+		case *ast.AssignStmt:
+			decl, doc = n, nil
+		default:
+			if decl != nil {
+				break outer
+			}
+		}
+	}
+	if decl == nil {
+		return selection
+	}
+
+	start := decl.Pos()
+	if doc != nil {
+		start = doc.Pos()
+	}
+	return core.Range{
+		Start: core.ByteOffsetToPosition(content, fset.Position(start).Offset),
+		End:   core.ByteOffsetToPosition(content, fset.Position(decl.End()).Offset),
+	}
+}
+
+// symbolLink returns a link to an indexed symbol. The index only knows the
+// range of the name, which serves as both target ranges.
+func symbolLink(symbol core.WorkspaceSymbol) core.LocationLink {
+	return core.LocationLink{
+		TargetURI:            symbol.Location.URI,
+		TargetRange:          symbol.Location.Range,
+		TargetSelectionRange: symbol.Location.Range,
+	}
+}
+
+// goPackageFilesOnDisk returns the content of the Go files in the
+// directory of the file at uri, by URI.
+func goPackageFilesOnDisk(uri string) map[string]string {
+	filePath, err := uripkg.ToPath(uri)
+	if err != nil {
+		return nil
+	}
+	dir := filepath.Dir(filePath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	files := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err == nil {
+			files[uripkg.FromPath(filepath.Join(dir, entry.Name()))] = string(content)
+		}
+	}
+	return files
+}
+
+// matchingTrailingElements returns the number of trailing path elements
+// dir and importPath have in common.
+func matchingTrailingElements(dir, importPath string) int {
+	dirElems := strings.Split(dir, "/")
+	pathElems := strings.Split(importPath, "/")
+	n := 0
+	for n < len(dirElems) && n < len(pathElems) && dirElems[len(dirElems)-1-n] == pathElems[len(pathElems)-1-n] {
+		n++
+	}
+	return n
+}
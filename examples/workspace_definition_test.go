@@ -0,0 +1,102 @@
+package examples
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+const definitionMainSource = `package main
+
+import "example.com/app/internal/util"
+
+func main() {
+	util.Helper(limit)
+	run()
+	local := 1
+	_ = local
+}
+`
+
+func TestGoWorkspaceDefinitionProvider(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.mod":  "module example.com/app\n",
+		"main.go": definitionMainSource,
+		"run.go": `package main
+
+// run runs the app.
+func run() {}
+
+const (
+	limit = 10
+)
+`,
+		"internal/util/util.go": `package util
+
+// Helper helps.
+func Helper(n int) {}
+`,
+		"other/util/util.go": "package util\n\nfunc Helper(n int) {}\n",
+	})
+	uri := func(name string) string { return uripkg.FromPath(filepath.Join(root, filepath.FromSlash(name))) }
+
+	symbols := NewGoWorkspaceSymbolProvider(root)
+	if err := symbols.IndexWorkspace(); err != nil {
+		t.Fatal(err)
+	}
+
+	content := definitionMainSource
+
+	tests := []struct {
+		name            string
+		symbols         *GoWorkspaceSymbolProvider
+		target          string // text at the cursor
+		wantURI         string
+		wantLine        int
+		wantRangeStart  int // line of the target range start
+		wantOriginChars [2]int
+	}{
+		{name: "imported package", symbols: symbols, target: "Helper(limit)", wantURI: uri("internal/util/util.go"), wantLine: 3, wantRangeStart: 3, wantOriginChars: [2]int{6, 12}},
+		{name: "other file via index", symbols: symbols, target: "run()", wantURI: uri("run.go"), wantLine: 3, wantRangeStart: 3, wantOriginChars: [2]int{1, 4}},
+		{name: "other file without index", target: "run()", wantURI: uri("run.go"), wantLine: 3, wantRangeStart: 2, wantOriginChars: [2]int{1, 4}},
+		{name: "grouped constant without index", target: "limit)", wantURI: uri("run.go"), wantLine: 6, wantRangeStart: 6, wantOriginChars: [2]int{13, 18}},
+		{name: "local", symbols: symbols, target: "local\n", wantURI: uri("main.go"), wantLine: 7, wantRangeStart: 7, wantOriginChars: [2]int{5, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &GoWorkspaceDefinitionProvider{Symbols: tt.symbols}
+			offset := strings.Index(content, tt.target)
+			links := provider.ProvideDefinitionLinks(uri("main.go"), content, core.ByteOffsetToPosition(content, offset))
+			if len(links) != 1 {
+				t.Fatalf("expected one link, got %+v", links)
+			}
+			link := links[0]
+			if link.TargetURI != tt.wantURI {
+				t.Errorf("TargetURI = %s, want %s", link.TargetURI, tt.wantURI)
+			}
+			if int(link.TargetSelectionRange.Start.Line) != tt.wantLine {
+				t.Errorf("TargetSelectionRange = %+v, want line %d", link.TargetSelectionRange, tt.wantLine)
+			}
+			if int(link.TargetRange.Start.Line) != tt.wantRangeStart {
+				t.Errorf("TargetRange = %+v, want start line %d", link.TargetRange, tt.wantRangeStart)
+			}
+			if link.OriginSelectionRange == nil ||
+				int(link.OriginSelectionRange.Start.Character) != tt.wantOriginChars[0] ||
+				int(link.OriginSelectionRange.End.Character) != tt.wantOriginChars[1] {
+				t.Errorf("OriginSelectionRange = %+v, want characters %v", link.OriginSelectionRange, tt.wantOriginChars)
+			}
+		})
+	}
+}
+
+func TestGoWorkspaceDefinitionProviderBuiltin(t *testing.T) {
+	content := "package main\n\nvar x int\n"
+	provider := &GoWorkspaceDefinitionProvider{PackageFiles: func(string) map[string]string { return nil }}
+	if links := provider.ProvideDefinitionLinks("file:///builtin.go", content, core.Position{Line: 2, Character: 7}); links != nil {
+		t.Errorf("expected no definition for a predeclared type, got %+v", links)
+	}
+}
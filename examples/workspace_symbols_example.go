@@ -1,32 +1,289 @@
 package examples
 
 import (
+	"encoding/json"
+	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"go/types"
+	"io/fs"
 	"os"
-	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/gomod"
+	"github.com/SCKelemen/lsp/server"
+	uripkg "github.com/SCKelemen/lsp/uri"
+	"github.com/SCKelemen/lsp/workspace"
 )
 
 // GoWorkspaceSymbolProvider searches for Go symbols across a workspace.
 // This is useful for "Go to Symbol in Workspace" functionality.
+//
+// The index is kept up to date incrementally: Attach it to the document
+// store and pass it saves and file watcher events, and it marks the
+// affected files dirty. Dirty files are re-indexed on the next query, from
+// the editor's buffer if the file is open and from disk otherwise, so a
+// burst of edits costs one re-index.
 type GoWorkspaceSymbolProvider struct {
 	// WorkspaceRoot is the root directory of the workspace
 	WorkspaceRoot string
 
-	// Cache of symbols indexed by file
-	// In a real implementation, this would be updated on file changes
-	symbolCache map[string][]core.WorkspaceSymbol
+	// Resolver, when set, makes IndexWorkspace index the directories of
+	// the main modules instead of WorkspaceRoot, like the modules of a
+	// go.work file, which may be outside of it.
+	Resolver *gomod.Resolver
+
+	// Limit is the maximum number of symbols a query returns. If zero,
+	// all matching symbols are returned. Queries stop scanning the index
+	// once Limit symbols are found, so which matches are returned is
+	// unspecified.
+	Limit int
+
+	// index holds the symbols of each file by normalized URI
+	index symbolIndex
+
+	// mu guards dirty, documents, watchers, indexed, and indexing
+	mu sync.Mutex
+	// dirty maps the normalized URIs of files to re-index to their URIs
+	dirty map[string]string
+	// documents holds the open documents, if attached
+	documents *core.DocumentManager
+	// watchers are called with the material changes of indexed symbols
+	watchers []func(SymbolsChangedEvent)
+	// indexed is when IndexWorkspace last finished, and indexing how long
+	// it took
+	indexed  time.Time
+	indexing time.Duration
 }
 
 func NewGoWorkspaceSymbolProvider(workspaceRoot string) *GoWorkspaceSymbolProvider {
 	return &GoWorkspaceSymbolProvider{
 		WorkspaceRoot: workspaceRoot,
-		symbolCache:   make(map[string][]core.WorkspaceSymbol),
+		dirty:         make(map[string]string),
+	}
+}
+
+// Attach makes the provider follow the documents of dm: files are marked
+// dirty when they are opened, edited, or closed, and open files are
+// indexed from their buffer.
+func (p *GoWorkspaceSymbolProvider) Attach(dm *core.DocumentManager) {
+	p.mu.Lock()
+	p.documents = dm
+	p.mu.Unlock()
+
+	dm.Watch(func(event core.DocumentEvent) {
+		p.MarkDirty(event.URI)
+	})
+}
+
+// DidSave marks a saved file dirty. Attached providers already see the
+// edits; this matters for servers that only sync documents on save.
+func (p *GoWorkspaceSymbolProvider) DidSave(uri string) {
+	p.MarkDirty(uri)
+}
+
+// DidChangeWatchedFiles applies the events of the client's file watcher:
+// created and changed files are marked dirty, and deleted files, or every
+// file below a deleted folder, are dropped from the index.
+func (p *GoWorkspaceSymbolProvider) DidChangeWatchedFiles(events []core.FileEvent) {
+	for _, event := range events {
+		if event.Type != core.FileChangeTypeDeleted {
+			p.MarkDirty(event.URI)
+			continue
+		}
+
+		deleted := uripkg.Normalize(event.URI)
+		for _, file := range p.index.removeUnder(deleted) {
+			// Files without symbols have no symbols to remove
+			if len(file.symbols) > 0 {
+				p.notifySymbols(file.symbols[0].Location.URI, file, nil)
+			}
+		}
+		p.mu.Lock()
+		for key := range p.dirty {
+			if _, ok := replacePathPrefix(key, deleted, ""); ok {
+				delete(p.dirty, key)
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// MarkDirty schedules a Go file to be re-indexed on the next query.
+func (p *GoWorkspaceSymbolProvider) MarkDirty(uri string) {
+	if !strings.HasSuffix(uri, ".go") {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dirty[uripkg.Normalize(uri)] = uri
+}
+
+// refresh re-indexes the dirty files. Files that are neither open nor
+// readable from disk are dropped from the index.
+func (p *GoWorkspaceSymbolProvider) refresh() {
+	p.mu.Lock()
+	dirty := p.dirty
+	if len(dirty) == 0 {
+		p.mu.Unlock()
+		return
+	}
+	p.dirty = make(map[string]string)
+	documents := p.documents
+	p.mu.Unlock()
+
+	for key, uri := range dirty {
+		if documents != nil {
+			if doc, ok := documents.Get(uri); ok {
+				p.IndexFile(uri, doc.GetContent())
+				continue
+			}
+		}
+		if err := p.indexFromDisk(uri); err != nil {
+			p.notifySymbols(uri, p.index.remove(key), nil)
+		}
+	}
+}
+
+// indexFromDisk indexes a Go file from disk.
+func (p *GoWorkspaceSymbolProvider) indexFromDisk(uri string) error {
+	filePath, err := uripkg.ToPath(uri)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
 	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	p.indexContent(uri, string(content), stampOf(info))
+	return nil
+}
+
+// IndexWorkspace indexes every Go file under WorkspaceRoot, or the roots
+// of Resolver, skipping ignored, vendored, and oversized files. Files
+// already indexed from disk are only read again if their modification time
+// or size changed, so indexing after UnmarshalIndex only reads the files
+// changed since the snapshot.
+func (p *GoWorkspaceSymbolProvider) IndexWorkspace() error {
+	start := time.Now()
+	defer func() {
+		p.mu.Lock()
+		p.indexed = time.Now()
+		p.indexing = p.indexed.Sub(start)
+		p.mu.Unlock()
+	}()
+	return walkGoRoots(p.WorkspaceRoot, p.Resolver, func(path string, info fs.FileInfo) error {
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		uri := uripkg.FromPath(path)
+		stamp := stampOf(info)
+		if indexed, ok := p.index.stamp(uripkg.Normalize(uri)); ok && indexed.equal(stamp) {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		p.indexContent(uri, string(content), stamp)
+		return nil
+	})
+}
+
+// IndexStatistics returns the statistics of the index, for the
+// $/statistics request of server.HandleStatistics.
+func (p *GoWorkspaceSymbolProvider) IndexStatistics() server.IndexStatistics {
+	files, symbols := p.index.count()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return server.IndexStatistics{
+		Files:              files,
+		Symbols:            symbols,
+		LastIndexed:        p.indexed,
+		LastIndexingMillis: p.indexing.Milliseconds(),
+	}
+}
+
+// symbolIndexFormat is the version of the encoding of MarshalIndex.
+const symbolIndexFormat = 1
+
+// symbolIndexSnapshot is the encoding of the index of MarshalIndex.
+type symbolIndexSnapshot struct {
+	Format int                   `json:"format"`
+	Files  []indexedFileSnapshot `json:"files"`
+}
+
+// indexedFileSnapshot is the encoding of an indexed file.
+type indexedFileSnapshot struct {
+	URI        string                 `json:"uri"`
+	ModTime    time.Time              `json:"modTime"`
+	Size       int64                  `json:"size"`
+	Symbols    []core.WorkspaceSymbol `json:"symbols,omitempty"`
+	Signatures []string               `json:"signatures,omitempty"`
+}
+
+// MarshalIndex encodes the files of the index indexed from disk, for
+// warmstart.Handler to save across restarts. Files indexed from an
+// editor's buffer are left out, as their content may differ from disk.
+// ([warmstart.Index] interface)
+func (p *GoWorkspaceSymbolProvider) MarshalIndex() ([]byte, error) {
+	p.refresh()
+
+	snapshot := symbolIndexSnapshot{Format: symbolIndexFormat, Files: []indexedFileSnapshot{}}
+	p.index.each(func(key string, file *indexedFile) {
+		if file.stamp.modTime.IsZero() {
+			return
+		}
+		snapshot.Files = append(snapshot.Files, indexedFileSnapshot{
+			URI:        key,
+			ModTime:    file.stamp.modTime,
+			Size:       file.stamp.size,
+			Symbols:    file.symbols,
+			Signatures: file.signatures,
+		})
+	})
+	return json.Marshal(snapshot)
+}
+
+// UnmarshalIndex restores the files of an encoding of MarshalIndex that are
+// unchanged on disk. Changed files are marked dirty rather than restored,
+// and deleted ones are left out; call IndexWorkspace afterwards to index
+// the files created since. ([warmstart.Index] interface)
+func (p *GoWorkspaceSymbolProvider) UnmarshalIndex(data []byte) error {
+	var snapshot symbolIndexSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+	if snapshot.Format != symbolIndexFormat {
+		return fmt.Errorf("symbol index format %d, want %d", snapshot.Format, symbolIndexFormat)
+	}
+
+	for _, file := range snapshot.Files {
+		filePath, err := uripkg.ToPath(file.URI)
+		if err != nil {
+			continue
+		}
+		info, err := os.Stat(filePath)
+		if err != nil {
+			continue
+		}
+		stamp := fileStamp{modTime: file.ModTime, size: file.Size}
+		if !stamp.equal(stampOf(info)) {
+			p.MarkDirty(file.URI)
+			continue
+		}
+		previous := p.index.set(file.URI, file.Symbols, file.Signatures, stamp)
+		p.notifySymbols(file.URI, previous, &indexedFile{symbols: file.Symbols, signatures: file.Signatures})
+	}
+	return nil
 }
 
 // IndexFile indexes symbols in a single Go file.
@@ -36,14 +293,66 @@ func (p *GoWorkspaceSymbolProvider) IndexFile(uri, content string) {
 		return
 	}
 
+	p.indexContent(uri, content, fileStamp{})
+}
+
+// indexContent indexes the content of a Go file, with the stamp of the file
+// on disk it was read from, if any.
+func (p *GoWorkspaceSymbolProvider) indexContent(uri, content string, stamp fileStamp) {
+	symbols, signatures := p.fileSymbols(uri, content)
+	previous := p.index.set(uripkg.Normalize(uri), symbols, signatures, stamp)
+	p.notifySymbols(uri, previous, &indexedFile{symbols: symbols, signatures: signatures})
+}
+
+// WatchSymbols registers fn to be called with the material changes of the
+// symbols of files as they are re-indexed or dropped from the index. It is
+// called synchronously, by whichever call indexed the file: IndexFile,
+// IndexWorkspace, a query refreshing dirty files, or
+// DidChangeWatchedFiles.
+func (p *GoWorkspaceSymbolProvider) WatchSymbols(fn func(SymbolsChangedEvent)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.watchers = append(p.watchers, fn)
+}
+
+// notifySymbols reports the changes from the symbols of before to those of
+// after to the watchers, if there are any.
+func (p *GoWorkspaceSymbolProvider) notifySymbols(uri string, before, after *indexedFile) {
+	p.mu.Lock()
+	watchers := p.watchers
+	p.mu.Unlock()
+	if len(watchers) == 0 {
+		return
+	}
+	event := diffSymbols(uri, before, after)
+	if event.empty() {
+		return
+	}
+	for _, fn := range watchers {
+		fn(event)
+	}
+}
+
+// fileSymbols returns the package-level symbols of a Go file with their
+// signatures, or nil if it does not parse.
+func (p *GoWorkspaceSymbolProvider) fileSymbols(uri, content string) ([]core.WorkspaceSymbol, []string) {
 	var symbols []core.WorkspaceSymbol
+	var signatures []string
 
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	fset, f, err := parseGoFile(uri, content)
 	if err != nil {
 		// Invalid syntax - clear symbols for this file
-		p.symbolCache[uri] = nil
-		return
+		return nil, nil
+	}
+
+	// Enums are Enum symbols, and their constants EnumMember symbols
+	// contained in the enum
+	enums := goEnumBlocks(f)
+	enumOf := make(map[*ast.GenDecl]string)
+	for typeName, blocks := range enums {
+		for _, block := range blocks {
+			enumOf[block] = typeName
+		}
 	}
 
 	// Extract package-level symbols
@@ -53,6 +362,7 @@ func (p *GoWorkspaceSymbolProvider) IndexFile(uri, content string) {
 			symbol := p.funcDeclToSymbol(d, fset, uri, f.Name.Name)
 			if symbol != nil {
 				symbols = append(symbols, *symbol)
+				signatures = append(signatures, types.ExprString(d.Type))
 			}
 
 		case *ast.GenDecl:
@@ -62,15 +372,25 @@ func (p *GoWorkspaceSymbolProvider) IndexFile(uri, content string) {
 				case *ast.TypeSpec:
 					symbol := p.typeSpecToSymbol(s, fset, uri, f.Name.Name)
 					if symbol != nil {
+						symbol.Tags = deprecatedSymbolTags(declSpecDoc(d, s))
+						if len(enums[s.Name.Name]) > 0 {
+							symbol.Kind = core.SymbolKindEnum
+						}
 						symbols = append(symbols, *symbol)
+						signatures = append(signatures, typeSpecSignature(s))
 					}
 
 				case *ast.ValueSpec:
 					// Constants and variables
 					kind := core.SymbolKindVariable
+					containerName := f.Name.Name
 					if d.Tok == token.CONST {
 						kind = core.SymbolKindConstant
 					}
+					if typeName, ok := enumOf[d]; ok {
+						kind = core.SymbolKindEnumMember
+						containerName = typeName
+					}
 
 					for _, name := range s.Names {
 						if name.Name == "_" {
@@ -80,10 +400,16 @@ func (p *GoWorkspaceSymbolProvider) IndexFile(uri, content string) {
 						pos := fset.Position(name.Pos())
 						endPos := fset.Position(name.End())
 
+						signature := ""
+						if s.Type != nil {
+							signature = types.ExprString(s.Type)
+						}
+						signatures = append(signatures, signature)
 						symbols = append(symbols, core.WorkspaceSymbol{
 							Name:          name.Name,
 							Kind:          kind,
-							ContainerName: f.Name.Name,
+							Tags:          deprecatedSymbolTags(declSpecDoc(d, s)),
+							ContainerName: containerName,
 							Location: core.Location{
 								URI: uri,
 								Range: core.Range{
@@ -98,7 +424,34 @@ func (p *GoWorkspaceSymbolProvider) IndexFile(uri, content string) {
 		}
 	}
 
-	p.symbolCache[uri] = symbols
+	return symbols, signatures
+}
+
+// typeSpecSignature returns the signature of a type declaration, like
+// "[T any] struct{v T}" or "= int" for an alias.
+func typeSpecSignature(ts *ast.TypeSpec) string {
+	var b strings.Builder
+	if ts.TypeParams != nil {
+		b.WriteByte('[')
+		for i, field := range ts.TypeParams.List {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			for j, name := range field.Names {
+				if j > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString(name.Name)
+			}
+			b.WriteString(" " + types.ExprString(field.Type))
+		}
+		b.WriteString("] ")
+	}
+	if ts.Assign.IsValid() {
+		b.WriteString("= ")
+	}
+	b.WriteString(types.ExprString(ts.Type))
+	return b.String()
 }
 
 func (p *GoWorkspaceSymbolProvider) funcDeclToSymbol(fn *ast.FuncDecl, fset *token.FileSet, uri, packageName string) *core.WorkspaceSymbol {
@@ -130,6 +483,7 @@ func (p *GoWorkspaceSymbolProvider) funcDeclToSymbol(fn *ast.FuncDecl, fset *tok
 	return &core.WorkspaceSymbol{
 		Name:          fn.Name.Name,
 		Kind:          kind,
+		Tags:          deprecatedSymbolTags(fn.Doc),
 		ContainerName: containerName,
 		Location: core.Location{
 			URI: uri,
@@ -177,22 +531,87 @@ func (p *GoWorkspaceSymbolProvider) typeSpecToSymbol(ts *ast.TypeSpec, fset *tok
 // ProvideWorkspaceSymbols returns symbols matching the query.
 // The query is matched against symbol names (case-insensitive substring match).
 func (p *GoWorkspaceSymbolProvider) ProvideWorkspaceSymbols(query string) []core.WorkspaceSymbol {
-	var results []core.WorkspaceSymbol
+	p.refresh()
+	return p.index.query(query, p.Limit)
+}
 
-	// Normalize query for case-insensitive matching
-	queryLower := strings.ToLower(query)
+// ResolveWorkspaceSymbol fills in the detail of a symbol from the index:
+// the signature of a function or method in short, like
+// "Fetch(ctx, id) (*User, error)", and the declared type of other symbols,
+// like "struct{ID int}" or "time.Duration". Symbols whose file changed since
+// they were returned are left as they are.
+func (p *GoWorkspaceSymbolProvider) ResolveWorkspaceSymbol(symbol core.WorkspaceSymbol) core.WorkspaceSymbol {
+	signature, ok := p.index.signatureOf(uripkg.Normalize(symbol.Location.URI), symbol)
+	if !ok || signature == "" {
+		return symbol
+	}
+	symbol.Detail = signature
+	if symbol.Kind == core.SymbolKindFunction || symbol.Kind == core.SymbolKindMethod {
+		symbol.Detail = shortFuncSignature(symbol.Name, signature)
+	}
+	return symbol
+}
 
-	// Search through all cached symbols
-	for _, symbols := range p.symbolCache {
-		for _, symbol := range symbols {
-			// Match query against symbol name (case-insensitive)
-			if query == "" || strings.Contains(strings.ToLower(symbol.Name), queryLower) {
-				results = append(results, symbol)
+// shortFuncSignature renders the signature of a function from its type,
+// like "func(ctx context.Context, id string) error", with only the names of
+// named parameters: "Fetch(ctx, id) error".
+func shortFuncSignature(name, signature string) string {
+	expr, err := parser.ParseExpr(signature)
+	if err != nil {
+		return signature
+	}
+	fn, ok := expr.(*ast.FuncType)
+	if !ok {
+		return signature
+	}
+
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('(')
+	for i, field := range fn.Params.List {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if len(field.Names) == 0 {
+			b.WriteString(types.ExprString(field.Type))
+			continue
+		}
+		for j, param := range field.Names {
+			if j > 0 {
+				b.WriteString(", ")
 			}
+			b.WriteString(param.Name)
 		}
 	}
+	b.WriteByte(')')
 
-	return results
+	if fn.Results == nil || len(fn.Results.List) == 0 {
+		return b.String()
+	}
+	var results []string
+	for _, field := range fn.Results.List {
+		for range max(len(field.Names), 1) {
+			results = append(results, types.ExprString(field.Type))
+		}
+	}
+	if len(results) == 1 {
+		b.WriteString(" " + results[0])
+	} else {
+		b.WriteString(" (" + strings.Join(results, ", ") + ")")
+	}
+	return b.String()
+}
+
+// symbolsNamed returns the indexed symbols named name, ignoring Limit.
+func (p *GoWorkspaceSymbolProvider) symbolsNamed(name string) []core.WorkspaceSymbol {
+	p.refresh()
+	var symbols []core.WorkspaceSymbol
+	for _, symbol := range p.index.query(name, 0) {
+		if symbol.Name == name {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
 }
 
 // SimpleWorkspaceSymbolProvider provides workspace symbols with a simple in-memory index.
@@ -241,24 +660,28 @@ func (p *SimpleWorkspaceSymbolProvider) ProvideWorkspaceSymbols(query string) []
 // This is less efficient but doesn't require maintaining a cache.
 type FileSystemWorkspaceSymbolProvider struct {
 	WorkspaceRoot string
+
+	// Walker selects the files to scan. If nil, goWorkspaceWalker is used.
+	Walker *workspace.Walker
 }
 
 func (p *FileSystemWorkspaceSymbolProvider) ProvideWorkspaceSymbols(query string) []core.WorkspaceSymbol {
 	var symbols []core.WorkspaceSymbol
 
-	// Walk the workspace directory
-	_ = filepath.Walk(p.WorkspaceRoot, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip files with errors
-		}
+	// Files on disk are parsed once and dropped, so they bypass the AST cache
+	// (which would evict open documents). One FileSet serves the whole walk;
+	// each file is removed from it once its symbols are extracted.
+	fset := token.NewFileSet()
 
-		// Only process .go files
-		if info.IsDir() || !strings.HasSuffix(path, ".go") {
-			return nil
-		}
+	walker := p.Walker
+	if walker == nil {
+		walker = goWorkspaceWalker(p.WorkspaceRoot)
+	}
 
-		// Skip test files and vendor directories
-		if strings.HasSuffix(path, "_test.go") || strings.Contains(path, "/vendor/") {
+	// Walk the workspace directory
+	_ = walker.Walk(func(path string, info fs.FileInfo) error {
+		// Only process non-test .go files
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
 			return nil
 		}
 
@@ -268,14 +691,16 @@ func (p *FileSystemWorkspaceSymbolProvider) ProvideWorkspaceSymbols(query string
 			return nil
 		}
 
-		fset := token.NewFileSet()
 		f, err := parser.ParseFile(fset, path, content, 0)
+		if file := fset.File(f.FileStart); file != nil {
+			defer fset.RemoveFile(file)
+		}
 		if err != nil {
 			return nil
 		}
 
 		// Extract symbols from this file
-		uri := "file://" + path
+		uri := uripkg.FromPath(path)
 		fileSymbols := p.extractSymbols(f, fset, uri, query)
 		symbols = append(symbols, fileSymbols...)
 
@@ -285,6 +710,31 @@ func (p *FileSystemWorkspaceSymbolProvider) ProvideWorkspaceSymbols(query string
 	return symbols
 }
 
+// goWorkspaceWalker returns the default walker for Go workspace scans:
+// workspace.NewWalker plus the vendor directory.
+func goWorkspaceWalker(root string) *workspace.Walker {
+	walker := workspace.NewWalker(root)
+	walker.Exclude = append(walker.Exclude, "vendor/")
+	return walker
+}
+
+// walkGoRoots walks the roots of resolver with goWorkspaceWalker, or root
+// if resolver is nil or finds no module.
+func walkGoRoots(root string, resolver *gomod.Resolver, fn workspace.WalkFunc) error {
+	roots := []string{root}
+	if resolver != nil {
+		if moduleRoots := resolver.Roots(); len(moduleRoots) > 0 {
+			roots = moduleRoots
+		}
+	}
+	for _, root := range roots {
+		if err := goWorkspaceWalker(root).Walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *FileSystemWorkspaceSymbolProvider) extractSymbols(f *ast.File, fset *token.FileSet, uri, query string) []core.WorkspaceSymbol {
 	var symbols []core.WorkspaceSymbol
 	queryLower := strings.ToLower(query)
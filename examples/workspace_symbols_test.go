@@ -1,10 +1,16 @@
 package examples
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
 	"github.com/SCKelemen/lsp/core"
+	uripkg "github.com/SCKelemen/lsp/uri"
 )
 
 // TestSimpleWorkspaceSymbolProvider tests basic workspace symbol search.
@@ -612,3 +618,374 @@ type 配置 struct {
 		}
 	}
 }
+
+// writeWorkspace creates a small Go workspace with ignored and vendored files.
+func writeWorkspace(t *testing.T) string {
+	t.Helper()
+	return writeFiles(t, map[string]string{
+		".gitignore":            "gen/\n",
+		"main.go":               "package main\n\nfunc Visible() {}\n",
+		"main_test.go":          "package main\n\nfunc TestHelper() {}\n",
+		"gen/gen.go":            "package gen\n\nfunc Generated() {}\n",
+		"vendor/dep/dep.go":     "package dep\n\nfunc Vendored() {}\n",
+		"node_modules/x/x.go":   "package x\n\nfunc Dependency() {}\n",
+		"internal/util/util.go": "package util\n\nfunc Helper() {}\n",
+	})
+}
+
+// writeFiles creates files, given by slash-separated path relative to the
+// root, in a temporary directory and returns the root.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// TestFileSystemWorkspaceSymbolProvider_SkipsIgnoredFiles tests that scans
+// honor .gitignore and skip dependency directories.
+func TestFileSystemWorkspaceSymbolProvider_SkipsIgnoredFiles(t *testing.T) {
+	provider := &FileSystemWorkspaceSymbolProvider{WorkspaceRoot: writeWorkspace(t)}
+
+	var names []string
+	for _, symbol := range provider.ProvideWorkspaceSymbols("") {
+		names = append(names, symbol.Name)
+	}
+	sort.Strings(names)
+
+	want := []string{"Helper", "Visible"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got symbols %v, want %v", names, want)
+	}
+}
+
+// TestGoWorkspaceSymbolProvider_IndexWorkspace tests indexing a workspace from disk.
+func TestGoWorkspaceSymbolProvider_IndexWorkspace(t *testing.T) {
+	provider := NewGoWorkspaceSymbolProvider(writeWorkspace(t))
+	if err := provider.IndexWorkspace(); err != nil {
+		t.Fatalf("IndexWorkspace failed: %v", err)
+	}
+
+	if symbols := provider.ProvideWorkspaceSymbols("Visible"); len(symbols) != 1 {
+		t.Errorf("expected Visible to be indexed, got %d symbols", len(symbols))
+	}
+	for _, query := range []string{"Generated", "Vendored", "Dependency"} {
+		if symbols := provider.ProvideWorkspaceSymbols(query); len(symbols) != 0 {
+			t.Errorf("expected %s to be skipped, got %d symbols", query, len(symbols))
+		}
+	}
+
+	statistics := provider.IndexStatistics()
+	if statistics.Files != 3 || statistics.Symbols != 3 || statistics.LastIndexed.IsZero() {
+		t.Errorf("expected 3 files and symbols indexed, got %+v", statistics)
+	}
+}
+
+// TestGoWorkspaceSymbolProvider_IncrementalUpdates tests that document
+// edits and file watcher events keep the index up to date.
+func TestGoWorkspaceSymbolProvider_IncrementalUpdates(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"a.go":      "package main\n\nfunc Alpha() {}\n",
+		"util/b.go": "package util\n\nfunc Beta() {}\n",
+	})
+	aURI := uripkg.FromPath(filepath.Join(root, "a.go"))
+
+	provider := NewGoWorkspaceSymbolProvider(root)
+	if err := provider.IndexWorkspace(); err != nil {
+		t.Fatalf("IndexWorkspace failed: %v", err)
+	}
+	documents := core.NewDocumentManager()
+	provider.Attach(documents)
+
+	names := func() []string {
+		var names []string
+		for _, symbol := range provider.ProvideWorkspaceSymbols("") {
+			names = append(names, symbol.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	expect := func(step string, want ...string) {
+		t.Helper()
+		if got := names(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got symbols %v, want %v", step, got, want)
+		}
+	}
+	expect("indexed", "Alpha", "Beta")
+
+	// Unsaved edits are indexed from the buffer
+	documents.Open(aURI, "package main\n\nfunc Alpha() {}\n", 1)
+	documents.Update(aURI, "package main\n\nfunc Alpha() {}\n\nfunc Gamma() {}\n")
+	expect("edited", "Alpha", "Beta", "Gamma")
+
+	// Closing without saving falls back to the file on disk
+	documents.Close(aURI)
+	expect("closed", "Alpha", "Beta")
+
+	// External changes come from the file watcher
+	if err := os.WriteFile(filepath.Join(root, "util", "c.go"), []byte("package util\n\nfunc Delta() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	provider.DidChangeWatchedFiles([]core.FileEvent{{URI: uripkg.FromPath(filepath.Join(root, "util", "c.go")), Type: core.FileChangeTypeCreated}})
+	expect("created", "Alpha", "Beta", "Delta")
+
+	// Deleting a folder drops every file below it
+	provider.DidChangeWatchedFiles([]core.FileEvent{{URI: uripkg.FromPath(filepath.Join(root, "util")), Type: core.FileChangeTypeDeleted}})
+	expect("deleted", "Alpha")
+
+	// A changed file that no longer exists is dropped too
+	if err := os.Remove(filepath.Join(root, "a.go")); err != nil {
+		t.Fatal(err)
+	}
+	provider.DidChangeWatchedFiles([]core.FileEvent{{URI: aURI, Type: core.FileChangeTypeChanged}})
+	expect("vanished")
+}
+
+// TestGoWorkspaceSymbolProvider_Limit tests that queries over many packages
+// return at most Limit symbols.
+func TestGoWorkspaceSymbolProvider_Limit(t *testing.T) {
+	provider := NewGoWorkspaceSymbolProvider("/workspace")
+	content := benchmarkGoSource(100)
+	for i := 0; i < 50; i++ {
+		provider.IndexFile(fmt.Sprintf("file:///workspace/pkg%d/file.go", i), content)
+	}
+
+	if symbols := provider.ProvideWorkspaceSymbols("Func4"); len(symbols) != 50*11 {
+		t.Errorf("expected %d symbols without a limit, got %d", 50*11, len(symbols))
+	}
+
+	provider.Limit = 25
+	symbols := provider.ProvideWorkspaceSymbols("Func4")
+	if len(symbols) != 25 {
+		t.Fatalf("expected 25 symbols with a limit, got %d", len(symbols))
+	}
+	for _, symbol := range symbols {
+		if !strings.HasPrefix(symbol.Name, "Func4") {
+			t.Errorf("unexpected symbol %s", symbol.Name)
+		}
+	}
+}
+
+func TestGoWorkspaceSymbolProvider_Deprecated(t *testing.T) {
+	provider := NewGoWorkspaceSymbolProvider("/workspace")
+	provider.IndexFile("file:///deprecated.go", `package main
+
+// Deprecated: Use Client.
+type OldClient struct{}
+
+type Client struct{}
+
+// Dial connects.
+//
+// Deprecated: Use Client.Dial.
+func Dial() {}
+
+// Deprecated: Use Client.Timeout.
+var DefaultTimeout = 10
+`)
+
+	deprecated := map[string]bool{}
+	for _, symbol := range provider.ProvideWorkspaceSymbols("") {
+		deprecated[symbol.Name] = len(symbol.Tags) == 1 && symbol.Tags[0] == core.SymbolTagDeprecated
+	}
+	want := map[string]bool{"OldClient": true, "Client": false, "Dial": true, "DefaultTimeout": true}
+	for name, wantDeprecated := range want {
+		if deprecated[name] != wantDeprecated {
+			t.Errorf("%s deprecated = %v, want %v", name, deprecated[name], wantDeprecated)
+		}
+	}
+}
+
+func TestGoWorkspaceSymbolProvider_WatchSymbols(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"util/a.go": "package util\n\nfunc Alpha(s string) error { return nil }\n\ntype Beta struct{}\n",
+	})
+	aURI := uripkg.FromPath(filepath.Join(root, "util", "a.go"))
+
+	provider := NewGoWorkspaceSymbolProvider(root)
+	var events []SymbolsChangedEvent
+	provider.WatchSymbols(func(event SymbolsChangedEvent) {
+		events = append(events, event)
+	})
+	names := func(symbols []core.WorkspaceSymbol) string {
+		var names []string
+		for _, symbol := range symbols {
+			names = append(names, symbol.Name)
+		}
+		return strings.Join(names, ",")
+	}
+	expect := func(step, added, removed, changed string) {
+		t.Helper()
+		if len(events) != 1 {
+			t.Fatalf("%s: got %d events, want 1", step, len(events))
+		}
+		event := events[0]
+		events = nil
+		if event.URI != aURI || names(event.Added) != added || names(event.Removed) != removed || names(event.Changed) != changed {
+			t.Errorf("%s: got added %q, removed %q, changed %q in %s, want %q, %q, %q",
+				step, names(event.Added), names(event.Removed), names(event.Changed), event.URI, added, removed, changed)
+		}
+	}
+
+	if err := provider.IndexWorkspace(); err != nil {
+		t.Fatal(err)
+	}
+	expect("indexed", "Alpha,Beta", "", "")
+
+	// Moving declarations and editing bodies are not material changes
+	provider.IndexFile(aURI, "package util\n\n// Beta is empty.\ntype Beta struct{}\n\nfunc Alpha(s string) error {\n\treturn fmt.Errorf(s)\n}\n")
+	if len(events) != 0 {
+		t.Errorf("got events %+v for a change of bodies, want none", events)
+	}
+
+	provider.IndexFile(aURI, "package util\n\ntype Beta struct{ n int }\n\nfunc Alpha(s string) error { return nil }\n\nfunc Gamma() {}\n")
+	expect("edited", "Gamma", "", "Beta")
+
+	provider.IndexFile(aURI, "package util\n\n// Deprecated: Use Gamma.\nfunc Alpha(s string) error { return nil }\n\nfunc Gamma() {}\n")
+	expect("deprecated", "", "Beta", "Alpha")
+
+	// Deleting a folder removes the symbols of its files
+	provider.DidChangeWatchedFiles([]core.FileEvent{{URI: uripkg.FromPath(filepath.Join(root, "util")), Type: core.FileChangeTypeDeleted}})
+	expect("deleted", "", "Alpha,Gamma", "")
+}
+
+func TestGoWorkspaceSymbolProvider_Enums(t *testing.T) {
+	provider := NewGoWorkspaceSymbolProvider("/workspace")
+	provider.IndexFile("file:///workspace/color.go", `package paint
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+)
+
+const Max = 10
+`)
+
+	got := map[string]string{}
+	for _, symbol := range provider.ProvideWorkspaceSymbols("") {
+		got[symbol.Name] = fmt.Sprintf("%d %s", symbol.Kind, symbol.ContainerName)
+	}
+	want := map[string]string{
+		"Color": fmt.Sprintf("%d paint", core.SymbolKindEnum),
+		"Red":   fmt.Sprintf("%d Color", core.SymbolKindEnumMember),
+		"Green": fmt.Sprintf("%d Color", core.SymbolKindEnumMember),
+		"Max":   fmt.Sprintf("%d paint", core.SymbolKindConstant),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("symbols = %v, want %v", got, want)
+	}
+}
+
+func TestGoWorkspaceSymbolProvider_ResolveDetail(t *testing.T) {
+	provider := NewGoWorkspaceSymbolProvider("/workspace")
+	provider.IndexFile("file:///workspace/users.go", `package users
+
+import "context"
+
+type User struct{ ID int }
+
+func Fetch(ctx context.Context, id int) (*User, error) { return nil, nil }
+
+func (u *User) Rename(string) {}
+
+var Timeout = 10
+`)
+
+	got := map[string]string{}
+	for _, symbol := range provider.ProvideWorkspaceSymbols("") {
+		if symbol.Detail != "" {
+			t.Errorf("%s has detail %q before resolve", symbol.Name, symbol.Detail)
+		}
+		got[symbol.Name] = provider.ResolveWorkspaceSymbol(symbol).Detail
+	}
+	want := map[string]string{
+		"User":    "struct{ID int}",
+		"Fetch":   "Fetch(ctx, id) (*User, error)",
+		"Rename":  "Rename(string)",
+		"Timeout": "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("details = %v, want %v", got, want)
+	}
+
+	// A symbol whose file changed since is left unresolved
+	symbols := provider.ProvideWorkspaceSymbols("Fetch")
+	provider.IndexFile("file:///workspace/users.go", "package users\n")
+	if detail := provider.ResolveWorkspaceSymbol(symbols[0]).Detail; detail != "" {
+		t.Errorf("detail of a removed symbol = %q, want none", detail)
+	}
+}
+
+func TestGoWorkspaceSymbolProvider_MarshalIndex(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"a.go": "package main\n\nfunc Alpha() {}\n",
+		"b.go": "package main\n\nfunc Beta() {}\n",
+		"c.go": "package main\n\nfunc Gamma() {}\n",
+	})
+	provider := NewGoWorkspaceSymbolProvider(root)
+	if err := provider.IndexWorkspace(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := provider.MarshalIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// While the server is down, b.go changes, c.go is deleted, and d.go
+	// is created. a.go is rewritten with its stamp kept, to tell whether
+	// it is read again.
+	aPath := filepath.Join(root, "a.go")
+	info, err := os.Stat(aPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"a.go": "package main\n\nfunc Alpho() {}\n",
+		"b.go": "package main\n\nfunc Delta(n int) {}\n",
+		"d.go": "package main\n\nfunc Epsilon() {}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(aPath, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(root, "c.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewGoWorkspaceSymbolProvider(root)
+	if err := restored.UnmarshalIndex(data); err != nil {
+		t.Fatal(err)
+	}
+	if files := restored.IndexStatistics().Files; files != 1 {
+		t.Errorf("restored %d files, want only the unchanged a.go", files)
+	}
+	if err := restored.IndexWorkspace(); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, symbol := range restored.ProvideWorkspaceSymbols("") {
+		names = append(names, symbol.Name)
+	}
+	sort.Strings(names)
+	if want := []string{"Alpha", "Delta", "Epsilon"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("symbols after restoring = %v, want %v", names, want)
+	}
+
+	if err := restored.UnmarshalIndex([]byte(`{"format":0}`)); err == nil {
+		t.Error("expected an error for another format")
+	}
+}
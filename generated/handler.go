@@ -0,0 +1,175 @@
+// Package generated keeps a server from nagging about files nobody edits by
+// hand: generated code, minified code, and binary data.
+//
+// A Handler middleware classifies documents with core.ClassifyContent as
+// they are opened and changed. For suppressed kinds, which are all but
+// source by default, their diagnostics are dropped and refactorings, like
+// code actions and renames, are not offered, while navigation, like hover
+// and go to definition, keeps working.
+package generated
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/internal/textdocument"
+	"github.com/SCKelemen/lsp/protocol"
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+// methodTextDocumentDiagnostic is the pull diagnostics request.
+const methodTextDocumentDiagnostic = "textDocument/diagnostic"
+
+// DefaultSuppressedMethods are the requests Handler answers with no result
+// for suppressed documents.
+var DefaultSuppressedMethods = map[string]bool{
+	protocol.MethodTextDocumentCodeAction:    true,
+	protocol.MethodTextDocumentRename:        true,
+	protocol.MethodTextDocumentPrepareRename: true,
+	methodTextDocumentDiagnostic:             true,
+}
+
+// DefaultSuppressedKinds are the kinds of content Handler suppresses.
+var DefaultSuppressedKinds = map[core.ContentKind]bool{
+	core.ContentGenerated: true,
+	core.ContentMinified:  true,
+	core.ContentBinary:    true,
+}
+
+// Handler is a handler middleware suppressing diagnostics and refactorings
+// in generated, minified, and binary documents.
+type Handler struct {
+	// Handler handles the messages, except the suppressed requests about
+	// suppressed documents.
+	Handler lsp.Handler
+
+	// Documents holds the content of the open documents, kept up to date
+	// by Handler.
+	Documents *core.DocumentManager
+
+	// Kinds are the kinds of content suppressed. Clear it, e.g. from the
+	// configuration of the server, to treat every document as source.
+	Kinds map[core.ContentKind]bool
+
+	// Methods are the requests suppressed.
+	Methods map[string]bool
+
+	mu    sync.Mutex
+	kinds map[string]core.ContentKind // by normalized document URI
+}
+
+// NewHandler creates a middleware suppressing DefaultSuppressedMethods and
+// diagnostics for DefaultSuppressedKinds, for documents whose content
+// handler keeps in documents.
+func NewHandler(handler lsp.Handler, documents *core.DocumentManager) *Handler {
+	return &Handler{
+		Handler:   handler,
+		Documents: documents,
+		Kinds:     DefaultSuppressedKinds,
+		Methods:   DefaultSuppressedMethods,
+	}
+}
+
+// Kind returns the kind of the content of the open document uri.
+func (h *Handler) Kind(uri string) core.ContentKind {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.kinds[uripkg.Normalize(uri)]
+}
+
+// Suppressed reports whether the open document uri is suppressed, e.g. for
+// diagnostics published outside of Handler to skip it.
+func (h *Handler) Suppressed(uri string) bool {
+	return h.Kinds[h.Kind(uri)]
+}
+
+// ([lsp.Handler] interface)
+func (h *Handler) Handle(context *lsp.Context) (any, bool, bool, error) {
+	uri := textdocument.URI(context.Params)
+	if uri != "" && h.Methods[context.Method] && h.Suppressed(uri) {
+		return h.suppressedResult(context.Method, uri)
+	}
+
+	handlerContext := *context
+	if context.Notify != nil {
+		handlerContext.Notify = func(method string, params any) {
+			if method == protocol.ServerTextDocumentPublishDiagnostics {
+				params = h.filterDiagnostics(params)
+			}
+			context.Notify(method, params)
+		}
+	}
+
+	result, validMethod, validParams, err := h.Handler.Handle(&handlerContext)
+
+	switch context.Method {
+	case protocol.MethodTextDocumentDidOpen, protocol.MethodTextDocumentDidChange:
+		if uri != "" {
+			h.classify(uri)
+		}
+	case protocol.MethodTextDocumentDidClose:
+		if uri != "" {
+			h.mu.Lock()
+			delete(h.kinds, uripkg.Normalize(uri))
+			h.mu.Unlock()
+		}
+	}
+
+	return result, validMethod, validParams, err
+}
+
+// suppressedResult answers a suppressed request.
+func (h *Handler) suppressedResult(method, uri string) (any, bool, bool, error) {
+	switch method {
+	case methodTextDocumentDiagnostic:
+		return protocol.RelatedFullDocumentDiagnosticReport{
+			FullDocumentDiagnosticReport: protocol.FullDocumentDiagnosticReport{
+				Kind:  string(protocol.DocumentDiagnosticReportKindFull),
+				Items: []protocol.Diagnostic{},
+			},
+		}, true, true, nil
+	case protocol.MethodTextDocumentRename:
+		return nil, true, true, fmt.Errorf("cannot rename in %s content", h.Kind(uri))
+	}
+	return nil, true, true, nil
+}
+
+// filterDiagnostics empties the diagnostics published for suppressed
+// documents.
+func (h *Handler) filterDiagnostics(params any) any {
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return params
+	}
+	var diagnostics protocol.PublishDiagnosticsParams
+	if err := json.Unmarshal(encoded, &diagnostics); err != nil {
+		return params
+	}
+	// Diagnostics published while handling a change are about the new
+	// content, which is not classified yet
+	h.classify(string(diagnostics.URI))
+	if !h.Suppressed(string(diagnostics.URI)) {
+		return params
+	}
+	diagnostics.Diagnostics = []protocol.Diagnostic{}
+	return diagnostics
+}
+
+// classify updates the kind of a document from its content.
+func (h *Handler) classify(uri string) {
+	document, ok := h.Documents.Get(uri)
+	if !ok {
+		return
+	}
+	kind := core.ClassifyContent(document.GetContent())
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.kinds == nil {
+		h.kinds = make(map[string]core.ContentKind)
+	}
+	h.kinds[uripkg.Normalize(uri)] = kind
+}
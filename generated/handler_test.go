@@ -0,0 +1,85 @@
+package generated
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/protocol"
+)
+
+// documentHandler keeps documents opened with full text and publishes a
+// diagnostic for them, and answers other requests with "handled".
+type documentHandler struct {
+	documents *core.DocumentManager
+}
+
+func (h *documentHandler) Handle(context *lsp.Context) (any, bool, bool, error) {
+	switch context.Method {
+	case protocol.MethodTextDocumentDidOpen:
+		var params protocol.DidOpenTextDocumentParams
+		json.Unmarshal(context.Params, &params)
+		h.documents.Open(string(params.TextDocument.URI), params.TextDocument.Text, int(params.TextDocument.Version))
+		context.Notify(protocol.ServerTextDocumentPublishDiagnostics, protocol.PublishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []protocol.Diagnostic{{Message: "unused variable"}},
+		})
+		return nil, true, true, nil
+	}
+	return "handled", true, true, nil
+}
+
+func TestHandler(t *testing.T) {
+	documents := core.NewDocumentManager()
+	handler := NewHandler(&documentHandler{documents: documents}, documents)
+
+	published := map[string]int{}
+	notify := func(method string, params any) {
+		if method == protocol.ServerTextDocumentPublishDiagnostics {
+			diagnostics := params.(protocol.PublishDiagnosticsParams)
+			published[string(diagnostics.URI)] = len(diagnostics.Diagnostics)
+		}
+	}
+	handle := func(method, uri string, textDocument map[string]any) (any, error) {
+		textDocument["uri"] = uri
+		encoded, _ := json.Marshal(map[string]any{"textDocument": textDocument})
+		result, _, _, err := handler.Handle(&lsp.Context{Method: method, Params: encoded, Notify: notify})
+		return result, err
+	}
+
+	handle(protocol.MethodTextDocumentDidOpen, "file:///a.go", map[string]any{"text": "package a\n", "version": 1})
+	handle(protocol.MethodTextDocumentDidOpen, "file:///a_string.go", map[string]any{"text": "// Code generated by stringer. DO NOT EDIT.\n\npackage a\n", "version": 1})
+
+	if published["file:///a.go"] != 1 || published["file:///a_string.go"] != 0 {
+		t.Errorf("published diagnostics %v, want only those of a.go", published)
+	}
+	if kind := handler.Kind("file:///a_string.go"); kind != core.ContentGenerated {
+		t.Errorf("kind %s, want generated", kind)
+	}
+
+	tests := []struct {
+		method  string
+		uri     string
+		want    any
+		wantErr bool
+	}{
+		{method: protocol.MethodTextDocumentCodeAction, uri: "file:///a.go", want: "handled"},
+		{method: protocol.MethodTextDocumentCodeAction, uri: "file:///a_string.go", want: nil},
+		{method: protocol.MethodTextDocumentRename, uri: "file:///a_string.go", want: nil, wantErr: true},
+		{method: protocol.MethodTextDocumentDefinition, uri: "file:///a_string.go", want: "handled"},
+		{method: protocol.MethodTextDocumentHover, uri: "file:///a_string.go", want: "handled"},
+	}
+	for _, tt := range tests {
+		got, err := handle(tt.method, tt.uri, map[string]any{})
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("%s on %s: got %v, %v, want %v", tt.method, tt.uri, got, err, tt.want)
+		}
+	}
+
+	// The escape hatch treats every document as source
+	handler.Kinds = nil
+	if got, _ := handle(protocol.MethodTextDocumentCodeAction, "file:///a_string.go", map[string]any{}); got != "handled" {
+		t.Errorf("code action with no suppressed kinds: got %v", got)
+	}
+}
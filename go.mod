@@ -7,7 +7,9 @@ require (
 	github.com/gorilla/websocket v1.5.3
 	github.com/pkg/errors v0.9.1
 	github.com/sourcegraph/jsonrpc2 v0.2.0
+	github.com/tetratelabs/wazero v1.9.0
 	github.com/tliron/commonlog v0.2.18
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,128 @@
+// Package gobuild selects the platforms Go files are analyzed for.
+//
+// Files guarded by build constraints, like "//go:build windows" or a
+// _linux.go name suffix, parse on every platform, but only make sense on
+// the platforms they are built for: analyzed with the rest of a package on
+// another one, they redeclare names and miss others. A Platform is a GOOS,
+// GOARCH, and set of build tags that files match or not, like with the go
+// command. Providers analyze a file for the configured platforms it
+// matches, and DiagnosticProvider merges the results, telling which
+// diagnostics only happen on some platforms.
+package gobuild
+
+import (
+	"go/build"
+	"go/build/constraint"
+	"io"
+	"path"
+	"strings"
+)
+
+// Platform is a build configuration files are analyzed for. Its JSON form
+// suits the configuration of a server, like
+//
+//	{"goos": "windows", "goarch": "amd64", "tags": ["integration"]}
+type Platform struct {
+	GOOS   string   `json:"goos"`
+	GOARCH string   `json:"goarch"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+// DefaultPlatform returns the platform of the environment: the GOOS and
+// GOARCH environment variables, like for the go command, or the platform
+// the server runs on.
+func DefaultPlatform() Platform {
+	return Platform{
+		GOOS:   build.Default.GOOS,
+		GOARCH: build.Default.GOARCH,
+		Tags:   build.Default.BuildTags,
+	}
+}
+
+// String returns the platform like "linux/amd64", followed by its tags.
+func (p Platform) String() string {
+	s := p.GOOS + "/" + p.GOARCH
+	if len(p.Tags) > 0 {
+		s += " (" + strings.Join(p.Tags, ",") + ")"
+	}
+	return s
+}
+
+// MatchFile reports whether a Go file is built on the platform: whether
+// its build constraint, the OS and architecture suffixes of its name, and
+// its use of cgo are satisfied. Files whose names start with _ or . and
+// files without the .go extension never match.
+func (p Platform) MatchFile(filename, content string) bool {
+	context := build.Default
+	context.GOOS = p.GOOS
+	context.GOARCH = p.GOARCH
+	context.BuildTags = p.Tags
+	// Cgo files are analyzed, even if the server could not build them
+	context.CgoEnabled = true
+	context.OpenFile = func(string) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(content)), nil
+	}
+	dir, name := path.Split(strings.ReplaceAll(filename, `\`, "/"))
+	match, err := context.MatchFile(dir, name)
+	return err == nil && match
+}
+
+// Matching returns the platforms of platforms a file is built on.
+func Matching(platforms []Platform, filename, content string) []Platform {
+	var matching []Platform
+	for _, platform := range platforms {
+		if platform.MatchFile(filename, content) {
+			matching = append(matching, platform)
+		}
+	}
+	return matching
+}
+
+// Constraint is the build constraint line of a file.
+type Constraint struct {
+	Expr constraint.Expr
+
+	// Line is the zero-based line of the constraint.
+	Line int
+}
+
+// FileConstraint returns the build constraint of a Go file, from its
+// //go:build line, or its // +build lines in files older than Go 1.17.
+// Constraints must come before the package clause, preceded only by blank
+// lines and other line comments.
+func FileConstraint(content string) (Constraint, bool) {
+	var plusBuild []constraint.Expr
+	plusLine := -1
+	for number, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "//") {
+			break
+		}
+		if constraint.IsGoBuild(line) {
+			if expr, err := constraint.Parse(line); err == nil {
+				return Constraint{Expr: expr, Line: number}, true
+			}
+			continue
+		}
+		if constraint.IsPlusBuild(line) {
+			if expr, err := constraint.Parse(line); err == nil {
+				plusBuild = append(plusBuild, expr)
+				if plusLine < 0 {
+					plusLine = number
+				}
+			}
+		}
+	}
+	if len(plusBuild) == 0 {
+		return Constraint{}, false
+	}
+	// Multiple +build lines must all be satisfied
+	expr := plusBuild[0]
+	for _, other := range plusBuild[1:] {
+		expr = &constraint.AndExpr{X: expr, Y: other}
+	}
+	return Constraint{Expr: expr, Line: plusLine}, true
+}
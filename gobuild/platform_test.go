@@ -0,0 +1,84 @@
+package gobuild
+
+import (
+	"testing"
+)
+
+var (
+	linux   = Platform{GOOS: "linux", GOARCH: "amd64"}
+	windows = Platform{GOOS: "windows", GOARCH: "amd64"}
+	wasm    = Platform{GOOS: "js", GOARCH: "wasm", Tags: []string{"browser"}}
+)
+
+func TestMatchFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		platform Platform
+		want     bool
+	}{
+		{"no constraint", "main.go", "package main\n", windows, true},
+		{"go:build match", "main.go", "//go:build linux\n\npackage main\n", linux, true},
+		{"go:build mismatch", "main.go", "//go:build linux\n\npackage main\n", windows, false},
+		{"unix", "main.go", "//go:build unix\n\npackage main\n", linux, true},
+		{"tag", "main.go", "//go:build browser && wasm\n\npackage main\n", wasm, true},
+		{"missing tag", "main.go", "//go:build integration\n\npackage main\n", linux, false},
+		{"plus build", "main.go", "// +build windows\n\npackage main\n", windows, true},
+		{"os suffix", "file_windows.go", "package main\n", linux, false},
+		{"arch suffix", "file_linux_amd64.go", "package main\n", linux, true},
+		{"directory", "/src/app/file_windows.go", "package main\n", windows, true},
+		{"ignored name", "_file.go", "package main\n", linux, false},
+		{"cgo", "main.go", "package main\n\nimport \"C\"\n", linux, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.platform.MatchFile(tt.filename, tt.content); got != tt.want {
+				t.Errorf("%v.MatchFile(%q) = %v, want %v", tt.platform, tt.filename, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatching(t *testing.T) {
+	matching := Matching([]Platform{linux, windows, wasm}, "main.go", "//go:build !windows\n\npackage main\n")
+	if len(matching) != 2 || matching[0].GOOS != "linux" || matching[1].GOOS != "js" {
+		t.Errorf("Matching = %v", matching)
+	}
+}
+
+func TestFileConstraint(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		line    int
+		ok      bool
+	}{
+		{"go:build", "// Copyright\n\n//go:build linux && !arm\n\npackage main\n", "linux && !arm", 2, true},
+		{"plus build lines", "// +build linux darwin\n// +build amd64\n\npackage main\n", "(linux || darwin) && amd64", 0, true},
+		{"go:build wins", "// +build linux\n//go:build darwin\n\npackage main\n", "darwin", 1, true},
+		{"after package", "package main\n\n//go:build linux\n", "", 0, false},
+		{"none", "package main\n", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ok := FileConstraint(tt.content)
+			if ok != tt.ok {
+				t.Fatalf("FileConstraint ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && (c.Expr.String() != tt.want || c.Line != tt.line) {
+				t.Errorf("FileConstraint = %q on line %d, want %q on line %d", c.Expr, c.Line, tt.want, tt.line)
+			}
+		})
+	}
+}
+
+func TestPlatformString(t *testing.T) {
+	if got := linux.String(); got != "linux/amd64" {
+		t.Errorf("String = %q", got)
+	}
+	if got := wasm.String(); got != "js/wasm (browser)" {
+		t.Errorf("String with tags = %q", got)
+	}
+}
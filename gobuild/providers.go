@@ -0,0 +1,165 @@
+package gobuild
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+// PlatformDiagnosticProvider provides the diagnostics of a document
+// analyzed for a platform.
+type PlatformDiagnosticProvider interface {
+	ProvidePlatformDiagnostics(platform Platform, uri, content string) []core.Diagnostic
+}
+
+// DiagnosticProvider analyzes Go documents for each platform they are
+// built on, and merges the diagnostics. Diagnostics found on only some of
+// the platforms name them, like "x redeclared in this block (windows/amd64)".
+// A document built on none of the platforms gets an information diagnostic
+// saying so, instead of diagnostics of an analysis that does not apply.
+type DiagnosticProvider struct {
+	Provider PlatformDiagnosticProvider
+
+	// Platforms are the platforms to analyze for, like from the
+	// configuration of the server. If empty, DefaultPlatform is used.
+	Platforms []Platform
+}
+
+// NewDiagnosticProvider creates a provider analyzing for platforms, or the
+// default platform if none are given.
+func NewDiagnosticProvider(provider PlatformDiagnosticProvider, platforms ...Platform) *DiagnosticProvider {
+	return &DiagnosticProvider{Provider: provider, Platforms: platforms}
+}
+
+func (p *DiagnosticProvider) ProvideDiagnostics(uri, content string) []core.Diagnostic {
+	platforms := p.platforms()
+	matching := Matching(platforms, documentFilename(uri), content)
+	if len(matching) == 0 {
+		return []core.Diagnostic{excludedDiagnostic(content, platforms)}
+	}
+
+	// Diagnostics by range and message, in the order first found
+	type found struct {
+		diagnostic core.Diagnostic
+		platforms  []string
+	}
+	var merged []*found
+	index := make(map[string]*found)
+	for _, platform := range matching {
+		for _, diagnostic := range p.Provider.ProvidePlatformDiagnostics(platform, uri, content) {
+			key := fmt.Sprintf("%v %s", diagnostic.Range, diagnostic.Message)
+			if f, ok := index[key]; ok {
+				f.platforms = append(f.platforms, platform.String())
+				continue
+			}
+			f := &found{diagnostic: diagnostic, platforms: []string{platform.String()}}
+			index[key] = f
+			merged = append(merged, f)
+		}
+	}
+
+	diagnostics := make([]core.Diagnostic, 0, len(merged))
+	for _, f := range merged {
+		if len(f.platforms) < len(matching) {
+			f.diagnostic.Message += " (" + strings.Join(f.platforms, ", ") + ")"
+		}
+		diagnostics = append(diagnostics, f.diagnostic)
+	}
+	return diagnostics
+}
+
+func (p *DiagnosticProvider) platforms() []Platform {
+	if len(p.Platforms) == 0 {
+		return []Platform{DefaultPlatform()}
+	}
+	return p.Platforms
+}
+
+// excludedDiagnostic returns the diagnostic of a document built on none of
+// platforms, on its build constraint if it has one.
+func excludedDiagnostic(content string, platforms []Platform) core.Diagnostic {
+	var r core.Range
+	if c, ok := FileConstraint(content); ok {
+		lineStart := 0
+		for range c.Line {
+			lineStart += strings.IndexByte(content[lineStart:], '\n') + 1
+		}
+		line, _, _ := strings.Cut(content[lineStart:], "\n")
+		r = core.Range{
+			Start: core.Position{Line: c.Line},
+			End:   core.Position{Line: c.Line, Character: len(strings.TrimRight(line, "\r"))},
+		}
+	}
+	names := make([]string, len(platforms))
+	for i, platform := range platforms {
+		names[i] = platform.String()
+	}
+	severity := core.SeverityInformation
+	return core.Diagnostic{
+		Range:    r,
+		Severity: &severity,
+		Source:   "go/build",
+		Message:  "This file is not built on " + strings.Join(names, ", ") + ", so it is not analyzed",
+	}
+}
+
+// HoverProvider shows the build constraint of a Go document, and the
+// platforms the document is built on, when hovering the constraint line.
+// Elsewhere it defers to Provider, if set.
+type HoverProvider struct {
+	Provider core.HoverProvider
+
+	// Platforms are the platforms analyzed for. If empty, DefaultPlatform
+	// is used.
+	Platforms []Platform
+}
+
+func (p *HoverProvider) ProvideHover(uri, content string, position core.Position) *core.HoverInfo {
+	c, ok := FileConstraint(content)
+	if !ok || position.Line != c.Line {
+		if p.Provider == nil {
+			return nil
+		}
+		return p.Provider.ProvideHover(uri, content, position)
+	}
+
+	platforms := p.Platforms
+	if len(platforms) == 0 {
+		platforms = []Platform{DefaultPlatform()}
+	}
+	var built, notBuilt []string
+	for _, platform := range platforms {
+		if platform.MatchFile(documentFilename(uri), content) {
+			built = append(built, platform.String())
+		} else {
+			notBuilt = append(notBuilt, platform.String())
+		}
+	}
+
+	var contents strings.Builder
+	fmt.Fprintf(&contents, "**Build constraint** `%s`", c.Expr)
+	if len(built) > 0 {
+		fmt.Fprintf(&contents, "\n\nBuilt on %s", strings.Join(built, ", "))
+	}
+	if len(notBuilt) > 0 {
+		fmt.Fprintf(&contents, "\n\nNot built on %s", strings.Join(notBuilt, ", "))
+	}
+	return &core.HoverInfo{Contents: contents.String()}
+}
+
+// documentFilename returns the file name of a document, for its name
+// suffixes. Documents without a .go name, like untitled ones, get one, as
+// they are only analyzed as Go.
+func documentFilename(uri string) string {
+	name := path.Base(uri)
+	if filePath, err := uripkg.ToPath(uri); err == nil {
+		name = filePath
+	}
+	if !strings.HasSuffix(name, ".go") {
+		name += ".go"
+	}
+	return name
+}
@@ -0,0 +1,87 @@
+package gobuild
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// archProvider reports a diagnostic on every platform, and one on 32-bit
+// architectures.
+type archProvider struct {
+	analyzed []string
+}
+
+func (p *archProvider) ProvidePlatformDiagnostics(platform Platform, uri, content string) []core.Diagnostic {
+	p.analyzed = append(p.analyzed, platform.String())
+	diagnostics := []core.Diagnostic{{Message: "unused variable"}}
+	if platform.GOARCH == "386" {
+		diagnostics = append(diagnostics, core.Diagnostic{Message: "constant overflows int"})
+	}
+	return diagnostics
+}
+
+func TestDiagnosticProviderMerges(t *testing.T) {
+	provider := &archProvider{}
+	platforms := []Platform{linux, {GOOS: "linux", GOARCH: "386"}, windows}
+	diagnostics := NewDiagnosticProvider(provider, platforms...).ProvideDiagnostics("file:///src/main.go", "//go:build linux\n\npackage main\n")
+
+	if len(provider.analyzed) != 2 {
+		t.Errorf("analyzed for %v, want the two linux platforms", provider.analyzed)
+	}
+	if len(diagnostics) != 2 {
+		t.Fatalf("got %d diagnostics, want 2: %+v", len(diagnostics), diagnostics)
+	}
+	if diagnostics[0].Message != "unused variable" {
+		t.Errorf("diagnostic of every platform = %q", diagnostics[0].Message)
+	}
+	if diagnostics[1].Message != "constant overflows int (linux/386)" {
+		t.Errorf("diagnostic of one platform = %q", diagnostics[1].Message)
+	}
+}
+
+func TestDiagnosticProviderExcluded(t *testing.T) {
+	provider := &archProvider{}
+	content := "// Package main.\n//go:build windows\n\npackage main\n"
+	diagnostics := NewDiagnosticProvider(provider, linux).ProvideDiagnostics("file:///src/main.go", content)
+
+	if len(provider.analyzed) != 0 {
+		t.Errorf("analyzed an excluded file for %v", provider.analyzed)
+	}
+	if len(diagnostics) != 1 || *diagnostics[0].Severity != core.SeverityInformation || !strings.Contains(diagnostics[0].Message, "not built on linux/amd64") {
+		t.Fatalf("diagnostics = %+v", diagnostics)
+	}
+	want := core.Range{Start: core.Position{Line: 1}, End: core.Position{Line: 1, Character: len("//go:build windows")}}
+	if diagnostics[0].Range != want {
+		t.Errorf("range = %v, want the constraint line %v", diagnostics[0].Range, want)
+	}
+}
+
+type fixedHover struct{}
+
+func (fixedHover) ProvideHover(uri, content string, position core.Position) *core.HoverInfo {
+	return &core.HoverInfo{Contents: "identifier"}
+}
+
+func TestHoverProvider(t *testing.T) {
+	provider := &HoverProvider{Provider: fixedHover{}, Platforms: []Platform{linux, windows}}
+	content := "//go:build linux || darwin\n\npackage main\n"
+
+	hover := provider.ProvideHover("file:///src/main.go", content, core.Position{Line: 0, Character: 4})
+	if hover == nil {
+		t.Fatal("no hover on the constraint")
+	}
+	for _, want := range []string{"`linux || darwin`", "Built on linux/amd64", "Not built on windows/amd64"} {
+		if !strings.Contains(hover.Contents, want) {
+			t.Errorf("hover %q does not contain %q", hover.Contents, want)
+		}
+	}
+
+	if hover := provider.ProvideHover("file:///src/main.go", content, core.Position{Line: 2}); hover == nil || hover.Contents != "identifier" {
+		t.Errorf("hover elsewhere = %+v, want the provider's", hover)
+	}
+	if hover := (&HoverProvider{}).ProvideHover("file:///src/main.go", "package main\n", core.Position{}); hover != nil {
+		t.Errorf("hover without constraint or provider = %+v", hover)
+	}
+}
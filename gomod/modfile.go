@@ -0,0 +1,139 @@
+package gomod
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ModFile is the content of a go.mod or go.work file that matters for
+// resolving imports.
+type ModFile struct {
+	// Module is the module path, from the module directive of go.mod.
+	Module string
+
+	// Go is the Go version of the go directive.
+	Go string
+
+	Require []Requirement
+	Replace []Replacement
+
+	// Use are the module directories of the use directives of go.work,
+	// relative to the directory of the file.
+	Use []string
+}
+
+// Requirement is a require directive.
+type Requirement struct {
+	Path    string
+	Version string
+}
+
+// Replacement is a replace directive. OldVersion is empty when every
+// version of Old is replaced, and NewVersion is empty when New is a local
+// directory.
+type Replacement struct {
+	Old        string
+	OldVersion string
+	New        string
+	NewVersion string
+}
+
+// IsLocal reports whether the module is replaced by a local directory.
+func (r Replacement) IsLocal() bool {
+	return r.NewVersion == ""
+}
+
+// ParseModFile parses a go.mod or go.work file. Directives that do not
+// matter for resolving imports, like exclude and retract, are skipped.
+func ParseModFile(data []byte) (*ModFile, error) {
+	file := &ModFile{}
+	block := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for number := 1; scanner.Scan(); number++ {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		verb := block
+		switch {
+		case block != "" && fields[0] == ")":
+			block = ""
+			continue
+		case block == "" && len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+			continue
+		case block == "":
+			verb, fields = fields[0], fields[1:]
+		}
+		for i, field := range fields {
+			if unquoted, err := strconv.Unquote(field); err == nil {
+				fields[i] = unquoted
+			}
+		}
+
+		if err := file.add(verb, fields); err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+	}
+	return file, scanner.Err()
+}
+
+// add adds a directive.
+func (f *ModFile) add(verb string, fields []string) error {
+	switch verb {
+	case "module":
+		if len(fields) != 1 {
+			return fmt.Errorf("usage: module module/path")
+		}
+		f.Module = fields[0]
+	case "go":
+		if len(fields) != 1 {
+			return fmt.Errorf("usage: go 1.23")
+		}
+		f.Go = fields[0]
+	case "require":
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: require module/path v1.2.3")
+		}
+		f.Require = append(f.Require, Requirement{Path: fields[0], Version: fields[1]})
+	case "replace":
+		arrow := -1
+		for i, field := range fields {
+			if field == "=>" {
+				arrow = i
+			}
+		}
+		old, replacement := fields[:max(arrow, 0)], fields[arrow+1:]
+		if arrow < 0 || len(old) < 1 || len(old) > 2 || len(replacement) < 1 || len(replacement) > 2 {
+			return fmt.Errorf("usage: replace module/path [v1.2.3] => other/module v1.4.5 or ../local/directory")
+		}
+		r := Replacement{Old: old[0], New: replacement[0]}
+		if len(old) == 2 {
+			r.OldVersion = old[1]
+		}
+		if len(replacement) == 2 {
+			r.NewVersion = replacement[1]
+		} else if !isLocalPath(r.New) {
+			return fmt.Errorf("replacement module %s has no version", r.New)
+		}
+		f.Replace = append(f.Replace, r)
+	case "use":
+		if len(fields) != 1 {
+			return fmt.Errorf("usage: use ./module/directory")
+		}
+		f.Use = append(f.Use, fields[0])
+	}
+	return nil
+}
+
+// isLocalPath reports whether the target of a replacement is a directory,
+// which the go command requires to start with ./, ../, or be absolute.
+func isLocalPath(path string) bool {
+	return strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") || strings.HasPrefix(path, "/") ||
+		strings.HasPrefix(path, `.\`) || strings.HasPrefix(path, `..\`) || (len(path) > 1 && path[1] == ':')
+}
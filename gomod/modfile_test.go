@@ -0,0 +1,81 @@
+package gomod
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseModFile(t *testing.T) {
+	content := `module github.com/me/project // the module
+
+go 1.22
+
+require github.com/pkg/errors v0.9.1
+
+require (
+	github.com/gorilla/websocket v1.5.3
+	golang.org/x/sys v0.23.0 // indirect
+)
+
+exclude github.com/pkg/errors v0.8.0
+
+replace github.com/gorilla/websocket => ../websocket
+
+replace (
+	golang.org/x/sys v0.23.0 => golang.org/x/sys v0.24.0
+)
+`
+	file, err := ParseModFile([]byte(content))
+	if err != nil {
+		t.Fatalf("ParseModFile: %v", err)
+	}
+	want := &ModFile{
+		Module: "github.com/me/project",
+		Go:     "1.22",
+		Require: []Requirement{
+			{Path: "github.com/pkg/errors", Version: "v0.9.1"},
+			{Path: "github.com/gorilla/websocket", Version: "v1.5.3"},
+			{Path: "golang.org/x/sys", Version: "v0.23.0"},
+		},
+		Replace: []Replacement{
+			{Old: "github.com/gorilla/websocket", New: "../websocket"},
+			{Old: "golang.org/x/sys", OldVersion: "v0.23.0", New: "golang.org/x/sys", NewVersion: "v0.24.0"},
+		},
+	}
+	if !reflect.DeepEqual(file, want) {
+		t.Errorf("ParseModFile =\n%+v\nwant\n%+v", file, want)
+	}
+	if !file.Replace[0].IsLocal() || file.Replace[1].IsLocal() {
+		t.Errorf("IsLocal of %+v is wrong", file.Replace)
+	}
+}
+
+func TestParseWorkFile(t *testing.T) {
+	content := "go 1.22\n\nuse (\n\t./core\n\t\"./tools\"\n)\n\nuse ./cmd\n"
+	file, err := ParseModFile([]byte(content))
+	if err != nil {
+		t.Fatalf("ParseModFile: %v", err)
+	}
+	if want := []string{"./core", "./tools", "./cmd"}; !reflect.DeepEqual(file.Use, want) {
+		t.Errorf("Use = %v, want %v", file.Use, want)
+	}
+}
+
+func TestParseModFileErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"module a b\n", "line 1: usage: module"},
+		{"module a\nrequire b\n", "line 2: usage: require"},
+		{"replace a b\n", "line 1: usage: replace"},
+		{"replace a => b\n", "line 1: replacement module b has no version"},
+	}
+	for _, tt := range tests {
+		_, err := ParseModFile([]byte(tt.content))
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("ParseModFile(%q) error = %v, want %q", tt.content, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+// Package gomod resolves Go import paths to the directories of their
+// packages, from the go.mod and go.work files of a workspace.
+//
+// Providers that follow imports, like document links, go to definition,
+// and import completion, need to know where a package lives: in a module
+// of the workspace, in a directory a replace directive points to, in the
+// module cache, or in the standard library. A Resolver reads the module
+// files once and answers for all of them:
+//
+//	resolver := gomod.NewResolver(workspaceRoot)
+//	dir, version, err := resolver.ResolveImport("github.com/pkg/errors")
+//
+// In a go.work workspace, several modules are developed together. Each is
+// a root to index (Roots), has its own configuration (ModuleSettings), and
+// imports of one module resolve to the directories of the others.
+package gomod
+
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ErrNotFound is returned for imports whose directory does not exist, like
+// packages of modules not downloaded into the module cache.
+var ErrNotFound = errors.New("package not found")
+
+// ErrNoModule is returned by Modules when no go.mod or go.work file is
+// found.
+var ErrNoModule = errors.New("no go.mod or go.work file found")
+
+// Module is a module of the workspace or one it requires.
+type Module struct {
+	Path string
+
+	// Version is the required version, or "" for the main modules, the
+	// modules of the workspace, and modules replaced by local directories.
+	Version string
+
+	// Dir is the directory of the module.
+	Dir string
+
+	// Main reports whether this is a module of the workspace.
+	Main bool
+}
+
+// Resolver resolves import paths for the workspace containing a directory.
+// It reads the module files on first use; call Invalidate when they change.
+// It is safe for concurrent use.
+type Resolver struct {
+	// Dir is a directory of the workspace. The resolver uses the go.work
+	// file in it or its parents, or else the nearest go.mod file, like the
+	// go command.
+	Dir string
+
+	// GOROOT is the Go installation whose standard library resolves.
+	GOROOT string
+
+	// ModCache is the module cache, GOMODCACHE of the go command.
+	ModCache string
+
+	mu      sync.Mutex
+	loaded  bool
+	modules []Module // sorted by descending path length
+	err     error
+}
+
+// NewResolver creates a resolver for the workspace containing dir, with
+// the GOROOT and module cache of the environment.
+func NewResolver(dir string) *Resolver {
+	return &Resolver{
+		Dir:      dir,
+		GOROOT:   build.Default.GOROOT,
+		ModCache: defaultModCache(),
+	}
+}
+
+// Invalidate makes the resolver read the module files again on next use.
+func (r *Resolver) Invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loaded = false
+	r.modules = nil
+	r.err = nil
+}
+
+// Modules returns the main modules and the modules they require, with
+// replacements applied, sorted by path.
+func (r *Resolver) Modules() ([]Module, error) {
+	modules, err := r.load()
+	sorted := append([]Module(nil), modules...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+	return sorted, err
+}
+
+// ResolveImport returns the directory of the package with an import path
+// and the version of its module, "" for the main modules and the standard
+// library. If the module is known but the directory does not exist, like
+// when the module is not downloaded, the version is returned with an error
+// wrapping ErrNotFound.
+func (r *Resolver) ResolveImport(importPath string) (dir, version string, err error) {
+	modules, loadErr := r.load()
+	for _, module := range modules {
+		rest, ok := strings.CutPrefix(importPath, module.Path)
+		if !ok || (rest != "" && rest[0] != '/') {
+			continue
+		}
+		dir = filepath.Join(module.Dir, filepath.FromSlash(rest))
+		if !isDir(dir) {
+			return "", module.Version, fmt.Errorf("%s: %w in %s", importPath, ErrNotFound, dir)
+		}
+		return dir, module.Version, nil
+	}
+
+	first, _, _ := strings.Cut(importPath, "/")
+	if !strings.Contains(first, ".") && r.GOROOT != "" {
+		dir = filepath.Join(r.GOROOT, "src", filepath.FromSlash(importPath))
+		if isDir(dir) {
+			return dir, "", nil
+		}
+	}
+	if loadErr != nil {
+		return "", "", fmt.Errorf("%s: %w", importPath, loadErr)
+	}
+	return "", "", fmt.Errorf("%s: %w: no module provides it", importPath, ErrNotFound)
+}
+
+// load reads the module files, if not done yet.
+func (r *Resolver) load() ([]Module, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.loaded {
+		r.modules, r.err = r.read()
+		sort.SliceStable(r.modules, func(i, j int) bool { return len(r.modules[i].Path) > len(r.modules[j].Path) })
+		r.loaded = true
+	}
+	return r.modules, r.err
+}
+
+// read reads the module files of the workspace.
+func (r *Resolver) read() ([]Module, error) {
+	var mainDirs []string
+	var workspaceReplace []Replacement
+	workDir := ""
+	if path, ok := findUp(r.Dir, "go.work"); ok {
+		work, err := readModFile(path)
+		if err != nil {
+			return nil, err
+		}
+		workDir = filepath.Dir(path)
+		for _, use := range work.Use {
+			mainDirs = append(mainDirs, resolveDir(workDir, use))
+		}
+		workspaceReplace = work.Replace
+	} else if path, ok := findUp(r.Dir, "go.mod"); ok {
+		mainDirs = []string{filepath.Dir(path)}
+	} else {
+		return nil, ErrNoModule
+	}
+
+	var modules []Module
+	required := make(map[string]string)
+	type replacement struct {
+		Replacement
+		dir string // of the file with the directive
+	}
+	var replacements []replacement
+	for _, dir := range mainDirs {
+		mod, err := readModFile(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, Module{Path: mod.Module, Dir: dir, Main: true})
+		for _, requirement := range mod.Require {
+			// The build uses the highest required version
+			if version, ok := required[requirement.Path]; !ok || CompareVersions(requirement.Version, version) > 0 {
+				required[requirement.Path] = requirement.Version
+			}
+		}
+		for _, replace := range mod.Replace {
+			replacements = append(replacements, replacement{replace, dir})
+		}
+	}
+	// Replacements of go.work override those of the modules
+	for _, replace := range workspaceReplace {
+		replacements = append(replacements, replacement{replace, workDir})
+	}
+
+	for path, version := range required {
+		if isMain(modules, path) {
+			continue
+		}
+		module := Module{Path: path, Version: version, Dir: r.cacheDir(path, version)}
+		for _, replace := range replacements {
+			if replace.Old != path || (replace.OldVersion != "" && replace.OldVersion != version) {
+				continue
+			}
+			if replace.IsLocal() {
+				module.Version = ""
+				module.Dir = resolveDir(replace.dir, replace.New)
+			} else {
+				module.Version = replace.NewVersion
+				module.Dir = r.cacheDir(replace.New, replace.NewVersion)
+			}
+		}
+		modules = append(modules, module)
+	}
+	return modules, nil
+}
+
+// cacheDir returns the directory of a module version in the module cache.
+func (r *Resolver) cacheDir(path, version string) string {
+	return filepath.Join(r.ModCache, filepath.FromSlash(escapePath(path)+"@"+escapePath(version)))
+}
+
+// escapePath escapes a module path or version for the module cache, which
+// must work on case-insensitive file systems: upper-case letters become an
+// exclamation mark followed by the lower-case letter.
+func escapePath(path string) string {
+	var escaped strings.Builder
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			escaped.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+// CompareVersions compares two semantic versions like v1.2.3-pre, returning
+// -1, 0, or 1.
+func CompareVersions(a, b string) int {
+	splitVersion := func(v string) ([]int, string) {
+		v, _, _ = strings.Cut(strings.TrimPrefix(v, "v"), "+")
+		core, pre, _ := strings.Cut(v, "-")
+		var numbers []int
+		for _, part := range strings.Split(core, ".") {
+			n, _ := strconv.Atoi(part)
+			numbers = append(numbers, n)
+		}
+		return numbers, pre
+	}
+	aNumbers, aPre := splitVersion(a)
+	bNumbers, bPre := splitVersion(b)
+	for i := 0; i < max(len(aNumbers), len(bNumbers)); i++ {
+		var x, y int
+		if i < len(aNumbers) {
+			x = aNumbers[i]
+		}
+		if i < len(bNumbers) {
+			y = bNumbers[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	// A release is greater than its pre-releases
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return strings.Compare(aPre, bPre)
+}
+
+// isMain reports whether path is the path of a main module.
+func isMain(modules []Module, path string) bool {
+	for _, module := range modules {
+		if module.Main && module.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// readModFile reads and parses a go.mod or go.work file.
+func readModFile(path string) (*ModFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := ParseModFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+// findUp returns the path of the file name in dir or its nearest parent
+// containing one.
+func findUp(dir, name string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// resolveDir resolves a directory of a directive relative to the
+// directory of its file.
+func resolveDir(base, dir string) string {
+	dir = filepath.FromSlash(dir)
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir)
+	}
+	return filepath.Join(base, dir)
+}
+
+// isDir reports whether path is an existing directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// defaultModCache returns the module cache of the go command: GOMODCACHE,
+// or pkg/mod in the first GOPATH entry.
+func defaultModCache() string {
+	if cache := os.Getenv("GOMODCACHE"); cache != "" {
+		return cache
+	}
+	gopath := filepath.SplitList(build.Default.GOPATH)
+	if len(gopath) == 0 {
+		return ""
+	}
+	return filepath.Join(gopath[0], "pkg", "mod")
+}
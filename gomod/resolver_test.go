@@ -0,0 +1,181 @@
+package gomod
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files, and the directories of paths ending in a
+// slash, under dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newTestResolver(t *testing.T, files map[string]string) (*Resolver, string) {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, files)
+	return &Resolver{
+		Dir:      filepath.Join(root, "work", "app"),
+		GOROOT:   filepath.Join(root, "goroot"),
+		ModCache: filepath.Join(root, "modcache"),
+	}, root
+}
+
+func TestResolveImportModule(t *testing.T) {
+	resolver, root := newTestResolver(t, map[string]string{
+		"work/app/go.mod": `module github.com/me/app
+
+require (
+	github.com/Azure/sdk v1.2.0
+	github.com/me/local v0.0.0
+	github.com/pkg/errors v0.9.1
+	golang.org/x/sys v0.23.0
+)
+
+replace github.com/me/local => ../local
+
+replace golang.org/x/sys v0.23.0 => golang.org/x/sys v0.24.0
+`,
+		"work/app/internal/util/":                 "",
+		"work/local/go.mod":                       "module github.com/me/local\n",
+		"work/local/sub/":                         "",
+		"modcache/github.com/!azure/sdk@v1.2.0/":  "",
+		"modcache/github.com/pkg/errors@v0.9.1/":  "",
+		"modcache/golang.org/x/sys@v0.24.0/unix/": "",
+		"goroot/src/net/http/":                    "",
+	})
+
+	tests := []struct {
+		importPath string
+		dir        string
+		version    string
+	}{
+		{"github.com/me/app/internal/util", "work/app/internal/util", ""},
+		{"github.com/me/app", "work/app", ""},
+		{"github.com/me/local/sub", "work/local/sub", ""},
+		{"github.com/Azure/sdk", "modcache/github.com/!azure/sdk@v1.2.0", "v1.2.0"},
+		{"github.com/pkg/errors", "modcache/github.com/pkg/errors@v0.9.1", "v0.9.1"},
+		{"golang.org/x/sys/unix", "modcache/golang.org/x/sys@v0.24.0/unix", "v0.24.0"},
+		{"net/http", "goroot/src/net/http", ""},
+	}
+	for _, tt := range tests {
+		dir, version, err := resolver.ResolveImport(tt.importPath)
+		if err != nil {
+			t.Errorf("ResolveImport(%q): %v", tt.importPath, err)
+			continue
+		}
+		if want := filepath.Join(root, filepath.FromSlash(tt.dir)); dir != want || version != tt.version {
+			t.Errorf("ResolveImport(%q) = %q, %q, want %q, %q", tt.importPath, dir, version, want, tt.version)
+		}
+	}
+
+	if _, version, err := resolver.ResolveImport("github.com/pkg/errors/missing"); !errors.Is(err, ErrNotFound) || version != "v0.9.1" {
+		t.Errorf("ResolveImport of a missing package = %q, %v, want the version and ErrNotFound", version, err)
+	}
+	if _, _, err := resolver.ResolveImport("github.com/unknown/module"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ResolveImport of an unknown module: %v, want ErrNotFound", err)
+	}
+	if _, _, err := resolver.ResolveImport("github.com/me/application"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ResolveImport of a path sharing a prefix with a module: %v, want ErrNotFound", err)
+	}
+}
+
+func TestResolveImportWorkspace(t *testing.T) {
+	resolver, root := newTestResolver(t, map[string]string{
+		"work/go.work":        "go 1.22\n\nuse (\n\t./app\n\t./lib\n)\n\nreplace github.com/pkg/errors => ./errors\n",
+		"work/app/go.mod":     "module example.com/app\n\nrequire github.com/pkg/errors v0.8.0\n",
+		"work/lib/go.mod":     "module example.com/lib\n\nrequire github.com/pkg/errors v0.9.1\n",
+		"work/lib/parse/":     "",
+		"work/errors/go.mod":  "module github.com/pkg/errors\n",
+		"goroot/src/strings/": "",
+	})
+
+	dir, version, err := resolver.ResolveImport("example.com/lib/parse")
+	if err != nil || dir != filepath.Join(root, "work", "lib", "parse") || version != "" {
+		t.Errorf("ResolveImport of a workspace module = %q, %q, %v", dir, version, err)
+	}
+	dir, version, err = resolver.ResolveImport("github.com/pkg/errors")
+	if err != nil || dir != filepath.Join(root, "work", "errors") || version != "" {
+		t.Errorf("ResolveImport of a module replaced in go.work = %q, %q, %v", dir, version, err)
+	}
+
+	modules, err := resolver.Modules()
+	if err != nil {
+		t.Fatalf("Modules: %v", err)
+	}
+	var paths []string
+	for _, module := range modules {
+		paths = append(paths, module.Path)
+	}
+	if len(paths) != 3 || paths[0] != "example.com/app" || paths[1] != "example.com/lib" || paths[2] != "github.com/pkg/errors" {
+		t.Errorf("Modules = %v", paths)
+	}
+}
+
+func TestResolverInvalidate(t *testing.T) {
+	resolver, root := newTestResolver(t, map[string]string{
+		"work/app/go.mod": "module example.com/app\n",
+	})
+	if _, _, err := resolver.ResolveImport("example.com/app"); err != nil {
+		t.Fatalf("ResolveImport: %v", err)
+	}
+
+	writeFiles(t, root, map[string]string{"work/app/go.mod": "module example.com/renamed\n"})
+	if _, _, err := resolver.ResolveImport("example.com/renamed"); err == nil {
+		t.Error("ResolveImport used go.mod changed before Invalidate")
+	}
+	resolver.Invalidate()
+	if _, _, err := resolver.ResolveImport("example.com/renamed"); err != nil {
+		t.Errorf("ResolveImport after Invalidate: %v", err)
+	}
+}
+
+func TestResolverNoModule(t *testing.T) {
+	resolver, _ := newTestResolver(t, map[string]string{
+		"work/app/":           "",
+		"goroot/src/strings/": "",
+	})
+	if _, err := resolver.Modules(); !errors.Is(err, ErrNoModule) {
+		t.Errorf("Modules without go.mod: %v, want ErrNoModule", err)
+	}
+	if dir, _, err := resolver.ResolveImport("strings"); err != nil || filepath.Base(dir) != "strings" {
+		t.Errorf("ResolveImport of the standard library without go.mod = %q, %v", dir, err)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"v1.10.0", "v1.9.0", 1},
+		{"v0.9.1", "v1.0.0", -1},
+		{"v1.0.0-rc.1", "v1.0.0", -1},
+		{"v0.0.0-20240101000000-abcdef", "v0.0.0-20230101000000-abcdef", 1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package gomod
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnknownModule is returned by a VersionResolver that knows no versions
+// of a module.
+var ErrUnknownModule = errors.New("no versions known for module")
+
+// VersionResolver finds the latest version of modules, for providers
+// offering to update requirements. Implementations may ask a module proxy;
+// the ones in this package never access the network.
+type VersionResolver interface {
+	// LatestVersion returns the latest version of the module path, or
+	// ErrUnknownModule.
+	LatestVersion(path string) (string, error)
+}
+
+// ModCacheVersions resolves versions from the module cache: the latest
+// version is the latest the go command has downloaded, so it is known
+// offline but may lag behind the versions published.
+type ModCacheVersions struct {
+	// ModCache is the module cache, GOMODCACHE of the go command.
+	ModCache string
+}
+
+// NewModCacheVersions creates a resolver for the module cache of the
+// environment.
+func NewModCacheVersions() *ModCacheVersions {
+	return &ModCacheVersions{ModCache: defaultModCache()}
+}
+
+// LatestVersion returns the latest release of a module in the download
+// cache, or its latest pre-release if it has no releases.
+func (v *ModCacheVersions) LatestVersion(path string) (string, error) {
+	dir := filepath.Join(v.ModCache, "cache", "download", filepath.FromSlash(escapePath(path)), "@v")
+	data, err := os.ReadFile(filepath.Join(dir, "list"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrUnknownModule
+	} else if err != nil {
+		return "", err
+	}
+	latest := LatestVersion(strings.Fields(string(data)))
+	if latest == "" {
+		return "", ErrUnknownModule
+	}
+	return latest, nil
+}
+
+// StaticVersions resolves the latest versions of modules from a map, by
+// module path. It is useful in tests and for versions fetched ahead of
+// time.
+type StaticVersions map[string]string
+
+func (v StaticVersions) LatestVersion(path string) (string, error) {
+	if version, ok := v[path]; ok {
+		return version, nil
+	}
+	return "", ErrUnknownModule
+}
+
+// LatestVersion returns the latest release of versions, or the latest
+// pre-release if none is a release, like the go command picks for
+// "go get module@latest". It returns "" for no versions.
+func LatestVersion(versions []string) string {
+	latest, latestPre := "", ""
+	for _, version := range versions {
+		if strings.Contains(version, "-") {
+			if latestPre == "" || CompareVersions(version, latestPre) > 0 {
+				latestPre = version
+			}
+		} else if latest == "" || CompareVersions(version, latest) > 0 {
+			latest = version
+		}
+	}
+	if latest == "" {
+		return latestPre
+	}
+	return latest
+}
@@ -0,0 +1,31 @@
+package gomod
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestModCacheVersions(t *testing.T) {
+	cache := t.TempDir()
+	writeFiles(t, cache, map[string]string{
+		"cache/download/github.com/!burnt!sushi/toml/@v/list": "v1.2.0\nv1.10.0\nv1.4.0\nv2.0.0-beta.1\n",
+		"cache/download/example.com/pre/@v/list":              "v0.1.0-rc.1\nv0.1.0-rc.2\n",
+		"cache/download/example.com/empty/@v/list":            "",
+	})
+	versions := &ModCacheVersions{ModCache: cache}
+
+	tests := map[string]string{
+		"github.com/BurntSushi/toml": "v1.10.0",
+		"example.com/pre":            "v0.1.0-rc.2",
+	}
+	for path, want := range tests {
+		if got, err := versions.LatestVersion(path); err != nil || got != want {
+			t.Errorf("LatestVersion(%s) = %q, %v, want %q", path, got, err, want)
+		}
+	}
+	for _, path := range []string{"example.com/empty", "example.com/missing"} {
+		if _, err := versions.LatestVersion(path); !errors.Is(err, ErrUnknownModule) {
+			t.Errorf("LatestVersion(%s) error = %v, want ErrUnknownModule", path, err)
+		}
+	}
+}
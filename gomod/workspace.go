@@ -0,0 +1,147 @@
+package gomod
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/SCKelemen/lsp/protocol"
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+// MainModules returns the modules of the workspace, sorted by path: the
+// modules used by go.work, or the module of the nearest go.mod.
+func (r *Resolver) MainModules() []Module {
+	modules, _ := r.Modules()
+	var main []Module
+	for _, module := range modules {
+		if module.Main {
+			main = append(main, module)
+		}
+	}
+	return main
+}
+
+// Roots returns the directories to index for the workspace: the
+// directories of the main modules, without those inside another one, as
+// walking the outer directory covers them. With go.work, modules may be
+// outside the directory of the workspace.
+func (r *Resolver) Roots() []string {
+	var dirs []string
+	for _, module := range r.MainModules() {
+		dirs = append(dirs, module.Dir)
+	}
+	sort.Strings(dirs)
+
+	var roots []string
+	for _, dir := range dirs {
+		if len(roots) > 0 && within(roots[len(roots)-1], dir) {
+			continue
+		}
+		roots = append(roots, dir)
+	}
+	return roots
+}
+
+// ModuleOf returns the main module containing a file or directory.
+func (r *Resolver) ModuleOf(path string) (Module, bool) {
+	path = filepath.Clean(path)
+	var found Module
+	ok := false
+	for _, module := range r.MainModules() {
+		// The innermost module, like the go command
+		if within(module.Dir, path) && (!ok || len(module.Dir) > len(found.Dir)) {
+			found, ok = module, true
+		}
+	}
+	return found, ok
+}
+
+// ImportPath returns the import path of the package in a directory of a
+// main module.
+func (r *Resolver) ImportPath(dir string) (string, bool) {
+	module, ok := r.ModuleOf(dir)
+	if !ok {
+		return "", false
+	}
+	rel, err := filepath.Rel(module.Dir, filepath.Clean(dir))
+	if err != nil {
+		return "", false
+	}
+	if rel == "." {
+		return module.Path, true
+	}
+	return module.Path + "/" + filepath.ToSlash(rel), true
+}
+
+// ConfigurationItems returns the items of a workspace/configuration request
+// asking for section in the scope of each main module, in the order of
+// MainModules, so each module can be configured on its own, like with
+// folder settings. Pass the results to ModuleSettings.SetAll.
+func (r *Resolver) ConfigurationItems(section string) []protocol.ConfigurationItem {
+	var items []protocol.ConfigurationItem
+	for _, module := range r.MainModules() {
+		scope := protocol.DocumentUri(uripkg.FromPath(module.Dir))
+		items = append(items, protocol.ConfigurationItem{ScopeURI: &scope, Section: &section})
+	}
+	return items
+}
+
+// ModuleSettings holds settings of type T for each main module of a
+// workspace, and routes documents to the settings of their module.
+// It is safe for concurrent use.
+type ModuleSettings[T any] struct {
+	Resolver *Resolver
+
+	// Default is used for documents outside of the main modules, and for
+	// modules without settings.
+	Default T
+
+	mu       sync.Mutex
+	settings map[string]T // by module directory
+}
+
+// Set sets the settings of the main module in a directory.
+func (s *ModuleSettings[T]) Set(moduleDir string, settings T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.settings == nil {
+		s.settings = make(map[string]T)
+	}
+	s.settings[filepath.Clean(moduleDir)] = settings
+}
+
+// SetAll sets the settings of the main modules, in the order of
+// MainModules, like the results of a request made with ConfigurationItems.
+func (s *ModuleSettings[T]) SetAll(settings []T) {
+	for i, module := range s.Resolver.MainModules() {
+		if i < len(settings) {
+			s.Set(module.Dir, settings[i])
+		}
+	}
+}
+
+// For returns the settings of the module of the document uri.
+func (s *ModuleSettings[T]) For(uri string) T {
+	path, err := uripkg.ToPath(uri)
+	if err != nil {
+		return s.Default
+	}
+	module, ok := s.Resolver.ModuleOf(path)
+	if !ok {
+		return s.Default
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if settings, ok := s.settings[module.Dir]; ok {
+		return settings
+	}
+	return s.Default
+}
+
+// within reports whether path is dir or inside it.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
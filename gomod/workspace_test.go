@@ -0,0 +1,91 @@
+package gomod
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+func newWorkspaceResolver(t *testing.T) (*Resolver, string) {
+	t.Helper()
+	resolver, root := newTestResolver(t, map[string]string{
+		"work/go.work":           "go 1.22\n\nuse (\n\t./app\n\t./app/tools\n\t../shared\n)\n",
+		"work/app/go.mod":        "module example.com/app\n",
+		"work/app/tools/go.mod":  "module example.com/tools\n",
+		"work/app/tools/lint/":   "",
+		"shared/go.mod":          "module example.com/shared\n",
+		"shared/internal/parse/": "",
+	})
+	return resolver, root
+}
+
+func TestWorkspaceModules(t *testing.T) {
+	resolver, root := newWorkspaceResolver(t)
+
+	var paths []string
+	for _, module := range resolver.MainModules() {
+		paths = append(paths, module.Path)
+	}
+	if want := []string{"example.com/app", "example.com/shared", "example.com/tools"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("MainModules = %v, want %v", paths, want)
+	}
+
+	// The nested module is walked with its parent
+	wantRoots := []string{filepath.Join(root, "shared"), filepath.Join(root, "work", "app")}
+	if roots := resolver.Roots(); !reflect.DeepEqual(roots, wantRoots) {
+		t.Errorf("Roots = %v, want %v", roots, wantRoots)
+	}
+}
+
+func TestWorkspaceImportPath(t *testing.T) {
+	resolver, root := newWorkspaceResolver(t)
+
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"work/app", "example.com/app"},
+		{"work/app/cmd", "example.com/app/cmd"},
+		{"work/app/tools/lint", "example.com/tools/lint"},
+		{"shared/internal/parse", "example.com/shared/internal/parse"},
+	}
+	for _, tt := range tests {
+		got, ok := resolver.ImportPath(filepath.Join(root, filepath.FromSlash(tt.dir)))
+		if !ok || got != tt.want {
+			t.Errorf("ImportPath(%q) = %q, %v, want %q", tt.dir, got, ok, tt.want)
+		}
+	}
+	if got, ok := resolver.ImportPath(filepath.Join(root, "work")); ok {
+		t.Errorf("ImportPath outside of the modules = %q", got)
+	}
+}
+
+func TestModuleSettings(t *testing.T) {
+	resolver, root := newWorkspaceResolver(t)
+
+	items := resolver.ConfigurationItems("go")
+	if len(items) != 3 || *items[0].Section != "go" || *items[0].ScopeURI != uripkg.FromPath(filepath.Join(root, "work", "app")) {
+		t.Fatalf("ConfigurationItems = %+v", items)
+	}
+
+	settings := &ModuleSettings[string]{Resolver: resolver, Default: "default"}
+	settings.SetAll([]string{"app", "shared", "tools"})
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"work/app/main.go", "app"},
+		{"work/app/tools/lint/lint.go", "tools"},
+		{"shared/internal/parse/parse.go", "shared"},
+		{"work/README.md", "default"},
+	}
+	for _, tt := range tests {
+		uri := uripkg.FromPath(filepath.Join(root, filepath.FromSlash(tt.file)))
+		if got := settings.For(uri); got != tt.want {
+			t.Errorf("For(%q) = %q, want %q", tt.file, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+// Package imports edits the import declarations of Go files.
+//
+// Completion adding the import of a package it completes, code actions
+// removing unused imports, and organizing imports on save all edit the
+// same declarations, and should agree on where an import goes and what
+// removing one leaves behind. A File answers each of them with text edits:
+//
+//	file, err := imports.Parse(content)
+//	if err != nil {
+//		return nil
+//	}
+//	edits := file.Add("strings", "")
+//	edits = file.Group(imports.GoimportsRules("github.com/me/project"))
+package imports
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// Import is an import of a file.
+type Import struct {
+	// Path is the import path.
+	Path string
+
+	// Alias is the name given to the import, "_" or "." for blank and dot
+	// imports, or "" if the import has none.
+	Alias string
+}
+
+// Name returns the name the file refers to the package by, assuming the
+// package name is the last element of the path.
+func (i Import) Name() string {
+	if i.Alias != "" {
+		return i.Alias
+	}
+	return defaultName(i.Path)
+}
+
+// File is the import declarations of a Go file. It only reads the syntax
+// tree, so trees shared with other features, like a parse cache, can be
+// used.
+type File struct {
+	content string
+	fset    *token.FileSet
+	file    *ast.File
+}
+
+// Parse parses the package clause and the import declarations of a Go
+// file. Errors after them do not matter.
+func Parse(content string) (*File, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	return NewFile(content, fset, file), nil
+}
+
+// NewFile wraps a parsed Go file. The tree must have been parsed from
+// content, with its comments.
+func NewFile(content string, fset *token.FileSet, file *ast.File) *File {
+	return &File{content: content, fset: fset, file: file}
+}
+
+// Imports returns the imports of the file, in their order.
+func (f *File) Imports() []Import {
+	imports := make([]Import, 0, len(f.file.Imports))
+	for _, spec := range f.file.Imports {
+		imports = append(imports, specImport(spec))
+	}
+	return imports
+}
+
+// Name returns the name the file refers to the import of path by, or "" if
+// the file does not import it.
+func (f *File) Name(path string) string {
+	for _, spec := range f.file.Imports {
+		if i := specImport(spec); i.Path == path {
+			return i.Name()
+		}
+	}
+	return ""
+}
+
+// Path returns the path of the import the file refers to by name, or "".
+func (f *File) Path(name string) string {
+	for _, spec := range f.file.Imports {
+		if i := specImport(spec); i.Name() == name {
+			return i.Path
+		}
+	}
+	return ""
+}
+
+// Add returns the edits importing path as alias, "" for no alias, or nil if
+// the file already imports it by that name. The import joins the first
+// parenthesized import declaration, sorted among its specs, or gets a
+// declaration of its own.
+func (f *File) Add(path, alias string) []core.TextEdit {
+	want := Import{Path: path, Alias: alias}
+	for _, spec := range f.file.Imports {
+		if i := specImport(spec); i.Path == path && i.Name() == want.Name() {
+			return nil
+		}
+	}
+	text := strconv.Quote(path)
+	if alias != "" {
+		text = alias + " " + text
+	}
+	insertAt := func(offset int, text string) []core.TextEdit {
+		pos := core.ByteOffsetToPosition(f.content, offset)
+		return []core.TextEdit{{Range: core.Range{Start: pos, End: pos}, NewText: text}}
+	}
+
+	var last *ast.GenDecl
+	for _, gen := range f.importDecls() {
+		if !gen.Lparen.IsValid() {
+			last = gen
+			continue
+		}
+
+		// Sort into the group, before the first spec with a greater path
+		for _, spec := range gen.Specs {
+			importSpec := spec.(*ast.ImportSpec)
+			if specImport(importSpec).Path > path {
+				offset := f.offset(importSpec.Pos())
+				return insertAt(offset, text+"\n"+f.lineIndent(offset))
+			}
+		}
+		return insertAt(f.offset(gen.Rparen), "\t"+text+"\n")
+	}
+
+	// After the comments ending the line, which stay with their line
+	if last != nil {
+		return insertAt(f.lineCommentEnd(last.End()), "\nimport "+text)
+	}
+	return insertAt(f.lineCommentEnd(f.file.Name.End()), "\n\nimport "+text)
+}
+
+// Remove returns the edits deleting the import of path, with its comments,
+// or nil if the file does not import it. A declaration left without
+// imports is deleted.
+func (f *File) Remove(path string) []core.TextEdit {
+	for _, gen := range f.importDecls() {
+		for _, spec := range gen.Specs {
+			importSpec := spec.(*ast.ImportSpec)
+			if specImport(importSpec).Path != path {
+				continue
+			}
+
+			// Delete the whole declaration if this is its only spec,
+			// otherwise the lines of the spec
+			start, end := f.offset(gen.Pos()), f.lineCommentEnd(gen.End())
+			if gen.Doc != nil {
+				start = f.offset(gen.Doc.Pos())
+			}
+			if len(gen.Specs) > 1 {
+				start, end = f.offset(importSpec.Pos()), f.offset(importSpec.End())
+				if importSpec.Doc != nil {
+					start = f.offset(importSpec.Doc.Pos())
+				}
+				if importSpec.Comment != nil {
+					end = f.offset(importSpec.Comment.End())
+				}
+			}
+			if lineStart := strings.LastIndexByte(f.content[:start], '\n') + 1; strings.TrimSpace(f.content[lineStart:start]) == "" {
+				start = lineStart
+			}
+			if end < len(f.content) && f.content[end] == '\n' {
+				end++
+			}
+			return []core.TextEdit{{
+				Range: core.Range{
+					Start: core.ByteOffsetToPosition(f.content, start),
+					End:   core.ByteOffsetToPosition(f.content, end),
+				},
+			}}
+		}
+	}
+	return nil
+}
+
+// Rule matches the import paths of a group of imports.
+type Rule func(path string) bool
+
+// Standard matches the packages of the standard library, whose paths do
+// not start with a domain.
+func Standard(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// Prefix returns a rule matching the packages of the given modules, or
+// other path prefixes.
+func Prefix(prefixes ...string) Rule {
+	return func(path string) bool {
+		for _, prefix := range prefixes {
+			prefix = strings.TrimSuffix(prefix, "/")
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// GoimportsRules returns the rules grouping imports as goimports does: the
+// standard library first, then other packages, then the packages of the
+// local modules, if any.
+func GoimportsRules(local ...string) []Rule {
+	if len(local) == 0 {
+		return []Rule{Standard}
+	}
+	isLocal := Prefix(local...)
+	return []Rule{Standard, func(path string) bool { return !isLocal(path) }}
+}
+
+// Group returns the edits merging the import declarations into one, with
+// an import group for each rule, in order, separated by blank lines. An
+// import goes into the group of the first rule it matches, and imports
+// matching none go into a last group. Imports are sorted by path within
+// groups, duplicates are dropped, and comments are kept. It returns nil if
+// the imports are already grouped.
+//
+// The cgo import of "C" and the declarations before it are not touched, as
+// the comment of the cgo import is C code.
+func (f *File) Group(rules []Rule) []core.TextEdit {
+	decls := f.importDecls()
+	for i, gen := range decls {
+		for _, spec := range gen.Specs {
+			if specImport(spec.(*ast.ImportSpec)).Path == "C" {
+				decls = decls[i+1:]
+				break
+			}
+		}
+	}
+	if len(decls) == 0 {
+		return nil
+	}
+	start, end := f.offset(decls[0].Pos()), f.offset(decls[len(decls)-1].End())
+
+	type entry struct {
+		Import
+		group   int
+		doc     []string
+		comment string
+	}
+	var entries []entry
+	attached := make(map[*ast.CommentGroup]bool)
+	seen := make(map[Import]bool)
+	for _, gen := range decls {
+		for _, spec := range gen.Specs {
+			importSpec := spec.(*ast.ImportSpec)
+			attached[importSpec.Doc] = true
+			attached[importSpec.Comment] = true
+			e := entry{Import: specImport(importSpec), group: len(rules)}
+			if seen[e.Import] {
+				continue
+			}
+			seen[e.Import] = true
+			for i, rule := range rules {
+				if rule(e.Path) {
+					e.group = i
+					break
+				}
+			}
+			if importSpec.Doc != nil {
+				e.doc = f.commentLines(importSpec.Doc)
+			}
+			if importSpec.Comment != nil {
+				e.comment = strings.Join(f.commentLines(importSpec.Comment), " ")
+			}
+			entries = append(entries, e)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].group != entries[j].group {
+			return entries[i].group < entries[j].group
+		}
+		if entries[i].Path != entries[j].Path {
+			return entries[i].Path < entries[j].Path
+		}
+		return entries[i].Alias < entries[j].Alias
+	})
+
+	// Comments between the specs that belong to none of them, like the
+	// comments of the declarations merged into the first one
+	var free []string
+	for _, group := range f.file.Comments {
+		if offset := f.offset(group.Pos()); offset > start && offset < end && !attached[group] {
+			free = append(free, f.commentLines(group)...)
+		}
+	}
+
+	spec := func(e entry) string {
+		text := strconv.Quote(e.Path)
+		if e.Alias != "" {
+			text = e.Alias + " " + text
+		}
+		if e.comment != "" {
+			text += " " + e.comment
+		}
+		return text
+	}
+	var text strings.Builder
+	if len(entries) == 1 && len(free) == 0 && entries[0].doc == nil {
+		text.WriteString("import " + spec(entries[0]))
+	} else {
+		text.WriteString("import (\n")
+		for _, line := range free {
+			text.WriteString("\t" + line + "\n")
+		}
+		for i, e := range entries {
+			if i > 0 && e.group != entries[i-1].group {
+				text.WriteString("\n")
+			}
+			for _, line := range e.doc {
+				text.WriteString("\t" + line + "\n")
+			}
+			text.WriteString("\t" + spec(e) + "\n")
+		}
+		text.WriteString(")")
+	}
+	return core.DiffEdits(f.content, f.content[:start]+text.String()+f.content[end:])
+}
+
+// importDecls returns the import declarations of the file.
+func (f *File) importDecls() []*ast.GenDecl {
+	var decls []*ast.GenDecl
+	for _, decl := range f.file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			decls = append(decls, gen)
+		}
+	}
+	return decls
+}
+
+// offset returns the byte offset of pos in the content.
+func (f *File) offset(pos token.Pos) int {
+	return f.fset.Position(pos).Offset
+}
+
+// lineCommentEnd returns the offset of the end of the comments following
+// pos on its line, or of pos if there are none.
+func (f *File) lineCommentEnd(pos token.Pos) int {
+	end := f.offset(pos)
+	line := f.fset.Position(pos).Line
+	for _, group := range f.file.Comments {
+		if group.Pos() >= pos && f.fset.Position(group.Pos()).Line == line {
+			end = f.offset(group.End())
+		}
+	}
+	return end
+}
+
+// lineIndent returns the indentation of the line containing offset.
+func (f *File) lineIndent(offset int) string {
+	start := strings.LastIndexByte(f.content[:offset], '\n') + 1
+	line := f.content[start:offset]
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// commentLines returns the lines of a comment group, without indentation.
+func (f *File) commentLines(group *ast.CommentGroup) []string {
+	var lines []string
+	for _, comment := range group.List {
+		for _, line := range strings.Split(comment.Text, "\n") {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	return lines
+}
+
+// specImport returns the import of a spec.
+func specImport(spec *ast.ImportSpec) Import {
+	path, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		path = strings.Trim(spec.Path.Value, "\"`")
+	}
+	var alias string
+	if spec.Name != nil {
+		alias = spec.Name.Name
+	}
+	return Import{Path: path, Alias: alias}
+}
+
+// defaultName returns the name of the package of an import path.
+func defaultName(path string) string {
+	return path[strings.LastIndexByte(path, '/')+1:]
+}
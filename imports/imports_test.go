@@ -0,0 +1,307 @@
+package imports
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// apply applies non-overlapping edits to content.
+func apply(t *testing.T, content string, edits []core.TextEdit) string {
+	t.Helper()
+	sorted := append([]core.TextEdit(nil), edits...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return core.ComparePositions(sorted[i].Range.Start, sorted[j].Range.Start) > 0
+	})
+	for _, edit := range sorted {
+		start := core.PositionToByteOffset(content, edit.Range.Start)
+		end := core.PositionToByteOffset(content, edit.Range.End)
+		content = content[:start] + edit.NewText + content[end:]
+	}
+	return content
+}
+
+func parse(t *testing.T, content string) *File {
+	t.Helper()
+	file, err := Parse(content)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return file
+}
+
+func TestParseIgnoresErrorsAfterImports(t *testing.T) {
+	file := parse(t, "package main\n\nimport f \"fmt\"\n\nfunc main() {\n")
+	imports := file.Imports()
+	if len(imports) != 1 || imports[0] != (Import{Path: "fmt", Alias: "f"}) {
+		t.Errorf("Imports() = %v", imports)
+	}
+	if _, err := Parse("not go"); err == nil {
+		t.Error("Parse of a file without a package clause succeeded")
+	}
+}
+
+func TestNameAndPath(t *testing.T) {
+	file := parse(t, "package main\n\nimport (\n\t\"fmt\"\n\tyaml \"gopkg.in/yaml.v3\"\n\t\"net/http\"\n)\n")
+
+	names := map[string]string{"fmt": "fmt", "gopkg.in/yaml.v3": "yaml", "net/http": "http", "os": ""}
+	for path, want := range names {
+		if got := file.Name(path); got != want {
+			t.Errorf("Name(%q) = %q, want %q", path, got, want)
+		}
+	}
+	paths := map[string]string{"fmt": "fmt", "yaml": "gopkg.in/yaml.v3", "http": "net/http", "os": ""}
+	for name, want := range paths {
+		if got := file.Path(name); got != want {
+			t.Errorf("Path(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		path    string
+		alias   string
+		want    string
+	}{
+		{
+			name:    "sorted into a group",
+			content: "package main\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n",
+			path:    "os",
+			want:    "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n\t\"strings\"\n)\n",
+		},
+		{
+			name:    "end of a group",
+			content: "package main\n\nimport (\n\t\"fmt\"\n)\n",
+			path:    "strings",
+			want:    "package main\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n",
+		},
+		{
+			name:    "alias",
+			content: "package main\n\nimport (\n\t\"fmt\"\n)\n",
+			path:    "gopkg.in/yaml.v3",
+			alias:   "yaml",
+			want:    "package main\n\nimport (\n\t\"fmt\"\n\tyaml \"gopkg.in/yaml.v3\"\n)\n",
+		},
+		{
+			name:    "after a single import",
+			content: "package main\n\nimport \"fmt\"\n",
+			path:    "os",
+			want:    "package main\n\nimport \"fmt\"\nimport \"os\"\n",
+		},
+		{
+			name:    "after a single import with a comment",
+			content: "package main\n\nimport \"fmt\" // keep\n",
+			path:    "os",
+			want:    "package main\n\nimport \"fmt\" // keep\nimport \"os\"\n",
+		},
+		{
+			name:    "after a single import with a block comment",
+			content: "package main\n\nimport \"fmt\" /* keep */\n",
+			path:    "os",
+			want:    "package main\n\nimport \"fmt\" /* keep */\nimport \"os\"\n",
+		},
+		{
+			name:    "after a package clause with a comment",
+			content: "package main // import \"example.com/main\"\n",
+			path:    "os",
+			want:    "package main // import \"example.com/main\"\n\nimport \"os\"\n",
+		},
+		{
+			name:    "no imports",
+			content: "package main\n\nfunc main() {}\n",
+			path:    "os",
+			want:    "package main\n\nimport \"os\"\n\nfunc main() {}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := apply(t, tt.content, parse(t, tt.content).Add(tt.path, tt.alias)); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddImported(t *testing.T) {
+	file := parse(t, "package main\n\nimport (\n\t\"fmt\"\n\t_ \"embed\"\n)\n")
+	if edits := file.Add("fmt", ""); edits != nil {
+		t.Errorf("Add of an import = %v, want nil", edits)
+	}
+	if edits := file.Add("embed", "_"); edits != nil {
+		t.Errorf("Add of a blank import = %v, want nil", edits)
+	}
+	if edits := file.Add("embed", ""); edits == nil {
+		t.Error("Add of a blank imported package by name = nil")
+	}
+}
+
+func TestRemove(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		path    string
+		want    string
+	}{
+		{
+			name:    "one of a group",
+			content: "package main\n\nimport (\n\t\"fmt\"\n\t// Strings.\n\t\"strings\" // unused\n)\n",
+			path:    "strings",
+			want:    "package main\n\nimport (\n\t\"fmt\"\n)\n",
+		},
+		{
+			name:    "only import",
+			content: "package main\n\nimport \"fmt\"\n\nfunc main() {}\n",
+			path:    "fmt",
+			want:    "package main\n\n\nfunc main() {}\n",
+		},
+		{
+			name:    "only import with a comment",
+			content: "package main\n\nimport \"fmt\" // unused\n\nfunc main() {}\n",
+			path:    "fmt",
+			want:    "package main\n\n\nfunc main() {}\n",
+		},
+		{
+			name:    "single import among others with comments",
+			content: "package main\n\n// Formatting.\nimport \"fmt\" // unused\nimport \"os\" // used\n",
+			path:    "fmt",
+			want:    "package main\n\nimport \"os\" // used\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := apply(t, tt.content, parse(t, tt.content).Remove(tt.path)); got != tt.want {
+				t.Errorf("got\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+
+	if edits := parse(t, "package main\n").Remove("fmt"); edits != nil {
+		t.Errorf("Remove of a missing import = %v, want nil", edits)
+	}
+}
+
+func TestRules(t *testing.T) {
+	for path, want := range map[string]bool{"fmt": true, "net/http": true, "github.com/a/b": false, "example.com": false} {
+		if got := Standard(path); got != want {
+			t.Errorf("Standard(%q) = %v, want %v", path, got, want)
+		}
+	}
+	local := Prefix("github.com/me/project/")
+	for path, want := range map[string]bool{"github.com/me/project": true, "github.com/me/project/core": true, "github.com/me/projector": false} {
+		if got := local(path); got != want {
+			t.Errorf("Prefix(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestGroup(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		rules   []Rule
+		want    string
+	}{
+		{
+			name: "goimports groups",
+			content: `package main
+
+import (
+	"github.com/me/project/core"
+	"os"
+	"github.com/pkg/errors"
+	"fmt"
+)
+
+func main() {}
+`,
+			rules: GoimportsRules("github.com/me/project"),
+			want: `package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/me/project/core"
+)
+
+func main() {}
+`,
+		},
+		{
+			name: "merged declarations with comments",
+			content: `package main
+
+import "os"
+
+// Errors.
+import errs "github.com/pkg/errors" // wrapping
+
+import (
+	// Printing.
+	"fmt"
+	"os"
+)
+`,
+			rules: GoimportsRules(),
+			want: `package main
+
+import (
+	// Errors.
+	// Printing.
+	"fmt"
+	"os"
+
+	errs "github.com/pkg/errors" // wrapping
+)
+`,
+		},
+		{
+			name:    "single import",
+			content: "package main\n\nimport (\n\t\"fmt\"\n)\n",
+			rules:   GoimportsRules(),
+			want:    "package main\n\nimport \"fmt\"\n",
+		},
+		{
+			name: "cgo",
+			content: `package main
+
+// #include <stdio.h>
+import "C"
+
+import "os"
+import "fmt"
+`,
+			rules: GoimportsRules(),
+			want: `package main
+
+// #include <stdio.h>
+import "C"
+
+import (
+	"fmt"
+	"os"
+)
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := apply(t, tt.content, parse(t, tt.content).Group(tt.rules)); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupGrouped(t *testing.T) {
+	content := "package main\n\nimport (\n\t\"fmt\"\n\n\t\"github.com/pkg/errors\"\n)\n"
+	if edits := parse(t, content).Group(GoimportsRules()); edits != nil {
+		t.Errorf("Group of grouped imports = %v, want nil", edits)
+	}
+}
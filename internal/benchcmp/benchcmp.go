@@ -0,0 +1,154 @@
+// Package benchcmp compares the output of two `go test -bench` runs.
+//
+// It is used to check that refactors of hot paths (position conversion,
+// parsing, word breaking, symbol indexing) do not regress, e.g.:
+//
+//	go test ./core/... -run '^$' -bench . -benchmem -count 5 > old.txt
+//	# make changes
+//	go test ./core/... -run '^$' -bench . -benchmem -count 5 > new.txt
+//	go run ./cmd/benchcmp old.txt new.txt
+package benchcmp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Result is the averaged measurement of one benchmark.
+type Result struct {
+	// Name is the benchmark name without the GOMAXPROCS suffix.
+	Name string
+
+	// Runs is the number of result lines averaged (the -count value).
+	Runs int
+
+	NsPerOp     float64
+	BytesPerOp  float64
+	AllocsPerOp float64
+}
+
+// Parse reads `go test -bench` output and returns the results by name.
+// Repeated runs of a benchmark (from -count) are averaged. Lines that are not
+// benchmark results are ignored.
+func Parse(r io.Reader) (map[string]*Result, error) {
+	results := make(map[string]*Result)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		// The second field is the iteration count
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+
+		name := trimProcs(fields[0])
+		result, ok := results[name]
+		if !ok {
+			result = &Result{Name: name}
+			results[name] = result
+		}
+		result.Runs++
+
+		// Measurements come in value/unit pairs
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("benchmark %s: invalid value %q", name, fields[i])
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				result.NsPerOp += value
+			case "B/op":
+				result.BytesPerOp += value
+			case "allocs/op":
+				result.AllocsPerOp += value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		runs := float64(result.Runs)
+		result.NsPerOp /= runs
+		result.BytesPerOp /= runs
+		result.AllocsPerOp /= runs
+	}
+
+	return results, nil
+}
+
+// trimProcs removes the -N GOMAXPROCS suffix from a benchmark name.
+func trimProcs(name string) string {
+	i := strings.LastIndexByte(name, '-')
+	if i < 0 {
+		return name
+	}
+	if _, err := strconv.Atoi(name[i+1:]); err != nil {
+		return name
+	}
+	return name[:i]
+}
+
+// Comparison is the change of one benchmark between two runs.
+// Deltas are relative: 0.1 means 10% more than before, -0.5 means half.
+type Comparison struct {
+	Name string
+	Old  Result
+	New  Result
+
+	NsDelta     float64
+	BytesDelta  float64
+	AllocsDelta float64
+}
+
+// Compare pairs up benchmarks present in both runs, sorted by name.
+func Compare(old, current map[string]*Result) []Comparison {
+	var comparisons []Comparison
+	for name, before := range old {
+		after, ok := current[name]
+		if !ok {
+			continue
+		}
+		comparisons = append(comparisons, Comparison{
+			Name:        name,
+			Old:         *before,
+			New:         *after,
+			NsDelta:     delta(before.NsPerOp, after.NsPerOp),
+			BytesDelta:  delta(before.BytesPerOp, after.BytesPerOp),
+			AllocsDelta: delta(before.AllocsPerOp, after.AllocsPerOp),
+		})
+	}
+
+	sort.Slice(comparisons, func(i, j int) bool {
+		return comparisons[i].Name < comparisons[j].Name
+	})
+	return comparisons
+}
+
+// Regressed reports whether time, bytes, or allocations per operation grew by
+// more than threshold (a fraction, e.g. 0.1 for 10%).
+func (c Comparison) Regressed(threshold float64) bool {
+	return c.NsDelta > threshold || c.BytesDelta > threshold || c.AllocsDelta > threshold
+}
+
+// delta returns the relative change from old to new.
+// Going from zero to a non-zero value is an infinite increase.
+func delta(before, after float64) float64 {
+	if before == 0 {
+		if after == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (after - before) / before
+}
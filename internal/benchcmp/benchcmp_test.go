@@ -0,0 +1,95 @@
+package benchcmp
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+const oldOutput = `goos: linux
+goarch: amd64
+pkg: github.com/SCKelemen/lsp/core
+BenchmarkPositionToByteOffset-8   	    1000	   1200000 ns/op	       0 B/op	       0 allocs/op
+BenchmarkPositionToByteOffset-8   	    1000	   1000000 ns/op	       0 B/op	       0 allocs/op
+BenchmarkDocumentApplyEdit-8      	     500	   2000000 ns/op	  800000 B/op	       4 allocs/op
+BenchmarkHover/cached-8           	  100000	      1000 ns/op	     528 B/op	      17 allocs/op
+PASS
+ok  	github.com/SCKelemen/lsp/core	3.2s
+`
+
+const newOutput = `BenchmarkPositionToByteOffset-8   	  100000	       100 ns/op	       0 B/op	       0 allocs/op
+BenchmarkDocumentApplyEdit-8      	     500	   2000000 ns/op	  800000 B/op	       6 allocs/op
+BenchmarkUTF8ToUTF16Offset-8      	  100000	       150 ns/op	       0 B/op	       0 allocs/op
+`
+
+func TestParse(t *testing.T) {
+	results, err := Parse(strings.NewReader(oldOutput))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+
+	pos := results["BenchmarkPositionToByteOffset"]
+	if pos == nil {
+		t.Fatal("expected the GOMAXPROCS suffix to be trimmed")
+	}
+	if pos.Runs != 2 || pos.NsPerOp != 1100000 {
+		t.Errorf("got runs=%d ns/op=%v, want 2 runs averaging 1100000", pos.Runs, pos.NsPerOp)
+	}
+
+	hover := results["BenchmarkHover/cached"]
+	if hover == nil {
+		t.Fatal("expected sub-benchmark result")
+	}
+	if hover.BytesPerOp != 528 || hover.AllocsPerOp != 17 {
+		t.Errorf("got %v B/op %v allocs/op, want 528 and 17", hover.BytesPerOp, hover.AllocsPerOp)
+	}
+}
+
+func TestParse_InvalidValue(t *testing.T) {
+	_, err := Parse(strings.NewReader("BenchmarkX-8 100 abc ns/op\n"))
+	if err == nil {
+		t.Error("expected an error for a malformed measurement")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	old, _ := Parse(strings.NewReader(oldOutput))
+	current, _ := Parse(strings.NewReader(newOutput))
+
+	comparisons := Compare(old, current)
+	if len(comparisons) != 2 {
+		t.Fatalf("got %d comparisons, want 2 (only benchmarks in both runs)", len(comparisons))
+	}
+
+	// Sorted by name
+	edit, pos := comparisons[0], comparisons[1]
+	if edit.Name != "BenchmarkDocumentApplyEdit" || pos.Name != "BenchmarkPositionToByteOffset" {
+		t.Fatalf("unexpected order: %s, %s", edit.Name, pos.Name)
+	}
+
+	if pos.Regressed(0.1) {
+		t.Error("a 10000x speedup is not a regression")
+	}
+	if got := edit.AllocsDelta; math.Abs(got-0.5) > 1e-9 {
+		t.Errorf("got allocs delta %v, want 0.5", got)
+	}
+	if !edit.Regressed(0.1) {
+		t.Error("expected 50% more allocations to be a regression at 10%")
+	}
+	if edit.Regressed(0.6) {
+		t.Error("expected 50% more allocations to pass at 60%")
+	}
+}
+
+func TestDelta_FromZero(t *testing.T) {
+	if d := delta(0, 0); d != 0 {
+		t.Errorf("delta(0, 0) = %v, want 0", d)
+	}
+	if d := delta(0, 3); !math.IsInf(d, 1) {
+		t.Errorf("delta(0, 3) = %v, want +Inf", d)
+	}
+}
@@ -0,0 +1,18 @@
+// Package textdocument decodes the text document that the params of most
+// requests and notifications refer to.
+package textdocument
+
+import (
+	"encoding/json"
+)
+
+// URI returns the URI of the text document of params, if any.
+func URI(params json.RawMessage) string {
+	var document struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+	}
+	json.Unmarshal(params, &document)
+	return document.TextDocument.URI
+}
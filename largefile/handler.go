@@ -0,0 +1,144 @@
+// Package largefile degrades the features of a server gracefully for
+// documents too large to analyze quickly, like generated code or logs.
+//
+// A Handler middleware checks documents against a core.LargeFilePolicy as
+// they are opened and changed. Requests of expensive methods, like semantic
+// tokens, about large documents go to a lightweight fallback handler, or
+// are answered with no result, and the user is told once per document with
+// a window message.
+package largefile
+
+import (
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/internal/textdocument"
+	"github.com/SCKelemen/lsp/protocol"
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+// DefaultExpensiveMethods are the methods Handler keeps from the handler
+// for large documents.
+var DefaultExpensiveMethods = map[string]bool{
+	protocol.MethodTextDocumentSemanticTokensFull:      true,
+	protocol.MethodTextDocumentSemanticTokensFullDelta: true,
+	protocol.MethodTextDocumentSemanticTokensRange:     true,
+	protocol.MethodTextDocumentInlayHint:               true,
+	protocol.MethodTextDocumentCodeLens:                true,
+	protocol.MethodTextDocumentDocumentSymbol:          true,
+	protocol.MethodTextDocumentFoldingRange:            true,
+	protocol.MethodTextDocumentDocumentHighlight:       true,
+}
+
+// Handler is a handler middleware applying a large file policy.
+type Handler struct {
+	// Handler handles the messages, and the requests of inexpensive
+	// methods about large documents.
+	Handler lsp.Handler
+
+	// Fallback, when set, handles the requests of expensive methods about
+	// large documents, e.g. with text-based providers. Requests it does not
+	// support get no result.
+	Fallback lsp.Handler
+
+	// Documents holds the content of the open documents, kept up to date
+	// by Handler.
+	Documents *core.DocumentManager
+
+	Policy core.LargeFilePolicy
+
+	// Expensive are the methods kept from Handler for large documents.
+	Expensive map[string]bool
+
+	mu    sync.Mutex
+	large map[string]bool // by normalized document URI
+}
+
+// NewHandler creates a middleware applying core.DefaultLargeFilePolicy to
+// DefaultExpensiveMethods, for documents whose content handler keeps in
+// documents.
+func NewHandler(handler lsp.Handler, documents *core.DocumentManager) *Handler {
+	return &Handler{
+		Handler:   handler,
+		Documents: documents,
+		Policy:    core.DefaultLargeFilePolicy,
+		Expensive: DefaultExpensiveMethods,
+	}
+}
+
+// IsLarge reports whether the open document uri is large, e.g. for
+// analysis to skip it.
+func (h *Handler) IsLarge(uri string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.large[uripkg.Normalize(uri)]
+}
+
+// ([lsp.Handler] interface)
+func (h *Handler) Handle(context *lsp.Context) (any, bool, bool, error) {
+	if h.Expensive[context.Method] {
+		if uri := textdocument.URI(context.Params); uri != "" && h.IsLarge(uri) {
+			return h.fallback(context)
+		}
+	}
+
+	result, validMethod, validParams, err := h.Handler.Handle(context)
+
+	switch context.Method {
+	case protocol.MethodTextDocumentDidOpen, protocol.MethodTextDocumentDidChange:
+		if uri := textdocument.URI(context.Params); uri != "" {
+			h.check(context, uri)
+		}
+	case protocol.MethodTextDocumentDidClose:
+		if uri := textdocument.URI(context.Params); uri != "" {
+			h.mu.Lock()
+			delete(h.large, uripkg.Normalize(uri))
+			h.mu.Unlock()
+		}
+	}
+
+	return result, validMethod, validParams, err
+}
+
+// fallback handles a request of an expensive method about a large document.
+func (h *Handler) fallback(context *lsp.Context) (any, bool, bool, error) {
+	if h.Fallback != nil {
+		if result, validMethod, validParams, err := h.Fallback.Handle(context); validMethod {
+			return result, validMethod, validParams, err
+		}
+	}
+	return nil, true, true, nil
+}
+
+// check applies the policy to the content of a document, and tells the
+// user when it becomes large.
+func (h *Handler) check(context *lsp.Context, uri string) {
+	document, ok := h.Documents.Get(uri)
+	if !ok {
+		return
+	}
+	large, reason := h.Policy.Check(document.GetContent())
+
+	key := uripkg.Normalize(uri)
+	h.mu.Lock()
+	wasLarge := h.large[key]
+	if large {
+		if h.large == nil {
+			h.large = make(map[string]bool)
+		}
+		h.large[key] = true
+	} else {
+		delete(h.large, key)
+	}
+	h.mu.Unlock()
+
+	if large && !wasLarge && context.Notify != nil {
+		context.Notify(protocol.ServerWindowShowMessage, protocol.ShowMessageParams{
+			Type:    protocol.MessageTypeWarning,
+			Message: fmt.Sprintf("%s is large (%s): semantic highlighting and analysis are turned off for it", path.Base(uri), reason),
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package largefile
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/protocol"
+)
+
+// documentHandler keeps documents opened with full text, and answers other
+// requests with the name of the handler.
+type documentHandler struct {
+	name      string
+	documents *core.DocumentManager
+}
+
+func (h *documentHandler) Handle(context *lsp.Context) (any, bool, bool, error) {
+	switch context.Method {
+	case protocol.MethodTextDocumentDidOpen:
+		var params protocol.DidOpenTextDocumentParams
+		json.Unmarshal(context.Params, &params)
+		h.documents.Open(string(params.TextDocument.URI), params.TextDocument.Text, int(params.TextDocument.Version))
+		return nil, true, true, nil
+	case protocol.MethodTextDocumentDidClose:
+		return nil, true, true, nil
+	case protocol.MethodTextDocumentFoldingRange:
+		if h.name == "fallback" {
+			return nil, false, false, nil
+		}
+	}
+	return h.name, true, true, nil
+}
+
+func TestHandler(t *testing.T) {
+	documents := core.NewDocumentManager()
+	handler := NewHandler(&documentHandler{name: "handler", documents: documents}, documents)
+	handler.Fallback = &documentHandler{name: "fallback"}
+	handler.Policy = core.LargeFilePolicy{MaxBytes: 10}
+
+	var messages []protocol.ShowMessageParams
+	notify := func(method string, params any) {
+		if method == protocol.ServerWindowShowMessage {
+			messages = append(messages, params.(protocol.ShowMessageParams))
+		}
+	}
+	handle := func(method, uri string, params map[string]any) any {
+		params["textDocument"].(map[string]any)["uri"] = uri
+		encoded, _ := json.Marshal(params)
+		result, _, _, _ := handler.Handle(&lsp.Context{Method: method, Params: encoded, Notify: notify})
+		return result
+	}
+	open := func(uri, text string) {
+		handle(protocol.MethodTextDocumentDidOpen, uri, map[string]any{"textDocument": map[string]any{"text": text, "languageId": "go", "version": 1}})
+	}
+	request := func(method, uri string) any {
+		return handle(method, uri, map[string]any{"textDocument": map[string]any{}})
+	}
+
+	open("file:///small.go", "package a")
+	open("file:///large.go", strings.Repeat("a", 20))
+
+	tests := []struct {
+		method string
+		uri    string
+		want   any
+	}{
+		{method: protocol.MethodTextDocumentSemanticTokensFull, uri: "file:///small.go", want: "handler"},
+		{method: protocol.MethodTextDocumentSemanticTokensFull, uri: "file:///large.go", want: "fallback"},
+		{method: protocol.MethodTextDocumentFoldingRange, uri: "file:///large.go", want: nil},
+		{method: protocol.MethodTextDocumentHover, uri: "file:///large.go", want: "handler"},
+	}
+	for _, tt := range tests {
+		if got := request(tt.method, tt.uri); got != tt.want {
+			t.Errorf("%s on %s: got %v, want %v", tt.method, tt.uri, got, tt.want)
+		}
+	}
+
+	if len(messages) != 1 || messages[0].Type != protocol.MessageTypeWarning || !strings.Contains(messages[0].Message, "large.go is large (20 bytes") {
+		t.Errorf("messages %+v, want one warning about large.go", messages)
+	}
+	if !handler.IsLarge("file:///large.go") || handler.IsLarge("file:///small.go") {
+		t.Error("IsLarge does not follow the policy")
+	}
+
+	request(protocol.MethodTextDocumentDidClose, "file:///large.go")
+	if handler.IsLarge("file:///large.go") {
+		t.Error("closed document still large")
+	}
+}
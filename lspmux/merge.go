@@ -0,0 +1,399 @@
+package lspmux
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/SCKelemen/lsp/protocol"
+)
+
+// methodCapabilities are the server capabilities telling which backends
+// support a request. Requests not listed go to every backend.
+var methodCapabilities = map[string]string{
+	protocol.MethodTextDocumentCompletion:              "completionProvider",
+	protocol.MethodCompletionItemResolve:               "completionProvider",
+	protocol.MethodTextDocumentHover:                   "hoverProvider",
+	protocol.MethodTextDocumentSignatureHelp:           "signatureHelpProvider",
+	protocol.MethodTextDocumentDeclaration:             "declarationProvider",
+	protocol.MethodTextDocumentDefinition:              "definitionProvider",
+	protocol.MethodTextDocumentTypeDefinition:          "typeDefinitionProvider",
+	protocol.MethodTextDocumentImplementation:          "implementationProvider",
+	protocol.MethodTextDocumentReferences:              "referencesProvider",
+	protocol.MethodTextDocumentDocumentHighlight:       "documentHighlightProvider",
+	protocol.MethodTextDocumentDocumentSymbol:          "documentSymbolProvider",
+	protocol.MethodTextDocumentCodeAction:              "codeActionProvider",
+	protocol.MethodCodeActionResolve:                   "codeActionProvider",
+	protocol.MethodTextDocumentCodeLens:                "codeLensProvider",
+	protocol.MethodCodeLensResolve:                     "codeLensProvider",
+	protocol.MethodTextDocumentDocumentLink:            "documentLinkProvider",
+	protocol.MethodDocumentLinkResolve:                 "documentLinkProvider",
+	protocol.MethodTextDocumentColor:                   "colorProvider",
+	protocol.MethodTextDocumentColorPresentation:       "colorProvider",
+	protocol.MethodTextDocumentFormatting:              "documentFormattingProvider",
+	protocol.MethodTextDocumentRangeFormatting:         "documentRangeFormattingProvider",
+	protocol.MethodTextDocumentOnTypeFormatting:        "documentOnTypeFormattingProvider",
+	protocol.MethodTextDocumentRename:                  "renameProvider",
+	protocol.MethodTextDocumentPrepareRename:           "renameProvider",
+	protocol.MethodTextDocumentFoldingRange:            "foldingRangeProvider",
+	protocol.MethodTextDocumentSelectionRange:          "selectionRangeProvider",
+	protocol.MethodTextDocumentPrepareCallHierarchy:    "callHierarchyProvider",
+	protocol.MethodTextDocumentSemanticTokensFull:      "semanticTokensProvider",
+	protocol.MethodTextDocumentSemanticTokensFullDelta: "semanticTokensProvider",
+	protocol.MethodTextDocumentSemanticTokensRange:     "semanticTokensProvider",
+	protocol.MethodTextDocumentLinkedEditingRange:      "linkedEditingRangeProvider",
+	protocol.MethodTextDocumentMoniker:                 "monikerProvider",
+	protocol.MethodTextDocumentInlayHint:               "inlayHintProvider",
+	protocol.MethodInlayHintResolve:                    "inlayHintProvider",
+	protocol.MethodWorkspaceSymbol:                     "workspaceSymbolProvider",
+	protocol.MethodWorkspaceExecuteCommand:             "executeCommandProvider",
+}
+
+// exclusiveCapabilities are taken from the first backend having them, and
+// only that backend gets their requests: semantic tokens are encoded with
+// the legend of one server, and positions with the encoding of one.
+var exclusiveCapabilities = map[string]bool{
+	"semanticTokensProvider": true,
+	"positionEncoding":       true,
+}
+
+// resolveMethods resolve an item that a backend returned; they go back to
+// that backend.
+var resolveMethods = map[string]struct{}{
+	protocol.MethodCompletionItemResolve: {},
+	protocol.MethodCodeActionResolve:     {},
+	protocol.MethodCodeLensResolve:       {},
+	protocol.MethodDocumentLinkResolve:   {},
+	protocol.MethodInlayHintResolve:      {},
+}
+
+// listMethods merge the results of several backends into one, given the
+// backend index of each result.
+var listMethods = map[string]func(values []json.RawMessage, indexes []int) (any, error){
+	protocol.MethodTextDocumentCompletion:        mergeCompletions,
+	protocol.MethodTextDocumentCodeAction:        mergeTaggedLists,
+	protocol.MethodTextDocumentCodeLens:          mergeTaggedLists,
+	protocol.MethodTextDocumentDocumentLink:      mergeTaggedLists,
+	protocol.MethodTextDocumentInlayHint:         mergeTaggedLists,
+	protocol.MethodTextDocumentDeclaration:       mergeLocations,
+	protocol.MethodTextDocumentDefinition:        mergeLocations,
+	protocol.MethodTextDocumentTypeDefinition:    mergeLocations,
+	protocol.MethodTextDocumentImplementation:    mergeLocations,
+	protocol.MethodTextDocumentReferences:        mergeLists,
+	protocol.MethodTextDocumentDocumentHighlight: mergeLists,
+	protocol.MethodTextDocumentDocumentSymbol:    mergeLists,
+	protocol.MethodTextDocumentFoldingRange:      mergeLists,
+	protocol.MethodTextDocumentColor:             mergeLists,
+	protocol.MethodTextDocumentMoniker:           mergeLists,
+	protocol.MethodWorkspaceSymbol:               mergeLists,
+}
+
+// mergeCapabilities merges the capabilities of a backend into those of the
+// backends before it. A capability is supported if any backend supports it;
+// the option lists of a capability, like trigger characters and commands,
+// are combined, while other option values of earlier backends win.
+func mergeCapabilities(merged, capabilities map[string]any) map[string]any {
+	for key, value := range capabilities {
+		existing, ok := merged[key]
+		switch {
+		case key == "textDocumentSync":
+			merged[key] = mergeTextDocumentSync(existing, value)
+		case !ok || !enabled(existing):
+			merged[key] = value
+		case exclusiveCapabilities[key]:
+		default:
+			merged[key] = mergeValues(existing, value)
+		}
+	}
+	return merged
+}
+
+func mergeValues(a, b any) any {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok {
+			// Options win over true
+			return a
+		}
+		for key, value := range b {
+			if existing, ok := a[key]; ok {
+				a[key] = mergeValues(existing, value)
+			} else {
+				a[key] = value
+			}
+		}
+		return a
+	case []any:
+		b, ok := b.([]any)
+		if !ok {
+			return a
+		}
+		for _, value := range b {
+			if !containsValue(a, value) {
+				a = append(a, value)
+			}
+		}
+		return a
+	case bool:
+		if _, ok := b.(map[string]any); ok || !a {
+			return b
+		}
+		return a
+	default:
+		return a
+	}
+}
+
+// mergeTextDocumentSync merges text document sync options so that every
+// backend gets the notifications it asked for: changes are sent in full if
+// any backend wants them in full.
+func mergeTextDocumentSync(a, b any) any {
+	if a == nil {
+		return b
+	}
+	x, y := syncOptions(a), syncOptions(b)
+	merged := map[string]any{
+		"openClose":         x["openClose"] == true || y["openClose"] == true,
+		"willSave":          x["willSave"] == true || y["willSave"] == true,
+		"willSaveWaitUntil": x["willSaveWaitUntil"] == true || y["willSaveWaitUntil"] == true,
+	}
+
+	// Numbers as decoded from JSON, to merge with the next backend
+	full := float64(protocol.TextDocumentSyncKindFull)
+	changeX, _ := x["change"].(float64)
+	changeY, _ := y["change"].(float64)
+	if changeX == full || changeY == full {
+		merged["change"] = full
+	} else {
+		merged["change"] = max(changeX, changeY)
+	}
+
+	if includeText(x["save"]) || includeText(y["save"]) {
+		merged["save"] = map[string]any{"includeText": true}
+	} else if enabled(x["save"]) || enabled(y["save"]) {
+		merged["save"] = true
+	}
+	return merged
+}
+
+// syncOptions returns text document sync options as an object.
+func syncOptions(value any) map[string]any {
+	switch value := value.(type) {
+	case map[string]any:
+		return value
+	case float64:
+		return map[string]any{"openClose": value > 0, "change": value}
+	default:
+		return map[string]any{}
+	}
+}
+
+func includeText(save any) bool {
+	options, ok := save.(map[string]any)
+	return ok && options["includeText"] == true
+}
+
+// enabled reports whether a capability value announces support.
+func enabled(value any) bool {
+	switch value := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return value
+	default:
+		return true
+	}
+}
+
+// hasCommand reports whether executeCommandProvider options list a command.
+func hasCommand(value any, command string) bool {
+	options, ok := value.(map[string]any)
+	if !ok {
+		return false
+	}
+	commands, _ := options["commands"].([]any)
+	return containsValue(commands, command)
+}
+
+func containsValue(values []any, value any) bool {
+	encoded, _ := json.Marshal(value)
+	for _, other := range values {
+		if otherEncoded, _ := json.Marshal(other); bytes.Equal(encoded, otherEncoded) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeLists concatenates results that are arrays.
+func mergeLists(values []json.RawMessage, indexes []int) (any, error) {
+	merged := []json.RawMessage{}
+	for _, value := range values {
+		var list []json.RawMessage
+		if err := json.Unmarshal(value, &list); err != nil {
+			return nil, err
+		}
+		merged = append(merged, list...)
+	}
+	return merged, nil
+}
+
+// mergeTaggedLists concatenates arrays of items that can be resolved,
+// tagging each with the backend that returned it.
+func mergeTaggedLists(values []json.RawMessage, indexes []int) (any, error) {
+	merged := []any{}
+	for i, value := range values {
+		var list []any
+		if err := json.Unmarshal(value, &list); err != nil {
+			return nil, err
+		}
+		for _, item := range list {
+			if item, ok := item.(map[string]any); ok && isResolvable(item) {
+				tag(item, indexes[i])
+			}
+			merged = append(merged, item)
+		}
+	}
+	return merged, nil
+}
+
+// isResolvable tells resolvable items from plain commands among code
+// actions, whose command is an object rather than a name.
+func isResolvable(item map[string]any) bool {
+	_, isCommand := item["command"].(string)
+	return !isCommand
+}
+
+// mergeCompletions merges completion items and lists into one list, which
+// is incomplete if any is. Item defaults belong to the list of a backend,
+// so they are applied to its items.
+func mergeCompletions(values []json.RawMessage, indexes []int) (any, error) {
+	merged := map[string]any{"isIncomplete": false}
+	items := []any{}
+	for i, value := range values {
+		var list struct {
+			IsIncomplete bool             `json:"isIncomplete"`
+			ItemDefaults map[string]any   `json:"itemDefaults"`
+			Items        []map[string]any `json:"items"`
+		}
+		if bytes.HasPrefix(bytes.TrimSpace(value), []byte("[")) {
+			if err := json.Unmarshal(value, &list.Items); err != nil {
+				return nil, err
+			}
+		} else if err := json.Unmarshal(value, &list); err != nil {
+			return nil, err
+		}
+
+		if list.IsIncomplete {
+			merged["isIncomplete"] = true
+		}
+		for _, item := range list.Items {
+			applyItemDefaults(item, list.ItemDefaults)
+			tag(item, indexes[i])
+			items = append(items, item)
+		}
+	}
+	merged["items"] = items
+	return merged, nil
+}
+
+// applyItemDefaults sets the fields of a completion item that are missing
+// from the defaults of its list.
+func applyItemDefaults(item map[string]any, defaults map[string]any) {
+	for _, key := range []string{"commitCharacters", "insertTextFormat", "insertTextMode", "data"} {
+		if value, ok := defaults[key]; ok {
+			if _, ok := item[key]; !ok {
+				item[key] = value
+			}
+		}
+	}
+
+	editRange, ok := defaults["editRange"].(map[string]any)
+	if _, hasEdit := item["textEdit"]; !ok || hasEdit {
+		return
+	}
+	newText, _ := item["textEditText"].(string)
+	if newText == "" {
+		newText, _ = item["label"].(string)
+	}
+	if _, isInsertReplace := editRange["insert"]; isInsertReplace {
+		item["textEdit"] = map[string]any{"newText": newText, "insert": editRange["insert"], "replace": editRange["replace"]}
+	} else {
+		item["textEdit"] = map[string]any{"newText": newText, "range": editRange}
+	}
+	delete(item, "textEditText")
+}
+
+// mergeLocations concatenates Location, Location[], and LocationLink[]
+// results. If any backend returns links, locations become links.
+func mergeLocations(values []json.RawMessage, indexes []int) (any, error) {
+	var merged []map[string]any
+	links := false
+	for _, value := range values {
+		var list []map[string]any
+		if bytes.HasPrefix(bytes.TrimSpace(value), []byte("[")) {
+			if err := json.Unmarshal(value, &list); err != nil {
+				return nil, err
+			}
+		} else {
+			var location map[string]any
+			if err := json.Unmarshal(value, &location); err != nil {
+				return nil, err
+			}
+			list = append(list, location)
+		}
+		for _, location := range list {
+			if _, ok := location["targetUri"]; ok {
+				links = true
+			}
+		}
+		merged = append(merged, list...)
+	}
+
+	if links {
+		for i, location := range merged {
+			if uri, ok := location["uri"]; ok {
+				merged[i] = map[string]any{
+					"targetUri":            uri,
+					"targetRange":          location["range"],
+					"targetSelectionRange": location["range"],
+				}
+			}
+		}
+	}
+	if merged == nil {
+		merged = []map[string]any{}
+	}
+	return merged, nil
+}
+
+// tagKey is the key of the data added to items to find their backend.
+const tagKey = "lspmux"
+
+// tag records the index of the backend that returned an item in its data.
+func tag(item map[string]any, index int) {
+	tagged := map[string]any{tagKey: index}
+	if data, ok := item["data"]; ok {
+		tagged["data"] = data
+	}
+	item["data"] = tagged
+}
+
+// untag restores the data of a tagged item, and returns the index of the
+// backend that returned it.
+func untag(item map[string]any) (int, bool) {
+	tagged, ok := item["data"].(map[string]any)
+	if !ok {
+		return 0, false
+	}
+	index, ok := tagged[tagKey].(float64)
+	if !ok {
+		return 0, false
+	}
+	if data, ok := tagged["data"]; ok {
+		item["data"] = data
+	} else {
+		delete(item, "data")
+	}
+	return int(index), true
+}
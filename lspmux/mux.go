@@ -0,0 +1,468 @@
+// Package lspmux composes several language servers into one.
+//
+// A Mux is an lsp.Handler: serve it with server.Server and it speaks LSP to
+// the client while forwarding every message to its backend servers, such
+// as a Go server and a spell checker. Requests go to the backends whose
+// capabilities cover them, and their results are merged: completions, code
+// actions, locations, and other lists are concatenated, while requests with
+// a single answer, like hover or formatting, get the first non-null result
+// in backend order. Diagnostics published by the backends are merged per
+// document.
+package lspmux
+
+import (
+	contextpkg "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/protocol"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/tliron/commonlog"
+)
+
+// Mux forwards the messages of a client to several language servers and
+// merges their answers.
+type Mux struct {
+	// Name is the server name reported to the client.
+	Name string
+
+	Log commonlog.Logger
+
+	backends []*backend
+
+	mu     sync.Mutex
+	client *lsp.Context // the last message from the client, to reach it
+
+	// diagnostics are the diagnostics published by each backend, by
+	// document and backend index
+	diagnostics map[string]map[int][]json.RawMessage
+}
+
+// backend is a language server behind a Mux.
+type backend struct {
+	name       string
+	connection *jsonrpc2.Conn
+	command    *exec.Cmd // nil if not started by the Mux
+
+	mu           sync.Mutex
+	capabilities map[string]any // from its initialize result
+}
+
+// NewMux creates a Mux without backends.
+func NewMux(name string) *Mux {
+	return &Mux{
+		Name:        name,
+		Log:         commonlog.GetLogger("lspmux"),
+		diagnostics: make(map[string]map[int][]json.RawMessage),
+	}
+}
+
+// AddBackend adds a language server speaking LSP over stream. Backends
+// must be added before the client initializes; those added first win
+// when results cannot be merged.
+func (self *Mux) AddBackend(name string, stream io.ReadWriteCloser) {
+	self.addBackend(name, stream, nil)
+}
+
+// StartBackend starts a language server speaking LSP over its standard
+// input and output, and adds it as a backend.
+func (self *Mux) StartBackend(name string, command *exec.Cmd) error {
+	stdin, err := command.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := command.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := command.Start(); err != nil {
+		return err
+	}
+	self.addBackend(name, &commandStream{stdout, stdin}, command)
+	return nil
+}
+
+func (self *Mux) addBackend(name string, stream io.ReadWriteCloser, command *exec.Cmd) {
+	b := &backend{name: name, command: command}
+	index := len(self.backends)
+	handler := &backendHandler{mux: self, index: index}
+	b.connection = jsonrpc2.NewConn(contextpkg.Background(), jsonrpc2.NewBufferedStream(stream, jsonrpc2.VSCodeObjectCodec{}), handler)
+	self.backends = append(self.backends, b)
+}
+
+// Close closes the connections to the backends, and waits for those it
+// started to exit.
+func (self *Mux) Close() error {
+	var errs []error
+	for _, b := range self.backends {
+		if err := b.connection.Close(); err != nil && !errors.Is(err, jsonrpc2.ErrClosed) {
+			errs = append(errs, fmt.Errorf("%s: %w", b.name, err))
+		}
+		if b.command != nil {
+			if err := b.command.Wait(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", b.name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ([lsp.Handler] interface)
+func (self *Mux) Handle(context *lsp.Context) (any, bool, bool, error) {
+	self.mu.Lock()
+	self.client = context
+	self.mu.Unlock()
+
+	if protocol.ClientNotifications[context.Method] {
+		self.broadcast(context)
+		return nil, true, true, nil
+	}
+
+	switch context.Method {
+	case protocol.MethodInitialize:
+		return self.initialize(context)
+	case protocol.MethodShutdown:
+		return self.shutdown(context)
+	}
+
+	if _, ok := resolveMethods[context.Method]; ok {
+		return self.resolve(context)
+	}
+	return self.request(context)
+}
+
+// broadcast sends a notification to every backend. Cancellations are not
+// forwarded, as the backends know requests by other ids.
+func (self *Mux) broadcast(context *lsp.Context) {
+	if context.Method == protocol.MethodCancelRequest {
+		return
+	}
+	for _, b := range self.backends {
+		if err := b.connection.Notify(contextpkg.Background(), context.Method, params(context)); err != nil {
+			self.Log.Errorf("%s: %s", b.name, err.Error())
+		}
+	}
+}
+
+// initialize initializes every backend with the client's parameters, and
+// answers with their merged capabilities.
+func (self *Mux) initialize(context *lsp.Context) (any, bool, bool, error) {
+	results := self.call(contextOf(context), self.backends, context.Method, params(context))
+
+	capabilities := map[string]any{}
+	var errs []error
+	for i, result := range results {
+		if result.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", self.backends[i].name, result.err))
+			continue
+		}
+		var initializeResult struct {
+			Capabilities map[string]any `json:"capabilities"`
+		}
+		if err := json.Unmarshal(result.value, &initializeResult); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", self.backends[i].name, err))
+			continue
+		}
+		self.backends[i].mu.Lock()
+		self.backends[i].capabilities = initializeResult.Capabilities
+		self.backends[i].mu.Unlock()
+		capabilities = mergeCapabilities(capabilities, initializeResult.Capabilities)
+	}
+	if len(errs) == len(self.backends) && len(errs) > 0 {
+		return nil, true, true, errors.Join(errs...)
+	}
+	for _, err := range errs {
+		self.Log.Errorf("could not initialize %s", err.Error())
+	}
+
+	return map[string]any{
+		"capabilities": capabilities,
+		"serverInfo":   map[string]any{"name": self.Name},
+	}, true, true, nil
+}
+
+func (self *Mux) shutdown(context *lsp.Context) (any, bool, bool, error) {
+	var errs []error
+	for i, result := range self.call(contextOf(context), self.backends, context.Method, nil) {
+		if result.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", self.backends[i].name, result.err))
+		}
+	}
+	return nil, true, true, errors.Join(errs...)
+}
+
+// request sends a request to the backends supporting it, and merges their
+// results.
+func (self *Mux) request(context *lsp.Context) (any, bool, bool, error) {
+	backends := self.supporting(context)
+	if len(backends) == 0 {
+		return nil, false, true, nil
+	}
+
+	results := self.call(contextOf(context), backends, context.Method, params(context))
+	var values []json.RawMessage
+	var indexes []int
+	var errs []error
+	methodNotFound := 0
+	for i, result := range results {
+		var rpcErr *jsonrpc2.Error
+		switch {
+		case errors.As(result.err, &rpcErr) && rpcErr.Code == jsonrpc2.CodeMethodNotFound:
+			methodNotFound++
+		case result.err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", backends[i].name, result.err))
+		case !isNull(result.value):
+			values = append(values, result.value)
+			indexes = append(indexes, self.indexOf(backends[i]))
+		}
+	}
+	if methodNotFound == len(backends) {
+		return nil, false, true, nil
+	}
+	if len(values) == 0 {
+		// Only an error if no backend could answer
+		if len(errs) == len(backends) {
+			return nil, true, true, errs[0]
+		}
+		return nil, true, true, nil
+	}
+	for _, err := range errs {
+		self.Log.Warningf("%s failed: %s", context.Method, err.Error())
+	}
+
+	if merge, ok := listMethods[context.Method]; ok {
+		result, err := merge(values, indexes)
+		return result, true, true, err
+	}
+	return values[0], true, true, nil
+}
+
+// resolve sends a resolve request to the backend that returned the item.
+func (self *Mux) resolve(context *lsp.Context) (any, bool, bool, error) {
+	var item map[string]any
+	if err := json.Unmarshal(context.Params, &item); err != nil {
+		return nil, true, false, err
+	}
+	index, ok := untag(item)
+	if !ok {
+		// Not from a Mux: the first backend able to resolve it decides
+		return self.request(context)
+	}
+	if index < 0 || index >= len(self.backends) {
+		return nil, true, false, fmt.Errorf("no backend %d", index)
+	}
+
+	var result json.RawMessage
+	if err := self.backends[index].connection.Call(contextOf(context), context.Method, item, &result); err != nil {
+		return nil, true, true, err
+	}
+	var resolved map[string]any
+	if err := json.Unmarshal(result, &resolved); err != nil || resolved == nil {
+		return result, true, true, nil
+	}
+	tag(resolved, index)
+	return resolved, true, true, nil
+}
+
+// supporting returns the backends whose capabilities cover a request.
+func (self *Mux) supporting(context *lsp.Context) []*backend {
+	capability, ok := methodCapabilities[context.Method]
+	if !ok {
+		// Unknown to the Mux: every backend may support it
+		return self.backends
+	}
+
+	var command string
+	if context.Method == protocol.MethodWorkspaceExecuteCommand {
+		var params struct {
+			Command string `json:"command"`
+		}
+		json.Unmarshal(context.Params, &params)
+		command = params.Command
+	}
+
+	var backends []*backend
+	for _, b := range self.backends {
+		b.mu.Lock()
+		value := b.capabilities[capability]
+		b.mu.Unlock()
+		if command != "" {
+			if !hasCommand(value, command) {
+				continue
+			}
+		} else if !enabled(value) {
+			continue
+		}
+		backends = append(backends, b)
+		if exclusiveCapabilities[capability] {
+			break
+		}
+	}
+	return backends
+}
+
+func (self *Mux) indexOf(b *backend) int {
+	for i, other := range self.backends {
+		if other == b {
+			return i
+		}
+	}
+	return -1
+}
+
+type callResult struct {
+	value json.RawMessage
+	err   error
+}
+
+// call sends a request to backends concurrently, and returns their
+// results in the same order.
+func (self *Mux) call(context contextpkg.Context, backends []*backend, method string, params any) []callResult {
+	results := make([]callResult, len(backends))
+	var wait sync.WaitGroup
+	for i, b := range backends {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			results[i].err = b.connection.Call(context, method, params, &results[i].value)
+		}()
+	}
+	wait.Wait()
+	return results
+}
+
+// publishDiagnostics publishes the diagnostics of a document from every
+// backend, after a backend published new ones.
+func (self *Mux) publishDiagnostics(index int, raw json.RawMessage) {
+	var params struct {
+		URI         string            `json:"uri"`
+		Version     *int              `json:"version,omitempty"`
+		Diagnostics []json.RawMessage `json:"diagnostics"`
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		self.Log.Errorf("invalid diagnostics from %s: %s", self.backends[index].name, err.Error())
+		return
+	}
+
+	self.mu.Lock()
+	byBackend, ok := self.diagnostics[params.URI]
+	if !ok {
+		byBackend = make(map[int][]json.RawMessage)
+		self.diagnostics[params.URI] = byBackend
+	}
+	if len(params.Diagnostics) == 0 {
+		delete(byBackend, index)
+	} else {
+		byBackend[index] = params.Diagnostics
+	}
+	diagnostics := []json.RawMessage{}
+	for i := range self.backends {
+		diagnostics = append(diagnostics, byBackend[i]...)
+	}
+	if len(byBackend) == 0 {
+		delete(self.diagnostics, params.URI)
+	}
+	client := self.client
+	self.mu.Unlock()
+
+	params.Diagnostics = diagnostics
+	if client != nil {
+		client.Notify(protocol.ServerTextDocumentPublishDiagnostics, params)
+	}
+}
+
+// backendHandler handles the messages a backend sends to the client.
+type backendHandler struct {
+	mux   *Mux
+	index int
+}
+
+// ([jsonrpc2.Handler] interface)
+func (self *backendHandler) Handle(context contextpkg.Context, connection *jsonrpc2.Conn, request *jsonrpc2.Request) {
+	var raw json.RawMessage
+	if request.Params != nil {
+		raw = *request.Params
+	}
+
+	if request.Method == protocol.ServerTextDocumentPublishDiagnostics {
+		self.mux.publishDiagnostics(self.index, raw)
+		return
+	}
+
+	self.mux.mu.Lock()
+	client := self.mux.client
+	self.mux.mu.Unlock()
+
+	if request.Notif {
+		// In order, like log messages and progress
+		if client != nil {
+			client.Notify(request.Method, raw)
+		}
+		return
+	}
+
+	// Requests, like workspace/configuration, may wait for the client
+	// while the backend keeps sending messages
+	go func() {
+		var result json.RawMessage
+		if client != nil {
+			if err := client.Call(request.Method, raw, &result); err != nil {
+				// The backend gets the client's error, not a null result
+				var rpcErr *jsonrpc2.Error
+				if !errors.As(err, &rpcErr) {
+					rpcErr = &jsonrpc2.Error{Code: jsonrpc2.CodeInternalError, Message: err.Error()}
+				}
+				if err := connection.ReplyWithError(context, request.ID, rpcErr); err != nil {
+					self.mux.Log.Errorf("%s: %s", self.mux.backends[self.index].name, err.Error())
+				}
+				return
+			}
+		}
+		if len(result) == 0 {
+			result = json.RawMessage("null")
+		}
+		if err := connection.Reply(context, request.ID, result); err != nil {
+			self.mux.Log.Errorf("%s: %s", self.mux.backends[self.index].name, err.Error())
+		}
+	}()
+}
+
+// commandStream is the standard input and output of a backend process.
+type commandStream struct {
+	io.ReadCloser
+	stdin io.WriteCloser
+}
+
+// ([io.Writer] interface)
+func (self *commandStream) Write(p []byte) (int, error) {
+	return self.stdin.Write(p)
+}
+
+// ([io.Closer] interface)
+func (self *commandStream) Close() error {
+	return errors.Join(self.stdin.Close(), self.ReadCloser.Close())
+}
+
+// params returns the parameters of a message to forward.
+func params(context *lsp.Context) any {
+	if context.Params == nil {
+		return nil
+	}
+	return context.Params
+}
+
+func contextOf(context *lsp.Context) contextpkg.Context {
+	if context.Context != nil {
+		return context.Context
+	}
+	return contextpkg.Background()
+}
+
+func isNull(value json.RawMessage) bool {
+	return len(value) == 0 || string(value) == "null"
+}
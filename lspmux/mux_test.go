@@ -0,0 +1,198 @@
+package lspmux
+
+import (
+	contextpkg "context"
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// testBackend answers requests with canned results, and publishes a
+// diagnostic for every opened document.
+type testBackend struct {
+	name    string
+	results map[string]any
+	methods chan string
+}
+
+func (self *testBackend) Handle(context contextpkg.Context, connection *jsonrpc2.Conn, request *jsonrpc2.Request) {
+	self.methods <- request.Method
+	if request.Method == "textDocument/didOpen" {
+		connection.Notify(context, "textDocument/publishDiagnostics", map[string]any{
+			"uri":         "file:///a.go",
+			"diagnostics": []any{map[string]any{"message": self.name}},
+		})
+	}
+	if request.Notif {
+		return
+	}
+	if request.Method == "completionItem/resolve" {
+		var item map[string]any
+		json.Unmarshal(*request.Params, &item)
+		item["detail"] = "resolved by " + self.name
+		connection.Reply(context, request.ID, item)
+		return
+	}
+	result, ok := self.results[request.Method]
+	if !ok {
+		connection.ReplyWithError(context, request.ID, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound})
+		return
+	}
+	connection.Reply(context, request.ID, result)
+}
+
+func newTestMux(t *testing.T, backends ...*testBackend) *Mux {
+	mux := NewMux("mux")
+	for _, b := range backends {
+		b.methods = make(chan string, 100)
+		muxSide, backendSide := net.Pipe()
+		jsonrpc2.NewConn(contextpkg.Background(), jsonrpc2.NewBufferedStream(backendSide, jsonrpc2.VSCodeObjectCodec{}), b)
+		mux.AddBackend(b.name, muxSide)
+	}
+	t.Cleanup(func() { mux.Close() })
+	return mux
+}
+
+func handle(t *testing.T, mux *Mux, client *lsp.Context, method string, params any) any {
+	t.Helper()
+	raw, _ := json.Marshal(params)
+	client.Method = method
+	client.Params = raw
+	result, validMethod, validParams, err := mux.Handle(client)
+	if !validMethod || !validParams || err != nil {
+		t.Fatalf("%s: valid method %t, valid params %t, error %v", method, validMethod, validParams, err)
+	}
+	encoded, _ := json.Marshal(result)
+	var decoded any
+	json.Unmarshal(encoded, &decoded)
+	return decoded
+}
+
+func TestMux(t *testing.T) {
+	golang := &testBackend{name: "go", results: map[string]any{
+		"initialize": map[string]any{"capabilities": map[string]any{
+			"textDocumentSync":   2,
+			"hoverProvider":      true,
+			"completionProvider": map[string]any{"triggerCharacters": []any{"."}},
+		}},
+		"textDocument/hover":      map[string]any{"contents": "func main()"},
+		"textDocument/completion": map[string]any{"isIncomplete": true, "items": []any{map[string]any{"label": "Println"}}},
+	}}
+	spelling := &testBackend{name: "spelling", results: map[string]any{
+		"initialize": map[string]any{"capabilities": map[string]any{
+			"textDocumentSync":   1,
+			"completionProvider": map[string]any{"triggerCharacters": []any{" ", "."}, "resolveProvider": true},
+			"codeActionProvider": true,
+		}},
+		"textDocument/completion": []any{map[string]any{"label": "spelling", "data": 7}},
+		"textDocument/codeAction": []any{map[string]any{"title": "Fix spelling"}},
+	}}
+	mux := newTestMux(t, golang, spelling)
+
+	notifications := make(chan any, 10)
+	client := &lsp.Context{Notify: func(method string, params any) {
+		if method == "textDocument/publishDiagnostics" {
+			notifications <- params
+		}
+	}}
+
+	initialize := handle(t, mux, client, "initialize", map[string]any{"processId": nil})
+	capabilities := initialize.(map[string]any)["capabilities"].(map[string]any)
+	if got := capabilities["completionProvider"].(map[string]any)["triggerCharacters"]; !reflect.DeepEqual(got, []any{".", " "}) {
+		t.Errorf("triggerCharacters = %v, want merged", got)
+	}
+	if got := capabilities["textDocumentSync"].(map[string]any)["change"]; got != float64(1) {
+		t.Errorf("textDocumentSync change = %v, want full", got)
+	}
+	if capabilities["hoverProvider"] != true || capabilities["codeActionProvider"] != true {
+		t.Errorf("expected hover and code actions, got %v", capabilities)
+	}
+
+	// Hover only goes to the Go server
+	hover := handle(t, mux, client, "textDocument/hover", map[string]any{})
+	if hover.(map[string]any)["contents"] != "func main()" {
+		t.Errorf("hover = %v", hover)
+	}
+
+	completion := handle(t, mux, client, "textDocument/completion", map[string]any{}).(map[string]any)
+	items := completion["items"].([]any)
+	if completion["isIncomplete"] != true || len(items) != 2 {
+		t.Fatalf("completion = %v, want two items, incomplete", completion)
+	}
+
+	// Resolving goes back to the backend of the item, with its own data
+	resolved := handle(t, mux, client, "completionItem/resolve", items[1]).(map[string]any)
+	if resolved["detail"] != "resolved by spelling" {
+		t.Errorf("resolved = %v", resolved)
+	}
+	if data := resolved["data"].(map[string]any); data["data"] != float64(7) {
+		t.Errorf("resolved data = %v, want the tagged original", data)
+	}
+
+	actions := handle(t, mux, client, "textDocument/codeAction", map[string]any{}).([]any)
+	if len(actions) != 1 {
+		t.Errorf("code actions = %v", actions)
+	}
+
+	// Both servers publish diagnostics for the opened document
+	handle(t, mux, client, "textDocument/didOpen", map[string]any{})
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case params := <-notifications:
+			encoded, _ := json.Marshal(params)
+			var published struct {
+				Diagnostics []any `json:"diagnostics"`
+			}
+			json.Unmarshal(encoded, &published)
+			if len(published.Diagnostics) == 2 {
+				return
+			}
+		case <-timeout:
+			t.Fatal("diagnostics were not merged")
+		}
+	}
+}
+
+func TestMuxMethodNotFound(t *testing.T) {
+	mux := newTestMux(t, &testBackend{name: "a", results: map[string]any{
+		"initialize": map[string]any{"capabilities": map[string]any{}},
+	}})
+	client := &lsp.Context{Notify: func(string, any) {}}
+	handle(t, mux, client, "initialize", map[string]any{})
+
+	for _, method := range []string{"textDocument/hover", "custom/method"} {
+		client.Method = method
+		if _, validMethod, _, _ := mux.Handle(client); validMethod {
+			t.Errorf("%s: expected an unsupported method", method)
+		}
+	}
+}
+
+func TestMuxClientCallError(t *testing.T) {
+	mux := NewMux("mux")
+	muxSide, backendSide := net.Pipe()
+	backend := jsonrpc2.NewConn(contextpkg.Background(), jsonrpc2.NewBufferedStream(backendSide, jsonrpc2.VSCodeObjectCodec{}), jsonrpc2.HandlerWithError(func(contextpkg.Context, *jsonrpc2.Conn, *jsonrpc2.Request) (any, error) {
+		return nil, nil
+	}))
+	mux.AddBackend("a", muxSide)
+	t.Cleanup(func() { mux.Close() })
+
+	mux.client = &lsp.Context{Call: func(method string, params any, result any) error {
+		return &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: "no configuration"}
+	}}
+
+	context, cancel := contextpkg.WithTimeout(contextpkg.Background(), 3*time.Second)
+	defer cancel()
+	var result any
+	err := backend.Call(context, "workspace/configuration", map[string]any{"items": []any{}}, &result)
+	rpcErr, ok := err.(*jsonrpc2.Error)
+	if !ok || rpcErr.Code != jsonrpc2.CodeMethodNotFound || rpcErr.Message != "no configuration" {
+		t.Errorf("error = %v, want the client's error", err)
+	}
+}
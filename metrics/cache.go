@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"github.com/SCKelemen/lsp/cache"
+)
+
+// CacheCollector reports the statistics of every cache managed by m:
+// lsp_cache_hits_total, lsp_cache_misses_total, lsp_cache_evictions_total,
+// lsp_cache_entries, and lsp_cache_bytes, labeled by cache name.
+func CacheCollector(m *cache.Manager) Collector {
+	return func(emit func(name string, kind Kind, value float64, labels ...Label)) {
+		for _, s := range m.Stats() {
+			label := Label{Name: "cache", Value: s.Name}
+			emit("lsp_cache_hits_total", KindCounter, float64(s.Hits), label)
+			emit("lsp_cache_misses_total", KindCounter, float64(s.Misses), label)
+			emit("lsp_cache_evictions_total", KindCounter, float64(s.Evictions), label)
+			emit("lsp_cache_entries", KindGauge, float64(s.Entries), label)
+			emit("lsp_cache_bytes", KindGauge, float64(s.Bytes), label)
+		}
+	}
+}
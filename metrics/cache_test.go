@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/SCKelemen/lsp/cache"
+)
+
+type nopStore struct{}
+
+func (nopStore) Evict(uri string) {}
+
+func TestCacheCollector(t *testing.T) {
+	manager := cache.NewManager(0)
+	manager.Register("go-ast", nopStore{})
+	manager.Add("go-ast", "file:///a.go", 100)
+	manager.Hit("go-ast", "file:///a.go")
+
+	r := NewRegistry()
+	r.AddCollector(CacheCollector(manager))
+
+	values := make(map[string]float64)
+	for _, m := range r.Snapshot() {
+		for _, s := range m.Samples {
+			if len(s.Labels) != 1 || s.Labels[0] != (Label{Name: "cache", Value: "go-ast"}) {
+				t.Fatalf("unexpected labels %v", s.Labels)
+			}
+			values[m.Name] = s.Value
+		}
+	}
+
+	expected := map[string]float64{
+		"lsp_cache_hits_total":      1,
+		"lsp_cache_misses_total":    1,
+		"lsp_cache_evictions_total": 0,
+		"lsp_cache_entries":         1,
+		"lsp_cache_bytes":           100,
+	}
+	for name, value := range expected {
+		if got, ok := values[name]; !ok || got != value {
+			t.Errorf("expected %s = %v, got %v", name, value, got)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"expvar"
+)
+
+// PublishExpvar publishes the registry under name in the expvar package,
+// so it appears on /debug/vars. Each metric maps to an object keyed by its
+// labels in Prometheus notation ("" for a metric without labels). Counters
+// and gauges map to numbers; histograms map to objects with count, sum, and
+// cumulative buckets.
+//
+// Like expvar.Publish, it panics if name is already published.
+func PublishExpvar(name string, r *Registry) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return expvarValue(r.Snapshot())
+	}))
+}
+
+func expvarValue(metrics []Metric) map[string]map[string]any {
+	result := make(map[string]map[string]any, len(metrics))
+	for _, m := range metrics {
+		series := make(map[string]any, len(m.Samples))
+		for _, s := range m.Samples {
+			key := formatLabels(s.Labels)
+			if m.Kind != KindHistogram {
+				series[key] = s.Value
+				continue
+			}
+
+			buckets := make(map[string]uint64, len(m.Buckets)+1)
+			for i, bound := range m.Buckets {
+				buckets[formatFloat(bound)] = s.Buckets[i]
+			}
+			buckets["+Inf"] = s.Count
+			series[key] = map[string]any{
+				"count":   s.Count,
+				"sum":     s.Sum,
+				"buckets": buckets,
+			}
+		}
+		result[m.Name] = series
+	}
+	return result
+}
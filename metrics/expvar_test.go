@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+func TestExpvarValue(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("lsp_requests_total", "method", "initialize").Inc()
+	r.Histogram("lsp_request_duration_seconds", []float64{1}).Observe(0.5)
+
+	// Decode the published JSON as a /debug/vars reader would
+	published := expvar.Func(func() any { return expvarValue(r.Snapshot()) })
+	var value map[string]map[string]any
+	if err := json.Unmarshal([]byte(published.String()), &value); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := value["lsp_requests_total"][`{method="initialize"}`]; got != 1.0 {
+		t.Errorf("expected counter 1, got %v", got)
+	}
+	histogram, ok := value["lsp_request_duration_seconds"][""].(map[string]any)
+	if !ok {
+		t.Fatalf("expected histogram object, got %v", value["lsp_request_duration_seconds"])
+	}
+	if histogram["count"] != 1.0 || histogram["sum"] != 0.5 {
+		t.Errorf("unexpected histogram %v", histogram)
+	}
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PrometheusHandler serves the registry in the Prometheus text exposition
+// format, e.g. on a /metrics endpoint next to a TCP or WebSocket server.
+func PrometheusHandler(r *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WritePrometheus(w, r.Snapshot())
+	})
+}
+
+// WritePrometheus writes metrics in the Prometheus text exposition format.
+func WritePrometheus(w io.Writer, metrics []Metric) error {
+	for _, m := range metrics {
+		if m.Help != "" {
+			if _, err := fmt.Fprintf(w, "# HELP %s %s\n", m.Name, escapeHelp(m.Help)); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "# TYPE %s %s\n", m.Name, m.Kind); err != nil {
+			return err
+		}
+
+		for _, s := range m.Samples {
+			if m.Kind != KindHistogram {
+				if _, err := fmt.Fprintf(w, "%s%s %s\n", m.Name, formatLabels(s.Labels), formatFloat(s.Value)); err != nil {
+					return err
+				}
+				continue
+			}
+
+			for i, bound := range m.Buckets {
+				labels := append(append([]Label(nil), s.Labels...), Label{Name: "le", Value: formatFloat(bound)})
+				if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", m.Name, formatLabels(labels), s.Buckets[i]); err != nil {
+					return err
+				}
+			}
+			inf := append(append([]Label(nil), s.Labels...), Label{Name: "le", Value: "+Inf"})
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+				m.Name, formatLabels(inf), s.Count,
+				m.Name, formatLabels(s.Labels), formatFloat(s.Sum),
+				m.Name, formatLabels(s.Labels), s.Count); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Name + "=" + strconv.Quote(l.Value)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+// formatFloat formats a value like Prometheus does.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
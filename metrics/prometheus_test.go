@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrometheusHandler(t *testing.T) {
+	r := NewRegistry()
+	r.Describe("lsp_requests_total", "Requests handled.")
+	r.Counter("lsp_requests_total", "method", "textDocument/hover").Add(2)
+	h := r.Histogram("lsp_request_duration_seconds", []float64{0.1, 1}, "method", "textDocument/hover")
+	h.Observe(0.05)
+	h.Observe(2)
+
+	recorder := httptest.NewRecorder()
+	PrometheusHandler(r).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	expected := `# TYPE lsp_request_duration_seconds histogram
+lsp_request_duration_seconds_bucket{method="textDocument/hover",le="0.1"} 1
+lsp_request_duration_seconds_bucket{method="textDocument/hover",le="1"} 1
+lsp_request_duration_seconds_bucket{method="textDocument/hover",le="+Inf"} 2
+lsp_request_duration_seconds_sum{method="textDocument/hover"} 2.05
+lsp_request_duration_seconds_count{method="textDocument/hover"} 2
+# HELP lsp_requests_total Requests handled.
+# TYPE lsp_requests_total counter
+lsp_requests_total{method="textDocument/hover"} 2
+`
+	if got := recorder.Body.String(); got != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", got, expected)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("unexpected content type %q", contentType)
+	}
+}
+
+func TestFormatLabelsEscapes(t *testing.T) {
+	got := formatLabels([]Label{{Name: "uri", Value: "a\"b\\c\n"}})
+	if got != `{uri="a\"b\\c\n"}` {
+		t.Errorf("unexpected labels %s", got)
+	}
+}
@@ -0,0 +1,345 @@
+// Package metrics collects counters and histograms for a language server and
+// exposes them to monitoring systems.
+//
+// A Registry holds metrics identified by a name and a set of label pairs,
+// e.g. the request counter of one LSP method. Sinks read a consistent
+// Snapshot of the registry: PrometheusHandler serves the Prometheus text
+// format and PublishExpvar publishes it through the expvar package.
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDurationBuckets are histogram bounds in seconds suited to request
+// and provider latencies, from 1ms to 10s.
+var DefaultDurationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Now returns the current time of the clock measuring durations, with
+// Stopwatch and Registry.Time. Tests may replace it with a fake clock.
+var Now = time.Now
+
+// Stopwatch starts measuring a duration on the Now clock and returns a
+// function that returns the time elapsed since.
+func Stopwatch() func() time.Duration {
+	start := Now()
+	return func() time.Duration {
+		return Now().Sub(start)
+	}
+}
+
+// Kind is the type of a metric.
+type Kind int
+
+const (
+	KindCounter Kind = iota
+	KindGauge
+	KindHistogram
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindCounter:
+		return "counter"
+	case KindGauge:
+		return "gauge"
+	case KindHistogram:
+		return "histogram"
+	default:
+		return "untyped"
+	}
+}
+
+// Label is a name/value pair identifying one series of a metric.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Counter is a monotonically increasing count.
+type Counter struct {
+	value atomic.Uint64
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add adds n to the counter.
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+// Value returns the current count.
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+// Histogram counts observations into buckets with fixed upper bounds.
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Observe records one value.
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := sort.SearchFloat64s(h.bounds, value)
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.sum += value
+	h.count++
+}
+
+// ObserveDuration records a duration in seconds.
+func (h *Histogram) ObserveDuration(d time.Duration) {
+	h.Observe(d.Seconds())
+}
+
+// Sample is the value of one series at snapshot time.
+type Sample struct {
+	Labels []Label
+
+	// Value holds the value of a counter or gauge.
+	Value float64
+
+	// Buckets hold cumulative counts for a histogram, one per bound in
+	// Metric.Buckets; the +Inf bucket equals Count.
+	Buckets []uint64
+	Sum     float64
+	Count   uint64
+}
+
+// Metric is a snapshot of all series of one metric name.
+type Metric struct {
+	Name    string
+	Help    string
+	Kind    Kind
+	Buckets []float64
+	Samples []Sample
+}
+
+// Collector reports values computed on demand, such as cache statistics.
+// It is called on every snapshot and reports each value through emit.
+type Collector func(emit func(name string, kind Kind, value float64, labels ...Label))
+
+// Registry holds the metrics of a server. It is safe for concurrent use.
+type Registry struct {
+	mu         sync.Mutex
+	help       map[string]string
+	counters   map[string]map[string]*counterSeries
+	histograms map[string]*histogramFamily
+	collectors []Collector
+}
+
+type counterSeries struct {
+	labels  []Label
+	counter *Counter
+}
+
+type histogramFamily struct {
+	bounds []float64
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labels    []Label
+	histogram *Histogram
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		help:       make(map[string]string),
+		counters:   make(map[string]map[string]*counterSeries),
+		histograms: make(map[string]*histogramFamily),
+	}
+}
+
+// Describe sets the help text of a metric.
+func (r *Registry) Describe(name, help string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.help[name] = help
+}
+
+// Counter returns the counter for name and the given label pairs
+// (name, value, name, value, ...), creating it on first use.
+func (r *Registry) Counter(name string, labelPairs ...string) *Counter {
+	labels := makeLabels(labelPairs)
+	key := labelKey(labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	family, ok := r.counters[name]
+	if !ok {
+		family = make(map[string]*counterSeries)
+		r.counters[name] = family
+	}
+	series, ok := family[key]
+	if !ok {
+		series = &counterSeries{labels: labels, counter: &Counter{}}
+		family[key] = series
+	}
+	return series.counter
+}
+
+// Histogram returns the histogram for name and the given label pairs,
+// creating it on first use. The bucket bounds of a name are fixed by its
+// first use; nil means DefaultDurationBuckets.
+func (r *Registry) Histogram(name string, bounds []float64, labelPairs ...string) *Histogram {
+	labels := makeLabels(labelPairs)
+	key := labelKey(labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	family, ok := r.histograms[name]
+	if !ok {
+		if bounds == nil {
+			bounds = DefaultDurationBuckets
+		}
+		bounds = append([]float64(nil), bounds...)
+		sort.Float64s(bounds)
+		family = &histogramFamily{bounds: bounds, series: make(map[string]*histogramSeries)}
+		r.histograms[name] = family
+	}
+	series, ok := family.series[key]
+	if !ok {
+		series = &histogramSeries{
+			labels: labels,
+			histogram: &Histogram{
+				bounds: family.bounds,
+				counts: make([]uint64, len(family.bounds)),
+			},
+		}
+		family.series[key] = series
+	}
+	return series.histogram
+}
+
+// Time starts timing an operation and returns a function that records its
+// duration in the named histogram, e.g.
+//
+//	defer registry.Time("lsp_provider_duration_seconds", "provider", "hover")()
+func (r *Registry) Time(name string, labelPairs ...string) func() {
+	h := r.Histogram(name, nil, labelPairs...)
+	elapsed := Stopwatch()
+	return func() {
+		h.ObserveDuration(elapsed())
+	}
+}
+
+// AddCollector registers a collector called on every snapshot.
+func (r *Registry) AddCollector(c Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Snapshot returns the current value of every metric, sorted by name and
+// then by labels.
+func (r *Registry) Snapshot() []Metric {
+	r.mu.Lock()
+	collectors := append([]Collector(nil), r.collectors...)
+	help := make(map[string]string, len(r.help))
+	for name, text := range r.help {
+		help[name] = text
+	}
+
+	metrics := make(map[string]*Metric)
+	for name, family := range r.counters {
+		m := &Metric{Name: name, Kind: KindCounter}
+		for _, series := range family {
+			m.Samples = append(m.Samples, Sample{
+				Labels: series.labels,
+				Value:  float64(series.counter.Value()),
+			})
+		}
+		metrics[name] = m
+	}
+	for name, family := range r.histograms {
+		m := &Metric{Name: name, Kind: KindHistogram, Buckets: family.bounds}
+		for _, series := range family.series {
+			m.Samples = append(m.Samples, series.histogram.sample(series.labels))
+		}
+		metrics[name] = m
+	}
+	r.mu.Unlock()
+
+	// Collectors run without the lock so they may use the registry
+	for _, collect := range collectors {
+		collect(func(name string, kind Kind, value float64, labels ...Label) {
+			m, ok := metrics[name]
+			if !ok {
+				m = &Metric{Name: name, Kind: kind}
+				metrics[name] = m
+			}
+			m.Samples = append(m.Samples, Sample{Labels: labels, Value: value})
+		})
+	}
+
+	result := make([]Metric, 0, len(metrics))
+	for name, m := range metrics {
+		m.Help = help[name]
+		sort.Slice(m.Samples, func(i, j int) bool {
+			return labelKey(m.Samples[i].Labels) < labelKey(m.Samples[j].Labels)
+		})
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// sample returns the histogram's state with cumulative bucket counts.
+func (h *Histogram) sample(labels []Label) Sample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	buckets := make([]uint64, len(h.counts))
+	var cumulative uint64
+	for i, n := range h.counts {
+		cumulative += n
+		buckets[i] = cumulative
+	}
+	return Sample{Labels: labels, Buckets: buckets, Sum: h.sum, Count: h.count}
+}
+
+// makeLabels turns name/value pairs into labels sorted by name.
+// A trailing name without a value is ignored.
+func makeLabels(pairs []string) []Label {
+	labels := make([]Label, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels = append(labels, Label{Name: pairs[i], Value: pairs[i+1]})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	return labels
+}
+
+// labelKey returns a string identifying a sorted label set.
+func labelKey(labels []Label) string {
+	var b strings.Builder
+	for _, l := range labels {
+		b.WriteString(l.Name)
+		b.WriteByte(0)
+		b.WriteString(l.Value)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegistryCounter(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("requests", "method", "hover").Inc()
+	r.Counter("requests", "method", "hover").Add(2)
+	r.Counter("requests", "method", "completion").Inc()
+
+	metrics := r.Snapshot()
+	if len(metrics) != 1 {
+		t.Fatalf("expected 1 metric, got %d", len(metrics))
+	}
+	m := metrics[0]
+	if m.Kind != KindCounter || len(m.Samples) != 2 {
+		t.Fatalf("unexpected metric %+v", m)
+	}
+	// Samples are sorted by labels
+	if m.Samples[0].Labels[0].Value != "completion" || m.Samples[0].Value != 1 {
+		t.Errorf("unexpected first sample %+v", m.Samples[0])
+	}
+	if m.Samples[1].Labels[0].Value != "hover" || m.Samples[1].Value != 3 {
+		t.Errorf("unexpected second sample %+v", m.Samples[1])
+	}
+}
+
+func TestRegistryLabelOrder(t *testing.T) {
+	r := NewRegistry()
+	a := r.Counter("requests", "method", "hover", "outcome", "ok")
+	b := r.Counter("requests", "outcome", "ok", "method", "hover")
+	if a != b {
+		t.Error("expected label order not to matter")
+	}
+}
+
+func TestRegistryHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.Histogram("latency", []float64{1, 0.1})
+	for _, v := range []float64{0.05, 0.5, 0.7, 3} {
+		h.Observe(v)
+	}
+
+	m := r.Snapshot()[0]
+	if m.Kind != KindHistogram {
+		t.Fatalf("expected histogram, got %v", m.Kind)
+	}
+	if len(m.Buckets) != 2 || m.Buckets[0] != 0.1 || m.Buckets[1] != 1 {
+		t.Fatalf("expected sorted bounds, got %v", m.Buckets)
+	}
+	s := m.Samples[0]
+	if s.Buckets[0] != 1 || s.Buckets[1] != 3 {
+		t.Errorf("expected cumulative buckets [1 3], got %v", s.Buckets)
+	}
+	if s.Count != 4 || s.Sum != 4.25 {
+		t.Errorf("expected count 4 and sum 4.25, got %d and %v", s.Count, s.Sum)
+	}
+}
+
+func TestRegistryTime(t *testing.T) {
+	r := NewRegistry()
+	r.Time("provider_duration", "provider", "hover")()
+
+	m := r.Snapshot()[0]
+	if len(m.Buckets) != len(DefaultDurationBuckets) {
+		t.Errorf("expected default buckets, got %v", m.Buckets)
+	}
+	if m.Samples[0].Count != 1 {
+		t.Errorf("expected one observation, got %d", m.Samples[0].Count)
+	}
+}
+
+func TestRegistryTimeClock(t *testing.T) {
+	now := time.Unix(0, 0)
+	defer func(clock func() time.Time) { Now = clock }(Now)
+	Now = func() time.Time { return now }
+
+	r := NewRegistry()
+	done := r.Time("provider_duration", "provider", "hover")
+	now = now.Add(250 * time.Millisecond)
+	done()
+
+	if sum := r.Snapshot()[0].Samples[0].Sum; sum != 0.25 {
+		t.Errorf("expected a sum of 0.25 seconds on the fake clock, got %v", sum)
+	}
+}
+
+func TestRegistryCollector(t *testing.T) {
+	r := NewRegistry()
+	r.Describe("open_documents", "Documents open in the editor.")
+	r.AddCollector(func(emit func(name string, kind Kind, value float64, labels ...Label)) {
+		emit("open_documents", KindGauge, 3)
+	})
+
+	m := r.Snapshot()[0]
+	if m.Name != "open_documents" || m.Kind != KindGauge || m.Samples[0].Value != 3 {
+		t.Errorf("unexpected metric %+v", m)
+	}
+	if m.Help != "Documents open in the editor." {
+		t.Errorf("expected help text, got %q", m.Help)
+	}
+}
@@ -0,0 +1,276 @@
+package plugin
+
+import (
+	contextpkg "context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/tliron/commonlog"
+)
+
+// DefaultTimeout is how long a Plugin waits for a result by default.
+const DefaultTimeout = 2 * time.Second
+
+// DefaultRestartDelay is how long a Plugin waits by default before
+// starting a plugin again after it exited.
+const DefaultRestartDelay = time.Second
+
+// ErrRestarting is returned while a plugin that exited waits to be started
+// again.
+var ErrRestarting = errors.New("plugin exited, waiting to restart")
+
+// Plugin is a provider served by another process, implementing the core
+// provider interfaces by calling it. A call that fails, or does not return
+// within Timeout, is logged and returns no results, and methods the plugin
+// does not support return no results without calling it. The plugin is
+// connected to on first use, and again on the first call after it exits,
+// no sooner than RestartDelay after the last connection. It is safe for
+// concurrent use.
+type Plugin struct {
+	// Name names the plugin in logs.
+	Name string
+
+	// Connect starts the plugin, or connects to it, returning the stream
+	// to talk to it over. Closing the stream stops the plugin.
+	Connect func() (io.ReadWriteCloser, error)
+
+	// Timeout is how long a call waits for its result.
+	Timeout time.Duration
+
+	// RestartDelay is the minimum time between two connections.
+	RestartDelay time.Duration
+
+	Log commonlog.Logger
+
+	mu           sync.Mutex
+	connection   *jsonrpc2.Conn
+	capabilities Capabilities
+	connected    time.Time
+	connecting   chan struct{}
+	closed       bool
+}
+
+// NewPlugin creates a plugin connected to with connect, like a Command.
+func NewPlugin(name string, connect func() (io.ReadWriteCloser, error)) *Plugin {
+	return &Plugin{
+		Name:         name,
+		Connect:      connect,
+		Timeout:      DefaultTimeout,
+		RestartDelay: DefaultRestartDelay,
+		Log:          commonlog.GetLogger("plugin"),
+	}
+}
+
+// Command returns a Connect function starting a plugin process serving
+// over its standard input and output, with ServeStdio. Closing the stream
+// kills the process.
+func Command(name string, arg ...string) func() (io.ReadWriteCloser, error) {
+	return func() (io.ReadWriteCloser, error) {
+		command := exec.Command(name, arg...)
+		stdin, err := command.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		stdout, err := command.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := command.Start(); err != nil {
+			return nil, err
+		}
+		return &commandStream{ReadCloser: stdout, stdin: stdin, command: command}, nil
+	}
+}
+
+// Capabilities returns the capabilities of the plugin, connecting to it if
+// needed.
+func (self *Plugin) Capabilities() (Capabilities, error) {
+	_, capabilities, err := self.connect()
+	return capabilities, err
+}
+
+// Close disconnects from the plugin, stopping it. Calls afterwards return
+// no results.
+func (self *Plugin) Close() error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.closed = true
+	if self.connection == nil {
+		return nil
+	}
+	err := self.connection.Close()
+	self.connection = nil
+	if errors.Is(err, jsonrpc2.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// ([core.DiagnosticProvider] interface)
+func (self *Plugin) ProvideDiagnostics(uri, content string) []core.Diagnostic {
+	var diagnostics []core.Diagnostic
+	self.call(func(c Capabilities) bool { return c.Diagnostics }, MethodDiagnostics, DocumentParams{URI: uri, Content: content}, &diagnostics)
+	return diagnostics
+}
+
+// ([core.CompletionProvider] interface)
+func (self *Plugin) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
+	var list *core.CompletionList
+	self.call(func(c Capabilities) bool { return c.Completion }, MethodCompletion, ctx, &list)
+	return list
+}
+
+// ([core.CodeFixProvider] interface)
+func (self *Plugin) ProvideCodeFixes(ctx core.CodeFixContext) []core.CodeAction {
+	var actions []core.CodeAction
+	self.call(func(c Capabilities) bool { return c.CodeFixes }, MethodCodeFixes, ctx, &actions)
+	return actions
+}
+
+// ([core.HoverProvider] interface)
+func (self *Plugin) ProvideHover(uri, content string, position core.Position) *core.HoverInfo {
+	var hover *core.HoverInfo
+	self.call(func(c Capabilities) bool { return c.Hover }, MethodHover, PositionParams{URI: uri, Content: content, Position: position}, &hover)
+	return hover
+}
+
+// call calls method if the plugin supports it, logging failures.
+func (self *Plugin) call(supports func(Capabilities) bool, method string, params any, result any) {
+	connection, capabilities, err := self.connect()
+	if err != nil {
+		if !errors.Is(err, ErrRestarting) {
+			self.Log.Errorf("%s: %s", self.Name, err.Error())
+		}
+		return
+	}
+	if !supports(capabilities) {
+		return
+	}
+
+	context, cancel := contextpkg.WithTimeout(contextpkg.Background(), self.Timeout)
+	defer cancel()
+	if err := connection.Call(context, method, params, result); err != nil {
+		if errors.Is(err, contextpkg.DeadlineExceeded) {
+			err = fmt.Errorf("%s timed out after %s", method, self.Timeout)
+		}
+		self.Log.Errorf("%s: %s", self.Name, err.Error())
+	}
+}
+
+// connect returns the connection to the plugin and its capabilities,
+// connecting if it is not connected. The lock is not held while connecting,
+// and concurrent calls wait for the connection in progress.
+func (self *Plugin) connect() (*jsonrpc2.Conn, Capabilities, error) {
+	connection, capabilities, connecting, err := self.current()
+	if connecting == nil {
+		return connection, capabilities, err
+	}
+
+	connection, capabilities, err = self.initialize()
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.connecting = nil
+	close(connecting)
+	if err != nil {
+		return nil, Capabilities{}, err
+	}
+	if self.closed {
+		connection.Close()
+		return nil, Capabilities{}, jsonrpc2.ErrClosed
+	}
+	self.connection, self.capabilities = connection, capabilities
+	return connection, capabilities, nil
+}
+
+// current returns the connection to the plugin and its capabilities, or,
+// when the caller must connect, a channel to close once it is done.
+func (self *Plugin) current() (*jsonrpc2.Conn, Capabilities, chan struct{}, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	for self.connecting != nil {
+		connecting := self.connecting
+		self.mu.Unlock()
+		<-connecting
+		self.mu.Lock()
+	}
+	if self.closed {
+		return nil, Capabilities{}, nil, jsonrpc2.ErrClosed
+	}
+	if self.connection != nil {
+		select {
+		case <-self.connection.DisconnectNotify():
+			self.connection = nil
+		default:
+			return self.connection, self.capabilities, nil, nil
+		}
+	}
+	if !self.connected.IsZero() && time.Since(self.connected) < self.RestartDelay {
+		return nil, Capabilities{}, nil, ErrRestarting
+	}
+
+	self.connected = time.Now()
+	self.connecting = make(chan struct{})
+	return nil, Capabilities{}, self.connecting, nil
+}
+
+// initialize connects to the plugin and asks for its capabilities. A
+// plugin that does not answer within Timeout is stopped.
+func (self *Plugin) initialize() (*jsonrpc2.Conn, Capabilities, error) {
+	stream, err := self.Connect()
+	if err != nil {
+		return nil, Capabilities{}, err
+	}
+	// The plugin has nothing to ask of the server
+	ignore := jsonrpc2.HandlerWithError(func(contextpkg.Context, *jsonrpc2.Conn, *jsonrpc2.Request) (any, error) {
+		return nil, nil
+	})
+	connection := jsonrpc2.NewConn(contextpkg.Background(), jsonrpc2.NewBufferedStream(stream, jsonrpc2.VSCodeObjectCodec{}), ignore)
+
+	context, cancel := contextpkg.WithTimeout(contextpkg.Background(), self.Timeout)
+	defer cancel()
+	// Closing the connection closes the stream, killing a plugin process
+	stop := contextpkg.AfterFunc(context, func() {
+		connection.Close()
+	})
+	var capabilities Capabilities
+	if err := connection.Call(context, MethodInitialize, nil, &capabilities); err != nil {
+		connection.Close()
+		if errors.Is(err, contextpkg.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", self.Timeout)
+		}
+		return nil, Capabilities{}, fmt.Errorf("initializing: %w", err)
+	}
+	if !stop() {
+		// The context expired as the result arrived
+		return nil, Capabilities{}, fmt.Errorf("initializing: timed out after %s", self.Timeout)
+	}
+	return connection, capabilities, nil
+}
+
+// commandStream is the standard input and output of a plugin process.
+type commandStream struct {
+	io.ReadCloser
+	stdin   io.WriteCloser
+	command *exec.Cmd
+}
+
+// ([io.Writer] interface)
+func (self *commandStream) Write(p []byte) (int, error) {
+	return self.stdin.Write(p)
+}
+
+// ([io.Closer] interface)
+func (self *commandStream) Close() error {
+	self.stdin.Close()
+	self.command.Process.Kill()
+	// Killed is how the process is expected to end
+	self.command.Wait()
+	return nil
+}
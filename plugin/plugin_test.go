@@ -0,0 +1,193 @@
+package plugin
+
+import (
+	contextpkg "context"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// todoProvider reports TODO comments, and hovers them slowly if asked.
+type todoProvider struct {
+	delay time.Duration
+}
+
+func (p *todoProvider) ProvideDiagnostics(uri, content string) []core.Diagnostic {
+	var diagnostics []core.Diagnostic
+	severity := core.SeverityInformation
+	for line, text := range strings.Split(content, "\n") {
+		if column := strings.Index(text, "TODO"); column >= 0 {
+			diagnostics = append(diagnostics, core.Diagnostic{
+				Range:    core.Range{Start: core.Position{Line: line, Character: column}, End: core.Position{Line: line, Character: column + 4}},
+				Severity: &severity,
+				Message:  "TODO",
+			})
+		}
+	}
+	return diagnostics
+}
+
+func (p *todoProvider) ProvideHover(uri, content string, position core.Position) *core.HoverInfo {
+	time.Sleep(p.delay)
+	return &core.HoverInfo{Contents: "a TODO"}
+}
+
+// pipeConnect returns a Connect function serving provider in process, and
+// the number of connections made.
+func pipeConnect(t *testing.T, provider any) (func() (io.ReadWriteCloser, error), *atomic.Int32) {
+	var connections atomic.Int32
+	connect := func() (io.ReadWriteCloser, error) {
+		connections.Add(1)
+		server, client := net.Pipe()
+		context, cancel := contextpkg.WithCancel(contextpkg.Background())
+		t.Cleanup(cancel)
+		go Serve(context, server, provider)
+		return client, nil
+	}
+	return connect, &connections
+}
+
+func TestPlugin(t *testing.T) {
+	connect, connections := pipeConnect(t, &todoProvider{})
+	plugin := NewPlugin("todo", connect)
+	t.Cleanup(func() { plugin.Close() })
+
+	capabilities, err := plugin.Capabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capabilities != (Capabilities{Diagnostics: true, Hover: true}) {
+		t.Errorf("capabilities = %+v, want diagnostics and hover", capabilities)
+	}
+
+	diagnostics := plugin.ProvideDiagnostics("file:///a.go", "package a\n\n// TODO: test\n")
+	if len(diagnostics) != 1 || diagnostics[0].Range.Start != (core.Position{Line: 2, Character: 3}) || diagnostics[0].Severity == nil || *diagnostics[0].Severity != core.SeverityInformation {
+		t.Errorf("diagnostics = %+v, want the TODO", diagnostics)
+	}
+	if hover := plugin.ProvideHover("file:///a.go", "", core.Position{}); hover == nil || hover.Contents != "a TODO" {
+		t.Errorf("hover = %+v", hover)
+	}
+	if completions := plugin.ProvideCompletions(core.CompletionContext{URI: "file:///a.go"}); completions != nil {
+		t.Errorf("completions of a plugin without them = %+v", completions)
+	}
+	if connections.Load() != 1 {
+		t.Errorf("connected %d times, want once", connections.Load())
+	}
+
+	plugin.Close()
+	if diagnostics := plugin.ProvideDiagnostics("file:///a.go", "// TODO"); diagnostics != nil {
+		t.Errorf("diagnostics of a closed plugin = %+v", diagnostics)
+	}
+}
+
+func TestPluginTimeout(t *testing.T) {
+	connect, _ := pipeConnect(t, &todoProvider{delay: time.Second})
+	plugin := NewPlugin("slow", connect)
+	plugin.Timeout = 50 * time.Millisecond
+	t.Cleanup(func() { plugin.Close() })
+	if _, err := plugin.Capabilities(); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if hover := plugin.ProvideHover("file:///a.go", "", core.Position{}); hover != nil {
+		t.Errorf("hover of a slow plugin = %+v, want none", hover)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("waited %s for a slow plugin", elapsed)
+	}
+}
+
+func TestPluginRestart(t *testing.T) {
+	var streams []io.ReadWriteCloser
+	connect, connections := pipeConnect(t, &todoProvider{})
+	plugin := NewPlugin("crashing", func() (io.ReadWriteCloser, error) {
+		stream, err := connect()
+		streams = append(streams, stream)
+		return stream, err
+	})
+	plugin.RestartDelay = 100 * time.Millisecond
+	t.Cleanup(func() { plugin.Close() })
+
+	if diagnostics := plugin.ProvideDiagnostics("file:///a.go", "// TODO"); len(diagnostics) != 1 {
+		t.Fatalf("diagnostics = %+v", diagnostics)
+	}
+
+	// The plugin crashes
+	streams[0].Close()
+	time.Sleep(10 * time.Millisecond)
+	if diagnostics := plugin.ProvideDiagnostics("file:///a.go", "// TODO"); diagnostics != nil {
+		t.Errorf("diagnostics while restarting = %+v, want none", diagnostics)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if diagnostics := plugin.ProvideDiagnostics("file:///a.go", "// TODO"); len(diagnostics) != 1 {
+		t.Errorf("diagnostics after restarting = %+v", diagnostics)
+	}
+	if connections.Load() != 2 {
+		t.Errorf("connected %d times, want twice", connections.Load())
+	}
+}
+
+func TestPluginInitializeTimeout(t *testing.T) {
+	killed := make(chan struct{})
+	plugin := NewPlugin("hanging", func() (io.ReadWriteCloser, error) {
+		server, client := net.Pipe()
+		// Reads the initialize request, and never answers
+		go func() {
+			io.Copy(io.Discard, server)
+			close(killed)
+		}()
+		return client, nil
+	})
+	plugin.Timeout = 200 * time.Millisecond
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := plugin.Capabilities()
+		errs <- err
+	}()
+
+	// Closing does not wait for the initialize round trip
+	time.Sleep(20 * time.Millisecond)
+	start := time.Now()
+	plugin.Close()
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("closing waited %s for initialize", elapsed)
+	}
+
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("expected a hanging plugin to fail initializing")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("initialize did not time out")
+	}
+	select {
+	case <-killed:
+	case <-time.After(3 * time.Second):
+		t.Error("the hanging plugin was not stopped")
+	}
+}
+
+// TestPluginCommand runs this test binary as a plugin process.
+func TestPluginCommand(t *testing.T) {
+	if os.Getenv("PLUGIN_TEST_PROCESS") == "1" {
+		ServeStdio(&todoProvider{})
+		os.Exit(0)
+	}
+	t.Setenv("PLUGIN_TEST_PROCESS", "1")
+
+	plugin := NewPlugin("process", Command(os.Args[0], "-test.run=^TestPluginCommand$"))
+	plugin.Timeout = 10 * time.Second
+	t.Cleanup(func() { plugin.Close() })
+	if diagnostics := plugin.ProvideDiagnostics("file:///a.go", "x\n// TODO"); len(diagnostics) != 1 || diagnostics[0].Range.Start.Line != 1 {
+		t.Errorf("diagnostics = %+v", diagnostics)
+	}
+}
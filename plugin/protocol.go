@@ -0,0 +1,71 @@
+// Package plugin runs providers in separate processes, so that a crash or
+// a hang in one, like a proprietary linter, cannot take down the server.
+//
+// The plugin process serves a provider with Serve or ServeStdio. The
+// server talks to it through a Plugin, which implements the core provider
+// interfaces the plugin supports by calling it over JSON-RPC, with the
+// methods and parameters below mirroring those interfaces. Calls that fail
+// or time out return no results, and a plugin that exits is started again
+// on the next call.
+package plugin
+
+import (
+	"github.com/SCKelemen/lsp/core"
+)
+
+const (
+	// MethodInitialize returns the Capabilities of the plugin.
+	MethodInitialize = "plugin/initialize"
+
+	// MethodDiagnostics calls core.DiagnosticProvider with DocumentParams
+	// and returns []core.Diagnostic.
+	MethodDiagnostics = "plugin/diagnostics"
+
+	// MethodCompletion calls core.CompletionProvider with a
+	// core.CompletionContext and returns a *core.CompletionList.
+	MethodCompletion = "plugin/completion"
+
+	// MethodCodeFixes calls core.CodeFixProvider with a
+	// core.CodeFixContext and returns []core.CodeAction.
+	MethodCodeFixes = "plugin/codeFixes"
+
+	// MethodHover calls core.HoverProvider with PositionParams and returns
+	// a *core.HoverInfo.
+	MethodHover = "plugin/hover"
+)
+
+// Capabilities are the provider interfaces a plugin implements.
+type Capabilities struct {
+	Diagnostics bool `json:"diagnostics,omitempty"`
+	Completion  bool `json:"completion,omitempty"`
+	CodeFixes   bool `json:"codeFixes,omitempty"`
+	Hover       bool `json:"hover,omitempty"`
+}
+
+// capabilitiesOf returns the capabilities of a provider.
+func capabilitiesOf(provider any) Capabilities {
+	_, diagnostics := provider.(core.DiagnosticProvider)
+	_, completion := provider.(core.CompletionProvider)
+	_, codeFixes := provider.(core.CodeFixProvider)
+	_, hover := provider.(core.HoverProvider)
+	return Capabilities{
+		Diagnostics: diagnostics,
+		Completion:  completion,
+		CodeFixes:   codeFixes,
+		Hover:       hover,
+	}
+}
+
+// DocumentParams are the parameters of requests about a document.
+type DocumentParams struct {
+	URI     string `json:"uri"`
+	Content string `json:"content"`
+}
+
+// PositionParams are the parameters of requests about a position in a
+// document.
+type PositionParams struct {
+	URI      string        `json:"uri"`
+	Content  string        `json:"content"`
+	Position core.Position `json:"position"`
+}
@@ -0,0 +1,160 @@
+package plugin
+
+import (
+	contextpkg "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// Serve serves a provider to the server over stream until the server
+// disconnects or context is done. The provider implements any of
+// core.DiagnosticProvider, core.CompletionProvider, core.CodeFixProvider,
+// and core.HoverProvider.
+func Serve(context contextpkg.Context, stream io.ReadWriteCloser, provider any) error {
+	handler := &providerHandler{provider: provider}
+	connection := jsonrpc2.NewConn(context, jsonrpc2.NewBufferedStream(stream, jsonrpc2.VSCodeObjectCodec{}), jsonrpc2.HandlerWithError(handler.handle))
+	select {
+	case <-connection.DisconnectNotify():
+		return nil
+	case <-context.Done():
+		connection.Close()
+		return context.Err()
+	}
+}
+
+// ServeStdio serves a provider over the standard input and output of the
+// plugin process, until the server closes them.
+func ServeStdio(provider any) error {
+	return Serve(contextpkg.Background(), stdio{}, provider)
+}
+
+// providerHandler answers the requests of the server with a provider.
+type providerHandler struct {
+	provider any
+}
+
+func (self *providerHandler) handle(context contextpkg.Context, connection *jsonrpc2.Conn, request *jsonrpc2.Request) (any, error) {
+	var params json.RawMessage
+	if request.Params != nil {
+		params = *request.Params
+	}
+
+	result, err := Dispatch(self.provider, request.Method, params)
+	var paramsErr *ParamsError
+	switch {
+	case errors.Is(err, ErrNotSupported):
+		return nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.CodeMethodNotFound,
+			Message: err.Error(),
+		}
+	case errors.As(err, &paramsErr):
+		return nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.CodeInvalidParams,
+			Message: err.Error(),
+		}
+	}
+	return result, err
+}
+
+// ErrNotSupported is returned by Dispatch for methods the provider does not
+// support.
+var ErrNotSupported = errors.New("method not supported")
+
+// ParamsError is returned by Dispatch for parameters that do not decode.
+type ParamsError struct {
+	Method string
+	Err    error
+}
+
+// ([error] interface)
+func (e *ParamsError) Error() string {
+	return fmt.Sprintf("invalid parameters of %s: %v", e.Method, e.Err)
+}
+
+// Unwrap returns the decoding error.
+func (e *ParamsError) Unwrap() error {
+	return e.Err
+}
+
+// Dispatch calls a provider for a plugin method with its encoded
+// parameters, returning the result to encode. It is the part of Serve
+// independent of the transport, for hosts running plugins other than as
+// processes.
+func Dispatch(provider any, method string, params json.RawMessage) (any, error) {
+	decode := func(v any) error {
+		if err := json.Unmarshal(params, v); err != nil {
+			return &ParamsError{Method: method, Err: err}
+		}
+		return nil
+	}
+
+	switch method {
+	case MethodInitialize:
+		return capabilitiesOf(provider), nil
+
+	case MethodDiagnostics:
+		if provider, ok := provider.(core.DiagnosticProvider); ok {
+			var document DocumentParams
+			if err := decode(&document); err != nil {
+				return nil, err
+			}
+			return provider.ProvideDiagnostics(document.URI, document.Content), nil
+		}
+
+	case MethodCompletion:
+		if provider, ok := provider.(core.CompletionProvider); ok {
+			var ctx core.CompletionContext
+			if err := decode(&ctx); err != nil {
+				return nil, err
+			}
+			return provider.ProvideCompletions(ctx), nil
+		}
+
+	case MethodCodeFixes:
+		if provider, ok := provider.(core.CodeFixProvider); ok {
+			var ctx core.CodeFixContext
+			if err := decode(&ctx); err != nil {
+				return nil, err
+			}
+			return provider.ProvideCodeFixes(ctx), nil
+		}
+
+	case MethodHover:
+		if provider, ok := provider.(core.HoverProvider); ok {
+			var position PositionParams
+			if err := decode(&position); err != nil {
+				return nil, err
+			}
+			return provider.ProvideHover(position.URI, position.Content, position.Position), nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrNotSupported, method)
+}
+
+// stdio is the standard input and output of the process.
+type stdio struct{}
+
+// ([io.Reader] interface)
+func (stdio) Read(p []byte) (int, error) {
+	return os.Stdin.Read(p)
+}
+
+// ([io.Writer] interface)
+func (stdio) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+// ([io.Closer] interface)
+func (stdio) Close() error {
+	if err := os.Stdin.Close(); err != nil {
+		return err
+	}
+	return os.Stdout.Close()
+}
@@ -0,0 +1,76 @@
+//go:build wasip1
+
+// Package guest implements the ABI of package wasm for providers written
+// in Go. A plugin registers its provider from an init function, and is
+// built as a reactor module:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o plugin.wasm
+package guest
+
+import (
+	"encoding/json"
+	"unsafe"
+
+	"github.com/SCKelemen/lsp/plugin"
+)
+
+var (
+	// provider is the registered provider.
+	provider any
+
+	// buffers keep the buffers allocated for the host alive until
+	// lsp_call takes them, by address.
+	buffers = make(map[uint32][]byte)
+
+	// last keeps the last response alive until the next call.
+	last []byte
+)
+
+// response is wasm.Response, which is not imported to keep the runtime of
+// the host out of plugins.
+type response struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Register sets the provider the plugin serves, implementing any of
+// core.DiagnosticProvider, core.CompletionProvider, core.CodeFixProvider,
+// and core.HoverProvider.
+func Register(p any) {
+	provider = p
+}
+
+//go:wasmexport lsp_alloc
+func alloc(size uint32) uint32 {
+	if size == 0 {
+		size = 1
+	}
+	buffer := make([]byte, size)
+	ptr := uint32(uintptr(unsafe.Pointer(&buffer[0])))
+	buffers[ptr] = buffer
+	return ptr
+}
+
+//go:wasmexport lsp_call
+func call(methodPtr, methodLen, paramsPtr, paramsLen uint32) uint64 {
+	method := string(take(methodPtr, methodLen))
+	params := take(paramsPtr, paramsLen)
+
+	var result response
+	value, err := plugin.Dispatch(provider, method, params)
+	if err == nil {
+		result.Result, err = json.Marshal(value)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	last, _ = json.Marshal(result)
+	return uint64(uint32(uintptr(unsafe.Pointer(&last[0]))))<<32 | uint64(len(last))
+}
+
+// take returns the allocated buffer at ptr, handing it over to the caller.
+func take(ptr, length uint32) []byte {
+	buffer := buffers[ptr]
+	delete(buffers, ptr)
+	return buffer[:length]
+}
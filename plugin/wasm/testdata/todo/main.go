@@ -0,0 +1,49 @@
+//go:build wasip1
+
+// Command todo is a plugin reporting TODO comments, for the tests of
+// package wasm.
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/plugin/wasm/guest"
+)
+
+type provider struct{}
+
+func (provider) ProvideDiagnostics(uri, content string) []core.Diagnostic {
+	var diagnostics []core.Diagnostic
+	for line, text := range strings.Split(content, "\n") {
+		if column := strings.Index(text, "TODO"); column >= 0 {
+			diagnostics = append(diagnostics, core.Diagnostic{
+				Range:   core.Range{Start: core.Position{Line: line, Character: column}, End: core.Position{Line: line, Character: column + 4}},
+				Message: "TODO",
+			})
+		}
+	}
+	return diagnostics
+}
+
+// ProvideHover shows the file named by the content of the document.
+func (provider) ProvideHover(uri, content string, position core.Position) *core.HoverInfo {
+	data, err := os.ReadFile(content)
+	if err != nil {
+		return &core.HoverInfo{Contents: "error: " + err.Error()}
+	}
+	return &core.HoverInfo{Contents: string(data)}
+}
+
+// ProvideCompletions never returns.
+func (provider) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
+	for {
+	}
+}
+
+func init() {
+	guest.Register(provider{})
+}
+
+func main() {}
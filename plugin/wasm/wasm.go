@@ -0,0 +1,293 @@
+// Package wasm runs providers compiled to WebAssembly inside the server
+// process, as an alternative to plugin processes that needs no separate
+// executable per platform.
+//
+// A plugin is a WASI module sandboxed by the runtime: it has no network,
+// no environment, and no files but the host directories mounted read-only
+// into it by Plugin.Mounts. Its calls run under a timeout, and a plugin
+// that traps or times out is instantiated again on the next call.
+//
+// The ABI mirrors the methods and parameters of package plugin. The module
+// exports its memory, an allocator, and a single entry point:
+//
+//	lsp_alloc(size i32) i32
+//	lsp_call(method_ptr i32, method_len i32, params_ptr i32, params_len i32) i64
+//
+// The host allocates buffers with lsp_alloc for the method name, like
+// "plugin/diagnostics", and its JSON parameters, and calls lsp_call, which
+// owns the buffers from then on. lsp_call returns the address of its JSON
+// response in the upper 32 bits and its length in the lower 32, the
+// response being valid until the next call. Reactor modules, like Go
+// programs built with -buildmode=c-shared, are initialized by their
+// _initialize export. Package guest implements the ABI for Go plugins.
+package wasm
+
+import (
+	contextpkg "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/plugin"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tliron/commonlog"
+)
+
+const (
+	// ExportAlloc is the name of the allocator export of a plugin.
+	ExportAlloc = "lsp_alloc"
+
+	// ExportCall is the name of the entry point export of a plugin.
+	ExportCall = "lsp_call"
+)
+
+// DefaultMemoryLimitPages is the memory limit of a plugin by default, in
+// 64 KiB pages: 256 MiB.
+const DefaultMemoryLimitPages = 4096
+
+// Response is the JSON response of lsp_call: the result of the method, or
+// an error.
+type Response struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Plugin is a provider compiled to WebAssembly, implementing the core
+// provider interfaces by calling it. Like plugin.Plugin, a call that fails
+// or does not return within Timeout is logged and returns no results, and
+// methods the plugin does not support return no results without calling
+// it. The module is compiled and instantiated on first use. Calls are
+// serialized, as an instance runs one at a time. It is safe for concurrent
+// use.
+type Plugin struct {
+	// Name names the plugin in logs.
+	Name string
+
+	// Binary is the WebAssembly module.
+	Binary []byte
+
+	// Mounts are the host directories the plugin can read, by their path
+	// in the plugin, like "/workspace". Set them before first use.
+	Mounts map[string]string
+
+	// Timeout is how long a call may run.
+	Timeout time.Duration
+
+	// MemoryLimitPages is the most memory the plugin may use, in 64 KiB
+	// pages.
+	MemoryLimitPages uint32
+
+	Log commonlog.Logger
+
+	mu           sync.Mutex
+	runtime      wazero.Runtime
+	compiled     wazero.CompiledModule
+	module       api.Module // nil until instantiated, or after a failure
+	capabilities plugin.Capabilities
+	closed       bool
+}
+
+// NewPlugin creates a plugin running the WebAssembly module binary.
+func NewPlugin(name string, binary []byte) *Plugin {
+	return &Plugin{
+		Name:             name,
+		Binary:           binary,
+		Timeout:          plugin.DefaultTimeout,
+		MemoryLimitPages: DefaultMemoryLimitPages,
+		Log:              commonlog.GetLogger("plugin.wasm"),
+	}
+}
+
+// Capabilities returns the capabilities of the plugin, instantiating it if
+// needed.
+func (self *Plugin) Capabilities() (plugin.Capabilities, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if err := self.instantiate(); err != nil {
+		return plugin.Capabilities{}, err
+	}
+	return self.capabilities, nil
+}
+
+// Close releases the runtime of the plugin. Calls afterwards return no
+// results.
+func (self *Plugin) Close() error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.closed = true
+	self.module = nil
+	if self.runtime == nil {
+		return nil
+	}
+	err := self.runtime.Close(contextpkg.Background())
+	self.runtime = nil
+	return err
+}
+
+// ([core.DiagnosticProvider] interface)
+func (self *Plugin) ProvideDiagnostics(uri, content string) []core.Diagnostic {
+	var diagnostics []core.Diagnostic
+	self.call(func(c plugin.Capabilities) bool { return c.Diagnostics }, plugin.MethodDiagnostics, plugin.DocumentParams{URI: uri, Content: content}, &diagnostics)
+	return diagnostics
+}
+
+// ([core.CompletionProvider] interface)
+func (self *Plugin) ProvideCompletions(ctx core.CompletionContext) *core.CompletionList {
+	var list *core.CompletionList
+	self.call(func(c plugin.Capabilities) bool { return c.Completion }, plugin.MethodCompletion, ctx, &list)
+	return list
+}
+
+// ([core.CodeFixProvider] interface)
+func (self *Plugin) ProvideCodeFixes(ctx core.CodeFixContext) []core.CodeAction {
+	var actions []core.CodeAction
+	self.call(func(c plugin.Capabilities) bool { return c.CodeFixes }, plugin.MethodCodeFixes, ctx, &actions)
+	return actions
+}
+
+// ([core.HoverProvider] interface)
+func (self *Plugin) ProvideHover(uri, content string, position core.Position) *core.HoverInfo {
+	var hover *core.HoverInfo
+	self.call(func(c plugin.Capabilities) bool { return c.Hover }, plugin.MethodHover, plugin.PositionParams{URI: uri, Content: content, Position: position}, &hover)
+	return hover
+}
+
+// call calls method if the plugin supports it, logging failures.
+func (self *Plugin) call(supports func(plugin.Capabilities) bool, method string, params any, result any) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if err := self.instantiate(); err != nil {
+		self.Log.Errorf("%s: %s", self.Name, err.Error())
+		return
+	}
+	if !supports(self.capabilities) {
+		return
+	}
+	if err := self.invoke(method, params, result); err != nil {
+		self.Log.Errorf("%s: %s", self.Name, err.Error())
+	}
+}
+
+// instantiate compiles and instantiates the module if it is not. It is
+// called with mu held.
+func (self *Plugin) instantiate() error {
+	if self.closed {
+		return errors.New("plugin closed")
+	}
+	if self.module != nil {
+		return nil
+	}
+
+	context := contextpkg.Background()
+	if self.runtime == nil {
+		config := wazero.NewRuntimeConfig().
+			WithCloseOnContextDone(true).
+			WithMemoryLimitPages(self.MemoryLimitPages)
+		runtime := wazero.NewRuntimeWithConfig(context, config)
+		if _, err := wasi_snapshot_preview1.Instantiate(context, runtime); err != nil {
+			runtime.Close(context)
+			return err
+		}
+		compiled, err := runtime.CompileModule(context, self.Binary)
+		if err != nil {
+			runtime.Close(context)
+			return fmt.Errorf("compiling: %w", err)
+		}
+		self.runtime, self.compiled = runtime, compiled
+	}
+
+	fsConfig := wazero.NewFSConfig()
+	for guestPath, hostDir := range self.Mounts {
+		fsConfig = fsConfig.WithReadOnlyDirMount(hostDir, guestPath)
+	}
+	config := wazero.NewModuleConfig().
+		WithName(""). // anonymous, so that a new instance can replace a failed one
+		WithStartFunctions("_initialize").
+		WithFSConfig(fsConfig)
+	module, err := self.runtime.InstantiateModule(context, self.compiled, config)
+	if err != nil {
+		return fmt.Errorf("instantiating: %w", err)
+	}
+	for _, name := range []string{ExportAlloc, ExportCall} {
+		if module.ExportedFunction(name) == nil {
+			module.Close(context)
+			return fmt.Errorf("module does not export %s", name)
+		}
+	}
+	self.module = module
+
+	if err := self.invoke(plugin.MethodInitialize, nil, &self.capabilities); err != nil {
+		return fmt.Errorf("initializing: %w", err)
+	}
+	return nil
+}
+
+// invoke calls method through lsp_call. A failed instance is dropped, to
+// be instantiated again by the next call. It is called with mu held.
+func (self *Plugin) invoke(method string, params any, result any) (err error) {
+	module := self.module
+	defer func() {
+		if err != nil && self.module == module {
+			module.Close(contextpkg.Background())
+			self.module = nil
+		}
+	}()
+
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	context, cancel := contextpkg.WithTimeout(contextpkg.Background(), self.Timeout)
+	defer cancel()
+
+	methodPtr, err := self.write(context, []byte(method))
+	if err != nil {
+		return err
+	}
+	paramsPtr, err := self.write(context, encoded)
+	if err != nil {
+		return err
+	}
+	results, err := module.ExportedFunction(ExportCall).Call(context, uint64(methodPtr), uint64(len(method)), uint64(paramsPtr), uint64(len(encoded)))
+	if err != nil {
+		if errors.Is(context.Err(), contextpkg.DeadlineExceeded) {
+			return fmt.Errorf("%s timed out after %s", method, self.Timeout)
+		}
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	responsePtr, responseLen := uint32(results[0]>>32), uint32(results[0])
+	data, ok := module.Memory().Read(responsePtr, responseLen)
+	if !ok {
+		return fmt.Errorf("%s: response out of memory bounds", method)
+	}
+	var response Response
+	if err := json.Unmarshal(data, &response); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if response.Error != "" {
+		return fmt.Errorf("%s: %s", method, response.Error)
+	}
+	if len(response.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(response.Result, result)
+}
+
+// write copies data into a buffer allocated by the module.
+func (self *Plugin) write(context contextpkg.Context, data []byte) (uint32, error) {
+	results, err := self.module.ExportedFunction(ExportAlloc).Call(context, uint64(len(data)))
+	if err != nil {
+		return 0, fmt.Errorf("allocating: %w", err)
+	}
+	ptr := uint32(results[0])
+	if !self.module.Memory().Write(ptr, data) {
+		return 0, errors.New("allocated buffer out of memory bounds")
+	}
+	return ptr, nil
+}
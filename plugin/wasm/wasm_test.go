@@ -0,0 +1,102 @@
+package wasm
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SCKelemen/lsp/core"
+	"github.com/SCKelemen/lsp/plugin"
+)
+
+// buildPlugin builds the plugin in testdata/name to WebAssembly.
+func buildPlugin(t *testing.T, name string) []byte {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("building a plugin is slow")
+	}
+	goCommand, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	output := filepath.Join(t.TempDir(), name+".wasm")
+	command := exec.Command(goCommand, "build", "-buildmode=c-shared", "-o", output, "./testdata/"+name)
+	command.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := command.CombinedOutput(); err != nil {
+		t.Fatalf("building %s: %v\n%s", name, err, out)
+	}
+	binary, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return binary
+}
+
+func TestPlugin(t *testing.T) {
+	config := t.TempDir()
+	if err := os.WriteFile(filepath.Join(config, "words.txt"), []byte("TODO FIXME"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(secret, []byte("password"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPlugin("todo", buildPlugin(t, "todo"))
+	p.Mounts = map[string]string{"/config": config}
+	p.Timeout = 5 * time.Second
+	t.Cleanup(func() { p.Close() })
+
+	capabilities, err := p.Capabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capabilities != (plugin.Capabilities{Diagnostics: true, Completion: true, Hover: true}) {
+		t.Errorf("capabilities = %+v", capabilities)
+	}
+
+	diagnostics := p.ProvideDiagnostics("file:///a.go", "package a\n\n// TODO: test\n")
+	if len(diagnostics) != 1 || diagnostics[0].Range.Start != (core.Position{Line: 2, Character: 3}) {
+		t.Errorf("diagnostics = %+v, want the TODO", diagnostics)
+	}
+	if actions := p.ProvideCodeFixes(core.CodeFixContext{URI: "file:///a.go"}); actions != nil {
+		t.Errorf("code fixes of a plugin without them = %+v", actions)
+	}
+
+	// The plugin reads the mounted directories, and nothing else
+	if hover := p.ProvideHover("file:///a.go", "/config/words.txt", core.Position{}); hover == nil || hover.Contents != "TODO FIXME" {
+		t.Errorf("hover of a mounted file = %+v", hover)
+	}
+	if hover := p.ProvideHover("file:///a.go", secret, core.Position{}); hover == nil || !strings.HasPrefix(hover.Contents, "error: ") {
+		t.Errorf("hover of a file outside the mounts = %+v, want an error", hover)
+	}
+
+	// A call running past the timeout is stopped, and the plugin
+	// instantiated again
+	p.Timeout = 200 * time.Millisecond
+	start := time.Now()
+	if completions := p.ProvideCompletions(core.CompletionContext{URI: "file:///a.go"}); completions != nil {
+		t.Errorf("completions of a hanging plugin = %+v", completions)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("waited %s for a hanging plugin", elapsed)
+	}
+	p.Timeout = 5 * time.Second
+	if diagnostics := p.ProvideDiagnostics("file:///a.go", "// TODO"); len(diagnostics) != 1 {
+		t.Errorf("diagnostics after a timeout = %+v", diagnostics)
+	}
+}
+
+func TestPluginInvalidModule(t *testing.T) {
+	p := NewPlugin("invalid", []byte("not wasm"))
+	t.Cleanup(func() { p.Close() })
+	if _, err := p.Capabilities(); err == nil {
+		t.Error("expected an error for an invalid module")
+	}
+	if diagnostics := p.ProvideDiagnostics("file:///a.go", "// TODO"); diagnostics != nil {
+		t.Errorf("diagnostics of an invalid module = %+v", diagnostics)
+	}
+}
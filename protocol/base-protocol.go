@@ -12,6 +12,30 @@ var False bool = false
 
 type Method = string
 
+/**
+ * The methods of the notifications a client sends to a server. Handlers
+ * see notifications and requests alike; this tells them apart.
+ */
+var ClientNotifications = map[Method]bool{
+	MethodInitialized:                        true,
+	MethodExit:                               true,
+	MethodCancelRequest:                      true,
+	MethodProgress:                           true,
+	MethodSetTrace:                           true,
+	MethodTextDocumentDidOpen:                true,
+	MethodTextDocumentDidChange:              true,
+	MethodTextDocumentWillSave:               true,
+	MethodTextDocumentDidSave:                true,
+	MethodTextDocumentDidClose:               true,
+	MethodWindowWorkDoneProgressCancel:       true,
+	MethodWorkspaceDidChangeWorkspaceFolders: true,
+	MethodWorkspaceDidChangeConfiguration:    true,
+	MethodWorkspaceDidChangeWatchedFiles:     true,
+	MethodWorkspaceDidCreateFiles:            true,
+	MethodWorkspaceDidRenameFiles:            true,
+	MethodWorkspaceDidDeleteFiles:            true,
+}
+
 // https://microsoft.github.io/language-server-protocol/specifications/specification-3-16#number
 
 /**
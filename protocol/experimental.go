@@ -0,0 +1,110 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/SCKelemen/lsp"
+)
+
+// standardMethodPrefixes are the namespaces of the methods of the
+// specification, which custom methods cannot use.
+var standardMethodPrefixes = []string{
+	"textDocument/", "workspace/", "window/", "client/", "notebookDocument/", "telemetry/",
+	"completionItem/", "codeAction/", "codeLens/", "documentLink/", "inlayHint/",
+	"callHierarchy/", "typeHierarchy/", "workspaceSymbol/",
+}
+
+// standardMethods are the methods of the specification outside those
+// namespaces.
+var standardMethods = map[Method]bool{
+	MethodInitialize:    true,
+	MethodInitialized:   true,
+	MethodShutdown:      true,
+	MethodExit:          true,
+	MethodCancelRequest: true,
+	MethodProgress:      true,
+	MethodLogTrace:      true,
+	MethodSetTrace:      true,
+}
+
+// IsCustomMethod reports whether method is outside the specification, like
+// "$/memoryUsage" or the vendor prefixed "rust-analyzer/expandMacro".
+func IsCustomMethod(method Method) bool {
+	if standardMethods[method] {
+		return false
+	}
+	for _, prefix := range standardMethodPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// DeclareExperimental declares an experimental capability of the server,
+// announced under capabilities.experimental by CreateServerCapabilities.
+func (self *Handler) DeclareExperimental(name string, value any) {
+	if self.Experimental == nil {
+		self.Experimental = make(map[string]any)
+	}
+	self.Experimental[name] = value
+}
+
+// HandleCustomRequest routes a custom request to a function with typed
+// params and result. Missing or null params decode as the zero value of P,
+// and params that do not decode fail the request. It fails for methods of
+// the specification.
+func HandleCustomRequest[P any, R any](handler *Handler, method Method, f func(context *lsp.Context, params *P) (R, error)) error {
+	if !IsCustomMethod(method) {
+		return fmt.Errorf("%s is a method of the specification", method)
+	}
+	if handler.CustomRequest == nil {
+		handler.CustomRequest = make(map[string]CustomRequestHandler)
+	}
+	handler.CustomRequest[method] = CustomRequestHandler{
+		Func: func(context *lsp.Context, raw json.RawMessage) (any, error) {
+			var params P
+			if len(raw) > 0 && string(raw) != "null" {
+				if err := json.Unmarshal(raw, &params); err != nil {
+					return nil, err
+				}
+			}
+			return f(context, &params)
+		},
+	}
+	return nil
+}
+
+// HandleCustomNotification routes a custom notification to a function with
+// typed params.
+func HandleCustomNotification[P any](handler *Handler, method Method, f func(context *lsp.Context, params *P) error) error {
+	return HandleCustomRequest(handler, method, func(context *lsp.Context, params *P) (any, error) {
+		return nil, f(context, params)
+	})
+}
+
+// ClientExperimental decodes the experimental capability name of a client
+// into v, and reports whether the client declared it.
+func ClientExperimental(capabilities *ClientCapabilities, name string, v any) bool {
+	if capabilities == nil || capabilities.Experimental == nil {
+		return false
+	}
+	encoded, err := json.Marshal(capabilities.Experimental)
+	if err != nil {
+		return false
+	}
+	var experimental map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &experimental); err != nil {
+		return false
+	}
+	value, ok := experimental[name]
+	if !ok {
+		return false
+	}
+	if v != nil && json.Unmarshal(value, v) != nil {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package protocol
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/SCKelemen/lsp"
+)
+
+type expandMacroParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type expandedMacro struct {
+	Name      string `json:"name"`
+	Expansion string `json:"expansion"`
+}
+
+func TestHandleCustomRequest(t *testing.T) {
+	handler := &Handler{}
+	handler.SetInitialized(true)
+	handler.DeclareExperimental("expandMacro", true)
+
+	err := HandleCustomRequest(handler, "rust-analyzer/expandMacro", func(context *lsp.Context, params *expandMacroParams) (*expandedMacro, error) {
+		return &expandedMacro{Name: params.TextDocument.URI, Expansion: "line"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloads := 0
+	err = HandleCustomNotification(handler, "$/reloadWorkspace", func(context *lsp.Context, params *struct{}) error {
+		reloads++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := HandleCustomRequest(handler, "textDocument/hover", func(*lsp.Context, *struct{}) (any, error) { return nil, nil }); err == nil {
+		t.Error("expected an error for a method of the specification")
+	}
+
+	result, validMethod, validParams, err := handler.Handle(&lsp.Context{
+		Method: "rust-analyzer/expandMacro",
+		Params: json.RawMessage(`{"textDocument":{"uri":"file:///lib.rs"},"position":{"line":1,"character":2}}`),
+	})
+	if !validMethod || !validParams || err != nil {
+		t.Fatalf("valid method %t, valid params %t, error %v", validMethod, validParams, err)
+	}
+	if expanded, ok := result.(*expandedMacro); !ok || expanded.Name != "file:///lib.rs" {
+		t.Errorf("result = %#v", result)
+	}
+
+	// Without params
+	if _, validMethod, validParams, err := handler.Handle(&lsp.Context{Method: "$/reloadWorkspace"}); !validMethod || !validParams || err != nil || reloads != 1 {
+		t.Errorf("notification: valid method %t, valid params %t, error %v, reloads %d", validMethod, validParams, err, reloads)
+	}
+
+	// Params of the wrong type
+	if _, _, _, err := handler.Handle(&lsp.Context{Method: "rust-analyzer/expandMacro", Params: json.RawMessage(`[]`)}); err == nil {
+		t.Error("expected an error")
+	}
+
+	capabilities := handler.CreateServerCapabilities()
+	if experimental, ok := capabilities.Experimental.(map[string]any); !ok || experimental["expandMacro"] != true {
+		t.Errorf("experimental capabilities = %#v", capabilities.Experimental)
+	}
+}
+
+func TestClientExperimental(t *testing.T) {
+	var capabilities ClientCapabilities
+	if err := json.Unmarshal([]byte(`{"experimental":{"snippetTextEdit":true,"commands":{"commands":["run"]}}}`), &capabilities); err != nil {
+		t.Fatal(err)
+	}
+
+	var snippets bool
+	if !ClientExperimental(&capabilities, "snippetTextEdit", &snippets) || !snippets {
+		t.Error("expected snippetTextEdit")
+	}
+	var commands struct {
+		Commands []string `json:"commands"`
+	}
+	if !ClientExperimental(&capabilities, "commands", &commands) || len(commands.Commands) != 1 {
+		t.Errorf("commands = %+v", commands)
+	}
+	if ClientExperimental(&capabilities, "missing", nil) {
+		t.Error("expected a missing capability")
+	}
+}
@@ -319,6 +319,20 @@ type ClientCapabilities struct {
 		 */
 		CodeLens *CodeLensWorkspaceClientCapabilities `json:"codeLens,omitempty"`
 
+		/**
+		 * Client workspace capabilities specific to inlay hints.
+		 *
+		 * @since 3.17.0
+		 */
+		InlayHint *InlayHintWorkspaceClientCapabilities `json:"inlayHint,omitempty"`
+
+		/**
+		 * Client workspace capabilities specific to diagnostics.
+		 *
+		 * @since 3.17.0
+		 */
+		Diagnostics *DiagnosticWorkspaceClientCapabilities `json:"diagnostics,omitempty"`
+
 		/**
 		 * The client has support for file requests/notifications.
 		 *
@@ -361,6 +375,13 @@ type ClientCapabilities struct {
 			 */
 			WillDelete *bool `json:"willDelete,omitempty"`
 		} `json:"fileOperations,omitempty"`
+
+		/**
+		 * Capabilities specific to the `workspace/textDocumentContent` request.
+		 *
+		 * @since 3.18.0
+		 */
+		TextDocumentContent *TextDocumentContentClientCapabilities `json:"textDocumentContent,omitempty"`
 	} `json:"workspace,omitempty"`
 
 	/**
@@ -680,6 +701,13 @@ type ServerCapabilitiesWorkspace struct {
 	 * @since 3.16.0
 	 */
 	FileOperations *ServerCapabilitiesWorkspaceFileOperations `json:"fileOperations,omitempty"`
+
+	/**
+	 * The server supports the `workspace/textDocumentContent` request.
+	 *
+	 * @since 3.18.0
+	 */
+	TextDocumentContent any `json:"textDocumentContent,omitempty"` // TextDocumentContentOptions | TextDocumentContentRegistrationOptions
 }
 
 type ServerCapabilitiesWorkspaceFileOperations struct {
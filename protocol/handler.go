@@ -37,6 +37,7 @@ type Handler struct {
 	WorkspaceWillDeleteFiles           WorkspaceWillDeleteFilesFunc
 	WorkspaceDidDeleteFiles            WorkspaceDidDeleteFilesFunc
 	WorkspaceSemanticTokensRefresh     WorkspaceSemanticTokensRefreshFunc
+	WorkspaceTextDocumentContent       WorkspaceTextDocumentContentFunc
 
 	// Text Document Synchronization
 	TextDocumentDidOpen           TextDocumentDidOpenFunc
@@ -85,6 +86,13 @@ type Handler struct {
 	// Custom Request/Notification
 	CustomRequest map[string]CustomRequestHandler
 
+	// Experimental capabilities, see DeclareExperimental
+	Experimental map[string]any
+
+	// ReadOnly, when set and returning true, refuses the requests of
+	// ReadOnlyMethods with ReadOnlyMessage, which clients show to the user
+	ReadOnly func() bool
+
 	initialized bool
 	lock        sync.Mutex
 }
@@ -94,6 +102,9 @@ func (self *Handler) Handle(context *lsp.Context) (r any, validMethod bool, vali
 	if !self.IsInitialized() && (context.Method != MethodInitialize) {
 		return nil, true, true, errors.New("server not initialized")
 	}
+	if ReadOnlyMethods[context.Method] && self.ReadOnly != nil && self.ReadOnly() {
+		return nil, true, true, errors.New(ReadOnlyMessage)
+	}
 
 	switch context.Method {
 	// Base Protocol
@@ -232,6 +243,16 @@ func (self *Handler) Handle(context *lsp.Context) (r any, validMethod bool, vali
 			}
 		}
 
+	case MethodWorkspaceTextDocumentContent:
+		if self.WorkspaceTextDocumentContent != nil {
+			validMethod = true
+			var params TextDocumentContentParams
+			if err = json.Unmarshal(context.Params, &params); err == nil {
+				validParams = true
+				r, err = self.WorkspaceTextDocumentContent(context, &params)
+			}
+		}
+
 	case MethodWorkspaceExecuteCommand:
 		if self.WorkspaceExecuteCommand != nil {
 			validMethod = true
@@ -717,7 +738,11 @@ func (self *Handler) Handle(context *lsp.Context) (r any, validMethod bool, vali
 		if self.CustomRequest != nil {
 			if handler, ok := self.CustomRequest[context.Method]; ok && (handler.Func != nil) {
 				validMethod = true
-				if err = json.Unmarshal(context.Params, &handler.Params); err == nil {
+				if len(context.Params) == 0 {
+					// Params are optional
+					validParams = true
+					r, err = handler.Func(context, nil)
+				} else if err = json.Unmarshal(context.Params, &handler.Params); err == nil {
 					validParams = true
 					r, err = handler.Func(context, handler.Params)
 				}
@@ -901,6 +926,17 @@ func (self *Handler) CreateServerCapabilities() ServerCapabilities {
 		capabilities.WorkspaceSymbolProvider = true
 	}
 
+	if self.WorkspaceTextDocumentContent != nil {
+		if capabilities.Workspace == nil {
+			capabilities.Workspace = &ServerCapabilitiesWorkspace{}
+		}
+		// The handler does not know the schemes: replace these options, as
+		// with adapter.CoreToProtocolTextDocumentContentOptions
+		capabilities.Workspace.TextDocumentContent = &TextDocumentContentOptions{
+			Schemes: []string{},
+		}
+	}
+
 	if self.WorkspaceDidCreateFiles != nil {
 		if capabilities.Workspace == nil {
 			capabilities.Workspace = &ServerCapabilitiesWorkspace{}
@@ -975,5 +1011,9 @@ func (self *Handler) CreateServerCapabilities() ServerCapabilities {
 		}
 	}
 
+	if len(self.Experimental) > 0 {
+		capabilities.Experimental = self.Experimental
+	}
+
 	return capabilities
 }
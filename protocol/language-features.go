@@ -97,6 +97,14 @@ type CompletionClientCapabilities struct {
 		InsertTextModeSupport *struct {
 			ValueSet []InsertTextMode `json:"valueSet"`
 		} `json:"insertTextModeSupport,omitempty"`
+
+		/**
+		 * The client has support for completion item label
+		 * details (see also `CompletionItemLabelDetails`).
+		 *
+		 * @since 3.17.0
+		 */
+		LabelDetailsSupport *bool `json:"labelDetailsSupport,omitempty"`
 	} `json:"completionItem,omitempty"`
 
 	CompletionItemKind *struct {
@@ -158,6 +166,23 @@ type CompletionOptions struct {
 	 * information for a completion item.
 	 */
 	ResolveProvider *bool `json:"resolveProvider,omitempty"`
+
+	/**
+	 * The server supports the following `CompletionItem` specific
+	 * capabilities.
+	 *
+	 * @since 3.17.0
+	 */
+	CompletionItem *struct {
+		/**
+		 * The server has support for completion item label
+		 * details (see also `CompletionItemLabelDetails`) when receiving
+		 * a completion item in a resolve call.
+		 *
+		 * @since 3.17.0
+		 */
+		LabelDetailsSupport *bool `json:"labelDetailsSupport,omitempty"`
+	} `json:"completionItem,omitempty"`
 }
 
 type CompletionRegistrationOptions struct {
@@ -408,6 +433,13 @@ type CompletionItem struct {
 	 */
 	Label string `json:"label"`
 
+	/**
+	 * Additional details for the label
+	 *
+	 * @since 3.17.0
+	 */
+	LabelDetails *CompletionItemLabelDetails `json:"labelDetails,omitempty"`
+
 	/**
 	 * The kind of this completion item. Based of the kind
 	 * an icon is chosen by the editor. The standardized set
@@ -553,27 +585,29 @@ type CompletionItem struct {
 // ([json.Unmarshaler] interface)
 func (self *CompletionItem) UnmarshalJSON(data []byte) error {
 	var value struct {
-		Label               string              `json:"label"`
-		Kind                *CompletionItemKind `json:"kind,omitempty"`
-		Tags                []CompletionItemTag `json:"tags,omitempty"`
-		Detail              *string             `json:"detail,omitempty"`
-		Documentation       json.RawMessage     `json:"documentation,omitempty"` // nil | string | MarkupContent
-		Deprecated          *bool               `json:"deprecated,omitempty"`
-		Preselect           *bool               `json:"preselect,omitempty"`
-		SortText            *string             `json:"sortText,omitempty"`
-		FilterText          *string             `json:"filterText,omitempty"`
-		InsertText          *string             `json:"insertText,omitempty"`
-		InsertTextFormat    *InsertTextFormat   `json:"insertTextFormat,omitempty"`
-		InsertTextMode      *InsertTextMode     `json:"insertTextMode,omitempty"`
-		TextEdit            json.RawMessage     `json:"textEdit,omitempty"` // nil | TextEdit | InsertReplaceEdit
-		AdditionalTextEdits []TextEdit          `json:"additionalTextEdits,omitempty"`
-		CommitCharacters    []string            `json:"commitCharacters,omitempty"`
-		Command             *Command            `json:"command,omitempty"`
-		Data                any                 `json:"data,omitempty"`
+		Label               string                      `json:"label"`
+		LabelDetails        *CompletionItemLabelDetails `json:"labelDetails,omitempty"`
+		Kind                *CompletionItemKind         `json:"kind,omitempty"`
+		Tags                []CompletionItemTag         `json:"tags,omitempty"`
+		Detail              *string                     `json:"detail,omitempty"`
+		Documentation       json.RawMessage             `json:"documentation,omitempty"` // nil | string | MarkupContent
+		Deprecated          *bool                       `json:"deprecated,omitempty"`
+		Preselect           *bool                       `json:"preselect,omitempty"`
+		SortText            *string                     `json:"sortText,omitempty"`
+		FilterText          *string                     `json:"filterText,omitempty"`
+		InsertText          *string                     `json:"insertText,omitempty"`
+		InsertTextFormat    *InsertTextFormat           `json:"insertTextFormat,omitempty"`
+		InsertTextMode      *InsertTextMode             `json:"insertTextMode,omitempty"`
+		TextEdit            json.RawMessage             `json:"textEdit,omitempty"` // nil | TextEdit | InsertReplaceEdit
+		AdditionalTextEdits []TextEdit                  `json:"additionalTextEdits,omitempty"`
+		CommitCharacters    []string                    `json:"commitCharacters,omitempty"`
+		Command             *Command                    `json:"command,omitempty"`
+		Data                any                         `json:"data,omitempty"`
 	}
 
 	if err := json.Unmarshal(data, &value); err == nil {
 		self.Label = value.Label
+		self.LabelDetails = value.LabelDetails
 		self.Kind = value.Kind
 		self.Tags = value.Tags
 		self.Detail = value.Detail
@@ -623,6 +657,27 @@ func (self *CompletionItem) UnmarshalJSON(data []byte) error {
 	}
 }
 
+/**
+ * Additional details for a completion item label.
+ *
+ * @since 3.17.0
+ */
+type CompletionItemLabelDetails struct {
+	/**
+	 * An optional string which is rendered less prominently directly after
+	 * {@link CompletionItem.label label}, without any spacing. Should be
+	 * used for function signatures or type annotations.
+	 */
+	Detail *string `json:"detail,omitempty"`
+
+	/**
+	 * An optional string which is rendered less prominently after
+	 * {@link CompletionItemLabelDetails.detail}. Should be used for fully qualified
+	 * names or file path.
+	 */
+	Description *string `json:"description,omitempty"`
+}
+
 /**
  * The kind of a completion entry.
  */
@@ -1396,6 +1451,26 @@ type InlayHintClientCapabilities struct {
 	} `json:"resolveSupport,omitempty"`
 }
 
+/**
+ * Client workspace capabilities specific to inlay hints.
+ *
+ * @since 3.17.0
+ */
+type InlayHintWorkspaceClientCapabilities struct {
+	/**
+	 * Whether the client implementation supports a refresh request sent from
+	 * the server to the client.
+	 *
+	 * Note that this event is global and will force the client to refresh all
+	 * inlay hints currently shown. It should be used with absolute care and
+	 * is useful for situation where a server for example detects a project wide
+	 * change that requires such a calculation.
+	 */
+	RefreshSupport *bool `json:"refreshSupport,omitempty"`
+}
+
+const ServerWorkspaceInlayHintRefresh = Method("workspace/inlayHint/refresh")
+
 type InlayHintOptions struct {
 	WorkDoneProgressOptions
 
@@ -2027,6 +2102,20 @@ const (
 	 */
 	CodeActionKindRefactorRewrite = CodeActionKind("refactor.rewrite")
 
+	/**
+	 * Base kind for refactoring move actions: `refactor.move`
+	 *
+	 * Example move actions:
+	 *
+	 * - Move a function to a new file
+	 * - Move a property between classes
+	 * - Move method to base class
+	 * - ...
+	 *
+	 * @since 3.18.0
+	 */
+	CodeActionKindRefactorMove = CodeActionKind("refactor.move")
+
 	/**
 	 * Base kind for source actions: `source`.
 	 *
@@ -2063,8 +2152,37 @@ type CodeActionContext struct {
 	 * shown. So servers can omit computing them.
 	 */
 	Only []CodeActionKind `json:"only,omitempty"`
+
+	/**
+	 * The reason why code actions were requested.
+	 *
+	 * @since 3.17.0
+	 */
+	TriggerKind *CodeActionTriggerKind `json:"triggerKind,omitempty"`
 }
 
+/**
+ * The reason why code actions were requested.
+ *
+ * @since 3.17.0
+ */
+type CodeActionTriggerKind Integer
+
+const (
+	/**
+	 * Code actions were explicitly requested by the user or by an extension.
+	 */
+	CodeActionTriggerKindInvoked = CodeActionTriggerKind(1)
+
+	/**
+	 * Code actions were requested automatically.
+	 *
+	 * This typically happens when current selection in a file changes, but can
+	 * also be triggered when file content changes.
+	 */
+	CodeActionTriggerKindAutomatic = CodeActionTriggerKind(2)
+)
+
 /**
  * A code action represents a change that can be performed in code, e.g. to fix
  * a problem or to refactor code.
@@ -2766,6 +2884,21 @@ type FoldingRangeClientCapabilities struct {
 	 */
 	LineFoldingOnly *bool `json:"lineFoldingOnly,omitempty"`
 
+	/**
+	 * Specific options for the folding range.
+	 *
+	 * @since 3.17.0
+	 */
+	FoldingRange *struct {
+		/**
+		 * If set, the client signals that it supports setting collapsedText on
+		 * folding ranges to display custom labels instead of the default text.
+		 *
+		 * @since 3.17.0
+		 */
+		CollapsedText *bool `json:"collapsedText,omitempty"`
+	} `json:"foldingRange,omitempty"`
+
 	/**
 	 * Whether the client supports sending a refresh request for folding ranges.
 	 *
@@ -2859,6 +2992,15 @@ type FoldingRange struct {
 	 * enumeration of standardized kinds.
 	 */
 	Kind *string `json:"kind,omitempty"`
+
+	/**
+	 * The text that the client should show when the specified range is
+	 * collapsed. If not defined or not supported by the client, a default
+	 * will be chosen by the client.
+	 *
+	 * @since 3.17.0
+	 */
+	CollapsedText *string `json:"collapsedText,omitempty"`
 }
 
 // https://microsoft.github.io/language-server-protocol/specifications/specification-3-16#textDocument_selectionRange
@@ -0,0 +1,182 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/SCKelemen/lsp"
+)
+
+// OptionsValidator is implemented by options types that check their
+// values after decoding.
+type OptionsValidator interface {
+	Validate() error
+}
+
+// OptionsMigration upgrades options written for an older schema, such as
+// options renamed since.
+type OptionsMigration struct {
+	// Version is the schema version the migration upgrades to. Options
+	// declaring this version or a later one are not migrated.
+	Version int
+
+	// Migrate updates the options in place and describes each change for
+	// the user, e.g. "option a was renamed to b".
+	Migrate func(options map[string]any) []string
+}
+
+// RenameOption returns a migration moving an option from one dotted path,
+// like "format.tabs", to another.
+func RenameOption(version int, from, to string) OptionsMigration {
+	return OptionsMigration{
+		Version: version,
+		Migrate: func(options map[string]any) []string {
+			value, ok := removeOption(options, from)
+			if !ok {
+				return nil
+			}
+			if _, exists := lookupOption(options, to); !exists {
+				setOption(options, to, value)
+			}
+			return []string{fmt.Sprintf("option %q was renamed to %q", from, to)}
+		},
+	}
+}
+
+// InitializationOptions decodes the user provided initialization options of
+// a server, or its settings from workspace/didChangeConfiguration, into T.
+//
+// Options missing from the client's keep their value in Defaults. Before
+// decoding, migrations bring options written for an older schema up to
+// date, so servers can rename options without breaking their users. The
+// user is told about migrated options with a warning, and about invalid
+// options, including those rejected by T's Validate method, with an error
+// message.
+type InitializationOptions[T any] struct {
+	Defaults T
+
+	// Migrations are applied in order.
+	Migrations []OptionsMigration
+
+	// VersionKey is the key of the schema version in the options. If it
+	// is empty or missing from the options, every migration is applied.
+	VersionKey string
+
+	// Strict rejects options unknown to T.
+	Strict bool
+}
+
+// Decode decodes options, like InitializeParams.InitializationOptions, and
+// shows the problems with them to the user with the Notify of context, if
+// set. If the options are invalid, it returns the defaults with the error.
+func (self InitializationOptions[T]) Decode(context *lsp.Context, options any) (T, error) {
+	decoded, warnings, err := self.decode(options)
+	notify := func(messageType MessageType, message string) {
+		if context != nil && context.Notify != nil {
+			context.Notify(ServerWindowShowMessage, ShowMessageParams{Type: messageType, Message: message})
+		}
+	}
+	for _, warning := range warnings {
+		notify(MessageTypeWarning, "Deprecated setting: "+warning)
+	}
+	if err != nil {
+		notify(MessageTypeError, "Invalid settings, using the defaults: "+err.Error())
+		return self.Defaults, err
+	}
+	return decoded, nil
+}
+
+func (self InitializationOptions[T]) decode(options any) (T, []string, error) {
+	// Decoding into a copy of the defaults keeps the missing options
+	decoded := self.Defaults
+	if options == nil {
+		return decoded, nil, nil
+	}
+
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		return self.Defaults, nil, err
+	}
+	var object map[string]any
+	if err := json.Unmarshal(encoded, &object); err != nil {
+		return self.Defaults, nil, errors.New("options must be an object")
+	}
+	if object == nil {
+		return decoded, nil, nil
+	}
+
+	var warnings []string
+	version := -1
+	if self.VersionKey != "" {
+		if value, ok := object[self.VersionKey].(float64); ok {
+			version = int(value)
+		}
+	}
+	for _, migration := range self.Migrations {
+		if version < 0 || version < migration.Version {
+			warnings = append(warnings, migration.Migrate(object)...)
+		}
+	}
+
+	if encoded, err = json.Marshal(object); err != nil {
+		return self.Defaults, warnings, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	if self.Strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(&decoded); err != nil {
+		return self.Defaults, warnings, err
+	}
+
+	if validator, ok := any(&decoded).(OptionsValidator); ok {
+		if err := validator.Validate(); err != nil {
+			return self.Defaults, warnings, err
+		}
+	}
+	return decoded, warnings, nil
+}
+
+func lookupOption(options map[string]any, path string) (any, bool) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := options[key].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		options = child
+	}
+	value, ok := options[keys[len(keys)-1]]
+	return value, ok
+}
+
+func removeOption(options map[string]any, path string) (any, bool) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := options[key].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		options = child
+	}
+	last := keys[len(keys)-1]
+	value, ok := options[last]
+	delete(options, last)
+	return value, ok
+}
+
+func setOption(options map[string]any, path string, value any) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := options[key].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			options[key] = child
+		}
+		options = child
+	}
+	options[keys[len(keys)-1]] = value
+}
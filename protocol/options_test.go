@@ -0,0 +1,77 @@
+package protocol
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/SCKelemen/lsp"
+)
+
+type testOptions struct {
+	TabSize int `json:"tabSize"`
+	Format  struct {
+		Gofumpt bool `json:"gofumpt"`
+	} `json:"format"`
+	Version int `json:"version"`
+}
+
+func (self testOptions) Validate() error {
+	if self.TabSize <= 0 {
+		return errors.New("tabSize must be positive")
+	}
+	return nil
+}
+
+func TestInitializationOptions(t *testing.T) {
+	defaults := testOptions{TabSize: 4}
+	schema := InitializationOptions[testOptions]{
+		Defaults:   defaults,
+		VersionKey: "version",
+		Migrations: []OptionsMigration{
+			RenameOption(2, "gofumpt", "format.gofumpt"),
+		},
+	}
+	gofumpt := defaults
+	gofumpt.Format.Gofumpt = true
+
+	tests := []struct {
+		name         string
+		schema       InitializationOptions[testOptions]
+		options      any
+		want         testOptions
+		wantErr      bool
+		wantMessages []MessageType
+	}{
+		{name: "missing", schema: schema, options: nil, want: defaults},
+		{name: "defaults kept", schema: schema, options: map[string]any{"format": map[string]any{"gofumpt": true}}, want: gofumpt},
+		{name: "renamed", schema: schema, options: map[string]any{"gofumpt": true}, want: gofumpt, wantMessages: []MessageType{MessageTypeWarning}},
+		{name: "current version not migrated", schema: schema, options: map[string]any{"gofumpt": true, "version": 2}, want: testOptions{TabSize: 4, Version: 2}},
+		{name: "invalid type", schema: schema, options: map[string]any{"tabSize": "four"}, want: defaults, wantErr: true, wantMessages: []MessageType{MessageTypeError}},
+		{name: "invalid value", schema: schema, options: map[string]any{"tabSize": 0}, want: defaults, wantErr: true, wantMessages: []MessageType{MessageTypeError}},
+		{name: "not an object", schema: schema, options: []any{1}, want: defaults, wantErr: true, wantMessages: []MessageType{MessageTypeError}},
+		{name: "unknown", schema: schema, options: map[string]any{"other": 1}, want: defaults},
+		{name: "unknown strict", schema: InitializationOptions[testOptions]{Defaults: defaults, Strict: true}, options: map[string]any{"other": 1}, want: defaults, wantErr: true, wantMessages: []MessageType{MessageTypeError}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var messages []MessageType
+			context := &lsp.Context{Notify: func(method string, params any) {
+				if method == ServerWindowShowMessage {
+					messages = append(messages, params.(ShowMessageParams).Type)
+				}
+			}}
+			got, err := tt.schema.Decode(context, tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(messages, tt.wantMessages) {
+				t.Errorf("messages = %v, want %v", messages, tt.wantMessages)
+			}
+		})
+	}
+}
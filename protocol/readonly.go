@@ -0,0 +1,39 @@
+package protocol
+
+// ReadOnlyOption is the key of the initialization option turning on
+// read-only mode, for deployments where users browse code but do not edit
+// it, like code review.
+const ReadOnlyOption = "readOnly"
+
+// ReadOnlyMessage is the error message of requests refused in read-only
+// mode.
+const ReadOnlyMessage = "The server is in read-only mode, so features that edit files are disabled"
+
+// ReadOnlyMethods are the methods of the requests a Handler refuses in
+// read-only mode: those returning edits, like rename, formatting, and the
+// edits made before saving or before files are created, renamed, or
+// deleted. Code actions are not refused, since many of them only navigate
+// or run commands; their providers disable the ones that edit instead.
+var ReadOnlyMethods = map[Method]bool{
+	MethodTextDocumentRename:            true,
+	MethodTextDocumentPrepareRename:     true,
+	MethodTextDocumentFormatting:        true,
+	MethodTextDocumentRangeFormatting:   true,
+	MethodTextDocumentOnTypeFormatting:  true,
+	MethodTextDocumentWillSaveWaitUntil: true,
+	MethodWorkspaceWillCreateFiles:      true,
+	MethodWorkspaceWillRenameFiles:      true,
+	MethodWorkspaceWillDeleteFiles:      true,
+}
+
+// InitialReadOnly returns whether read-only mode is on according to
+// initialization options, like InitializeParams.InitializationOptions, and
+// whether they declare it with ReadOnlyOption.
+func InitialReadOnly(options any) (readOnly bool, ok bool) {
+	object, isObject := options.(map[string]any)
+	if !isObject {
+		return false, false
+	}
+	readOnly, ok = object[ReadOnlyOption].(bool)
+	return readOnly, ok
+}
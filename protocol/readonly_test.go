@@ -0,0 +1,114 @@
+package protocol
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/SCKelemen/lsp"
+)
+
+func TestInitialReadOnly(t *testing.T) {
+	tests := []struct {
+		options      string
+		wantReadOnly bool
+		wantOK       bool
+	}{
+		{options: `{"readOnly":true}`, wantReadOnly: true, wantOK: true},
+		{options: `{"readOnly":false,"other":1}`, wantReadOnly: false, wantOK: true},
+		{options: `{"readOnly":1}`},
+		{options: `{}`},
+		{options: `null`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.options, func(t *testing.T) {
+			var params InitializeParams
+			if err := json.Unmarshal([]byte(`{"processId":null,"rootUri":null,"capabilities":{},"initializationOptions":`+tt.options+`}`), &params); err != nil {
+				t.Fatal(err)
+			}
+			readOnly, ok := InitialReadOnly(params.InitializationOptions)
+			if readOnly != tt.wantReadOnly || ok != tt.wantOK {
+				t.Errorf("got %t, %t, want %t, %t", readOnly, ok, tt.wantReadOnly, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestHandlerReadOnly(t *testing.T) {
+	readOnly := true
+	renamed, hovered := false, false
+	handler := &Handler{
+		TextDocumentRename: func(context *lsp.Context, params *RenameParams) (*WorkspaceEdit, error) {
+			renamed = true
+			return &WorkspaceEdit{}, nil
+		},
+		TextDocumentHover: func(context *lsp.Context, params *HoverParams) (*Hover, error) {
+			hovered = true
+			return &Hover{}, nil
+		},
+		ReadOnly: func() bool { return readOnly },
+	}
+	handler.SetInitialized(true)
+	params := json.RawMessage(`{"textDocument":{"uri":"file:///a.go"},"position":{"line":0,"character":0},"newName":"b"}`)
+
+	_, validMethod, validParams, err := handler.Handle(&lsp.Context{Method: MethodTextDocumentRename, Params: params})
+	if !validMethod || !validParams || err == nil || err.Error() != ReadOnlyMessage {
+		t.Errorf("rename in read-only mode: %t, %t, %v, want the read-only error", validMethod, validParams, err)
+	}
+	if renamed {
+		t.Error("rename handler called in read-only mode")
+	}
+	if _, _, _, err := handler.Handle(&lsp.Context{Method: MethodTextDocumentHover, Params: params}); err != nil || !hovered {
+		t.Errorf("hover in read-only mode: %v, called %t, want it to work", err, hovered)
+	}
+
+	readOnly = false
+	if _, _, _, err := handler.Handle(&lsp.Context{Method: MethodTextDocumentRename, Params: params}); err != nil || !renamed {
+		t.Errorf("rename: %v, called %t, want it to work", err, renamed)
+	}
+}
+
+func TestHandlerReadOnlyEdits(t *testing.T) {
+	called := map[Method]bool{}
+	handler := &Handler{
+		TextDocumentWillSaveWaitUntil: func(context *lsp.Context, params *WillSaveTextDocumentParams) ([]TextEdit, error) {
+			called[MethodTextDocumentWillSaveWaitUntil] = true
+			return []TextEdit{}, nil
+		},
+		WorkspaceWillCreateFiles: func(context *lsp.Context, params *CreateFilesParams) (*WorkspaceEdit, error) {
+			called[MethodWorkspaceWillCreateFiles] = true
+			return &WorkspaceEdit{}, nil
+		},
+		WorkspaceWillRenameFiles: func(context *lsp.Context, params *RenameFilesParams) (*WorkspaceEdit, error) {
+			called[MethodWorkspaceWillRenameFiles] = true
+			return &WorkspaceEdit{}, nil
+		},
+		WorkspaceWillDeleteFiles: func(context *lsp.Context, params *DeleteFilesParams) (*WorkspaceEdit, error) {
+			called[MethodWorkspaceWillDeleteFiles] = true
+			return &WorkspaceEdit{}, nil
+		},
+		ReadOnly: func() bool { return true },
+	}
+	handler.SetInitialized(true)
+
+	tests := []struct {
+		method Method
+		params string
+	}{
+		{method: MethodTextDocumentWillSaveWaitUntil, params: `{"textDocument":{"uri":"file:///a.go"},"reason":1}`},
+		{method: MethodWorkspaceWillCreateFiles, params: `{"files":[{"uri":"file:///a.go"}]}`},
+		{method: MethodWorkspaceWillRenameFiles, params: `{"files":[{"oldUri":"file:///a.go","newUri":"file:///b.go"}]}`},
+		{method: MethodWorkspaceWillDeleteFiles, params: `{"files":[{"uri":"file:///a.go"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			_, _, _, err := handler.Handle(&lsp.Context{Method: tt.method, Params: json.RawMessage(tt.params)})
+			if err == nil || err.Error() != ReadOnlyMessage {
+				t.Errorf("error = %v, want the read-only error", err)
+			}
+			if called[tt.method] {
+				t.Error("handler called in read-only mode")
+			}
+		})
+	}
+}
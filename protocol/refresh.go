@@ -0,0 +1,189 @@
+package protocol
+
+import (
+	"sync"
+	"time"
+
+	"github.com/SCKelemen/lsp"
+)
+
+const (
+	// DefaultRefreshDelay is how long a Refresher waits for more changes
+	// before sending a refresh.
+	DefaultRefreshDelay = 200 * time.Millisecond
+
+	// DefaultRefreshMaxDelay bounds how long a Refresher postpones a refresh
+	// while changes keep coming.
+	DefaultRefreshMaxDelay = 2 * time.Second
+)
+
+// refreshMethods are the refresh requests in the order a Refresher sends them.
+var refreshMethods = []Method{
+	ServerWorkspaceSemanticTokensRefresh,
+	ServerWorkspaceInlayHintRefresh,
+	ServerWorkspaceCodeLensRefresh,
+	ServerWorkspaceDiagnosticRefresh,
+}
+
+// Refresher asks the client to refresh inlay hints, code lenses, semantic
+// tokens, and pulled diagnostics after configuration or indexes change.
+//
+// Refresh requests make the client re-request the feature for every visible
+// document, so a burst of changes (e.g. a branch switch re-indexing many
+// files) must not send one request per change. Refresh is debounced: a
+// request is sent once no further refresh was asked for during Delay, but no
+// later than MaxDelay after the first one. Requests the client does not
+// support are never sent.
+type Refresher struct {
+	// Call sends a request to the client, e.g. the Call of the context
+	// passed to the initialize handler.
+	Call lsp.CallFunc
+
+	Delay    time.Duration
+	MaxDelay time.Duration
+
+	lock      sync.Mutex
+	supported map[Method]bool
+	pending   map[Method]bool
+	first     time.Time
+	timer     *time.Timer
+	stopped   bool
+}
+
+// NewRefresher creates a refresher sending requests with call for the
+// refresh requests that capabilities declare support for.
+func NewRefresher(call lsp.CallFunc, capabilities *ClientCapabilities) *Refresher {
+	self := Refresher{
+		Call:      call,
+		Delay:     DefaultRefreshDelay,
+		MaxDelay:  DefaultRefreshMaxDelay,
+		supported: make(map[Method]bool),
+		pending:   make(map[Method]bool),
+	}
+
+	if capabilities != nil && capabilities.Workspace != nil {
+		workspace := capabilities.Workspace
+		if workspace.SemanticTokens != nil && isTrue(workspace.SemanticTokens.RefreshSupport) {
+			self.supported[ServerWorkspaceSemanticTokensRefresh] = true
+		}
+		if workspace.InlayHint != nil && isTrue(workspace.InlayHint.RefreshSupport) {
+			self.supported[ServerWorkspaceInlayHintRefresh] = true
+		}
+		if workspace.CodeLens != nil && isTrue(workspace.CodeLens.RefreshSupport) {
+			self.supported[ServerWorkspaceCodeLensRefresh] = true
+		}
+		if workspace.Diagnostics != nil && isTrue(workspace.Diagnostics.RefreshSupport) {
+			self.supported[ServerWorkspaceDiagnosticRefresh] = true
+		}
+	}
+
+	return &self
+}
+
+// Supports reports whether the client supports the refresh request method.
+func (self *Refresher) Supports(method Method) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.supported[method]
+}
+
+// Refresh schedules the refresh requests methods. Unsupported methods are
+// ignored.
+func (self *Refresher) Refresh(methods ...Method) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.stopped {
+		return
+	}
+
+	scheduled := false
+	for _, method := range methods {
+		if self.supported[method] {
+			self.pending[method] = true
+			scheduled = true
+		}
+	}
+	if !scheduled {
+		return
+	}
+
+	now := time.Now()
+	if self.first.IsZero() {
+		self.first = now
+	}
+	wait := self.Delay
+	if deadline := self.first.Add(self.MaxDelay); now.Add(wait).After(deadline) {
+		wait = deadline.Sub(now)
+	}
+
+	if self.timer == nil {
+		self.timer = time.AfterFunc(wait, self.Flush)
+	} else {
+		self.timer.Reset(wait)
+	}
+}
+
+// RefreshSemanticTokens schedules a workspace/semanticTokens/refresh request.
+func (self *Refresher) RefreshSemanticTokens() {
+	self.Refresh(ServerWorkspaceSemanticTokensRefresh)
+}
+
+// RefreshInlayHints schedules a workspace/inlayHint/refresh request.
+func (self *Refresher) RefreshInlayHints() {
+	self.Refresh(ServerWorkspaceInlayHintRefresh)
+}
+
+// RefreshCodeLenses schedules a workspace/codeLens/refresh request.
+func (self *Refresher) RefreshCodeLenses() {
+	self.Refresh(ServerWorkspaceCodeLensRefresh)
+}
+
+// RefreshDiagnostics schedules a workspace/diagnostic/refresh request.
+func (self *Refresher) RefreshDiagnostics() {
+	self.Refresh(ServerWorkspaceDiagnosticRefresh)
+}
+
+// RefreshAll schedules every supported refresh request, e.g. after the
+// configuration changed.
+func (self *Refresher) RefreshAll() {
+	self.Refresh(refreshMethods...)
+}
+
+// Flush sends the scheduled refresh requests now.
+func (self *Refresher) Flush() {
+	self.lock.Lock()
+	if self.timer != nil {
+		self.timer.Stop()
+	}
+	var methods []Method
+	for _, method := range refreshMethods {
+		if self.pending[method] {
+			methods = append(methods, method)
+		}
+	}
+	self.pending = make(map[Method]bool)
+	self.first = time.Time{}
+	self.lock.Unlock()
+
+	for _, method := range methods {
+		self.Call(string(method), nil, nil)
+	}
+}
+
+// Stop drops the scheduled refresh requests and ignores later ones, e.g.
+// on shutdown.
+func (self *Refresher) Stop() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.stopped = true
+	if self.timer != nil {
+		self.timer.Stop()
+	}
+	self.pending = make(map[Method]bool)
+}
+
+func isTrue(value *bool) bool {
+	return value != nil && *value
+}
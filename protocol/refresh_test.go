@@ -0,0 +1,123 @@
+package protocol
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordedCalls struct {
+	lock    sync.Mutex
+	methods []string
+}
+
+func (self *recordedCalls) call(method string, params any, result any) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.methods = append(self.methods, method)
+	return nil
+}
+
+func (self *recordedCalls) get() []string {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return append([]string(nil), self.methods...)
+}
+
+func refreshCapabilities(t *testing.T) *ClientCapabilities {
+	var capabilities ClientCapabilities
+	err := json.Unmarshal([]byte(`{"workspace": {
+		"inlayHint": {"refreshSupport": true},
+		"codeLens": {"refreshSupport": true},
+		"semanticTokens": {"refreshSupport": false}
+	}}`), &capabilities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &capabilities
+}
+
+func TestRefresherSendsSupportedRequestsOnce(t *testing.T) {
+	calls := &recordedCalls{}
+	refresher := NewRefresher(calls.call, refreshCapabilities(t))
+	refresher.Delay = time.Hour
+
+	for i := 0; i < 10; i++ {
+		refresher.RefreshInlayHints()
+		refresher.RefreshAll()
+	}
+	if got := calls.get(); len(got) != 0 {
+		t.Fatalf("expected refreshes to be delayed, got %v", got)
+	}
+
+	refresher.Flush()
+	want := []string{"workspace/inlayHint/refresh", "workspace/codeLens/refresh"}
+	if got := calls.get(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	refresher.Flush()
+	if got := calls.get(); len(got) != 2 {
+		t.Fatalf("expected no requests without new refreshes, got %v", got)
+	}
+}
+
+func TestRefresherDebounce(t *testing.T) {
+	calls := &recordedCalls{}
+	refresher := NewRefresher(calls.call, refreshCapabilities(t))
+	refresher.Delay = 20 * time.Millisecond
+	refresher.MaxDelay = time.Hour
+
+	refresher.RefreshCodeLenses()
+	time.Sleep(5 * time.Millisecond)
+	refresher.RefreshCodeLenses()
+
+	deadline := time.Now().Add(time.Second)
+	for len(calls.get()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if got := calls.get(); !reflect.DeepEqual(got, []string{"workspace/codeLens/refresh"}) {
+		t.Fatalf("expected one debounced request, got %v", got)
+	}
+}
+
+func TestRefresherMaxDelay(t *testing.T) {
+	calls := &recordedCalls{}
+	refresher := NewRefresher(calls.call, refreshCapabilities(t))
+	refresher.Delay = time.Hour
+	refresher.MaxDelay = 10 * time.Millisecond
+
+	refresher.RefreshInlayHints()
+	deadline := time.Now().Add(time.Second)
+	for len(calls.get()) == 0 && time.Now().Before(deadline) {
+		refresher.RefreshInlayHints()
+		time.Sleep(time.Millisecond)
+	}
+	if len(calls.get()) == 0 {
+		t.Fatal("expected MaxDelay to bound a continuous stream of refreshes")
+	}
+}
+
+func TestRefresherUnsupportedAndStopped(t *testing.T) {
+	calls := &recordedCalls{}
+	refresher := NewRefresher(calls.call, nil)
+	refresher.RefreshAll()
+	refresher.Flush()
+	if got := calls.get(); len(got) != 0 {
+		t.Fatalf("expected no requests without client support, got %v", got)
+	}
+
+	refresher = NewRefresher(calls.call, refreshCapabilities(t))
+	refresher.Stop()
+	refresher.RefreshAll()
+	refresher.Flush()
+	if got := calls.get(); len(got) != 0 {
+		t.Fatalf("expected no requests after Stop, got %v", got)
+	}
+	if refresher.Supports(ServerWorkspaceSemanticTokensRefresh) || !refresher.Supports(ServerWorkspaceInlayHintRefresh) {
+		t.Error("Supports reported the wrong methods")
+	}
+}
@@ -0,0 +1,27 @@
+package protocol
+
+// MethodSetWorkspaceTrust is a custom notification from the client telling
+// the server whether the user trusts the workspace, e.g. after the editor
+// asked the user. Register it with HandleCustomNotification.
+const MethodSetWorkspaceTrust = Method("$/setWorkspaceTrust")
+
+// WorkspaceTrustOption is the key of the initialization option declaring
+// whether the workspace is trusted.
+const WorkspaceTrustOption = "workspaceTrusted"
+
+// SetWorkspaceTrustParams are the params of MethodSetWorkspaceTrust.
+type SetWorkspaceTrustParams struct {
+	Trusted bool `json:"trusted"`
+}
+
+// InitialWorkspaceTrust returns whether the workspace is trusted according
+// to initialization options, like InitializeParams.InitializationOptions,
+// and whether they declare it with WorkspaceTrustOption.
+func InitialWorkspaceTrust(options any) (trusted bool, ok bool) {
+	object, isObject := options.(map[string]any)
+	if !isObject {
+		return false, false
+	}
+	trusted, ok = object[WorkspaceTrustOption].(bool)
+	return trusted, ok
+}
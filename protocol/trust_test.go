@@ -0,0 +1,34 @@
+package protocol
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestInitialWorkspaceTrust(t *testing.T) {
+	tests := []struct {
+		options     string
+		wantTrusted bool
+		wantOK      bool
+	}{
+		{options: `{"workspaceTrusted":true}`, wantTrusted: true, wantOK: true},
+		{options: `{"workspaceTrusted":false,"other":1}`, wantTrusted: false, wantOK: true},
+		{options: `{"workspaceTrusted":"yes"}`},
+		{options: `{}`},
+		{options: `null`},
+		{options: `[true]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.options, func(t *testing.T) {
+			var params InitializeParams
+			if err := json.Unmarshal([]byte(`{"processId":null,"rootUri":null,"capabilities":{},"initializationOptions":`+tt.options+`}`), &params); err != nil {
+				t.Fatal(err)
+			}
+			trusted, ok := InitialWorkspaceTrust(params.InitializationOptions)
+			if trusted != tt.wantTrusted || ok != tt.wantOK {
+				t.Errorf("got %t, %t, want %t, %t", trusted, ok, tt.wantTrusted, tt.wantOK)
+			}
+		})
+	}
+}
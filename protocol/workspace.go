@@ -580,6 +580,11 @@ const MethodWorkspaceSemanticTokensRefresh = Method("workspace/semanticTokens/re
 
 type WorkspaceSemanticTokensRefreshFunc func(context *lsp.Context) error
 
+// ServerWorkspaceSemanticTokensRefresh is the same request as
+// MethodWorkspaceSemanticTokensRefresh, named for the direction it is sent in:
+// from the server to the client.
+const ServerWorkspaceSemanticTokensRefresh = MethodWorkspaceSemanticTokensRefresh
+
 type SemanticTokensWorkspaceClientCapabilities struct {
 	/**
 	 * Whether the client implementation supports a refresh request sent from
@@ -592,3 +597,86 @@ type SemanticTokensWorkspaceClientCapabilities struct {
 	 */
 	RefreshSupport *bool `json:"refreshSupport,omitempty"`
 }
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#workspace_textDocumentContent
+
+const MethodWorkspaceTextDocumentContent = Method("workspace/textDocumentContent")
+
+type WorkspaceTextDocumentContentFunc func(context *lsp.Context, params *TextDocumentContentParams) (*TextDocumentContentResult, error)
+
+/**
+ * Client capabilities for a text document content provider.
+ *
+ * @since 3.18.0
+ */
+type TextDocumentContentClientCapabilities struct {
+	/**
+	 * Text document content provider supports dynamic registration.
+	 */
+	DynamicRegistration *bool `json:"dynamicRegistration,omitempty"`
+}
+
+/**
+ * Text document content provider options.
+ *
+ * @since 3.18.0
+ */
+type TextDocumentContentOptions struct {
+	/**
+	 * The schemes for which the server provides content.
+	 */
+	Schemes []string `json:"schemes"`
+}
+
+/**
+ * Text document content provider registration options.
+ *
+ * @since 3.18.0
+ */
+type TextDocumentContentRegistrationOptions struct {
+	TextDocumentContentOptions
+	StaticRegistrationOptions
+}
+
+/**
+ * Parameters for the `workspace/textDocumentContent` request.
+ *
+ * @since 3.18.0
+ */
+type TextDocumentContentParams struct {
+	/**
+	 * The uri of the text document.
+	 */
+	URI DocumentUri `json:"uri"`
+}
+
+/**
+ * Result of the `workspace/textDocumentContent` request.
+ *
+ * @since 3.18.0
+ */
+type TextDocumentContentResult struct {
+	/**
+	 * The text content of the text document. Please note, that the content of
+	 * any subsequent open notifications for the text document might differ
+	 * from the returned content due to whitespace and line ending
+	 * normalizations done on the client
+	 */
+	Text string `json:"text"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#workspace_textDocumentContentRefresh
+
+const ServerWorkspaceTextDocumentContentRefresh = Method("workspace/textDocumentContent/refresh")
+
+/**
+ * Parameters for the `workspace/textDocumentContent/refresh` request.
+ *
+ * @since 3.18.0
+ */
+type TextDocumentContentRefreshParams struct {
+	/**
+	 * The uri of the text document to refresh.
+	 */
+	URI DocumentUri `json:"uri"`
+}
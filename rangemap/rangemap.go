@@ -0,0 +1,203 @@
+// Package rangemap translates ranges between versions of a document.
+//
+// Results such as diagnostics and code lenses are computed against one
+// version of a document, but the user keeps typing while they are computed
+// and between analysis runs. A Map records the changes applied to a document
+// so ranges computed against an older version can be moved to where the same
+// text is in the current version (or back), keeping them anchored to the
+// code they describe instead of drifting by the lines inserted above them.
+package rangemap
+
+import (
+	"sync"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// DefaultMaxVersions is the number of versions a Map created with New keeps.
+const DefaultMaxVersions = 100
+
+// Map records the changes to one document. It is safe for concurrent use.
+type Map struct {
+	mu sync.Mutex
+
+	// base is the oldest version ranges can be translated from or to.
+	base int
+
+	// steps hold the changes from base to the current version, one per
+	// version, in order.
+	steps []step
+
+	// MaxVersions is the number of versions kept; older versions are
+	// dropped as new ones are recorded. Zero means no limit.
+	MaxVersions int
+}
+
+// step holds the changes that produced one version from the previous one.
+type step struct {
+	version int
+
+	// full means the whole content was replaced, so ranges cannot be
+	// translated across this step.
+	full bool
+
+	changes []change
+}
+
+// change is one edit, by the range it replaced in the previous content and
+// the range its new text spans in the next content.
+type change struct {
+	replaced core.Range
+	inserted core.Range
+}
+
+// New creates a map for a document opened at version.
+func New(version int) *Map {
+	return &Map{base: version, MaxVersions: DefaultMaxVersions}
+}
+
+// Version returns the current version of the document.
+func (m *Map) Version() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.versionLocked()
+}
+
+// Apply records incremental changes that produced version, in the order of
+// a didChange notification: each edit's range refers to the content after
+// the edits before it.
+func (m *Map) Apply(version int, edits ...core.TextEdit) {
+	changes := make([]change, len(edits))
+	for i, edit := range edits {
+		changes[i] = change{replaced: edit.Range, inserted: core.InsertedRange(edit)}
+	}
+	m.record(step{version: version, changes: changes})
+}
+
+// Replace records that version replaced the whole content. Ranges cannot be
+// translated across a replacement.
+func (m *Map) Replace(version int) {
+	m.record(step{version: version, full: true})
+}
+
+func (m *Map) record(s step) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.steps = append(m.steps, s)
+	if m.MaxVersions > 0 && len(m.steps) > m.MaxVersions {
+		drop := len(m.steps) - m.MaxVersions
+		m.base = m.steps[drop-1].version
+		m.steps = append([]step(nil), m.steps[drop:]...)
+	}
+}
+
+// Translate maps r from version from to version to, forward or backward.
+// The result is false if either version is unknown, if the whole content was
+// replaced in between, or if an edit in between replaced text crossing r's
+// start or end. A range containing an edit grows or shrinks with it.
+func (m *Map) Translate(r core.Range, from, to int) (core.Range, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	start, ok := m.indexLocked(from)
+	if !ok {
+		return core.Range{}, false
+	}
+	end, ok := m.indexLocked(to)
+	if !ok {
+		return core.Range{}, false
+	}
+
+	// Steps start..end-1 lead from one version to the other
+	if start <= end {
+		for _, s := range m.steps[start:end] {
+			if r, ok = s.forward(r); !ok {
+				return core.Range{}, false
+			}
+		}
+	} else {
+		for i := start - 1; i >= end; i-- {
+			if r, ok = m.steps[i].backward(r); !ok {
+				return core.Range{}, false
+			}
+		}
+	}
+	return r, true
+}
+
+// TranslateDiagnostics maps diagnostics computed against version from to
+// version to. Diagnostics whose ranges cannot be translated are dropped,
+// since their text was edited.
+func (m *Map) TranslateDiagnostics(diagnostics []core.Diagnostic, from, to int) []core.Diagnostic {
+	result := make([]core.Diagnostic, 0, len(diagnostics))
+	for _, diagnostic := range diagnostics {
+		r, ok := m.Translate(diagnostic.Range, from, to)
+		if !ok {
+			continue
+		}
+		diagnostic.Range = r
+		result = append(result, diagnostic)
+	}
+	return result
+}
+
+// Forget drops the history before version, e.g. once no results computed
+// against older versions remain.
+func (m *Map) Forget(version int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index, ok := m.indexLocked(version)
+	if !ok || index == 0 {
+		return
+	}
+	m.base = m.steps[index-1].version
+	m.steps = append([]step(nil), m.steps[index:]...)
+}
+
+func (m *Map) versionLocked() int {
+	if len(m.steps) == 0 {
+		return m.base
+	}
+	return m.steps[len(m.steps)-1].version
+}
+
+// indexLocked returns the number of steps from base to version.
+func (m *Map) indexLocked(version int) (int, bool) {
+	if version == m.base {
+		return 0, true
+	}
+	for i, s := range m.steps {
+		if s.version == version {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+func (s step) forward(r core.Range) (core.Range, bool) {
+	if s.full {
+		return core.Range{}, false
+	}
+	for _, c := range s.changes {
+		var ok bool
+		if r, ok = core.ShiftRange(r, c.replaced, c.inserted); !ok {
+			return core.Range{}, false
+		}
+	}
+	return r, true
+}
+
+func (s step) backward(r core.Range) (core.Range, bool) {
+	if s.full {
+		return core.Range{}, false
+	}
+	for i := len(s.changes) - 1; i >= 0; i-- {
+		var ok bool
+		if r, ok = core.ShiftRange(r, s.changes[i].inserted, s.changes[i].replaced); !ok {
+			return core.Range{}, false
+		}
+	}
+	return r, true
+}
@@ -0,0 +1,165 @@
+package rangemap
+
+import (
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+func rng(startLine, startChar, endLine, endChar int) core.Range {
+	return core.Range{
+		Start: core.Position{Line: startLine, Character: startChar},
+		End:   core.Position{Line: endLine, Character: endChar},
+	}
+}
+
+// editor applies changes to a document and records them in a Map, keeping
+// the content of every version. Each change is one version, like a didChange
+// notification.
+type editor struct {
+	m        *Map
+	doc      *core.Document
+	version  int
+	versions map[int]string
+}
+
+func newEditor(content string) *editor {
+	return &editor{
+		m:        New(1),
+		doc:      core.NewDocument("file:///test.go", content, 1),
+		version:  1,
+		versions: map[int]string{1: content},
+	}
+}
+
+func (e *editor) apply(edits ...core.TextEdit) {
+	for _, edit := range edits {
+		e.doc.ApplyEdit(edit.Range, edit.NewText)
+	}
+	e.version++
+	e.m.Apply(e.version, edits...)
+	e.versions[e.version] = e.doc.GetContent()
+}
+
+// text returns the text of r in version.
+func (e *editor) text(version int, r core.Range) string {
+	content := e.versions[version]
+	return content[core.PositionToByteOffset(content, r.Start):core.PositionToByteOffset(content, r.End)]
+}
+
+func TestTranslateForwardAndBackward(t *testing.T) {
+	e := newEditor("package main\n\nfunc main() {\n\tx := compute()\n}\n")
+	compute := rng(3, 6, 3, 13)
+
+	// Version 2: a comment above; version 3: two edits in one change
+	e.apply(core.TextEdit{Range: rng(1, 0, 1, 0), NewText: "// main runs\n// the program\n"})
+	e.apply(
+		core.TextEdit{Range: rng(5, 1, 5, 1), NewText: "var "},
+		core.TextEdit{Range: rng(5, 6, 5, 8), NewText: "="},
+	)
+	if got := e.m.Version(); got != 3 {
+		t.Fatalf("expected version 3, got %d", got)
+	}
+
+	forward, ok := e.m.Translate(compute, 1, 3)
+	if !ok {
+		t.Fatal("expected range to translate forward")
+	}
+	if text := e.text(3, forward); text != "compute" {
+		t.Errorf("translated range covers %q in version 3", text)
+	}
+
+	back, ok := e.m.Translate(forward, 3, 1)
+	if !ok || back != compute {
+		t.Errorf("expected backward translation to return %s, got %s, %v", compute, back, ok)
+	}
+
+	same, ok := e.m.Translate(compute, 1, 1)
+	if !ok || same != compute {
+		t.Errorf("expected identity translation, got %s, %v", same, ok)
+	}
+}
+
+func TestTranslateEditedRange(t *testing.T) {
+	e := newEditor("func compute() int {\n\treturn 1\n}\n")
+	name := rng(0, 5, 0, 12)
+	body := rng(1, 0, 1, 9)
+
+	// Rename part of the name and change the body
+	e.apply(core.TextEdit{Range: rng(0, 3, 0, 8), NewText: "c re"})
+	e.apply(core.TextEdit{Range: rng(1, 8, 1, 9), NewText: "42"})
+
+	if _, ok := e.m.Translate(name, 1, 3); ok {
+		t.Error("expected range crossing an edit not to translate")
+	}
+
+	got, ok := e.m.Translate(body, 1, 3)
+	if !ok {
+		t.Fatal("expected range containing an edit to translate")
+	}
+	if text := e.text(3, got); text != "\treturn 42" {
+		t.Errorf("translated range covers %q", text)
+	}
+}
+
+func TestTranslateUnknownVersions(t *testing.T) {
+	m := New(5)
+	m.Apply(6, core.TextEdit{Range: rng(0, 0, 0, 0), NewText: "x"})
+	m.Replace(7)
+	m.Apply(8, core.TextEdit{Range: rng(0, 0, 0, 0), NewText: "y"})
+
+	r := rng(0, 1, 0, 2)
+	if _, ok := m.Translate(r, 4, 6); ok {
+		t.Error("expected version before the first to be unknown")
+	}
+	if _, ok := m.Translate(r, 5, 9); ok {
+		t.Error("expected future version to be unknown")
+	}
+	if _, ok := m.Translate(r, 5, 8); ok {
+		t.Error("expected full replacement to stop translation")
+	}
+	if got, ok := m.Translate(r, 7, 8); !ok || got != rng(0, 2, 0, 3) {
+		t.Errorf("expected translation after the replacement, got %s, %v", got, ok)
+	}
+}
+
+func TestMaxVersionsAndForget(t *testing.T) {
+	m := New(1)
+	m.MaxVersions = 2
+	for version := 2; version <= 5; version++ {
+		m.Apply(version, core.TextEdit{Range: rng(0, 0, 0, 0), NewText: "\n"})
+	}
+
+	if _, ok := m.Translate(rng(0, 0, 0, 1), 2, 5); ok {
+		t.Error("expected dropped version to be unknown")
+	}
+	got, ok := m.Translate(rng(0, 0, 0, 1), 3, 5)
+	if !ok || got != rng(2, 0, 2, 1) {
+		t.Errorf("expected translation from the oldest kept version, got %s, %v", got, ok)
+	}
+
+	m.Forget(4)
+	if _, ok := m.Translate(rng(0, 0, 0, 1), 3, 5); ok {
+		t.Error("expected forgotten version to be unknown")
+	}
+	if m.Version() != 5 {
+		t.Errorf("expected version 5, got %d", m.Version())
+	}
+}
+
+func TestTranslateDiagnostics(t *testing.T) {
+	m := New(1)
+	m.Apply(2, core.TextEdit{Range: rng(0, 0, 0, 3), NewText: "\n\n"})
+
+	diagnostics := []core.Diagnostic{
+		{Range: rng(2, 0, 2, 4), Message: "kept"},
+		{Range: rng(0, 1, 0, 5), Message: "edited"},
+	}
+	got := m.TranslateDiagnostics(diagnostics, 1, 2)
+	if len(got) != 1 || got[0].Message != "kept" || got[0].Range != rng(4, 0, 4, 4) {
+		t.Errorf("unexpected diagnostics %+v", got)
+	}
+	if diagnostics[0].Range != rng(2, 0, 2, 4) {
+		t.Error("expected input diagnostics to be unchanged")
+	}
+}
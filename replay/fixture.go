@@ -0,0 +1,327 @@
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/internal/textdocument"
+	"github.com/SCKelemen/lsp/protocol"
+	uripkg "github.com/SCKelemen/lsp/uri"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// FixtureRecorder is a handler middleware for development that saves real
+// client requests as fixtures, to reproduce the quirks of an editor.
+//
+// Each fixture is a session log of a single request: the client's
+// initialize request, the notifications that opened and changed the
+// document of the request, the request and the response, then shutdown
+// and exit. Replay it, e.g. with lsp-replay, against a server build to see
+// whether the response changed.
+//
+// Messages are sanitized before they are saved: workspace folders become
+// file:///workspace, the process id is dropped, and settings whose names
+// suggest secrets, like tokens and passwords, are redacted.
+type FixtureRecorder struct {
+	// Handler handles the messages.
+	Handler lsp.Handler
+
+	// Dir receives the fixtures, named after their method and numbered,
+	// like textDocument_hover-1.jsonl. Existing files are kept.
+	Dir string
+
+	// MaxPerMethod is the number of fixtures saved per method, 0 for no
+	// limit.
+	MaxPerMethod int
+
+	// Sanitize, when set, further rewrites the messages of fixtures.
+	Sanitize func(message json.RawMessage) json.RawMessage
+
+	mu         sync.Mutex
+	initialize json.RawMessage
+	replacer   *strings.Replacer
+	documents  map[string][]json.RawMessage // notifications by document URI
+	counts     map[string]int
+	err        error
+}
+
+// NewFixtureRecorder creates a recorder saving up to 5 fixtures per method
+// to dir.
+func NewFixtureRecorder(handler lsp.Handler, dir string) *FixtureRecorder {
+	return &FixtureRecorder{
+		Handler:      handler,
+		Dir:          dir,
+		MaxPerMethod: 5,
+	}
+}
+
+// Err returns the first error saving a fixture, if any.
+func (r *FixtureRecorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// ([lsp.Handler] interface)
+func (r *FixtureRecorder) Handle(context *lsp.Context) (any, bool, bool, error) {
+	start := time.Now()
+	result, validMethod, validParams, err := r.Handler.Handle(context)
+
+	switch {
+	case context.Method == protocol.MethodInitialize:
+		r.setInitialize(context.Params)
+	case protocol.ClientNotifications[context.Method]:
+		r.trackDocument(context.Method, context.Params)
+	case validMethod && validParams && context.Method != protocol.MethodShutdown:
+		if saveErr := r.save(context.Method, context.Params, start, result, err); saveErr != nil {
+			r.mu.Lock()
+			if r.err == nil {
+				r.err = saveErr
+			}
+			r.mu.Unlock()
+		}
+	}
+
+	return result, validMethod, validParams, err
+}
+
+func (r *FixtureRecorder) setInitialize(params json.RawMessage) {
+	var initialize struct {
+		RootURI          string `json:"rootUri"`
+		RootPath         string `json:"rootPath"`
+		WorkspaceFolders []struct {
+			URI string `json:"uri"`
+		} `json:"workspaceFolders"`
+	}
+	json.Unmarshal(params, &initialize)
+
+	var roots []string
+	for _, folder := range initialize.WorkspaceFolders {
+		roots = append(roots, folder.URI)
+	}
+	if initialize.RootURI != "" {
+		roots = append(roots, initialize.RootURI)
+	} else if initialize.RootPath != "" {
+		roots = append(roots, uripkg.FromPath(initialize.RootPath))
+	}
+
+	// Every spelling of the root, as a URI or a path, within strings;
+	// nested roots first, as the replacer tries them in order
+	sort.SliceStable(roots, func(i, j int) bool { return len(roots[i]) > len(roots[j]) })
+	var replacements []string
+	placeholders := map[string]string{}
+	for _, root := range roots {
+		root = strings.TrimSuffix(root, "/")
+		if _, ok := placeholders[root]; ok {
+			continue
+		}
+		placeholder := "file:///workspace"
+		if n := len(placeholders); n > 0 {
+			placeholder = fmt.Sprintf("file:///workspace%d", n+1)
+		}
+		placeholders[root] = placeholder
+		replacements = append(replacements, root, placeholder)
+		if path, err := uripkg.ToPath(root); err == nil {
+			replacements = append(replacements, path, strings.TrimPrefix(placeholder, "file://"))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.initialize = params
+	r.replacer = strings.NewReplacer(replacements...)
+	r.documents = make(map[string][]json.RawMessage)
+	if r.counts == nil {
+		r.counts = make(map[string]int)
+	}
+}
+
+// trackDocument keeps the notifications about open documents.
+func (r *FixtureRecorder) trackDocument(method string, params json.RawMessage) {
+	uri := textdocument.URI(params)
+	if uri == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.documents == nil {
+		return
+	}
+	switch method {
+	case protocol.MethodTextDocumentDidOpen:
+		r.documents[uri] = []json.RawMessage{notification(method, params)}
+	case protocol.MethodTextDocumentDidChange, protocol.MethodTextDocumentDidSave:
+		if opened, ok := r.documents[uri]; ok {
+			r.documents[uri] = append(opened, notification(method, params))
+		}
+	case protocol.MethodTextDocumentDidClose:
+		delete(r.documents, uri)
+	}
+}
+
+// save writes the fixture of a request, unless the method has enough.
+func (r *FixtureRecorder) save(method string, params json.RawMessage, start time.Time, result any, err error) error {
+	end := time.Now()
+
+	r.mu.Lock()
+	if r.initialize == nil || (r.MaxPerMethod > 0 && r.counts[method] >= r.MaxPerMethod) {
+		r.mu.Unlock()
+		return nil
+	}
+	r.counts[method]++
+	messages := []json.RawMessage{
+		request(1, protocol.MethodInitialize, r.initialize),
+		notification(protocol.MethodInitialized, json.RawMessage("{}")),
+	}
+	if uri := textdocument.URI(params); uri != "" {
+		messages = append(messages, r.documents[uri]...)
+	} else {
+		for _, notifications := range r.documents {
+			messages = append(messages, notifications...)
+		}
+	}
+	replacer := r.replacer
+	r.mu.Unlock()
+
+	response, marshalErr := responseMessage(2, result, err)
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	var entries []Entry
+	for _, message := range messages {
+		entries = append(entries, Entry{Time: start, Direction: Inbound, Message: message})
+	}
+	entries = append(entries,
+		Entry{Time: start, Direction: Inbound, Message: request(2, method, params)},
+		Entry{Time: end, Direction: Outbound, Message: response},
+		Entry{Time: end, Direction: Inbound, Message: request(3, protocol.MethodShutdown, nil)},
+		Entry{Time: end, Direction: Inbound, Message: notification(protocol.MethodExit, nil)},
+	)
+	for i := range entries {
+		entries[i].Message = sanitize(entries[i].Message, replacer)
+		if r.Sanitize != nil {
+			entries[i].Message = r.Sanitize(entries[i].Message)
+		}
+	}
+
+	return r.write(method, entries)
+}
+
+// write saves entries in the first free file for method.
+func (r *FixtureRecorder) write(method string, entries []Entry) error {
+	if err := os.MkdirAll(r.Dir, 0o755); err != nil {
+		return err
+	}
+	name := strings.NewReplacer("/", "_", "$", "").Replace(method)
+	for n := 1; ; n++ {
+		file, err := os.OpenFile(filepath.Join(r.Dir, fmt.Sprintf("%s-%d.jsonl", name, n)), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(file)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				file.Close()
+				return err
+			}
+		}
+		return file.Close()
+	}
+}
+
+// secretKey matches the names of settings to redact.
+var secretKey = regexp.MustCompile(`(?i)token|secret|password|passwd|credential|api_?key`)
+
+// settingsKeys hold the settings of the client, where secrets are redacted.
+var settingsKeys = map[string]bool{"initializationOptions": true, "settings": true}
+
+// sanitize replaces the workspace roots in the strings of a message,
+// redacts secrets in settings, and drops the process id.
+func sanitize(message json.RawMessage, replacer *strings.Replacer) json.RawMessage {
+	var value any
+	if err := json.Unmarshal(message, &value); err != nil {
+		return message
+	}
+	value = sanitizeValue(value, replacer, false)
+	if sanitized, err := json.Marshal(value); err == nil {
+		return sanitized
+	}
+	return message
+}
+
+func sanitizeValue(value any, replacer *strings.Replacer, inSettings bool) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for k, v := range value {
+			_, isString := v.(string)
+			switch {
+			case k == "processId":
+				value[k] = nil
+			case inSettings && isString && secretKey.MatchString(k):
+				value[k] = "REDACTED"
+			default:
+				value[k] = sanitizeValue(v, replacer, inSettings || settingsKeys[k])
+			}
+		}
+		return value
+	case []any:
+		for i, v := range value {
+			value[i] = sanitizeValue(v, replacer, inSettings)
+		}
+		return value
+	case string:
+		if replacer != nil {
+			return replacer.Replace(value)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+func request(id uint64, method string, params json.RawMessage) json.RawMessage {
+	message := map[string]any{"jsonrpc": "2.0", "id": id, "method": method}
+	if params != nil {
+		message["params"] = params
+	}
+	encoded, _ := json.Marshal(message)
+	return encoded
+}
+
+func notification(method string, params json.RawMessage) json.RawMessage {
+	message := map[string]any{"jsonrpc": "2.0", "method": method}
+	if params != nil {
+		message["params"] = params
+	}
+	encoded, _ := json.Marshal(message)
+	return encoded
+}
+
+func responseMessage(id uint64, result any, err error) (json.RawMessage, error) {
+	message := map[string]any{"jsonrpc": "2.0", "id": id}
+	if err != nil {
+		var rpcErr *jsonrpc2.Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &jsonrpc2.Error{Code: jsonrpc2.CodeInternalError, Message: err.Error()}
+		}
+		message["error"] = rpcErr
+	} else {
+		message["result"] = result
+	}
+	return json.Marshal(message)
+}
@@ -0,0 +1,87 @@
+package replay
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/internal/textdocument"
+)
+
+// hoverHandler answers hover requests with the URI of the document.
+type hoverHandler struct{}
+
+func (hoverHandler) Handle(context *lsp.Context) (any, bool, bool, error) {
+	switch context.Method {
+	case "textDocument/hover":
+		return map[string]any{"contents": textdocument.URI(context.Params)}, true, true, nil
+	case "unknown":
+		return nil, false, true, nil
+	}
+	return nil, true, true, nil
+}
+
+func TestFixtureRecorder(t *testing.T) {
+	dir := t.TempDir()
+	recorder := NewFixtureRecorder(hoverHandler{}, dir)
+	recorder.MaxPerMethod = 1
+
+	handle := func(method, params string) {
+		t.Helper()
+		if _, _, _, err := recorder.Handle(&lsp.Context{Method: method, Params: json.RawMessage(params)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handle("initialize", `{"processId":42,"rootUri":"file:///home/user/project","initializationOptions":{"apiToken":"abc","tokenLimit":3}}`)
+	handle("initialized", `{}`)
+	handle("textDocument/didOpen", `{"textDocument":{"uri":"file:///home/user/project/main.go","text":"package main"}}`)
+	handle("textDocument/didOpen", `{"textDocument":{"uri":"file:///home/user/project/other.go","text":"package other"}}`)
+	handle("textDocument/didChange", `{"textDocument":{"uri":"file:///home/user/project/main.go"},"contentChanges":[{"text":"package main\n"}]}`)
+	handle("textDocument/hover", `{"textDocument":{"uri":"file:///home/user/project/main.go"},"position":{"line":0,"character":0}}`)
+	handle("textDocument/hover", `{"textDocument":{"uri":"file:///home/user/project/other.go"},"position":{"line":0,"character":0}}`)
+	handle("unknown", `{}`)
+	if err := recorder.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	names, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(names) != 1 || filepath.Base(names[0]) != "textDocument_hover-1.jsonl" {
+		t.Fatalf("expected one hover fixture, got %v", names)
+	}
+	file, err := os.Open(names[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	entries, err := ReadLog(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var methods []string
+	var log strings.Builder
+	for _, entry := range entries {
+		var m message
+		json.Unmarshal(entry.Message, &m)
+		methods = append(methods, string(entry.Direction)+" "+m.Method)
+		log.Write(entry.Message)
+	}
+	want := "in initialize,in initialized,in textDocument/didOpen,in textDocument/didChange,in textDocument/hover,out ,in shutdown,in exit"
+	if got := strings.Join(methods, ","); got != want {
+		t.Errorf("messages = %s, want %s", got, want)
+	}
+
+	for _, sanitized := range []string{"/home/user", "abc", "42"} {
+		if strings.Contains(log.String(), sanitized) {
+			t.Errorf("fixture contains %q: %s", sanitized, log.String())
+		}
+	}
+	for _, kept := range []string{`"contents":"file:///workspace/main.go"`, `"apiToken":"REDACTED"`, `"tokenLimit":3`} {
+		if !strings.Contains(log.String(), kept) {
+			t.Errorf("fixture lacks %s: %s", kept, log.String())
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Package replay records the messages of LSP sessions and replays them
+// against a server, for reproducing bugs and for regression testing the
+// performance of a server build.
+//
+// A recording is a log of JSON lines, one Entry per message, written by a
+// Recorder wrapped around the server's transport. Replay feeds the client's
+// side of a recording to a server and compares its responses and latencies
+// with the recorded ones.
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// Direction tells which side of the connection sent a message, from the
+// server's point of view.
+type Direction string
+
+const (
+	// Inbound messages are sent by the client.
+	Inbound Direction = "in"
+
+	// Outbound messages are sent by the server.
+	Outbound Direction = "out"
+)
+
+// Entry is one recorded message.
+type Entry struct {
+	Time      time.Time       `json:"time"`
+	Direction Direction       `json:"direction"`
+	Message   json.RawMessage `json:"message"`
+}
+
+// Recorder writes messages to a session log. It is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	err     error
+}
+
+// NewRecorder creates a recorder writing JSON lines to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{encoder: json.NewEncoder(w)}
+}
+
+// Record appends a message to the log. After a write fails, further
+// messages are dropped and Err reports the failure.
+func (r *Recorder) Record(direction Direction, message json.RawMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return
+	}
+	r.err = r.encoder.Encode(Entry{Time: time.Now(), Direction: direction, Message: message})
+}
+
+// Err returns the first error writing the log, if any.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Stream wraps the object stream of a server connection so every message
+// read from and written to it is recorded.
+func (r *Recorder) Stream(stream jsonrpc2.ObjectStream) jsonrpc2.ObjectStream {
+	return &recordingStream{stream: stream, recorder: r}
+}
+
+type recordingStream struct {
+	stream   jsonrpc2.ObjectStream
+	recorder *Recorder
+}
+
+// ([jsonrpc2.ObjectStream] interface)
+func (self *recordingStream) WriteObject(obj any) error {
+	message, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	self.recorder.Record(Outbound, message)
+	return self.stream.WriteObject(json.RawMessage(message))
+}
+
+// ([jsonrpc2.ObjectStream] interface)
+func (self *recordingStream) ReadObject(v any) error {
+	var message json.RawMessage
+	if err := self.stream.ReadObject(&message); err != nil {
+		return err
+	}
+	self.recorder.Record(Inbound, message)
+	return json.Unmarshal(message, v)
+}
+
+// ([jsonrpc2.ObjectStream] interface)
+func (self *recordingStream) Close() error {
+	return self.stream.Close()
+}
+
+// ReadLog reads the entries of a session log.
+func ReadLog(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 256<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,210 @@
+package replay
+
+import (
+	"bytes"
+	contextpkg "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// Result describes one replayed request.
+type Result struct {
+	Method string
+
+	// Recorded is the latency in the recording, or zero if its response was
+	// not recorded.
+	Recorded time.Duration
+
+	// Duration is the latency when replayed.
+	Duration time.Duration
+
+	// Mismatch is true if the response differs from the recorded one.
+	Mismatch bool
+
+	// Err is set if the request failed when replayed; a JSON-RPC error that
+	// matches the recorded one is not an error.
+	Err error
+}
+
+// message holds the fields of a JSON-RPC message needed for replay.
+type message struct {
+	ID     *jsonrpc2.ID     `json:"id"`
+	Method string           `json:"method"`
+	Params *json.RawMessage `json:"params"`
+	Result json.RawMessage  `json:"result"`
+	Error  *jsonrpc2.Error  `json:"error"`
+}
+
+// Replay sends the client messages of a recorded session over stream, in
+// order, and returns a Result for every request. Notifications are sent
+// without waiting; each request waits for its response before the next
+// message is sent, so latencies are measured one at a time.
+//
+// Requests from the server are answered with the client's recorded
+// responses to requests of the same method, in order, or with a null result
+// if there are none. Replay stops after the "exit" notification and closes
+// the connection when done.
+func Replay(context contextpkg.Context, stream jsonrpc2.ObjectStream, entries []Entry) ([]Result, error) {
+	session, err := parseSession(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := jsonrpc2.NewConn(context, stream, jsonrpc2.HandlerWithError(session.handleServerRequest))
+	defer connection.Close()
+
+	var results []Result
+	for _, call := range session.calls {
+		if call.ID == nil {
+			if err := connection.Notify(context, call.Method, call.params()); err != nil {
+				return results, err
+			}
+			if call.Method == "exit" {
+				break
+			}
+			continue
+		}
+
+		var result json.RawMessage
+		start := time.Now()
+		err := connection.Call(context, call.Method, call.params(), &result)
+		replayed := Result{Method: call.Method, Duration: time.Since(start)}
+
+		if recorded, ok := session.responses[*call.ID]; ok {
+			replayed.Recorded = recorded.time.Sub(call.time)
+			replayed.Mismatch, replayed.Err = compare(recorded.message, result, err)
+		} else if err != nil {
+			replayed.Err = err
+		}
+		if errors.Is(err, jsonrpc2.ErrClosed) {
+			return append(results, replayed), err
+		}
+		results = append(results, replayed)
+	}
+
+	return results, nil
+}
+
+type timedMessage struct {
+	message
+	time time.Time
+}
+
+// params returns the parameters to send, leaving them out if the recorded
+// message had none.
+func (self timedMessage) params() any {
+	if self.Params == nil {
+		return nil
+	}
+	return self.Params
+}
+
+type session struct {
+	// calls are the client's requests and notifications, in order.
+	calls []timedMessage
+
+	// responses are the server's recorded responses by request ID.
+	responses map[jsonrpc2.ID]timedMessage
+
+	mu sync.Mutex
+	// answers are the client's recorded responses to server requests,
+	// by method.
+	answers map[string][]message
+}
+
+func parseSession(entries []Entry) (*session, error) {
+	session := &session{
+		responses: make(map[jsonrpc2.ID]timedMessage),
+		answers:   make(map[string][]message),
+	}
+	serverRequests := make(map[jsonrpc2.ID]string)
+	var clientResponses []message
+
+	for i, entry := range entries {
+		var m message
+		if err := json.Unmarshal(entry.Message, &m); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+
+		switch {
+		case entry.Direction == Inbound && m.Method != "":
+			session.calls = append(session.calls, timedMessage{m, entry.Time})
+		case entry.Direction == Inbound && m.ID != nil:
+			clientResponses = append(clientResponses, m)
+		case entry.Direction == Outbound && m.Method != "" && m.ID != nil:
+			serverRequests[*m.ID] = m.Method
+		case entry.Direction == Outbound && m.Method == "" && m.ID != nil:
+			session.responses[*m.ID] = timedMessage{m, entry.Time}
+		}
+	}
+
+	for _, response := range clientResponses {
+		if method, ok := serverRequests[*response.ID]; ok {
+			session.answers[method] = append(session.answers[method], response)
+		}
+	}
+
+	return session, nil
+}
+
+func (self *session) handleServerRequest(context contextpkg.Context, connection *jsonrpc2.Conn, request *jsonrpc2.Request) (any, error) {
+	if request.Notif {
+		return nil, nil
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	answers := self.answers[request.Method]
+	if len(answers) == 0 {
+		return nil, nil
+	}
+	self.answers[request.Method] = answers[1:]
+
+	if answers[0].Error != nil {
+		return nil, answers[0].Error
+	}
+	return answers[0].Result, nil
+}
+
+// compare reports whether a replayed response differs from the recorded one.
+// A transport failure is returned as an error.
+func compare(recorded message, result json.RawMessage, err error) (bool, error) {
+	var rpcErr *jsonrpc2.Error
+	if err != nil && !errors.As(err, &rpcErr) {
+		return true, err
+	}
+
+	if recorded.Error != nil || rpcErr != nil {
+		if recorded.Error == nil || rpcErr == nil || recorded.Error.Code != rpcErr.Code {
+			return true, err
+		}
+		return false, nil
+	}
+
+	return !equalJSON(recorded.Result, result), nil
+}
+
+// equalJSON compares JSON values ignoring formatting and key order.
+func equalJSON(a, b json.RawMessage) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	var va, vb any
+	if len(a) == 0 {
+		a = json.RawMessage("null")
+	}
+	if len(b) == 0 {
+		b = json.RawMessage("null")
+	}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -0,0 +1,160 @@
+package replay
+
+import (
+	"bytes"
+	contextpkg "context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// serve starts a server connection running handler and returns the
+// client's end of the stream. Messages are recorded if recorder is set.
+func serve(t *testing.T, handler jsonrpc2.Handler, recorder *Recorder) jsonrpc2.ObjectStream {
+	t.Helper()
+	serverSide, clientSide := net.Pipe()
+
+	stream := jsonrpc2.NewBufferedStream(serverSide, jsonrpc2.VSCodeObjectCodec{})
+	if recorder != nil {
+		stream = recorder.Stream(stream)
+	}
+	connection := jsonrpc2.NewConn(contextpkg.Background(), stream, jsonrpc2.AsyncHandler(handler))
+	t.Cleanup(func() { connection.Close() })
+
+	return jsonrpc2.NewBufferedStream(clientSide, jsonrpc2.VSCodeObjectCodec{})
+}
+
+// echoHandler answers "echo" with its params and "ask" with the client's
+// answer to a workspace/configuration request.
+func echoHandler(suffix string) jsonrpc2.Handler {
+	return jsonrpc2.HandlerWithError(func(context contextpkg.Context, connection *jsonrpc2.Conn, request *jsonrpc2.Request) (any, error) {
+		switch request.Method {
+		case "echo":
+			var params map[string]string
+			json.Unmarshal(*request.Params, &params)
+			return params["text"] + suffix, nil
+		case "ask":
+			var answer []string
+			if err := connection.Call(context, "workspace/configuration", nil, &answer); err != nil {
+				return nil, err
+			}
+			return answer, nil
+		case "initialized":
+			return nil, nil
+		}
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound}
+	})
+}
+
+// record runs a client session against echoHandler and returns its log.
+func record(t *testing.T) []Entry {
+	t.Helper()
+	var log bytes.Buffer
+	recorder := NewRecorder(&log)
+
+	client := jsonrpc2.NewConn(contextpkg.Background(), serve(t, echoHandler(""), recorder),
+		jsonrpc2.HandlerWithError(func(contextpkg.Context, *jsonrpc2.Conn, *jsonrpc2.Request) (any, error) {
+			return []string{"tabs"}, nil
+		}))
+	defer client.Close()
+
+	context := contextpkg.Background()
+	var result any
+	if err := client.Notify(context, "initialized", map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(context, "echo", map[string]string{"text": "hello"}, &result); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(context, "ask", nil, &result); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(context, "unknown", nil, &result); err == nil {
+		t.Fatal("expected error for unknown method")
+	}
+	if err := recorder.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadLog(&log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestRecorderStream(t *testing.T) {
+	entries := record(t)
+
+	// initialized, 3 requests with responses, and the configuration
+	// request with its response
+	if len(entries) != 9 {
+		t.Fatalf("expected 9 entries, got %d", len(entries))
+	}
+
+	var m message
+	if err := json.Unmarshal(entries[0].Message, &m); err != nil {
+		t.Fatal(err)
+	}
+	if entries[0].Direction != Inbound || m.Method != "initialized" {
+		t.Errorf("expected inbound initialized first, got %s %s", entries[0].Direction, entries[0].Message)
+	}
+	if err := json.Unmarshal(entries[2].Message, &m); err != nil {
+		t.Fatal(err)
+	}
+	if entries[2].Direction != Outbound || string(m.Result) != `"hello"` {
+		t.Errorf("expected outbound echo response, got %s %s", entries[2].Direction, entries[2].Message)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	entries := record(t)
+
+	results, err := Replay(contextpkg.Background(), serve(t, echoHandler(""), nil), entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	for _, r := range results {
+		if r.Mismatch || r.Err != nil {
+			t.Errorf("%s: expected the same response, got mismatch %v and error %v", r.Method, r.Mismatch, r.Err)
+		}
+		if r.Recorded <= 0 || r.Duration <= 0 {
+			t.Errorf("%s: expected latencies, got %v and %v", r.Method, r.Recorded, r.Duration)
+		}
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	entries := record(t)
+
+	results, err := Replay(contextpkg.Background(), serve(t, echoHandler("!"), nil), entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results[0].Method != "echo" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if !results[0].Mismatch {
+		t.Error("expected changed echo response to be reported")
+	}
+	if results[1].Mismatch || results[2].Mismatch {
+		t.Error("expected other responses to match")
+	}
+}
+
+func TestEqualJSON(t *testing.T) {
+	if !equalJSON(json.RawMessage(`{"a":1,"b":[2]}`), json.RawMessage(`{ "b": [2], "a": 1 }`)) {
+		t.Error("expected key order and whitespace to be ignored")
+	}
+	if !equalJSON(nil, json.RawMessage(`null`)) {
+		t.Error("expected missing result to equal null")
+	}
+	if equalJSON(json.RawMessage(`1`), json.RawMessage(`2`)) {
+		t.Error("expected different values to differ")
+	}
+}
@@ -0,0 +1,145 @@
+package rules
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+// Source is the source of the diagnostics of an Engine.
+const Source = "rules"
+
+// Engine checks documents with a set of rules, which can be replaced at
+// runtime, e.g. when the rules file changes. It is a
+// core.DiagnosticProvider, and a core.CodeFixProvider offering the fixes
+// of the rules as quick fixes. It is safe for concurrent use.
+type Engine struct {
+	rules atomic.Pointer[RuleSet]
+}
+
+// NewEngine creates an engine checking documents with set, which may be
+// nil for no rules.
+func NewEngine(set *RuleSet) *Engine {
+	e := &Engine{}
+	e.SetRules(set)
+	return e
+}
+
+// Rules returns the rules of the engine.
+func (e *Engine) Rules() *RuleSet {
+	if set := e.rules.Load(); set != nil {
+		return set
+	}
+	return &RuleSet{}
+}
+
+// SetRules replaces the rules of the engine. Documents are checked with
+// the new rules from the next call.
+func (e *Engine) SetRules(set *RuleSet) {
+	e.rules.Store(set)
+}
+
+// LoadFile replaces the rules of the engine with those of a file. If the
+// file does not load, the rules are left as they are.
+func (e *Engine) LoadFile(path string) error {
+	set, err := Load(path)
+	if err != nil {
+		return err
+	}
+	e.SetRules(set)
+	return nil
+}
+
+// ([core.DiagnosticProvider] interface)
+func (e *Engine) ProvideDiagnostics(uri, content string) []core.Diagnostic {
+	var diagnostics []core.Diagnostic
+	for _, rule := range e.Rules().Rules {
+		if !rule.Applies(uri) {
+			continue
+		}
+		for _, m := range rule.matches(content) {
+			severity := rule.severity
+			code := core.NewStringCode(rule.ID)
+			diagnostics = append(diagnostics, core.Diagnostic{
+				Range:    matchRange(content, m),
+				Severity: &severity,
+				Code:     &code,
+				Source:   Source,
+				Message:  expand(rule.Message, m.captures),
+			})
+		}
+	}
+	return diagnostics
+}
+
+// ([core.CodeFixProvider] interface)
+func (e *Engine) ProvideCodeFixes(ctx core.CodeFixContext) []core.CodeAction {
+	if !kindRequested(ctx.Only, core.CodeActionKindQuickFix) {
+		return nil
+	}
+
+	set := e.Rules()
+	var actions []core.CodeAction
+	for _, diagnostic := range ctx.Diagnostics {
+		if diagnostic.Source != Source || diagnostic.Code == nil {
+			continue
+		}
+		rule, ok := set.Rule(diagnostic.Code.String())
+		if !ok || rule.Fix == nil || !rule.Applies(ctx.URI) {
+			continue
+		}
+		// The diagnostic is of the match at its range, if the content
+		// has not changed since
+		for _, m := range rule.matches(ctx.Content) {
+			if matchRange(ctx.Content, m) != diagnostic.Range {
+				continue
+			}
+			actions = append(actions, rule.fixAction(ctx.URI, diagnostic, m))
+			break
+		}
+	}
+	return actions
+}
+
+// fixAction returns the action applying the fix of the rule to a match.
+func (r *Rule) fixAction(uri string, diagnostic core.Diagnostic, m match) core.CodeAction {
+	title := "Fix " + r.ID
+	if r.Fix.Title != "" {
+		title = expand(r.Fix.Title, m.captures)
+	}
+	kind := core.CodeActionKindQuickFix
+	return core.CodeAction{
+		Title:       title,
+		Kind:        &kind,
+		Diagnostics: []core.Diagnostic{diagnostic},
+		IsPreferred: true,
+		Edit: &core.WorkspaceEdit{
+			Changes: map[string][]core.TextEdit{
+				uri: {{Range: diagnostic.Range, NewText: expand(r.Fix.Replace, m.captures)}},
+			},
+		},
+	}
+}
+
+// matchRange returns the range of a match.
+func matchRange(content string, m match) core.Range {
+	return core.Range{
+		Start: core.ByteOffsetToPosition(content, m.start),
+		End:   core.ByteOffsetToPosition(content, m.end),
+	}
+}
+
+// kindRequested reports whether actions of kind were requested: if only
+// is empty, or names kind or a parent of it.
+func kindRequested(only []core.CodeActionKind, kind core.CodeActionKind) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, requested := range only {
+		if requested == kind || strings.HasPrefix(string(kind), string(requested)+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SCKelemen/lsp/core"
+)
+
+const testRules = `
+rules:
+  - id: no-println
+    message: "Use the logger instead of fmt.Println"
+    pattern: "fmt.Println($args...)"
+    fix:
+      title: "Log $args"
+      replace: "log.Print($args)"
+  - id: todo-owner
+    message: "TODO without an owner"
+    severity: information
+    regex: 'TODO:'
+`
+
+func TestEngine(t *testing.T) {
+	set, err := Parse([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewEngine(set)
+
+	uri := "file:///a.go"
+	content := "package a\n\n// TODO: log\nfunc f() {\n\tfmt.Println(\"héllo\", 1)\n}\n"
+	diagnostics := engine.ProvideDiagnostics(uri, content)
+	if len(diagnostics) != 2 {
+		t.Fatalf("diagnostics = %+v, want 2", diagnostics)
+	}
+	println, todo := diagnostics[0], diagnostics[1]
+	if println.Code.String() != "no-println" || println.Source != Source || *println.Severity != core.SeverityWarning {
+		t.Errorf("println diagnostic = %+v", println)
+	}
+	if want := (core.Range{Start: core.Position{Line: 4, Character: 1}, End: core.Position{Line: 4, Character: 25}}); println.Range != want {
+		t.Errorf("println range = %v, want %v", println.Range, want)
+	}
+	if todo.Message != "TODO without an owner" || *todo.Severity != core.SeverityInformation {
+		t.Errorf("todo diagnostic = %+v", todo)
+	}
+
+	actions := engine.ProvideCodeFixes(core.CodeFixContext{URI: uri, Content: content, Diagnostics: diagnostics})
+	if len(actions) != 1 {
+		t.Fatalf("actions = %+v, want the println fix", actions)
+	}
+	if actions[0].Title != `Log "héllo", 1` || *actions[0].Kind != core.CodeActionKindQuickFix {
+		t.Errorf("action = %+v", actions[0])
+	}
+	edits := actions[0].Edit.Changes[uri]
+	if len(edits) != 1 || edits[0].NewText != `log.Print("héllo", 1)` || edits[0].Range != println.Range {
+		t.Errorf("edits = %+v", edits)
+	}
+
+	// Fixes are quick fixes, and not offered once the match is gone
+	if actions := engine.ProvideCodeFixes(core.CodeFixContext{URI: uri, Content: content, Diagnostics: diagnostics, Only: []core.CodeActionKind{core.CodeActionKindRefactor}}); len(actions) != 0 {
+		t.Errorf("refactorings = %+v, want none", actions)
+	}
+	if actions := engine.ProvideCodeFixes(core.CodeFixContext{URI: uri, Content: "package a\n", Diagnostics: diagnostics}); len(actions) != 0 {
+		t.Errorf("actions for changed content = %+v, want none", actions)
+	}
+}
+
+func TestEngineLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	engine := NewEngine(nil)
+	if diagnostics := engine.ProvideDiagnostics("file:///a.go", "// TODO: x\n"); len(diagnostics) != 0 {
+		t.Errorf("diagnostics without rules = %+v", diagnostics)
+	}
+
+	if err := os.WriteFile(path, []byte(testRules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if diagnostics := engine.ProvideDiagnostics("file:///a.go", "// TODO: x\n"); len(diagnostics) != 1 {
+		t.Errorf("diagnostics after loading = %+v, want 1", diagnostics)
+	}
+
+	// Invalid rules keep the previous ones
+	if err := os.WriteFile(path, []byte("rules: [{id: broken}]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.LoadFile(path); err == nil {
+		t.Error("expected an error for invalid rules")
+	}
+	if len(engine.Rules().Rules) != 2 {
+		t.Errorf("rules after a failed load = %d, want the previous 2", len(engine.Rules().Rules))
+	}
+}
@@ -0,0 +1,182 @@
+package rules
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const (
+	// metaPrefix and restPrefix start the identifiers standing for the
+	// metavariables of a pattern once parsed.
+	metaPrefix = "__rules_meta_"
+	restPrefix = "__rules_rest_"
+)
+
+// metavariable matches the metavariables of a pattern: $name, or $name...
+// for the remaining arguments of a call.
+var metavariable = regexp.MustCompile(`\$(\w+)(\.\.\.)?`)
+
+// pattern is a compiled Go expression pattern.
+type pattern struct {
+	expr ast.Expr
+}
+
+func compilePattern(source string) (*pattern, error) {
+	source = metavariable.ReplaceAllStringFunc(source, func(variable string) string {
+		if name, ok := strings.CutSuffix(variable[1:], "..."); ok {
+			return restPrefix + name
+		}
+		return metaPrefix + variable[1:]
+	})
+	expr, err := parser.ParseExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	return &pattern{expr: expr}, nil
+}
+
+// matches returns the expressions of a Go file matching the pattern. Files
+// with syntax errors are matched as far as they parse.
+func (p *pattern) matches(content string) []match {
+	fset := token.NewFileSet()
+	file, _ := parser.ParseFile(fset, "", content, parser.SkipObjectResolution)
+	if file == nil {
+		return nil
+	}
+	tokenFile := fset.File(file.Pos())
+
+	var matches []match
+	ast.Inspect(file, func(node ast.Node) bool {
+		expr, ok := node.(ast.Expr)
+		if !ok {
+			return true
+		}
+		m := &matcher{content: content, file: tokenFile, captures: make(map[string]string)}
+		if m.match(reflect.ValueOf(p.expr), reflect.ValueOf(expr)) {
+			start, end := tokenFile.Offset(expr.Pos()), tokenFile.Offset(expr.End())
+			m.captures["0"] = content[start:end]
+			matches = append(matches, match{start: start, end: end, captures: m.captures})
+		}
+		return true
+	})
+	return matches
+}
+
+var (
+	posType      = reflect.TypeFor[token.Pos]()
+	objectType   = reflect.TypeFor[*ast.Object]()
+	scopeType    = reflect.TypeFor[*ast.Scope]()
+	commentsType = reflect.TypeFor[*ast.CommentGroup]()
+)
+
+// matcher matches a pattern against a syntax tree, capturing the source
+// of the expressions matched by metavariables.
+type matcher struct {
+	content  string
+	file     *token.File
+	captures map[string]string
+}
+
+// match reports whether the pattern p matches the node n, comparing
+// their fields but positions, comments, and scopes.
+func (m *matcher) match(p, n reflect.Value) bool {
+	if p.Kind() == reflect.Interface {
+		if p.IsNil() || n.IsNil() {
+			return p.IsNil() && n.IsNil()
+		}
+		p, n = p.Elem(), n.Elem()
+	}
+	if ident, ok := p.Interface().(*ast.Ident); ok && ident != nil {
+		if name, ok := strings.CutPrefix(ident.Name, metaPrefix); ok {
+			if n.Kind() == reflect.Pointer && n.IsNil() {
+				return false
+			}
+			expr, ok := n.Interface().(ast.Expr)
+			return ok && m.capture(name, expr.Pos(), expr.End())
+		}
+	}
+	if p.Type() != n.Type() {
+		return false
+	}
+
+	switch p.Kind() {
+	case reflect.Pointer:
+		if p.IsNil() || n.IsNil() {
+			return p.IsNil() && n.IsNil()
+		}
+		return m.match(p.Elem(), n.Elem())
+	case reflect.Struct:
+		for i := 0; i < p.NumField(); i++ {
+			switch p.Type().Field(i).Type {
+			case posType, objectType, scopeType, commentsType:
+				continue
+			}
+			if !m.match(p.Field(i), n.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		return m.matchSlice(p, n)
+	default:
+		return p.Interface() == n.Interface()
+	}
+}
+
+// matchSlice matches slices element by element. A last element of the
+// pattern that is a $name... metavariable matches the remaining elements.
+func (m *matcher) matchSlice(p, n reflect.Value) bool {
+	length := p.Len()
+	if length > 0 {
+		if ident, ok := p.Index(length - 1).Interface().(*ast.Ident); ok && ident != nil {
+			if name, ok := strings.CutPrefix(ident.Name, restPrefix); ok {
+				if n.Len() < length-1 {
+					return false
+				}
+				for i := 0; i < length-1; i++ {
+					if !m.match(p.Index(i), n.Index(i)) {
+						return false
+					}
+				}
+				if n.Len() == length-1 {
+					return m.captureText(name, "")
+				}
+				first, _ := n.Index(length - 1).Interface().(ast.Node)
+				last, _ := n.Index(n.Len() - 1).Interface().(ast.Node)
+				return first != nil && last != nil && m.capture(name, first.Pos(), last.End())
+			}
+		}
+	}
+
+	if length != n.Len() {
+		return false
+	}
+	for i := 0; i < length; i++ {
+		if !m.match(p.Index(i), n.Index(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// capture captures the source from pos to end under name.
+func (m *matcher) capture(name string, pos, end token.Pos) bool {
+	return m.captureText(name, m.content[m.file.Offset(pos):m.file.Offset(end)])
+}
+
+// captureText captures text under name, or reports whether it is the text
+// captured under name before. $_ captures nothing.
+func (m *matcher) captureText(name, text string) bool {
+	if name == "_" {
+		return true
+	}
+	if captured, ok := m.captures[name]; ok {
+		return captured == text
+	}
+	m.captures[name] = text
+	return true
+}
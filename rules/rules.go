@@ -0,0 +1,251 @@
+// Package rules is a lint engine for checks declared as data rather than
+// code, so that teams can ship custom checks without recompiling the
+// server.
+//
+// A rule matches a regular expression, or a Go expression pattern, and
+// reports the matches with a message and a severity. It may offer a fix,
+// replacing the match with a template. Rules are loaded from YAML, or
+// JSON, at runtime:
+//
+//	rules:
+//	  - id: todo-owner
+//	    message: "TODO without an owner"
+//	    severity: information
+//	    regex: 'TODO(:|\s)'
+//	  - id: no-println
+//	    message: "Use the logger instead of fmt.Println"
+//	    pattern: "fmt.Println($args...)"
+//	    fix:
+//	      title: "Log with log.Print"
+//	      replace: "log.Print($args)"
+//
+// In messages and fixes, $name and ${name} expand to the captures of the
+// match: the groups of a regular expression by number or name, and the
+// metavariables of a pattern. $0 expands to the whole match, and $$ to a
+// dollar sign.
+//
+// In patterns, $name matches any expression, and the same expression
+// wherever it appears again; $_ matches any expression without capturing
+// it. As the last argument of a call, $name... matches the remaining
+// arguments, possibly none.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/SCKelemen/lsp/core"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule is a declarative check.
+type Rule struct {
+	// ID identifies the rule, and is the code of its diagnostics.
+	ID string `yaml:"id"`
+
+	// Message is the message of the diagnostics, a template.
+	Message string `yaml:"message"`
+
+	// Severity is error, warning, information, or hint. If empty, it is
+	// warning.
+	Severity string `yaml:"severity,omitempty"`
+
+	// Files are the glob patterns of the names of the files checked, like
+	// "*.go". If empty, every file is checked, or Go files for patterns.
+	Files []string `yaml:"files,omitempty"`
+
+	// Regex is the regular expression matched, in the syntax of package
+	// regexp. A rule has either Regex or Pattern.
+	Regex string `yaml:"regex,omitempty"`
+
+	// Pattern is the Go expression pattern matched.
+	Pattern string `yaml:"pattern,omitempty"`
+
+	// Fix, if set, offers to replace the matches.
+	Fix *Fix `yaml:"fix,omitempty"`
+
+	severity core.DiagnosticSeverity
+	regex    *regexp.Regexp
+	pattern  *pattern
+}
+
+// Fix is the fix of a rule.
+type Fix struct {
+	// Title is the title of the code action, a template. If empty, it is
+	// "Fix " followed by the ID of the rule.
+	Title string `yaml:"title,omitempty"`
+
+	// Replace is the replacement of the match, a template.
+	Replace string `yaml:"replace"`
+}
+
+// RuleSet is a set of compiled rules.
+type RuleSet struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// Parse parses and compiles rules from YAML or JSON.
+func Parse(data []byte) (*RuleSet, error) {
+	var set RuleSet
+	if err := yaml.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	if err := set.Compile(); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// Load parses and compiles the rules of a file.
+func Load(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return set, nil
+}
+
+// Compile validates and compiles the rules of a set built in code.
+func (s *RuleSet) Compile() error {
+	var errs []error
+	ids := make(map[string]bool)
+	for i, rule := range s.Rules {
+		if err := rule.compile(); err != nil {
+			errs = append(errs, fmt.Errorf("rule %d (%s): %w", i+1, rule.ID, err))
+			continue
+		}
+		if ids[rule.ID] {
+			errs = append(errs, fmt.Errorf("rule %d: duplicate id %s", i+1, rule.ID))
+		}
+		ids[rule.ID] = true
+	}
+	return errors.Join(errs...)
+}
+
+// Rule returns the rule with an ID.
+func (s *RuleSet) Rule(id string) (*Rule, bool) {
+	for _, rule := range s.Rules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return nil, false
+}
+
+var severities = map[string]core.DiagnosticSeverity{
+	"":            core.SeverityWarning,
+	"error":       core.SeverityError,
+	"warning":     core.SeverityWarning,
+	"information": core.SeverityInformation,
+	"info":        core.SeverityInformation,
+	"hint":        core.SeverityHint,
+}
+
+func (r *Rule) compile() error {
+	switch {
+	case r.ID == "":
+		return errors.New("missing id")
+	case r.Message == "":
+		return errors.New("missing message")
+	case (r.Regex == "") == (r.Pattern == ""):
+		return errors.New("needs either a regex or a pattern")
+	case r.Fix != nil && r.Fix.Replace == "" && r.Fix.Title == "":
+		return errors.New("fix without a replacement")
+	}
+
+	severity, ok := severities[strings.ToLower(r.Severity)]
+	if !ok {
+		return fmt.Errorf("unknown severity %q", r.Severity)
+	}
+	r.severity = severity
+	for _, glob := range r.Files {
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("files %q: %w", glob, err)
+		}
+	}
+
+	var err error
+	if r.Regex != "" {
+		r.regex, err = regexp.Compile(r.Regex)
+		if err != nil {
+			return fmt.Errorf("regex: %w", err)
+		}
+		return nil
+	}
+	r.pattern, err = compilePattern(r.Pattern)
+	if err != nil {
+		return fmt.Errorf("pattern: %w", err)
+	}
+	return nil
+}
+
+// Applies reports whether the rule checks the document uri.
+func (r *Rule) Applies(uri string) bool {
+	name := path.Base(uri)
+	if len(r.Files) == 0 {
+		return r.pattern == nil || strings.HasSuffix(name, ".go")
+	}
+	for _, glob := range r.Files {
+		if matched, _ := path.Match(glob, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// match is a match of a rule: byte offsets and captures.
+type match struct {
+	start, end int
+	captures   map[string]string
+}
+
+// matches returns the matches of the rule in content.
+func (r *Rule) matches(content string) []match {
+	if r.pattern != nil {
+		return r.pattern.matches(content)
+	}
+
+	var matches []match
+	names := r.regex.SubexpNames()
+	for _, indexes := range r.regex.FindAllStringSubmatchIndex(content, -1) {
+		if indexes[0] == indexes[1] {
+			continue
+		}
+		captures := make(map[string]string)
+		for group := 0; group*2 < len(indexes); group++ {
+			start, end := indexes[group*2], indexes[group*2+1]
+			if start < 0 {
+				continue
+			}
+			captures[fmt.Sprint(group)] = content[start:end]
+			if names[group] != "" {
+				captures[names[group]] = content[start:end]
+			}
+		}
+		matches = append(matches, match{start: indexes[0], end: indexes[1], captures: captures})
+	}
+	return matches
+}
+
+// templateVariable matches $$, $name, and ${name} in templates.
+var templateVariable = regexp.MustCompile(`\$(?:\$|(\w+)|\{(\w+)\})`)
+
+// expand expands the captures of a match in a template. Unknown names
+// expand to nothing.
+func expand(template string, captures map[string]string) string {
+	return templateVariable.ReplaceAllStringFunc(template, func(variable string) string {
+		if variable == "$$" {
+			return "$"
+		}
+		name := strings.Trim(variable, "${}")
+		return captures[name]
+	})
+}
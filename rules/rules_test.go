@@ -0,0 +1,120 @@
+package rules
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	set, err := Parse([]byte(`
+rules:
+  - id: todo
+    message: "TODO by $owner"
+    severity: hint
+    files: ["*.go", "*.md"]
+    regex: 'TODO\((?P<owner>\w+)\)'
+  - id: println
+    message: "println"
+    pattern: "fmt.Println($args...)"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Rules) != 2 {
+		t.Fatalf("parsed %d rules, want 2", len(set.Rules))
+	}
+	todo, ok := set.Rule("todo")
+	if !ok || todo.regex == nil || todo.pattern != nil {
+		t.Fatalf("todo rule = %+v", todo)
+	}
+	for uri, want := range map[string]bool{"file:///a.go": true, "file:///README.md": true, "file:///a.txt": false} {
+		if got := todo.Applies(uri); got != want {
+			t.Errorf("todo applies to %s = %v, want %v", uri, got, want)
+		}
+	}
+	println, _ := set.Rule("println")
+	if println.Applies("file:///a.txt") || !println.Applies("file:///a.go") {
+		t.Error("pattern rules apply to Go files by default")
+	}
+
+	// JSON is YAML
+	if _, err := Parse([]byte(`{"rules": [{"id": "x", "message": "x", "regex": "x"}]}`)); err != nil {
+		t.Errorf("parsing JSON: %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"missing id":                  `{"rules": [{"message": "m", "regex": "x"}]}`,
+		"missing message":             `{"rules": [{"id": "a", "regex": "x"}]}`,
+		"either a regex or a pattern": `{"rules": [{"id": "a", "message": "m", "regex": "x", "pattern": "x"}]}`,
+		"unknown severity":            `{"rules": [{"id": "a", "message": "m", "regex": "x", "severity": "fatal"}]}`,
+		"regex":                       `{"rules": [{"id": "a", "message": "m", "regex": "("}]}`,
+		"pattern":                     `{"rules": [{"id": "a", "message": "m", "pattern": "f("}]}`,
+		"duplicate id":                `{"rules": [{"id": "a", "message": "m", "regex": "x"}, {"id": "a", "message": "m", "regex": "y"}]}`,
+	}
+	for want, data := range tests {
+		if _, err := Parse([]byte(data)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want one about %s", err, want)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	captures := map[string]string{"0": "all", "1": "one", "name": "value"}
+	if got := expand("$0 ${1}x $name $$name $missing.", captures); got != "all onex value $name ." {
+		t.Errorf("expand = %q", got)
+	}
+}
+
+func TestPatternMatches(t *testing.T) {
+	content := `package a
+
+import "fmt"
+
+func f(x, y int) {
+	fmt.Println()
+	fmt.Println("a", x)
+	fmt.Printf("%d", x)
+	_ = x == x
+	_ = x == y
+	_ = len(fmt.Sprint(y))
+}
+`
+	tests := []struct {
+		pattern string
+		want    []map[string]string
+	}{
+		{"fmt.Println($args...)", []map[string]string{
+			{"0": "fmt.Println()", "args": ""},
+			{"0": `fmt.Println("a", x)`, "args": `"a", x`},
+		}},
+		{"fmt.Printf($format, $_)", []map[string]string{
+			{"0": `fmt.Printf("%d", x)`, "format": `"%d"`},
+		}},
+		// A metavariable matches the same expression wherever it appears
+		{"$x == $x", []map[string]string{
+			{"0": "x == x", "x": "x"},
+		}},
+		{"len($s)", []map[string]string{
+			{"0": "len(fmt.Sprint(y))", "s": "fmt.Sprint(y)"},
+		}},
+	}
+	for _, test := range tests {
+		p, err := compilePattern(test.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []map[string]string
+		for _, m := range p.matches(content) {
+			if content[m.start:m.end] != m.captures["0"] {
+				t.Errorf("%s: match at %d-%d is not %q", test.pattern, m.start, m.end, m.captures["0"])
+			}
+			got = append(got, m.captures)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s matches %v, want %v", test.pattern, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package schedule
+
+import (
+	"errors"
+	"sync"
+)
+
+// Priority is the queue of a task of a Pool.
+type Priority int
+
+const (
+	// Background tasks, like indexing, diagnostics, and code lenses, run
+	// when no interactive task is waiting.
+	Background Priority = iota
+
+	// Interactive tasks, like completion, hover, and signature help, are
+	// what the user waits on while typing.
+	Interactive
+)
+
+// ErrPoolClosed is returned for tasks submitted to a closed Pool.
+var ErrPoolClosed = errors.New("pool is closed")
+
+// MethodPriority returns the priority of the requests of method: Interactive
+// for DefaultInteractiveMethods, Background otherwise.
+func MethodPriority(method string) Priority {
+	if DefaultInteractiveMethods[method] {
+		return Interactive
+	}
+	return Background
+}
+
+// Pool runs tasks on two sets of workers, so background work such as
+// indexing a workspace never starves the requests of a typing user.
+//
+// Interactive workers only run interactive tasks, so they are free for the
+// next keystroke however many background tasks are queued. Background
+// workers run background tasks, but take a waiting interactive task first.
+// Tasks of the same priority start in the order they were submitted.
+type Pool struct {
+	mu          sync.Mutex
+	cond        *sync.Cond
+	interactive []func()
+	background  []func()
+	closed      bool
+	workers     sync.WaitGroup
+}
+
+// NewPool starts a pool with the given numbers of interactive and
+// background workers, at least one each.
+func NewPool(interactiveWorkers, backgroundWorkers int) *Pool {
+	p := &Pool{}
+	p.cond = sync.NewCond(&p.mu)
+	for range max(interactiveWorkers, 1) {
+		p.workers.Add(1)
+		go p.work(Interactive)
+	}
+	for range max(backgroundWorkers, 1) {
+		p.workers.Add(1)
+		go p.work(Background)
+	}
+	return p
+}
+
+// Go queues a task.
+func (p *Pool) Go(priority Priority, task func()) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return ErrPoolClosed
+	}
+	if priority == Interactive {
+		p.interactive = append(p.interactive, task)
+	} else {
+		p.background = append(p.background, task)
+	}
+	p.cond.Broadcast()
+	return nil
+}
+
+// Pending returns the number of queued tasks of priority that have not
+// started.
+func (p *Pool) Pending(priority Priority) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if priority == Interactive {
+		return len(p.interactive)
+	}
+	return len(p.background)
+}
+
+// Close stops accepting tasks, and waits for the queued ones to finish.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.mu.Unlock()
+
+	p.workers.Wait()
+}
+
+// work runs tasks until the pool is closed and its queues are empty.
+func (p *Pool) work(priority Priority) {
+	defer p.workers.Done()
+	for {
+		p.mu.Lock()
+		task := p.next(priority)
+		for task == nil {
+			if p.closed {
+				p.mu.Unlock()
+				return
+			}
+			p.cond.Wait()
+			task = p.next(priority)
+		}
+		p.mu.Unlock()
+
+		task()
+	}
+}
+
+// next dequeues the next task for a worker of priority, if any.
+func (p *Pool) next(priority Priority) func() {
+	var queue *[]func()
+	switch {
+	case len(p.interactive) > 0:
+		queue = &p.interactive
+	case priority == Background && len(p.background) > 0:
+		queue = &p.background
+	default:
+		return nil
+	}
+	task := (*queue)[0]
+	(*queue)[0] = nil
+	*queue = (*queue)[1:]
+	return task
+}
@@ -0,0 +1,114 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SCKelemen/lsp/protocol"
+)
+
+func TestPool_InteractiveNotStarved(t *testing.T) {
+	pool := NewPool(1, 2)
+	defer pool.Close()
+
+	// Occupy every background worker and queue more background work
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	for range 10 {
+		pool.Go(Background, func() {
+			started <- struct{}{}
+			<-release
+		})
+	}
+	defer close(release)
+	<-started
+	<-started
+
+	done := make(chan struct{})
+	pool.Go(Interactive, func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("interactive task waited for background tasks")
+	}
+	if n := pool.Pending(Background); n != 8 {
+		t.Errorf("%d background tasks pending, want 8", n)
+	}
+}
+
+func TestPool_Next(t *testing.T) {
+	tests := []struct {
+		name        string
+		interactive int
+		background  int
+		priority    Priority
+		want        string
+	}{
+		{name: "interactive worker", interactive: 1, background: 1, priority: Interactive, want: "interactive"},
+		{name: "interactive worker skips background", background: 1, priority: Interactive, want: ""},
+		{name: "background worker prefers interactive", interactive: 1, background: 1, priority: Background, want: "interactive"},
+		{name: "background worker", background: 1, priority: Background, want: "background"},
+		{name: "empty", priority: Background, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ran string
+			pool := &Pool{}
+			for range tt.interactive {
+				pool.interactive = append(pool.interactive, func() { ran = "interactive" })
+			}
+			for range tt.background {
+				pool.background = append(pool.background, func() { ran = "background" })
+			}
+			if task := pool.next(tt.priority); task != nil {
+				task()
+			}
+			if ran != tt.want {
+				t.Errorf("ran %q, want %q", ran, tt.want)
+			}
+		})
+	}
+}
+
+func TestPool_Closed(t *testing.T) {
+	pool := NewPool(1, 1)
+	ran := false
+	pool.Go(Background, func() { ran = true })
+	pool.Close()
+	if !ran {
+		t.Error("Close did not wait for the queued task")
+	}
+	if err := pool.Go(Interactive, func() {}); err != ErrPoolClosed {
+		t.Errorf("err = %v, want ErrPoolClosed", err)
+	}
+}
+
+func TestMethodPriority(t *testing.T) {
+	tests := []struct {
+		method string
+		want   Priority
+	}{
+		{protocol.MethodTextDocumentCompletion, Interactive},
+		{protocol.MethodTextDocumentSignatureHelp, Interactive},
+		{protocol.MethodTextDocumentCodeLens, Background},
+		{protocol.MethodWorkspaceSymbol, Background},
+	}
+	for _, tt := range tests {
+		if got := MethodPriority(tt.method); got != tt.want {
+			t.Errorf("MethodPriority(%s) = %d, want %d", tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestScheduler_Pool(t *testing.T) {
+	pool := NewPool(1, 1)
+	defer pool.Close()
+	recorder := newAnalysisRecorder(false)
+	scheduler := NewScheduler(recorder.analyze)
+	scheduler.Delay = time.Millisecond
+	scheduler.Pool = pool
+	defer scheduler.Stop()
+
+	scheduler.Changed("file:///a.go")
+	waitStarted(t, recorder, "file:///a.go")
+}
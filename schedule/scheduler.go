@@ -0,0 +1,267 @@
+// Package schedule runs the work of a server in the order the user
+// notices it: requests the user is waiting on, like completion and hover,
+// come before background work, like analyzing a document after each
+// keystroke.
+package schedule
+
+import (
+	contextpkg "context"
+	"sync"
+	"time"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/internal/textdocument"
+	"github.com/SCKelemen/lsp/protocol"
+)
+
+// DefaultAnalysisDelay is how long a Scheduler waits for a document to stop
+// changing before analyzing it.
+const DefaultAnalysisDelay = 300 * time.Millisecond
+
+// DefaultInteractiveMethods are the requests that Handler runs ahead of
+// background analysis, and that MethodPriority makes Interactive.
+var DefaultInteractiveMethods = map[string]bool{
+	protocol.MethodTextDocumentCompletion:        true,
+	protocol.MethodCompletionItemResolve:         true,
+	protocol.MethodTextDocumentHover:             true,
+	protocol.MethodTextDocumentSignatureHelp:     true,
+	protocol.MethodTextDocumentDocumentHighlight: true,
+}
+
+// AnalyzeFunc analyzes a document, e.g. parses it and publishes its
+// diagnostics. It should return early once ctx is cancelled, which happens
+// when the document changed again or was closed.
+type AnalyzeFunc func(ctx contextpkg.Context, uri string)
+
+// Scheduler coalesces the changes of documents into analysis runs.
+//
+// A document is analyzed once it did not change during Delay, so typing
+// does not start one run per keystroke. A change cancels the analysis of
+// the document in flight, whose results would be stale, and runs of a
+// document never overlap. Runs wait while interactive requests begun with
+// BeginInteractive are in flight.
+type Scheduler struct {
+	Delay   time.Duration
+	Analyze AnalyzeFunc
+
+	// Pool, when set, runs the analysis as Background tasks.
+	Pool *Pool
+
+	mu          sync.Mutex
+	cond        *sync.Cond
+	documents   map[string]*scheduledDocument
+	interactive int
+	stopped     bool
+	runs        sync.WaitGroup
+}
+
+type scheduledDocument struct {
+	generation uint64
+	timer      *time.Timer
+	cancel     contextpkg.CancelFunc
+	running    bool
+
+	// analyzed is the generation analyzed last
+	analyzed uint64
+}
+
+// NewScheduler creates a scheduler calling analyze after DefaultAnalysisDelay.
+func NewScheduler(analyze AnalyzeFunc) *Scheduler {
+	s := &Scheduler{
+		Delay:     DefaultAnalysisDelay,
+		Analyze:   analyze,
+		documents: make(map[string]*scheduledDocument),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Changed schedules the analysis of a document that was opened or changed,
+// superseding the scheduled or running one.
+func (s *Scheduler) Changed(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return
+	}
+	document := s.documents[uri]
+	if document == nil {
+		document = &scheduledDocument{}
+		s.documents[uri] = document
+	}
+	document.generation++
+	if document.cancel != nil {
+		document.cancel()
+		document.cancel = nil
+	}
+	if document.timer != nil {
+		document.timer.Stop()
+	}
+	generation := document.generation
+	document.timer = time.AfterFunc(s.Delay, func() {
+		if s.Pool != nil {
+			// Dropped once the pool is closed
+			s.Pool.Go(Background, func() { s.run(uri, generation) })
+			return
+		}
+		s.run(uri, generation)
+	})
+}
+
+// Closed drops the scheduled analysis of a closed document and cancels the
+// running one.
+func (s *Scheduler) Closed(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if document := s.documents[uri]; document != nil {
+		document.generation++
+		s.drop(document)
+		if !document.running {
+			delete(s.documents, uri)
+		}
+	}
+}
+
+// BeginInteractive marks an interactive request as in flight until the
+// returned function is called. Analysis does not start meanwhile.
+func (s *Scheduler) BeginInteractive() func() {
+	s.mu.Lock()
+	s.interactive++
+	s.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.interactive--
+			s.cond.Broadcast()
+			s.mu.Unlock()
+		})
+	}
+}
+
+// Stop drops the scheduled analysis, cancels the running one, and waits
+// for it to return, e.g. on shutdown. Later changes are ignored.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	for _, document := range s.documents {
+		s.drop(document)
+	}
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	s.runs.Wait()
+}
+
+// Pending returns the number of documents whose analysis is scheduled or
+// running, like for a status bar.
+func (s *Scheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := 0
+	for _, document := range s.documents {
+		if document.analyzed != document.generation {
+			pending++
+		}
+	}
+	return pending
+}
+
+// drop stops the timer of a document and cancels its running analysis.
+func (s *Scheduler) drop(document *scheduledDocument) {
+	if document.timer != nil {
+		document.timer.Stop()
+		document.timer = nil
+	}
+	if document.cancel != nil {
+		document.cancel()
+		document.cancel = nil
+	}
+}
+
+// run analyzes a document, unless a later change superseded generation.
+func (s *Scheduler) run(uri string, generation uint64) {
+	s.mu.Lock()
+	document := s.documents[uri]
+	for !s.stopped && document != nil && document.generation == generation && (s.interactive > 0 || document.running) {
+		s.cond.Wait()
+	}
+	if s.stopped || document == nil || document.generation != generation {
+		s.mu.Unlock()
+		return
+	}
+	ctx, cancel := contextpkg.WithCancel(contextpkg.Background())
+	document.cancel = cancel
+	document.running = true
+	s.runs.Add(1)
+	s.mu.Unlock()
+
+	defer s.runs.Done()
+	defer cancel()
+	s.Analyze(ctx, uri)
+
+	s.mu.Lock()
+	document.running = false
+	if document.generation == generation {
+		document.cancel = nil
+		document.analyzed = generation
+	}
+	if s.documents[uri] == document && document.timer == nil && document.cancel == nil {
+		// Closed while running
+		delete(s.documents, uri)
+	}
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+// Handler is a handler middleware scheduling the analysis of documents as
+// they are opened and changed, and running interactive requests ahead of
+// it.
+type Handler struct {
+	// Handler handles the messages, before the analysis of changes is
+	// scheduled.
+	Handler lsp.Handler
+
+	Scheduler *Scheduler
+
+	// Interactive are the methods of interactive requests.
+	Interactive map[string]bool
+}
+
+// NewHandler creates a middleware scheduling analysis with scheduler and
+// treating DefaultInteractiveMethods as interactive.
+func NewHandler(handler lsp.Handler, scheduler *Scheduler) *Handler {
+	return &Handler{
+		Handler:     handler,
+		Scheduler:   scheduler,
+		Interactive: DefaultInteractiveMethods,
+	}
+}
+
+// ([lsp.Handler] interface)
+func (h *Handler) Handle(context *lsp.Context) (any, bool, bool, error) {
+	if h.Interactive[context.Method] {
+		done := h.Scheduler.BeginInteractive()
+		defer done()
+	}
+
+	result, validMethod, validParams, err := h.Handler.Handle(context)
+
+	switch context.Method {
+	case protocol.MethodTextDocumentDidOpen, protocol.MethodTextDocumentDidChange:
+		if uri := textdocument.URI(context.Params); uri != "" {
+			h.Scheduler.Changed(uri)
+		}
+	case protocol.MethodTextDocumentDidClose:
+		if uri := textdocument.URI(context.Params); uri != "" {
+			h.Scheduler.Closed(uri)
+		}
+	case protocol.MethodShutdown:
+		h.Scheduler.Stop()
+	}
+
+	return result, validMethod, validParams, err
+}
@@ -0,0 +1,183 @@
+package schedule
+
+import (
+	contextpkg "context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/protocol"
+)
+
+// analysisRecorder records the runs of an AnalyzeFunc.
+type analysisRecorder struct {
+	mu      sync.Mutex
+	runs    []string
+	started chan string
+	// block makes runs wait for their cancellation
+	block bool
+}
+
+func newAnalysisRecorder(block bool) *analysisRecorder {
+	return &analysisRecorder{started: make(chan string, 10), block: block}
+}
+
+func (r *analysisRecorder) analyze(ctx contextpkg.Context, uri string) {
+	r.mu.Lock()
+	r.runs = append(r.runs, uri)
+	r.mu.Unlock()
+	r.started <- uri
+	if r.block {
+		<-ctx.Done()
+	}
+}
+
+func (r *analysisRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.runs)
+}
+
+func waitStarted(t *testing.T, r *analysisRecorder, want string) {
+	t.Helper()
+	select {
+	case uri := <-r.started:
+		if uri != want {
+			t.Fatalf("analyzed %s, want %s", uri, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("%s was not analyzed", want)
+	}
+}
+
+func TestScheduler_Coalesces(t *testing.T) {
+	recorder := newAnalysisRecorder(false)
+	scheduler := NewScheduler(recorder.analyze)
+	scheduler.Delay = 20 * time.Millisecond
+	defer scheduler.Stop()
+
+	for range 5 {
+		scheduler.Changed("file:///a.go")
+	}
+	scheduler.Changed("file:///b.go")
+
+	time.Sleep(50 * time.Millisecond)
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	sort.Strings(recorder.runs)
+	if want := []string{"file:///a.go", "file:///b.go"}; !reflect.DeepEqual(recorder.runs, want) {
+		t.Errorf("analyzed %v, want %v", recorder.runs, want)
+	}
+}
+
+func TestScheduler_CancelsSuperseded(t *testing.T) {
+	recorder := newAnalysisRecorder(true)
+	scheduler := NewScheduler(recorder.analyze)
+	scheduler.Delay = time.Millisecond
+
+	scheduler.Changed("file:///a.go")
+	waitStarted(t, recorder, "file:///a.go")
+	// The change cancels the blocked run, which lets the next one start
+	scheduler.Changed("file:///a.go")
+	waitStarted(t, recorder, "file:///a.go")
+
+	scheduler.Closed("file:///a.go")
+	done := make(chan struct{})
+	go func() {
+		scheduler.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("closing the document did not cancel its analysis")
+	}
+}
+
+func TestScheduler_InteractiveFirst(t *testing.T) {
+	recorder := newAnalysisRecorder(false)
+	scheduler := NewScheduler(recorder.analyze)
+	scheduler.Delay = time.Millisecond
+	defer scheduler.Stop()
+
+	done := scheduler.BeginInteractive()
+	scheduler.Changed("file:///a.go")
+	time.Sleep(30 * time.Millisecond)
+	if n := recorder.count(); n != 0 {
+		t.Fatalf("%d runs during an interactive request, want 0", n)
+	}
+	done()
+	waitStarted(t, recorder, "file:///a.go")
+}
+
+// interactiveHandler records whether hovers are handled as interactive.
+type interactiveHandler struct {
+	scheduler   *Scheduler
+	interactive bool
+}
+
+func (h *interactiveHandler) Handle(context *lsp.Context) (any, bool, bool, error) {
+	if context.Method == protocol.MethodTextDocumentHover {
+		h.scheduler.mu.Lock()
+		h.interactive = h.scheduler.interactive == 1
+		h.scheduler.mu.Unlock()
+	}
+	return nil, true, true, nil
+}
+
+func TestHandler(t *testing.T) {
+	recorder := newAnalysisRecorder(false)
+	scheduler := NewScheduler(recorder.analyze)
+	scheduler.Delay = time.Millisecond
+
+	inner := &interactiveHandler{scheduler: scheduler}
+	handler := NewHandler(inner, scheduler)
+
+	params := json.RawMessage(`{"textDocument":{"uri":"file:///a.go","version":1}}`)
+	handler.Handle(&lsp.Context{Method: protocol.MethodTextDocumentDidOpen, Params: params})
+	waitStarted(t, recorder, "file:///a.go")
+
+	handler.Handle(&lsp.Context{Method: protocol.MethodTextDocumentHover, Params: params})
+	if !inner.interactive {
+		t.Error("hover was not handled as interactive")
+	}
+
+	handler.Handle(&lsp.Context{Method: protocol.MethodShutdown})
+	scheduler.Changed("file:///a.go")
+	time.Sleep(20 * time.Millisecond)
+	if n := recorder.count(); n != 1 {
+		t.Errorf("%d runs, want 1 as changes after shutdown are ignored", n)
+	}
+}
+
+func TestScheduler_Pending(t *testing.T) {
+	recorder := newAnalysisRecorder(false)
+	scheduler := NewScheduler(recorder.analyze)
+	scheduler.Delay = time.Hour
+	defer scheduler.Stop()
+
+	scheduler.Changed("file:///a.go")
+	scheduler.Changed("file:///b.go")
+	if pending := scheduler.Pending(); pending != 2 {
+		t.Errorf("Pending = %d with two scheduled documents, want 2", pending)
+	}
+	scheduler.Closed("file:///b.go")
+	if pending := scheduler.Pending(); pending != 1 {
+		t.Errorf("Pending = %d after closing a document, want 1", pending)
+	}
+
+	scheduler.Delay = time.Millisecond
+	scheduler.Changed("file:///a.go")
+	waitStarted(t, recorder, "file:///a.go")
+	deadline := time.Now().Add(time.Second)
+	for scheduler.Pending() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Pending = %d after the analysis, want 0", scheduler.Pending())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
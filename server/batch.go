@@ -0,0 +1,232 @@
+package server
+
+import (
+	"bytes"
+	contextpkg "context"
+	"encoding/json"
+	"sync"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// batchStream adds JSON-RPC batches to a stream, which jsonrpc2.Conn does
+// not support: the members of a batch array are read one by one, and the
+// responses to its requests are held back and written together as one
+// array once all are ready.
+//
+// Only the members of a batch may be handled concurrently; see
+// batchHandler.
+type batchStream struct {
+	stream jsonrpc2.ObjectStream
+
+	// writeMu serializes writes to stream, which come from the connection
+	// and, for invalid batches, from ReadObject
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	queue   []json.RawMessage
+	batches map[string]*batch // by the id of each request not answered yet
+}
+
+// batch collects the responses to the requests of a batch.
+type batch struct {
+	pending   int
+	responses []json.RawMessage
+}
+
+// messageFields holds the fields of a message telling requests,
+// notifications, and responses apart.
+type messageFields struct {
+	ID     json.RawMessage `json:"id"`
+	Method *string         `json:"method"`
+}
+
+func newBatchStream(stream jsonrpc2.ObjectStream) *batchStream {
+	return &batchStream{
+		stream:  stream,
+		batches: make(map[string]*batch),
+	}
+}
+
+// ([jsonrpc2.ObjectStream] interface)
+func (self *batchStream) ReadObject(v any) error {
+	for {
+		self.mu.Lock()
+		if len(self.queue) > 0 {
+			message := self.queue[0]
+			self.queue = self.queue[1:]
+			self.mu.Unlock()
+			return json.Unmarshal(message, v)
+		}
+		self.mu.Unlock()
+
+		var message json.RawMessage
+		if err := self.stream.ReadObject(&message); err != nil {
+			return err
+		}
+		message = bytes.TrimSpace(message)
+		if len(message) == 0 || message[0] != '[' {
+			return json.Unmarshal(message, v)
+		}
+		if err := self.readBatch(message); err != nil {
+			return err
+		}
+	}
+}
+
+// readBatch queues the members of a batch array.
+func (self *batchStream) readBatch(message json.RawMessage) error {
+	var members []json.RawMessage
+	if err := json.Unmarshal(message, &members); err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return self.writeRaw(invalidRequestResponse)
+	}
+
+	b := &batch{}
+	self.mu.Lock()
+	for _, member := range members {
+		var fields messageFields
+		if member = bytes.TrimSpace(member); len(member) == 0 || member[0] != '{' || json.Unmarshal(member, &fields) != nil {
+			// Answered right away, within the batch response
+			b.responses = append(b.responses, invalidRequestResponse)
+			continue
+		}
+		if fields.Method != nil && fields.ID != nil && !bytes.Equal(fields.ID, []byte("null")) {
+			key := idKey(fields.ID)
+			if _, ok := self.batches[key]; ok {
+				// The id of a request not answered yet, in this batch or
+				// another, would get both responses mixed up
+				b.responses = append(b.responses, errorResponse(fields.ID, jsonrpc2.CodeInvalidRequest, "duplicate request id"))
+				continue
+			}
+			b.pending++
+			self.batches[key] = b
+		}
+		self.queue = append(self.queue, member)
+	}
+	self.mu.Unlock()
+
+	if b.pending == 0 && len(b.responses) > 0 {
+		return self.writeBatch(b)
+	}
+	return nil
+}
+
+// ([jsonrpc2.ObjectStream] interface)
+func (self *batchStream) WriteObject(obj any) error {
+	message, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	var fields messageFields
+	if err := json.Unmarshal(message, &fields); err == nil && fields.Method == nil && fields.ID != nil {
+		self.mu.Lock()
+		b, ok := self.batches[idKey(fields.ID)]
+		if ok {
+			delete(self.batches, idKey(fields.ID))
+			b.responses = append(b.responses, message)
+			b.pending--
+		}
+		self.mu.Unlock()
+		if ok {
+			if b.pending > 0 {
+				return nil
+			}
+			return self.writeBatch(b)
+		}
+	}
+	return self.writeRaw(message)
+}
+
+// ([jsonrpc2.ObjectStream] interface)
+func (self *batchStream) Close() error {
+	return self.stream.Close()
+}
+
+// concurrent reports whether the request with id is a member of a batch
+// that has not been answered yet.
+func (self *batchStream) concurrent(id jsonrpc2.ID) bool {
+	key, err := json.Marshal(id)
+	if err != nil {
+		return false
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	_, ok := self.batches[idKey(key)]
+	return ok
+}
+
+// writeBatch writes the responses of a complete batch as one array.
+func (self *batchStream) writeBatch(b *batch) error {
+	message, err := json.Marshal(b.responses)
+	if err != nil {
+		return err
+	}
+	return self.writeRaw(message)
+}
+
+func (self *batchStream) writeRaw(message json.RawMessage) error {
+	self.writeMu.Lock()
+	defer self.writeMu.Unlock()
+	return self.stream.WriteObject(message)
+}
+
+// idKey returns the map key of a request id, so that 1 and "1" differ.
+func idKey(id json.RawMessage) string {
+	var buffer bytes.Buffer
+	if err := json.Compact(&buffer, id); err != nil {
+		return string(id)
+	}
+	return buffer.String()
+}
+
+// invalidRequestResponse is the response to a batch member that is not a
+// request, and to an empty batch.
+var invalidRequestResponse = errorResponse(nil, jsonrpc2.CodeInvalidRequest, "invalid request")
+
+// errorResponse returns an error response to the request with id, or with
+// a null id if the id is unknown.
+func errorResponse(id json.RawMessage, code int64, message string) json.RawMessage {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	response, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error":   &jsonrpc2.Error{Code: code, Message: message},
+	})
+	return response
+}
+
+// batchHandler handles the requests of a batch concurrently, and every
+// other message in order: a notification, like textDocument/didChange, or
+// a request outside a batch waits until all requests read before it are
+// done, and messages read after it wait for it.
+type batchHandler struct {
+	handler jsonrpc2.Handler
+	stream  *batchStream
+
+	inflight sync.WaitGroup
+}
+
+func newBatchHandler(handler jsonrpc2.Handler, stream *batchStream) *batchHandler {
+	return &batchHandler{handler: handler, stream: stream}
+}
+
+// ([jsonrpc2.Handler] interface)
+func (self *batchHandler) Handle(context contextpkg.Context, connection *jsonrpc2.Conn, request *jsonrpc2.Request) {
+	if !request.Notif && self.stream.concurrent(request.ID) {
+		self.inflight.Add(1)
+		go func() {
+			defer self.inflight.Done()
+			self.handler.Handle(context, connection, request)
+		}()
+		return
+	}
+
+	self.inflight.Wait()
+	self.handler.Handle(context, connection, request)
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// orderHandler records the methods it handles. "wait" blocks until
+// "release" is handled.
+type orderHandler struct {
+	mu      sync.Mutex
+	events  []string
+	release chan struct{}
+}
+
+func (h *orderHandler) Handle(context *lsp.Context) (any, bool, bool, error) {
+	switch context.Method {
+	case "wait":
+		select {
+		case <-h.release:
+		case <-time.After(2 * time.Second):
+		}
+	case "release":
+		close(h.release)
+	}
+	h.mu.Lock()
+	h.events = append(h.events, context.Method)
+	h.mu.Unlock()
+	return context.Method, true, true, nil
+}
+
+func newBatchTestConnection(t *testing.T, handler lsp.Handler) jsonrpc2.ObjectStream {
+	serverSide, clientSide := net.Pipe()
+	server := NewServer(handler, "server-test-batch", false)
+	connection := server.newStreamConnection(serverSide)
+	client := jsonrpc2.NewBufferedStream(clientSide, jsonrpc2.VSCodeObjectCodec{})
+	t.Cleanup(func() {
+		client.Close()
+		connection.Close()
+	})
+	return client
+}
+
+// readMessage reads the next message from the server, failing the test
+// after a timeout.
+func readMessage(t *testing.T, client jsonrpc2.ObjectStream) json.RawMessage {
+	t.Helper()
+	messages := make(chan json.RawMessage, 1)
+	go func() {
+		var message json.RawMessage
+		if err := client.ReadObject(&message); err == nil {
+			messages <- message
+		}
+	}()
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(3 * time.Second):
+		t.Fatal("no message from the server")
+		return nil
+	}
+}
+
+type testResponse struct {
+	ID     *jsonrpc2.ID    `json:"id"`
+	Result string          `json:"result"`
+	Error  *jsonrpc2.Error `json:"error"`
+}
+
+func TestBatchRequestsConcurrently(t *testing.T) {
+	handler := &orderHandler{release: make(chan struct{})}
+	client := newBatchTestConnection(t, handler)
+
+	// Handled one at a time, "wait" would block until it times out before
+	// "release" is handled
+	batch := json.RawMessage(`[
+		{"jsonrpc":"2.0","id":1,"method":"wait"},
+		{"jsonrpc":"2.0","id":"two","method":"release"},
+		{"jsonrpc":"2.0","method":"notify"},
+		{"jsonrpc":"2.0","id":3,"method":"after"}
+	]`)
+	start := time.Now()
+	if err := client.WriteObject(batch); err != nil {
+		t.Fatal(err)
+	}
+
+	var responses []testResponse
+	if err := json.Unmarshal(readMessage(t, client), &responses); err != nil {
+		t.Fatalf("expected a batch response: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the requests of the batch to run concurrently, took %s", elapsed)
+	}
+	if len(responses) != 3 {
+		t.Fatalf("expected a response per request, got %+v", responses)
+	}
+	results := map[string]string{}
+	for _, response := range responses {
+		results[response.ID.String()] = response.Result
+	}
+	if results["1"] != "wait" || results[`"two"`] != "release" || results["3"] != "after" {
+		t.Errorf("unexpected responses %+v", responses)
+	}
+
+	// The notification waits for the requests before it, and the request
+	// after it waits for the notification
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	if len(handler.events) != 4 || handler.events[2] != "notify" || handler.events[3] != "after" {
+		t.Errorf("expected the notification between the requests, got %v", handler.events)
+	}
+}
+
+func TestBatchInvalid(t *testing.T) {
+	client := newBatchTestConnection(t, &orderHandler{release: make(chan struct{})})
+
+	if err := client.WriteObject(json.RawMessage(`[]`)); err != nil {
+		t.Fatal(err)
+	}
+	var response testResponse
+	if err := json.Unmarshal(readMessage(t, client), &response); err != nil || response.Error == nil || response.Error.Code != jsonrpc2.CodeInvalidRequest {
+		t.Fatalf("expected an invalid request error for an empty batch, got %+v (%v)", response, err)
+	}
+
+	if err := client.WriteObject(json.RawMessage(`[1, {"jsonrpc":"2.0","id":3,"method":"ping"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	var responses []testResponse
+	if err := json.Unmarshal(readMessage(t, client), &responses); err != nil {
+		t.Fatalf("expected a batch response: %v", err)
+	}
+	if len(responses) != 2 || responses[0].Error == nil || responses[0].ID != nil || responses[1].Result != "ping" {
+		t.Errorf("unexpected responses %+v", responses)
+	}
+
+	// A repeated id is answered with an error, and the batch completes
+	if err := client.WriteObject(json.RawMessage(`[{"jsonrpc":"2.0","id":5,"method":"ping"}, {"jsonrpc":"2.0","id":5,"method":"ping"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	responses = nil
+	if err := json.Unmarshal(readMessage(t, client), &responses); err != nil {
+		t.Fatalf("expected a batch response: %v", err)
+	}
+	if len(responses) != 2 || responses[0].Error == nil || responses[0].Error.Code != jsonrpc2.CodeInvalidRequest || responses[1].Result != "ping" {
+		t.Errorf("unexpected responses %+v", responses)
+	}
+
+	// Messages outside batches are answered on their own
+	if err := client.WriteObject(json.RawMessage(`{"jsonrpc":"2.0","id":4,"method":"ping"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(readMessage(t, client), &response); err != nil || response.Result != "ping" {
+		t.Errorf("unexpected response %+v (%v)", response, err)
+	}
+}
@@ -0,0 +1,215 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ContentEncoding compresses message bodies on stream connections.
+type ContentEncoding interface {
+	NewReader(reader io.Reader) (io.ReadCloser, error)
+	NewWriter(writer io.Writer) io.WriteCloser
+}
+
+// ContentEncodings are the content encodings of stream connections by
+// name. Register more, like "zstd", before running the server.
+var ContentEncodings = map[string]ContentEncoding{
+	"gzip": gzipEncoding{},
+}
+
+// minCompressedSize is the size of the smallest message body worth
+// compressing.
+const minCompressedSize = 1024
+
+type gzipEncoding struct{}
+
+// ([ContentEncoding] interface)
+func (gzipEncoding) NewReader(reader io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(reader)
+}
+
+// ([ContentEncoding] interface)
+func (gzipEncoding) NewWriter(writer io.Writer) io.WriteCloser {
+	return gzip.NewWriter(writer)
+}
+
+// streamCodec is jsonrpc2.VSCodeObjectCodec with a limit on the size of the
+// messages it reads and with compression.
+//
+// A message over the limit is skipped without holding its body in memory,
+// and reading returns a messageTooLargeError; the stream stays usable. A
+// limit of 0 means no limit.
+//
+// Compression is negotiated with headers, as in vscode-jsonrpc: when
+// enabled, every message has an "Accept-Encoding" header listing the
+// ContentEncodings, and bodies with a "Content-Encoding" header are
+// decompressed. Once the client sends an "Accept-Encoding" header, large
+// bodies are compressed with the first encoding it lists that is known.
+type streamCodec struct {
+	maxSize     int64
+	compression bool
+
+	encoding *atomic.Value // string, the encoding accepted by the client
+}
+
+func newStreamCodec(maxSize int64, compression bool) streamCodec {
+	encoding := new(atomic.Value)
+	encoding.Store("")
+	return streamCodec{maxSize: maxSize, compression: compression, encoding: encoding}
+}
+
+// ([jsonrpc2.ObjectCodec] interface)
+func (self streamCodec) WriteObject(stream io.Writer, obj any) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	var header strings.Builder
+	if self.compression {
+		if name := self.encoding.Load().(string); name != "" && len(data) >= minCompressedSize {
+			var buffer bytes.Buffer
+			writer := ContentEncodings[name].NewWriter(&buffer)
+			if _, err := writer.Write(data); err != nil {
+				return err
+			}
+			if err := writer.Close(); err != nil {
+				return err
+			}
+			data = buffer.Bytes()
+			header.WriteString("Content-Encoding: " + name + "\r\n")
+		}
+		header.WriteString("Accept-Encoding: " + strings.Join(contentEncodingNames(), ", ") + "\r\n")
+	}
+
+	if _, err := fmt.Fprintf(stream, "Content-Length: %d\r\n%s\r\n", len(data), header.String()); err != nil {
+		return err
+	}
+	_, err = stream.Write(data)
+	return err
+}
+
+// ([jsonrpc2.ObjectCodec] interface)
+func (self streamCodec) ReadObject(stream *bufio.Reader, v any) error {
+	var contentLength int64 = -1
+	var contentEncoding string
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if !strings.HasSuffix(line, "\r\n") {
+			return errors.New(`jsonrpc2: line endings must be \r\n`)
+		}
+		line = strings.TrimSuffix(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "content-length":
+			if contentLength, err = strconv.ParseInt(value, 10, 64); err != nil || contentLength < 0 {
+				return fmt.Errorf("jsonrpc2: invalid Content-Length %q", value)
+			}
+		case "content-encoding":
+			contentEncoding = value
+		case "accept-encoding":
+			if self.compression {
+				self.encoding.Store(acceptedEncoding(value))
+			}
+		}
+	}
+	if contentLength <= 0 {
+		return errors.New("jsonrpc2: no Content-Length header found")
+	}
+
+	if self.maxSize > 0 && contentLength > self.maxSize {
+		// Look for the id while skipping, so the error answers the request
+		var scanner idScanner
+		if contentEncoding == "" || contentEncoding == "identity" {
+			if _, err := io.CopyN(&scanner, stream, contentLength); err != nil {
+				return err
+			}
+		} else if _, err := io.CopyN(io.Discard, stream, contentLength); err != nil {
+			return err
+		}
+		id, notification := scanner.result()
+		return &messageTooLargeError{size: contentLength, limit: self.maxSize, id: id, notification: notification}
+	}
+
+	// Decode from the stream rather than a copy of the body, and skip
+	// whatever follows the value so the next header is found
+	body := io.LimitReader(stream, contentLength)
+	err := self.decode(body, contentEncoding, v)
+	if _, discardErr := io.Copy(io.Discard, body); err == nil {
+		err = discardErr
+	}
+	return err
+}
+
+func (self streamCodec) decode(body io.Reader, contentEncoding string, v any) error {
+	if contentEncoding == "" || contentEncoding == "identity" {
+		return json.NewDecoder(body).Decode(v)
+	}
+
+	encoding, ok := ContentEncodings[contentEncoding]
+	if !ok || !self.compression {
+		return fmt.Errorf("jsonrpc2: unsupported Content-Encoding %q", contentEncoding)
+	}
+	reader, err := encoding.NewReader(body)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if self.maxSize <= 0 {
+		return json.NewDecoder(reader).Decode(v)
+	}
+	// The limit applies to the decompressed body too
+	data, err := io.ReadAll(io.LimitReader(reader, self.maxSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > self.maxSize {
+		var scanner idScanner
+		scanner.Write(data)
+		id, _ := scanner.result()
+		return &messageTooLargeError{limit: self.maxSize, id: id}
+	}
+	return json.Unmarshal(data, v)
+}
+
+// acceptedEncoding returns the first of a list of content encodings, as
+// in an "Accept-Encoding" header, that is one of ContentEncodings.
+func acceptedEncoding(header string) string {
+	for _, name := range strings.Split(header, ",") {
+		// Quality values are ignored
+		name, _, _ = strings.Cut(name, ";")
+		if name = strings.TrimSpace(name); ContentEncodings[name] != nil {
+			return name
+		}
+	}
+	return ""
+}
+
+func contentEncodingNames() []string {
+	names := make([]string, 0, len(ContentEncodings))
+	for name := range ContentEncodings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
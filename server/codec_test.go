@@ -0,0 +1,100 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStreamCodecCompression(t *testing.T) {
+	server := newStreamCodec(0, true)
+	client := newStreamCodec(0, true)
+	large := strings.Repeat("semantic tokens ", 200)
+
+	// Before the client accepts an encoding, nothing is compressed
+	var buffer bytes.Buffer
+	if err := server.WriteObject(&buffer, large); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buffer.String(), "Content-Encoding") || !strings.Contains(buffer.String(), "Accept-Encoding: gzip\r\n") {
+		t.Errorf("expected an uncompressed message accepting gzip, got headers %q", strings.SplitN(buffer.String(), "\r\n\r\n", 2)[0])
+	}
+
+	// Reading the server's message makes the client compress
+	var message string
+	if err := client.ReadObject(bufio.NewReader(&buffer), &message); err != nil || message != large {
+		t.Fatalf("could not read the uncompressed message: %v", err)
+	}
+	buffer.Reset()
+	if err := client.WriteObject(&buffer, large); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buffer.String(), "Content-Encoding: gzip\r\n") || buffer.Len() >= len(large) {
+		t.Errorf("expected a compressed message, got %d bytes", buffer.Len())
+	}
+	message = ""
+	if err := server.ReadObject(bufio.NewReader(&buffer), &message); err != nil || message != large {
+		t.Fatalf("could not read the compressed message: %v", err)
+	}
+
+	// Small messages are not worth compressing
+	buffer.Reset()
+	if err := server.WriteObject(&buffer, "small"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buffer.String(), "Content-Encoding") {
+		t.Errorf("expected a small message to be uncompressed, got %q", buffer.String())
+	}
+}
+
+func TestStreamCodecLimits(t *testing.T) {
+	compressed := func(size int) *bytes.Buffer {
+		client := newStreamCodec(0, true)
+		client.encoding.Store("gzip")
+		var buffer bytes.Buffer
+		if err := client.WriteObject(&buffer, strings.Repeat("x", size)); err != nil {
+			t.Fatal(err)
+		}
+		buffer.WriteString("Content-Length: 4\r\n\r\ntrue")
+		return &buffer
+	}
+
+	tests := []struct {
+		name        string
+		codec       streamCodec
+		input       *bytes.Buffer
+		wantTooBig  bool
+		wantErr     bool
+		wantMessage string
+	}{
+		{name: "too large", codec: newStreamCodec(10, false), input: bytes.NewBufferString("Content-Length: 11\r\n\r\n\"123456789\"Content-Length: 4\r\n\r\ntrue"), wantTooBig: true},
+		{name: "decompressed too large", codec: newStreamCodec(2000, true), input: compressed(5000), wantTooBig: true},
+		{name: "decompressed within limit", codec: newStreamCodec(6000, true), input: compressed(5000), wantMessage: strings.Repeat("x", 5000)},
+		{name: "compression disabled", codec: newStreamCodec(0, false), input: compressed(5000), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReader(tt.input)
+			var message string
+			err := tt.codec.ReadObject(reader, &message)
+			var tooLarge *messageTooLargeError
+			switch {
+			case tt.wantTooBig && !errors.As(err, &tooLarge):
+				t.Fatalf("expected a message too large error, got %v", err)
+			case tt.wantErr && err == nil:
+				t.Fatal("expected an error")
+			case !tt.wantTooBig && !tt.wantErr && (err != nil || message != tt.wantMessage):
+				t.Fatalf("got %d bytes, %v", len(message), err)
+			}
+
+			// The next message is read either way
+			var next bool
+			if err := tt.codec.ReadObject(reader, &next); err != nil || !next {
+				t.Errorf("could not read the next message: %v", err)
+			}
+		})
+	}
+}
@@ -11,25 +11,44 @@ import (
 )
 
 func (self *Server) newStreamConnection(stream io.ReadWriteCloser) *jsonrpc2.Conn {
-	handler := self.newHandler()
-	connectionOptions := self.newConnectionOptions()
-
-	// Use background context for connection lifetime - LSP connections should persist
-	// for the duration of the editor session, not be limited by a timeout
-	context := contextpkg.Background()
-
-	return jsonrpc2.NewConn(context, jsonrpc2.NewBufferedStream(stream, jsonrpc2.VSCodeObjectCodec{}), handler, connectionOptions...)
+	return self.newConnection(jsonrpc2.NewBufferedStream(stream, newStreamCodec(self.MaxMessageSize, self.Compression)))
 }
 
 func (self *Server) newWebSocketConnection(socket *websocket.Conn) *jsonrpc2.Conn {
-	handler := self.newHandler()
+	if self.MaxMessageSize > 0 {
+		// The connection fails on larger messages
+		socket.SetReadLimit(self.MaxMessageSize)
+	}
+	// Only applies if negotiated by the upgrader
+	socket.EnableWriteCompression(self.Compression)
+	return self.newConnection(wsjsonrpc2.NewObjectStream(socket))
+}
+
+func (self *Server) newConnection(stream jsonrpc2.ObjectStream) *jsonrpc2.Conn {
+	session := self.openSession()
+	batches := newBatchStream(self.newLimitStream(stream))
+	handler := newBatchHandler(self.newHandler(session), batches)
 	connectionOptions := self.newConnectionOptions()
 
 	// Use background context for connection lifetime - LSP connections should persist
 	// for the duration of the editor session, not be limited by a timeout
 	context := contextpkg.Background()
 
-	return jsonrpc2.NewConn(context, wsjsonrpc2.NewObjectStream(socket), handler, connectionOptions...)
+	connection := jsonrpc2.NewConn(context, self.newObjectStream(batches), handler, connectionOptions...)
+	go func() {
+		<-connection.DisconnectNotify()
+		self.closeSession(session)
+	}()
+	return connection
+}
+
+// newObjectStream wraps the stream of a connection, which splits batches,
+// so that the recorder sees the members of a batch as single messages.
+func (self *Server) newObjectStream(stream jsonrpc2.ObjectStream) jsonrpc2.ObjectStream {
+	if self.Recorder != nil {
+		return self.Recorder.Stream(stream)
+	}
+	return stream
 }
 
 func (self *Server) newConnectionOptions() []jsonrpc2.ConnOpt {
@@ -0,0 +1,66 @@
+package server
+
+import (
+	contextpkg "context"
+	"encoding/json"
+	"errors"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/protocol"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// Error codes of the LSP specification that jsonrpc2 does not define.
+const (
+	CodeRequestFailed    int64 = -32803
+	CodeServerCancelled  int64 = -32802
+	CodeRequestCancelled int64 = -32800
+)
+
+// HandleRequest registers a function handling the requests of a custom
+// method on handler, with protocol.HandleCustomRequest, so they are
+// dispatched after the handler's initialization and read-only checks. It
+// fails for methods of the specification.
+//
+// The params are decoded into TParams, and missing or null params leave it
+// zero; params that do not decode fail with an invalid params error. The
+// result is encoded as JSON. Errors that are *jsonrpc2.Error are sent as
+// they are, cancellations as CodeRequestCancelled, and other errors as
+// CodeRequestFailed.
+func HandleRequest[TParams any, TResult any](handler *protocol.Handler, method string, f func(context *lsp.Context, params TParams) (TResult, error)) error {
+	return protocol.HandleCustomRequest(handler, method, func(context *lsp.Context, raw *json.RawMessage) (any, error) {
+		var params TParams
+		if len(*raw) > 0 && string(*raw) != "null" {
+			if err := json.Unmarshal(*raw, &params); err != nil {
+				return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: err.Error()}
+			}
+		}
+		result, err := f(context, params)
+		if err != nil {
+			return nil, responseError(err)
+		}
+		return result, nil
+	})
+}
+
+// HandleNotification registers a function handling the notifications of a
+// custom method on handler. Params are decoded as with HandleRequest, and
+// errors are logged by the connection.
+func HandleNotification[TParams any](handler *protocol.Handler, method string, f func(context *lsp.Context, params TParams) error) error {
+	return HandleRequest(handler, method, func(context *lsp.Context, params TParams) (any, error) {
+		return nil, f(context, params)
+	})
+}
+
+// responseError maps an error of a handler to a JSON-RPC error.
+func responseError(err error) *jsonrpc2.Error {
+	var rpcErr *jsonrpc2.Error
+	switch {
+	case errors.As(err, &rpcErr):
+		return rpcErr
+	case errors.Is(err, contextpkg.Canceled):
+		return &jsonrpc2.Error{Code: CodeRequestCancelled, Message: err.Error()}
+	default:
+		return &jsonrpc2.Error{Code: CodeRequestFailed, Message: err.Error()}
+	}
+}
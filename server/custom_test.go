@@ -0,0 +1,111 @@
+package server
+
+import (
+	contextpkg "context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/protocol"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+type expandParams struct {
+	Name string `json:"name"`
+}
+
+type expandResult struct {
+	Expansion string `json:"expansion"`
+}
+
+func TestHandleRequest(t *testing.T) {
+	handler := &protocol.Handler{}
+	err := HandleRequest(handler, "custom/expand", func(context *lsp.Context, params expandParams) (expandResult, error) {
+		switch params.Name {
+		case "":
+			return expandResult{}, errors.New("no name")
+		case "cancel":
+			return expandResult{}, contextpkg.Canceled
+		}
+		return expandResult{Expansion: params.Name + "!"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	notified := make(chan expandParams, 1)
+	err = HandleNotification(handler, "$/custom", func(context *lsp.Context, params expandParams) error {
+		notified <- params
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := HandleRequest(handler, "textDocument/hover", func(*lsp.Context, struct{}) (any, error) { return nil, nil }); err == nil {
+		t.Error("expected a method of the specification to be refused")
+	}
+	server := NewServer(handler, "server-test-custom", false)
+
+	serverSide, clientSide := net.Pipe()
+	connection := server.newStreamConnection(serverSide)
+	client := jsonrpc2.NewBufferedStream(clientSide, jsonrpc2.VSCodeObjectCodec{})
+	t.Cleanup(func() {
+		client.Close()
+		connection.Close()
+	})
+
+	tests := []struct {
+		name      string
+		message   string
+		wantCode  int64
+		wantValue string
+	}{
+		{name: "result", message: `{"jsonrpc":"2.0","id":1,"method":"custom/expand","params":{"name":"macro"}}`, wantValue: `{"expansion":"macro!"}`},
+		{name: "invalid params", message: `{"jsonrpc":"2.0","id":2,"method":"custom/expand","params":[1]}`, wantCode: jsonrpc2.CodeInvalidParams},
+		{name: "error", message: `{"jsonrpc":"2.0","id":3,"method":"custom/expand"}`, wantCode: CodeRequestFailed},
+		{name: "cancelled", message: `{"jsonrpc":"2.0","id":4,"method":"custom/expand","params":{"name":"cancel"}}`, wantCode: CodeRequestCancelled},
+		{name: "unknown", message: `{"jsonrpc":"2.0","id":5,"method":"custom/other"}`, wantCode: jsonrpc2.CodeMethodNotFound},
+	}
+
+	// Custom methods wait for initialization like the others
+	if err := client.WriteObject(json.RawMessage(`{"jsonrpc":"2.0","id":0,"method":"custom/expand","params":{"name":"macro"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	var uninitialized struct {
+		Error *jsonrpc2.Error `json:"error"`
+	}
+	if err := json.Unmarshal(readMessage(t, client), &uninitialized); err != nil || uninitialized.Error == nil {
+		t.Fatalf("expected an error before initialization, got %+v (%v)", uninitialized, err)
+	}
+	handler.SetInitialized(true)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := client.WriteObject(json.RawMessage(tt.message)); err != nil {
+				t.Fatal(err)
+			}
+			var response struct {
+				Result json.RawMessage `json:"result"`
+				Error  *jsonrpc2.Error `json:"error"`
+			}
+			if err := json.Unmarshal(readMessage(t, client), &response); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantCode != 0 {
+				if response.Error == nil || response.Error.Code != tt.wantCode {
+					t.Errorf("error = %+v, want code %d", response.Error, tt.wantCode)
+				}
+			} else if string(response.Result) != tt.wantValue {
+				t.Errorf("result = %s, want %s (error %+v)", response.Result, tt.wantValue, response.Error)
+			}
+		})
+	}
+
+	if err := client.WriteObject(json.RawMessage(`{"jsonrpc":"2.0","method":"$/custom","params":{"name":"n"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if params := <-notified; params.Name != "n" {
+		t.Errorf("notification params = %+v", params)
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	contextpkg "context"
+	"sync"
+	"time"
+
+	"github.com/SCKelemen/lsp/metrics"
+	"github.com/SCKelemen/lsp/protocol"
+)
+
+// PartialResultMethods are the methods whose results may be incomplete, so
+// that with Server.PartialResults their handlers are cancelled at their
+// deadline and answer with the results found so far.
+var PartialResultMethods = map[string]bool{
+	protocol.MethodWorkspaceSymbol:        true,
+	protocol.MethodTextDocumentReferences: true,
+}
+
+// ProviderTiming is the time spent in a provider during a request.
+type ProviderTiming struct {
+	Provider string
+	Duration time.Duration
+}
+
+// SlowRequest describes a request that took longer than its deadline.
+type SlowRequest struct {
+	Method   string
+	Duration time.Duration
+	Deadline time.Duration
+
+	// Providers are the providers timed with TimeProvider, in the order
+	// they were first called.
+	Providers []ProviderTiming
+}
+
+type requestTimingsKey struct{}
+
+// requestTimings collects the provider timings of a request.
+type requestTimings struct {
+	mu        sync.Mutex
+	providers []ProviderTiming
+}
+
+func (self *requestTimings) add(provider string, duration time.Duration) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	for i := range self.providers {
+		if self.providers[i].Provider == provider {
+			self.providers[i].Duration += duration
+			return
+		}
+	}
+	self.providers = append(self.providers, ProviderTiming{Provider: provider, Duration: duration})
+}
+
+func (self *requestTimings) get() []ProviderTiming {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return append([]ProviderTiming(nil), self.providers...)
+}
+
+// TimeProvider starts timing a provider called while handling a request and
+// returns a function that adds its duration to the slow request report, if
+// the method of the request has a deadline, e.g.
+//
+//	defer server.TimeProvider(context.Context, "go/types")()
+//
+// The durations of a provider called several times are added up. They are
+// measured on the clock of the metrics package, like the requests.
+func TimeProvider(context contextpkg.Context, provider string) func() {
+	if context == nil {
+		return func() {}
+	}
+	timings, ok := context.Value(requestTimingsKey{}).(*requestTimings)
+	if !ok {
+		return func() {}
+	}
+	elapsed := metrics.Stopwatch()
+	return func() {
+		timings.add(provider, elapsed())
+	}
+}
+
+// withRequestTimings returns a context collecting provider timings.
+func withRequestTimings(context contextpkg.Context) (contextpkg.Context, *requestTimings) {
+	timings := new(requestTimings)
+	return contextpkg.WithValue(context, requestTimingsKey{}, timings), timings
+}
+
+// handlerContext returns the context given to the handler of a request,
+// which ends at the deadline of methods with partial results.
+func (self *Server) handlerContext(context contextpkg.Context, method string) (contextpkg.Context, contextpkg.CancelFunc) {
+	if deadline := self.Deadlines[method]; deadline > 0 && self.PartialResults && PartialResultMethods[method] {
+		return contextpkg.WithTimeout(context, deadline)
+	}
+	return context, func() {}
+}
+
+// reportSlowRequest logs a request that exceeded its deadline.
+func (self *Server) reportSlowRequest(event SlowRequest) {
+	keysAndValues := []any{"method", event.Method, "duration", event.Duration.String(), "deadline", event.Deadline.String()}
+	for _, provider := range event.Providers {
+		keysAndValues = append(keysAndValues, "provider."+provider.Provider, provider.Duration.String())
+	}
+	self.Log.Warning("slow request", keysAndValues...)
+
+	if self.OnSlowRequest != nil {
+		self.OnSlowRequest(event)
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	contextpkg "context"
+	"testing"
+	"time"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/metrics"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// timedHandler spends time in providers, or until its context ends.
+type timedHandler struct {
+	providers map[string]time.Duration
+	untilDone bool
+	cancelled bool
+}
+
+func (self *timedHandler) Handle(context *lsp.Context) (any, bool, bool, error) {
+	for _, provider := range []string{"parse", "types"} {
+		if duration, ok := self.providers[provider]; ok {
+			done := TimeProvider(context.Context, provider)
+			time.Sleep(duration)
+			done()
+		}
+	}
+	if self.untilDone {
+		select {
+		case <-context.Context.Done():
+			self.cancelled = true
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	return []string{"partial"}, true, true, nil
+}
+
+func TestSlowRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		deadline      time.Duration
+		providers     map[string]time.Duration
+		wantSlow      bool
+		wantProviders []string
+	}{
+		{name: "fast", deadline: time.Second, providers: map[string]time.Duration{"parse": 0}},
+		{name: "slow", deadline: 5 * time.Millisecond, providers: map[string]time.Duration{"parse": time.Millisecond, "types": 10 * time.Millisecond}, wantSlow: true, wantProviders: []string{"parse", "types"}},
+		{name: "no deadline", providers: map[string]time.Duration{"types": 10 * time.Millisecond}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(&timedHandler{providers: tt.providers}, "server-test-slow-request", false)
+			if tt.deadline > 0 {
+				server.Deadlines = map[string]time.Duration{"textDocument/hover": tt.deadline}
+			}
+			var events []SlowRequest
+			server.OnSlowRequest = func(event SlowRequest) { events = append(events, event) }
+
+			if _, err := server.handle(contextpkg.Background(), nil, &jsonrpc2.Request{Method: "textDocument/hover"}); err != nil {
+				t.Fatal(err)
+			}
+			if (len(events) > 0) != tt.wantSlow {
+				t.Fatalf("slow request events %+v, want slow %t", events, tt.wantSlow)
+			}
+			if !tt.wantSlow {
+				return
+			}
+			event := events[0]
+			if event.Method != "textDocument/hover" || event.Deadline != tt.deadline || event.Duration <= tt.deadline {
+				t.Errorf("event %+v", event)
+			}
+			if len(event.Providers) != len(tt.wantProviders) {
+				t.Fatalf("providers %+v, want %v", event.Providers, tt.wantProviders)
+			}
+			for i, provider := range event.Providers {
+				if provider.Provider != tt.wantProviders[i] || provider.Duration < tt.providers[provider.Provider] {
+					t.Errorf("provider %+v, want %s taking at least %s", provider, tt.wantProviders[i], tt.providers[provider.Provider])
+				}
+			}
+		})
+	}
+}
+
+// clockHandler advances the clock of the metrics package in a provider.
+type clockHandler struct {
+	now *time.Time
+}
+
+func (self *clockHandler) Handle(context *lsp.Context) (any, bool, bool, error) {
+	done := TimeProvider(context.Context, "types")
+	*self.now = self.now.Add(time.Second)
+	done()
+	return nil, true, true, nil
+}
+
+func TestSlowRequestClock(t *testing.T) {
+	now := time.Unix(0, 0)
+	defer func(clock func() time.Time) { metrics.Now = clock }(metrics.Now)
+	metrics.Now = func() time.Time { return now }
+
+	server := NewServer(&clockHandler{now: &now}, "server-test-slow-request-clock", false)
+	server.Metrics = metrics.NewRegistry()
+	server.Deadlines = map[string]time.Duration{"textDocument/hover": 500 * time.Millisecond}
+	var events []SlowRequest
+	server.OnSlowRequest = func(event SlowRequest) { events = append(events, event) }
+
+	if _, err := server.handle(contextpkg.Background(), nil, &jsonrpc2.Request{Method: "textDocument/hover"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Duration != time.Second {
+		t.Fatalf("slow request events %+v, want one taking 1s", events)
+	}
+	if providers := events[0].Providers; len(providers) != 1 || providers[0].Duration != time.Second {
+		t.Errorf("providers %+v, want types taking 1s", providers)
+	}
+	for _, metric := range server.Metrics.Snapshot() {
+		if metric.Name == requestDurationMetric && metric.Samples[0].Sum != 1 {
+			t.Errorf("request duration %v, want 1s", metric.Samples[0].Sum)
+		}
+	}
+}
+
+func TestPartialResults(t *testing.T) {
+	tests := []struct {
+		method        string
+		wantCancelled bool
+	}{
+		{method: "workspace/symbol", wantCancelled: true},
+		{method: "textDocument/references", wantCancelled: true},
+		{method: "textDocument/hover", wantCancelled: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			handler := &timedHandler{untilDone: true}
+			server := NewServer(handler, "server-test-partial-results", false)
+			server.Deadlines = map[string]time.Duration{tt.method: 10 * time.Millisecond}
+			server.PartialResults = true
+
+			result, err := server.handle(contextpkg.Background(), nil, &jsonrpc2.Request{Method: tt.method})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if handler.cancelled != tt.wantCancelled {
+				t.Errorf("cancelled %t, want %t", handler.cancelled, tt.wantCancelled)
+			}
+			if results, ok := result.([]string); !ok || len(results) != 1 {
+				t.Errorf("result %#v, want the partial results", result)
+			}
+		})
+	}
+}
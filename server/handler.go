@@ -2,19 +2,51 @@ package server
 
 import (
 	contextpkg "context"
+	"errors"
 	"fmt"
 
 	"github.com/sourcegraph/jsonrpc2"
 	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/metrics"
 )
 
 // See: https://github.com/sourcegraph/go-langserver/blob/master/langserver/handler.go#L206
 
-func (self *Server) newHandler() jsonrpc2.Handler {
-	return jsonrpc2.HandlerWithError(self.handle)
+// newHandler returns the handler of the connection of a session, whose
+// requests have the session in their context.
+func (self *Server) newHandler(session *Session) jsonrpc2.Handler {
+	return jsonrpc2.HandlerWithError(func(context contextpkg.Context, connection *jsonrpc2.Conn, request *jsonrpc2.Request) (any, error) {
+		return self.handle(withSession(context, session), connection, request)
+	})
 }
 
 func (self *Server) handle(context contextpkg.Context, connection *jsonrpc2.Conn, request *jsonrpc2.Request) (any, error) {
+	deadline := self.Deadlines[request.Method]
+	if self.Metrics == nil && deadline <= 0 {
+		return self.safeDispatch(context, connection, request)
+	}
+
+	var timings *requestTimings
+	if deadline > 0 {
+		context, timings = withRequestTimings(context)
+	}
+
+	elapsed := metrics.Stopwatch()
+	result, err := self.safeDispatch(context, connection, request)
+	duration := elapsed()
+	if self.Metrics != nil {
+		self.recordRequest(request.Method, err, duration)
+	}
+	if deadline > 0 && duration > deadline {
+		self.reportSlowRequest(SlowRequest{Method: request.Method, Duration: duration, Deadline: deadline, Providers: timings.get()})
+	}
+	return result, err
+}
+
+func (self *Server) dispatch(context contextpkg.Context, connection *jsonrpc2.Conn, request *jsonrpc2.Request) (any, error) {
+	handlerContext, cancel := self.handlerContext(context, request.Method)
+	defer cancel()
+
 	glspContext := lsp.Context{
 		Method: request.Method,
 		Notify: func(method string, params any) {
@@ -22,29 +54,36 @@ func (self *Server) handle(context contextpkg.Context, connection *jsonrpc2.Conn
 				self.Log.Error(err.Error())
 			}
 		},
-		Call: func(method string, params any, result any) {
-			if err := connection.Call(context, method, params, result); err != nil {
+		Call: func(method string, params any, result any) error {
+			err := connection.Call(context, method, params, result)
+			if err != nil {
 				self.Log.Error(err.Error())
 			}
+			return err
 		},
-		Context: context,
+		Context: handlerContext,
 	}
 
 	if request.Params != nil {
 		glspContext.Params = *request.Params
 	}
 
+	handler := self.Handler
+	if session, ok := SessionFromContext(context); ok {
+		handler = session.Handler
+	}
+
 	switch request.Method {
 	case "exit":
 		// We're giving the attached handler a chance to handle it first, but we'll ignore any result
-		self.Handler.Handle(&glspContext)
+		handler.Handle(&glspContext)
 		err := connection.Close()
 		return nil, err
 
 	default:
 		// Note: jsonrpc2 will not even call this function if reqest.Params is invalid JSON,
 		// so we don't need to handle jsonrpc2.CodeParseError here
-		result, validMethod, validParams, err := self.Handler.Handle(&glspContext)
+		result, validMethod, validParams, err := handler.Handle(&glspContext)
 		if !validMethod {
 			return nil, &jsonrpc2.Error{
 				Code:    jsonrpc2.CodeMethodNotFound,
@@ -62,6 +101,11 @@ func (self *Server) handle(context contextpkg.Context, connection *jsonrpc2.Conn
 				}
 			}
 		} else if err != nil {
+			// Handlers choose the code by returning a *jsonrpc2.Error
+			var rpcErr *jsonrpc2.Error
+			if errors.As(err, &rpcErr) {
+				return nil, rpcErr
+			}
 			return nil, &jsonrpc2.Error{
 				Code:    jsonrpc2.CodeInvalidRequest,
 				Message: err.Error(),
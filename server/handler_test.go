@@ -5,10 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/metrics"
+	"github.com/SCKelemen/lsp/protocol"
 	"github.com/sourcegraph/jsonrpc2"
 )
 
@@ -201,6 +206,93 @@ func TestHandleExitClosesConnection(t *testing.T) {
 	}
 }
 
+func TestHandleRecordsMetrics(t *testing.T) {
+	handler := &stubHandler{
+		validMethod: true,
+		validParams: true,
+	}
+	server := NewServer(handler, "server-test-metrics", false)
+	server.Metrics = metrics.NewRegistry()
+
+	for i := 0; i < 2; i++ {
+		if _, err := server.handle(contextpkg.Background(), nil, &jsonrpc2.Request{
+			Method: "textDocument/hover",
+		}); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+	}
+	handler.validMethod = false
+	for _, method := range []string{"custom/unsupported", "custom/unsupported2"} {
+		server.handle(contextpkg.Background(), nil, &jsonrpc2.Request{
+			Method: method,
+		})
+	}
+
+	if got := server.Metrics.Counter(requestsMetric, "method", "textDocument/hover", "outcome", "ok").Value(); got != 2 {
+		t.Fatalf("expected 2 ok requests, got %d", got)
+	}
+	if got := server.Metrics.Counter(requestsMetric, "method", otherMethodLabel, "outcome", "method_not_found").Value(); got != 2 {
+		t.Fatalf("expected 2 method-not-found requests labeled other, got %d", got)
+	}
+
+	var durations *metrics.Metric
+	snapshot := server.Metrics.Snapshot()
+	for i := range snapshot {
+		if snapshot[i].Name == requestDurationMetric {
+			durations = &snapshot[i]
+		}
+	}
+	if durations == nil || len(durations.Samples) != 2 {
+		t.Fatalf("expected durations for 2 methods, got %+v", durations)
+	}
+}
+
+type panicHandler struct{}
+
+func (panicHandler) Handle(context *lsp.Context) (any, bool, bool, error) {
+	panic("provider bug")
+}
+
+func TestHandleRecoversPanic(t *testing.T) {
+	server := NewServer(panicHandler{}, "server-test-panic", false)
+	server.CrashReportDir = t.TempDir()
+
+	params := json.RawMessage(`{"textDocument":{"uri":"file:///secret.go","text":"password := 42"},"position":{"line":3,"character":7}}`)
+	result, err := server.handle(contextpkg.Background(), nil, &jsonrpc2.Request{
+		Method: "textDocument/hover",
+		Params: &params,
+	})
+	if result != nil {
+		t.Fatalf("expected nil result, got %#v", result)
+	}
+
+	var rpcErr *jsonrpc2.Error
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("expected jsonrpc2.Error, got %T", err)
+	}
+	if rpcErr.Code != jsonrpc2.CodeInternalError {
+		t.Fatalf("expected internal-error code, got %d", rpcErr.Code)
+	}
+
+	reports, err := filepath.Glob(filepath.Join(server.CrashReportDir, "lsp-crash-*.txt"))
+	if err != nil || len(reports) != 1 {
+		t.Fatalf("expected one crash report, got %v (%v)", reports, err)
+	}
+	content, err := os.ReadFile(reports[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(content)
+	for _, expected := range []string{"method=textDocument/hover", "uri=file:///secret.go", "position=3:7", "panic: provider bug", "recover.go"} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("expected crash report to contain %q, got:\n%s", expected, report)
+		}
+	}
+	if strings.Contains(report, "password") {
+		t.Error("expected document content to be left out of the crash report")
+	}
+}
+
 func newJSONRPCConnPair() (*jsonrpc2.Conn, *jsonrpc2.Conn) {
 	serverSide, clientSide := net.Pipe()
 	noop := jsonrpc2.HandlerWithError(func(contextpkg.Context, *jsonrpc2.Conn, *jsonrpc2.Request) (any, error) {
@@ -218,3 +310,35 @@ func newJSONRPCConnPair() (*jsonrpc2.Conn, *jsonrpc2.Conn) {
 	)
 	return serverConn, clientConn
 }
+
+func TestHandleCustomMethods(t *testing.T) {
+	handler := &protocol.Handler{}
+	err := protocol.HandleCustomRequest(handler, "custom/expand", func(context *lsp.Context, params *struct{ Name string }) (string, error) {
+		if params.Name == "" {
+			return "", &jsonrpc2.Error{Code: -32803, Message: "no name"}
+		}
+		return params.Name + "!", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(handler, "server-test-custom", false)
+
+	request := func(params string) (any, error) {
+		raw := json.RawMessage(params)
+		return server.handle(contextpkg.Background(), nil, &jsonrpc2.Request{Method: "custom/expand", Params: &raw})
+	}
+
+	if _, err := request(`{"Name":"macro"}`); err == nil || !strings.Contains(err.Error(), "not initialized") {
+		t.Errorf("error before initialization = %v, want not initialized", err)
+	}
+
+	handler.SetInitialized(true)
+	if result, err := request(`{"Name":"macro"}`); err != nil || result != "macro!" {
+		t.Errorf("result = %#v, %v, want macro!", result, err)
+	}
+	var rpcErr *jsonrpc2.Error
+	if _, err := request(`{}`); !errors.As(err, &rpcErr) || rpcErr.Code != -32803 {
+		t.Errorf("error = %v, want the handler's code -32803", err)
+	}
+}
@@ -0,0 +1,338 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/tliron/commonlog"
+)
+
+// errSlowClient is returned by writes to a client that stopped reading.
+var errSlowClient = errors.New("client is not reading its messages")
+
+// messageTooLargeError is returned by streamCodec for a message over
+// the limit, after skipping its body.
+type messageTooLargeError struct {
+	size  int64
+	limit int64
+
+	// id is the id of the request found while skipping the body, or nil
+	id json.RawMessage
+
+	// notification is true if the body was an object without an id
+	notification bool
+}
+
+func (self *messageTooLargeError) Error() string {
+	if self.size == 0 {
+		// Decompressing stopped at the limit
+		return fmt.Sprintf("message exceeds the limit of %d bytes", self.limit)
+	}
+	return fmt.Sprintf("message of %d bytes exceeds the limit of %d bytes", self.size, self.limit)
+}
+
+// limitStream enforces the message size limit of a connection and queues
+// its outbound messages.
+//
+// Inbound messages over the limit, as reported by streamCodec, are
+// answered with an error. Outbound responses over the limit are replaced
+// with an error response; other outbound messages over the limit fail.
+// Writers block while the queue is full, and a client that does not read
+// for slowClientTimeout is disconnected.
+type limitStream struct {
+	stream            jsonrpc2.ObjectStream
+	maxSize           int64
+	slowClientTimeout time.Duration
+	log               commonlog.Logger
+
+	queue     chan json.RawMessage // nil if writes are not queued
+	done      chan struct{}        // closed by Close
+	flushed   chan struct{}        // closed when the queued writes are done
+	closeOnce sync.Once
+}
+
+func (self *Server) newLimitStream(stream jsonrpc2.ObjectStream) *limitStream {
+	limited := &limitStream{
+		stream:            stream,
+		maxSize:           self.MaxMessageSize,
+		slowClientTimeout: self.SlowClientTimeout,
+		log:               self.Log,
+		done:              make(chan struct{}),
+		flushed:           make(chan struct{}),
+	}
+	if self.MaxPendingWrites > 0 {
+		limited.queue = make(chan json.RawMessage, self.MaxPendingWrites)
+		go limited.write()
+	} else {
+		close(limited.flushed)
+	}
+	return limited
+}
+
+// ([jsonrpc2.ObjectStream] interface)
+func (self *limitStream) ReadObject(v any) error {
+	for {
+		err := self.stream.ReadObject(v)
+		var tooLarge *messageTooLargeError
+		if !errors.As(err, &tooLarge) {
+			return err
+		}
+		self.log.Warning(tooLarge.Error())
+		if tooLarge.notification {
+			continue
+		}
+		// Without an id, as when the body was cut short, the error has a
+		// null id
+		if err := self.enqueue(errorResponse(tooLarge.id, jsonrpc2.CodeInvalidRequest, tooLarge.Error())); err != nil {
+			return err
+		}
+	}
+}
+
+// ([jsonrpc2.ObjectStream] interface)
+func (self *limitStream) WriteObject(obj any) error {
+	message, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	if self.maxSize > 0 && int64(len(message)) > self.maxSize {
+		tooLarge := &messageTooLargeError{size: int64(len(message)), limit: self.maxSize}
+		var fields messageFields
+		if err := json.Unmarshal(message, &fields); err != nil || fields.Method != nil || fields.ID == nil {
+			return tooLarge
+		}
+		self.log.Warningf("response %s", tooLarge.Error())
+		message = errorResponse(fields.ID, jsonrpc2.CodeInternalError, "response "+tooLarge.Error())
+	}
+	return self.enqueue(message)
+}
+
+// enqueue queues a message for writing, waiting while the queue is full.
+func (self *limitStream) enqueue(message json.RawMessage) error {
+	if self.queue == nil {
+		return self.stream.WriteObject(message)
+	}
+
+	select {
+	case self.queue <- message:
+		return nil
+	case <-self.done:
+		return io.ErrClosedPipe
+	default:
+	}
+
+	// The queue is full: wait for the client to catch up
+	var timeout <-chan time.Time
+	if self.slowClientTimeout > 0 {
+		timer := time.NewTimer(self.slowClientTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case self.queue <- message:
+		return nil
+	case <-self.done:
+		return io.ErrClosedPipe
+	case <-timeout:
+		self.log.Errorf("disconnecting client: %s for %s", errSlowClient.Error(), self.slowClientTimeout)
+		self.close(false)
+		return errSlowClient
+	}
+}
+
+// ([jsonrpc2.ObjectStream] interface)
+func (self *limitStream) Close() error {
+	return self.close(true)
+}
+
+// close closes the stream, after writing the queued messages if flush is
+// true. The client gets slowClientTimeout to read them.
+func (self *limitStream) close(flush bool) error {
+	var err error
+	self.closeOnce.Do(func() {
+		close(self.done)
+		if flush {
+			var timeout <-chan time.Time
+			if self.slowClientTimeout > 0 {
+				timer := time.NewTimer(self.slowClientTimeout)
+				defer timer.Stop()
+				timeout = timer.C
+			}
+			select {
+			case <-self.flushed:
+			case <-timeout:
+			}
+		}
+		err = self.stream.Close()
+	})
+	return err
+}
+
+// write writes the queued messages in order until the stream is closed,
+// then writes those still queued.
+func (self *limitStream) write() {
+	defer close(self.flushed)
+	for {
+		select {
+		case message := <-self.queue:
+			if !self.writeQueued(message) {
+				return
+			}
+		case <-self.done:
+			for {
+				select {
+				case message := <-self.queue:
+					if !self.writeQueued(message) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (self *limitStream) writeQueued(message json.RawMessage) bool {
+	if err := self.stream.WriteObject(message); err != nil {
+		self.log.Errorf("could not write message: %s", err.Error())
+		// Not waiting: Close may be waiting for this goroutine
+		go self.close(false)
+		return false
+	}
+	return true
+}
+
+// maxScannedIDSize is the size of the largest id idScanner keeps.
+const maxScannedIDSize = 256
+
+// idScanner finds the id of a JSON-RPC message in a body written to it
+// piece by piece, holding no more of the body than the id.
+type idScanner struct {
+	depth    int
+	object   bool // the body is an object
+	complete bool // the object was closed
+
+	inString bool
+	escaped  bool
+
+	expectKey  bool
+	readingKey bool
+	key        []byte
+	isID       bool
+
+	capturing bool
+	value     []byte
+	id        json.RawMessage
+}
+
+// ([io.Writer] interface)
+func (self *idScanner) Write(p []byte) (int, error) {
+	for _, c := range p {
+		self.scan(c)
+	}
+	return len(p), nil
+}
+
+// result returns the id, or nil, and whether the body was an object
+// without an id.
+func (self *idScanner) result() (json.RawMessage, bool) {
+	if len(self.id) > 0 && (self.id[0] == '"' || self.id[0] == '-' || (self.id[0] >= '0' && self.id[0] <= '9')) {
+		return self.id, false
+	}
+	return nil, self.object && self.complete && self.id == nil
+}
+
+func (self *idScanner) scan(c byte) {
+	if self.capturing {
+		self.capture(c)
+	}
+
+	if self.inString {
+		switch {
+		case self.escaped:
+			self.escaped = false
+		case c == '\\':
+			self.escaped = true
+			if self.readingKey {
+				// "id" has no escapes
+				self.key = append(self.key, 0)
+			}
+		case c == '"':
+			self.inString = false
+			if self.readingKey {
+				self.readingKey = false
+				self.isID = string(self.key) == "id"
+			}
+		case self.readingKey && len(self.key) < 3:
+			self.key = append(self.key, c)
+		}
+		return
+	}
+
+	switch c {
+	case '"':
+		self.inString = true
+		if self.depth == 1 && self.expectKey {
+			self.expectKey = false
+			self.readingKey = true
+			self.key = self.key[:0]
+		}
+	case '{', '[':
+		if self.depth == 0 && c == '{' {
+			self.object = true
+		}
+		self.depth++
+		if self.depth == 1 && c == '{' {
+			self.expectKey = true
+		}
+	case '}', ']':
+		if self.depth == 1 {
+			self.endValue()
+			if self.object {
+				self.complete = true
+			}
+		}
+		self.depth--
+	case ',':
+		if self.depth == 1 {
+			self.endValue()
+			self.expectKey = self.object
+		}
+	case ':':
+		if self.depth == 1 && self.isID {
+			self.isID = false
+			self.capturing = true
+			self.value = self.value[:0]
+		}
+	}
+}
+
+func (self *idScanner) capture(c byte) {
+	if self.depth == 1 && !self.inString && (c == ',' || c == '}' || c == ':') {
+		return
+	}
+	if !self.inString && (c == ' ' || c == '\t' || c == '\r' || c == '\n') {
+		return
+	}
+	if len(self.value) <= maxScannedIDSize {
+		self.value = append(self.value, c)
+	}
+}
+
+func (self *idScanner) endValue() {
+	if !self.capturing {
+		return
+	}
+	self.capturing = false
+	if len(self.value) <= maxScannedIDSize {
+		self.id = append(json.RawMessage(nil), self.value...)
+	} else {
+		self.id = json.RawMessage("null")
+	}
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// sizeHandler returns a result of "size" bytes for "big", and notifies the
+// client until it is disconnected for "flood".
+type sizeHandler struct {
+	size int
+}
+
+func (h *sizeHandler) Handle(context *lsp.Context) (any, bool, bool, error) {
+	switch context.Method {
+	case "big":
+		return strings.Repeat("x", h.size), true, true, nil
+	case "flood":
+		for i := 0; i < 100; i++ {
+			context.Notify("flooded", i)
+		}
+	}
+	return context.Method, true, true, nil
+}
+
+func newLimitTestConnection(t *testing.T, server *Server) (jsonrpc2.ObjectStream, *jsonrpc2.Conn) {
+	serverSide, clientSide := net.Pipe()
+	connection := server.newStreamConnection(serverSide)
+	client := jsonrpc2.NewBufferedStream(clientSide, jsonrpc2.VSCodeObjectCodec{})
+	t.Cleanup(func() {
+		client.Close()
+		connection.Close()
+	})
+	return client, connection
+}
+
+func TestMessageSizeLimit(t *testing.T) {
+	server := NewServer(&sizeHandler{size: 200}, "server-test-limits", false)
+	server.MaxMessageSize = 100
+	client, _ := newLimitTestConnection(t, server)
+
+	large := json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"echo","params":"` + strings.Repeat("x", 100) + `"}`)
+	if err := client.WriteObject(large); err != nil {
+		t.Fatal(err)
+	}
+	var response testResponse
+	if err := json.Unmarshal(readMessage(t, client), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.ID == nil || response.ID.Num != 1 || response.Error == nil || response.Error.Code != jsonrpc2.CodeInvalidRequest {
+		t.Errorf("expected an invalid request error for request 1, got %+v", response)
+	}
+
+	// A notification over the limit is not answered
+	large = json.RawMessage(`{"jsonrpc":"2.0","method":"echo","params":"` + strings.Repeat("x", 100) + `"}`)
+	if err := client.WriteObject(large); err != nil {
+		t.Fatal(err)
+	}
+
+	// The connection is still usable
+	if err := client.WriteObject(json.RawMessage(`{"jsonrpc":"2.0","id":2,"method":"echo"}`)); err != nil {
+		t.Fatal(err)
+	}
+	response = testResponse{}
+	if err := json.Unmarshal(readMessage(t, client), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.ID == nil || response.ID.Num != 2 || response.Result != "echo" {
+		t.Errorf("expected the result of request 2, got %+v", response)
+	}
+
+	// A response over the limit is replaced with an error
+	if err := client.WriteObject(json.RawMessage(`{"jsonrpc":"2.0","id":3,"method":"big"}`)); err != nil {
+		t.Fatal(err)
+	}
+	response = testResponse{}
+	if err := json.Unmarshal(readMessage(t, client), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.ID == nil || response.ID.Num != 3 || response.Error == nil || response.Error.Code != jsonrpc2.CodeInternalError {
+		t.Errorf("expected an error for request 3, got %+v", response)
+	}
+}
+
+func TestIDScanner(t *testing.T) {
+	tests := []struct {
+		body             string
+		wantID           string
+		wantNotification bool
+	}{
+		{body: `{"jsonrpc":"2.0","method":"m","params":{"id":1,"s":"\"id\":2"},"id":"a,b}"}`, wantID: `"a,b}"`},
+		{body: `{"id" : 42 ,"method":"m"}`, wantID: `42`},
+		{body: `{"method":"m","params":[{"id":1}]}`, wantNotification: true},
+		{body: `{"method":"m","params":"cut short`},
+		{body: `[{"id":1,"method":"m"}]`},
+		{body: `{"id":{"nested":1},"method":"m"}`},
+	}
+	for _, tt := range tests {
+		var scanner idScanner
+		// Written byte by byte, as a body arrives in pieces
+		for i := range len(tt.body) {
+			scanner.Write([]byte{tt.body[i]})
+		}
+		id, notification := scanner.result()
+		if string(id) != tt.wantID || notification != tt.wantNotification {
+			t.Errorf("%s: id = %s, notification = %t, want %s, %t", tt.body, id, notification, tt.wantID, tt.wantNotification)
+		}
+	}
+}
+
+func TestSlowClientDisconnected(t *testing.T) {
+	server := NewServer(&sizeHandler{}, "server-test-limits", false)
+	server.MaxPendingWrites = 1
+	server.SlowClientTimeout = 50 * time.Millisecond
+	client, connection := newLimitTestConnection(t, server)
+
+	// The client never reads the notifications
+	if err := client.WriteObject(json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"flood"}`)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-connection.DisconnectNotify():
+	case <-time.After(3 * time.Second):
+		t.Fatal("slow client was not disconnected")
+	}
+}
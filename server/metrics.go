@@ -0,0 +1,46 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+const (
+	requestsMetric        = "lsp_requests_total"
+	requestDurationMetric = "lsp_request_duration_seconds"
+
+	// otherMethodLabel labels the requests of methods the server does not
+	// handle, so clients cannot add series without bound
+	otherMethodLabel = "other"
+)
+
+// recordRequest counts a handled request by method and outcome and records
+// its duration.
+func (self *Server) recordRequest(method string, err error, duration time.Duration) {
+	outcome := requestOutcome(err)
+	if outcome == "method_not_found" {
+		method = otherMethodLabel
+	}
+	self.Metrics.Counter(requestsMetric, "method", method, "outcome", outcome).Inc()
+	self.Metrics.Histogram(requestDurationMetric, nil, "method", method).ObserveDuration(duration)
+}
+
+// requestOutcome classifies the error returned by dispatch.
+func requestOutcome(err error) string {
+	if err == nil {
+		return "ok"
+	}
+
+	var rpcErr *jsonrpc2.Error
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.Code {
+		case jsonrpc2.CodeMethodNotFound:
+			return "method_not_found"
+		case jsonrpc2.CodeInvalidParams:
+			return "invalid_params"
+		}
+	}
+	return "error"
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	contextpkg "context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// safeDispatch dispatches a request and converts a panic in the handler into
+// a JSON-RPC internal error, so one faulty provider does not take down the
+// server. Panics in goroutines started by a handler are not recovered.
+func (self *Server) safeDispatch(context contextpkg.Context, connection *jsonrpc2.Conn, request *jsonrpc2.Request) (result any, err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			result = nil
+			err = self.recoverRequest(request, value, debug.Stack())
+		}
+	}()
+	return self.dispatch(context, connection, request)
+}
+
+// recoverRequest logs a panic with its stack trace and a redacted snapshot of
+// the request, writes a crash report if CrashReportDir is set, and returns
+// the error to send to the client.
+func (self *Server) recoverRequest(request *jsonrpc2.Request, value any, stack []byte) error {
+	report := newCrashReport(request, value, stack)
+	self.Log.Errorf("panic handling %s: %v\n%s\n%s", request.Method, value, report.request(), stack)
+
+	if self.CrashReportDir != "" {
+		if path, err := report.write(self.CrashReportDir); err == nil {
+			self.Log.Errorf("crash report written to %s", path)
+		} else {
+			self.Log.Errorf("could not write crash report: %s", err.Error())
+		}
+	}
+
+	return &jsonrpc2.Error{
+		Code:    jsonrpc2.CodeInternalError,
+		Message: fmt.Sprintf("internal error handling %s", request.Method),
+	}
+}
+
+// crashReport describes a panic. Only the method, document URI, and position
+// of the request are kept; document content and other parameters may be
+// confidential and are left out.
+type crashReport struct {
+	Time     time.Time
+	Method   string
+	URI      string
+	Position *crashPosition
+	Panic    string
+	Stack    string
+}
+
+type crashPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+func newCrashReport(request *jsonrpc2.Request, value any, stack []byte) *crashReport {
+	report := &crashReport{
+		Time:   time.Now(),
+		Method: request.Method,
+		Panic:  fmt.Sprintf("%v", value),
+		Stack:  string(stack),
+	}
+
+	if request.Params != nil {
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			Position *crashPosition `json:"position"`
+		}
+		if err := json.Unmarshal(*request.Params, &params); err == nil {
+			report.URI = params.TextDocument.URI
+			report.Position = params.Position
+		}
+	}
+
+	return report
+}
+
+// request describes the triggering request in one line.
+func (self *crashReport) request() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "request: method=%s", self.Method)
+	if self.URI != "" {
+		fmt.Fprintf(&builder, " uri=%s", self.URI)
+	}
+	if self.Position != nil {
+		fmt.Fprintf(&builder, " position=%d:%d", self.Position.Line, self.Position.Character)
+	}
+	return builder.String()
+}
+
+// write writes the report to a new file in dir and returns its path.
+func (self *crashReport) write(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("lsp-crash-%s.txt", self.Time.UTC().Format("20060102T150405.000000000"))
+	path := filepath.Join(dir, name)
+	content := fmt.Sprintf("time: %s\n%s\npanic: %s\n\n%s",
+		self.Time.UTC().Format(time.RFC3339Nano), self.request(), self.Panic, self.Stack)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -10,12 +10,37 @@ import (
 )
 
 func (self *Server) RunWebSocket(address string) error {
-	mux := http.NewServeMux()
-	upgrader := websocket.Upgrader{CheckOrigin: func(request *http.Request) bool { return true }}
+	listener, err := self.newNetworkListener("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	// Not wrapped in http.TimeoutHandler, which cannot be upgraded to a web
+	// socket
+	server := http.Server{
+		Handler:      self.WebSocketHandler(),
+		ReadTimeout:  self.ReadTimeout,
+		WriteTimeout: self.WriteTimeout,
+	}
+
+	self.Log.Notice("listening for web socket connections", "address", address)
+	err = server.Serve(*listener)
+	return errors.Wrap(err, "WebSocket")
+}
+
+// WebSocketHandler returns an HTTP handler serving LSP over web sockets, for
+// mounting the server in an existing HTTP server, e.g. next to the files of
+// an in-browser editor.
+func (self *Server) WebSocketHandler() http.Handler {
+	upgrader := websocket.Upgrader{
+		HandshakeTimeout:  self.Timeout,
+		CheckOrigin:       func(request *http.Request) bool { return true },
+		EnableCompression: self.Compression,
+	}
 
 	var connectionCount uint64
 
-	mux.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 		connection, err := upgrader.Upgrade(writer, request, nil)
 		if err != nil {
 			self.Log.Warningf("error upgrading HTTP to web socket: %s", err.Error())
@@ -27,19 +52,4 @@ func (self *Server) RunWebSocket(address string) error {
 		defer commonlog.CallAndLogError(connection.Close, "connection.Close", log)
 		self.ServeWebSocket(connection, log)
 	})
-
-	listener, err := self.newNetworkListener("tcp", address)
-	if err != nil {
-		return err
-	}
-
-	server := http.Server{
-		Handler:      http.TimeoutHandler(mux, self.Timeout, ""),
-		ReadTimeout:  self.ReadTimeout,
-		WriteTimeout: self.WriteTimeout,
-	}
-
-	self.Log.Notice("listening for web socket connections", "address", address)
-	err = server.Serve(*listener)
-	return errors.Wrap(err, "WebSocket")
 }
@@ -5,6 +5,8 @@ import (
 
 	"github.com/tliron/commonlog"
 	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/metrics"
+	"github.com/SCKelemen/lsp/replay"
 )
 
 var DefaultTimeout = time.Minute
@@ -24,6 +26,60 @@ type Server struct {
 	WriteTimeout     time.Duration
 	StreamTimeout    time.Duration
 	WebSocketTimeout time.Duration
+
+	// Metrics records request counts and latencies per method when set
+	Metrics *metrics.Registry
+
+	// CrashReportDir, when set, receives a report file for every request
+	// whose handler panicked
+	CrashReportDir string
+
+	// Recorder, when set, records every message of every connection for
+	// later replay
+	Recorder *replay.Recorder
+
+	// MaxMessageSize, when set, is the maximum size in bytes of a message.
+	// Larger requests are answered with an error without being read into
+	// memory, and larger responses are replaced with an error. It is 0, no
+	// limit, by default
+	MaxMessageSize int64
+
+	// MaxPendingWrites, when set, is the number of outbound messages queued
+	// for a client before handlers writing more have to wait. It is 0 by
+	// default, writing messages synchronously
+	MaxPendingWrites int
+
+	// SlowClientTimeout, when set, is how long handlers wait for a client
+	// with a full write queue before the client is disconnected. It is 0 by
+	// default, waiting forever
+	SlowClientTimeout time.Duration
+
+	// Compression compresses large messages for clients that support it:
+	// with per-message deflate on web sockets, and with the ContentEncodings
+	// negotiated with headers on streams, like TCP connections
+	Compression bool
+
+	// Deadlines are soft deadlines per method. Requests taking longer are
+	// logged as slow, with the time spent in the providers timed with
+	// TimeProvider
+	Deadlines map[string]time.Duration
+
+	// PartialResults cancels the context of the requests of
+	// PartialResultMethods at their deadline, so their handlers can answer
+	// with the results found so far
+	PartialResults bool
+
+	// OnSlowRequest, when set, is called with every slow request after it
+	// is logged
+	OnSlowRequest func(event SlowRequest)
+
+	// NewSession, when set, creates the handler of each client connection,
+	// so one server process serves several editors, each with its own
+	// capabilities and open documents. Without it, all connections share
+	// Handler
+	NewSession func(session *Session) lsp.Handler
+
+	sessions sessions
 }
 
 func NewServer(handler lsp.Handler, logName string, debug bool) *Server {
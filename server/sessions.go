@@ -0,0 +1,114 @@
+package server
+
+import (
+	contextpkg "context"
+	"sort"
+	"sync"
+
+	"github.com/SCKelemen/lsp"
+)
+
+// Session is the state of the connection of one client. A server process
+// serving several editors, as with RunTCP, has one session per connection:
+// with Server.NewSession set, each has its own handler, and so its own
+// client capabilities and open documents, while state shared between the
+// clients, like a workspace index, is acquired from a SharedIndexes.
+type Session struct {
+	// ID identifies the session among those of the server, counting from 1.
+	ID uint64
+
+	// Handler handles the messages of the client.
+	Handler lsp.Handler
+
+	mu      sync.Mutex
+	onClose []func()
+	closed  bool
+}
+
+// OnClose registers fn to run when the client disconnects, like releasing
+// a shared index. Functions run in the reverse order of registration; on a
+// closed session, fn runs right away.
+func (self *Session) OnClose(fn func()) {
+	self.mu.Lock()
+	if self.closed {
+		self.mu.Unlock()
+		fn()
+		return
+	}
+	self.onClose = append(self.onClose, fn)
+	self.mu.Unlock()
+}
+
+// close runs the functions registered with OnClose.
+func (self *Session) close() {
+	self.mu.Lock()
+	onClose := self.onClose
+	self.onClose = nil
+	self.closed = true
+	self.mu.Unlock()
+
+	for i := len(onClose) - 1; i >= 0; i-- {
+		onClose[i]()
+	}
+}
+
+type sessionContextKey struct{}
+
+// SessionFromContext returns the session of the connection a request
+// belongs to, from the context of its handler, lsp.Context.Context.
+func SessionFromContext(context contextpkg.Context) (*Session, bool) {
+	session, ok := context.Value(sessionContextKey{}).(*Session)
+	return session, ok
+}
+
+func withSession(context contextpkg.Context, session *Session) contextpkg.Context {
+	return contextpkg.WithValue(context, sessionContextKey{}, session)
+}
+
+// sessions are the open sessions of a server.
+type sessions struct {
+	mu     sync.Mutex
+	lastID uint64
+	open   map[uint64]*Session
+}
+
+// Sessions returns the sessions of the connected clients, in the order
+// they connected.
+func (self *Server) Sessions() []*Session {
+	self.sessions.mu.Lock()
+	defer self.sessions.mu.Unlock()
+
+	sessions := make([]*Session, 0, len(self.sessions.open))
+	for _, session := range self.sessions.open {
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].ID < sessions[j].ID })
+	return sessions
+}
+
+// openSession creates the session of a new connection, with a handler of
+// its own if NewSession is set.
+func (self *Server) openSession() *Session {
+	self.sessions.mu.Lock()
+	self.sessions.lastID++
+	session := &Session{ID: self.sessions.lastID, Handler: self.Handler}
+	if self.sessions.open == nil {
+		self.sessions.open = make(map[uint64]*Session)
+	}
+	self.sessions.open[session.ID] = session
+	self.sessions.mu.Unlock()
+
+	if self.NewSession != nil {
+		session.Handler = self.NewSession(session)
+	}
+	return session
+}
+
+// closeSession forgets the session of a closed connection and runs its
+// OnClose functions.
+func (self *Server) closeSession(session *Session) {
+	self.sessions.mu.Lock()
+	delete(self.sessions.open, session.ID)
+	self.sessions.mu.Unlock()
+	session.close()
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// documentsHandler keeps the documents a client opened, as a per-client
+// handler does.
+type documentsHandler struct {
+	documents []string
+}
+
+func (h *documentsHandler) Handle(context *lsp.Context) (any, bool, bool, error) {
+	switch context.Method {
+	case "open":
+		var uri string
+		if err := json.Unmarshal(context.Params, &uri); err != nil {
+			return nil, true, false, err
+		}
+		h.documents = append(h.documents, uri)
+		return nil, true, true, nil
+	case "documents":
+		session, _ := SessionFromContext(context.Context)
+		return fmt.Sprintf("%d %v", session.ID, h.documents), true, true, nil
+	}
+	return nil, false, true, nil
+}
+
+func TestServerSessions(t *testing.T) {
+	server := NewServer(nil, "server-test-sessions", false)
+	closed := make(chan uint64, 2)
+	server.NewSession = func(session *Session) lsp.Handler {
+		session.OnClose(func() { closed <- session.ID })
+		return &documentsHandler{}
+	}
+
+	type client struct {
+		stream     jsonrpc2.ObjectStream
+		connection *jsonrpc2.Conn
+	}
+	var clients []client
+	for range 2 {
+		serverSide, clientSide := net.Pipe()
+		connection := server.newStreamConnection(serverSide)
+		stream := jsonrpc2.NewBufferedStream(clientSide, jsonrpc2.VSCodeObjectCodec{})
+		clients = append(clients, client{stream, connection})
+		t.Cleanup(func() {
+			stream.Close()
+			connection.Close()
+		})
+	}
+	call := func(c client, id int, method, params string) string {
+		t.Helper()
+		message := fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":%q,"params":%s}`, id, method, params)
+		if err := c.stream.WriteObject(json.RawMessage(message)); err != nil {
+			t.Fatal(err)
+		}
+		var response struct {
+			Result string `json:"result"`
+		}
+		if err := json.Unmarshal(readMessage(t, c.stream), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response.Result
+	}
+
+	call(clients[0], 1, "open", `"file:///a.go"`)
+	call(clients[1], 1, "open", `"file:///b.go"`)
+	if got := call(clients[0], 2, "documents", "null"); got != "1 [file:///a.go]" {
+		t.Errorf("documents of the first client = %q", got)
+	}
+	if got := call(clients[1], 2, "documents", "null"); got != "2 [file:///b.go]" {
+		t.Errorf("documents of the second client = %q", got)
+	}
+	if sessions := server.Sessions(); len(sessions) != 2 || sessions[0].ID != 1 || sessions[1].ID != 2 {
+		t.Errorf("sessions = %v, want 1 and 2", sessions)
+	}
+
+	clients[0].stream.Close()
+	select {
+	case id := <-closed:
+		if id != 1 {
+			t.Errorf("closed session %d, want 1", id)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("session not closed after the client disconnected")
+	}
+	if sessions := server.Sessions(); len(sessions) != 1 || sessions[0].ID != 2 {
+		t.Errorf("sessions after disconnecting = %v, want 2", sessions)
+	}
+	if got := call(clients[1], 3, "documents", "null"); got != "2 [file:///b.go]" {
+		t.Errorf("documents of the remaining client = %q", got)
+	}
+}
+
+type closingIndex struct {
+	root   string
+	closed atomic.Bool
+}
+
+func (i *closingIndex) Close() error {
+	i.closed.Store(true)
+	return nil
+}
+
+func TestSharedIndexes(t *testing.T) {
+	var builds atomic.Int32
+	indexes := NewSharedIndexes(func(root string) (*closingIndex, error) {
+		builds.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return &closingIndex{root: root}, nil
+	})
+
+	// Sessions acquiring the index while it is built wait for it
+	var wg sync.WaitGroup
+	results := make([]*closingIndex, 4)
+	releases := make([]func(), 4)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			index, release, err := indexes.Acquire("/ws")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results[i], releases[i] = index, release
+		}()
+	}
+	wg.Wait()
+	if builds.Load() != 1 {
+		t.Fatalf("built %d times, want once", builds.Load())
+	}
+	for _, index := range results {
+		if index != results[0] {
+			t.Fatal("sessions got different indexes")
+		}
+	}
+	if refs := indexes.Refs("/ws"); refs != 4 {
+		t.Errorf("refs = %d, want 4", refs)
+	}
+
+	for i, release := range releases[:3] {
+		release()
+		release() // releasing twice counts once
+		if refs := indexes.Refs("/ws"); refs != 3-i {
+			t.Errorf("refs after %d releases = %d, want %d", i+1, refs, 3-i)
+		}
+	}
+	if results[0].closed.Load() {
+		t.Error("index closed while a session holds it")
+	}
+	releases[3]()
+	if !results[0].closed.Load() || indexes.Refs("/ws") != 0 {
+		t.Error("index not closed after the last release")
+	}
+
+	// A session closing releases the index it acquired
+	session := &Session{ID: 1}
+	index, err := indexes.AcquireFor(session, "/ws")
+	if err != nil || builds.Load() != 2 {
+		t.Fatalf("AcquireFor = %v, %v after %d builds, want a rebuilt index", index, err, builds.Load())
+	}
+	session.close()
+	if !index.closed.Load() {
+		t.Error("index not released when its session closed")
+	}
+}
+
+func TestSharedIndexesBuildError(t *testing.T) {
+	fail := true
+	indexes := NewSharedIndexes(func(root string) (string, error) {
+		if fail {
+			return "", errors.New("no workspace")
+		}
+		return root, nil
+	})
+	if _, release, err := indexes.Acquire("/ws"); err == nil || release != nil {
+		t.Fatalf("Acquire = %v, want the build error", err)
+	}
+	if refs := indexes.Refs("/ws"); refs != 0 {
+		t.Errorf("refs after a failed build = %d, want 0", refs)
+	}
+	fail = false
+	if index, _, err := indexes.Acquire("/ws"); err != nil || index != "/ws" {
+		t.Errorf("Acquire after a failed build = %q, %v, want it rebuilt", index, err)
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"io"
+	"sync"
+)
+
+// SharedIndexes shares values that are expensive to build, like workspace
+// indexes, between the sessions of a server, by key, like the workspace
+// root. A value is built by the first session acquiring it, and the
+// sessions acquiring it meanwhile wait for it rather than building their
+// own. It is dropped, and closed if it is an io.Closer, when the last
+// session holding it releases it. It is safe for concurrent use.
+type SharedIndexes[T any] struct {
+	// Build builds the value of a key.
+	Build func(key string) (T, error)
+
+	mu      sync.Mutex
+	entries map[string]*sharedIndex[T]
+}
+
+type sharedIndex[T any] struct {
+	value T
+	err   error
+	ready chan struct{} // closed when built
+	refs  int
+}
+
+// NewSharedIndexes creates shared values built with build.
+func NewSharedIndexes[T any](build func(key string) (T, error)) *SharedIndexes[T] {
+	return &SharedIndexes[T]{Build: build}
+}
+
+// Acquire returns the value of key, building it if no session holds it.
+// Call release when done with it; calls after the first do nothing.
+func (self *SharedIndexes[T]) Acquire(key string) (value T, release func(), err error) {
+	self.mu.Lock()
+	entry, ok := self.entries[key]
+	if !ok {
+		entry = &sharedIndex[T]{ready: make(chan struct{})}
+		if self.entries == nil {
+			self.entries = make(map[string]*sharedIndex[T])
+		}
+		self.entries[key] = entry
+	}
+	entry.refs++
+	self.mu.Unlock()
+
+	if !ok {
+		entry.value, entry.err = self.Build(key)
+		if entry.err != nil {
+			self.mu.Lock()
+			if self.entries[key] == entry {
+				delete(self.entries, key)
+			}
+			self.mu.Unlock()
+		}
+		close(entry.ready)
+	} else {
+		<-entry.ready
+	}
+
+	if entry.err != nil {
+		var zero T
+		return zero, nil, entry.err
+	}
+	return entry.value, sync.OnceFunc(func() { self.release(key, entry) }), nil
+}
+
+// AcquireFor acquires the value of key for a session, which releases it
+// when its client disconnects.
+func (self *SharedIndexes[T]) AcquireFor(session *Session, key string) (T, error) {
+	value, release, err := self.Acquire(key)
+	if err != nil {
+		return value, err
+	}
+	session.OnClose(release)
+	return value, nil
+}
+
+// Refs returns the number of holders of the value of key, 0 if it is not
+// built.
+func (self *SharedIndexes[T]) Refs(key string) int {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if entry, ok := self.entries[key]; ok {
+		return entry.refs
+	}
+	return 0
+}
+
+func (self *SharedIndexes[T]) release(key string, entry *sharedIndex[T]) {
+	self.mu.Lock()
+	entry.refs--
+	if entry.refs > 0 || self.entries[key] != entry {
+		self.mu.Unlock()
+		return
+	}
+	delete(self.entries, key)
+	self.mu.Unlock()
+
+	if closer, ok := any(entry.value).(io.Closer); ok {
+		closer.Close()
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/cache"
+	"github.com/SCKelemen/lsp/protocol"
+)
+
+// StatisticsMethod is the method of the custom request answered by
+// HandleStatistics. It has no params. It is outside the workspace/
+// namespace, which belongs to the specification.
+const StatisticsMethod = "$/statistics"
+
+// Statistics describes the state of a server, for editor status bars and
+// troubleshooting. Parts without a source are left out.
+type Statistics struct {
+	Index           *IndexStatistics  `json:"index,omitempty"`
+	OpenDocuments   *int              `json:"openDocuments,omitempty"`
+	PendingAnalyses *int              `json:"pendingAnalyses,omitempty"`
+	Caches          []CacheStatistics `json:"caches,omitempty"`
+	Memory          MemoryStatistics  `json:"memory"`
+}
+
+// IndexStatistics describes a workspace index.
+type IndexStatistics struct {
+	Files   int `json:"files"`
+	Symbols int `json:"symbols"`
+
+	// LastIndexed is when the last indexing of the workspace finished, or
+	// zero if it was not indexed.
+	LastIndexed time.Time `json:"lastIndexed,omitzero"`
+
+	// LastIndexingMillis is how long the last indexing took.
+	LastIndexingMillis int64 `json:"lastIndexingMillis"`
+}
+
+// CacheStatistics describes a cache of a cache.Manager.
+type CacheStatistics struct {
+	Name      string  `json:"name"`
+	Entries   int     `json:"entries"`
+	Bytes     int64   `json:"bytes"`
+	Hits      uint64  `json:"hits"`
+	Misses    uint64  `json:"misses"`
+	Evictions uint64  `json:"evictions"`
+	HitRate   float64 `json:"hitRate"`
+}
+
+// MemoryStatistics describes the memory of the server process.
+type MemoryStatistics struct {
+	// HeapBytes is the memory of live and unswept heap objects.
+	HeapBytes uint64 `json:"heapBytes"`
+
+	// SystemBytes is the memory obtained from the operating system.
+	SystemBytes uint64 `json:"systemBytes"`
+
+	// CacheBytes is the memory accounted to the caches.
+	CacheBytes int64 `json:"cacheBytes"`
+
+	GCCycles uint32 `json:"gcCycles"`
+}
+
+// StatisticsSources are where Statistics come from. Nil sources are left
+// out of the statistics.
+type StatisticsSources struct {
+	// Index returns the statistics of the workspace index.
+	Index func() IndexStatistics
+
+	// OpenDocuments returns the number of open documents, like
+	// core.DocumentManager.Len.
+	OpenDocuments func() int
+
+	// PendingAnalyses returns the length of the analysis queue, like
+	// schedule.Scheduler.Pending.
+	PendingAnalyses func() int
+
+	Caches *cache.Manager
+}
+
+// Collect returns the current statistics.
+func (self StatisticsSources) Collect() Statistics {
+	var statistics Statistics
+	if self.Index != nil {
+		index := self.Index()
+		statistics.Index = &index
+	}
+	if self.OpenDocuments != nil {
+		open := self.OpenDocuments()
+		statistics.OpenDocuments = &open
+	}
+	if self.PendingAnalyses != nil {
+		pending := self.PendingAnalyses()
+		statistics.PendingAnalyses = &pending
+	}
+	if self.Caches != nil {
+		for _, s := range self.Caches.Stats() {
+			statistics.Caches = append(statistics.Caches, CacheStatistics{
+				Name:      s.Name,
+				Entries:   s.Entries,
+				Bytes:     s.Bytes,
+				Hits:      s.Hits,
+				Misses:    s.Misses,
+				Evictions: s.Evictions,
+				HitRate:   s.HitRate(),
+			})
+		}
+		statistics.Memory.CacheBytes = self.Caches.Used()
+	}
+
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+	statistics.Memory.HeapBytes = memory.HeapAlloc
+	statistics.Memory.SystemBytes = memory.Sys
+	statistics.Memory.GCCycles = memory.NumGC
+	return statistics
+}
+
+// HandleStatistics registers the StatisticsMethod request on handler,
+// answered with the statistics collected from sources.
+func HandleStatistics(handler *protocol.Handler, sources StatisticsSources) error {
+	return HandleRequest(handler, StatisticsMethod, func(context *lsp.Context, params struct{}) (Statistics, error) {
+		return sources.Collect(), nil
+	})
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/SCKelemen/lsp/cache"
+	"github.com/SCKelemen/lsp/protocol"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+type nopStore struct{}
+
+func (nopStore) Evict(uri string) {}
+
+func TestHandleStatistics(t *testing.T) {
+	caches := cache.NewManager(1 << 20)
+	caches.Register("ast", nopStore{})
+	caches.Add("ast", "file:///a.go", 100)
+	caches.Hit("ast", "file:///a.go")
+
+	indexed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	handler := &protocol.Handler{}
+	handler.SetInitialized(true)
+	err := HandleStatistics(handler, StatisticsSources{
+		Index: func() IndexStatistics {
+			return IndexStatistics{Files: 3, Symbols: 42, LastIndexed: indexed, LastIndexingMillis: 15}
+		},
+		OpenDocuments: func() int { return 2 },
+		Caches:        caches,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(handler, "server-test-statistics", false)
+
+	serverSide, clientSide := net.Pipe()
+	connection := server.newStreamConnection(serverSide)
+	client := jsonrpc2.NewBufferedStream(clientSide, jsonrpc2.VSCodeObjectCodec{})
+	t.Cleanup(func() {
+		client.Close()
+		connection.Close()
+	})
+
+	if err := client.WriteObject(json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"$/statistics"}`)); err != nil {
+		t.Fatal(err)
+	}
+	var response struct {
+		Result Statistics      `json:"result"`
+		Error  *jsonrpc2.Error `json:"error"`
+	}
+	message := readMessage(t, client)
+	if err := json.Unmarshal(message, &response); err != nil || response.Error != nil {
+		t.Fatalf("response %s: %v", message, err)
+	}
+
+	statistics := response.Result
+	if index := statistics.Index; index == nil || index.Files != 3 || index.Symbols != 42 || !index.LastIndexed.Equal(indexed) || index.LastIndexingMillis != 15 {
+		t.Errorf("index = %+v", statistics.Index)
+	}
+	if statistics.OpenDocuments == nil || *statistics.OpenDocuments != 2 {
+		t.Errorf("openDocuments = %v, want 2", statistics.OpenDocuments)
+	}
+	if statistics.PendingAnalyses != nil {
+		t.Errorf("pendingAnalyses = %v without a source, want it left out", *statistics.PendingAnalyses)
+	}
+	if len(statistics.Caches) != 1 || statistics.Caches[0].Name != "ast" || statistics.Caches[0].Bytes != 100 || statistics.Caches[0].Hits != 1 {
+		t.Errorf("caches = %+v", statistics.Caches)
+	}
+	if statistics.Memory.CacheBytes != 100 || statistics.Memory.HeapBytes == 0 {
+		t.Errorf("memory = %+v", statistics.Memory)
+	}
+}
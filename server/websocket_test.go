@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocketHandler(t *testing.T) {
+	server := NewServer(&sizeHandler{size: 5000}, "server-test-websocket", false)
+	server.Compression = true
+	httpServer := httptest.NewServer(server.WebSocketHandler())
+	defer httpServer.Close()
+
+	dialer := websocket.Dialer{EnableCompression: true, HandshakeTimeout: 3 * time.Second}
+	socket, _, err := dialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer socket.Close()
+
+	for id, method := range []string{"echo", "big"} {
+		if err := socket.WriteJSON(map[string]any{"jsonrpc": "2.0", "id": id, "method": method}); err != nil {
+			t.Fatal(err)
+		}
+		socket.SetReadDeadline(time.Now().Add(3 * time.Second))
+		var response testResponse
+		if err := socket.ReadJSON(&response); err != nil {
+			t.Fatal(err)
+		}
+		if response.ID == nil || int(response.ID.Num) != id || response.Error != nil {
+			t.Errorf("%s: unexpected response %+v", method, response)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Package uri converts between document URIs and file paths and compares
+// URIs the way clients mean them.
+//
+// Clients differ in how they spell the same file: VS Code sends
+// "file:///c%3A/Users/me/main.go" where another client sends
+// "file:///C:/Users/me/main.go", and a server building URIs by concatenation
+// produces "file://C:\Users\me\main.go". Normalize maps all of these to one
+// form so they can be used as map keys and compared with Equal.
+//
+// Windows paths are recognized by their syntax (a drive letter or a UNC
+// prefix) on every platform, so URIs from a Windows client are handled the
+// same way by a server running elsewhere, and the results do not depend on
+// the platform the tests run on.
+package uri
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// FileScheme is the scheme of URIs referring to files on disk.
+const FileScheme = "file"
+
+// Parse parses a URI and returns it in normal form. It fails if s is not an
+// absolute URI.
+func Parse(s string) (string, error) {
+	u, err := parse(s)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// Normalize returns the normal form of a URI, or s unchanged if it cannot be
+// parsed. In the normal form:
+//   - the scheme and the host of file URIs are lower case, and a "localhost"
+//     host is dropped
+//   - Windows drive letters are lower case and followed by an unescaped colon
+//   - percent-encoding is applied only where needed, with upper case hex
+//     digits; backslashes in file URIs become slashes
+func Normalize(s string) string {
+	u, err := parse(s)
+	if err != nil {
+		return s
+	}
+	return u.String()
+}
+
+// Equal reports whether two URIs refer to the same document.
+func Equal(a, b string) bool {
+	return a == b || Normalize(a) == Normalize(b)
+}
+
+// IsFile reports whether s is a file URI.
+func IsFile(s string) bool {
+	scheme, _, ok := strings.Cut(s, ":")
+	return ok && strings.EqualFold(scheme, FileScheme)
+}
+
+// FromPath returns the file URI for an absolute path. Slash-separated paths,
+// Windows paths with a drive letter ("C:\dir\file.go"), and UNC paths
+// ("\\server\share\file.go") are supported. A relative path is treated as
+// relative to the root directory.
+func FromPath(filePath string) string {
+	u := &url.URL{Scheme: FileScheme}
+
+	switch {
+	case isUNC(filePath):
+		host, rest, _ := strings.Cut(strings.ReplaceAll(filePath[2:], `\`, "/"), "/")
+		u.Host = strings.ToLower(host)
+		u.Path = "/" + rest
+
+	case hasDriveLetter(filePath):
+		u.Path = "/" + strings.ToLower(filePath[:1]) + strings.ReplaceAll(filePath[1:], `\`, "/")
+
+	default:
+		u.Path = filePath
+		if !strings.HasPrefix(u.Path, "/") {
+			u.Path = "/" + u.Path
+		}
+	}
+
+	u.Path = cleanPath(u.Path)
+	return u.String()
+}
+
+// ToPath returns the file path of a file URI. URIs with a drive letter or a
+// host map to Windows paths ("c:\dir\file.go", "\\server\share\file.go");
+// others map to slash-separated paths.
+func ToPath(s string) (string, error) {
+	u, err := parse(s)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != FileScheme {
+		return "", fmt.Errorf("not a file URI: %s", s)
+	}
+
+	switch {
+	case u.Host != "":
+		return `\\` + u.Host + strings.ReplaceAll(u.Path, "/", `\`), nil
+	case len(u.Path) > 1 && hasDriveLetter(u.Path[1:]):
+		return strings.ReplaceAll(u.Path[1:], "/", `\`), nil
+	default:
+		return u.Path, nil
+	}
+}
+
+func parse(s string) (*url.URL, error) {
+	if IsFile(s) {
+		// Paths concatenated onto "file://" may contain backslashes
+		s = strings.ReplaceAll(s, `\`, "/")
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" {
+		return nil, errors.New("URI has no scheme: " + s)
+	}
+
+	if u.Scheme == FileScheme {
+		u.Host = strings.ToLower(u.Host)
+		if u.Host == "localhost" {
+			u.Host = ""
+		}
+		// "file://c:/dir" puts the drive letter in the host
+		if len(u.Host) == 2 && u.Host[1] == ':' && isLetter(u.Host[0]) {
+			u.Path = "/" + u.Host + u.Path
+			u.Host = ""
+		}
+		if len(u.Path) > 1 && hasDriveLetter(u.Path[1:]) {
+			u.Path = "/" + strings.ToLower(u.Path[1:2]) + u.Path[2:]
+		}
+	}
+
+	// Re-encode from the decoded path so escaping is canonical, unless an
+	// escaped slash must stay distinct from a separator
+	if !strings.Contains(strings.ToUpper(u.EscapedPath()), "%2F") {
+		u.RawPath = ""
+	}
+	return u, nil
+}
+
+// cleanPath cleans a slash-separated path, keeping a trailing slash.
+func cleanPath(p string) string {
+	cleaned := path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// hasDriveLetter reports whether p starts with a Windows drive letter and
+// colon, e.g. "C:" in "C:\dir" or "c:/dir".
+func hasDriveLetter(p string) bool {
+	return len(p) >= 2 && p[1] == ':' && isLetter(p[0]) && (len(p) == 2 || p[2] == '/' || p[2] == '\\')
+}
+
+// isUNC reports whether p is a Windows UNC path such as \\server\share.
+func isUNC(p string) bool {
+	return len(p) > 2 && (strings.HasPrefix(p, `\\`) || strings.HasPrefix(p, "//")) && p[2] != '\\' && p[2] != '/'
+}
+
+func isLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
@@ -0,0 +1,104 @@
+package uri
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		uri      string
+		expected string
+	}{
+		{"escaped drive colon", "file:///c%3A/Users/me/main.go", "file:///c:/Users/me/main.go"},
+		{"upper case drive", "file:///C:/Users/me/main.go", "file:///c:/Users/me/main.go"},
+		{"concatenated windows path", `file://C:\Users\me\main.go`, "file:///c:/Users/me/main.go"},
+		{"space", "file:///home/me/a b.go", "file:///home/me/a%20b.go"},
+		{"needless escape", "file:///home/%7Eme/main.go", "file:///home/~me/main.go"},
+		{"escaped slash", "file:///home/me/a%2fb.go", "file:///home/me/a%2fb.go"},
+		{"scheme case and localhost", "FILE://localhost/etc/hosts", "file:///etc/hosts"},
+		{"unc host", "file://Server/share/main.go", "file://server/share/main.go"},
+		{"other scheme", "untitled:Untitled-1", "untitled:Untitled-1"},
+		{"unparsable", "not a uri", "not a uri"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.uri); got != tt.expected {
+				t.Errorf("Normalize(%q) = %q, expected %q", tt.uri, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	if got, err := Parse("file:///C%3A/x.go"); err != nil || got != "file:///c:/x.go" {
+		t.Errorf("Parse = %q, %v", got, err)
+	}
+	if _, err := Parse("/relative/path"); err == nil {
+		t.Error("expected error for URI without scheme")
+	}
+	if _, err := Parse("file://%zz"); err == nil {
+		t.Error("expected error for invalid escape")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	if !Equal("file:///c%3A/a/b.go", `file:///C:/a/b.go`) {
+		t.Error("expected spellings of the same Windows file to be equal")
+	}
+	if Equal("file:///a/b.go", "file:///a/B.go") {
+		t.Error("expected paths differing in case to differ")
+	}
+}
+
+func TestFromPathAndToPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		uri  string
+		// back is the result of ToPath, if it differs from path
+		back string
+	}{
+		{"unix", "/home/me/main.go", "file:///home/me/main.go", ""},
+		{"unix with space", "/home/me/my file.go", "file:///home/me/my%20file.go", ""},
+		{"unix with hash", "/tmp/a#b.go", "file:///tmp/a%23b.go", ""},
+		{"trailing slash", "/home/me/", "file:///home/me/", ""},
+		{"unclean", "/home/me/../you/./x.go", "file:///home/you/x.go", "/home/you/x.go"},
+		{"windows", `C:\Users\me\main.go`, "file:///c:/Users/me/main.go", `c:\Users\me\main.go`},
+		{"windows forward slashes", "d:/src/main.go", "file:///d:/src/main.go", `d:\src\main.go`},
+		{"unc", `\\Server\share\dir\main.go`, "file://server/share/dir/main.go", `\\server\share\dir\main.go`},
+		{"relative", "dir/main.go", "file:///dir/main.go", "/dir/main.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri := FromPath(tt.path)
+			if uri != tt.uri {
+				t.Fatalf("FromPath(%q) = %q, expected %q", tt.path, uri, tt.uri)
+			}
+
+			expected := tt.back
+			if expected == "" {
+				expected = tt.path
+			}
+			back, err := ToPath(uri)
+			if err != nil || back != expected {
+				t.Errorf("ToPath(%q) = %q, %v, expected %q", uri, back, err, expected)
+			}
+		})
+	}
+}
+
+func TestToPathErrors(t *testing.T) {
+	if _, err := ToPath("https://example.com/x.go"); err == nil {
+		t.Error("expected error for non-file URI")
+	}
+	if got, err := ToPath("file:///c%3A/x%20y.go"); err != nil || got != `c:\x y.go` {
+		t.Errorf("ToPath = %q, %v", got, err)
+	}
+}
+
+func TestIsFile(t *testing.T) {
+	if !IsFile("FILE:///x") || IsFile("untitled:x") || IsFile("/x") {
+		t.Error("unexpected IsFile result")
+	}
+}
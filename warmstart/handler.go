@@ -0,0 +1,237 @@
+package warmstart
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/protocol"
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+// Handler is a handler middleware saving a snapshot of the session on
+// shutdown and restoring the indexes from it when the next session
+// initializes the same workspace.
+type Handler struct {
+	// Handler handles the messages.
+	Handler lsp.Handler
+
+	// Store keeps the snapshots.
+	Store *Store
+
+	// Indexes are the indexes saved and restored, by name. Indexes the
+	// snapshot does not have, like ones added since, are left as they are,
+	// for the server to build.
+	Indexes map[string]Index
+
+	mu            sync.Mutex
+	root          string
+	documents     []Document
+	configuration json.RawMessage
+	restored      *Snapshot
+}
+
+// NewHandler creates a middleware keeping the snapshots of handler and
+// indexes in store.
+func NewHandler(handler lsp.Handler, store *Store, indexes map[string]Index) *Handler {
+	return &Handler{
+		Handler: handler,
+		Store:   store,
+		Indexes: indexes,
+	}
+}
+
+// ([lsp.Handler] interface)
+func (h *Handler) Handle(context *lsp.Context) (any, bool, bool, error) {
+	switch context.Method {
+	case protocol.MethodInitialize:
+		if err := h.restore(context.Params); err != nil && context.Notify != nil {
+			context.Notify(protocol.ServerWindowLogMessage, protocol.LogMessageParams{
+				Type:    protocol.MessageTypeWarning,
+				Message: fmt.Sprintf("Restoring the previous session failed: %v", err),
+			})
+		}
+	case protocol.MethodTextDocumentDidOpen:
+		var params protocol.DidOpenTextDocumentParams
+		if json.Unmarshal(context.Params, &params) == nil {
+			h.open(Document{
+				URI:        params.TextDocument.URI,
+				LanguageID: params.TextDocument.LanguageID,
+				Version:    int(params.TextDocument.Version),
+			})
+		}
+	case protocol.MethodTextDocumentDidChange:
+		var params protocol.DidChangeTextDocumentParams
+		if json.Unmarshal(context.Params, &params) == nil {
+			h.change(params.TextDocument.URI, int(params.TextDocument.Version))
+		}
+	case protocol.MethodTextDocumentDidClose:
+		var params protocol.DidCloseTextDocumentParams
+		if json.Unmarshal(context.Params, &params) == nil {
+			h.close(params.TextDocument.URI)
+		}
+	case protocol.MethodWorkspaceDidChangeConfiguration:
+		var params struct {
+			Settings json.RawMessage `json:"settings"`
+		}
+		if json.Unmarshal(context.Params, &params) == nil {
+			h.mu.Lock()
+			h.configuration = params.Settings
+			h.mu.Unlock()
+		}
+	}
+
+	result, validMethod, validParams, err := h.Handler.Handle(context)
+
+	if context.Method == protocol.MethodShutdown && err == nil {
+		// The client may not be listening anymore, but it may be
+		if saveErr := h.Save(); saveErr != nil && context.Notify != nil {
+			context.Notify(protocol.ServerWindowLogMessage, protocol.LogMessageParams{
+				Type:    protocol.MessageTypeWarning,
+				Message: fmt.Sprintf("Saving the session failed: %v", saveErr),
+			})
+		}
+	}
+	return result, validMethod, validParams, err
+}
+
+// Restored returns the snapshot the session was restored from, if any,
+// e.g. for the server to analyze the documents that were open before the
+// editor reopens them.
+func (h *Handler) Restored() (*Snapshot, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.restored, h.restored != nil
+}
+
+// Snapshot takes a snapshot of the session. The indexes that fail to
+// encode are left out, and their errors returned along with the snapshot.
+func (h *Handler) Snapshot() (*Snapshot, error) {
+	h.mu.Lock()
+	snapshot := &Snapshot{
+		Root:          h.root,
+		Documents:     append([]Document(nil), h.documents...),
+		Configuration: h.configuration,
+	}
+	h.mu.Unlock()
+
+	var errs []error
+	for name, index := range h.Indexes {
+		if err := snapshot.AddIndex(name, index); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return snapshot, errors.Join(errs...)
+}
+
+// Save saves a snapshot of the session to the store. It is called on
+// shutdown, and can be called periodically to survive crashes.
+func (h *Handler) Save() error {
+	h.mu.Lock()
+	root := h.root
+	h.mu.Unlock()
+	if root == "" {
+		return errors.New("no workspace to save the session of")
+	}
+
+	snapshot, err := h.Snapshot()
+	if saveErr := h.Store.Save(snapshot); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// restore loads the snapshot of the workspace being initialized and
+// restores the indexes from it.
+func (h *Handler) restore(params json.RawMessage) error {
+	var initialize protocol.InitializeParams
+	if err := json.Unmarshal(params, &initialize); err != nil {
+		return nil
+	}
+	root := workspaceRoot(&initialize)
+	h.mu.Lock()
+	h.root = root
+	h.mu.Unlock()
+	if root == "" {
+		return nil
+	}
+
+	snapshot, err := h.Store.Load(root)
+	if errors.Is(err, ErrNoSnapshot) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var errs []error
+	for name, index := range h.Indexes {
+		if _, err := snapshot.RestoreIndex(name, index); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	h.mu.Lock()
+	h.restored = snapshot
+	// The client may not send its configuration again
+	if h.configuration == nil {
+		h.configuration = snapshot.Configuration
+	}
+	h.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// open records a document opened, replacing it if it was open.
+func (h *Handler) open(document Document) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i := h.find(document.URI); i >= 0 {
+		h.documents[i] = document
+		return
+	}
+	h.documents = append(h.documents, document)
+}
+
+// change records the new version of an open document.
+func (h *Handler) change(uri string, version int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i := h.find(uri); i >= 0 {
+		h.documents[i].Version = version
+	}
+}
+
+// close forgets a closed document.
+func (h *Handler) close(uri string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i := h.find(uri); i >= 0 {
+		h.documents = append(h.documents[:i], h.documents[i+1:]...)
+	}
+}
+
+// find returns the position of an open document, or -1. It is called with
+// mu held.
+func (h *Handler) find(uri string) int {
+	for i, document := range h.documents {
+		if uripkg.Equal(document.URI, uri) {
+			return i
+		}
+	}
+	return -1
+}
+
+// workspaceRoot returns the normalized URI of the workspace of an
+// initialize request: its root URI, its first folder, or its root path.
+func workspaceRoot(params *protocol.InitializeParams) string {
+	switch {
+	case params.RootURI != nil && *params.RootURI != "":
+		return uripkg.Normalize(*params.RootURI)
+	case len(params.WorkspaceFolders) > 0:
+		return uripkg.Normalize(params.WorkspaceFolders[0].URI)
+	case params.RootPath != nil && *params.RootPath != "":
+		return uripkg.Normalize(uripkg.FromPath(*params.RootPath))
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package warmstart
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/SCKelemen/lsp"
+	"github.com/SCKelemen/lsp/protocol"
+)
+
+// countingHandler counts the requests it handles into an index.
+type countingHandler struct {
+	index *countIndex
+}
+
+func (h *countingHandler) Handle(context *lsp.Context) (any, bool, bool, error) {
+	if context.Method == protocol.MethodInitialize {
+		return h.index.count, true, true, nil
+	}
+	h.index.count++
+	return nil, true, true, nil
+}
+
+func TestHandler(t *testing.T) {
+	store := NewStore(t.TempDir())
+	session := func() (*Handler, *countIndex, func(method string, params any) any) {
+		index := &countIndex{}
+		handler := NewHandler(&countingHandler{index: index}, store, map[string]Index{"count": index})
+		handle := func(method string, params any) any {
+			t.Helper()
+			encoded, _ := json.Marshal(params)
+			result, _, _, err := handler.Handle(&lsp.Context{
+				Method: method,
+				Params: encoded,
+				Notify: func(method string, params any) {
+					t.Errorf("notified %s %v", method, params)
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return result
+		}
+		return handler, index, handle
+	}
+	initialize := map[string]any{"rootUri": "file:///ws"}
+
+	first, _, handle := session()
+	if count := handle(protocol.MethodInitialize, initialize); count != 0 {
+		t.Fatalf("count at the first start = %v, want 0", count)
+	}
+	if _, ok := first.Restored(); ok {
+		t.Error("first session restored without a snapshot")
+	}
+	document := func(uri string, version int) map[string]any {
+		return map[string]any{"textDocument": map[string]any{"uri": uri, "languageId": "go", "version": version}}
+	}
+	handle(protocol.MethodTextDocumentDidOpen, document("file:///ws/a.go", 1))
+	handle(protocol.MethodTextDocumentDidOpen, document("file:///ws/b.go", 1))
+	handle(protocol.MethodTextDocumentDidChange, document("file:///ws/a.go", 2))
+	handle(protocol.MethodTextDocumentDidClose, document("file:///ws/b.go", 1))
+	handle(protocol.MethodWorkspaceDidChangeConfiguration, map[string]any{"settings": map[string]any{"tabSize": 4}})
+	handle(protocol.MethodShutdown, nil)
+
+	// The next session initializing the workspace restores the index,
+	// saved after the handler handled shutdown, before the handler sees
+	// the initialize request
+	second, _, handle := session()
+	if count := handle(protocol.MethodInitialize, initialize); count != 6 {
+		t.Errorf("count at the warm start = %v, want 6", count)
+	}
+	snapshot, ok := second.Restored()
+	if !ok {
+		t.Fatal("second session not restored")
+	}
+	if want := (Document{URI: "file:///ws/a.go", LanguageID: "go", Version: 2}); len(snapshot.Documents) != 1 || snapshot.Documents[0] != want {
+		t.Errorf("restored documents %+v, want %+v", snapshot.Documents, want)
+	}
+	if string(snapshot.Configuration) != `{"tabSize":4}` {
+		t.Errorf("restored configuration %s", snapshot.Configuration)
+	}
+
+	// The configuration carries over to the next snapshot
+	current, err := second.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(current.Configuration) != `{"tabSize":4}` || len(current.Documents) != 0 {
+		t.Errorf("snapshot of the second session %+v", current)
+	}
+
+	// Other workspaces start cold
+	_, _, handle = session()
+	if count := handle(protocol.MethodInitialize, map[string]any{"rootUri": "file:///other"}); count != 0 {
+		t.Errorf("count of another workspace = %v, want 0", count)
+	}
+}
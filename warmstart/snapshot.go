@@ -0,0 +1,201 @@
+// Package warmstart lets a server restart without re-indexing the
+// workspace from scratch.
+//
+// On shutdown, a Handler middleware saves a Snapshot of the session to a
+// Store on disk: the metadata of the open documents, the configuration the
+// client last sent, and the state of the registered indexes. When the next
+// session initializes the same workspace, the Handler restores the indexes
+// before the wrapped handler sees the initialize request, so a reconnecting
+// editor is ready as soon as the indexes have checked which files changed
+// in the meantime, rather than after a full re-index.
+package warmstart
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FormatVersion is the version of the snapshot format. Snapshots of other
+// versions are ignored.
+const FormatVersion = 1
+
+// ErrNoSnapshot is returned by Store.Load when there is no usable snapshot
+// of a workspace.
+var ErrNoSnapshot = errors.New("no snapshot")
+
+// Index is the state of a server worth keeping across restarts, like a
+// symbol index. Its encoding is opaque to the snapshot, but must be JSON.
+type Index interface {
+	// MarshalIndex encodes the state of the index.
+	MarshalIndex() ([]byte, error)
+
+	// UnmarshalIndex restores the state of the index from an encoding of
+	// MarshalIndex, possibly by an earlier process. The files it covers
+	// may have changed since: it is up to the index to check them.
+	UnmarshalIndex(data []byte) error
+}
+
+// Document is the metadata of a document open when a snapshot was taken.
+// The content is not kept: the editor sends it again when it reopens the
+// document.
+type Document struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId,omitempty"`
+	Version    int    `json:"version"`
+}
+
+// Snapshot is the state of a session of a server, by workspace.
+type Snapshot struct {
+	// Version is the FormatVersion the snapshot was saved with.
+	Version int `json:"version"`
+
+	// Root is the URI of the workspace.
+	Root string `json:"root"`
+
+	// Saved is when the snapshot was taken.
+	Saved time.Time `json:"saved"`
+
+	// Documents are the documents that were open, in the order they were
+	// opened.
+	Documents []Document `json:"documents,omitempty"`
+
+	// Configuration holds the settings of the last
+	// workspace/didChangeConfiguration notification, if any.
+	Configuration json.RawMessage `json:"configuration,omitempty"`
+
+	// Indexes hold the encodings of the indexes, by name.
+	Indexes map[string]json.RawMessage `json:"indexes,omitempty"`
+}
+
+// AddIndex adds the state of an index to the snapshot under name.
+func (s *Snapshot) AddIndex(name string, index Index) error {
+	data, err := index.MarshalIndex()
+	if err != nil {
+		return fmt.Errorf("index %s: %w", name, err)
+	}
+	if !json.Valid(data) {
+		return fmt.Errorf("index %s: encoding is not JSON", name)
+	}
+	if s.Indexes == nil {
+		s.Indexes = make(map[string]json.RawMessage)
+	}
+	s.Indexes[name] = data
+	return nil
+}
+
+// RestoreIndex restores an index from its state under name, reporting
+// whether the snapshot has it.
+func (s *Snapshot) RestoreIndex(name string, index Index) (bool, error) {
+	data, ok := s.Indexes[name]
+	if !ok {
+		return false, nil
+	}
+	if err := index.UnmarshalIndex(data); err != nil {
+		return false, fmt.Errorf("index %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// Store keeps snapshots in a directory, one file per workspace.
+type Store struct {
+	// Dir is the directory of the snapshots, created as needed.
+	Dir string
+
+	// MaxAge, if positive, is the age past which Load ignores a snapshot.
+	MaxAge time.Duration
+}
+
+// NewStore creates a store keeping snapshots in dir.
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// DefaultStore returns a store in the user's cache directory, under name,
+// like the name of the server.
+func DefaultStore(name string) (*Store, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(filepath.Join(dir, name, "warmstart")), nil
+}
+
+// Save writes a snapshot, replacing the previous one of its workspace. The
+// file is replaced atomically, so a crash while saving leaves the previous
+// snapshot intact.
+func (s *Store) Save(snapshot *Snapshot) error {
+	snapshot.Version = FormatVersion
+	if snapshot.Saved.IsZero() {
+		snapshot.Saved = time.Now()
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(s.Dir, "snapshot-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), s.path(snapshot.Root))
+}
+
+// Load reads the snapshot of the workspace root. It returns ErrNoSnapshot
+// if there is none, or if it is of another format version or older than
+// MaxAge.
+func (s *Store) Load(root string) (*Snapshot, error) {
+	data, err := os.ReadFile(s.path(root))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNoSnapshot
+	} else if err != nil {
+		return nil, err
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoSnapshot, err)
+	}
+	switch {
+	case snapshot.Version != FormatVersion:
+		return nil, fmt.Errorf("%w: format version %d, want %d", ErrNoSnapshot, snapshot.Version, FormatVersion)
+	case snapshot.Root != root:
+		return nil, fmt.Errorf("%w: snapshot of %s", ErrNoSnapshot, snapshot.Root)
+	case s.MaxAge > 0 && time.Since(snapshot.Saved) > s.MaxAge:
+		return nil, fmt.Errorf("%w: saved %s", ErrNoSnapshot, snapshot.Saved.Format(time.RFC3339))
+	}
+	return &snapshot, nil
+}
+
+// Remove deletes the snapshot of the workspace root, if any.
+func (s *Store) Remove(root string) error {
+	err := os.Remove(s.path(root))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// path returns the file of the snapshot of a workspace, named after a hash
+// of its URI.
+func (s *Store) path(root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:16])+".json")
+}
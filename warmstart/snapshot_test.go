@@ -0,0 +1,105 @@
+package warmstart
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countIndex is an index of a count.
+type countIndex struct {
+	count int
+}
+
+func (i *countIndex) MarshalIndex() ([]byte, error) {
+	return []byte{byte('0' + i.count)}, nil
+}
+
+func (i *countIndex) UnmarshalIndex(data []byte) error {
+	if len(data) != 1 {
+		return errors.New("not a count")
+	}
+	i.count = int(data[0] - '0')
+	return nil
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "snapshots"))
+	if _, err := store.Load("file:///ws"); !errors.Is(err, ErrNoSnapshot) {
+		t.Fatalf("Load before saving = %v, want ErrNoSnapshot", err)
+	}
+
+	snapshot := &Snapshot{
+		Root:          "file:///ws",
+		Documents:     []Document{{URI: "file:///ws/a.go", LanguageID: "go", Version: 3}},
+		Configuration: []byte(`{"tabSize":4}`),
+	}
+	if err := snapshot.AddIndex("count", &countIndex{count: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := store.Load("file:///ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Documents) != 1 || loaded.Documents[0] != snapshot.Documents[0] || string(loaded.Configuration) != `{"tabSize":4}` {
+		t.Errorf("loaded %+v, want %+v", loaded, snapshot)
+	}
+	var index countIndex
+	if ok, err := loaded.RestoreIndex("count", &index); !ok || err != nil || index.count != 7 {
+		t.Errorf("RestoreIndex = %v, %v with count %d, want 7", ok, err, index.count)
+	}
+	if ok, err := loaded.RestoreIndex("symbols", &index); ok || err != nil {
+		t.Errorf("RestoreIndex of a missing index = %v, %v", ok, err)
+	}
+
+	// Snapshots are by workspace
+	if _, err := store.Load("file:///other"); !errors.Is(err, ErrNoSnapshot) {
+		t.Errorf("Load of another workspace = %v, want ErrNoSnapshot", err)
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(store.Dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("store holds %d files (%v), want 1", len(entries), err)
+	}
+
+	store.MaxAge = time.Hour
+	snapshot.Saved = time.Now().Add(-2 * time.Hour)
+	if err := store.Save(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load("file:///ws"); !errors.Is(err, ErrNoSnapshot) {
+		t.Errorf("Load of an old snapshot = %v, want ErrNoSnapshot", err)
+	}
+
+	if err := store.Remove("file:///ws"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Remove("file:///ws"); err != nil {
+		t.Errorf("Remove of a removed snapshot = %v", err)
+	}
+}
+
+func TestStoreFormatVersion(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if err := store.Save(&Snapshot{Root: "file:///ws"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(store.path("file:///ws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = []byte(string(data[:len(data)-1]) + `,"version":0}`)
+	if err := os.WriteFile(store.path("file:///ws"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load("file:///ws"); !errors.Is(err, ErrNoSnapshot) {
+		t.Errorf("Load of another format version = %v, want ErrNoSnapshot", err)
+	}
+}
@@ -0,0 +1,129 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+// ErrOutsideWorkspace is returned by a Guard for files outside its roots.
+var ErrOutsideWorkspace = errors.New("file is outside the workspace")
+
+// ErrSchemeNotAllowed is returned by a Guard for URIs with a scheme it does
+// not allow.
+var ErrSchemeNotAllowed = errors.New("URI scheme not allowed")
+
+// Guard restricts the files a server reads on behalf of a client to the
+// workspace. URIs and paths come from documents and requests, so a link to
+// "../../etc/passwd", a URI like "file:///ws/%2e%2e/secret", or a symbolic
+// link out of the workspace must not make the server read other files.
+//
+// A Guard normalizes paths, resolving ".." and symbolic links, and accepts
+// only those within one of its roots. Providers, walkers, and content
+// providers take an optional Guard; a nil Guard allows every file.
+type Guard struct {
+	// Roots are the directories files may be in.
+	Roots []string
+
+	// Schemes are the URI schemes allowed besides "file", like "untitled".
+	// URIs with other schemes are rejected by CheckURI.
+	Schemes []string
+}
+
+// NewGuard creates a guard allowing the files under roots.
+func NewGuard(roots ...string) *Guard {
+	return &Guard{Roots: roots}
+}
+
+// CheckURI checks that uri has an allowed scheme and, for a file URI, that
+// the file is within the roots.
+func (g *Guard) CheckURI(uri string) error {
+	if g == nil {
+		return nil
+	}
+	if uripkg.IsFile(uri) {
+		_, err := g.Path(uri)
+		return err
+	}
+	scheme, _, _ := strings.Cut(uri, ":")
+	for _, allowed := range g.Schemes {
+		if strings.EqualFold(scheme, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrSchemeNotAllowed, uri)
+}
+
+// Path returns the path of the file a file URI refers to, if it is within
+// the roots.
+func (g *Guard) Path(uri string) (string, error) {
+	if !uripkg.IsFile(uri) {
+		return "", fmt.Errorf("%w: %s", ErrSchemeNotAllowed, uri)
+	}
+	path, err := uripkg.ToPath(uri)
+	if err != nil {
+		return "", err
+	}
+	return g.CheckPath(path)
+}
+
+// CheckPath returns the clean absolute form of path, if it is within the
+// roots. The path is checked after resolving symbolic links, as far as it
+// exists, so links cannot lead out of the roots.
+func (g *Guard) CheckPath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if g == nil {
+		return path, nil
+	}
+	real := resolveSymlinks(path)
+	for _, root := range g.Roots {
+		root, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		if within(root, path) && within(resolveSymlinks(root), real) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrOutsideWorkspace, path)
+}
+
+// ReadFile reads the file a file URI refers to, if it is within the roots.
+func (g *Guard) ReadFile(uri string) ([]byte, error) {
+	path, err := g.Path(uri)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// resolveSymlinks returns path with the symbolic links of its longest
+// existing prefix resolved, so paths of files not created yet resolve too.
+func resolveSymlinks(path string) string {
+	var rest []string
+	for {
+		if real, err := filepath.EvalSymlinks(path); err == nil {
+			return filepath.Join(append([]string{real}, rest...)...)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(append([]string{path}, rest...)...)
+		}
+		rest = append([]string{filepath.Base(path)}, rest...)
+		path = parent
+	}
+}
+
+// within reports whether path is dir or inside it. Both are clean and
+// absolute.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
@@ -0,0 +1,93 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	uripkg "github.com/SCKelemen/lsp/uri"
+)
+
+func TestGuard(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "ws")
+	writeTree(t, dir, map[string]string{
+		"ws/main.go":    "package main\n",
+		"ws-other/x.go": "package x\n",
+		"secret.txt":    "secret\n",
+	})
+	if err := os.Symlink(filepath.Join(dir, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Skipf("symbolic links not supported: %v", err)
+	}
+	guard := &Guard{Roots: []string{root}, Schemes: []string{"untitled"}}
+
+	allowed := []string{
+		uripkg.FromPath(filepath.Join(root, "main.go")),
+		uripkg.FromPath(filepath.Join(root, "new", "file.go")),
+		uripkg.FromPath(root) + "/sub/../main.go",
+		"untitled:Untitled-1",
+	}
+	for _, uri := range allowed {
+		if err := guard.CheckURI(uri); err != nil {
+			t.Errorf("CheckURI(%s) = %v, want allowed", uri, err)
+		}
+	}
+
+	outside := []string{
+		uripkg.FromPath(filepath.Join(dir, "secret.txt")),
+		uripkg.FromPath(root) + "/../secret.txt",
+		uripkg.FromPath(root) + "/%2e%2e/secret.txt",
+		uripkg.FromPath(filepath.Join(dir, "ws-other", "x.go")),
+		uripkg.FromPath(filepath.Join(root, "link.txt")),
+	}
+	for _, uri := range outside {
+		if err := guard.CheckURI(uri); !errors.Is(err, ErrOutsideWorkspace) {
+			t.Errorf("CheckURI(%s) = %v, want ErrOutsideWorkspace", uri, err)
+		}
+		if _, err := guard.ReadFile(uri); err == nil {
+			t.Errorf("ReadFile(%s) succeeded, want an error", uri)
+		}
+	}
+
+	for _, uri := range []string{"https://example.com/x", "vscode-remote://host/ws/main.go"} {
+		if err := guard.CheckURI(uri); !errors.Is(err, ErrSchemeNotAllowed) {
+			t.Errorf("CheckURI(%s) = %v, want ErrSchemeNotAllowed", uri, err)
+		}
+	}
+
+	data, err := guard.ReadFile(uripkg.FromPath(filepath.Join(root, "main.go")))
+	if err != nil || string(data) != "package main\n" {
+		t.Errorf("ReadFile = %q, %v", data, err)
+	}
+
+	var nilGuard *Guard
+	if err := nilGuard.CheckURI(uripkg.FromPath(filepath.Join(dir, "secret.txt"))); err != nil {
+		t.Errorf("nil guard rejected a file: %v", err)
+	}
+}
+
+func TestWalker_Guard(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "ws")
+	writeTree(t, dir, map[string]string{
+		"ws/main.go":        "package main\n",
+		"outside/secret.go": "package secret\n",
+	})
+	if err := os.Symlink(filepath.Join(dir, "outside"), filepath.Join(root, "linked")); err != nil {
+		t.Skipf("symbolic links not supported: %v", err)
+	}
+
+	w := NewWalker(root)
+	w.Symlinks = SymlinkFollow
+	w.Guard = NewGuard(root)
+	if got := walkedFiles(t, w); len(got) != 1 || got[0] != "main.go" {
+		t.Errorf("walked %v, want only main.go", got)
+	}
+
+	w = NewWalker(filepath.Join(dir, "outside"))
+	w.Guard = NewGuard(root)
+	if err := w.Walk(func(string, os.FileInfo) error { return nil }); !errors.Is(err, ErrOutsideWorkspace) {
+		t.Errorf("Walk outside the guard = %v, want ErrOutsideWorkspace", err)
+	}
+}
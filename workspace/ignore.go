@@ -0,0 +1,156 @@
+package workspace
+
+import (
+	"bufio"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is one pattern from an ignore file or exclude list.
+type ignoreRule struct {
+	// base is the directory the pattern is relative to, slash-separated and
+	// relative to the walk root ("" for the root itself).
+	base     string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+	re       *regexp.Regexp
+}
+
+// ignoreList is an ordered set of rules. Later rules take precedence, so
+// rules from nested ignore files are appended after those of their parents.
+type ignoreList struct {
+	rules []ignoreRule
+}
+
+// parseIgnorePattern parses one line in .gitignore syntax.
+// It returns false for blank lines and comments.
+func parseIgnorePattern(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		// Escaped leading "!" or "#"
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	// A slash anywhere but at the end anchors the pattern to base;
+	// otherwise it matches the name at any depth.
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	re, err := regexp.Compile("^" + globToRegexp(line) + "$")
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// globToRegexp translates a gitignore glob to a regular expression.
+// "*" and "?" do not match "/", "**" matches across directories, and
+// bracket expressions are passed through.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// add appends the rules parsed from lines, relative to base.
+func (l *ignoreList) add(base string, lines []string) {
+	for _, line := range lines {
+		if rule, ok := parseIgnorePattern(base, line); ok {
+			l.rules = append(l.rules, rule)
+		}
+	}
+}
+
+// addFile appends the rules of an ignore file.
+func (l *ignoreList) addFile(base string, f io.Reader) {
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	l.add(base, lines)
+}
+
+// clone returns a copy that can be extended without affecting l.
+func (l *ignoreList) clone() *ignoreList {
+	return &ignoreList{rules: append([]ignoreRule(nil), l.rules...)}
+}
+
+// ignored reports whether rel (slash-separated, relative to the walk root)
+// is excluded. The last matching rule decides.
+func (l *ignoreList) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range l.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		target := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			target = rel[len(rule.base)+1:]
+		}
+		if !rule.anchored {
+			target = path.Base(target)
+		}
+
+		if rule.re.MatchString(target) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,48 @@
+package workspace
+
+import (
+	"testing"
+)
+
+func TestIgnoreList(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		lines   []string
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{name: "name at any depth", lines: []string{"*.log"}, path: "a/b/debug.log", ignored: true},
+		{name: "no match", lines: []string{"*.log"}, path: "a/main.go", ignored: false},
+		{name: "comment and blank", lines: []string{"# *.go", ""}, path: "main.go", ignored: false},
+		{name: "directory only matches dir", lines: []string{"build/"}, path: "build", isDir: true, ignored: true},
+		{name: "directory only skips file", lines: []string{"build/"}, path: "build", ignored: false},
+		{name: "anchored with leading slash", lines: []string{"/out"}, path: "out", isDir: true, ignored: true},
+		{name: "anchored does not match nested", lines: []string{"/out"}, path: "src/out", isDir: true, ignored: false},
+		{name: "anchored with inner slash", lines: []string{"docs/*.md"}, path: "docs/a.md", ignored: true},
+		{name: "star does not cross directories", lines: []string{"docs/*.md"}, path: "docs/x/a.md", ignored: false},
+		{name: "double star", lines: []string{"**/gen/*.go"}, path: "a/b/gen/x.go", ignored: true},
+		{name: "double star at root", lines: []string{"**/gen/*.go"}, path: "gen/x.go", ignored: true},
+		{name: "trailing double star", lines: []string{"tmp/**"}, path: "tmp/a/b.txt", ignored: true},
+		{name: "negation re-includes", lines: []string{"*.log", "!keep.log"}, path: "keep.log", ignored: false},
+		{name: "last match wins", lines: []string{"!keep.log", "*.log"}, path: "keep.log", ignored: true},
+		{name: "question mark", lines: []string{"file?.txt"}, path: "file1.txt", ignored: true},
+		{name: "bracket class", lines: []string{"file[0-9].txt"}, path: "file7.txt", ignored: true},
+		{name: "negated bracket class", lines: []string{"file[!0-9].txt"}, path: "file7.txt", ignored: false},
+		{name: "escaped bang", lines: []string{`\!important`}, path: "!important", ignored: true},
+		{name: "nested base applies below", base: "sub", lines: []string{"*.tmp"}, path: "sub/x/a.tmp", ignored: true},
+		{name: "nested base does not apply outside", base: "sub", lines: []string{"*.tmp"}, path: "other/a.tmp", ignored: false},
+		{name: "nested anchored", base: "sub", lines: []string{"/gen"}, path: "sub/gen", isDir: true, ignored: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &ignoreList{}
+			l.add(tt.base, tt.lines)
+			if got := l.ignored(tt.path, tt.isDir); got != tt.ignored {
+				t.Errorf("ignored(%q) = %v, want %v", tt.path, got, tt.ignored)
+			}
+		})
+	}
+}
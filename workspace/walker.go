@@ -0,0 +1,219 @@
+// Package workspace provides utilities for scanning a workspace on disk.
+//
+// The Walker streams the files of a workspace to a callback while skipping
+// version control and dependency directories, files excluded by .gitignore,
+// and files that are too large to be worth indexing. Workspace symbol
+// providers, indexers, and exporters can share it so they agree on which files
+// belong to the workspace.
+//
+// Walkers read the OS file system unless given an fs.FS, as in browsers
+// where a WebAssembly language server has no disk.
+//
+// A Guard keeps the files a server reads for its clients within the
+// workspace roots. Walkers and the providers reading files take one, so
+// the rules are set in one place.
+package workspace
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// DefaultExcludes are directories skipped by a Walker created with NewWalker.
+var DefaultExcludes = []string{".git/", ".hg/", ".svn/", "node_modules/"}
+
+// DefaultMaxFileSize is the file size limit of a Walker created with NewWalker.
+const DefaultMaxFileSize = 2 << 20 // 2MB
+
+// SymlinkPolicy controls how a Walker treats symbolic links.
+type SymlinkPolicy int
+
+const (
+	// SymlinkSkip ignores symbolic links.
+	SymlinkSkip SymlinkPolicy = iota
+
+	// SymlinkFollow follows symbolic links to files and directories.
+	// Each directory is visited at most once, so link cycles terminate.
+	SymlinkFollow
+)
+
+// WalkFunc is called for each file found by a Walker.
+// path is the file's path on disk (joined to the walker's Root), or in the
+// walker's FS if set, and info
+// describes the file, following symbolic links. Returning fs.SkipAll stops
+// the walk without error; any other error stops it and is returned by Walk.
+type WalkFunc func(path string, info fs.FileInfo) error
+
+// Walker walks the files of a workspace.
+type Walker struct {
+	// Root is the workspace directory.
+	Root string
+
+	// Exclude lists patterns in .gitignore syntax, relative to Root, for
+	// files and directories to skip in addition to the ignore files.
+	Exclude []string
+
+	// IgnoreFiles are the names of ignore files honored in every directory,
+	// e.g. ".gitignore". Patterns in a nested ignore file apply below its
+	// directory and take precedence over those of its parents.
+	IgnoreFiles []string
+
+	// Symlinks controls whether symbolic links are followed.
+	Symlinks SymlinkPolicy
+
+	// MaxFileSize skips files larger than this many bytes. Zero means no limit.
+	MaxFileSize int64
+
+	// FS, when set, is walked instead of the OS file system, with Root as
+	// a slash-separated path in it, like ".". Symbolic links are skipped:
+	// only on the OS file system can cycles be detected.
+	FS fs.FS
+
+	// Guard, when set, restricts the walk to its roots: Walk fails for a
+	// Root outside them, and symbolic links leading out of them are
+	// skipped. It does not apply to an FS.
+	Guard *Guard
+}
+
+// NewWalker creates a walker for root that honors .gitignore, skips
+// DefaultExcludes and symbolic links, and skips files over DefaultMaxFileSize.
+func NewWalker(root string) *Walker {
+	return &Walker{
+		Root:        root,
+		Exclude:     append([]string(nil), DefaultExcludes...),
+		IgnoreFiles: []string{".gitignore"},
+		MaxFileSize: DefaultMaxFileSize,
+	}
+}
+
+// Walk calls fn for every file in the workspace that is not excluded, in
+// lexical order within each directory. Files are reported as they are found
+// rather than collected first. Entries that cannot be read are skipped; Walk
+// only fails if Root cannot be read, the Guard rejects it, or fn returns an
+// error.
+func (w *Walker) Walk(fn WalkFunc) error {
+	if w.Guard != nil && w.FS == nil {
+		if _, err := w.Guard.CheckPath(w.Root); err != nil {
+			return err
+		}
+	}
+	if _, err := w.readDir(w.Root); err != nil {
+		return err
+	}
+
+	ignores := &ignoreList{}
+	ignores.add("", w.Exclude)
+
+	visited := make(map[string]bool)
+	if real, err := filepath.EvalSymlinks(w.Root); err == nil {
+		visited[real] = true
+	}
+
+	err := w.walkDir(w.Root, "", ignores, visited, fn)
+	if err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkDir walks dir, whose slash-separated path relative to Root is rel.
+func (w *Walker) walkDir(dir, rel string, ignores *ignoreList, visited map[string]bool, fn WalkFunc) error {
+	entries, err := w.readDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	// Ignore files in this directory extend the inherited rules
+	for _, name := range w.IgnoreFiles {
+		if f, err := w.open(w.join(dir, name)); err == nil {
+			ignores = ignores.clone()
+			ignores.addFile(rel, f)
+			f.Close()
+		}
+	}
+
+	for _, entry := range entries {
+		full := w.join(dir, entry.Name())
+		entryRel := path.Join(rel, entry.Name())
+
+		info, err := w.entryInfo(full, entry)
+		if err != nil || info == nil {
+			continue
+		}
+
+		if info.IsDir() {
+			if ignores.ignored(entryRel, true) {
+				continue
+			}
+			if w.followSymlinks() {
+				// Record every directory so one reached again via a link
+				// (including a cycle back to an ancestor) is skipped
+				real, err := filepath.EvalSymlinks(full)
+				if err != nil || visited[real] {
+					continue
+				}
+				visited[real] = true
+			}
+			if err := w.walkDir(full, entryRel, ignores, visited, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !info.Mode().IsRegular() || ignores.ignored(entryRel, false) {
+			continue
+		}
+		if w.MaxFileSize > 0 && info.Size() > w.MaxFileSize {
+			continue
+		}
+		if err := fn(full, info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// entryInfo returns the file info for an entry, resolving symbolic links if
+// the policy allows. It returns nil for links that are skipped.
+func (w *Walker) entryInfo(full string, entry fs.DirEntry) (fs.FileInfo, error) {
+	if entry.Type()&fs.ModeSymlink == 0 {
+		return entry.Info()
+	}
+	if !w.followSymlinks() {
+		return nil, nil
+	}
+	if w.Guard != nil {
+		if _, err := w.Guard.CheckPath(full); err != nil {
+			return nil, nil
+		}
+	}
+	return os.Stat(full)
+}
+
+func (w *Walker) followSymlinks() bool {
+	return w.Symlinks == SymlinkFollow && w.FS == nil
+}
+
+func (w *Walker) readDir(dir string) ([]fs.DirEntry, error) {
+	if w.FS != nil {
+		return fs.ReadDir(w.FS, dir)
+	}
+	return os.ReadDir(dir)
+}
+
+func (w *Walker) open(name string) (fs.File, error) {
+	if w.FS != nil {
+		return w.FS.Open(name)
+	}
+	return os.Open(name)
+}
+
+func (w *Walker) join(dir, name string) string {
+	if w.FS != nil {
+		return path.Join(dir, name)
+	}
+	return filepath.Join(dir, name)
+}
@@ -0,0 +1,173 @@
+package workspace
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// writeTree creates files under root; content sizes are taken from the map.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// walkedFiles returns the slash-separated paths relative to root that w reports.
+func walkedFiles(t *testing.T, w *Walker) []string {
+	t.Helper()
+	var files []string
+	err := w.Walk(func(path string, info fs.FileInfo) error {
+		rel, err := filepath.Rel(w.Root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	return files
+}
+
+func TestWalker_IgnoresAndExcludes(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".gitignore":              "build/\n*.log\n!keep.log\n",
+		".git/HEAD":               "ref: refs/heads/main\n",
+		"node_modules/x/index.js": "module.exports = {}\n",
+		"build/out.go":            "package out\n",
+		"debug.log":               "log\n",
+		"keep.log":                "log\n",
+		"main.go":                 "package main\n",
+		"pkg/a.go":                "package pkg\n",
+		"pkg/.gitignore":          "generated.go\n",
+		"pkg/generated.go":        "package pkg\n",
+		"other/generated.go":      "package other\n",
+		"testdata/fixture.txt":    "fixture\n",
+	})
+
+	w := NewWalker(root)
+	w.Exclude = append(w.Exclude, "testdata/")
+
+	got := walkedFiles(t, w)
+	want := []string{".gitignore", "keep.log", "main.go", "other/generated.go", "pkg/.gitignore", "pkg/a.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWalker_MaxFileSize(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"small.go": "package small\n",
+		"large.go": "package large\n" + strings.Repeat("// padding\n", 100),
+	})
+
+	w := NewWalker(root)
+	w.MaxFileSize = 100
+
+	got := walkedFiles(t, w)
+	if !reflect.DeepEqual(got, []string{"small.go"}) {
+		t.Errorf("got %v, want only small.go", got)
+	}
+}
+
+func TestWalker_Symlinks(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"src/a.go": "package src\n",
+	})
+	if err := os.Symlink(filepath.Join(root, "src"), filepath.Join(root, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	// A link back to the root forms a cycle
+	if err := os.Symlink(root, filepath.Join(root, "src", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWalker(root)
+	if got := walkedFiles(t, w); !reflect.DeepEqual(got, []string{"src/a.go"}) {
+		t.Errorf("skip policy: got %v, want only src/a.go", got)
+	}
+
+	// With links followed, src is reached twice (directly and via link) but
+	// each directory is only walked once and the cycle terminates.
+	w.Symlinks = SymlinkFollow
+	got := walkedFiles(t, w)
+	if len(got) != 1 || (got[0] != "src/a.go" && got[0] != "link/a.go") {
+		t.Errorf("follow policy: got %v, want a.go once", got)
+	}
+}
+
+func TestWalker_SkipAll(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"a.go": "package a\n",
+		"b.go": "package b\n",
+	})
+
+	count := 0
+	err := NewWalker(root).Walk(func(path string, info fs.FileInfo) error {
+		count++
+		return fs.SkipAll
+	})
+	if err != nil {
+		t.Errorf("expected nil error after SkipAll, got %v", err)
+	}
+	if count != 1 {
+		t.Errorf("callback called %d times, want 1", count)
+	}
+}
+
+func TestWalker_MissingRoot(t *testing.T) {
+	err := NewWalker(filepath.Join(t.TempDir(), "missing")).Walk(func(string, fs.FileInfo) error {
+		return nil
+	})
+	if err == nil {
+		t.Error("expected an error for a missing root")
+	}
+}
+
+func TestWalker_FS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ws/.gitignore":     {Data: []byte("*.log\n")},
+		"ws/debug.log":      {Data: []byte("log\n")},
+		"ws/main.go":        {Data: []byte("package main\n")},
+		"ws/pkg/a.go":       {Data: []byte("package pkg\n")},
+		"ws/.git/HEAD":      {Data: []byte("ref: refs/heads/main\n")},
+		"ws/pkg/large.go":   {Data: []byte(strings.Repeat("x", 200))},
+		"ws/pkg/link":       {Data: []byte("a.go"), Mode: fs.ModeSymlink},
+		"outside/ignore.go": {Data: []byte("package outside\n")},
+	}
+
+	w := NewWalker("ws")
+	w.FS = fsys
+	w.MaxFileSize = 100
+	w.Symlinks = SymlinkFollow
+
+	var got []string
+	err := w.Walk(func(path string, info fs.FileInfo) error {
+		got = append(got, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ws/.gitignore", "ws/main.go", "ws/pkg/a.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}